@@ -0,0 +1,274 @@
+// Package arr integrates with Sonarr / Radarr (v3 api): query their wanted (missing) lists, which can be used
+// to select site torrents to download, and notify them to import downloaded releases.
+// See https://sonarr.tv/docs/api/ and https://radarr.video/docs/api/ .
+package arr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+// Page size of Sonarr wanted/missing api requests.
+const WANTED_PAGE_SIZE = 250
+
+// A wanted (monitored and missing) item of arr: a missing episode (Sonarr) or movie (Radarr).
+type Wanted struct {
+	Arr     string `json:"arr"`
+	Title   string `json:"title"`   // series or movie title
+	Year    int64  `json:"year"`    // 0 if unknown
+	Season  int64  `json:"season"`  // Sonarr only
+	Episode int64  `json:"episode"` // Sonarr only
+	ImdbId  string `json:"imdbId"`
+	TmdbId  int64  `json:"tmdbId"`
+	TvdbId  int64  `json:"tvdbId"` // Sonarr only
+	title   string // normalized title
+}
+
+type Client struct {
+	Name       string
+	Type       string // config.ARR_TYPE_SONARR or config.ARR_TYPE_RADARR
+	config     *config.ArrConfigStruct
+	apiUrl     string // e.g. "http://localhost:8989/api/v3/"
+	httpClient *http.Client
+}
+
+type Status struct {
+	AppName      string `json:"appName"`
+	InstanceName string `json:"instanceName"`
+	Version      string `json:"version"`
+}
+
+type sonarrMissingResponse struct {
+	Page         int64 `json:"page"`
+	PageSize     int64 `json:"pageSize"`
+	TotalRecords int64 `json:"totalRecords"`
+	Records      []*struct {
+		SeasonNumber  int64 `json:"seasonNumber"`
+		EpisodeNumber int64 `json:"episodeNumber"`
+		Series        *struct {
+			Title  string `json:"title"`
+			Year   int64  `json:"year"`
+			ImdbId string `json:"imdbId"`
+			TmdbId int64  `json:"tmdbId"`
+			TvdbId int64  `json:"tvdbId"`
+		} `json:"series"`
+	} `json:"records"`
+}
+
+type radarrMovie struct {
+	Title       string `json:"title"`
+	Year        int64  `json:"year"`
+	ImdbId      string `json:"imdbId"`
+	TmdbId      int64  `json:"tmdbId"`
+	Monitored   bool   `json:"monitored"`
+	HasFile     bool   `json:"hasFile"`
+	IsAvailable bool   `json:"isAvailable"`
+}
+
+type command struct {
+	Id         int64  `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Path       string `json:"path,omitempty"`
+	ImportMode string `json:"importMode,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+// Sonarr series titles of same name shows are disambiguated by year, e.g. "Doctor Who (2005)".
+var titleYearRegexp = regexp.MustCompile(`\s*\(\d{4}\)$`)
+
+// Create client of the arr of name in config file.
+func New(name string) (*Client, error) {
+	arrConfig := config.GetArrConfig(name)
+	if arrConfig == nil {
+		return nil, fmt.Errorf("arr %s: %w", name, constants.ErrNotFound)
+	}
+	httpClient := &http.Client{
+		Timeout: time.Duration(util.FirstNonZeroIntegerArg(config.Timeout, config.DEFAULT_TIMEOUT)) * time.Second,
+	}
+	if proxy := config.GetProxy(); proxy != "" && proxy != constants.NONE {
+		transport, err := util.NewProxyTransport(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create proxy transport: %w", err)
+		}
+		httpClient.Transport = transport
+	}
+	return &Client{
+		Name:       arrConfig.Name,
+		Type:       arrConfig.Type,
+		config:     arrConfig,
+		apiUrl:     strings.TrimSuffix(arrConfig.Url, "/") + "/api/v3/",
+		httpClient: httpClient,
+	}, nil
+}
+
+// Return the system status of arr. It can be used to test the connection.
+func (c *Client) Status() (*Status, error) {
+	status := &Status{}
+	if err := util.FetchJson(c.apiUrl+"system/status", status, c.httpClient, c.header()); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Return the wanted list of arr: the monitored missing episodes (Sonarr) or
+// monitored, available and missing movies (Radarr).
+func (c *Client) Wanted() ([]*Wanted, error) {
+	var wanted []*Wanted
+	if c.Type == config.ARR_TYPE_RADARR {
+		var movies []*radarrMovie
+		if err := util.FetchJson(c.apiUrl+"movie", &movies, c.httpClient, c.header()); err != nil {
+			return nil, err
+		}
+		for _, movie := range movies {
+			if movie.Monitored && !movie.HasFile && movie.IsAvailable {
+				wanted = append(wanted, c.newWanted(&Wanted{
+					Title:  movie.Title,
+					Year:   movie.Year,
+					ImdbId: movie.ImdbId,
+					TmdbId: movie.TmdbId,
+				}))
+			}
+		}
+		return wanted, nil
+	}
+	for page := int64(1); ; page++ {
+		res := &sonarrMissingResponse{}
+		params := url.Values{
+			"page":          {fmt.Sprint(page)},
+			"pageSize":      {fmt.Sprint(WANTED_PAGE_SIZE)},
+			"includeSeries": {"true"},
+			"monitored":     {"true"},
+		}
+		if err := util.FetchJson(c.apiUrl+"wanted/missing?"+params.Encode(), res, c.httpClient,
+			c.header()); err != nil {
+			return nil, err
+		}
+		for _, record := range res.Records {
+			if record.Series == nil {
+				continue
+			}
+			wanted = append(wanted, c.newWanted(&Wanted{
+				Title:   record.Series.Title,
+				Year:    record.Series.Year,
+				Season:  record.SeasonNumber,
+				Episode: record.EpisodeNumber,
+				ImdbId:  record.Series.ImdbId,
+				TmdbId:  record.Series.TmdbId,
+				TvdbId:  record.Series.TvdbId,
+			}))
+		}
+		if len(res.Records) == 0 || page*WANTED_PAGE_SIZE >= res.TotalRecords {
+			break
+		}
+	}
+	return wanted, nil
+}
+
+// Notify arr to scan and import the downloaded release at path (a file or folder),
+// which must be accessible by arr. Return the id of the queued arr command.
+func (c *Client) Notify(path string) (int64, error) {
+	name := "DownloadedEpisodesScan"
+	if c.Type == config.ARR_TYPE_RADARR {
+		name = "DownloadedMoviesScan"
+	}
+	importMode := c.config.ImportMode
+	if importMode == "" {
+		importMode = "Copy"
+	}
+	data, err := json.Marshal(&command{Name: name, Path: path, ImportMode: importMode})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.apiUrl+"command", bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = c.header()
+	req.Header.Set("Content-Type", "application/json")
+	util.LogHttpRequest(req)
+	res, err := c.httpClient.Do(req)
+	util.LogHttpResponse(res, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post command: %w: %w", constants.ErrNetwork, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("failed to post command: %w", util.NewHttpStatusError(res.StatusCode))
+	}
+	result := &command{}
+	if err = json.Unmarshal(body, result); err != nil {
+		return 0, fmt.Errorf("invalid command response: %w", err)
+	}
+	return result.Id, nil
+}
+
+func (c *Client) header() http.Header {
+	return http.Header{
+		"X-Api-Key": {c.config.ApiKey},
+		"Accept":    {"application/json"},
+	}
+}
+
+func (c *Client) newWanted(wanted *Wanted) *Wanted {
+	wanted.Arr = c.Name
+	wanted.Title = titleYearRegexp.ReplaceAllString(wanted.Title, "")
+	wanted.title = releasename.NormalizeTitle(wanted.Title)
+	return wanted
+}
+
+// Report whether release (parsed from a torrent name) matches the wanted item:
+// same (normalized) title, and year if both known. For Sonarr items, the release must be a TV release of
+// the same season & episode, a season pack of the same season or a complete series (no season).
+func (w *Wanted) Match(release *releasename.Release) bool {
+	if w.title == "" || releasename.NormalizeTitle(release.Title) != w.title {
+		return false
+	}
+	if w.Year > 0 && release.Year > 0 && w.Year != release.Year {
+		return false
+	}
+	if w.Season == 0 && w.Episode == 0 { // movie
+		return !release.TV
+	}
+	if !release.TV {
+		return false
+	}
+	return release.Season == 0 ||
+		release.Season == w.Season && (release.Episode == 0 || release.Episode == w.Episode)
+}
+
+// Return the first item of wanted that matches release. Return nil if none.
+func MatchWanted(wanted []*Wanted, release *releasename.Release) *Wanted {
+	for _, w := range wanted {
+		if w.Match(release) {
+			return w
+		}
+	}
+	return nil
+}
+
+func (w *Wanted) String() string {
+	str := w.Title
+	if w.Year > 0 {
+		str += fmt.Sprintf(" (%d)", w.Year)
+	}
+	if w.Season > 0 || w.Episode > 0 {
+		str += fmt.Sprintf(" S%02dE%02d", w.Season, w.Episode)
+	}
+	return str
+}
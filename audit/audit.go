@@ -0,0 +1,128 @@
+// Audit log: an append-only local log of all mutating operations performed by ptool
+// (e.g. torrents added to / deleted from client, client config changed, local files removed,
+// ptool config file written).
+// Each record is a JSON line in the "ptool_audit.txt" file of config dir.
+// It's best effort: failures of writing audit log are logged but never abort the operation.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+// Actions of audit records.
+const (
+	ACTION_ADD_TORRENT    = "add"
+	ACTION_DELETE_TORRENT = "delete"
+	ACTION_MODIFY_TORRENT = "modify"
+	ACTION_SET_SAVE_PATH  = "setsavepath"
+	ACTION_EDIT_TRACKER   = "edittracker"
+	ACTION_SET_CONFIG     = "setconfig"
+	ACTION_REMOVE_FILE    = "removefile"
+	ACTION_WRITE_CONFIG   = "writeconfig"
+)
+
+type Record struct {
+	Ts      int64          `json:"ts"`
+	Action  string         `json:"action"`
+	Target  string         `json:"target"`           // client name, or file path for file actions
+	Command string         `json:"command"`          // ptool command (args) that performed the action
+	Params  map[string]any `json:"params,omitempty"` // e.g. infoHashes, category
+	Error   string         `json:"error,omitempty"`  // non-empty if the action failed
+}
+
+var mu sync.Mutex
+
+// Return the audit log file path.
+func Filename() string {
+	return filepath.Join(config.ConfigDir, config.AUDIT_FILENAME)
+}
+
+// Append a record of action to audit log. err is the result of the action.
+// Nothing is recorded in dry run mode.
+func Log(action string, target string, params map[string]any, err error) {
+	if flags.DryRun || config.ConfigDir == "" {
+		return
+	}
+	record := &Record{
+		Ts:      util.Now(),
+		Action:  action,
+		Target:  target,
+		Command: strings.Join(os.Args[1:], " "),
+		Params:  params,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	file, err := os.OpenFile(Filename(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, constants.PERM)
+	if err != nil {
+		log.Warnf("Failed to open audit log: %v", err)
+		return
+	}
+	defer file.Close()
+	if err = json.NewEncoder(file).Encode(record); err != nil {
+		log.Warnf("Failed to write audit log: %v", err)
+	}
+}
+
+// Read all records of audit log file, in time order. Return nil if the file does not exist.
+func Load(filename string) ([]*Record, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	records := []*Record{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, constants.BIG_FILE_SIZE)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		record := &Record{}
+		if err := json.Unmarshal(line, record); err != nil {
+			log.Warnf("Invalid audit log line %d: %v", lineno, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// Write the current config data back to config file (see config.Set) and record it to audit log.
+func SetConfig() error {
+	err := config.Set()
+	Log(ACTION_WRITE_CONFIG, filepath.Join(config.ConfigDir, config.ConfigFile), nil, err)
+	return err
+}
+
+// Remove the local file and record it to audit log.
+func RemoveFile(filename string) error {
+	err := os.Remove(util.LongPath(filename))
+	if absFilename, err := filepath.Abs(filename); err == nil {
+		filename = absFilename
+	}
+	Log(ACTION_REMOVE_FILE, filename, nil, err)
+	return err
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"bytes"
+
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/sagan/ptool/audit"
+)
+
+// A client wrapper that records the mutating operations on torrents or client config to audit log.
+// Only the operations which are hard to revert are recorded (e.g. tags changes are not).
+type auditedClient struct {
+	Client
+}
+
+func (c *auditedClient) log(action string, params map[string]any, err error) {
+	audit.Log(action, c.GetName(), params, err)
+}
+
+func (c *auditedClient) AddTorrent(torrentContent []byte, option *TorrentOption, meta map[string]int64) error {
+	err := c.Client.AddTorrent(torrentContent, option, meta)
+	params := map[string]any{}
+	if metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent)); err == nil {
+		params["infoHash"] = metaInfo.HashInfoBytes().HexString()
+		if info, err := metaInfo.UnmarshalInfo(); err == nil {
+			params["name"] = info.BestName()
+		}
+	}
+	if option != nil {
+		params["category"] = option.Category
+		params["tags"] = option.Tags
+		params["savePath"] = option.SavePath
+		params["paused"] = option.Pause
+	}
+	c.log(audit.ACTION_ADD_TORRENT, params, err)
+	return err
+}
+
+func (c *auditedClient) ModifyTorrent(infoHash string, option *TorrentOption, meta map[string]int64) error {
+	err := c.Client.ModifyTorrent(infoHash, option, meta)
+	params := map[string]any{"infoHash": infoHash}
+	if option != nil {
+		params["name"] = option.Name
+		params["category"] = option.Category
+		params["savePath"] = option.SavePath
+	}
+	c.log(audit.ACTION_MODIFY_TORRENT, params, err)
+	return err
+}
+
+func (c *auditedClient) DeleteTorrents(infoHashes []string, deleteFiles bool) error {
+	err := c.Client.DeleteTorrents(infoHashes, deleteFiles)
+	c.log(audit.ACTION_DELETE_TORRENT, map[string]any{"infoHashes": infoHashes, "deleteFiles": deleteFiles}, err)
+	return err
+}
+
+func (c *auditedClient) SetTorrentsSavePath(infoHashes []string, savePath string) error {
+	err := c.Client.SetTorrentsSavePath(infoHashes, savePath)
+	c.log(audit.ACTION_SET_SAVE_PATH, map[string]any{"infoHashes": infoHashes, "savePath": savePath}, err)
+	return err
+}
+
+func (c *auditedClient) SetAllTorrentsSavePath(savePath string) error {
+	err := c.Client.SetAllTorrentsSavePath(savePath)
+	c.log(audit.ACTION_SET_SAVE_PATH, map[string]any{"all": true, "savePath": savePath}, err)
+	return err
+}
+
+func (c *auditedClient) EditTorrentTracker(infoHash string, oldTracker string, newTracker string,
+	replaceHost bool) error {
+	err := c.Client.EditTorrentTracker(infoHash, oldTracker, newTracker, replaceHost)
+	c.log(audit.ACTION_EDIT_TRACKER, map[string]any{
+		"infoHash":    infoHash,
+		"oldTracker":  oldTracker,
+		"newTracker":  newTracker,
+		"replaceHost": replaceHost,
+	}, err)
+	return err
+}
+
+func (c *auditedClient) SetConfig(variable string, value string) error {
+	err := c.Client.SetConfig(variable, value)
+	c.log(audit.ACTION_SET_CONFIG, map[string]any{"variable": variable, "value": value}, err)
+	return err
+}
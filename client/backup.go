@@ -0,0 +1,182 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/sagan/ptool/constants"
+)
+
+const (
+	BACKUP_VERSION       = 1
+	BACKUP_MANIFEST_FILE = "manifest.json"
+	BACKUP_TORRENTS_DIR  = "torrents"
+)
+
+// Client independent preferences that are saved in backup.
+var BackupPreferences = []string{"global_download_speed_limit", "global_upload_speed_limit", "save_path"}
+
+// The manifest of a client backup archive, saved as "manifest.json" file in archive.
+// The .torrent files are saved as "torrents/<infoHash>.torrent" files in archive.
+type BackupManifest struct {
+	Version     int64              `json:"version"`
+	Client      string             `json:"client"`
+	ClientType  string             `json:"client_type"`
+	Time        int64              `json:"time"`
+	Preferences map[string]string  `json:"preferences"`
+	Categories  []*TorrentCategory `json:"categories"`
+	Tags        []string           `json:"tags"`
+	Torrents    []*BackupTorrent   `json:"torrents"`
+}
+
+type BackupTorrent struct {
+	InfoHash           string           `json:"info_hash"`
+	Name               string           `json:"name"`
+	State              string           `json:"state"`
+	SavePath           string           `json:"save_path"`
+	Category           string           `json:"category"`
+	Tags               []string         `json:"tags"`
+	Meta               map[string]int64 `json:"meta"`
+	DownloadSpeedLimit int64            `json:"download_speed_limit"`
+	UploadSpeedLimit   int64            `json:"upload_speed_limit"`
+	SequentialDownload bool             `json:"sequential_download"`
+	Trackers           []string         `json:"trackers"`
+}
+
+// Write client backup archive to filename. The compression format is determined by file extension:
+// ".zst" : zstd, ".gz" / ".tgz" : gzip, otherwise no compression (plain tar).
+// torrentContents: infoHash => .torrent file contents.
+func WriteBackup(filename string, manifest *BackupManifest, torrentContents map[string][]byte) (err error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.PERM)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	var writer io.Writer = file
+	var compressor io.WriteCloser
+	switch {
+	case strings.HasSuffix(filename, ".zst"):
+		if compressor, err = zstd.NewWriter(file); err != nil {
+			return err
+		}
+	case strings.HasSuffix(filename, ".gz") || strings.HasSuffix(filename, ".tgz"):
+		compressor = gzip.NewWriter(file)
+	}
+	if compressor != nil {
+		writer = compressor
+	}
+	tarWriter := tar.NewWriter(writer)
+	modTime := time.Unix(manifest.Time, 0)
+	writeEntry := func(name string, data []byte) error {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    constants.PERM,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		_, err := tarWriter.Write(data)
+		return err
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err = writeEntry(BACKUP_MANIFEST_FILE, manifestData); err != nil {
+		return err
+	}
+	infoHashes := []string{}
+	for infoHash := range torrentContents {
+		infoHashes = append(infoHashes, infoHash)
+	}
+	sort.Strings(infoHashes)
+	for _, infoHash := range infoHashes {
+		if err = writeEntry(path.Join(BACKUP_TORRENTS_DIR, infoHash+".torrent"), torrentContents[infoHash]); err != nil {
+			return err
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		return compressor.Close()
+	}
+	return nil
+}
+
+// Read client backup archive file written by WriteBackup.
+func ReadBackup(filename string) (manifest *BackupManifest, torrentContents map[string][]byte, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	var reader io.Reader
+	switch {
+	case strings.HasSuffix(filename, ".zst"):
+		zstdReader, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	case strings.HasSuffix(filename, ".gz") || strings.HasSuffix(filename, ".tgz"):
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	default:
+		reader = file
+	}
+	tarReader := tar.NewReader(reader)
+	torrentContents = map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s in archive: %w", header.Name, err)
+		}
+		if header.Name == BACKUP_MANIFEST_FILE {
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+		} else if dir, name := path.Split(header.Name); dir == BACKUP_TORRENTS_DIR+"/" &&
+			strings.HasSuffix(name, ".torrent") {
+			torrentContents[strings.TrimSuffix(name, ".torrent")] = data
+		}
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("invalid backup: %s not found in archive", BACKUP_MANIFEST_FILE)
+	}
+	if manifest.Version > BACKUP_VERSION {
+		return nil, nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	return manifest, torrentContents, nil
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"regexp"
 	"slices"
@@ -20,7 +21,8 @@ import (
 
 // @todo: considering changing it to interface
 type Torrent struct {
-	InfoHash           string
+	InfoHash           string // v1 info hash, or the truncated (first 20 bytes) v2 info hash of v2 only torrent
+	InfoHashV2         string // BitTorrent v2 info hash. Empty if it's a v1 only torrent or client does not support v2
 	Name               string
 	TrackerDomain      string // e.g. tracker.m-team.cc
 	TrackerBaseDomain  string // e.g. m-team.cc
@@ -46,6 +48,10 @@ type Torrent struct {
 	Seeders            int64 // Cnt of seeders (including self client, if it's seeding), returned by tracker
 	Leechers           int64
 	Meta               map[string]int64
+	SequentialDownload bool // qb only
+	FirstLastPiecePrio bool // qb only. First and last pieces of each file are prioritized
+	// Position in client's download / seed queue, 1 is the top. 0 if not queued (e.g. queueing is disabled in qb)
+	QueuePosition int64
 }
 
 type TorrentContentFile struct {
@@ -55,8 +61,27 @@ type TorrentContentFile struct {
 	Progress float64 // [0, 1]
 	Ignored  bool    // true if file is ignored (excluded from downloading)
 	Complete bool    // true if file is fullly downloaded
+	// Percentage of file pieces currently available in swarm, [0, 1]. qb only, -1 means unknown
+	Availability float64
 }
 
+// Queue move actions of Client.MoveTorrentsInQueue
+const (
+	QUEUE_TOP    = "top"
+	QUEUE_BOTTOM = "bottom"
+	QUEUE_UP     = "up"
+	QUEUE_DOWN   = "down"
+)
+
+var QueueActions = []string{QUEUE_TOP, QUEUE_BOTTOM, QUEUE_UP, QUEUE_DOWN}
+
+// Piece states, returned by Client.GetTorrentPieceStates
+const (
+	PIECE_NOT_DOWNLOADED = 0
+	PIECE_DOWNLOADING    = 1
+	PIECE_DOWNLOADED     = 2
+)
+
 type Status struct {
 	FreeSpaceOnDisk           int64 // -1 means unknown
 	UnfinishedSize            int64
@@ -69,10 +94,34 @@ type Status struct {
 	NoDel                     bool  // if true, brush and other tasks will NOT delete any torrent from client
 }
 
+// The alternative global speed limits of client ("Alternative Speed Limits" of qBittorrent,
+// "Turtle Mode" of Transmission), which can be enabled to temporarily replace the normal global speed limits.
+type AltSpeed struct {
+	Enabled            bool
+	DownloadSpeedLimit int64 // bytes/s. <= 0 means no limit
+	UploadSpeedLimit   int64 // bytes/s. <= 0 means no limit
+}
+
 type TorrentTracker struct {
-	Status string //working|notcontacted|error|updating|disabled|unknown
-	Url    string
-	Msg    string
+	Status       string //working|notcontacted|error|updating|disabled|unknown
+	Url          string
+	Msg          string
+	Tier         int64
+	Seeders      int64 // as reported by the tracker. -1 means unknown
+	Leechers     int64 // as reported by the tracker. -1 means unknown
+	NextAnnounce int64 // timestamp of next announce. 0 means unknown
+}
+
+type TorrentPeer struct {
+	Address       string // ip:port
+	Client        string
+	Country       string // country code, if available
+	Progress      float64
+	DownloadSpeed int64  // download speed from peer
+	UploadSpeed   int64  // upload speed to peer
+	Downloaded    int64  // downloaded from peer. -1 means unknown
+	Uploaded      int64  // uploaded to peer. -1 means unknown
+	Flags         string // client specific flags of peer connection. E.g. qb "D X E P"
 }
 
 type TorrentTrackers []TorrentTracker
@@ -136,6 +185,9 @@ type Client interface {
 	SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error
 	TorrentRootPathExists(rootFolder string) bool
 	GetTorrentContents(infoHash string) ([]*TorrentContentFile, error)
+	// Return state (PIECE_* constants) of each piece of torrent.
+	// Transmission does NOT report downloading pieces, so it's either downloaded or not.
+	GetTorrentPieceStates(infoHash string) ([]int64, error)
 	PurgeCache()
 	GetStatus() (*Status, error)
 	GetName() string
@@ -143,11 +195,28 @@ type Client interface {
 	SetConfig(variable string, value string) error
 	GetConfig(variable string) (string, error)
 	GetTorrentTrackers(infoHash string) (TorrentTrackers, error)
+	GetTorrentPeers(infoHash string) ([]*TorrentPeer, error)
 	EditTorrentTracker(infoHash string, oldTracker string, newTracker string, replaceHost bool) error
 	AddTorrentTrackers(infoHash string, trackers []string, oldTracker string, removeExisting bool) error
 	RemoveTorrentTrackers(infoHash string, trackers []string) error
 	// QB only, priority: 0	Do not download; 1	Normal priority; 6	High priority; 7	Maximal priority
 	SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error
+	// Rename a file or folder in torrent contents. oldPath & newPath are relative paths in torrent contents,
+	// e.g. "Root/file.mkv". Transmission can only rename the last component (basename) of a path.
+	RenameTorrentPath(infoHash string, oldPath string, newPath string, isFolder bool) error
+	// Enable or disable sequential download / first-last piece priority of torrents (for streaming).
+	// qBittorrent supports them natively; Transmission emulates them by setting file priorities.
+	SetTorrentsSequentialDownload(infoHashes []string, enabled bool) error
+	SetTorrentsFirstLastPiecePrio(infoHashes []string, enabled bool) error
+	// Move torrents in client's download / seed queue. action: QUEUE_TOP | QUEUE_BOTTOM | QUEUE_UP | QUEUE_DOWN.
+	// qBittorrent requires queueing to be enabled (clientctl "queueing_enabled" option).
+	MoveTorrentsInQueue(infoHashes []string, action string) error
+	GetAltSpeed() (*AltSpeed, error)
+	// Enable or disable the alternative speed limits of client.
+	SetAltSpeedEnabled(enabled bool) error
+	// Set the alternative speed limits (bytes/s). A negative limit is left unchanged.
+	// 0 means no limit, which is not supported by Transmission (the turtle mode speeds must be > 0).
+	SetAltSpeedLimits(downloadSpeedLimit int64, uploadSpeedLimit int64) error
 	Cached() bool
 	Close()
 }
@@ -168,6 +237,11 @@ var (
 	clients = map[string]Client{}
 )
 
+var tracker_timeout_msgs = []string{
+	"timed out",
+	"timeout",
+}
+
 var tracker_invalid_torrent_msgs = []string{
 	"not registered",
 	"not exists",
@@ -208,6 +282,14 @@ func (tracker *TorrentTracker) SeemsInvalidTorrent() bool {
 	return false
 }
 
+// Return true if the tracker is not working because the announce request timed out.
+func (tracker *TorrentTracker) SeemsTimeout() bool {
+	return tracker.Status != "working" && tracker.Msg != "" &&
+		slices.ContainsFunc(tracker_timeout_msgs, func(msg string) bool {
+			return util.ContainsI(tracker.Msg, msg)
+		})
+}
+
 func (cs *Status) Print(f io.Writer, name string, additionalInfo string) {
 	info := fmt.Sprintf("FreeSpace: %s; Unfinished(All/DL): %s/%s",
 		util.BytesSizeAround(float64(cs.FreeSpaceOnDisk)),
@@ -257,17 +339,30 @@ func CreateClient(name string) (Client, error) {
 	}
 	clientConfig := config.GetClientConfig(name)
 	if clientConfig == nil {
-		return nil, fmt.Errorf("client %s not existed", name)
+		return nil, fmt.Errorf("client %s %w", name, constants.ErrNotFound)
 	}
 	regInfo, err := Find(clientConfig.Type)
 	if err != nil {
 		return nil, fmt.Errorf("unsupported client type %s", clientConfig.Type)
 	}
 	clientInstance, err := regInfo.Creator(name, clientConfig, config.Get())
-	if err == nil {
-		clients[name] = clientInstance
+	if err != nil {
+		return nil, err
 	}
-	return clientInstance, err
+	clientInstance = &auditedClient{Client: clientInstance}
+	clients[name] = clientInstance
+	return clientInstance, nil
+}
+
+// Return the http transport used to access client, which uses the effective proxy of client, following the orders:
+// --proxy flag, client proxy, global clientProxy, HTTP_PROXY & HTTPS_PROXY envs.
+func NewHttpTransport(clientConfig *config.ClientConfigStruct, globalConfig *config.ConfigStruct) (
+	*http.Transport, error) {
+	transport, err := util.NewProxyTransport(config.GetProxy(clientConfig.Proxy, globalConfig.ClientProxy))
+	if err != nil {
+		return nil, fmt.Errorf("client %s: %w", clientConfig.Name, err)
+	}
+	return transport, nil
 }
 
 func GenerateNameWithMeta(name string, meta map[string]int64) string {
@@ -311,6 +406,16 @@ func ParseMetaFromName(fullname string) (name string, meta map[string]int64) {
 	return
 }
 
+// Return true if infoHash, which could be a v1 or v2 info hash, is the info hash of torrent.
+func (torrent *Torrent) MatchInfoHash(infoHash string) bool {
+	if len(infoHash) == 64 {
+		// clients identify v2 only torrent by the truncated v2 info hash
+		return strings.EqualFold(infoHash, torrent.InfoHashV2) ||
+			torrent.InfoHashV2 == "" && strings.EqualFold(infoHash[:40], torrent.InfoHash)
+	}
+	return strings.EqualFold(infoHash, torrent.InfoHash)
+}
+
 func (torrent *Torrent) MatchFilter(filter string) bool {
 	if filter == "" || util.ContainsI(torrent.Name, filter) {
 		return true
@@ -336,6 +441,16 @@ func (torrent *Torrent) MatchTracker(tracker string) bool {
 	return torrent.TrackerDomain == tracker
 }
 
+// Return true if torrent tracker matches tracker, which is an url, or a domain that also matches it's sub domains,
+// e.g. "hdsky.me" or "*.hdsky.me" matches "pt.hdsky.me".
+func (torrent *Torrent) MatchTrackerOrSubdomain(tracker string) bool {
+	if util.IsUrl(tracker) {
+		return torrent.Tracker == tracker
+	}
+	domain := strings.TrimPrefix(tracker, "*.")
+	return torrent.TrackerDomain == domain || strings.HasSuffix(torrent.TrackerDomain, "."+domain)
+}
+
 func (torrent *Torrent) StateIconText() string {
 	s := ""
 	showProcess := false
@@ -425,6 +540,18 @@ func (torrent *Torrent) IsFull() bool {
 	return torrent.Size == torrent.SizeTotal
 }
 
+// Return share ratio (uploaded / downloaded) of torrent.
+// If torrent has not downloaded anything (e.g. xseed torrent), the size is used as the downloaded amount.
+func (torrent *Torrent) Ratio() float64 {
+	if torrent.Downloaded > 0 {
+		return float64(torrent.Uploaded) / float64(torrent.Downloaded)
+	}
+	if torrent.Size > 0 {
+		return float64(torrent.Uploaded) / float64(torrent.Size)
+	}
+	return 0
+}
+
 func (torrent *Torrent) HasTag(tag string) bool {
 	return slices.ContainsFunc(torrent.Tags, func(t string) bool {
 		return strings.EqualFold(tag, t)
@@ -514,6 +641,9 @@ func (torrent *Torrent) Print() {
 	}
 	fmt.Printf("Torrent name: %s\n", torrent.Name)
 	fmt.Printf("- InfoHash: %s\n", torrent.InfoHash)
+	if torrent.InfoHashV2 != "" {
+		fmt.Printf("- InfoHashV2: %s\n", torrent.InfoHashV2)
+	}
 	fmt.Printf("- Size: %s (%d)", util.BytesSize(float64(torrent.Size)), torrent.Size)
 	if torrent.Size != torrent.SizeTotal {
 		fmt.Printf(" (partial)")
@@ -544,22 +674,42 @@ func (torrent *Torrent) Print() {
 
 // showSum: 0 - no; 1 - yes; 2 - sum only
 func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum int64, dense bool) {
+	PrintTorrentsInColumns(output, torrents, filter, showSum, dense, DefaultTorrentColumns, "")
+}
+
+// Print torrents in table of columns (names of TorrentColumns).
+// layout: "" (default) - fit in terminal width (at least config.CLIENT_TORRENTS_WIDTH);
+// TORRENTS_LAYOUT_NARROW - fit in terminal width; TORRENTS_LAYOUT_WIDE - unlimited width.
+func PrintTorrentsInColumns(output io.Writer, torrents []*Torrent, filter string, showSum int64, dense bool,
+	columns []string, layout string) {
 	width, _, _ := term.GetSize(int(os.Stdout.Fd()))
-	if width < config.CLIENT_TORRENTS_WIDTH {
-		width = config.CLIENT_TORRENTS_WIDTH
+	switch layout {
+	case TORRENTS_LAYOUT_WIDE:
+		width = 0
+	case TORRENTS_LAYOUT_NARROW:
+		if width <= 0 {
+			width = config.CLIENT_TORRENTS_WIDTH
+		}
+	default:
+		if width < config.CLIENT_TORRENTS_WIDTH {
+			width = config.CLIENT_TORRENTS_WIDTH
+		}
+	}
+	var tableColumns []*util.TableColumn
+	var torrentColumns []*TorrentColumn
+	for _, name := range columns {
+		if index := slices.IndexFunc(TorrentColumns, func(c *TorrentColumn) bool { return c.Name == name }); index != -1 {
+			tableColumns = append(tableColumns, TorrentColumns[index].Column)
+			torrentColumns = append(torrentColumns, TorrentColumns[index])
+		}
 	}
-	widthExcludingName := 105 // 40+6+5+6+6+5+5+16+8*2
-	widthName := width - widthExcludingName
+	var rows [][]string
 	cnt := int64(0)
 	var cntPaused, cntDownloading, cntSeeding, cntCompleted, cntOthers int64
 	size := int64(0)
 	smallestSize := int64(-1)
 	largestSize := int64(-1)
 	sizeUnfinished := int64(0)
-	if showSum < 2 {
-		fmt.Fprintf(output, "%-*s  %-40s  %-6s  %-5s  %-6s  %-6s  %-5s  %-5s  %-16s\n",
-			widthName, "Name", "InfoHash", "Size", "State", "↓S(/s)", "↑S(/s)", "Seeds", "Peers", "Tracker")
-	}
 	for _, torrent := range torrents {
 		if filter != "" && !torrent.MatchFilter(filter) {
 			continue
@@ -588,44 +738,12 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 		if showSum >= 2 {
 			continue
 		}
-		name := torrent.Name
-		if dense && (torrent.Category != "" || len(torrent.Tags) > 0 || torrent.ContentPath != "") {
-			name += " //"
-			if torrent.Category != "" {
-				name += " " + strconv.Quote(torrent.Category)
-			}
-			if len(torrent.Tags) > 0 {
-				name += fmt.Sprintf(" [%s]", strings.Join(util.Map(torrent.Tags, func(t string) string {
-					return fmt.Sprintf("%q", t)
-				}), ", "))
-			}
-			if torrent.ContentPath != "" {
-				name += ` >` + torrent.ContentPath
-			}
-		}
-		remain := util.PrintStringInWidth(output, name, int64(widthName), true)
-		// 目前遇到的tracker域名最长的: "wintersakura.net"
-		trackerBaseDomain, _ := util.StringPrefixInWidth(torrent.TrackerBaseDomain, 16)
-		fmt.Fprintf(output, "  %-40s  %-6s  %-5s  %-6s  %-6s  %-5d  %-5d  %-16s\n",
-			torrent.InfoHash,
-			util.BytesSizeAround(float64(torrent.Size)),
-			torrent.StateIconText(),
-			util.BytesSizeAround(float64(torrent.DownloadSpeed)),
-			util.BytesSizeAround(float64(torrent.UploadSpeed)),
-			torrent.Seeders,
-			torrent.Leechers,
-			trackerBaseDomain,
-		)
-		if dense {
-			for {
-				remain = strings.TrimSpace(remain)
-				if remain == "" {
-					break
-				}
-				remain = util.PrintStringInWidth(output, remain, int64(widthName), true)
-				fmt.Fprintf(output, "\n")
-			}
-		}
+		rows = append(rows, util.Map(torrentColumns, func(c *TorrentColumn) string {
+			return c.Value(torrent, dense)
+		}))
+	}
+	if showSum < 2 {
+		util.PrintTable(output, tableColumns, rows, int64(width), dense, false)
 	}
 	if showSum > 0 {
 		averageSize := int64(0)
@@ -740,7 +858,7 @@ func QueryTorrents(clientInstance Client, category string, tag string, filter st
 					if torrent.MatchStateFilter(arg) {
 						torrents2 = append(torrents2, torrent)
 					}
-				} else if arg == torrent.InfoHash {
+				} else if torrent.MatchInfoHash(arg) {
 					torrents2 = append(torrents2, torrent)
 				}
 			}
@@ -750,7 +868,8 @@ func QueryTorrents(clientInstance Client, category string, tag string, filter st
 }
 
 // Query torrents that meet criterion and return infoHashes. Specially, return nil slice if all torrents selected.
-// If all hashOrStateFilters is plain info-hash and all other conditions empty, just return hashOrStateFilters,nil.
+// If all hashOrStateFilters is plain v1 info-hash and all other conditions empty, just return hashOrStateFilters,nil.
+// The v2 info-hashes are resolved to the info-hashes (torrent ids) used by client.
 // tag: comma-separated list, a torrent matches if it has any tag that in the list;
 // specially, "none" means untagged torrents.
 func SelectTorrents(clientInstance Client, category string, tag string, filter string,
@@ -759,7 +878,10 @@ func SelectTorrents(clientInstance Client, category string, tag string, filter s
 	isAll := len(hashOrStateFilters) == 0
 	isPlainInfoHashes := true
 	for _, arg := range hashOrStateFilters {
-		if IsValidInfoHash(arg) {
+		if infoHashV1Regex.MatchString(arg) {
+			continue
+		} else if infoHashV2Regex.MatchString(arg) {
+			isPlainInfoHashes = false
 			continue
 		}
 		if !IsValidStateFilter(arg) {
@@ -804,7 +926,7 @@ func SelectTorrents(clientInstance Client, category string, tag string, filter s
 					if torrent.MatchStateFilter(arg) {
 						infoHashes = append(infoHashes, torrent.InfoHash)
 					}
-				} else if arg == torrent.InfoHash {
+				} else if torrent.MatchInfoHash(arg) {
 					infoHashes = append(infoHashes, torrent.InfoHash)
 				}
 			}
@@ -854,7 +976,17 @@ func IsValidInfoHashOrStateFilter(stateFilter string) bool {
 	return IsValidInfoHash(stateFilter) || IsValidStateFilter(stateFilter)
 }
 
+// Parse the value of "listen_port" client config, which must be an integer of 1-65535.
+func ParseListenPort(value string) (int64, error) {
+	port, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid listen port %q: must be an integer of 1-65535", value)
+	}
+	return port, nil
+}
+
 func init() {
+	config.OnReload(Reset)
 }
 
 // called by main codes on program exit. clean resources
@@ -872,6 +1004,12 @@ func Exit() {
 	resourcesWaitGroup.Wait()
 }
 
+// Close and remove all created client instances, so that they will be re-created using current config.
+func Reset() {
+	Exit()
+	clients = map[string]Client{}
+}
+
 // Purge client cache
 func Purge(clientName string) {
 	if clientName == "" {
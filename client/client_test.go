@@ -0,0 +1,40 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+)
+
+func TestParseListenPort(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1", 1, false},
+		{"51413", 51413, false},
+		{" 6881 ", 6881, false},
+		{"65535", 65535, false},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"65536", 0, true},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"80.5", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			port, err := client.ParseListenPort(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %d", port)
+				}
+				return
+			}
+			if err != nil || port != test.expected {
+				t.Errorf("expected %d, got %d (err=%v)", test.expected, port, err)
+			}
+		})
+	}
+}
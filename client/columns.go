@@ -0,0 +1,133 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Layout of client torrents list. See PrintTorrentsInColumns.
+const (
+	TORRENTS_LAYOUT_NARROW = "narrow" // less columns, fit in small terminal width
+	TORRENTS_LAYOUT_WIDE   = "wide"   // more columns, do not truncate the name
+)
+
+type TorrentColumn struct {
+	Name   string
+	Column *util.TableColumn
+	Value  func(torrent *Torrent, dense bool) string
+}
+
+var TorrentColumns = []*TorrentColumn{
+	{"name", &util.TableColumn{Title: "Name", MinWidth: 15}, func(torrent *Torrent, dense bool) string {
+		name := torrent.Name
+		if dense && (torrent.Category != "" || len(torrent.Tags) > 0 || torrent.ContentPath != "") {
+			name += " //"
+			if torrent.Category != "" {
+				name += " " + strconv.Quote(torrent.Category)
+			}
+			if len(torrent.Tags) > 0 {
+				name += fmt.Sprintf(" [%s]", strings.Join(util.Map(torrent.Tags, func(t string) string {
+					return fmt.Sprintf("%q", t)
+				}), ", "))
+			}
+			if torrent.ContentPath != "" {
+				name += ` >` + torrent.ContentPath
+			}
+		}
+		return name
+	}},
+	{"infohash", &util.TableColumn{Title: "InfoHash", Width: 40}, func(torrent *Torrent, dense bool) string {
+		return torrent.InfoHash
+	}},
+	{"size", &util.TableColumn{Title: "Size", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return util.BytesSizeAround(float64(torrent.Size))
+	}},
+	{"state", &util.TableColumn{Title: "State", Width: 5}, func(torrent *Torrent, dense bool) string {
+		return torrent.StateIconText()
+	}},
+	{"dlspeed", &util.TableColumn{Title: "↓S(/s)", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return util.BytesSizeAround(float64(torrent.DownloadSpeed))
+	}},
+	{"upspeed", &util.TableColumn{Title: "↑S(/s)", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return util.BytesSizeAround(float64(torrent.UploadSpeed))
+	}},
+	{"seeds", &util.TableColumn{Title: "Seeds", Width: 5}, func(torrent *Torrent, dense bool) string {
+		return fmt.Sprint(torrent.Seeders)
+	}},
+	{"peers", &util.TableColumn{Title: "Peers", Width: 5}, func(torrent *Torrent, dense bool) string {
+		return fmt.Sprint(torrent.Leechers)
+	}},
+	// 目前遇到的tracker域名最长的: "wintersakura.net"
+	{"tracker", &util.TableColumn{Title: "Tracker", Width: 16}, func(torrent *Torrent, dense bool) string {
+		return torrent.TrackerBaseDomain
+	}},
+	{"ratio", &util.TableColumn{Title: "Ratio", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return fmt.Sprintf("%.2f", torrent.Ratio())
+	}},
+	{"uploaded", &util.TableColumn{Title: "Up", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return util.BytesSizeAround(float64(torrent.Uploaded))
+	}},
+	{"downloaded", &util.TableColumn{Title: "Down", Width: 6}, func(torrent *Torrent, dense bool) string {
+		return util.BytesSizeAround(float64(torrent.Downloaded))
+	}},
+	{"category", &util.TableColumn{Title: "Category", Width: 12}, func(torrent *Torrent, dense bool) string {
+		return torrent.Category
+	}},
+	{"tags", &util.TableColumn{Title: "Tags", Width: 20}, func(torrent *Torrent, dense bool) string {
+		return strings.Join(torrent.Tags, ",")
+	}},
+	{"added", &util.TableColumn{Title: "Added", Width: 19}, func(torrent *Torrent, dense bool) string {
+		return util.FormatTime(torrent.Atime)
+	}},
+	{"completed", &util.TableColumn{Title: "Completed", Width: 19}, func(torrent *Torrent, dense bool) string {
+		if torrent.Ctime <= 0 {
+			return "-"
+		}
+		return util.FormatTime(torrent.Ctime)
+	}},
+	{"activity", &util.TableColumn{Title: "Activity", Width: 19}, func(torrent *Torrent, dense bool) string {
+		return util.FormatTime(torrent.ActivityTime)
+	}},
+	{"savepath", &util.TableColumn{Title: "SavePath", MinWidth: 15}, func(torrent *Torrent, dense bool) string {
+		return torrent.SavePath
+	}},
+	{"queue", &util.TableColumn{Title: "Queue", Width: 5}, func(torrent *Torrent, dense bool) string {
+		if torrent.QueuePosition <= 0 {
+			return "-"
+		}
+		return fmt.Sprint(torrent.QueuePosition)
+	}},
+}
+
+var (
+	DefaultTorrentColumns = []string{"name", "infohash", "size", "state", "dlspeed", "upspeed", "seeds", "peers",
+		"tracker"}
+	NarrowTorrentColumns = []string{"name", "size", "state", "tracker"}
+	WideTorrentColumns   = []string{"name", "infohash", "size", "state", "dlspeed", "upspeed", "seeds", "peers",
+		"tracker", "ratio", "category", "added"}
+)
+
+// Parse comma-separated column names. If columns is empty, return the default columns of layout.
+func ParseTorrentColumns(columns string, layout string) ([]string, error) {
+	if columns == "" {
+		switch layout {
+		case TORRENTS_LAYOUT_NARROW:
+			return NarrowTorrentColumns, nil
+		case TORRENTS_LAYOUT_WIDE:
+			return WideTorrentColumns, nil
+		}
+		return DefaultTorrentColumns, nil
+	}
+	names := util.SplitCsv(columns)
+	for _, name := range names {
+		if !slices.ContainsFunc(TorrentColumns, func(c *TorrentColumn) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("invalid column %q. available columns: %s", name,
+				strings.Join(util.Map(TorrentColumns, func(c *TorrentColumn) string { return c.Name }), ","))
+		}
+	}
+	return names, nil
+}
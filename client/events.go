@@ -0,0 +1,144 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Torrent events of client, detected by comparing successive snapshots of client torrents.
+// For qBittorrent, each poll only fetches the changes since last poll (sync/maindata delta).
+const (
+	EVENT_ADDED         = "added"
+	EVENT_COMPLETED     = "completed"
+	EVENT_STALLED       = "stalled"      // downloading but no data received for EVENT_STALLED_TIME
+	EVENT_ERRORED       = "errored"      // torrent state became "error", e.g. missing files
+	EVENT_TRACKER_ERROR = "trackererror" // torrent lost it's working tracker. qb only
+	EVENT_REMOVED       = "removed"
+)
+
+// Seconds without download activity before a downloading torrent is considered stalled.
+const EVENT_STALLED_TIME = 300
+
+var Events = []string{
+	EVENT_ADDED, EVENT_COMPLETED, EVENT_STALLED, EVENT_ERRORED, EVENT_TRACKER_ERROR, EVENT_REMOVED,
+}
+
+type TorrentEvent struct {
+	Time     int64    `json:"time"`
+	Event    string   `json:"event"`
+	Client   string   `json:"client"`
+	InfoHash string   `json:"infoHash"`
+	Name     string   `json:"name"`
+	State    string   `json:"state"`
+	Message  string   `json:"message,omitempty"` // e.g. tracker message of EVENT_TRACKER_ERROR
+	Torrent  *Torrent `json:"-"`                 // the last known torrent info for EVENT_REMOVED
+}
+
+type torrentSnapshot struct {
+	torrent  *Torrent
+	complete bool
+	stalled  bool
+	errored  bool
+	tracker  bool // has working tracker
+}
+
+// Watch a client for torrent events. It's the event source of "events" and "watch" commands.
+type EventWatcher struct {
+	client    Client
+	snapshots map[string]*torrentSnapshot // nil: not polled yet
+}
+
+func NewEventWatcher(clientInstance Client) *EventWatcher {
+	return &EventWatcher{client: clientInstance}
+}
+
+// Fetch current client torrents and return the events since last poll.
+// The first poll only takes the initial snapshot and returns no event.
+func (watcher *EventWatcher) Poll() ([]*TorrentEvent, error) {
+	watcher.client.PurgeCache()
+	torrents, err := watcher.client.GetTorrents("", "", true)
+	if err != nil {
+		return nil, err
+	}
+	now := util.Now()
+	first := watcher.snapshots == nil
+	snapshots := map[string]*torrentSnapshot{}
+	var events []*TorrentEvent
+	addEvent := func(event string, torrent *Torrent, message string) {
+		events = append(events, &TorrentEvent{
+			Time:     now,
+			Event:    event,
+			Client:   watcher.client.GetName(),
+			InfoHash: torrent.InfoHash,
+			Name:     torrent.Name,
+			State:    torrent.State,
+			Message:  message,
+			Torrent:  torrent,
+		})
+	}
+	for _, torrent := range torrents {
+		snapshot := &torrentSnapshot{
+			torrent:  torrent,
+			complete: torrent.IsComplete(),
+			stalled:  isStalled(torrent, now),
+			errored:  torrent.State == "error",
+			tracker:  torrent.Tracker != "",
+		}
+		snapshots[torrent.InfoHash] = snapshot
+		if first {
+			continue
+		}
+		previous := watcher.snapshots[torrent.InfoHash]
+		if previous == nil {
+			addEvent(EVENT_ADDED, torrent, "")
+			previous = &torrentSnapshot{}
+		}
+		if snapshot.complete && !previous.complete {
+			addEvent(EVENT_COMPLETED, torrent, "")
+		}
+		if snapshot.stalled && !previous.stalled {
+			addEvent(EVENT_STALLED, torrent, "")
+		}
+		if snapshot.errored && !previous.errored {
+			addEvent(EVENT_ERRORED, torrent, torrent.LowLevelState)
+		}
+		// qb returns empty tracker if none of the trackers is working. Other clients always return a tracker
+		if !snapshot.tracker && previous.tracker && torrent.State != "paused" && torrent.State != "completed" &&
+			torrent.State != "checking" {
+			addEvent(EVENT_TRACKER_ERROR, torrent, watcher.trackerMessage(torrent.InfoHash))
+		}
+	}
+	if !first {
+		for infoHash, previous := range watcher.snapshots {
+			if snapshots[infoHash] == nil {
+				addEvent(EVENT_REMOVED, previous.torrent, "")
+			}
+		}
+	}
+	watcher.snapshots = snapshots
+	return events, nil
+}
+
+// Return the messages of not working trackers of torrent.
+func (watcher *EventWatcher) trackerMessage(infoHash string) string {
+	trackers, err := watcher.client.GetTorrentTrackers(infoHash)
+	if err != nil {
+		return ""
+	}
+	var msgs []string
+	for _, tracker := range trackers {
+		if tracker.Status != "working" && tracker.Msg != "" {
+			msgs = append(msgs, tracker.Msg)
+		}
+	}
+	return strings.Join(util.UniqueSlice(msgs), "; ")
+}
+
+func isStalled(torrent *Torrent, now int64) bool {
+	if torrent.State != "downloading" || torrent.IsComplete() || torrent.DownloadSpeed > 0 {
+		return false
+	}
+	activityTime := max(torrent.ActivityTime, torrent.Atime)
+	return now-activityTime >= EVENT_STALLED_TIME
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/sagan/ptool/util/filterexpr"
+)
+
+// Available fields of client torrent in filter expression.
+var TorrentExprSchema = filterexpr.Schema{
+	"name":         filterexpr.String,
+	"hash":         filterexpr.String,
+	"category":     filterexpr.String,
+	"tags":         filterexpr.StringList,
+	"tracker":      filterexpr.String,
+	"state":        filterexpr.String,
+	"save_path":    filterexpr.String,
+	"content_path": filterexpr.String,
+	"size":         filterexpr.Size,
+	"size_total":   filterexpr.Size,
+	"downloaded":   filterexpr.Size,
+	"uploaded":     filterexpr.Size,
+	"dlspeed":      filterexpr.Size,
+	"upspeed":      filterexpr.Size,
+	"ratio":        filterexpr.Number,
+	"progress":     filterexpr.Number,
+	"seeders":      filterexpr.Number,
+	"leechers":     filterexpr.Number,
+	"added":        filterexpr.Time,
+	"completed":    filterexpr.Time,
+	"activity":     filterexpr.Time,
+	"complete":     filterexpr.Bool,
+	"partial":      filterexpr.Bool,
+}
+
+// Advanced torrent filter. A torrent matches if it meets all the (set) conditions.
+// For numeric size / ratio limits, negative value means no limit;
+// For time conditions, 0 means no limit.
+type TorrentFilter struct {
+	Tracker     string         // tracker url or domain. See Torrent.MatchTracker
+	States      []string       // state filters (e.g. "_seeding", "_done"), matches if torrent is in any state
+	MinRatio    float64        // ratio >= this
+	MaxRatio    float64        // ratio <= this
+	MinSize     int64          // size >= this
+	MaxSize     int64          // size <= this
+	NameRegex   *regexp.Regexp // torrent name matches this regexp
+	AddedAfter  int64          // added time >= this
+	AddedBefore int64          // added time < this
+	Expr        *filterexpr.Expr
+}
+
+// Return a TorrentFilter that does NOT have any condition.
+func NewTorrentFilter() *TorrentFilter {
+	return &TorrentFilter{
+		MinRatio: -1,
+		MaxRatio: -1,
+		MinSize:  -1,
+		MaxSize:  -1,
+	}
+}
+
+func (tf *TorrentFilter) IsEmpty() bool {
+	return tf == nil || tf.Tracker == "" && len(tf.States) == 0 && tf.MinRatio < 0 && tf.MaxRatio < 0 &&
+		tf.MinSize < 0 && tf.MaxSize < 0 && tf.NameRegex == nil && tf.AddedAfter <= 0 && tf.AddedBefore <= 0 &&
+		tf.Expr == nil
+}
+
+func (tf *TorrentFilter) Match(torrent *Torrent) bool {
+	if tf == nil {
+		return true
+	}
+	if tf.Tracker != "" && !torrent.MatchTracker(tf.Tracker) ||
+		len(tf.States) > 0 && !slices.ContainsFunc(tf.States, torrent.MatchStateFilter) ||
+		tf.MinRatio >= 0 && torrent.Ratio() < tf.MinRatio ||
+		tf.MaxRatio >= 0 && torrent.Ratio() > tf.MaxRatio ||
+		tf.MinSize >= 0 && torrent.Size < tf.MinSize ||
+		tf.MaxSize >= 0 && torrent.Size > tf.MaxSize ||
+		tf.NameRegex != nil && !tf.NameRegex.MatchString(torrent.Name) ||
+		tf.AddedAfter > 0 && torrent.Atime < tf.AddedAfter ||
+		tf.AddedBefore > 0 && torrent.Atime >= tf.AddedBefore ||
+		tf.Expr != nil && !torrent.MatchExpr(tf.Expr) {
+		return false
+	}
+	return true
+}
+
+// Compile a torrent filter expression. See TorrentExprSchema for available fields.
+func ParseTorrentExpr(src string) (*filterexpr.Expr, error) {
+	return filterexpr.Compile(src, TorrentExprSchema)
+}
+
+func (torrent *Torrent) MatchExpr(expr *filterexpr.Expr) bool {
+	return expr.Match(torrent.ExprField)
+}
+
+// Return the value of torrent field in filter expression.
+func (torrent *Torrent) ExprField(name string) any {
+	switch name {
+	case "name":
+		return torrent.Name
+	case "hash":
+		return torrent.InfoHash
+	case "category":
+		return torrent.Category
+	case "tags":
+		return torrent.Tags
+	case "tracker":
+		return torrent.TrackerDomain
+	case "state":
+		return torrent.State
+	case "save_path":
+		return torrent.SavePath
+	case "content_path":
+		return torrent.ContentPath
+	case "size":
+		return torrent.Size
+	case "size_total":
+		return torrent.SizeTotal
+	case "downloaded":
+		return torrent.Downloaded
+	case "uploaded":
+		return torrent.Uploaded
+	case "dlspeed":
+		return torrent.DownloadSpeed
+	case "upspeed":
+		return torrent.UploadSpeed
+	case "ratio":
+		return torrent.Ratio()
+	case "progress":
+		if torrent.Size <= 0 {
+			return float64(0)
+		}
+		return float64(torrent.SizeCompleted) / float64(torrent.Size)
+	case "seeders":
+		return torrent.Seeders
+	case "leechers":
+		return torrent.Leechers
+	case "added":
+		return torrent.Atime
+	case "completed":
+		return torrent.Ctime
+	case "activity":
+		return torrent.ActivityTime
+	case "complete":
+		return torrent.IsComplete()
+	case "partial":
+		return !torrent.IsFull()
+	}
+	return nil
+}
+
+// Similar to SelectTorrents, but also apply the advanced torrentFilter.
+// If torrentFilter is empty, it's equivalent to SelectTorrents (may return nil if all torrents selected);
+// Otherwise it always returns a non-nil slice.
+func SelectTorrentsWithFilter(clientInstance Client, category string, tag string, filter string,
+	torrentFilter *TorrentFilter, hashOrStateFilters ...string) ([]string, error) {
+	if torrentFilter.IsEmpty() {
+		return SelectTorrents(clientInstance, category, tag, filter, hashOrStateFilters...)
+	}
+	torrents, err := QueryTorrents(clientInstance, category, tag, filter, hashOrStateFilters...)
+	if err != nil {
+		return nil, err
+	}
+	infoHashes := []string{}
+	for _, torrent := range torrents {
+		if torrentFilter.Match(torrent) {
+			infoHashes = append(infoHashes, torrent.InfoHash)
+		}
+	}
+	return infoHashes, nil
+}
+
+// Return the fields of torrent (the same ones of filter expression, see TorrentExprSchema)
+// as the value passed to script functions.
+func (torrent *Torrent) ScriptValue() map[string]any {
+	value := map[string]any{}
+	for name := range TorrentExprSchema {
+		value[name] = torrent.ExprField(name)
+	}
+	return value
+}
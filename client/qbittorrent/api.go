@@ -1,6 +1,8 @@
 package qbittorrent
 
 import (
+	"encoding/json"
+
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/util"
 )
@@ -33,6 +35,25 @@ type apiTorrentContent struct {
 	Availability float64 `json:"availability"` // Percentage of file pieces currently available (percentage/100)
 }
 
+type apiTorrentPeer struct {
+	Client       string  `json:"client"`
+	Connection   string  `json:"connection"`
+	Country_code string  `json:"country_code"`
+	Dl_speed     int64   `json:"dl_speed"`
+	Downloaded   int64   `json:"downloaded"`
+	Flags        string  `json:"flags"`
+	Ip           string  `json:"ip"`
+	Port         int64   `json:"port"`
+	Progress     float64 `json:"progress"`
+	Up_speed     int64   `json:"up_speed"`
+	Uploaded     int64   `json:"uploaded"`
+}
+
+// api/v2/sync/torrentPeers
+type apiTorrentPeers struct {
+	Peers map[string]*apiTorrentPeer `json:"peers"` // "ip:port" => peer
+}
+
 type apiSyncMaindata struct {
 	Server_state *apiTransferInfo                   `json:"server_state"`
 	Tags         []string                           `json:"tags"`
@@ -40,6 +61,20 @@ type apiSyncMaindata struct {
 	Torrents     map[string]*apiTorrentInfo         `json:"torrents"`
 }
 
+// Response of api/v2/sync/maindata?rid=N. If Full_update is false, it only contains changed items
+// (and changed fields of them) since rid, so items are kept raw and merged into cached data.
+type apiSyncMaindataDiff struct {
+	Rid                int64                      `json:"rid"`
+	Full_update        bool                       `json:"full_update"`
+	Server_state       json.RawMessage            `json:"server_state"`
+	Tags               []string                   `json:"tags"`
+	Tags_removed       []string                   `json:"tags_removed"`
+	Categories         map[string]json.RawMessage `json:"categories"`
+	Categories_removed []string                   `json:"categories_removed"`
+	Torrents           map[string]json.RawMessage `json:"torrents"`
+	Torrents_removed   []string                   `json:"torrents_removed"`
+}
+
 type apiTransferInfo struct {
 	Free_space_on_disk int64  `json:"free_space_on_disk"`
 	Dl_info_speed      int64  `json:"dl_info_speed"`     //Global download rate (bytes/s)
@@ -105,6 +140,8 @@ type apiTorrentInfo struct {
 	F_l_piece_prio     bool    `json:"f_l_piece_prio"`     //	bool	True if first last piece are prioritized
 	Force_start        bool    `json:"force_start"`        //	bool	True if force start is enabled for this torrent
 	Hash               string  `json:"hash"`               //	string	Torrent hash
+	Infohash_v1        string  `json:"infohash_v1"`        //	string	Torrent SHA1 Hash (qb 4.4+). Empty if it's a v2 only torrent
+	Infohash_v2        string  `json:"infohash_v2"`        //	string	Torrent SHA256 Hash (qb 4.4+). Empty if it's a v1 only torrent
 	Last_activity      int64   `json:"last_activity"`      //	integer	Last time (Unix Epoch) when a chunk was downloaded/uploaded
 	Magnet_uri         string  `json:"magnet_uri"`         //	string	Magnet URI corresponding to this torrent
 	Max_ratio          float64 `json:"max_ratio"`          //	float	Maximum share ratio until torrent is stopped from seeding/uploading
@@ -317,6 +354,7 @@ func (qbtorrent *apiTorrentInfo) ToTorrentState() string {
 func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
 	torrent := &client.Torrent{
 		InfoHash:           qbtorrent.Hash,
+		InfoHashV2:         qbtorrent.Infohash_v2,
 		Name:               qbtorrent.Name,
 		TrackerDomain:      util.ParseUrlHostname(qbtorrent.Tracker),
 		TrackerBaseDomain:  util.GetUrlDomain(qbtorrent.Tracker),
@@ -342,6 +380,11 @@ func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
 		SizeTotal:          qbtorrent.Total_size,
 		Leechers:           qbtorrent.Num_incomplete,
 		Meta:               map[string]int64{},
+		SequentialDownload: qbtorrent.Seq_dl,
+		FirstLastPiecePrio: qbtorrent.F_l_piece_prio,
+	}
+	if qbtorrent.Priority > 0 {
+		torrent.QueuePosition = qbtorrent.Priority
 	}
 	torrent.Name, torrent.Meta = client.ParseMetaFromName(torrent.Name)
 	return torrent
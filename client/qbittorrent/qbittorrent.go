@@ -5,6 +5,7 @@ package qbittorrent
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -15,6 +16,7 @@ import (
 	"reflect"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -34,6 +36,7 @@ type Client struct {
 	preferences               *apiPreferences
 	Logined                   bool
 	datatime                  int64
+	rid                       int64 // response id of last maindata sync, for incremental sync
 	unfinishedSize            int64
 	unfinishedDownloadingSize int64
 	contentPathTorrents       map[string][]*apiTorrentInfo
@@ -77,9 +80,17 @@ func (qbclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 }
 
 func (qbclient *Client) apiPost(apiUrl string, data url.Values) error {
+	return qbclient.doApiPost(apiUrl, data, true)
+}
+
+func (qbclient *Client) doApiPost(apiUrl string, data url.Values, retry bool) error {
 	resp, err := qbclient.HttpClient.PostForm(qbclient.ClientConfig.Url+apiUrl, data)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", constants.ErrNetwork, err)
+	}
+	if retry && qbclient.relogin(apiUrl, resp.StatusCode) {
+		resp.Body.Close()
+		return qbclient.doApiPost(apiUrl, data, false)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -90,19 +101,26 @@ func (qbclient *Client) apiPost(apiUrl string, data url.Values) error {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("apiPost error: status=%d", resp.StatusCode)
+		return fmt.Errorf("apiPost error: %w", util.NewHttpStatusError(resp.StatusCode))
 	}
 	return nil
 }
 
 func (qbclient *Client) apiRequest(apiPath string, v any) error {
+	return qbclient.doApiRequest(apiPath, v, true)
+}
+
+func (qbclient *Client) doApiRequest(apiPath string, v any, retry bool) error {
 	resp, err := qbclient.HttpClient.Get(qbclient.ClientConfig.Url + apiPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", constants.ErrNetwork, err)
 	}
 	defer resp.Body.Close()
+	if retry && qbclient.relogin(apiPath, resp.StatusCode) {
+		return qbclient.doApiRequest(apiPath, v, false)
+	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("apiRequest %s response %d status", apiPath, resp.StatusCode)
+		return fmt.Errorf("apiRequest %s response error: %w", apiPath, util.NewHttpStatusError(resp.StatusCode))
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -115,6 +133,21 @@ func (qbclient *Client) apiRequest(apiPath string, v any) error {
 	}
 }
 
+// qb responds 403 if the SID cookie is invalid or expired (e.g. qb restarted or WebUI session timeout).
+// In that case it logins again and returns true, so the request can be retried.
+func (qbclient *Client) relogin(apiPath string, statusCode int) bool {
+	if statusCode != http.StatusForbidden || !qbclient.Logined || apiPath == "api/v2/auth/login" {
+		return false
+	}
+	log.Warnf("Client %s session expired, login again", qbclient.Name)
+	qbclient.Logined = false
+	if err := qbclient.login(); err != nil {
+		log.Errorf("Client %s failed to login: %v", qbclient.Name, err)
+		return false
+	}
+	return true
+}
+
 func (qbclient *Client) login() error {
 	if qbclient.Logined || qbclient.ClientConfig.QbittorrentNoLogin {
 		return nil
@@ -135,6 +168,9 @@ func (qbclient *Client) login() error {
 	err := qbclient.apiPost("api/v2/auth/login", data)
 	if err == nil {
 		qbclient.Logined = true
+	} else if !errors.Is(err, constants.ErrNetwork) && !errors.Is(err, constants.ErrAuth) {
+		// qb responds "Fails." if username or password is wrong
+		err = fmt.Errorf("%w: %w", constants.ErrAuth, err)
 	}
 	return err
 }
@@ -204,11 +240,11 @@ func (qbclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 	resp, err := qbclient.HttpClient.Post(qbclient.ClientConfig.Url+"api/v2/torrents/add",
 		mp.FormDataContentType(), body)
 	if err != nil {
-		return fmt.Errorf("add torrent error: %w", err)
+		return fmt.Errorf("add torrent error: %w: %w", constants.ErrNetwork, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("add torrent error: status=%d", resp.StatusCode)
+		return fmt.Errorf("add torrent error: %w", util.NewHttpStatusError(resp.StatusCode))
 	}
 	return err
 }
@@ -507,8 +543,12 @@ func (qbclient *Client) ModifyTorrent(infoHash string,
 		}
 	}
 
-	// @todo: apply option.SequentialDownload using qb toggleSequentialDownload.
-	// However, we must know current sequentialDownload status in ahead.
+	if option.SequentialDownload && !qbtorrent.Seq_dl {
+		err := qbclient.apiPost("api/v2/torrents/toggleSequentialDownload", url.Values{"hashes": {infoHash}})
+		if err != nil {
+			return err
+		}
+	}
 
 	if option.Category != "" {
 		category := option.Category
@@ -605,8 +645,13 @@ func (qbclient *Client) ModifyTorrent(infoHash string,
 	return nil
 }
 
+// Mark cached data as stale, so that next sync will refresh it.
+// The cached maindata is kept (unless incremental sync is disabled) and next sync only fetches the changes.
 func (qbclient *Client) PurgeCache() {
-	qbclient.data = nil
+	if qbclient.ClientConfig.NoIncrementalSync {
+		qbclient.data = nil
+		qbclient.rid = 0
+	}
 	qbclient.preferences = nil
 	qbclient.unfinishedSize = 0
 	qbclient.unfinishedDownloadingSize = 0
@@ -626,15 +671,81 @@ func (qbclient *Client) sync() error {
 	if err != nil {
 		return fmt.Errorf("login error: %w", err)
 	}
-	err = qbclient.apiRequest("api/v2/sync/maindata", &qbclient.data)
+	rid := int64(0)
+	if qbclient.data != nil {
+		rid = qbclient.rid
+	}
+	var diff *apiSyncMaindataDiff
+	err = qbclient.apiRequest(fmt.Sprintf("api/v2/sync/maindata?rid=%d", rid), &diff)
 	if err != nil {
 		return err
 	}
+	if err = qbclient.applyMaindataDiff(diff); err != nil {
+		qbclient.data = nil
+		qbclient.rid = 0
+		return fmt.Errorf("invalid maindata: %w", err)
+	}
 	qbclient.datatime = util.Now()
 	qbclient.buildDerivative()
 	return nil
 }
 
+// Merge maindata diff into cached data. For a full update, the cached data is replaced.
+func (qbclient *Client) applyMaindataDiff(diff *apiSyncMaindataDiff) error {
+	if diff == nil {
+		return fmt.Errorf("empty response")
+	}
+	if diff.Full_update || qbclient.data == nil {
+		qbclient.data = &apiSyncMaindata{
+			Server_state: &apiTransferInfo{},
+			Categories:   map[string]*client.TorrentCategory{},
+			Torrents:     map[string]*apiTorrentInfo{},
+		}
+	}
+	data := qbclient.data
+	if diff.Server_state != nil {
+		if err := json.Unmarshal(diff.Server_state, data.Server_state); err != nil {
+			return err
+		}
+	}
+	data.Tags = util.Filter(data.Tags, func(tag string) bool {
+		return !slices.Contains(diff.Tags_removed, tag)
+	})
+	for _, tag := range diff.Tags {
+		if !slices.Contains(data.Tags, tag) {
+			data.Tags = append(data.Tags, tag)
+		}
+	}
+	for _, name := range diff.Categories_removed {
+		delete(data.Categories, name)
+	}
+	for name, raw := range diff.Categories {
+		category := data.Categories[name]
+		if category == nil {
+			category = &client.TorrentCategory{}
+		}
+		if err := json.Unmarshal(raw, category); err != nil {
+			return err
+		}
+		data.Categories[name] = category
+	}
+	for _, hash := range diff.Torrents_removed {
+		delete(data.Torrents, hash)
+	}
+	for hash, raw := range diff.Torrents {
+		torrent := data.Torrents[hash]
+		if torrent == nil {
+			torrent = &apiTorrentInfo{}
+		}
+		if err := json.Unmarshal(raw, torrent); err != nil {
+			return err
+		}
+		data.Torrents[hash] = torrent
+	}
+	qbclient.rid = diff.Rid
+	return nil
+}
+
 func (qbclient *Client) buildDerivative() {
 	unfinishedSize := int64(0)
 	unfinishedDownloadingSize := int64(0)
@@ -787,6 +898,60 @@ func (qbclient *Client) GetConfig(variable string) (string, error) {
 			return "", err
 		}
 		return preferences.Save_path, nil
+	case "listen_port":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(preferences.Listen_port), nil
+	case "network_interface":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return preferences.Current_network_interface, nil
+	case "bind_address":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return preferences.Current_interface_address, nil
+	case "queueing_enabled":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(preferences.Queueing_enabled), nil
+	case "max_active_downloads":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(preferences.Max_active_downloads), nil
+	case "max_active_uploads":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(preferences.Max_active_uploads), nil
+	case "max_active_torrents":
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(preferences.Max_active_torrents), nil
+	case "alt_speed_enabled", "alt_download_speed_limit", "alt_upload_speed_limit":
+		altSpeed, err := qbclient.GetAltSpeed()
+		if err != nil {
+			return "", err
+		}
+		switch variable {
+		case "alt_download_speed_limit":
+			return fmt.Sprint(altSpeed.DownloadSpeedLimit), nil
+		case "alt_upload_speed_limit":
+			return fmt.Sprint(altSpeed.UploadSpeedLimit), nil
+		}
+		return fmt.Sprint(altSpeed.Enabled), nil
 	default:
 		return "", nil
 	}
@@ -819,10 +984,35 @@ func (qbclient *Client) SetConfig(variable string, value string) error {
 			err = qbclient.apiPost("api/v2/transfer/setUploadLimit", data)
 			return err
 		}
-	case "free_disk_space", "global_download_speed", "global_upload_speed":
+	case "free_disk_space", "global_download_speed", "global_upload_speed", "network_interface", "bind_address",
+		"listen_port_open":
 		return fmt.Errorf("%s is read-only", variable)
 	case "save_path":
 		return qbclient.setPreferences(map[string]any{"save_path": value})
+	case "listen_port":
+		port, err := client.ParseListenPort(value)
+		if err != nil {
+			return err
+		}
+		return qbclient.setPreferences(map[string]any{"listen_port": port})
+	case "queueing_enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool value %q", value)
+		}
+		return qbclient.setPreferences(map[string]any{"queueing_enabled": enabled})
+	case "max_active_downloads", "max_active_uploads", "max_active_torrents":
+		return qbclient.setPreferences(map[string]any{variable: util.ParseInt(value)})
+	case "alt_speed_enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool value %q", value)
+		}
+		return qbclient.SetAltSpeedEnabled(enabled)
+	case "alt_download_speed_limit":
+		return qbclient.SetAltSpeedLimits(util.ParseInt(value), -1)
+	case "alt_upload_speed_limit":
+		return qbclient.SetAltSpeedLimits(-1, util.ParseInt(value))
 	default:
 		return nil
 	}
@@ -847,6 +1037,14 @@ func (qbclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
 		return nil, err
 	}
 	qbtorrent := qbclient.data.Torrents[infoHash]
+	if qbtorrent == nil && len(infoHash) == 64 {
+		for _, t := range qbclient.data.Torrents {
+			if t.Infohash_v2 == infoHash {
+				qbtorrent = t
+				break
+			}
+		}
+	}
 	if qbtorrent == nil {
 		return nil, nil
 	}
@@ -896,12 +1094,13 @@ func (qbclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 	torrentContents := []*client.TorrentContentFile{}
 	for _, qbTorrentContent := range qbTorrentContents {
 		torrentContents = append(torrentContents, &client.TorrentContentFile{
-			Index:    qbTorrentContent.Index,
-			Path:     strings.ReplaceAll(qbTorrentContent.Name, `\`, "/"),
-			Size:     qbTorrentContent.Size,
-			Ignored:  qbTorrentContent.Priority == 0,
-			Complete: qbTorrentContent.Is_seed,
-			Progress: qbTorrentContent.Progress,
+			Index:        qbTorrentContent.Index,
+			Path:         strings.ReplaceAll(qbTorrentContent.Name, `\`, "/"),
+			Size:         qbTorrentContent.Size,
+			Ignored:      qbTorrentContent.Priority == 0,
+			Complete:     qbTorrentContent.Is_seed,
+			Progress:     qbTorrentContent.Progress,
+			Availability: qbTorrentContent.Availability,
 		})
 	}
 	sort.Slice(torrentContents, func(i, j int) bool {
@@ -910,6 +1109,20 @@ func (qbclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 	return torrentContents, nil
 }
 
+func (qbclient *Client) GetTorrentPieceStates(infoHash string) ([]int64, error) {
+	err := qbclient.login()
+	if err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+	apiUrl := qbclient.ClientConfig.Url + "api/v2/torrents/pieceStates?hash=" + infoHash
+	var states []int64
+	err = util.FetchJson(apiUrl, &states, qbclient.HttpClient, nil)
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
 func (qbclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
 	err := qbclient.login()
 	if err != nil {
@@ -942,14 +1155,48 @@ func (qbclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrack
 			status = "unknown"
 		}
 		return client.TorrentTracker{
-			Url:    qbtracker.Url,
-			Msg:    qbtracker.Msg,
-			Status: status,
+			Url:      qbtracker.Url,
+			Msg:      qbtracker.Msg,
+			Status:   status,
+			Tier:     qbtracker.Tier,
+			Seeders:  qbtracker.Num_seeds,
+			Leechers: qbtracker.Num_leeches,
 		}
 	})
 	return trackers, nil
 }
 
+func (qbclient *Client) GetTorrentPeers(infoHash string) ([]*client.TorrentPeer, error) {
+	err := qbclient.login()
+	if err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+	apiUrl := qbclient.ClientConfig.Url + "api/v2/sync/torrentPeers?rid=0&hash=" + infoHash
+	var data apiTorrentPeers
+	err = util.FetchJson(apiUrl, &data, qbclient.HttpClient, nil)
+	if err != nil {
+		return nil, err
+	}
+	peers := []*client.TorrentPeer{}
+	for address, qbpeer := range data.Peers {
+		peers = append(peers, &client.TorrentPeer{
+			Address:       address,
+			Client:        qbpeer.Client,
+			Country:       qbpeer.Country_code,
+			Progress:      qbpeer.Progress,
+			DownloadSpeed: qbpeer.Dl_speed,
+			UploadSpeed:   qbpeer.Up_speed,
+			Downloaded:    qbpeer.Downloaded,
+			Uploaded:      qbpeer.Uploaded,
+			Flags:         qbpeer.Flags,
+		})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Address < peers[j].Address
+	})
+	return peers, nil
+}
+
 func (qbclient *Client) EditTorrentTracker(infoHash string, oldTracker string,
 	newTracker string, replaceHost bool) error {
 	if replaceHost {
@@ -1066,8 +1313,131 @@ func (qbclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, pr
 	return qbclient.apiPost("api/v2/torrents/filePrio", data)
 }
 
+func (qbclient *Client) RenameTorrentPath(infoHash string, oldPath string, newPath string, isFolder bool) error {
+	data := url.Values{
+		"hash":    {infoHash},
+		"oldPath": {oldPath},
+		"newPath": {newPath},
+	}
+	if isFolder {
+		return qbclient.apiPost("api/v2/torrents/renameFolder", data)
+	}
+	return qbclient.apiPost("api/v2/torrents/renameFile", data)
+}
+
+func (qbclient *Client) SetTorrentsSequentialDownload(infoHashes []string, enabled bool) error {
+	return qbclient.toggleTorrents("api/v2/torrents/toggleSequentialDownload", infoHashes, enabled,
+		func(torrent *client.Torrent) bool { return torrent.SequentialDownload })
+}
+
+func (qbclient *Client) SetTorrentsFirstLastPiecePrio(infoHashes []string, enabled bool) error {
+	return qbclient.toggleTorrents("api/v2/torrents/toggleFirstLastPiecePrio", infoHashes, enabled,
+		func(torrent *client.Torrent) bool { return torrent.FirstLastPiecePrio })
+}
+
+// qb only provides "toggle" APIs for some torrent flags,
+// so only toggle the torrents which current flag value (got by current) is not the wanted one.
+func (qbclient *Client) toggleTorrents(api string, infoHashes []string, enabled bool,
+	current func(torrent *client.Torrent) bool) error {
+	toggleInfoHashes := []string{}
+	for _, infoHash := range infoHashes {
+		torrent, err := qbclient.GetTorrent(infoHash)
+		if err != nil {
+			return err
+		}
+		if torrent == nil {
+			return fmt.Errorf("torrent %s not found", infoHash)
+		}
+		if current(torrent) != enabled {
+			toggleInfoHashes = append(toggleInfoHashes, infoHash)
+		}
+	}
+	if len(toggleInfoHashes) == 0 {
+		return nil
+	}
+	data := url.Values{
+		"hashes": {strings.Join(toggleInfoHashes, "|")},
+	}
+	err := qbclient.apiPost(api, data)
+	qbclient.PurgeCache()
+	return err
+}
+
+func (qbclient *Client) MoveTorrentsInQueue(infoHashes []string, action string) error {
+	var api string
+	switch action {
+	case client.QUEUE_TOP:
+		api = "api/v2/torrents/topPrio"
+	case client.QUEUE_BOTTOM:
+		api = "api/v2/torrents/bottomPrio"
+	case client.QUEUE_UP:
+		api = "api/v2/torrents/increasePrio"
+	case client.QUEUE_DOWN:
+		api = "api/v2/torrents/decreasePrio"
+	default:
+		return fmt.Errorf("invalid queue action %q", action)
+	}
+	data := url.Values{
+		"hashes": {strings.Join(infoHashes, "|")},
+	}
+	err := qbclient.apiPost(api, data)
+	qbclient.PurgeCache()
+	return err
+}
+
+func (qbclient *Client) GetAltSpeed() (*client.AltSpeed, error) {
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return nil, err
+	}
+	mode := 0
+	if err = qbclient.apiRequest("api/v2/transfer/speedLimitsMode", &mode); err != nil {
+		return nil, err
+	}
+	// the alt_dl_limit / alt_up_limit preferences are actually in bytes/s
+	return &client.AltSpeed{
+		Enabled:            mode == 1,
+		DownloadSpeedLimit: preferences.Alt_dl_limit,
+		UploadSpeedLimit:   preferences.Alt_up_limit,
+	}, nil
+}
+
+func (qbclient *Client) SetAltSpeedEnabled(enabled bool) error {
+	err := qbclient.login()
+	if err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	mode := 0
+	if err = qbclient.apiRequest("api/v2/transfer/speedLimitsMode", &mode); err != nil {
+		return err
+	}
+	// qb only provides the toggle API
+	if enabled == (mode == 1) {
+		return nil
+	}
+	return qbclient.apiPost("api/v2/transfer/toggleSpeedLimitsMode", nil)
+}
+
+func (qbclient *Client) SetAltSpeedLimits(downloadSpeedLimit int64, uploadSpeedLimit int64) error {
+	preferences := map[string]any{}
+	if downloadSpeedLimit >= 0 {
+		preferences["alt_dl_limit"] = downloadSpeedLimit
+	}
+	if uploadSpeedLimit >= 0 {
+		preferences["alt_up_limit"] = uploadSpeedLimit
+	}
+	if len(preferences) == 0 {
+		return nil
+	}
+	err := qbclient.setPreferences(preferences)
+	qbclient.preferences = nil
+	return err
+}
+
 func (qbclient *Client) Close() {
 	qbclient.PurgeCache()
+	qbclient.data = nil
+	qbclient.rid = 0
 	if qbclient.Logined && !qbclient.ClientConfig.QbittorrentNoLogout {
 		qbclient.Logined = false
 		qbclient.apiPost("api/v2/auth/logout", nil)
@@ -1080,12 +1450,17 @@ func NewClient(name string, clientConfig *config.ClientConfigStruct, config *con
 	if err != nil {
 		return nil, err
 	}
+	transport, err := client.NewHttpTransport(clientConfig, config)
+	if err != nil {
+		return nil, err
+	}
 	client := &Client{
 		Name:         name,
 		ClientConfig: clientConfig,
 		Config:       config,
 		HttpClient: &http.Client{
-			Jar: jar,
+			Jar:       jar,
+			Transport: transport,
 		},
 	}
 	return client, nil
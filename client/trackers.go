@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A pending replacement of a torrent tracker url.
+type TrackerReplacement struct {
+	Torrent *Torrent
+	OldUrl  string
+	NewUrl  string
+}
+
+// Check all trackers of torrents, return the replacements that need to be applied.
+// The replacer func returns the new url of a tracker, or empty string if the tracker should be kept untouched.
+// Torrents which trackers failed to be fetched are skipped and counted in errorCnt.
+func FindTrackerReplacements(clientInstance Client, torrents []*Torrent,
+	replacer func(torrent *Torrent, trackerUrl string) string) (replacements []*TrackerReplacement, errorCnt int64) {
+	for _, torrent := range torrents {
+		trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+		if err != nil {
+			log.Errorf("Failed to get torrent %s trackers: %v", torrent.InfoHash, err)
+			errorCnt++
+			continue
+		}
+		for _, tracker := range trackers {
+			newUrl := replacer(torrent, tracker.Url)
+			if newUrl == "" || newUrl == tracker.Url {
+				continue
+			}
+			replacements = append(replacements, &TrackerReplacement{Torrent: torrent, OldUrl: tracker.Url, NewUrl: newUrl})
+		}
+	}
+	return
+}
+
+func PrintTrackerReplacements(output io.Writer, replacements []*TrackerReplacement) {
+	for _, r := range replacements {
+		fmt.Fprintf(output, "%s (%s)\n  %s\n  => %s\n", r.Torrent.InfoHash, r.Torrent.Name, r.OldUrl, r.NewUrl)
+	}
+}
+
+// Apply the replacements to client, return the count of failed ones.
+func ApplyTrackerReplacements(clientInstance Client, replacements []*TrackerReplacement) (errorCnt int64) {
+	for _, r := range replacements {
+		err := clientInstance.EditTorrentTracker(r.Torrent.InfoHash, r.OldUrl, r.NewUrl, false)
+		if err != nil {
+			log.Errorf("Failed to replace torrent %s tracker: %v", r.Torrent.InfoHash, err)
+			errorCnt++
+		} else {
+			fmt.Printf("✓ replaced torrent %s (%s) tracker\n", r.Torrent.InfoHash, r.Torrent.Name)
+		}
+	}
+	return
+}
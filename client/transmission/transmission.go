@@ -8,9 +8,12 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"path"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/ettle/strcase"
@@ -29,6 +32,9 @@ type Client struct {
 	client                    *transmissionrpc.Client
 	datatime                  int64
 	datatimeMeta              int64
+	synctime                  int64    // time of last torrents sync. kept by PurgeCache, for incremental sync
+	fullSynctime              int64    // time of last full torrents sync
+	editedHashes              []string // torrents edited by ptool since last sync, which are always re-fetched
 	torrents                  map[string]*transmissionrpc.Torrent
 	sessionStats              *transmissionrpc.SessionStats
 	sessionArgs               *transmissionrpc.SessionArguments
@@ -54,6 +60,33 @@ var (
 	ErrNotImplemented = errors.New("not implemented yet")
 )
 
+// Wrap the error of transmission rpc request with the typed error category.
+func wrapError(err error) error {
+	var statusErr transmissionrpc.HTTPStatusCode
+	if errors.As(err, &statusErr) {
+		return fmt.Errorf("%w: %w", err, util.NewHttpStatusError(int(statusErr)))
+	}
+	if util.AsNetworkError(err) {
+		return fmt.Errorf("%w: %w", constants.ErrNetwork, err)
+	}
+	return err
+}
+
+const (
+	// Transmission returns torrents that are active in the last 60 seconds as "recently-active".
+	// Use a smaller value to tolerate the latency of requests.
+	RECENTLY_ACTIVE_WINDOW = 50
+	FULL_SYNC_INTERVAL     = 600 // seconds
+)
+
+// fields of torrents fetched in sync
+var torrentFields = []string{
+	"activityDate", "addedDate", "doneDate", "downloadDir", "downloadedEver", "downloadLimit", "downloadLimited",
+	"hashString", "id", "labels", "name", "peersGettingFromUs", "peersSendingToUs", "percentDone", "rateDownload",
+	"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit", "uploadLimited",
+	"queuePosition",
+}
+
 // SetAllTorrentsShareLimits implements client.Client.
 func (trclient *Client) SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error {
 	return ErrNotImplemented
@@ -66,7 +99,7 @@ func (trclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 
 // get a torrent info from rpc. return error if torrent not found
 func (trclient *Client) getTorrent(infoHash string, full bool) (*transmissionrpc.Torrent, error) {
-	if !full && trclient.torrents[infoHash] != nil {
+	if !full && trclient.Cached() && trclient.torrents[infoHash] != nil {
 		return trclient.torrents[infoHash], nil
 	}
 	if trclient.lastTorrent != nil && *trclient.lastTorrent.HashString == infoHash {
@@ -75,10 +108,10 @@ func (trclient *Client) getTorrent(infoHash string, full bool) (*transmissionrpc
 	transmissionbt := trclient.client
 	torrents, err := transmissionbt.TorrentGetAllForHashes(context.TODO(), []string{infoHash})
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	if len(torrents) == 0 {
-		return nil, fmt.Errorf("torrent not found")
+		return nil, fmt.Errorf("torrent %w", constants.ErrNotFound)
 	}
 	trclient.lastTorrent = &torrents[0]
 	return &torrents[0], err
@@ -116,26 +149,78 @@ func (trclient *Client) sync() error {
 	if trclient.datatime > 0 {
 		return nil
 	}
-	transmissionbt := trclient.client
 	now := util.Now()
-	torrents, err := transmissionbt.TorrentGet(context.TODO(), []string{
-		"activityDate", "addedDate", "doneDate", "downloadDir", "downloadedEver", "downloadLimit", "downloadLimited",
-		"hashString", "id", "labels", "name", "peersGettingFromUs", "peersSendingToUs", "percentDone", "rateDownload",
-		"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit", "uploadLimited",
-	}, nil)
+	if trclient.canSyncIncrementally(now) {
+		if err := trclient.syncIncrementally(); err != nil {
+			return wrapError(err)
+		}
+	} else {
+		torrents, err := trclient.client.TorrentGet(context.TODO(), torrentFields, nil)
+		if err != nil {
+			return wrapError(err)
+		}
+		torrentsMap := map[string]*transmissionrpc.Torrent{}
+		for i := range torrents {
+			torrentsMap[*torrents[i].HashString] = &torrents[i]
+		}
+		trclient.torrents = torrentsMap
+		trclient.fullSynctime = now
+	}
+	trclient.editedHashes = nil
+	trclient.datatime = now
+	trclient.synctime = now
+	trclient.buildDerivative()
+	return nil
+}
+
+// Incremental sync is only possible if the cached torrents are fetched within the "recently-active" window
+// of transmission, as torrents that are active before it will not be returned.
+// A full sync is also forced periodically in case some changes (e.g. made by other tools) are not reported.
+func (trclient *Client) canSyncIncrementally(now int64) bool {
+	return !trclient.ClientConfig.NoIncrementalSync && trclient.torrents != nil &&
+		now-trclient.synctime < RECENTLY_ACTIVE_WINDOW && now-trclient.fullSynctime < FULL_SYNC_INTERVAL
+}
+
+// Fetch recently active (and edited by ptool) torrents and merge them into cached torrents.
+func (trclient *Client) syncIncrementally() error {
+	transmissionbt := trclient.client
+	torrents, removed, err := transmissionbt.TorrentGetRecentlyActive(context.TODO(), torrentFields)
 	if err != nil {
 		return err
 	}
-	torrentsMap := map[string]*transmissionrpc.Torrent{}
+	if len(trclient.editedHashes) > 0 {
+		editedTorrents, err := transmissionbt.TorrentGetHashes(context.TODO(), torrentFields,
+			util.UniqueSlice(trclient.editedHashes))
+		if err != nil {
+			return err
+		}
+		torrents = append(torrents, editedTorrents...)
+	}
+	if len(removed) > 0 {
+		for infoHash, torrent := range trclient.torrents {
+			if slices.Contains(removed, *torrent.ID) {
+				delete(trclient.torrents, infoHash)
+			}
+		}
+	}
 	for i := range torrents {
-		torrentsMap[*torrents[i].HashString] = &torrents[i]
+		trclient.torrents[*torrents[i].HashString] = &torrents[i]
 	}
-	trclient.datatime = now
-	trclient.torrents = torrentsMap
-	trclient.buildDerivative()
+	log.Tracef("tr incremental sync: %d torrents updated, %d removed", len(torrents), len(removed))
 	return nil
 }
 
+// Mark torrents as edited by ptool, so that next (incremental) sync will fetch them.
+// Changes of some torrent properties (e.g. labels) may not make the torrent "recently-active".
+// If infoHashes is nil, all torrents are marked and next sync will be a full one.
+func (trclient *Client) markEdited(infoHashes []string) {
+	if infoHashes == nil {
+		trclient.fullSynctime = 0
+		return
+	}
+	trclient.editedHashes = append(trclient.editedHashes, infoHashes...)
+}
+
 func (trclient *Client) buildDerivative() {
 	unfinishedSize := int64(0)
 	unfinishedDownloadingSize := int64(0)
@@ -162,7 +247,7 @@ func (trclient *Client) syncMeta() error {
 	now := util.Now()
 	sessionStats, err := transmissionbt.SessionStats(context.TODO())
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 	sessionArgs, err := transmissionbt.SessionArgumentsGet(context.TODO(), nil)
 	if err != nil {
@@ -188,6 +273,10 @@ func (trclient *Client) GetTorrent(infoHash string) (*client.Torrent, error) {
 		return nil, err
 	}
 	trtorrent := trclient.torrents[infoHash]
+	if trtorrent == nil && len(infoHash) == 64 {
+		// v2 only torrent is identified by the truncated v2 info hash
+		trtorrent = trclient.torrents[infoHash[:40]]
+	}
 	if trtorrent == nil {
 		return nil, nil
 	}
@@ -243,6 +332,7 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 	if err != nil {
 		return err
 	}
+	trclient.markEdited([]string{*torrent.HashString})
 
 	name := option.Name
 	if name != "" {
@@ -299,6 +389,7 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		return err
 	}
 	torrent := tr2Torrent(trtorrent)
+	trclient.markEdited([]string{infoHash})
 
 	if option.Name != "" && option.Name != torrent.Name {
 		err := transmissionbt.TorrentRenamePathHash(context.TODO(), infoHash, *trtorrent.Name, option.Name)
@@ -407,10 +498,12 @@ func (trclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) (e
 }
 
 func (trclient *Client) PauseTorrents(infoHashes []string) error {
+	trclient.markEdited(infoHashes)
 	return trclient.client.TorrentStopHashes(context.TODO(), infoHashes)
 }
 
 func (trclient *Client) ResumeTorrents(infoHashes []string) error {
+	trclient.markEdited(infoHashes)
 	return trclient.client.TorrentStartHashes(context.TODO(), infoHashes)
 }
 
@@ -448,6 +541,7 @@ func (trclient *Client) RemoveTagsFromTorrents(infoHashes []string, tags []strin
 
 func (trclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string) error {
 	// it's a limit imposed by transmissionrpc library that can not batch update savePath
+	trclient.markEdited(infoHashes)
 	for _, infoHash := range infoHashes {
 		err := trclient.client.TorrentSetLocationHash(context.TODO(), infoHash, savePath, true)
 		if err != nil {
@@ -458,10 +552,12 @@ func (trclient *Client) SetTorrentsSavePath(infoHashes []string, savePath string
 }
 
 func (trclient *Client) PauseAllTorrents() error {
+	trclient.markEdited(nil)
 	return trclient.client.TorrentStopHashes(context.TODO(), nil)
 }
 
 func (trclient *Client) ResumeAllTorrents() error {
+	trclient.markEdited(nil)
 	return trclient.client.TorrentStartHashes(context.TODO(), nil)
 }
 
@@ -590,16 +686,41 @@ func (trclient *Client) GetTorrentContents(infoHash string) ([]*client.TorrentCo
 	files := []*client.TorrentContentFile{}
 	for i, trTorrentFile := range torrent.Files {
 		files = append(files, &client.TorrentContentFile{
-			Path:     trTorrentFile.Name,
-			Size:     trTorrentFile.Length,
-			Ignored:  !torrent.FileStats[i].Wanted,
-			Complete: trTorrentFile.BytesCompleted == trTorrentFile.Length,
-			Progress: float64(trTorrentFile.BytesCompleted) / float64(trTorrentFile.Length),
+			Path:         trTorrentFile.Name,
+			Size:         trTorrentFile.Length,
+			Ignored:      !torrent.FileStats[i].Wanted,
+			Complete:     trTorrentFile.BytesCompleted == trTorrentFile.Length,
+			Progress:     float64(trTorrentFile.BytesCompleted) / float64(trTorrentFile.Length),
+			Availability: -1,
 		})
 	}
 	return files, nil
 }
 
+func (trclient *Client) GetTorrentPieceStates(infoHash string) ([]int64, error) {
+	torrent, err := trclient.getTorrent(infoHash, true)
+	if err != nil {
+		return nil, err
+	}
+	if torrent.Pieces == nil || torrent.PieceCount == nil {
+		return nil, fmt.Errorf("pieces info not available")
+	}
+	// a bitfield, in which the highest bit of first byte is piece 0
+	bitfield, err := base64.StdEncoding.DecodeString(*torrent.Pieces)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pieces bitfield: %w", err)
+	}
+	states := make([]int64, *torrent.PieceCount)
+	for i := range states {
+		if i/8 < len(bitfield) && bitfield[i/8]&(0x80>>(i%8)) != 0 {
+			states[i] = client.PIECE_DOWNLOADED
+		}
+	}
+	return states, nil
+}
+
+// Mark cached data as stale, so that next sync will refresh it.
+// The cached torrents are kept (unless incremental sync is disabled) and next sync may only fetch the changes.
 func (trclient *Client) PurgeCache() {
 	trclient.datatime = 0
 	trclient.datatimeMeta = 0
@@ -607,7 +728,9 @@ func (trclient *Client) PurgeCache() {
 	trclient.unfinishedDownloadingSize = 0
 	trclient.sessionArgs = nil
 	trclient.sessionStats = nil
-	trclient.torrents = nil
+	if trclient.ClientConfig.NoIncrementalSync {
+		trclient.torrents = nil
+	}
 	trclient.lastTorrent = nil
 	trclient.contentPathTorrents = nil
 }
@@ -694,12 +817,52 @@ func (trclient *Client) SetConfig(variable string, value string) error {
 			SpeedLimitUpEnabled: &limited,
 			SpeedLimitUp:        &limit,
 		})
-	case "free_disk_space", "global_download_speed", "global_upload_speed":
+	case "free_disk_space", "global_download_speed", "global_upload_speed", "network_interface", "bind_address",
+		"listen_port_open":
 		return fmt.Errorf("%s is read-only", variable)
 	case "save_path":
 		return transmissionbt.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
 			DownloadDir: &value,
 		})
+	case "listen_port":
+		port, err := client.ParseListenPort(value)
+		if err != nil {
+			return err
+		}
+		return transmissionbt.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+			PeerPort: &port,
+		})
+	case "queueing_enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool value %q", value)
+		}
+		return transmissionbt.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+			DownloadQueueEnabled: &enabled,
+			SeedQueueEnabled:     &enabled,
+		})
+	case "max_active_downloads":
+		size := util.ParseInt(value)
+		return transmissionbt.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+			DownloadQueueSize: &size,
+		})
+	case "max_active_uploads":
+		size := util.ParseInt(value)
+		return transmissionbt.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+			SeedQueueSize: &size,
+		})
+	case "max_active_torrents":
+		return fmt.Errorf("%s is not supported by transmission", variable)
+	case "alt_speed_enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool value %q", value)
+		}
+		return trclient.SetAltSpeedEnabled(enabled)
+	case "alt_download_speed_limit":
+		return trclient.SetAltSpeedLimits(util.ParseInt(value), -1)
+	case "alt_upload_speed_limit":
+		return trclient.SetAltSpeedLimits(-1, util.ParseInt(value))
 	default:
 		return nil
 	}
@@ -748,10 +911,31 @@ func (trclient *Client) GetConfig(variable string) (string, error) {
 		return fmt.Sprint(status.UploadSpeed), nil
 	case "save_path":
 		return *trclient.sessionArgs.DownloadDir, nil
+	case "listen_port":
+		return fmt.Sprint(*trclient.sessionArgs.PeerPort), nil
+	case "queueing_enabled":
+		return fmt.Sprint(*trclient.sessionArgs.DownloadQueueEnabled || *trclient.sessionArgs.SeedQueueEnabled), nil
+	case "max_active_downloads":
+		return fmt.Sprint(*trclient.sessionArgs.DownloadQueueSize), nil
+	case "max_active_uploads":
+		return fmt.Sprint(*trclient.sessionArgs.SeedQueueSize), nil
+	case "alt_speed_enabled":
+		return fmt.Sprint(*trclient.sessionArgs.AltSpeedEnabled), nil
+	case "alt_download_speed_limit":
+		return fmt.Sprint(*trclient.sessionArgs.AltSpeedDown * 1024), nil
+	case "alt_upload_speed_limit":
+		return fmt.Sprint(*trclient.sessionArgs.AltSpeedUp * 1024), nil
+	case "listen_port_open":
+		open, err := trclient.client.PortTest(context.TODO())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(open), nil
 	default:
 		return "", nil
 	}
 }
+
 func (trclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrackers, error) {
 	torrent, err := trclient.getTorrent(infoHash, true)
 	if err != nil {
@@ -769,15 +953,44 @@ func (trclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrack
 		if msg == "" {
 			msg = trackerStat.LastScrapeResult
 		}
+		nextAnnounce := int64(0)
+		if !trackerStat.NextAnnounceTime.IsZero() && trackerStat.NextAnnounceTime.Unix() > 0 {
+			nextAnnounce = trackerStat.NextAnnounceTime.Unix()
+		}
 		trackers = append(trackers, client.TorrentTracker{
-			Url:    trackerStat.Announce,
-			Status: status,
-			Msg:    msg,
+			Url:          trackerStat.Announce,
+			Status:       status,
+			Msg:          msg,
+			Tier:         trackerStat.Tier,
+			Seeders:      trackerStat.SeederCount,
+			Leechers:     trackerStat.LeecherCount,
+			NextAnnounce: nextAnnounce,
 		})
 	}
 	return trackers, nil
 }
 
+func (trclient *Client) GetTorrentPeers(infoHash string) ([]*client.TorrentPeer, error) {
+	torrent, err := trclient.getTorrent(infoHash, true)
+	if err != nil {
+		return nil, err
+	}
+	peers := []*client.TorrentPeer{}
+	for _, trpeer := range torrent.Peers {
+		peers = append(peers, &client.TorrentPeer{
+			Address:       net.JoinHostPort(trpeer.Address, fmt.Sprint(trpeer.Port)),
+			Client:        trpeer.ClientName,
+			Progress:      trpeer.Progress,
+			DownloadSpeed: trpeer.RateToClient,
+			UploadSpeed:   trpeer.RateToPeer,
+			Downloaded:    -1,
+			Uploaded:      -1,
+			Flags:         trpeer.FlagStr,
+		})
+	}
+	return peers, nil
+}
+
 func (trclient *Client) EditTorrentTracker(infoHash string, oldTracker string, newTracker string, replaceHost bool) error {
 	trtorrent, err := trclient.getTorrent(infoHash, false)
 	if err != nil {
@@ -815,6 +1028,7 @@ func (trclient *Client) EditTorrentTracker(infoHash string, oldTracker string, n
 	if oldTrackerUrl == newTrackerUrl {
 		return nil
 	}
+	trclient.markEdited([]string{infoHash})
 	// this is broken for now as transmission RPC expects trackerReplace to be
 	// a mixed types array of ids (integer) and urls(string)
 	// it's a problem of transmissionrpc library
@@ -845,6 +1059,7 @@ func (trclient *Client) AddTorrentTrackers(infoHash string, trackers []string,
 		})
 	}
 	if len(trackers) > 0 {
+		trclient.markEdited([]string{infoHash})
 		payload := transmissionrpc.TorrentSetPayload{
 			IDs:        []int64{*trtorrent.ID},
 			TrackerAdd: trackers,
@@ -869,6 +1084,7 @@ func (trclient *Client) RemoveTorrentTrackers(infoHash string, trackers []string
 		}
 	}
 	if len(trackerIds) > 0 {
+		trclient.markEdited([]string{infoHash})
 		return trclient.client.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
 			IDs:           []int64{*trtorrent.ID},
 			TrackerRemove: trackerIds,
@@ -881,8 +1097,165 @@ func (trclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, pr
 	return ErrNotImplemented
 }
 
+func (trclient *Client) RenameTorrentPath(infoHash string, oldPath string, newPath string, isFolder bool) error {
+	if path.Dir(oldPath) != path.Dir(newPath) {
+		return fmt.Errorf("transmission can only rename the basename of a path, can not move it to another folder")
+	}
+	transmissionbt := trclient.client
+	err := transmissionbt.TorrentRenamePathHash(context.TODO(), infoHash, oldPath, path.Base(newPath))
+	if err == nil {
+		trclient.markEdited([]string{infoHash})
+		trclient.PurgeCache()
+	}
+	return err
+}
+
+// Emulate sequential download by setting file priorities: files in (path) order,
+// the first 1/3 ones get high priority, the last 1/3 ones get low priority.
+// Disabling it resets all files to normal priority.
+func (trclient *Client) SetTorrentsSequentialDownload(infoHashes []string, enabled bool) error {
+	return trclient.setTorrentsFilePriorities(infoHashes, func(cnt int, i int) (int64, bool) {
+		if !enabled {
+			return 0, true
+		}
+		if i < cnt/3 || cnt < 3 && i == 0 {
+			return 1, true
+		} else if i >= cnt-cnt/3 {
+			return -1, true
+		}
+		return 0, true
+	})
+}
+
+// Emulate first-last piece priority by setting the first and last file (in path order) to high priority.
+// Disabling it resets them to normal priority. The priorities of other files are not touched.
+func (trclient *Client) SetTorrentsFirstLastPiecePrio(infoHashes []string, enabled bool) error {
+	return trclient.setTorrentsFilePriorities(infoHashes, func(cnt int, i int) (int64, bool) {
+		if i != 0 && i != cnt-1 {
+			return 0, false
+		}
+		if enabled {
+			return 1, true
+		}
+		return 0, true
+	})
+}
+
+// Set file priorities of torrents. The priority func receives files count and index of a file in path order,
+// and returns the priority of it: 1 - high; 0 - normal; -1 - low; ok is false if the file should not be touched.
+func (trclient *Client) setTorrentsFilePriorities(infoHashes []string,
+	priority func(cnt int, i int) (priority int64, ok bool)) error {
+	transmissionbt := trclient.client
+	for _, infoHash := range infoHashes {
+		trtorrent, err := trclient.getTorrent(infoHash, true)
+		if err != nil {
+			return err
+		}
+		indexes := make([]int64, len(trtorrent.Files))
+		for i := range indexes {
+			indexes[i] = int64(i)
+		}
+		slices.SortStableFunc(indexes, func(a, b int64) int {
+			return strings.Compare(trtorrent.Files[a].Name, trtorrent.Files[b].Name)
+		})
+		payload := transmissionrpc.TorrentSetPayload{
+			IDs: []int64{*trtorrent.ID},
+		}
+		touched := false
+		for i, index := range indexes {
+			value, ok := priority(len(indexes), i)
+			if !ok {
+				continue
+			}
+			touched = true
+			switch value {
+			case 1:
+				payload.PriorityHigh = append(payload.PriorityHigh, index)
+			case -1:
+				payload.PriorityLow = append(payload.PriorityLow, index)
+			default:
+				payload.PriorityNormal = append(payload.PriorityNormal, index)
+			}
+		}
+		if !touched {
+			continue
+		}
+		if err := transmissionbt.TorrentSet(context.TODO(), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (trclient *Client) MoveTorrentsInQueue(infoHashes []string, action string) error {
+	if err := trclient.sync(); err != nil {
+		return err
+	}
+	ids := trclient.getIds(infoHashes)
+	if len(ids) == 0 {
+		return nil
+	}
+	// the queue positions of other torrents are also changed
+	trclient.markEdited(nil)
+	defer trclient.PurgeCache()
+	transmissionbt := trclient.client
+	switch action {
+	case client.QUEUE_TOP:
+		return transmissionbt.QueueMoveTop(context.TODO(), ids)
+	case client.QUEUE_BOTTOM:
+		return transmissionbt.QueueMoveBottom(context.TODO(), ids)
+	case client.QUEUE_UP:
+		return transmissionbt.QueueMoveUp(context.TODO(), ids)
+	case client.QUEUE_DOWN:
+		return transmissionbt.QueueMoveDown(context.TODO(), ids)
+	default:
+		return fmt.Errorf("invalid queue action %q", action)
+	}
+}
+
+func (trclient *Client) GetAltSpeed() (*client.AltSpeed, error) {
+	if err := trclient.syncMeta(); err != nil {
+		return nil, err
+	}
+	return &client.AltSpeed{
+		Enabled:            *trclient.sessionArgs.AltSpeedEnabled,
+		DownloadSpeedLimit: *trclient.sessionArgs.AltSpeedDown * 1024,
+		UploadSpeedLimit:   *trclient.sessionArgs.AltSpeedUp * 1024,
+	}, nil
+}
+
+func (trclient *Client) SetAltSpeedEnabled(enabled bool) error {
+	defer trclient.PurgeCache()
+	return trclient.client.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+		AltSpeedEnabled: &enabled,
+	})
+}
+
+// Transmission alt speeds are in KB/s.
+func (trclient *Client) SetAltSpeedLimits(downloadSpeedLimit int64, uploadSpeedLimit int64) error {
+	if downloadSpeedLimit == 0 || uploadSpeedLimit == 0 {
+		return fmt.Errorf("transmission does not support unlimited alt speed")
+	}
+	args := transmissionrpc.SessionArguments{}
+	if downloadSpeedLimit > 0 {
+		limit := max(downloadSpeedLimit/1024, 1)
+		args.AltSpeedDown = &limit
+	}
+	if uploadSpeedLimit > 0 {
+		limit := max(uploadSpeedLimit/1024, 1)
+		args.AltSpeedUp = &limit
+	}
+	if args.AltSpeedDown == nil && args.AltSpeedUp == nil {
+		return nil
+	}
+	defer trclient.PurgeCache()
+	return trclient.client.SessionArgumentsSet(context.TODO(), args)
+}
+
 func (trclient *Client) Close() {
 	trclient.PurgeCache()
+	trclient.torrents = nil
+	trclient.editedHashes = nil
 }
 
 func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
@@ -908,10 +1281,15 @@ func NewClient(name string, clientConfig *config.ClientConfigStruct, config *con
 	if (schema != "http" && schema != "https") || hostname == "" || port == 0 {
 		return nil, fmt.Errorf("invalid tr url: %s", clientConfig.Url)
 	}
+	transport, err := client.NewHttpTransport(clientConfig, config)
+	if err != nil {
+		return nil, err
+	}
 	client, err := transmissionrpc.New(hostname, clientConfig.Username, clientConfig.Password,
 		&transmissionrpc.AdvancedConfig{
-			HTTPS: isHttps,
-			Port:  uint16(port),
+			HTTPS:     isHttps,
+			Port:      uint16(port),
+			Transport: transport,
 		})
 	if err != nil {
 		return nil, err
@@ -1009,6 +1387,9 @@ func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
 		Leechers:           *trtorrent.PeersGettingFromUs, // it's meaning is inconsistent with qb for now
 		Meta:               nil,
 	}
+	if trtorrent.QueuePosition != nil {
+		torrent.QueuePosition = *trtorrent.QueuePosition + 1 // 0-based in transmission
+	}
 	torrent.Meta = torrent.GetMetadataFromTags()
 	torrent.Category = torrent.GetCategoryFromTag()
 	torrent.RemoveSubstituteTags()
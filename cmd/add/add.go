@@ -8,24 +8,35 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
 )
 
 var command = &cobra.Command{
-	Use:         "add {client} {torrentFilename | torrentId | torrentUrl}...",
+	Use:         "add {client} {torrentFilename | torrentId | torrentUrl | dir}...",
 	Aliases:     []string{"addlocal"},
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "add"},
 	Short:       "Add torrents to client.",
 	Long: fmt.Sprintf(`Add torrents to client.
 First arg is client. The following args is the args list.
 %s.
+If an arg is a local dir, all *.torrent files inside it (not recursive) are added.
+
+It outputs the result of each torrent: "✓" (added), "-" (skipped as it already exists in client) or "✕" (failed).
+The "--if-exists" flag controls the action if torrent already exists in client (has the same info-hash):
+skip it (default), treat it as fail, or add the trackers of torrent to the existing one ("merge-trackers").
+If "--check-existing-content" flag is set, the client torrent which has identical contents (but different
+info-hash, e.g. a xseed torrent of other site) is also treated as existing (trackers are not merged in this case).
+For local .torrent files, if "--rename-added" or "--delete-added" flag is set, the added (or already existing)
+ones are renamed to *%s or deleted; if "--rename-fail" flag is set, the failed ones are renamed to *%s.
 
 By default, if the arg is a http(s) url, it will try to parse it as a site torrent url,
 download the .torrent file to local and verify it's a valid metainfo file,
@@ -47,8 +58,11 @@ Flags:
 
 If --use-comment-meta flag is set, ptool will extract torrent's category & tags & savePath meta info
 from the 'comment' field of .torrent file (parsed in json '{tags, category, save_path, comment}' format).
-The "ptool export" command has the same flag that saves meta info to 'comment' field when exporting torrents.`,
-		constants.HELP_TORRENT_ARGS),
+The "ptool export" command has the same flag that saves meta info to 'comment' field when exporting torrents.
+The "--stage-dir" flag of "ptool dltorrent", "ptool batchdl" and "ptool iyuu xseed" commands also saves
+meta info to 'comment' field of staged torrents, which can then be added by:
+  ptool addlocal <client> <dir> --use-comment-meta --rename-added`,
+		constants.HELP_TORRENT_ARGS, constants.FILENAME_SUFFIX_ADDED, constants.FILENAME_SUFFIX_FAIL),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
 	RunE: add,
 }
@@ -62,6 +76,9 @@ var (
 	skipCheck          = false
 	sequentialDownload = false
 	renameAdded        = false
+	renameFail         = false
+	checkContent       = false
+	noFilePriority     = false
 	deleteAdded        = false
 	forceLocal         = false
 	ratioLimit         = float64(0)
@@ -71,6 +88,7 @@ var (
 	defaultSite        = ""
 	addTags            = ""
 	savePath           = ""
+	ifExists           = ""
 	mapSavePaths       []string
 )
 
@@ -89,6 +107,9 @@ func init() {
 	command.Flags().BoolVarP(&renameAdded, "rename-added", "", false,
 		"Rename successfully added .torrent file to *"+constants.FILENAME_SUFFIX_ADDED+
 			" unless it's name already has that suffix")
+	command.Flags().BoolVarP(&renameFail, "rename-fail", "", false,
+		"Rename failed to add .torrent file to *"+constants.FILENAME_SUFFIX_FAIL+
+			" unless it's name already has that suffix")
 	command.Flags().BoolVarP(&deleteAdded, "delete-added", "", false, "Delete successfully added *.torrent file")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all arg as local torrent filename")
 	command.Flags().Int64VarP(&seedingTimeLimit, "seeding-time-limit", "", 0,
@@ -100,6 +121,11 @@ func init() {
 	command.Flags().StringVarP(&savePath, "add-save-path", "", "", "Set save path of added torrents")
 	command.Flags().StringVarP(&defaultSite, "site", "", "", "Set default site of added torrents")
 	command.Flags().StringVarP(&addTags, "add-tags", "", "", "Add tags to added torrent (comma-separated)")
+	command.Flags().BoolVarP(&noFilePriority, "no-file-priority", "", false,
+		`Do not apply "filePriorities" rules of config to added torrents`)
+	command.Flags().BoolVarP(&checkContent, "check-existing-content", "", false,
+		"Also treat the client torrent which has identical contents but different info-hash as existing")
+	cmd.AddEnumFlagP(command, &ifExists, "if-exists", "", common.IfExistsFlag)
 	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
 		`Used with "--use-comment-meta". Map save path from torrent comment to the file system of BitTorrent client. `+
 			`Format: "comment_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS)
@@ -118,6 +144,9 @@ func add(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if torrents, err = helper.ExpandTorrentDirs(torrents); err != nil {
+		return err
+	}
 	clientInstance, err := client.CreateClient(clientName)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
@@ -140,10 +169,36 @@ func add(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("invalid map-save-path(s): %w", err)
 		}
 	}
+	existingChecker := common.NewExistingTorrentChecker(clientInstance, ifExists, checkContent)
 	errorCnt := int64(0)
+	var lastErr error
 	cntAdded := int64(0)
+	cntDuplicate := int64(0)
 	sizeAdded := int64(0)
 	cntAll := len(torrents)
+	// rename or delete the processed local .torrent file according to flags
+	handleProcessed := func(torrent string, isLocal bool, success bool) {
+		if !isLocal || torrent == "-" || flags.DryRun {
+			return
+		}
+		if success {
+			if renameAdded && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_ADDED) {
+				if err := os.Rename(torrent, util.TrimAnySuffix(torrent,
+					constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_ADDED); err != nil {
+					log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_ADDED, err)
+				}
+			} else if deleteAdded {
+				if err := audit.RemoveFile(torrent); err != nil {
+					log.Debugf("Failed to delete %s: %v", torrent, err)
+				}
+			}
+		} else if renameFail && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_FAIL) && util.FileExists(torrent) {
+			if err := os.Rename(torrent, util.TrimAnySuffix(torrent,
+				constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_FAIL); err != nil {
+				log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_FAIL, err)
+			}
+		}
+	}
 
 	for i, torrent := range torrents {
 		option.Category = ""
@@ -154,9 +209,12 @@ func add(cmd *cobra.Command, args []string) error {
 			option.Category = addCategory
 			option.Tags = fixedTags
 			option.SavePath = savePath
-			if err = clientInstance.AddTorrent([]byte(torrent), option, nil); err != nil {
+			if flags.DryRun {
+				fmt.Printf("✓ %s (%d/%d) (dry-run)\n", torrent, i+1, cntAll)
+			} else if err = clientInstance.AddTorrent([]byte(torrent), option, nil); err != nil {
 				fmt.Printf("✕ %s (%d/%d): failed to add to client: %v\n", torrent, i+1, cntAll, err)
 				errorCnt++
+				lastErr = err
 			} else {
 				fmt.Printf("✓ %s (%d/%d)\n", torrent, i+1, cntAll)
 			}
@@ -171,6 +229,8 @@ func add(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			fmt.Printf("✕ %s (%d/%d): %v\n", torrent, i+1, cntAll, err)
 			errorCnt++
+			lastErr = err
+			handleProcessed(torrent, isLocal, false)
 			continue
 		}
 		size := int64(0)
@@ -189,10 +249,12 @@ func add(cmd *cobra.Command, args []string) error {
 			if tinfo == nil {
 				fmt.Printf("✕ %s (%d/%d): can NOT parse comment meta (invalid torrent)\n", torrent, i+1, cntAll)
 				errorCnt++
+				handleProcessed(torrent, isLocal, false)
 				continue
 			} else if commentMeta := tinfo.DecodeComment(); commentMeta == nil {
 				fmt.Printf("✕ %s (%d/%d): failed to parse comment meta\n", torrent, i+1, cntAll)
 				errorCnt++
+				handleProcessed(torrent, isLocal, false)
 				continue
 			} else {
 				log.Debugf("Found and use torrent %s comment meta %v", torrent, commentMeta)
@@ -204,6 +266,7 @@ func add(cmd *cobra.Command, args []string) error {
 						fmt.Printf("✕ %s (%d/%d): failed to map comment meta save path %q\n",
 							torrent, i+1, cntAll, option.SavePath)
 						errorCnt++
+						handleProcessed(torrent, isLocal, false)
 						continue
 					} else {
 						option.SavePath = _savePath
@@ -258,34 +321,49 @@ func add(cmd *cobra.Command, args []string) error {
 		if option.SavePath == "" {
 			option.SavePath = savePath
 		}
+		if tinfo != nil {
+			if existing, err := existingChecker.Check(tinfo); existing != nil && err == nil {
+				cntDuplicate++
+				fmt.Printf("- %s (%d/%d) (site=%s): already exists in client as %s (%s)\n",
+					torrent, i+1, cntAll, sitename, existing.InfoHash, existing.Name)
+				handleProcessed(torrent, isLocal, true)
+				continue
+			} else if err != nil {
+				fmt.Printf("✕ %s (%d/%d) (site=%s): %v\n", torrent, i+1, cntAll, sitename, err)
+				errorCnt++
+				lastErr = err
+				handleProcessed(torrent, isLocal, false)
+				continue
+			}
+		}
+		if flags.DryRun {
+			cntAdded++
+			sizeAdded += size
+			fmt.Printf("✓ %s (%d/%d) (site=%s). infoHash=%s // %s (%s) (dry-run)\n",
+				torrent, i+1, cntAll, sitename, infoHash, contentPath, util.BytesSize(float64(size)))
+			continue
+		}
 		err = clientInstance.AddTorrent(content, option, nil)
 		if err != nil {
 			fmt.Printf("✕ %s (%d/%d) (site=%s): failed to add torrent to client: %v // %s (%s)\n",
 				torrent, i+1, cntAll, sitename, err, contentPath, util.BytesSize(float64(size)))
 			errorCnt++
+			lastErr = err
+			handleProcessed(torrent, isLocal, false)
 			continue
 		}
-		if isLocal && torrent != "-" {
-			if renameAdded && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_ADDED) {
-				if err := os.Rename(torrent, util.TrimAnySuffix(torrent,
-					constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_ADDED); err != nil {
-					log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_ADDED, err)
-				}
-			} else if deleteAdded {
-				if err := os.Remove(torrent); err != nil {
-					log.Debugf("Failed to delete %s: %v // %s", torrent, err, contentPath)
-				}
+		if !noFilePriority && infoHash != "" {
+			if err := common.ApplyFilePriorityRules(clientInstance, infoHash); err != nil {
+				log.Warnf("Failed to apply file priority rules to torrent %s: %v", infoHash, err)
 			}
 		}
+		handleProcessed(torrent, isLocal, true)
 		cntAdded++
 		sizeAdded += size
 		fmt.Printf("✓ %s (%d/%d) (site=%s). infoHash=%s // %s (%s)\n",
 			torrent, i+1, cntAll, sitename, infoHash, contentPath, util.BytesSize(float64(size)))
 	}
-	fmt.Fprintf(os.Stderr, "\n// Done. Added torrent (Size/Cnt): %s / %d; ErrorCnt: %d\n",
-		util.BytesSize(float64(sizeAdded)), cntAdded, errorCnt)
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	fmt.Fprintf(os.Stderr, "\n// Done. Added torrent (Size/Cnt): %s / %d; DuplicateCnt: %d; ErrorCnt: %d\n",
+		util.BytesSize(float64(sizeAdded)), cntAdded, cntDuplicate, errorCnt)
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
@@ -2,11 +2,13 @@ package addtags
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -57,6 +59,9 @@ func addtags(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, fmt.Sprintf("add tags %s to", strings.Join(tags, ","))) {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.AddTagsToAllTorrents(tags)
 		if err != nil {
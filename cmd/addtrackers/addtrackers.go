@@ -10,7 +10,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -90,7 +92,7 @@ func addtrackers(cmd *cobra.Command, args []string) error {
 		log.Infof("No matched torrents found")
 		return nil
 	}
-	if !force {
+	if !force && !flags.DryRun {
 		client.PrintTorrents(os.Stdout, torrents, "", 1, false)
 		fmt.Printf("\n")
 		condition := ""
@@ -107,16 +109,19 @@ func addtrackers(cmd *cobra.Command, args []string) error {
 		}
 	}
 	errorCnt := int64(0)
+	var lastErr error
 	for _, torrent := range torrents {
+		if common.DryRun("add trackers %s to torrent %s (%s)", strings.Join(trackers, ", "),
+			torrent.InfoHash, torrent.Name) {
+			continue
+		}
 		fmt.Printf("Add trackers to torrent %s (%s)\n", torrent.InfoHash, torrent.Name)
 		err := clientInstance.AddTorrentTrackers(torrent.InfoHash, trackers, oldTracker, removeExisting)
 		if err != nil {
 			log.Errorf("Failed to add trackers: %v\n", err)
 			errorCnt++
+			lastErr = err
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
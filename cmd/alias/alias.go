@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/plugin"
 	"github.com/sagan/ptool/config"
 )
 
@@ -42,7 +43,10 @@ func aliascmd(_ *cobra.Command, args []string) error {
 
 	aliasConfig := config.GetAliasConfig(aliasName)
 	if aliasConfig == nil {
-		return fmt.Errorf("command or alias '%s' not found. Run 'ptool --help' for usage", aliasName)
+		if pluginPath := plugin.Find(aliasName); pluginPath != "" {
+			return plugin.Run(aliasName, pluginPath, args)
+		}
+		return fmt.Errorf("command, alias or plugin '%s' not found. Run 'ptool --help' for usage", aliasName)
 	}
 	argsCmd := strings.TrimSpace(aliasConfig.Cmd)
 	if argsCmd == "" {
@@ -6,52 +6,93 @@ import (
 	_ "github.com/sagan/ptool/cmd/addtags"
 	_ "github.com/sagan/ptool/cmd/addtrackers"
 	_ "github.com/sagan/ptool/cmd/alias"
+	_ "github.com/sagan/ptool/cmd/archive"
+	_ "github.com/sagan/ptool/cmd/arr/all"
+	_ "github.com/sagan/ptool/cmd/auditcmd"
+	_ "github.com/sagan/ptool/cmd/autoremove"
+	_ "github.com/sagan/ptool/cmd/autotag"
+	_ "github.com/sagan/ptool/cmd/backup"
 	_ "github.com/sagan/ptool/cmd/batchdl"
+	_ "github.com/sagan/ptool/cmd/bonus"
 	_ "github.com/sagan/ptool/cmd/brush"
+	_ "github.com/sagan/ptool/cmd/checksum"
 	_ "github.com/sagan/ptool/cmd/checktag"
+	_ "github.com/sagan/ptool/cmd/clientcmd/all"
 	_ "github.com/sagan/ptool/cmd/clientctl"
 	_ "github.com/sagan/ptool/cmd/configcmd/all"
 	_ "github.com/sagan/ptool/cmd/cookiecloud/all"
 	_ "github.com/sagan/ptool/cmd/createcategory"
 	_ "github.com/sagan/ptool/cmd/createtags"
+	_ "github.com/sagan/ptool/cmd/dedupe"
 	_ "github.com/sagan/ptool/cmd/delete"
 	_ "github.com/sagan/ptool/cmd/deletecategories"
 	_ "github.com/sagan/ptool/cmd/deletetags"
 	_ "github.com/sagan/ptool/cmd/dltorrent"
+	_ "github.com/sagan/ptool/cmd/du"
 	_ "github.com/sagan/ptool/cmd/dynamicseeding"
 	_ "github.com/sagan/ptool/cmd/edittorrent"
 	_ "github.com/sagan/ptool/cmd/edittracker"
+	_ "github.com/sagan/ptool/cmd/events"
 	_ "github.com/sagan/ptool/cmd/export"
 	_ "github.com/sagan/ptool/cmd/findalone"
 	_ "github.com/sagan/ptool/cmd/getcategories"
 	_ "github.com/sagan/ptool/cmd/gettags"
 	_ "github.com/sagan/ptool/cmd/hardlink/all"
 	_ "github.com/sagan/ptool/cmd/iyuu/all"
+	_ "github.com/sagan/ptool/cmd/journal"
+	_ "github.com/sagan/ptool/cmd/login"
 	_ "github.com/sagan/ptool/cmd/maketorrent"
+	_ "github.com/sagan/ptool/cmd/movedata"
+	_ "github.com/sagan/ptool/cmd/nettest"
 	_ "github.com/sagan/ptool/cmd/parsetorrent"
 	_ "github.com/sagan/ptool/cmd/partialdownload"
+	_ "github.com/sagan/ptool/cmd/passkey"
 	_ "github.com/sagan/ptool/cmd/pause"
+	_ "github.com/sagan/ptool/cmd/peers"
+	_ "github.com/sagan/ptool/cmd/peerstats"
+	_ "github.com/sagan/ptool/cmd/pieces"
+	_ "github.com/sagan/ptool/cmd/plugin"
+	_ "github.com/sagan/ptool/cmd/proxytest"
+	_ "github.com/sagan/ptool/cmd/prunereport"
 	_ "github.com/sagan/ptool/cmd/publish"
+	_ "github.com/sagan/ptool/cmd/queue"
+	_ "github.com/sagan/ptool/cmd/ratioplan"
 	_ "github.com/sagan/ptool/cmd/reannounce"
 	_ "github.com/sagan/ptool/cmd/recheck"
 	_ "github.com/sagan/ptool/cmd/removetags"
 	_ "github.com/sagan/ptool/cmd/removetrackers"
 	_ "github.com/sagan/ptool/cmd/renametag"
+	_ "github.com/sagan/ptool/cmd/renametorrent"
+	_ "github.com/sagan/ptool/cmd/replacetrackers"
 	_ "github.com/sagan/ptool/cmd/reseed/all"
+	_ "github.com/sagan/ptool/cmd/restore"
 	_ "github.com/sagan/ptool/cmd/resume"
 	_ "github.com/sagan/ptool/cmd/run"
 	_ "github.com/sagan/ptool/cmd/search"
+	_ "github.com/sagan/ptool/cmd/selfupdate"
+	_ "github.com/sagan/ptool/cmd/serve"
 	_ "github.com/sagan/ptool/cmd/setcategory"
 	_ "github.com/sagan/ptool/cmd/setsavepath"
 	_ "github.com/sagan/ptool/cmd/setsharelimits"
 	_ "github.com/sagan/ptool/cmd/shell"
 	_ "github.com/sagan/ptool/cmd/show"
+	_ "github.com/sagan/ptool/cmd/sitecheck"
+	_ "github.com/sagan/ptool/cmd/sitemsg"
 	_ "github.com/sagan/ptool/cmd/sites/all"
+	_ "github.com/sagan/ptool/cmd/speedtest"
 	_ "github.com/sagan/ptool/cmd/statscmd"
 	_ "github.com/sagan/ptool/cmd/status"
+	_ "github.com/sagan/ptool/cmd/stream"
 	_ "github.com/sagan/ptool/cmd/tidyup"
+	_ "github.com/sagan/ptool/cmd/torrentctl"
+	_ "github.com/sagan/ptool/cmd/trackers"
+	_ "github.com/sagan/ptool/cmd/trackerstatus"
+	_ "github.com/sagan/ptool/cmd/unarchive"
+	_ "github.com/sagan/ptool/cmd/undelete"
+	_ "github.com/sagan/ptool/cmd/upload"
 	_ "github.com/sagan/ptool/cmd/verifytorrent"
 	_ "github.com/sagan/ptool/cmd/versioncmd"
+	_ "github.com/sagan/ptool/cmd/watch"
 	_ "github.com/sagan/ptool/cmd/xseedadd"
 	_ "github.com/sagan/ptool/cmd/xseedcheck"
 )
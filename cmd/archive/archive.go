@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "archive {client} {infoHash}... [--category category] [--tag tag] [--filter filter] [--force]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "archive"},
+	Short:       "Archive torrents of client: remove them from client with content files kept, for re-adding later.",
+	Long: fmt.Sprintf(`Archive torrents of client: remove them from client with content files kept, for re-adding later.
+%s.
+
+For each torrent, it exports the .torrent file and saves the resume state (save path, category, tags,
+trackers, skipped files, speed limits, state, completion and transfer stats) to archive dir
+(the "archiveDir" config, default is "archive" in config dir), then deletes the torrent from client
+without deleting it's content files. It helps to keep the number of torrents in client manageable,
+e.g. for long-term seeding torrents that are rarely active.
+
+Use "ptool unarchive" to list the archived torrents or re-add them to client. The torrents which were
+completely downloaded when archived are re-added with hash checking skipped.
+
+It will ask for confirmation, unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: archive,
+}
+
+var (
+	force    = false
+	category = ""
+	tag      = ""
+	filter   = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only display torrents that would be archived, do NOT actually modify client")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func archive(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if category == "" && tag == "" && filter == "" {
+		if len(infoHashes) == 0 {
+			return fmt.Errorf("no torrent to archive")
+		}
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch client torrents: %w", err)
+	}
+	if len(torrents) == 0 {
+		log.Infof("No matched torrents found")
+		return nil
+	}
+	if !force || flags.DryRun {
+		client.PrintTorrents(os.Stdout, torrents, "", 1, false)
+		fmt.Printf("Above %d torrents will be archived (removed from client, content files are kept)\n", len(torrents))
+		if flags.DryRun {
+			return nil
+		}
+		if !helper.AskYesNoConfirm("") {
+			return fmt.Errorf("abort")
+		}
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	cntAll := len(torrents)
+	for i, torrent := range torrents {
+		if _, err := common.ArchiveTorrent(clientInstance, torrent); err != nil {
+			fmt.Printf("✕ %s : failed to archive %s: %v (%d/%d)\n", torrent.InfoHash, torrent.Name, err, i+1, cntAll)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		fmt.Printf("✓ %s : archived %s (%d/%d)\n", torrent.InfoHash, torrent.Name, i+1, cntAll)
+	}
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+}
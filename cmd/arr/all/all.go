@@ -0,0 +1,8 @@
+package all
+
+import (
+	_ "github.com/sagan/ptool/cmd/arr"
+	_ "github.com/sagan/ptool/cmd/arr/notify"
+	_ "github.com/sagan/ptool/cmd/arr/status"
+	_ "github.com/sagan/ptool/cmd/arr/wanted"
+)
@@ -0,0 +1,45 @@
+package arr
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+)
+
+var Command = &cobra.Command{
+	Use:   "arr",
+	Short: "Sonarr / Radarr integration: show wanted lists or notify them to import downloaded releases.",
+	Long: `Sonarr / Radarr integration: show wanted lists or notify them to import downloaded releases.
+To use this feature, add the Sonarr / Radarr instances to config file, e.g. :
+
+ptool.toml
+----------
+[[arrs]]
+name = 'sonarr'
+type = 'sonarr'
+url = 'http://localhost:8989'
+apiKey = 'key'
+----------
+
+The wanted lists can also be used to select torrents to download by "batchdl --arr-wanted" flag,
+and a "complete" event hook with "arr" set notifies the arr to import the completed torrent (see "watch" command).`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Command)
+}
+
+// Return the names of arrs. If names is empty, return all enabled arrs in config file.
+func ParseNames(names []string) []string {
+	if len(names) > 0 {
+		return names
+	}
+	for _, arr := range config.Get().Arrs {
+		if !arr.Disabled {
+			names = append(names, arr.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/arr"
+	arrcmd "github.com/sagan/ptool/cmd/arr"
+	"github.com/sagan/ptool/cmd/common"
+)
+
+var command = &cobra.Command{
+	Use:         "notify {arr} {path}...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "arr.notify"},
+	Short:       "Notify Sonarr / Radarr to import downloaded releases.",
+	Long: `Notify Sonarr / Radarr to import downloaded releases.
+Each {path} is a downloaded file or folder, it must be accessible by arr (the path on the arr host).
+It queues a "DownloadedEpisodesScan" (Sonarr) or "DownloadedMoviesScan" (Radarr) command in arr,
+using the "importMode" of arr in config file ("Copy" by default, which keeps the original files for seeding).`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: notify,
+}
+
+func init() {
+	arrcmd.Command.AddCommand(command)
+}
+
+func notify(cmd *cobra.Command, args []string) error {
+	arrClient, err := arr.New(args[0])
+	if err != nil {
+		return err
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	for _, path := range args[1:] {
+		if common.DryRun("notify arr %s to import %s", arrClient.Name, path) {
+			continue
+		}
+		id, err := arrClient.Notify(path)
+		if err != nil {
+			log.Errorf("Failed to notify arr %s to import %s: %v", arrClient.Name, path, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		fmt.Printf("✓ notified arr %s to import %s (command %d)\n", arrClient.Name, path, id)
+	}
+	return common.ItemsError(errorCnt, int64(len(args)-1), lastErr)
+}
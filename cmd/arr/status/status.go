@@ -0,0 +1,52 @@
+package status
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/arr"
+	arrcmd "github.com/sagan/ptool/cmd/arr"
+	"github.com/sagan/ptool/cmd/common"
+)
+
+var command = &cobra.Command{
+	Use:         "status [arr]...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "arr.status"},
+	Short:       "Test connections to Sonarr / Radarr and show their status.",
+	Long: `Test connections to Sonarr / Radarr and show their status.
+If no arr names provided, all arrs in config file are tested.`,
+	Args: cobra.MatchAll(cobra.ArbitraryArgs, cobra.OnlyValidArgs),
+	RunE: status,
+}
+
+func init() {
+	arrcmd.Command.AddCommand(command)
+}
+
+func status(cmd *cobra.Command, args []string) error {
+	names := arrcmd.ParseNames(args)
+	if len(names) == 0 {
+		return fmt.Errorf("no arr specified or found")
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	for _, name := range names {
+		arrClient, err := arr.New(name)
+		if err != nil {
+			fmt.Printf("✕arr %s: %v\n", name, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		status, err := arrClient.Status()
+		if err != nil {
+			fmt.Printf("✕arr %s (%s) test failed: %v\n", name, arrClient.Type, err)
+			errorCnt++
+			lastErr = err
+		} else {
+			fmt.Printf("✓arr %s (%s) test ok: %s %s\n", name, arrClient.Type, status.AppName, status.Version)
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(names)), lastErr)
+}
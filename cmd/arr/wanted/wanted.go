@@ -0,0 +1,87 @@
+package wanted
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/arr"
+	arrcmd "github.com/sagan/ptool/cmd/arr"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "wanted [arr]... [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "arr.wanted"},
+	Short:       "Show wanted (monitored and missing) episodes / movies of Sonarr / Radarr.",
+	Long: `Show wanted (monitored and missing) episodes / movies of Sonarr / Radarr.
+If no arr names provided, all arrs in config file are queried.
+For Radarr, only the movies that have been released (available) are shown.`,
+	Args: cobra.MatchAll(cobra.ArbitraryArgs, cobra.OnlyValidArgs),
+	RunE: wanted,
+}
+
+var (
+	showJson = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	arrcmd.Command.AddCommand(command)
+}
+
+func wanted(cmd *cobra.Command, args []string) error {
+	names := arrcmd.ParseNames(args)
+	if len(names) == 0 {
+		return fmt.Errorf("no arr specified or found")
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	list := []*arr.Wanted{}
+	for _, name := range names {
+		arrClient, err := arr.New(name)
+		if err != nil {
+			log.Errorf("Failed to create arr %s: %v", name, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		wanted, err := arrClient.Wanted()
+		if err != nil {
+			log.Errorf("Failed to get wanted list of arr %s: %v", name, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		list = append(list, wanted...)
+	}
+	if showJson {
+		if err := util.PrintJson(os.Stdout, list); err != nil {
+			return err
+		}
+	} else {
+		columns := []*util.TableColumn{
+			{Title: "Arr", Width: 10},
+			{Title: "Title"},
+			{Title: "Year", Width: 4},
+			{Title: "Season", Width: 6, RightAlign: true},
+			{Title: "Episode", Width: 7, RightAlign: true},
+			{Title: "IMDb", Width: 10},
+		}
+		rows := util.Map(list, func(w *arr.Wanted) []string {
+			season, episode, year := "-", "-", "-"
+			if w.Season > 0 || w.Episode > 0 {
+				season, episode = fmt.Sprint(w.Season), fmt.Sprint(w.Episode)
+			}
+			if w.Year > 0 {
+				year = fmt.Sprint(w.Year)
+			}
+			return []string{w.Arr, w.Title, year, season, episode, w.ImdbId}
+		})
+		util.PrintTable(os.Stdout, columns, rows, 0, false, false)
+	}
+	return common.ItemsError(errorCnt, int64(len(names)), lastErr)
+}
@@ -0,0 +1,135 @@
+package auditcmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "audit [target]... [flags]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "audit"},
+	Short:       "Show the audit log of mutating operations performed by ptool.",
+	Long: `Show the audit log of mutating operations performed by ptool.
+All operations performed by ptool that modify clients or local files are recorded into an append-only
+audit log file ("` + config.AUDIT_FILENAME + `" in the same dir of ptool.toml file), including:
+torrents added to / deleted from / modified of client, torrents save path and tracker changes,
+client config changes, local files removal and ptool config file writes. Operations in dry run mode (--dry-run) are NOT recorded.
+
+Each record has the time, action, target (client name or local file path), parameters,
+the ptool command that performed the action and the error if it failed.
+Available actions: ` + strings.Join(actions, ", ") + `.
+
+If [target]... args are provided, only show records of these targets (clients or file paths).
+
+Examples:
+  # show all torrents deleted within recent 7 days
+  ptool audit --action delete --since 7d
+
+  # show last 10 records of client "local"
+  ptool audit local --max-records 10`,
+	RunE: auditlog,
+}
+
+var actions = []string{
+	audit.ACTION_ADD_TORRENT,
+	audit.ACTION_DELETE_TORRENT,
+	audit.ACTION_MODIFY_TORRENT,
+	audit.ACTION_SET_SAVE_PATH,
+	audit.ACTION_EDIT_TRACKER,
+	audit.ACTION_SET_CONFIG,
+	audit.ACTION_REMOVE_FILE,
+	audit.ACTION_WRITE_CONFIG,
+}
+
+var (
+	showJson   = false
+	failedOnly = false
+	maxRecords = int64(0)
+	action     = ""
+	since      = ""
+	auditFile  = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&failedOnly, "failed", "", false, "Only show records of failed operations")
+	command.Flags().Int64VarP(&maxRecords, "max-records", "", 0, "Only show last n records. 0 == no limit")
+	command.Flags().StringVarP(&action, "action", "", "",
+		"Comma-separated list. Only show records of these actions. Available actions: "+strings.Join(actions, ", "))
+	command.Flags().StringVarP(&since, "since", "", "",
+		`Only show records since this time. It can be a time (e.g. "2024-01-01 00:00:00") `+
+			`or a time duration til now (e.g. "7d")`)
+	command.Flags().StringVarP(&auditFile, "audit-file", "", "",
+		"Manually specify audit log file ("+config.AUDIT_FILENAME+") path")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func auditlog(command *cobra.Command, args []string) error {
+	var filterActions []string
+	if action != "" {
+		filterActions = util.SplitCsv(action)
+		for _, action := range filterActions {
+			if !slices.Contains(actions, action) {
+				return fmt.Errorf("invalid action %q", action)
+			}
+		}
+	}
+	sinceTime := int64(0)
+	if since != "" {
+		var err error
+		if sinceTime, err = util.ParseTime(since, nil); err != nil {
+			return fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if auditFile == "" {
+		auditFile = audit.Filename()
+	}
+	records, err := audit.Load(auditFile)
+	if err != nil {
+		return err
+	}
+	records = util.Filter(records, func(record *audit.Record) bool {
+		return (len(filterActions) == 0 || slices.Contains(filterActions, record.Action)) &&
+			(len(args) == 0 || slices.Contains(args, record.Target)) &&
+			(!failedOnly || record.Error != "") && record.Ts >= sinceTime
+	})
+	if maxRecords > 0 && int64(len(records)) > maxRecords {
+		records = records[int64(len(records))-maxRecords:]
+	}
+	if showJson {
+		return util.PrintJson(os.Stdout, records)
+	}
+	fmt.Printf("%-19s  %-11s  %-15s  %s\n", "Time", "Action", "Target", "Params")
+	for _, record := range records {
+		fmt.Printf("%-19s  %-11s  %-15s  %s\n", util.FormatTime(record.Ts), record.Action, record.Target,
+			formatParams(record.Params))
+		fmt.Printf("%-19s  %-11s  %-15s  $ ptool %s\n", "", "", "", record.Command)
+		if record.Error != "" {
+			fmt.Printf("%-19s  %-11s  %-15s  Error: %s\n", "", "", "", record.Error)
+		}
+	}
+	return nil
+}
+
+// Format params to "key=value" pairs in key order.
+func formatParams(params map[string]any) string {
+	keys := []string{}
+	for key := range params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, params[key]))
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,176 @@
+package autoremove
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "autoremove {client}... [--strategy name]... [--force]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "autoremove"},
+	Short:       "Remove torrents from clients using the strategies defined in config file.",
+	Long: `Remove torrents from clients using the strategies defined in config file.
+The [[autoremoves]] strategies of config file are similar to the ones of autoremove-torrents.
+
+A strategy first selects torrents of client by "categories", "excludedCategories", "trackers",
+"excludedTrackers", "tags", "excludedTags" and "states" (all the set ones must be met).
+Then the selected torrents that meet the removal conditions are removed. The conditions are:
+"minRatio", "minSeedingTime", "minSeeders", "expr" (a filter expression, see "ptool show --help")
+and "script" (a Starlark script file that defines a "remove(torrent)" function, torrent has the same
+fields of filter expression).
+By default a torrent must meet all the set conditions ("logic = 'and'");
+if "logic = 'or'", a torrent that meets any condition is removed.
+A torrent is removed by the first strategy (in config file order) that decides to remove it.
+
+Content files of removed torrents are also deleted from disk, unless "preserveFiles" of strategy is true
+or other xseed torrent (with the same content path) exists in client.
+
+It displays every torrent to remove with the decision of conditions, and asks for confirmation
+unless --force flag is set. Use --dry-run flag to only display decisions. Use -v flag to also display
+the decisions of selected torrents that are kept.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: autoremove,
+}
+
+var (
+	force      = false
+	strategies []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().StringArrayVarP(&strategies, "strategy", "", nil,
+		"Only use the strategies of these names. By default all enabled strategies are used")
+	cmd.RootCmd.AddCommand(command)
+}
+
+// A torrent to remove.
+type removal struct {
+	torrent  *client.Torrent
+	strategy *strategy
+	reason   string
+}
+
+func autoremove(cmd *cobra.Command, args []string) error {
+	var allStrategies []*strategy
+	for _, strategyConfig := range config.Get().Autoremoves {
+		if len(strategies) > 0 {
+			if !slices.Contains(strategies, strategyConfig.Name) {
+				continue
+			}
+		} else if strategyConfig.Disabled {
+			continue
+		}
+		s, err := newStrategy(strategyConfig)
+		if err != nil {
+			return fmt.Errorf("invalid strategy %s: %w", strategyConfig.Name, err)
+		}
+		allStrategies = append(allStrategies, s)
+	}
+	for _, name := range strategies {
+		if !slices.ContainsFunc(allStrategies, func(s *strategy) bool { return s.Name == name }) {
+			return fmt.Errorf("strategy %s not found", name)
+		}
+	}
+	if len(allStrategies) == 0 {
+		return fmt.Errorf("no enabled [[autoremoves]] strategies defined in config file")
+	}
+	for _, clientName := range args {
+		if config.GetClientConfig(clientName) == nil {
+			return fmt.Errorf("client %s not found", clientName)
+		}
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	for _, clientName := range args {
+		if err := autoremoveClient(clientName, allStrategies); err != nil {
+			log.Errorf("Client %s: %v", clientName, err)
+			errorCnt++
+			lastErr = err
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(args)), lastErr)
+}
+
+func autoremoveClient(clientName string, allStrategies []*strategy) error {
+	clientStrategies := util.Filter(allStrategies, func(s *strategy) bool { return s.appliesToClient(clientName) })
+	if len(clientStrategies) == 0 {
+		log.Infof("Client %s: no strategies apply", clientName)
+		return nil
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+	now := time.Now().Unix()
+	removals := []*removal{}
+	for _, torrent := range torrents {
+		for _, s := range clientStrategies {
+			if !s.selects(torrent) {
+				continue
+			}
+			remove, reason := s.decide(torrent, now)
+			if !remove {
+				log.Infof("Keep %s (%s): strategy %s: %s", torrent.InfoHash, torrent.Name, s.Name, reason)
+				continue
+			}
+			removals = append(removals, &removal{torrent: torrent, strategy: s, reason: reason})
+			break
+		}
+	}
+	if len(removals) == 0 {
+		fmt.Printf("Client %s: no torrents to remove (%d torrents checked)\n", clientName, len(torrents))
+		return nil
+	}
+	fmt.Printf("Client %s: %d torrents to remove (%d torrents checked):\n", clientName, len(removals), len(torrents))
+	for _, r := range removals {
+		fmt.Printf("%s  %s  %s\n  strategy %s (preserve files: %t): %s\n",
+			r.torrent.InfoHash, util.BytesSize(float64(r.torrent.Size)), r.torrent.Name,
+			r.strategy.Name, r.strategy.PreserveFiles, r.reason)
+	}
+	if flags.DryRun {
+		fmt.Printf("Dry run. %d torrents would be removed\n", len(removals))
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will remove above %d torrents from client %s",
+		len(removals), clientName)) {
+		return fmt.Errorf("abort")
+	}
+	var preserveFilesInfoHashes, infoHashes []string
+	for _, r := range removals {
+		if r.strategy.PreserveFiles {
+			preserveFilesInfoHashes = append(preserveFilesInfoHashes, r.torrent.InfoHash)
+		} else {
+			infoHashes = append(infoHashes, r.torrent.InfoHash)
+		}
+	}
+	if len(preserveFilesInfoHashes) > 0 {
+		if err := clientInstance.DeleteTorrents(preserveFilesInfoHashes, false); err != nil {
+			return fmt.Errorf("failed to delete torrents: %w", err)
+		}
+	}
+	if len(infoHashes) > 0 {
+		if err := client.DeleteTorrentsAuto(clientInstance, infoHashes); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Client %s: removed %d torrents\n", clientName, len(removals))
+	return nil
+}
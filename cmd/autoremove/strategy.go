@@ -0,0 +1,157 @@
+package autoremove
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
+	"github.com/sagan/ptool/util/script"
+)
+
+// A parsed autoremove strategy of config file.
+type strategy struct {
+	*config.AutoremoveConfigStruct
+	minSeedingTime int64 // seconds
+	states         []string
+	expr           *filterexpr.Expr
+	script         *script.Script
+}
+
+// The result of a removal condition of a strategy on a torrent.
+type condition struct {
+	desc string // e.g. "ratio 2.35 >= 2"
+	met  bool
+}
+
+func newStrategy(strategyConfig *config.AutoremoveConfigStruct) (s *strategy, err error) {
+	s = &strategy{AutoremoveConfigStruct: strategyConfig}
+	if s.Logic != "" && s.Logic != config.AUTOREMOVE_LOGIC_AND && s.Logic != config.AUTOREMOVE_LOGIC_OR {
+		return nil, fmt.Errorf("invalid logic %q", s.Logic)
+	}
+	if s.MinSeedingTime != "" {
+		if s.minSeedingTime, err = util.ParseTimeDuration(s.MinSeedingTime); err != nil {
+			return nil, fmt.Errorf("invalid minSeedingTime: %w", err)
+		}
+	}
+	for _, state := range s.States {
+		if !strings.HasPrefix(state, "_") {
+			state = "_" + state
+		}
+		if !client.IsValidStateFilter(state) {
+			return nil, fmt.Errorf("invalid state %q", state)
+		}
+		s.states = append(s.states, state)
+	}
+	if s.Expr != "" {
+		if s.expr, err = client.ParseTorrentExpr(s.Expr); err != nil {
+			return nil, fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+	if s.Script != "" {
+		if s.script, err = script.Load(config.ResolvePath(s.Script)); err != nil {
+			return nil, err
+		}
+		if !s.script.Has("remove") {
+			return nil, fmt.Errorf("script %s does NOT define remove function", s.Script)
+		}
+	}
+	if s.MinRatio <= 0 && s.minSeedingTime <= 0 && s.MinSeeders <= 0 && s.expr == nil && s.script == nil {
+		// otherwise all selected torrents would be removed
+		return nil, fmt.Errorf("no removal condition set")
+	}
+	return s, nil
+}
+
+func (s *strategy) appliesToClient(clientName string) bool {
+	return len(s.Clients) == 0 || slices.Contains(s.Clients, clientName)
+}
+
+// Return true if torrent is selected by the strategy, i.e. it meets all set selection conditions.
+func (s *strategy) selects(torrent *client.Torrent) bool {
+	matchCategory := func(category string) bool {
+		if category == constants.NONE {
+			return torrent.Category == ""
+		}
+		return torrent.Category == category
+	}
+	if len(s.Categories) > 0 && !slices.ContainsFunc(s.Categories, matchCategory) ||
+		slices.ContainsFunc(s.ExcludedCategories, matchCategory) ||
+		len(s.Trackers) > 0 && !slices.ContainsFunc(s.Trackers, torrent.MatchTracker) ||
+		slices.ContainsFunc(s.ExcludedTrackers, torrent.MatchTracker) ||
+		len(s.Tags) > 0 && !slices.ContainsFunc(s.Tags, torrent.HasTag) ||
+		slices.ContainsFunc(s.ExcludedTags, torrent.HasTag) ||
+		len(s.states) > 0 && !slices.ContainsFunc(s.states, torrent.MatchStateFilter) {
+		return false
+	}
+	return true
+}
+
+// Evaluate the removal conditions on torrent.
+// Return whether the torrent should be removed, and the description of the decision.
+func (s *strategy) decide(torrent *client.Torrent, now int64) (remove bool, reason string) {
+	conditions := []*condition{}
+	if s.MinRatio > 0 {
+		ratio := torrent.Ratio()
+		conditions = append(conditions, &condition{
+			desc: fmt.Sprintf("ratio %.2f >= %g", ratio, s.MinRatio),
+			met:  ratio >= s.MinRatio,
+		})
+	}
+	if s.minSeedingTime > 0 {
+		seedingTime := int64(0)
+		if torrent.IsComplete() && torrent.Ctime > 0 {
+			seedingTime = max(now-torrent.Ctime, 0)
+		}
+		conditions = append(conditions, &condition{
+			desc: fmt.Sprintf("seeding time %s >= %s",
+				util.GetDurationString(seedingTime), util.GetDurationString(s.minSeedingTime)),
+			met: seedingTime >= s.minSeedingTime,
+		})
+	}
+	if s.MinSeeders > 0 {
+		conditions = append(conditions, &condition{
+			desc: fmt.Sprintf("seeders %d >= %d", torrent.Seeders, s.MinSeeders),
+			met:  torrent.Seeders >= s.MinSeeders,
+		})
+	}
+	if s.expr != nil {
+		conditions = append(conditions, &condition{
+			desc: fmt.Sprintf("expr %q", s.Expr),
+			met:  torrent.MatchExpr(s.expr),
+		})
+	}
+	if s.script != nil {
+		met, err := s.script.CallBool("remove", torrent.ScriptValue())
+		if err != nil {
+			// a script error never leads to removal, regardless of other conditions
+			log.Warnf("Failed to check torrent %s using script: %v", torrent.Name, err)
+			return false, fmt.Sprintf("✕ script %q error: %v", s.Script, err)
+		}
+		conditions = append(conditions, &condition{desc: fmt.Sprintf("script %q", s.Script), met: met})
+	}
+	if s.Logic == config.AUTOREMOVE_LOGIC_OR {
+		remove = slices.ContainsFunc(conditions, func(c *condition) bool { return c.met })
+	} else {
+		remove = !slices.ContainsFunc(conditions, func(c *condition) bool { return !c.met })
+	}
+	descs := []string{}
+	for _, c := range conditions {
+		if c.met {
+			descs = append(descs, "✓ "+c.desc)
+		} else {
+			descs = append(descs, "✕ "+c.desc)
+		}
+	}
+	logic := s.Logic
+	if logic == "" {
+		logic = config.AUTOREMOVE_LOGIC_AND
+	}
+	return remove, fmt.Sprintf("%s (%s)", strings.Join(descs, ", "), logic)
+}
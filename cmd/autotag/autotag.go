@@ -0,0 +1,212 @@
+package autotag
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+var command = &cobra.Command{
+	Use:         "autotag {client}... [--rule name]... [--interval seconds]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "autotag"},
+	Short:       "Add tags / set category of client torrents by tracker, using the rules defined in config file.",
+	Long: `Add tags / set category of client torrents by tracker, using the rules defined in config file.
+
+A [[autotags]] rule of config file matches torrents by "trackers" (tracker domains or urls).
+A domain also matches it's sub domains, e.g. "hdsky.me" or "*.hdsky.me" matches "pt.hdsky.me".
+A rule can also (or instead) match torrents by the release info parsed from torrent name:
+"groups" (release groups), "resolutions" (e.g. "2160p") and "sources" (e.g. "BluRay", "WEB-DL", "Remux").
+All conditions set in a rule must be matched.
+The "tags" of rule are added to matched torrents. The "category" of rule is set to matched torrents that
+do not have a category, or to all matched torrents if "overwriteCategory" of rule is true.
+If multiple rules set the category of a torrent, the first one (in config file order) is used.
+
+By default it processes the clients once and exits. If --interval flag is set, it runs forever and
+processes the clients periodically. To run it in background, use --fork flag.
+Use --dry-run flag to only display the changes.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: autotag,
+}
+
+var (
+	interval = int64(0)
+	rules    []string
+)
+
+func init() {
+	command.Flags().Int64VarP(&interval, "interval", "", 0,
+		"Run forever and process clients every this seconds. 0 == process clients only once")
+	command.Flags().StringArrayVarP(&rules, "rule", "", nil,
+		"Only use the rules of these names. By default all enabled rules are used")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func autotag(cmd *cobra.Command, args []string) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid interval %d", interval)
+	}
+	var allRules []*config.AutotagConfigStruct
+	for _, rule := range config.Get().Autotags {
+		if len(rules) > 0 {
+			if !slices.Contains(rules, rule.Name) {
+				continue
+			}
+		} else if rule.Disabled {
+			continue
+		}
+		if len(rule.Trackers) == 0 && len(rule.Groups) == 0 && len(rule.Resolutions) == 0 && len(rule.Sources) == 0 {
+			return fmt.Errorf("invalid rule %s: trackers (or groups / resolutions / sources) not set", rule.Name)
+		}
+		allRules = append(allRules, rule)
+	}
+	for _, name := range rules {
+		if !slices.ContainsFunc(allRules, func(r *config.AutotagConfigStruct) bool { return r.Name == name }) {
+			return fmt.Errorf("rule %s not found", name)
+		}
+	}
+	if len(allRules) == 0 {
+		return fmt.Errorf("no enabled [[autotags]] rules defined in config file")
+	}
+	for _, clientName := range args {
+		if config.GetClientConfig(clientName) == nil {
+			return fmt.Errorf("client %s not found", clientName)
+		}
+	}
+
+	for {
+		errorCnt := int64(0)
+		var lastErr error
+		for _, clientName := range args {
+			if err := autotagClient(clientName, allRules); err != nil {
+				log.Errorf("Client %s: %v", clientName, err)
+				errorCnt++
+				lastErr = err
+			}
+		}
+		if interval == 0 {
+			return common.ItemsError(errorCnt, int64(len(args)), lastErr)
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+func autotagClient(clientName string, allRules []*config.AutotagConfigStruct) error {
+	clientRules := util.Filter(allRules, func(r *config.AutotagConfigStruct) bool {
+		return len(r.Clients) == 0 || slices.Contains(r.Clients, clientName)
+	})
+	if len(clientRules) == 0 {
+		log.Infof("Client %s: no rules apply", clientName)
+		return nil
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	clientInstance.PurgeCache()
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+	// tag / category => info-hashes of torrents to add / set
+	tagTorrents := map[string][]string{}
+	categoryTorrents := map[string][]string{}
+	for _, torrent := range torrents {
+		categorySet := false
+		release := releasename.Parse(torrent.Name)
+		for _, rule := range clientRules {
+			if !matchRule(rule, torrent, release) {
+				continue
+			}
+			for _, tag := range rule.Tags {
+				if !torrent.HasTag(tag) && !slices.Contains(tagTorrents[tag], torrent.InfoHash) {
+					tagTorrents[tag] = append(tagTorrents[tag], torrent.InfoHash)
+				}
+			}
+			if rule.Category != "" && !categorySet {
+				categorySet = true
+				if torrent.Category == "" || rule.OverwriteCategory && torrent.Category != rule.Category {
+					categoryTorrents[rule.Category] = append(categoryTorrents[rule.Category], torrent.InfoHash)
+				}
+			}
+		}
+	}
+	if len(tagTorrents) == 0 && len(categoryTorrents) == 0 {
+		log.Infof("Client %s: no changes (%d torrents checked)", clientName, len(torrents))
+		return nil
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	if len(categoryTorrents) > 0 && !flags.DryRun {
+		categories, err := clientInstance.GetCategories()
+		if err != nil {
+			return fmt.Errorf("failed to get categories: %w", err)
+		}
+		for category := range categoryTorrents {
+			if !slices.ContainsFunc(categories, func(c *client.TorrentCategory) bool { return c.Name == category }) {
+				if err := clientInstance.MakeCategory(category, ""); err != nil {
+					return fmt.Errorf("failed to create category %s: %w", category, err)
+				}
+			}
+		}
+	}
+	for _, category := range util.MapKeys(categoryTorrents) {
+		infoHashes := categoryTorrents[category]
+		fmt.Printf("Client %s: set category %q of %d torrents\n", clientName, category, len(infoHashes))
+		if flags.DryRun {
+			continue
+		}
+		if err := clientInstance.SetTorrentsCatetory(infoHashes, category); err != nil {
+			log.Errorf("Client %s: failed to set category %q: %v", clientName, category, err)
+			errorCnt++
+			lastErr = err
+		}
+	}
+	for _, tag := range util.MapKeys(tagTorrents) {
+		infoHashes := tagTorrents[tag]
+		fmt.Printf("Client %s: add tag %q to %d torrents\n", clientName, tag, len(infoHashes))
+		if flags.DryRun {
+			continue
+		}
+		if err := clientInstance.AddTagsToTorrents(infoHashes, []string{tag}); err != nil {
+			log.Errorf("Client %s: failed to add tag %q: %v", clientName, tag, err)
+			errorCnt++
+			lastErr = err
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(categoryTorrents)+len(tagTorrents)), lastErr)
+}
+
+// Report whether torrent matches all conditions of rule. release: parsed from torrent name.
+func matchRule(rule *config.AutotagConfigStruct, torrent *client.Torrent, release *releasename.Release) bool {
+	if len(rule.Trackers) > 0 && !slices.ContainsFunc(rule.Trackers, torrent.MatchTrackerOrSubdomain) {
+		return false
+	}
+	for _, condition := range []struct {
+		values []string
+		value  string
+	}{
+		{rule.Groups, release.Group},
+		{rule.Resolutions, release.Resolution},
+		{rule.Sources, release.Source},
+	} {
+		if len(condition.values) > 0 && !slices.ContainsFunc(condition.values, func(value string) bool {
+			return condition.value != "" && strings.EqualFold(value, condition.value)
+		}) {
+			return false
+		}
+	}
+	return true
+}
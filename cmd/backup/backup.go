@@ -0,0 +1,148 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+var command = &cobra.Command{
+	Use: "backup {client} --out {filename} [--category category] [--tag tag] [--filter filter] " +
+		"[infoHash]...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "backup"},
+	Short:       "Backup torrents and settings of client to a archive file.",
+	Long: fmt.Sprintf(`Backup torrents and settings of client to a archive file.
+%s.
+If no torrent or filter is provided, all torrents in client are backed up.
+
+The backup archive is a tar file which contains:
+- The .torrent (metainfo) files of torrents.
+- Per-torrent settings: state (paused or not), save path, category, tags, speed limits and trackers.
+- Client categories (with save paths) and tags.
+- Client preferences: %s.
+
+The compression format of archive is determined by the extension of filename:
+".tar.zst" : zstd; ".tar.gz" or ".tgz" : gzip; otherwise: no compression.
+
+Use "ptool restore" command to restore the backup to the same or a different client.
+
+Examples:
+  ptool backup local --out local-backup.tar.zst`, constants.HELP_INFOHASH_ARGS,
+		strings.Join(client.BackupPreferences, ", ")),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: backup,
+}
+
+var (
+	category = ""
+	tag      = ""
+	filter   = ""
+	output   = ""
+)
+
+func init() {
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&output, "out", "", "", `(Required) Output backup archive filename. `+
+		`E.g. "backup.tar.zst"`)
+	command.MarkFlagRequired("out")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func backup(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		infoHashes = []string{"_all"}
+	} else if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+
+	manifest := &client.BackupManifest{
+		Version:     client.BACKUP_VERSION,
+		Client:      clientName,
+		ClientType:  clientInstance.GetClientConfig().Type,
+		Time:        time.Now().Unix(),
+		Preferences: map[string]string{},
+	}
+	for _, name := range client.BackupPreferences {
+		value, err := clientInstance.GetConfig(name)
+		if err != nil {
+			return fmt.Errorf("failed to get client preference %s: %w", name, err)
+		}
+		manifest.Preferences[name] = value
+	}
+	if manifest.Categories, err = clientInstance.GetCategories(); err != nil {
+		return fmt.Errorf("failed to get client categories: %w", err)
+	}
+	if manifest.Tags, err = clientInstance.GetTags(); err != nil {
+		return fmt.Errorf("failed to get client tags: %w", err)
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	cntAll := len(torrents)
+	torrentContents := map[string][]byte{}
+	for i, torrent := range torrents {
+		content, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
+		if err != nil {
+			fmt.Printf("✕ %s : failed to export %s: %v (%d/%d)\n", torrent.InfoHash, torrent.Name, err, i+1, cntAll)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+		if err != nil {
+			fmt.Printf("✕ %s : failed to get trackers: %v (%d/%d)\n", torrent.InfoHash, err, i+1, cntAll)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		torrentContents[torrent.InfoHash] = content
+		backupTorrent := &client.BackupTorrent{
+			InfoHash:           torrent.InfoHash,
+			Name:               torrent.Name,
+			State:              torrent.State,
+			SavePath:           torrent.SavePath,
+			Category:           torrent.Category,
+			Tags:               torrent.Tags,
+			Meta:               torrent.Meta,
+			DownloadSpeedLimit: torrent.DownloadSpeedLimit,
+			UploadSpeedLimit:   torrent.UploadedSpeedLimit,
+			SequentialDownload: torrent.SequentialDownload,
+		}
+		for _, tracker := range trackers {
+			backupTorrent.Trackers = append(backupTorrent.Trackers, tracker.Url)
+		}
+		manifest.Torrents = append(manifest.Torrents, backupTorrent)
+		log.Debugf("Backup torrent %s (%s) (%d/%d)", torrent.InfoHash, torrent.Name, i+1, cntAll)
+	}
+	if err := client.WriteBackup(output, manifest, torrentContents); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	fmt.Printf("Backed up %d torrents of client %s to %s\n", len(manifest.Torrents), clientName, output)
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+}
@@ -5,26 +5,35 @@ package batchdl
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/arr"
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/metadata"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
+	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
 )
 
+// The sort of site torrents that is done locally by ptool. See fetchTorrentsSortedByExpiry.
+const SORT_EXPIRY = "expiry"
+
 var command = &cobra.Command{
-	Use:         "batchdl {site} [--download | --add-client client] [--base-url torrents_page_url]",
+	Use:         "batchdl {site} [--download | --add-client client | --stage-dir dir] [--base-url torrents_page_url]",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "batchdl"},
 	Aliases:     []string{"ebookgod"},
 	Short:       "Batch display or download torrents from a site.",
@@ -33,10 +42,32 @@ By default it displays all non-dead torrents of site that have not been download
 one page by page infinitely, until reachs the end of all site torrents. Press Ctrl+C to stop in the middle.
 If --download flag is set, it will download found torrents to dir specified by "--download dir" flag (default ".").
 If --add-client flag is set, it will directly add found torrents to the specified client.
+If a [[seasonPacks]] rule of config file applies to the category of added torrents, single episode torrents
+that are superseded by a season pack already in client are skipped (if "skipEpisodes" of rule is true),
+and the episode torrents in client superseded by an added season pack are reported (see "ptool watch").
+If --stage-dir flag is set, it will download found torrents to the staging dir, to be added to client later.
 
 For the format of displayed torrents list, see help of "ptool search" command.
 
 To query site torrents by any other order than size asc, use "--sort" and "--order" flags.
+Specially, "--sort expiry" sorts torrents by the discount (e.g. free) end time, the soonest expiring first
+(torrents which discount is not time-limited, or have no discount, are the last). As sites do not support it,
+all pages (or only the first "--max-pages" pages) of site torrents (in site default order) are fetched first
+and sorted locally, and resuming using "--start-page" is not supported.
+
+Bulk-grabbing can be bounded using "--max-torrents" (or "--max-count"), "--max-total-size",
+"--published-after" and "--published-before" flags.
+
+To filter torrents by expression, use "--expr" flag, e.g. "--expr 'rating >= 7.5 && year >= 2010'".
+The media details fields require "--details" flag, which fetches the details page of every candidate torrent,
+and the metadata (title, year, ratings) fields require "--metadata" flag, which resolves IMDb / TMDb metadata
+of every candidate torrent from its name (see help of "ptool search" command). Both send extra requests
+for every torrent that passes all other filters.
+
+To only download torrents that are wanted by Sonarr / Radarr, use "--arr-wanted" flag, e.g. "--arr-wanted sonarr".
+The wanted (monitored and missing) lists of the arrs are fetched first, then a torrent is selected only if its
+parsed release name matches a wanted movie, or a missing episode (or season pack / complete series of it) of
+a wanted show (see "ptool arr wanted").
 
 It supports resuming from the page that last time this command is interrupted,
 using "--start-page" flag, set it to the "LastPage" value last time this command outputed in the end.
@@ -59,7 +90,29 @@ It will output the summary of downloads result in the end:
 * AllTorrents : All torrents fetched, including not-downloaded (skipped)
 * LastPage : The last processed site page. To continue (resume) downloading torrents from here,
   run the same command again with "--start-page page" flag set to this value
-* ErrorCnt : Count of all types of errors (failed to download torrent or add torrent to client)`,
+* ErrorCnt : Count of all types of errors (failed to download torrent or add torrent to client)
+
+When adding torrents to client, a torrent that already exists in client is skipped by default,
+use "--if-exists" flag to treat it as an error or add the trackers of torrent to the existing one instead.
+
+Queue mode:
+If "--queue" flag is set (must be used with "--add-client"), found torrents are not added to client at once.
+Instead, they are queued, and after all pages are processed, ptool keeps running and checks the queue every
+"--queue-interval" seconds, and starts (downloads and adds to client) queued torrents when:
+* "--queue-free" flag is set and the torrent becomes free (e.g. the site free-leech promotion starts).
+  The site pages where queued torrents were found are re-fetched to update their discount status.
+  If "--free-time" flag is also set, the remaining free time of torrent must be at least that value.
+* "--queue-idle-speed" flag is set and the client is idle (current download speed < that value).
+  Only one queued torrent is started on each check, in the order they were found.
+The queued torrents that are not started in "--queue-timeout" time are dropped.
+The "--max-total-size" and "--max-torrents" limits also apply to (count) queued torrents.
+
+Staging:
+If "--stage-dir" flag is set, found torrents are downloaded to the staging dir, with the category, tags & save path
+that would be used when adding them to client ("--add-*" flags) encoded into their 'comment' field.
+If "--stage-hook" flag is also set, the command is executed for each staged torrent to validate it;
+rejected torrents are renamed to *.fail. After inspecting the staging dir, add staged torrents to client by:
+  ptool addlocal <client> <dir> --use-comment-meta --rename-added`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	RunE: batchdl,
 }
@@ -71,14 +124,21 @@ var (
 	skipExisting       = false
 	downloadAll        = false
 	onePage            = false
+	fetchDetails       = false
+	lookupMetadata     = false
 	addPaused          = false
 	dense              = false
 	addRespectNoadd    = false
+	checkContent       = false
+	noFilePriority     = false
 	includeDownloaded  = false
 	onlyDownloaded     = false
 	freeOnly           = false
 	noPaid             = false
+	maxBonusCost       = float64(0)
 	noNeutral          = false
+	queueMode          = false
+	queueFree          = false
 	nohr               = false
 	allowBreak         = false
 	addCategoryAuto    = false
@@ -87,23 +147,36 @@ var (
 	newestFlag         = false
 	saveAppend         = false
 	maxTorrents        = int64(0)
+	maxCount           = int64(0)
+	maxPages           = int64(0)
 	minSeeders         = int64(0)
 	maxSeeders         = int64(0)
 	maxConsecutiveFail = int64(0)
+	queueInterval      = int64(0)
+	announceInterval   = int64(0)
 	addCategory        = ""
 	addClient          = ""
 	addTags            = ""
 	tag                = ""
 	filter             = ""
+	expr               = ""
+	arrWanted          = ""
 	excludes           = ""
 	addSavePath        = ""
 	minTorrentSizeStr  = ""
 	maxTorrentSizeStr  = ""
 	maxTotalSizeStr    = ""
 	freeTimeAtLeastStr = ""
+	discountStr        = ""
+	queueIdleSpeedStr  = ""
+	queueTimeoutStr    = ""
 	publishedAfterStr  = ""
+	publishedBeforeStr = ""
 	startPage          = ""
 	downloadDir        = ""
+	stageDir           = ""
+	stageHook          = ""
+	ifExists           = ""
 	baseUrl            = ""
 	rename             = ""
 	sortFlag           = ""
@@ -131,6 +204,7 @@ func init() {
 	command.Flags().BoolVarP(&dense, "dense", "d", false, "Dense mode: show full torrent title & subtitle")
 	command.Flags().BoolVarP(&freeOnly, "free", "", false, "Skip none-free torrent")
 	command.Flags().BoolVarP(&noPaid, "no-paid", "", false, "Skip paid (cost bonus points) torrent")
+	command.Flags().Float64VarP(&maxBonusCost, "max-bonus-cost", "", -1, constants.HELP_ARG_MAX_BONUS_COST)
 	command.Flags().BoolVarP(&noNeutral, "no-neutral", "", false,
 		"Skip neutral (do not count uploading & downloading & seeding bonus) torrent")
 	command.Flags().BoolVarP(&largestFlag, "largest", "l", false,
@@ -143,6 +217,19 @@ func init() {
 	command.Flags().BoolVarP(&addRespectNoadd, "add-respect-noadd", "", false,
 		`Used with "--add-client". Check and respect "`+config.NOADD_TAG+
 			`" flag tag in client. If the tag exists in client, skip the execution (do not add any torrent to client)`)
+	command.Flags().BoolVarP(&queueMode, "queue", "", false,
+		`Used with "--add-client". Queue found torrents, start them when free or client idle. See queue mode`)
+	command.Flags().BoolVarP(&queueFree, "queue-free", "", false,
+		`Used with "--queue". Start queued torrent when it becomes free`)
+	command.Flags().Int64VarP(&queueInterval, "queue-interval", "", 300,
+		`Used with "--queue". The interval (seconds) between checks of queued torrents`)
+	command.Flags().Int64VarP(&announceInterval, "announce-interval", "", 0,
+		`Used with "--add-client". `+constants.HELP_ARG_ANNOUNCE_INTERVAL)
+	command.Flags().StringVarP(&queueIdleSpeedStr, "queue-idle-speed", "", "-1",
+		`Used with "--queue". Start a queued torrent when client download speed is lower than (<) this value. `+
+			`-1 == disabled`)
+	command.Flags().StringVarP(&queueTimeoutStr, "queue-timeout", "", "1d",
+		`Used with "--queue". Drop the queued torrents that are not started in this time`)
 	command.Flags().BoolVarP(&nohr, "no-hr", "", false,
 		"Skip torrent that has any type of HnR (Hit and Run) restriction")
 	command.Flags().BoolVarP(&allowBreak, "break", "", false,
@@ -170,11 +257,26 @@ func init() {
 	command.Flags().Int64VarP(&maxConsecutiveFail, "max-consecutive-fail", "", 3,
 		"Stop after consecutive fails to download torrent from site of this times. -1 == no limit (never stop)")
 	command.Flags().StringVarP(&freeTimeAtLeastStr, "free-time", "", "",
-		"Used with --free. Set the allowed minimal remaining torrent free time. e.g. 12h, 1d")
+		"Used with --free or --discount. Set the allowed minimal remaining torrent free (discount) time. "+
+			"Torrents which discount is not time-limited always pass. e.g. 12h, 1d")
+	command.Flags().StringVarP(&discountStr, "discount", "", "", constants.HELP_ARG_DISCOUNT)
+	command.Flags().Int64VarP(&maxCount, "max-count", "", -1, `Alias of "--max-torrents"`)
+	command.Flags().Int64VarP(&maxPages, "max-pages", "", -1,
+		`Used with "--sort expiry". Number limit of site pages fetched. -1 == no limit`)
+	command.Flags().StringVarP(&publishedBeforeStr, "published-before", "", "",
+		`If set, only display or download torrent that was published before (<) this. `+constants.HELP_ARG_TIMES)
 	command.Flags().StringVarP(&publishedAfterStr, "published-after", "", "",
 		`If set, only display or download torrent that was published after (>=) this. `+constants.HELP_ARG_TIMES)
 	command.Flags().StringVarP(&filter, "filter", "", "",
 		"If set, only display or download torrent which title or subtitle contains this string")
+	command.Flags().StringVarP(&expr, "expr", "", "", constants.HELP_ARG_SITE_TORRENT_EXPR)
+	command.Flags().BoolVarP(&fetchDetails, "details", "", false,
+		"Fetch and parse the details page of every candidate torrent. It sends one request per torrent")
+	command.Flags().BoolVarP(&lookupMetadata, "metadata", "", false,
+		"Resolve IMDb / TMDb metadata (ids, ratings and year) of every candidate torrent")
+	command.Flags().StringVarP(&arrWanted, "arr-wanted", "", "",
+		"Comma-separated list of Sonarr / Radarr (arrs in config file). If set, only display or download torrent "+
+			"that is in the wanted list of any one of them")
 	command.Flags().StringVarP(&tag, "tag", "", "",
 		"Comma-separated list. If set, only display or download torrent which tags contain any one in the list")
 	command.Flags().StringArrayVarP(&includes, "include", "", nil,
@@ -188,12 +290,14 @@ func init() {
 	command.Flags().StringVarP(&downloadDir, "download-dir", "", ".",
 		`Used with "--download". Set the local dir of downloaded torrents. Default == current dir`)
 	command.Flags().StringVarP(&addClient, "add-client", "", "", `Add found torrents to this client`)
+	command.Flags().StringVarP(&stageDir, "stage-dir", "", "", constants.HELP_ARG_STAGE_DIR)
+	command.Flags().StringVarP(&stageHook, "stage-hook", "", "", constants.HELP_ARG_STAGE_HOOK)
 	command.Flags().StringVarP(&addCategory, "add-category", "", "",
-		`Used with "--add-client". Set the category when adding torrent to client`)
+		`Used with "--add-client" or "--stage-dir". Set the category when adding torrent to client`)
 	command.Flags().StringVarP(&addTags, "add-tags", "", "",
-		`Used with "--add-client". Set the tags when adding torrent to client (comma-separated)`)
+		`Used with "--add-client" or "--stage-dir". Set the tags when adding torrent to client (comma-separated)`)
 	command.Flags().StringVarP(&addSavePath, "add-save-path", "", "",
-		`Used with "--add-client". Set contents save path of added torrents`)
+		`Used with "--add-client" or "--stage-dir". Set contents save path of added torrents`)
 	command.Flags().StringVarP(&baseUrl, "base-url", "", "",
 		`Manually set the base url of torrents list page. e.g. "special.php", "torrents.php?cat=100"`)
 	command.Flags().StringVarP(&rename, "rename", "", "", "Rename downloaded or added torrents (supports variables)")
@@ -208,6 +312,11 @@ func init() {
 			"If --save-append flag is not set, file will be truncated and the whole contents of it will be a "+
 			"valid json of array of torrent objects; If --save-append flag is set, each line of the file will be "+
 			"json of torrent object")
+	cmd.AddEnumFlagP(command, &ifExists, "if-exists", "", common.IfExistsFlag)
+	command.Flags().BoolVarP(&noFilePriority, "no-file-priority", "", false, `Used with "--add-client". `+
+		`Do not apply "filePriorities" rules of config to added torrents`)
+	command.Flags().BoolVarP(&checkContent, "check-existing-content", "", false, `Used with "--add-client". `+
+		"Also treat the client torrent which has identical contents but different info-hash as existing")
 	cmd.AddEnumFlagP(command, &sortFlag, "sort", "", common.SiteTorrentSortFlag)
 	cmd.AddEnumFlagP(command, &orderFlag, "order", "", common.OrderFlag)
 	cmd.RootCmd.AddCommand(command)
@@ -230,21 +339,52 @@ func batchdl(command *cobra.Command, args []string) error {
 	if util.CountNonZeroVariables(largestFlag, latestFlag, newestFlag) > 1 {
 		return fmt.Errorf("--largest, --latest and --newest flags are NOT compatible")
 	}
-	if util.CountNonZeroVariables(doDownload, addClient) > 1 {
-		return fmt.Errorf("--download and --add-client flags are NOT compatible")
+	if util.CountNonZeroVariables(doDownload, addClient, stageDir) > 1 {
+		return fmt.Errorf("--download, --add-client and --stage-dir flags are NOT compatible")
 	}
 	if !doDownload && (skipExisting || downloadDir != ".") {
 		return fmt.Errorf(`found flags that are can only be used with "--download"`)
-	} else if addClient == "" && util.CountNonZeroVariables(
-		addCategoryAuto, addCategory, addClient, addPaused, addRespectNoadd, addSavePath) > 0 {
+	} else if addClient == "" && util.CountNonZeroVariables(addPaused, addRespectNoadd, checkContent) > 0 {
 		return fmt.Errorf(`found flags that are can only be used with "--add-client"`)
+	} else if addClient == "" && stageDir == "" &&
+		util.CountNonZeroVariables(addCategoryAuto, addCategory, addSavePath) > 0 {
+		return fmt.Errorf(`found flags that are can only be used with "--add-client" or "--stage-dir"`)
+	} else if stageDir == "" && stageHook != "" {
+		return fmt.Errorf(`found flags that are can only be used with "--stage-dir"`)
 	}
-	if !doDownload && addClient == "" && (saveOkFilename != "" || saveFailFilename != "") {
-		return fmt.Errorf(`found flags that are can only be used with "--download" or "--add-client"`)
+	if !doDownload && addClient == "" && stageDir == "" && (saveOkFilename != "" || saveFailFilename != "") {
+		return fmt.Errorf(`found flags that are can only be used with "--download", "--add-client" or "--stage-dir"`)
+	}
+	queueIdleSpeed, err := util.RAMInBytes(queueIdleSpeedStr)
+	if err != nil {
+		return fmt.Errorf("invalid queue-idle-speed: %w", err)
+	}
+	queueTimeout, err := util.ParseTimeDuration(queueTimeoutStr)
+	if err != nil {
+		return fmt.Errorf("invalid queue-timeout: %w", err)
+	}
+	if queueMode {
+		if addClient == "" {
+			return fmt.Errorf(`--queue flag can only be used with "--add-client"`)
+		}
+		if !queueFree && queueIdleSpeed < 0 {
+			return fmt.Errorf(`--queue flag must be used with "--queue-free" or "--queue-idle-speed"`)
+		}
+		if queueInterval <= 0 {
+			return fmt.Errorf("invalid queue-interval %d", queueInterval)
+		}
+	} else if queueFree || queueIdleSpeed >= 0 {
+		return fmt.Errorf(`found flags that are can only be used with "--queue"`)
 	}
 	if util.CountNonZeroVariables(skipExisting, rename) > 1 {
 		return fmt.Errorf("--skip-existing and --rename flags are NOT compatible")
 	}
+	if maxCount >= 0 {
+		if maxTorrents >= 0 {
+			return fmt.Errorf("--max-torrents and --max-count flags are NOT compatible")
+		}
+		maxTorrents = maxCount
+	}
 	if largestFlag {
 		sortFlag = "size"
 		orderFlag = "desc"
@@ -265,6 +405,32 @@ func batchdl(command *cobra.Command, args []string) error {
 	if excludes != "" {
 		excludesList = util.SplitCsv(excludes)
 	}
+	var torrentExpr *filterexpr.Expr
+	if expr != "" {
+		if torrentExpr, err = site.ParseTorrentExpr(expr); err != nil {
+			return fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+	if lookupMetadata {
+		if err = metadata.CheckProvider(); err != nil {
+			return err
+		}
+	}
+	var wantedList []*arr.Wanted
+	if arrWanted != "" {
+		for _, name := range util.SplitCsv(arrWanted) {
+			arrClient, err := arr.New(name)
+			if err != nil {
+				return err
+			}
+			wanted, err := arrClient.Wanted()
+			if err != nil {
+				return fmt.Errorf("failed to get wanted list of arr %s: %w", name, err)
+			}
+			wantedList = append(wantedList, wanted...)
+		}
+		log.Infof("Found %d wanted items in arrs", len(wantedList))
+	}
 	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
 	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
 	maxTotalSize, _ := util.RAMInBytes(maxTotalSizeStr)
@@ -280,6 +446,22 @@ func batchdl(command *cobra.Command, args []string) error {
 		}
 		freeTimeAtLeast = t
 	}
+	var discountTypes []string
+	if discountStr != "" {
+		if discountTypes, err = site.ParseDiscountTypes(discountStr); err != nil {
+			return fmt.Errorf("invalid --discount: %w", err)
+		}
+	}
+	if sortFlag == SORT_EXPIRY && startPage != "" && startPage != "0" {
+		return fmt.Errorf(`--start-page flag is NOT supported with "--sort %s"`, SORT_EXPIRY)
+	}
+	var publishedBefore int64
+	if publishedBeforeStr != "" {
+		publishedBefore, err = util.ParseTime(publishedBeforeStr, nil)
+		if err != nil {
+			return fmt.Errorf("invalid published-before: %w", err)
+		}
+	}
 	var publishedAfter int64
 	if publishedAfterStr != "" {
 		publishedAfter, err = util.ParseTime(publishedAfterStr, nil)
@@ -296,6 +478,8 @@ func batchdl(command *cobra.Command, args []string) error {
 	var clientInstance client.Client
 	var clientAddTorrentOption *client.TorrentOption
 	var clientAddFixedTags []string
+	var existingChecker *common.ExistingTorrentChecker
+	var siteSlots *common.SiteSlots
 	if addClient != "" {
 		clientInstance, err = client.CreateClient(addClient)
 		if err != nil {
@@ -309,6 +493,12 @@ func batchdl(command *cobra.Command, args []string) error {
 			log.Warnf("Client has _noadd flag and --add-respect-noadd flag is set. Abort task")
 			return nil
 		}
+		existingChecker = common.NewExistingTorrentChecker(clientInstance, ifExists, checkContent)
+		if siteSlots, err = common.LoadSiteSlots(clientInstance); err != nil {
+			return err
+		}
+	}
+	if addClient != "" || stageDir != "" {
 		clientAddTorrentOption = &client.TorrentOption{
 			Pause:    addPaused,
 			SavePath: addSavePath,
@@ -339,6 +529,113 @@ func batchdl(command *cobra.Command, args []string) error {
 	if saveJsonFile != nil && !saveAppend {
 		saveJsonFile.WriteString("[\n")
 	}
+	announcePacer := common.NewAnnouncePacer(announceInterval)
+	addToClient := func(torrent *site.Torrent, torrentContent []byte, _filename string,
+		tinfo *torrentutil.TorrentMeta, now int64) error {
+		tags := []string{}
+		tags = append(tags, clientAddFixedTags...)
+		ratioLimit := float64(0)
+		if tinfo.IsPrivate() {
+			tags = append(tags, config.PRIVATE_TAG)
+		} else {
+			tags = append(tags, config.PUBLIC_TAG)
+			ratioLimit = config.Get().PublicTorrentRatioLimit
+		}
+		if torrent.HasHnR || siteInstance.GetSiteConfig().GlobalHnR {
+			tags = append(tags, config.HR_TAG)
+		}
+		clientAddTorrentOption.Tags = tags
+		clientAddTorrentOption.RatioLimit = ratioLimit
+		if addCategoryAuto {
+			clientAddTorrentOption.Category = sitename
+		} else {
+			clientAddTorrentOption.Category = addCategory
+		}
+		if stageDir != "" {
+			if common.DryRun("stage torrent %s (%s) to %s", torrent.Id, torrent.Name, stageDir) {
+				return nil
+			}
+			filename := _filename
+			if rename != "" {
+				filename = torrentutil.RenameTorrent(rename, sitename, torrent.Id, _filename, tinfo)
+			}
+			filename, err := helper.StageTorrent(stageDir, filename, torrentContent, tinfo,
+				&torrentutil.TorrentCommentMeta{
+					Category: clientAddTorrentOption.Category,
+					Tags:     clientAddTorrentOption.Tags,
+					SavePath: clientAddTorrentOption.SavePath,
+				}, stageHook)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to stage: %v\n", torrent.Id, torrent.Name, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "torrent %s - %s (%s): staged to %s\n", torrent.Id, torrent.Name,
+					util.BytesSize(float64(torrent.Size)), filename)
+			}
+			return err
+		}
+		if existing, err := existingChecker.Check(tinfo); err != nil {
+			fmt.Fprintf(os.Stderr, "torrent %s (%s): %v\n", torrent.Id, torrent.Name, err)
+			return err
+		} else if existing != nil {
+			fmt.Fprintf(os.Stderr, "torrent %s (%s): already exists in client as %s (%s)\n", torrent.Id, torrent.Name,
+				existing.InfoHash, existing.Name)
+			return nil
+		}
+		if rule := common.GetSeasonPackRule(clientInstance.GetName(), clientAddTorrentOption.Category); rule != nil {
+			clientTorrents, err := clientInstance.GetTorrents("", "", true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to get client torrents: %v\n",
+					torrent.Id, torrent.Name, err)
+				return err
+			}
+			if pack := common.FindSeasonPack(tinfo.ContentPath, clientAddTorrentOption.Category,
+				clientTorrents); pack != nil && rule.SkipEpisodes {
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): skipped, superseded by season pack %s (%s) in client\n",
+					torrent.Id, torrent.Name, pack.InfoHash, pack.Name)
+				return nil
+			}
+			if episodes := common.FindSupersededEpisodes(&client.Torrent{
+				InfoHash: tinfo.InfoHash,
+				Name:     tinfo.ContentPath,
+				Category: clientAddTorrentOption.Category,
+			}, util.Map(tinfo.Files, func(file torrentutil.TorrentMetaFile) string { return file.Path }),
+				clientTorrents); len(episodes) > 0 {
+				action := rule.Action
+				if action == "" {
+					action = config.SEASON_PACK_ACTION_NONE
+				}
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): season pack supersedes %d episode torrents in client "+
+					"(handled by watch after it completes, action: %s)\n", torrent.Id, torrent.Name, len(episodes), action)
+			}
+		}
+		if rename != "" {
+			clientAddTorrentOption.Name = torrentutil.RenameTorrent(rename, sitename, torrent.Id, _filename, tinfo)
+		}
+		if common.DryRun("add torrent %s (%s) to client %s", torrent.Id, torrent.Name, clientInstance.GetName()) {
+			return nil
+		}
+		if !siteSlots.Acquire(siteInstance.GetName()) {
+			return errNoSlot
+		}
+		if !clientAddTorrentOption.Pause {
+			announcePacer.Wait(tinfo.Trackers)
+		}
+		err := clientInstance.AddTorrent(torrentContent, clientAddTorrentOption, nil)
+		if err != nil {
+			siteSlots.Release(siteInstance.GetName())
+			fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to add to client: %v\n", torrent.Id, torrent.Name, err)
+		} else {
+			if !noFilePriority {
+				if err := common.ApplyFilePriorityRules(clientInstance, tinfo.InfoHash); err != nil {
+					log.Warnf("torrent %s (%s): failed to apply file priority rules: %v", torrent.Id, torrent.Name, err)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "torrent %s - %s (%s) (seeders=%d, time=%s): added to client\n", torrent.Id,
+				torrent.Name, util.BytesSize(float64(torrent.Size)),
+				torrent.Seeders, util.FormatDuration(now-torrent.Time))
+		}
+		return err
+	}
 
 	cntTorrents := int64(0)
 	cntAllTorrents := int64(0)
@@ -349,6 +646,7 @@ func batchdl(command *cobra.Command, args []string) error {
 	var torrents []*site.Torrent
 	var marker = startPage
 	var lastMarker = ""
+	var queue []*queuedTorrent
 	doneHandle := func() {
 		fmt.Fprintf(os.Stderr,
 			"\n"+`Done. Torrents / AllTorrents / LastPage: %s (%d) / %s (%d) / "%s"; ErrorCnt: %d`+"\n",
@@ -385,7 +683,12 @@ mainloop:
 		now := util.Now()
 		lastMarker = marker
 		log.Printf("Get torrents with page parker '%s'", marker)
-		torrents, marker, err = siteInstance.GetAllTorrents(sortFlag, desc, marker, baseUrl)
+		if sortFlag == SORT_EXPIRY {
+			torrents, err = fetchTorrentsSortedByExpiry(siteInstance, desc, marker, flowControlInterval)
+			marker = ""
+		} else {
+			torrents, marker, err = siteInstance.GetAllTorrents(sortFlag, desc, marker, baseUrl)
+		}
 		cntTorrentsThisPage := 0
 
 		if err != nil {
@@ -423,6 +726,14 @@ mainloop:
 					continue
 				}
 			}
+			if publishedBefore > 0 && torrent.Time >= publishedBefore {
+				log.Debugf("Skip torrent %s due to too new", torrent.Name)
+				if sortFlag == "time" && !desc {
+					break mainloop
+				} else {
+					continue
+				}
+			}
 			if !onlyDownloaded && !includeDownloaded && torrent.IsActive {
 				log.Debugf("Skip active torrent %s", torrent.Name)
 				continue
@@ -463,15 +774,18 @@ mainloop:
 				log.Debugf("Skip torrent %s due to includes does NOT match", torrent.Name)
 				continue
 			}
-			if freeOnly {
-				if torrent.DownloadMultiplier != 0 {
-					log.Debugf("Skip none-free torrent %s", torrent.Name)
-					continue
-				}
-				if freeTimeAtLeast > 0 && torrent.DiscountEndTime > 0 && torrent.DiscountEndTime < now+freeTimeAtLeast {
-					log.Debugf("Skip torrent %s which remaining free time is too short", torrent.Name)
-					continue
-				}
+			if freeOnly && torrent.DownloadMultiplier != 0 {
+				log.Debugf("Skip none-free torrent %s", torrent.Name)
+				continue
+			}
+			if discountTypes != nil && !torrent.MatchDiscountTypes(discountTypes) {
+				log.Debugf("Skip torrent %s of discount %s", torrent.Name, torrent.DiscountType())
+				continue
+			}
+			if (freeOnly || discountTypes != nil) && freeTimeAtLeast > 0 &&
+				torrent.DiscountEndTime > 0 && torrent.DiscountEndTime < now+freeTimeAtLeast {
+				log.Debugf("Skip torrent %s which remaining free time is too short", torrent.Name)
+				continue
 			}
 			if nohr && torrent.HasHnR {
 				log.Debugf("Skip HR torrent %s", torrent.Name)
@@ -481,10 +795,36 @@ mainloop:
 				log.Debugf("Skip paid torrent %s", torrent.Name)
 				continue
 			}
+			if maxBonusCost >= 0 && torrent.ExceedsBonusCost(maxBonusCost) {
+				log.Debugf("Skip paid torrent %s which price %g exceeds max bonus cost", torrent.Name, torrent.BonusCost)
+				continue
+			}
 			if noNeutral && torrent.Neutral {
 				log.Debugf("Skip neutral torrent %s", torrent.Name)
 				continue
 			}
+			if fetchDetails && torrent.Details == nil {
+				details, detailsErr := siteInstance.GetTorrentDetails(torrent.ID())
+				if detailsErr != nil {
+					log.Warnf("Failed to get torrent %s details: %v", torrent.Name, detailsErr)
+				}
+				torrent.Details = details
+			}
+			if lookupMetadata && torrent.Metadata == nil {
+				torrentMetadata, metadataErr := metadata.Lookup(torrent)
+				if metadataErr != nil {
+					log.Debugf("Failed to get torrent %s metadata: %v", torrent.Name, metadataErr)
+				}
+				torrent.Metadata = torrentMetadata
+			}
+			if torrentExpr != nil && !torrent.MatchExpr(torrentExpr) {
+				log.Debugf("Skip torrent %s due to expr does NOT match", torrent.Name)
+				continue
+			}
+			if arrWanted != "" && arr.MatchWanted(wantedList, torrent.Release()) == nil {
+				log.Debugf("Skip torrent %s due to not wanted by arr", torrent.Name)
+				continue
+			}
 			if maxTotalSize >= 0 && totalSize+torrent.Size > maxTotalSize {
 				log.Debugf("Skip torrent %s which would break max total size limit", torrent.Name)
 				if sortFlag == "size" && !desc {
@@ -516,7 +856,7 @@ mainloop:
 			cntTorrents++
 			cntTorrentsThisPage++
 			totalSize += torrent.Size
-			if !doDownload && addClient == "" {
+			if !doDownload && addClient == "" && stageDir == "" {
 				if showJson {
 					util.PrintJson(os.Stdout, torrent)
 				} else {
@@ -524,6 +864,17 @@ mainloop:
 				}
 				continue
 			}
+			if queueMode {
+				fmt.Fprintf(os.Stderr, "torrent %s - %s (%s): queued\n", torrent.Id, torrent.Name,
+					util.BytesSize(float64(torrent.Size)))
+				queue = append(queue, &queuedTorrent{torrent: torrent, marker: lastMarker})
+				continue
+			}
+			if addClient != "" && siteSlots.Available(siteInstance.GetName()) == 0 {
+				log.Warnf("Site %s has no available download slots (maxDownloadingTorrents) in client. Stop adding",
+					sitename)
+				break mainloop
+			}
 			var err error
 			filename := ""
 			if doDownload && skipExisting && torrent.Id != "" {
@@ -539,11 +890,7 @@ mainloop:
 			}
 			var torrentContent []byte
 			var _filename string
-			if torrent.DownloadUrl != "" {
-				torrentContent, _filename, _, err = siteInstance.DownloadTorrent(torrent.DownloadUrl)
-			} else {
-				torrentContent, _filename, _, err = siteInstance.DownloadTorrent(torrent.Id)
-			}
+			torrentContent, _filename, err = downloadTorrent(siteInstance, torrent)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to download: %v\n", torrent.Id, torrent.Name, err)
 				consecutiveFail++
@@ -572,37 +919,10 @@ mainloop:
 							fmt.Fprintf(os.Stderr, "torrent %s - %s (%s): downloaded to %s/%s\n", torrent.Id, torrent.Name,
 								util.BytesSize(float64(torrent.Size)), downloadDir, filename)
 						}
-					} else if addClient != "" {
-						tags := []string{}
-						tags = append(tags, clientAddFixedTags...)
-						ratioLimit := float64(0)
-						if tinfo.IsPrivate() {
-							tags = append(tags, config.PRIVATE_TAG)
-						} else {
-							tags = append(tags, config.PUBLIC_TAG)
-							ratioLimit = config.Get().PublicTorrentRatioLimit
-						}
-						if torrent.HasHnR || siteInstance.GetSiteConfig().GlobalHnR {
-							tags = append(tags, config.HR_TAG)
-						}
-						clientAddTorrentOption.Tags = tags
-						clientAddTorrentOption.RatioLimit = ratioLimit
-						if addCategoryAuto {
-							clientAddTorrentOption.Category = sitename
-						} else {
-							clientAddTorrentOption.Category = addCategory
-						}
-						if rename != "" {
-							clientAddTorrentOption.Name = torrentutil.RenameTorrent(rename, sitename, torrent.Id, _filename, tinfo)
-						}
-						err = clientInstance.AddTorrent(torrentContent, clientAddTorrentOption, nil)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to add to client: %v\n", torrent.Id, torrent.Name, err)
-						} else {
-							fmt.Fprintf(os.Stderr, "torrent %s - %s (%s) (seeders=%d, time=%s): added to client\n", torrent.Id,
-								torrent.Name, util.BytesSize(float64(torrent.Size)),
-								torrent.Seeders, util.FormatDuration(now-torrent.Time))
-						}
+					} else if err = addToClient(torrent, torrentContent, _filename, tinfo, now); err == errNoSlot {
+						log.Warnf("Site %s has no available download slots (maxDownloadingTorrents) in client. "+
+							"Stop adding", sitename)
+						break mainloop
 					}
 				}
 			}
@@ -633,6 +953,98 @@ mainloop:
 			util.BytesSize(float64(totalAllSize)), cntAllTorrents, marker, flowControlInterval)
 		util.Sleep(flowControlInterval)
 	} // main loop
+	if len(queue) > 0 {
+		q := &torrentQueue{
+			torrents:            queue,
+			siteInstance:        siteInstance,
+			clientInstance:      clientInstance,
+			desc:                desc,
+			freeTimeAtLeast:     freeTimeAtLeast,
+			idleSpeed:           queueIdleSpeed,
+			deadline:            util.Now() + queueTimeout,
+			flowControlInterval: flowControlInterval,
+			start: func(torrent *site.Torrent) error {
+				if siteSlots != nil {
+					if slots, err := common.LoadSiteSlots(clientInstance); err == nil {
+						siteSlots = slots
+					}
+				}
+				if siteSlots.Available(siteInstance.GetName()) == 0 {
+					return errNoSlot
+				}
+				torrentContent, _filename, err := downloadTorrent(siteInstance, torrent)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to download: %v\n", torrent.Id, torrent.Name, err)
+				} else if tinfo, _err := torrentutil.ParseTorrent(torrentContent); _err != nil {
+					err = _err
+					fmt.Fprintf(os.Stderr, "torrent %s (%s): failed to parse: %v\n", torrent.Id, torrent.Name, err)
+				} else {
+					err = addToClient(torrent, torrentContent, _filename, tinfo, util.Now())
+				}
+				if err != nil {
+					if saveFailFile != nil {
+						saveFailFile.WriteString(torrent.Id + "\n")
+					}
+				} else if saveOkFile != nil {
+					saveOkFile.WriteString(torrent.Id + "\n")
+				}
+				return err
+			},
+		}
+		errorCnt += q.run()
+	}
 	doneHandle()
 	return nil
 }
+
+// The site has no available download slots (maxDownloadingTorrents) in client.
+var errNoSlot = fmt.Errorf("no available download slots of site")
+
+func downloadTorrent(siteInstance site.Site, torrent *site.Torrent) (content []byte, filename string, err error) {
+	if torrent.DownloadUrl != "" {
+		content, filename, _, err = siteInstance.DownloadTorrent(torrent.DownloadUrl)
+	} else {
+		content, filename, _, err = siteInstance.DownloadTorrent(torrent.Id)
+	}
+	return
+}
+
+// Fetch site torrents of all pages (at most --max-pages pages), in site default order,
+// then sort them by discount end time.
+func fetchTorrentsSortedByExpiry(siteInstance site.Site, desc bool, marker string, flowControlInterval int64) (
+	torrents []*site.Torrent, err error) {
+	for page := int64(0); maxPages < 0 || page < maxPages; page++ {
+		if page > 0 {
+			log.Warnf("Fetched %d pages (%d torrents). Will fetch next page %s in %d seconds",
+				page, len(torrents), marker, flowControlInterval)
+			util.Sleep(flowControlInterval)
+		}
+		var pageTorrents []*site.Torrent
+		pageTorrents, marker, err = siteInstance.GetAllTorrents(constants.NONE, false, marker, baseUrl)
+		if err != nil {
+			if len(torrents) == 0 {
+				return nil, err
+			}
+			log.Errorf("Failed to fetch page %s torrents: %v", marker, err)
+			break
+		}
+		torrents = append(torrents, pageTorrents...)
+		if onePage || marker == "" {
+			break
+		}
+	}
+	// time-limited discounts first, in end time asc order
+	expiry := func(torrent *site.Torrent) int64 {
+		if torrent.DiscountEndTime > 0 && torrent.DownloadMultiplier < 1 {
+			return torrent.DiscountEndTime
+		}
+		return math.MaxInt64
+	}
+	sort.SliceStable(torrents, func(i, j int) bool {
+		if desc {
+			return expiry(torrents[i]) > expiry(torrents[j])
+		}
+		return expiry(torrents[i]) < expiry(torrents[j])
+	})
+	return torrents, nil
+}
@@ -0,0 +1,124 @@
+package batchdl
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// A found site torrent that waits to be started (added to client). See "queue mode" of batchdl.
+type queuedTorrent struct {
+	torrent *site.Torrent
+	marker  string // the site page marker where the torrent was found
+}
+
+type torrentQueue struct {
+	torrents            []*queuedTorrent
+	siteInstance        site.Site
+	clientInstance      client.Client
+	desc                bool
+	freeTimeAtLeast     int64
+	idleSpeed           int64 // -1: disabled
+	deadline            int64 // timestamp. Queued torrents not started before this are dropped
+	flowControlInterval int64
+	start               func(torrent *site.Torrent) error
+}
+
+// Check queued torrents periodically and start them when free or client idle,
+// until the queue is empty or the deadline reaches. Return the count of errors.
+func (q *torrentQueue) run() (errorCnt int64) {
+	for {
+		now := util.Now()
+		if queueFree {
+			q.refresh()
+		}
+		idle := false
+		if q.idleSpeed >= 0 {
+			q.clientInstance.PurgeCache()
+			if status, err := q.clientInstance.GetStatus(); err != nil {
+				log.Errorf("Failed to get client status: %v", err)
+			} else {
+				idle = status.DownloadSpeed < q.idleSpeed
+				log.Debugf("Client download speed %s/s, idle: %t", util.BytesSize(float64(status.DownloadSpeed)), idle)
+			}
+		}
+		remains := []*queuedTorrent{}
+		for _, qt := range q.torrents {
+			torrent := qt.torrent
+			reason := ""
+			if queueFree && torrent.DownloadMultiplier == 0 && (q.freeTimeAtLeast <= 0 ||
+				torrent.DiscountEndTime <= 0 || torrent.DiscountEndTime >= now+q.freeTimeAtLeast) {
+				reason = "free"
+			} else if idle {
+				reason = "client idle"
+				idle = false
+			}
+			if reason == "" {
+				remains = append(remains, qt)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "torrent %s (%s): start queued torrent (%s)\n", torrent.Id, torrent.Name, reason)
+			if err := q.start(torrent); err == errNoSlot {
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): wait for available download slot of site "+
+					"(maxDownloadingTorrents)\n", torrent.Id, torrent.Name)
+				remains = append(remains, qt)
+			} else if err != nil {
+				errorCnt++
+			}
+		}
+		q.torrents = remains
+		if len(q.torrents) == 0 {
+			break
+		}
+		if now+queueInterval > q.deadline {
+			log.Warnf("Queue timeout. Drop %d queued torrents", len(q.torrents))
+			for _, qt := range q.torrents {
+				fmt.Fprintf(os.Stderr, "torrent %s (%s): dropped\n", qt.torrent.Id, qt.torrent.Name)
+			}
+			break
+		}
+		log.Warnf("%d torrents queued. Will check them again in %d seconds. Press Ctrl + C to stop",
+			len(q.torrents), queueInterval)
+		util.Sleep(queueInterval)
+	}
+	return errorCnt
+}
+
+// Re-fetch the site pages where queued torrents were found, update the (discount) info of queued torrents.
+func (q *torrentQueue) refresh() {
+	markers := []string{}
+	for _, qt := range q.torrents {
+		if !slices.Contains(markers, qt.marker) {
+			markers = append(markers, qt.marker)
+		}
+	}
+	for i, marker := range markers {
+		if i > 0 {
+			util.Sleep(q.flowControlInterval)
+		}
+		var torrents []*site.Torrent
+		var err error
+		if sortFlag == SORT_EXPIRY {
+			torrents, err = fetchTorrentsSortedByExpiry(q.siteInstance, q.desc, marker, q.flowControlInterval)
+		} else {
+			torrents, _, err = q.siteInstance.GetAllTorrents(sortFlag, q.desc, marker, baseUrl)
+		}
+		if err != nil {
+			log.Errorf("Failed to fetch page %s torrents: %v", marker, err)
+			continue
+		}
+		for _, torrent := range torrents {
+			for _, qt := range q.torrents {
+				if qt.torrent.Id == torrent.Id {
+					qt.torrent = torrent
+				}
+			}
+		}
+	}
+}
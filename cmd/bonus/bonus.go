@@ -0,0 +1,174 @@
+package bonus
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "bonus {site} [--exchange type[:amount]]... [--min-balance bonus] [--force]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "bonus"},
+	Short:       "Show or exchange site bonus points for upload credit or invites.",
+	Long: `Show or exchange site bonus points (魔力值) for upload credit or invites.
+Currently only NexusPHP sites are supported.
+
+Without --exchange flag, it displays the current bonus points of user, the exchange options of site,
+and the recent exchange records.
+
+Use --exchange flag to exchange bonus points. The format is "type[:amount]", where type is one of:
+* upload : Upload credit. amount is the total upload size wanted, e.g. "upload:10GiB".
+* invite : Invite. amount is the count of invites wanted, default is 1. E.g. "invite", "invite:2".
+The amount is fulfilled by the exchange options of site, using the options of larger amount first.
+E.g. if site provides 1GiB / 5GiB / 10GiB upload options, "upload:12GiB" will exchange 10GiB once and 1GiB twice.
+It fails with an error, before exchanging anything, if the total price is higher than the available bonus points
+(current bonus points minus --min-balance).
+
+It displays the planned exchanges and asks for confirmation unless --force flag is set.
+Use --dry-run flag to only display the planned exchanges.
+Each exchange is recorded in the "` + config.STATS_FILENAME + `" file (in the same dir of ptool.toml file).
+
+Examples:
+  ptool bonus mysite
+  ptool bonus mysite --exchange upload:10GiB
+  ptool bonus mysite --exchange upload:50GiB --exchange invite --min-balance 10000`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: bonus,
+}
+
+// Max count of recent exchange records displayed.
+const MAX_HISTORY = 10
+
+var (
+	force      = false
+	minBalance = float64(0)
+	exchanges  []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().Float64VarP(&minBalance, "min-balance", "", 0,
+		"Keep at least this bonus points after exchanging")
+	command.Flags().StringArrayVarP(&exchanges, "exchange", "", nil,
+		`Exchange bonus points for this. E.g. "upload:10GiB", "invite", "invite:2"`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func bonus(cmd *cobra.Command, args []string) error {
+	sitename := args[0]
+	siteInstance, err := site.CreateSite(sitename)
+	if err != nil {
+		return fmt.Errorf("failed to create site: %w", err)
+	}
+	type exchangeTarget struct {
+		exchangeType string
+		value        int64
+	}
+	var targets []*exchangeTarget
+	for _, exchange := range exchanges {
+		exchangeType, value, err := site.ParseBonusExchange(exchange)
+		if err != nil {
+			return fmt.Errorf("invalid --exchange %q: %w", exchange, err)
+		}
+		targets = append(targets, &exchangeTarget{exchangeType, value})
+	}
+	statDb, err := stats.NewDb(filepath.Join(config.ConfigDir, config.STATS_FILENAME))
+	if err != nil {
+		return fmt.Errorf("failed to create stats db: %w", err)
+	}
+	balance, options, err := siteInstance.GetBonusExchangeOptions()
+	if err != nil {
+		return fmt.Errorf("failed to get bonus exchange options: %w", err)
+	}
+
+	if len(targets) == 0 {
+		fmt.Printf("Site %s: bonus points %.1f\n\n", sitename, balance)
+		fmt.Printf("%-6s  %-6s  %10s  %12s  %s\n", "Id", "Type", "Value", "Price", "Name")
+		for _, option := range options {
+			exchangeType, value := option.Type, option.ValueString()
+			if exchangeType == "" {
+				exchangeType, value = "-", "-"
+			}
+			fmt.Printf("%-6s  %-6s  %10s  %12.1f  %s\n", option.Id, exchangeType, value, option.Price, option.Name)
+		}
+		records := statDb.GetBonusExchangeStats(sitename)
+		if len(records) > MAX_HISTORY {
+			records = records[len(records)-MAX_HISTORY:]
+		}
+		if len(records) > 0 {
+			fmt.Printf("\nRecent exchanges:\n")
+			fmt.Printf("%-19s  %12s  %12s  %s\n", "Time", "Price", "Bonus", "Option")
+			for _, record := range records {
+				fmt.Printf("%-19s  %12.1f  %12.1f  %s\n", util.FormatTime(record.Ts),
+					record.Exchange.Price, record.Exchange.Bonus, record.Exchange.Option)
+			}
+		}
+		return nil
+	}
+
+	var planned []*site.BonusExchangeOption
+	totalPrice := float64(0)
+	for _, target := range targets {
+		targetExchanges, err := site.PlanBonusExchanges(options, target.exchangeType, target.value)
+		if err != nil {
+			return err
+		}
+		planned = append(planned, targetExchanges...)
+	}
+	fmt.Printf("Site %s: %d exchanges planned:\n", sitename, len(planned))
+	for i, option := range planned {
+		fmt.Printf("%d. %s (%s %s): price %.1f\n", i+1, option.Name, option.Type, option.ValueString(), option.Price)
+		totalPrice += option.Price
+	}
+	fmt.Printf("Total price %.1f; Current bonus points %.1f; Min balance %.1f\n", totalPrice, balance, minBalance)
+	if totalPrice > balance-minBalance {
+		return fmt.Errorf("insufficient bonus points: need %.1f, available %.1f", totalPrice, balance-minBalance)
+	}
+	if flags.DryRun {
+		fmt.Printf("Dry run. Exit\n")
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will spend %.1f bonus points of site %s",
+		totalPrice, sitename)) {
+		return fmt.Errorf("abort")
+	}
+
+	spent := float64(0)
+	for i, option := range planned {
+		if err := siteInstance.ExchangeBonus(option); err != nil {
+			return fmt.Errorf("exchange %d (%s) failed: %w. %d exchanges done", i+1, option.Name, err, i)
+		}
+		statDb.AddBonusExchangeStat(time.Now().Unix(), &stats.BonusExchangeStat{
+			Site:   sitename,
+			Option: option.Name,
+			Type:   option.Type,
+			Value:  option.Value,
+			Price:  option.Price,
+			Bonus:  balance - spent,
+		})
+		spent += option.Price
+		fmt.Printf("✓ %d. %s\n", i+1, option.Name)
+	}
+	newBalance, _, err := siteInstance.GetBonusExchangeOptions()
+	if err != nil {
+		log.Warnf("Failed to get bonus points after exchanging: %v", err)
+		return nil
+	}
+	fmt.Printf("Done %d exchanges. Bonus points: %.1f => %.1f\n", len(planned), balance, newBalance)
+	// Bonus points keep growing during exchanging, so tolerate some error.
+	if balance-newBalance < spent*0.9 {
+		log.Warnf("Bonus points decreased less than the total price %.1f, some exchanges may have failed", spent)
+	}
+	return nil
+}
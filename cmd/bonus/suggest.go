@@ -0,0 +1,21 @@
+package bonus
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("bonus", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex != 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		return suggest.SiteArg(info.MatchingPrefix)
+	})
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/brush/strategy"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/stats"
 	"github.com/sagan/ptool/util"
@@ -28,7 +30,6 @@ var command = &cobra.Command{
 }
 
 var (
-	dryRun    = false
 	addPaused = false
 	ordered   = false
 	force     = false
@@ -36,7 +37,7 @@ var (
 )
 
 func init() {
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do not actually controlling client")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Do not actually controlling client")
 	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add torrents to client in paused state")
 	command.Flags().BoolVarP(&ordered, "ordered", "", false, "Brush sites provided in order")
 	command.Flags().BoolVarP(&force, "force", "", false, `Force mode. Ignore "`+config.NOADD_TAG+`" flag tag in client`)
@@ -88,6 +89,10 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			continue
 		}
 		noadd := !force && status.NoAdd
+		backoff, backoffMsg := checkSiteBackoff(siteInstance, util.Now())
+		if backoffMsg != "" {
+			log.Printf("Site %s status: %s", sitename, backoffMsg)
+		}
 		var siteTorrents []*site.Torrent
 		if status.UploadSpeedLimit > 0 && (status.UploadSpeedLimit < strategy.SLOW_UPLOAD_SPEED ||
 			(float64(status.UploadSpeed)/float64(status.UploadSpeedLimit)) >= strategy.BANDWIDTH_FULL_PERCENT) {
@@ -101,6 +106,8 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			log.Printf("Site %s enforces global HnR. Do not fetch site new torrents", sitename)
 		} else if noadd {
 			log.Printf("Client %s in NoAdd status. Do not fetch site new torrents", clientInstance.GetName())
+		} else if backoff == SITE_BACKOFF_PAUSE {
+			log.Printf("Site %s status is abnormal, brushing is paused. Do not fetch site new torrents", sitename)
 		} else {
 			siteTorrents, err = siteInstance.GetLatestTorrents(true)
 			if err != nil {
@@ -113,7 +120,11 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			log.Printf("Failed to get client %s torrents: %v ", clientInstance.GetName(), err)
 			continue
 		}
-		brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, util.Now())
+		brushSiteOption, err := strategy.GetBrushSiteOptions(siteInstance, util.Now())
+		if err != nil {
+			log.Errorf("Failed to get site %s brush options: %v", sitename, err)
+			continue
+		}
 		brushMaxTorrents := clientInstance.GetClientConfig().BrushMaxTorrents
 		if siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent != 0 {
 			p := float64(siteInstance.GetSiteConfig().BrushAllowAddTorrentsPercent) / 100.0
@@ -122,9 +133,24 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 
 		currentTorrents := len(getTorrentsOfSite(clientTorrents, sitename))
 		brushSiteOption.AllowAddTorrents = brushMaxTorrents - int64(currentTorrents)
+		if slots, err := common.LoadSiteSlots(clientInstance); err != nil {
+			log.Errorf("Failed to get site %s download slots: %v", sitename, err)
+		} else if available := slots.Available(siteInstance.GetName()); available >= 0 &&
+			available < brushSiteOption.AllowAddTorrents {
+			log.Printf("Site %s has %d available download slots (maxDownloadingTorrents)", sitename, available)
+			brushSiteOption.AllowAddTorrents = available
+		}
+		if backoff == SITE_BACKOFF_SLOW && brushSiteOption.AllowAddTorrents > 1 {
+			log.Printf("Site %s status is abnormal, brushing is slowed down. Allow to add at most 1 torrent", sitename)
+			brushSiteOption.AllowAddTorrents = 1
+		}
 		log.Printf("Site %s already have %d torrents, max %d, allow %d", sitename,
 			len(getTorrentsOfSite(clientTorrents, sitename)), brushMaxTorrents, brushSiteOption.AllowAddTorrents)
-		brushClientOption := strategy.GetBrushClientOptions(clientInstance)
+		brushClientOption, err := strategy.GetBrushClientOptions(clientInstance)
+		if err != nil {
+			log.Errorf("Failed to get client %s brush options: %v", clientInstance.GetName(), err)
+			continue
+		}
 		log.Printf(
 			"Brush Options: minDiskSpace=%v, slowUploadSpeedTier=%v, torrentUploadSpeedLimit=%v/s,"+
 				" maxDownloadingTorrents=%d, maxTorrents=%d, minRatio=%f",
@@ -194,13 +220,13 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 			})
 			deleteTorrentInfoHashes = append(deleteTorrentInfoHashes, clientTorrent.InfoHash)
 		}
-		if !dryRun {
+		if !flags.DryRun {
 			err := client.DeleteTorrentsAuto(clientInstance, deleteTorrentInfoHashes)
 			log.Printf("Delete torrents result: error=%v", err)
 			if err == nil {
 				cntDeleteTorrents += int64(len(deleteTorrentInfoHashes))
 				if statDb != nil {
-					statDb.AddTorrentStats(brushSiteOption.Now, 1, deleteTorrentStats)
+					statDb.AddTorrentStats(brushSiteOption.Now, stats.EVENT_TORRENT_DELETE, deleteTorrentStats)
 				}
 			}
 		}
@@ -209,7 +235,7 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 		for _, torrent := range result.StallTorrents {
 			log.Printf("Stall client %s torrent: %v / %v / %v",
 				clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
-			if dryRun {
+			if flags.DryRun {
 				continue
 			}
 			err := clientInstance.ModifyTorrent(torrent.InfoHash, &client.TorrentOption{
@@ -224,7 +250,7 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 				log.Printf("Resume client %s torrent: %v / %v / %v",
 					clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg)
 			}
-			if !dryRun {
+			if !flags.DryRun {
 				err := clientInstance.ResumeTorrents(util.Map(result.ResumeTorrents,
 					func(t strategy.AlgorithmOperationTorrent) string {
 						return t.InfoHash
@@ -237,7 +263,7 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 		for _, torrent := range result.ModifyTorrents {
 			log.Printf("Modify client %s torrent: %v / %v / %v / %v ",
 				clientInstance.GetName(), torrent.Name, torrent.InfoHash, torrent.Msg, torrent.Meta)
-			if dryRun {
+			if flags.DryRun {
 				continue
 			}
 			err := clientInstance.ModifyTorrent(torrent.InfoHash, nil, torrent.Meta)
@@ -249,7 +275,7 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 		for _, torrent := range result.AddTorrents {
 			log.Printf("Add site %s torrent to client %s: %s / %s / %v",
 				siteInstance.GetName(), clientInstance.GetName(), torrent.Name, torrent.Msg, torrent.Meta)
-			if dryRun {
+			if flags.DryRun {
 				continue
 			}
 			torrentdata, _, _, err := siteInstance.DownloadTorrent(torrent.DownloadUrl)
@@ -285,7 +311,7 @@ func brush(cmd *cobra.Command, args []string) (err error) {
 				Tags:             tags,
 				UploadSpeedLimit: siteInstance.GetSiteConfig().TorrentUploadSpeedLimitValue,
 			}
-			if !dryRun {
+			if !flags.DryRun {
 				err = clientInstance.AddTorrent(torrentdata, torrentOption, torrent.Meta)
 				log.Printf("Add torrent result: error=%v", err)
 				if err == nil {
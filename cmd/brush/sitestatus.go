@@ -0,0 +1,118 @@
+package brush
+
+// Brush backoff based on the user status (uploaded / downloaded) reported by site,
+// which reflects the actual traffic that site records, rather than the client side stats.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	SITE_BACKOFF_NONE  = iota
+	SITE_BACKOFF_SLOW  // add at most 1 new torrent
+	SITE_BACKOFF_PAUSE // do not add new torrents
+)
+
+// Recorded site status older than this (seconds) is not used to calculate recent ratio / upload speed.
+const SITE_STATUS_MAX_AGE = 86400
+
+// Site user status recorded in last brush of the site.
+type siteStatusRecord struct {
+	Time           int64 `json:"time"`
+	UserUploaded   int64 `json:"user_uploaded"`
+	UserDownloaded int64 `json:"user_downloaded"`
+}
+
+// Fetch site user status and decide whether brushing the site should be paused or slowed,
+// according to brushSite* thresholds of site config. The status is recorded and compared with in next brush.
+// If site status is not available, brushing is not affected.
+func checkSiteBackoff(siteInstance site.Site, now int64) (backoff int, msg string) {
+	siteConfig := siteInstance.GetSiteConfig()
+	if siteConfig.BrushSiteMinRatio <= 0 && siteConfig.BrushSiteMinRecentRatio <= 0 &&
+		siteConfig.BrushSiteMaxUploadSpeedValue <= 0 {
+		return SITE_BACKOFF_NONE, ""
+	}
+	status, err := siteInstance.GetStatus()
+	if err != nil {
+		return SITE_BACKOFF_NONE, fmt.Sprintf("failed to get site status, ignore it: %v", err)
+	}
+	records := loadSiteStatusRecords()
+	previous := records[siteInstance.GetName()]
+	records[siteInstance.GetName()] = &siteStatusRecord{
+		Time:           now,
+		UserUploaded:   status.UserUploaded,
+		UserDownloaded: status.UserDownloaded,
+	}
+	if !flags.DryRun {
+		saveSiteStatusRecords(records)
+	}
+
+	msgs := []string{fmt.Sprintf("↑%s ↓%s", util.BytesSize(float64(status.UserUploaded)),
+		util.BytesSize(float64(status.UserDownloaded)))}
+	if siteConfig.BrushSiteMinRatio > 0 && status.UserDownloaded > 0 {
+		ratio := float64(status.UserUploaded) / float64(status.UserDownloaded)
+		if ratio < siteConfig.BrushSiteMinRatio {
+			backoff = SITE_BACKOFF_PAUSE
+			msgs = append(msgs, fmt.Sprintf("ratio %.2f < %g", ratio, siteConfig.BrushSiteMinRatio))
+		}
+	}
+	if previous != nil && now > previous.Time && now-previous.Time <= SITE_STATUS_MAX_AGE {
+		uploaded := status.UserUploaded - previous.UserUploaded
+		downloaded := status.UserDownloaded - previous.UserDownloaded
+		msgs = append(msgs, fmt.Sprintf("recent (%s) ↑%s ↓%s", util.GetDurationString(now-previous.Time),
+			util.BytesSize(float64(uploaded)), util.BytesSize(float64(downloaded))))
+		if siteConfig.BrushSiteMaxUploadSpeedValue > 0 {
+			speed := uploaded / (now - previous.Time)
+			if speed >= siteConfig.BrushSiteMaxUploadSpeedValue {
+				backoff = SITE_BACKOFF_PAUSE
+				msgs = append(msgs, fmt.Sprintf("upload speed %s/s >= %s/s", util.BytesSize(float64(speed)),
+					util.BytesSize(float64(siteConfig.BrushSiteMaxUploadSpeedValue))))
+			}
+		}
+		if siteConfig.BrushSiteMinRecentRatio > 0 && downloaded > 0 {
+			ratio := float64(uploaded) / float64(downloaded)
+			if ratio < siteConfig.BrushSiteMinRecentRatio {
+				backoff = max(backoff, SITE_BACKOFF_SLOW)
+				msgs = append(msgs, fmt.Sprintf("recent ratio %.2f < %g", ratio, siteConfig.BrushSiteMinRecentRatio))
+			}
+		}
+	}
+	return backoff, strings.Join(msgs, "; ")
+}
+
+// Return sitename => last recorded status.
+func loadSiteStatusRecords() map[string]*siteStatusRecord {
+	records := map[string]*siteStatusRecord{}
+	contents, err := os.ReadFile(filepath.Join(config.ConfigDir, config.BRUSH_SITE_STATUS_FILENAME))
+	if err != nil {
+		return records
+	}
+	if err := json.Unmarshal(contents, &records); err != nil {
+		log.Warnf("Failed to parse %s: %v", config.BRUSH_SITE_STATUS_FILENAME, err)
+		return map[string]*siteStatusRecord{}
+	}
+	return records
+}
+
+func saveSiteStatusRecords(records map[string]*siteStatusRecord) {
+	contents, err := json.Marshal(records)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(config.ConfigDir, config.BRUSH_SITE_STATUS_FILENAME), contents,
+			constants.PERM)
+	}
+	if err != nil {
+		log.Warnf("Failed to save %s: %v", config.BRUSH_SITE_STATUS_FILENAME, err)
+	}
+}
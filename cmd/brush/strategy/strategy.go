@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/script"
 )
 
 const (
@@ -33,6 +36,7 @@ const (
 type BrushSiteOptionStruct struct {
 	AllowNoneFree           bool
 	AllowPaid               bool
+	MaxBonusCost            float64 // if > 0, paid torrents of price <= this are allowed, regardless of AllowPaid
 	AllowHr                 bool
 	AllowZeroSeeders        bool
 	TorrentUploadSpeedLimit int64
@@ -40,7 +44,10 @@ type BrushSiteOptionStruct struct {
 	TorrentMaxSizeLimit     int64
 	Now                     int64
 	Excludes                []string
+	Discounts               []string // allowed discount types (lowercase). If set, AllowNoneFree is ignored
+	MinFreeTime             int64    // min remaining time of time-limited discount
 	AllowAddTorrents        int64
+	Script                  *script.Script // if set, use it's score(torrent, score) function to rate torrents
 }
 
 type BrushClientOptionStruct struct {
@@ -50,6 +57,7 @@ type BrushClientOptionStruct struct {
 	MaxTorrents             int64
 	MinRatio                float64
 	DefaultUploadSpeedLimit int64
+	Script                  *script.Script // if set, delete torrents that it's delete(torrent) function returns True
 }
 
 type AlgorithmAddTorrent struct {
@@ -220,6 +228,21 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 			continue
 		}
 
+		if clientOption.Script != nil {
+			if remove, err := clientOption.Script.CallBool("delete", torrent.ScriptValue()); err != nil {
+				log.Warnf("Failed to check torrent %s using brush script: %v", torrent.Name, err)
+			} else if remove {
+				deleteCandidateTorrents = append(deleteCandidateTorrents, candidateClientTorrentStruct{
+					InfoHash:    torrent.InfoHash,
+					Score:       DELETE_TORRENT_IMMEDIATELY_SCORE,
+					FutureValue: 0,
+					Msg:         "deleted by brush script",
+				})
+				clientTorrentsMap[torrent.InfoHash].DeleteCandidateFlag = true
+				continue
+			}
+		}
+
 		if torrent.State == "error" && (torrent.UploadSpeed < clientOption.SlowUploadSpeedTier ||
 			torrent.UploadSpeed < clientOption.SlowUploadSpeedTier*2 && freespace == 0) &&
 			len(candidateTorrents) > 0 {
@@ -497,7 +520,23 @@ func Decide(clientStatus *client.Status, clientTorrents []*client.Torrent, siteT
 	return
 }
 
+// Rate site torrent. If the score of brush strategy is positive and the brush script of site is set,
+// the final score is the return value of script's score(torrent, score) function.
 func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
+	score float64, predictionUploadSpeed int64, note string) {
+	score, predictionUploadSpeed, note = rateSiteTorrent(siteTorrent, siteOption)
+	if score > 0 && siteOption.Script != nil {
+		scriptScore, err := siteOption.Script.CallNumber("score", siteTorrent.ScriptValue(), score)
+		if err != nil {
+			log.Warnf("Failed to rate torrent %s using brush script: %v", siteTorrent.Name, err)
+			return 0, predictionUploadSpeed, "brush script error"
+		}
+		score = max(scriptScore, 0)
+	}
+	return
+}
+
+func rateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruct) (
 	score float64, predictionUploadSpeed int64, note string) {
 	if log.GetLevel() >= log.TraceLevel {
 		defer func() {
@@ -514,11 +553,13 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 	}
 	if siteTorrent.IsActive || siteTorrent.UploadMultiplier == 0 ||
 		(!siteOption.AllowHr && siteTorrent.HasHnR) ||
-		(!siteOption.AllowNoneFree && siteTorrent.DownloadMultiplier != 0) ||
-		(!siteOption.AllowPaid && siteTorrent.Paid && !siteTorrent.Bought) ||
+		(len(siteOption.Discounts) > 0 && !siteTorrent.MatchDiscountTypes(siteOption.Discounts)) ||
+		(len(siteOption.Discounts) == 0 && !siteOption.AllowNoneFree && siteTorrent.DownloadMultiplier != 0) ||
+		(siteOption.MaxBonusCost <= 0 && !siteOption.AllowPaid && siteTorrent.Paid && !siteTorrent.Bought) ||
+		(siteOption.MaxBonusCost > 0 && siteTorrent.ExceedsBonusCost(siteOption.MaxBonusCost)) ||
 		siteTorrent.Size < siteOption.TorrentMinSizeLimit ||
 		siteTorrent.Size > siteOption.TorrentMaxSizeLimit ||
-		(siteTorrent.DiscountEndTime > 0 && siteTorrent.DiscountEndTime-siteOption.Now < 3600) ||
+		(siteTorrent.DiscountEndTime > 0 && siteTorrent.DiscountEndTime-siteOption.Now < siteOption.MinFreeTime) ||
 		(!siteOption.AllowZeroSeeders && siteTorrent.Seeders == 0) ||
 		siteTorrent.Leechers <= siteTorrent.Seeders {
 		score = 0
@@ -586,22 +627,33 @@ func RateSiteTorrent(siteTorrent *site.Torrent, siteOption *BrushSiteOptionStruc
 	return
 }
 
-func GetBrushSiteOptions(siteInstance site.Site, ts int64) *BrushSiteOptionStruct {
-	return &BrushSiteOptionStruct{
+func GetBrushSiteOptions(siteInstance site.Site, ts int64) (*BrushSiteOptionStruct, error) {
+	siteOption := &BrushSiteOptionStruct{
 		TorrentMinSizeLimit:     siteInstance.GetSiteConfig().BrushTorrentMinSizeLimitValue,
 		TorrentMaxSizeLimit:     siteInstance.GetSiteConfig().BrushTorrentMaxSizeLimitValue,
 		TorrentUploadSpeedLimit: siteInstance.GetSiteConfig().TorrentUploadSpeedLimitValue,
 		AllowNoneFree:           siteInstance.GetSiteConfig().BrushAllowNoneFree,
 		AllowPaid:               siteInstance.GetSiteConfig().BrushAllowPaid,
+		MaxBonusCost:            siteInstance.GetSiteConfig().BrushMaxBonusCost,
 		AllowHr:                 siteInstance.GetSiteConfig().BrushAllowHr,
 		AllowZeroSeeders:        siteInstance.GetSiteConfig().BrushAllowZeroSeeders,
 		Excludes:                siteInstance.GetSiteConfig().BrushExcludes,
+		Discounts:               util.Map(siteInstance.GetSiteConfig().BrushDiscounts, strings.ToLower),
+		MinFreeTime:             siteInstance.GetSiteConfig().BrushMinFreeTimeValue,
 		Now:                     ts,
 	}
+	if siteInstance.GetSiteConfig().BrushScript != "" {
+		brushScript, err := loadScript(siteInstance.GetSiteConfig().BrushScript, "score")
+		if err != nil {
+			return nil, err
+		}
+		siteOption.Script = brushScript
+	}
+	return siteOption, nil
 }
 
-func GetBrushClientOptions(clientInstance client.Client) *BrushClientOptionStruct {
-	return &BrushClientOptionStruct{
+func GetBrushClientOptions(clientInstance client.Client) (*BrushClientOptionStruct, error) {
+	clientOption := &BrushClientOptionStruct{
 		MinDiskSpace:            clientInstance.GetClientConfig().BrushMinDiskSpaceValue,
 		SlowUploadSpeedTier:     clientInstance.GetClientConfig().BrushSlowUploadSpeedTierValue,
 		MaxDownloadingTorrents:  clientInstance.GetClientConfig().BrushMaxDownloadingTorrents,
@@ -609,4 +661,24 @@ func GetBrushClientOptions(clientInstance client.Client) *BrushClientOptionStruc
 		MinRatio:                clientInstance.GetClientConfig().BrushMinRatio,
 		DefaultUploadSpeedLimit: clientInstance.GetClientConfig().BrushDefaultUploadSpeedLimitValue,
 	}
+	if clientInstance.GetClientConfig().BrushScript != "" {
+		brushScript, err := loadScript(clientInstance.GetClientConfig().BrushScript, "delete")
+		if err != nil {
+			return nil, err
+		}
+		clientOption.Script = brushScript
+	}
+	return clientOption, nil
+}
+
+// Load the brush script file (relative to config dir) which must define the function.
+func loadScript(filename string, function string) (*script.Script, error) {
+	brushScript, err := script.Load(config.ResolvePath(filename))
+	if err != nil {
+		return nil, err
+	}
+	if !brushScript.Has(function) {
+		return nil, fmt.Errorf("brush script %s does NOT define %s function", filename, function)
+	}
+	return brushScript, nil
 }
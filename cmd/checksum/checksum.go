@@ -0,0 +1,248 @@
+package checksum
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "checksum {client} {infoHash}... [--algo sha256] [--out manifest.txt | --check manifest.txt]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "checksum"},
+	Short:       "Generate or verify checksum manifest of the contents of torrents in client.",
+	Long: fmt.Sprintf(`Generate or verify checksum manifest of the contents of torrents in client.
+%s.
+
+It computes the checksum of each downloaded file of torrents and writes the manifest to --out file (default stdout).
+The manifest is in the same format as "sha256sum" (and other coreutils *sum tools) outputs:
+"<hex checksum>  <file path>" per line, where file path is relative to the save path of torrent,
+so it can also be verified by "sha256sum -c manifest.txt" in the save path.
+Incomplete (or skipped) files of torrents are not included.
+
+If --check flag is set, it reads the manifest file and verifies the files of torrents against it instead.
+Each file in manifest must be a (downloaded) file of one of the torrents and have the same checksum;
+files of torrents that are not in manifest are ignored.
+
+Supported algorithms (--algo): %s.
+
+ptool must have access to the save path of torrents, use --map-save-path if it's different from client's.`,
+		constants.HELP_INFOHASH_ARGS, strings.Join(algorithms, ", ")),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: checksum,
+}
+
+var hashers = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var algorithms = []string{"md5", "sha1", "sha256", "sha512"}
+
+var (
+	category     = ""
+	tag          = ""
+	filter       = ""
+	algo         = ""
+	output       = ""
+	checkFile    = ""
+	mapSavePaths []string
+)
+
+func init() {
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&algo, "algo", "", "sha256", "Checksum algorithm: "+strings.Join(algorithms, ", "))
+	command.Flags().StringVarP(&output, "out", "", "-", `Output manifest filename. "-" for stdout`)
+	command.Flags().StringVarP(&checkFile, "check", "", "", "Verify files of torrents against this manifest file")
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func checksum(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if category == "" && tag == "" && filter == "" {
+		if len(infoHashes) == 0 {
+			return fmt.Errorf("no torrent to checksum")
+		}
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	newHash := hashers[algo]
+	if newHash == nil {
+		return fmt.Errorf("unsupported algo %q", algo)
+	}
+	if checkFile != "" && cmd.Flags().Changed("out") {
+		return fmt.Errorf("--out and --check flags are NOT compatible")
+	}
+	var manifest map[string]string
+	if checkFile != "" {
+		var err error
+		if manifest, err = readManifest(checkFile, newHash().Size()); err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+	}
+	savePathMapper, err := common.GetSavePathMapper(clientName, mapSavePaths, false)
+	if err != nil {
+		return err
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if checkFile == "" && output != "-" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	errorCnt := int64(0)
+	checked := map[string]bool{}
+	for _, torrent := range torrents {
+		savePath := torrent.SavePath
+		if savePathMapper != nil {
+			var match bool
+			if savePath, match = savePathMapper.Before2After(torrent.SavePath); !match {
+				fmt.Fprintf(os.Stderr, "✕ %s (%s): save path %q does not match with any map-save-path rule\n",
+					torrent.InfoHash, torrent.Name, torrent.SavePath)
+				errorCnt++
+				continue
+			}
+		}
+		contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✕ %s (%s): failed to get torrent contents: %v\n", torrent.InfoHash, torrent.Name, err)
+			errorCnt++
+			continue
+		}
+		for _, file := range contents {
+			if manifest != nil {
+				sum, ok := manifest[file.Path]
+				if !ok {
+					continue
+				}
+				checked[file.Path] = true
+				if !file.Complete {
+					fmt.Printf("✕ %s: not downloaded\n", file.Path)
+					errorCnt++
+				} else if localSum, err := fileChecksum(filepath.Join(savePath, file.Path), newHash); err != nil {
+					fmt.Printf("✕ %s: %v\n", file.Path, err)
+					errorCnt++
+				} else if localSum != sum {
+					fmt.Printf("✕ %s: checksum mismatch (%s)\n", file.Path, localSum)
+					errorCnt++
+				} else {
+					fmt.Printf("✓ %s\n", file.Path)
+				}
+				continue
+			}
+			if file.Ignored || !file.Complete {
+				continue
+			}
+			sum, err := fileChecksum(filepath.Join(savePath, file.Path), newHash)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✕ %s (%s): %s: %v\n", torrent.InfoHash, torrent.Name, file.Path, err)
+				errorCnt++
+				continue
+			}
+			fmt.Fprintf(out, "%s  %s\n", sum, file.Path)
+		}
+	}
+	if manifest != nil {
+		files := maps.Keys(manifest)
+		slices.Sort(files)
+		for _, file := range files {
+			if !checked[file] {
+				fmt.Printf("✕ %s: not found in torrents\n", file)
+				errorCnt++
+			}
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
+	return nil
+}
+
+// Return the hex checksum of file.
+func fileChecksum(filename string, newHash func() hash.Hash) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := newHash()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Read a "sha256sum" format manifest file, return file path => (lower case hex) checksum map.
+// The "<checksum> *<file path>" (binary mode) lines are also accepted. Empty or "#" comment lines are skipped.
+func readManifest(filename string, hashSize int) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	manifest := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, path, found := strings.Cut(line, " ")
+		path = strings.TrimPrefix(strings.TrimPrefix(path, " "), "*")
+		if !found || path == "" || len(sum) != hashSize*2 {
+			return nil, fmt.Errorf("invalid line %d: %q", lineNo, line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("invalid checksum in line %d: %w", lineNo, err)
+		}
+		manifest[path] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,6 @@
+package all
+
+import (
+	_ "github.com/sagan/ptool/cmd/clientcmd"
+	_ "github.com/sagan/ptool/cmd/clientcmd/inspect"
+)
@@ -0,0 +1,18 @@
+package clientcmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+)
+
+var Command = &cobra.Command{
+	Use:   "client",
+	Short: "Inspect BitTorrent clients in config file.",
+	Long:  `Inspect BitTorrent clients in config file.`,
+	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Command)
+}
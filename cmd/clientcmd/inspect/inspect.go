@@ -0,0 +1,436 @@
+package inspect
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/clientcmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "inspect {client} [--search-path path]... [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "client.inspect"},
+	Short:       "Detect whether client runs in Docker (different file system) and suggest pathMappings.",
+	Long: `Detect whether client runs in Docker (different file system) and suggest pathMappings.
+
+It gets the save paths of client (the default save path, save paths of categories and torrents),
+and probes them in the local file system of ptool: a save path is accessible if it exists locally
+(after applying the "pathMappings" of client in config file) and contents of (sampled) completed torrents
+in it are found. Save path status: "ok" (accessible in the same path), "mapped" (accessible via pathMappings),
+"mismatch" (path exists locally but torrent contents are not found) or "missing".
+
+For inaccessible save paths, it searches the local dirs (up to --search-depth levels) of --search-path
+(default: "/mnt", "/media", "/srv", "/data", "/volume1", "/share" and home dir)
+for the dir that contains the torrent contents, and generates "pathMappings" entries
+(format: "client_save_path|ptool_save_path") which can be added to the [[clients]] block of config file.
+The "pathMappings" are used by path-sensitive commands (e.g. "findalone", "movedata") automatically.
+
+Example:
+  ptool client inspect local --search-path /mnt/nas`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: inspect,
+}
+
+const (
+	STATUS_OK       = "ok"
+	STATUS_MAPPED   = "mapped"
+	STATUS_MISMATCH = "mismatch"
+	STATUS_MISSING  = "missing"
+)
+
+// Default local dirs to search for the inaccessible save paths of client. Home dir is also searched.
+var defaultSearchPaths = []string{"/mnt", "/media", "/srv", "/data", "/volume1", "/share"}
+
+// Windows local (e.g. "D:/Downloads") or UNC path.
+var windowsPathRegexp = regexp.MustCompile(`^([a-zA-Z]:/|//[^/?.])`)
+
+var (
+	showJson    = false
+	sampleSize  = int64(0)
+	searchDepth = int64(0)
+	searchPaths []string
+)
+
+type SavePath struct {
+	Path      string   `json:"path"` // In client's view
+	Torrents  int64    `json:"torrents"`
+	LocalPath string   `json:"localPath"` // Path in ptool's view, mapped by configured pathMappings
+	Sampled   int64    `json:"sampled"`   // Number of sampled completed torrents
+	Found     int64    `json:"found"`     // Number of sampled torrents whose contents are found in LocalPath
+	Status    string   `json:"status"`
+	Suggested string   `json:"suggested,omitempty"` // Local path found by searching. Only for inaccessible path
+	samples   []string // Content paths (relative to save path) of sampled torrents
+}
+
+type Report struct {
+	Client        string      `json:"client"`
+	Type          string      `json:"type"`
+	Url           string      `json:"url"`
+	LocalHost     bool        `json:"localHost"`     // Client url host is this machine
+	PtoolInDocker bool        `json:"ptoolInDocker"` // ptool itself runs in Docker
+	PathMappings  []string    `json:"pathMappings"`  // Configured pathMappings of client
+	SavePaths     []*SavePath `json:"savePaths"`
+	Suggestions   []string    `json:"suggestions"` // Suggested new pathMappings entries
+	Verdict       string      `json:"verdict"`
+}
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().Int64VarP(&sampleSize, "sample", "", 5,
+		"Number of completed torrents of each save path to check contents of")
+	command.Flags().Int64VarP(&searchDepth, "search-depth", "", 2, "Max depth of dirs of --search-path to search")
+	command.Flags().StringArrayVarP(&searchPaths, "search-path", "", nil,
+		"Local dir to search for inaccessible save paths of client. Can be set multiple times")
+	clientcmd.Command.AddCommand(command)
+}
+
+func inspect(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	pathMapper, err := common.GetClientPathMapper(clientName)
+	if err != nil {
+		return err
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	clientSavePaths, err := common.GetClientSavePaths(clientInstance, torrents)
+	if err != nil {
+		return fmt.Errorf("failed to get client save paths: %w", err)
+	}
+	clientConfig := clientInstance.GetClientConfig()
+	report := &Report{
+		Client:       clientName,
+		Type:         clientConfig.Type,
+		Url:          clientConfig.Url,
+		LocalHost:    isLocalHost(clientConfig.Url),
+		PathMappings: clientConfig.PathMappings,
+		Suggestions:  []string{},
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		report.PtoolInDocker = true
+	}
+	savePaths := map[string]*SavePath{}
+	for _, clientSavePath := range clientSavePaths {
+		savePath := &SavePath{Path: clientSavePath}
+		savePaths[clientSavePath] = savePath
+		report.SavePaths = append(report.SavePaths, savePath)
+	}
+	for _, torrent := range torrents {
+		savePath := savePaths[util.CleanPath(torrent.SavePath)]
+		if savePath == nil {
+			continue
+		}
+		savePath.Torrents++
+		if torrent.SizeCompleted > 0 && int64(len(savePath.samples)) < sampleSize {
+			savePath.samples = append(savePath.samples, relativeContentPath(torrent))
+		}
+	}
+
+	for _, savePath := range report.SavePaths {
+		savePath.LocalPath = savePath.Path
+		if pathMapper != nil {
+			if localPath, match := pathMapper.Before2After(savePath.Path); match {
+				savePath.LocalPath = localPath
+			}
+		}
+		savePath.Sampled = int64(len(savePath.samples))
+		savePath.Found = probe(savePath.LocalPath, savePath.samples)
+		switch {
+		case savePath.Found > 0 || savePath.Sampled == 0 && isDir(savePath.LocalPath):
+			if savePath.LocalPath == savePath.Path {
+				savePath.Status = STATUS_OK
+			} else {
+				savePath.Status = STATUS_MAPPED
+			}
+		case isDir(savePath.LocalPath):
+			savePath.Status = STATUS_MISMATCH
+		default:
+			savePath.Status = STATUS_MISSING
+		}
+	}
+	suggestPathMappings(report)
+	report.Verdict = verdict(report)
+
+	if showJson {
+		return util.PrintJson(os.Stdout, report)
+	}
+	host := "remote host"
+	if report.LocalHost {
+		host = "local host"
+	}
+	fmt.Printf("Client: %s (%s) %s (%s)\n", report.Client, report.Type, report.Url, host)
+	fmt.Printf("ptool: %s/%s (in Docker: %t)\n", runtime.GOOS, runtime.GOARCH, report.PtoolInDocker)
+	if len(report.PathMappings) > 0 {
+		fmt.Printf("Configured pathMappings: %s\n", strings.Join(report.PathMappings, ", "))
+	} else {
+		fmt.Printf("Configured pathMappings: <none>\n")
+	}
+	fmt.Printf("\n")
+	columns := []*util.TableColumn{
+		{Title: "Save Path"},
+		{Title: "Torrents", Width: 8, RightAlign: true},
+		{Title: "Found", Width: 7, RightAlign: true},
+		{Title: "Status", Width: 8},
+		{Title: "Local Path"},
+	}
+	rows := util.Map(report.SavePaths, func(savePath *SavePath) []string {
+		localPath := savePath.LocalPath
+		if savePath.Suggested != "" {
+			localPath = "=> " + savePath.Suggested
+		} else if savePath.Status == STATUS_MISSING || savePath.Status == STATUS_MISMATCH {
+			localPath = "-"
+		}
+		return []string{savePath.Path, fmt.Sprint(savePath.Torrents),
+			fmt.Sprintf("%d/%d", savePath.Found, savePath.Sampled), savePath.Status, localPath}
+	})
+	util.PrintTable(os.Stdout, columns, rows, 0, false, false)
+	fmt.Printf("\nVerdict: %s\n", report.Verdict)
+	if len(report.Suggestions) > 0 {
+		pathMappings := mergePathMappings(report.PathMappings, report.Suggestions)
+		fmt.Printf("\nSuggested config (add it to the [[clients]] block of %q client in config file):\n\n", clientName)
+		fmt.Printf("pathMappings = [%s]\n", strings.Join(util.Map(pathMappings, func(rule string) string {
+			return "'" + rule + "'"
+		}), ", "))
+	}
+	return nil
+}
+
+// Search local dirs for the inaccessible save paths of report, and generate pathMappings suggestions.
+func suggestPathMappings(report *Report) {
+	var bases []string
+	var suggestedMapper *common.PathMapper
+	for _, savePath := range report.SavePaths {
+		if savePath.Status != STATUS_MISSING && savePath.Status != STATUS_MISMATCH || savePath.Sampled == 0 {
+			continue
+		}
+		if suggestedMapper != nil {
+			if localPath, match := suggestedMapper.Before2After(savePath.Path); match &&
+				probe(localPath, savePath.samples) > 0 {
+				savePath.Suggested = localPath
+				continue
+			}
+		}
+		if bases == nil {
+			bases = getSearchBases()
+			log.Debugf("Search %d local dirs for save paths", len(bases))
+		}
+		localPath, found := "", int64(0)
+		for _, candidate := range getCandidates(savePath.Path, bases) {
+			if cnt := probe(candidate, savePath.samples); cnt > found ||
+				cnt == found && cnt > 0 && len(candidate) < len(localPath) {
+				localPath, found = candidate, cnt
+			}
+		}
+		if found == 0 {
+			continue
+		}
+		savePath.Suggested = localPath
+		report.Suggestions = append(report.Suggestions, mappingRule(savePath.Path, localPath))
+		suggestedMapper, _ = common.NewPathMapper(report.Suggestions)
+	}
+	if suggestedMapper == nil {
+		return
+	}
+	// save paths without completed torrents (e.g. of empty categories)
+	for _, savePath := range report.SavePaths {
+		if savePath.Status != STATUS_MISSING || savePath.Sampled > 0 {
+			continue
+		}
+		if localPath, match := suggestedMapper.Before2After(savePath.Path); match && isDir(localPath) {
+			savePath.Suggested = localPath
+		}
+	}
+}
+
+func verdict(report *Report) string {
+	inaccessible, mapped, windowsPaths := 0, 0, 0
+	for _, savePath := range report.SavePaths {
+		switch savePath.Status {
+		case STATUS_MISSING, STATUS_MISMATCH:
+			inaccessible++
+		case STATUS_MAPPED:
+			mapped++
+		}
+		if windowsPathRegexp.MatchString(savePath.Path) {
+			windowsPaths++
+		}
+	}
+	suffix := ""
+	if len(report.Suggestions) > 0 {
+		suffix = `. Add the suggested "pathMappings" to config file`
+	} else if inaccessible > 0 {
+		suffix = `. No local dir matches, use --search-path flag to specify where the files are mounted in ptool`
+	}
+	switch {
+	case len(report.SavePaths) == 0:
+		return "no save path found in client"
+	case inaccessible == 0 && mapped == 0:
+		return "client shares the same file system with ptool, no pathMappings needed"
+	case inaccessible == 0:
+		return "client uses a different file system (e.g. Docker), the configured pathMappings work"
+	case windowsPaths > 0 != (runtime.GOOS == "windows"):
+		return "client runs on a different OS than ptool, its files are only accessible via network shares" + suffix
+	case report.LocalHost:
+		return "client runs on this host but sees different paths, it most likely runs in Docker " +
+			"(or another container) with volume mounts" + suffix
+	default:
+		return "client runs on a remote host (or in Docker on it), its files are only accessible via network shares" +
+			suffix
+	}
+}
+
+// Return the content path (relative to save path) of torrent.
+func relativeContentPath(torrent *client.Torrent) string {
+	if torrent.ContentPath != "" {
+		savePath := strings.TrimSuffix(util.CleanPath(torrent.SavePath), "/") + "/"
+		if contentPath := util.CleanPath(torrent.ContentPath); strings.HasPrefix(contentPath, savePath) {
+			return contentPath[len(savePath):]
+		}
+	}
+	return torrent.Name
+}
+
+// Return the number of contents (relative paths) that exist in local dir.
+func probe(dir string, contents []string) (found int64) {
+	for _, content := range contents {
+		if _, err := os.Stat(util.LongPath(util.JoinPath(dir, content))); err == nil {
+			found++
+		}
+	}
+	return found
+}
+
+func isDir(dir string) bool {
+	stat, err := os.Stat(util.LongPath(dir))
+	return err == nil && stat.IsDir()
+}
+
+// Return the local dirs of search paths (up to searchDepth levels, including search paths themselves).
+func getSearchBases() []string {
+	roots := searchPaths
+	if len(roots) == 0 {
+		roots = util.CopySlice(defaultSearchPaths)
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			roots = append(roots, homeDir)
+		}
+	}
+	bases := []string{}
+	var walk func(dir string, depth int64)
+	walk = func(dir string, depth int64) {
+		bases = append(bases, dir)
+		if depth >= searchDepth {
+			return
+		}
+		entries, err := os.ReadDir(util.LongPath(dir))
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				walk(util.JoinPath(dir, entry.Name()), depth+1)
+			}
+		}
+	}
+	for _, root := range roots {
+		if isDir(root) {
+			walk(util.CleanPath(root), 0)
+		}
+	}
+	return bases
+}
+
+// Return the candidate local paths of client save path: each base dir joined with
+// each trailing part of save path, e.g. for "/downloads/tv": "{base}", "{base}/tv", "{base}/downloads/tv".
+func getCandidates(clientSavePath string, bases []string) []string {
+	parts := splitPath(clientSavePath)
+	candidates := []string{}
+	for _, base := range bases {
+		for i := len(parts); i >= 0; i-- {
+			candidates = append(candidates, util.JoinPath(base, strings.Join(parts[i:], "/")))
+		}
+	}
+	return candidates
+}
+
+// Return the pathMappings rule "client_save_path|ptool_save_path" of the save path accessible in localPath.
+// The common trailing parts of both paths are stripped, e.g. "/downloads/tv" & "/mnt/nas/downloads/tv"
+// => "/downloads|/mnt/nas/downloads". Neither path is stripped to root (or the server of UNC path).
+func mappingRule(clientSavePath string, localPath string) string {
+	clientParts, localParts := splitPath(clientSavePath), splitPath(localPath)
+	for len(clientParts) > minParts(clientSavePath) && len(localParts) > minParts(localPath) &&
+		clientParts[len(clientParts)-1] == localParts[len(localParts)-1] {
+		clientSavePath = clientSavePath[:strings.LastIndex(clientSavePath, "/")]
+		localPath = localPath[:strings.LastIndex(localPath, "/")]
+		clientParts, localParts = clientParts[:len(clientParts)-1], localParts[:len(localParts)-1]
+	}
+	return clientSavePath + "|" + localPath
+}
+
+// Append the suggested rules to configured pathMappings, replacing configured rules of same client path.
+func mergePathMappings(pathMappings []string, suggestions []string) []string {
+	befores := map[string]struct{}{}
+	for _, rule := range suggestions {
+		before, _, _ := util.ParsePathMapperRule(rule)
+		befores[before] = struct{}{}
+	}
+	merged := util.Filter(pathMappings, func(rule string) bool {
+		before, _, err := util.ParsePathMapperRule(rule)
+		_, ok := befores[before]
+		return err == nil && !ok
+	})
+	return append(merged, suggestions...)
+}
+
+// Return the min number of parts of a path that is not root: 2 for UNC path ("//server/share"), 1 otherwise.
+func minParts(p string) int {
+	if util.IsUncPath(p) {
+		return 2
+	}
+	return 1
+}
+
+func splitPath(p string) []string {
+	return util.Filter(strings.Split(p, "/"), func(part string) bool { return part != "" })
+}
+
+// Report whether host of url is this machine.
+func isLocalHost(rawUrl string) bool {
+	urlObj, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	host := urlObj.Hostname()
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() {
+			return true
+		}
+		addrs, _ := net.InterfaceAddrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	hostname, err := os.Hostname()
+	return err == nil && strings.EqualFold(hostname, host)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/util"
 )
 
@@ -31,6 +32,8 @@ If '[={value}]' part is present, set the config, otherwise get current config.
 {value}: the value of config item to set. For config item of boolean type, use literal "false" or "true";
 for config item of size or speed type, use unit chars (B/K/M/G/T/P/E), e.g. "10M" means 10MiB or 10MiB/s.
 
+With --dry-run flag, it only displays the config items to set without actually setting them.
+
 Examples:
   ptool clientctl local save_path # display current default download dir
   ptool clientctl local global_upload_speed_limit=10M # set global upload speed limit of local to 10MiB/s
@@ -47,6 +50,20 @@ var (
 		{"global_upload_speed", 1, true, false, "Current global upload speed (/s)"},
 		{"free_disk_space", 2, true, false, "Current free disk space of default save path"},
 		{"save_path", 0, false, false, "Default save path"},
+		{"listen_port", 0, false, false, "Port for incoming peer connections"},
+		{"listen_port_open", 0, true, false,
+			"Whether the listen port is reachable from outside, tested by client (transmission only)"},
+		{"network_interface", 0, true, false, "Network interface that client binds to (qBittorrent only)"},
+		{"bind_address", 0, true, false, "IP address that client binds to (qBittorrent only)"},
+		{"queueing_enabled", 0, false, false,
+			"Whether torrent queueing is enabled (transmission: both download and seed queues)"},
+		{"max_active_downloads", 0, false, false, "Maximum number of active downloading torrents (if queueing enabled)"},
+		{"max_active_uploads", 0, false, false, "Maximum number of active seeding torrents (if queueing enabled)"},
+		{"max_active_torrents", 0, false, false, "Maximum number of active torrents (qBittorrent only)"},
+		{"alt_speed_enabled", 0, false, false,
+			"Whether the alternative speed limits are enabled (transmission: turtle mode)"},
+		{"alt_download_speed_limit", 1, false, false, "Alternative global download speed limit (/s)"},
+		{"alt_upload_speed_limit", 1, false, false, "Alternative global upload speed limit (/s)"},
 		{"qb_*", 0, false, false, "The qBittorrent specific preferences. " +
 			"For full list see https://github.com/qbittorrent/qBittorrent/wiki/" +
 			"WebUI-API-(qBittorrent-4.1)#get-application-preferences . E.g. qb_start_paused_enabled"},
@@ -95,6 +112,7 @@ func clientctl(cmd *cobra.Command, args []string) error {
 	}
 	args = args[1:]
 	errorCnt := int64(0)
+	var lastErr error
 	if len(args) == 0 {
 		args = []string{}
 		for _, option := range allOptions {
@@ -117,6 +135,8 @@ func clientctl(cmd *cobra.Command, args []string) error {
 				if err != nil {
 					log.Errorf("Error get %s: %v", name, err)
 				}
+			} else if common.DryRun("set client %s config %s=%s", clientInstance.GetName(), name, s[1]) {
+				continue
 			} else {
 				value = s[1]
 				err = clientInstance.SetConfig(name, value)
@@ -132,6 +152,7 @@ func clientctl(cmd *cobra.Command, args []string) error {
 				}
 			} else {
 				errorCnt++
+				lastErr = err
 			}
 			continue
 		}
@@ -145,6 +166,7 @@ func clientctl(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				log.Errorf("Error get client %s config %s: %v", clientInstance.GetName(), name, err)
 				errorCnt++
+				lastErr = err
 			}
 		} else {
 			if option.Readonly {
@@ -153,6 +175,17 @@ func clientctl(cmd *cobra.Command, args []string) error {
 				continue
 			}
 			value = s[1]
+			if name == "listen_port" {
+				if _, err = client.ParseListenPort(value); err != nil {
+					log.Errorf("Error set client %s config %s: %v", clientInstance.GetName(), name, err)
+					errorCnt++
+					lastErr = err
+					continue
+				}
+			}
+			if common.DryRun("set client %s config %s=%s", clientInstance.GetName(), name, value) {
+				continue
+			}
 			if option.Type > 0 {
 				v, _ := util.RAMInBytes(value)
 				err = clientInstance.SetConfig(name, fmt.Sprint(v))
@@ -163,6 +196,7 @@ func clientctl(cmd *cobra.Command, args []string) error {
 				log.Errorf("Error set client %s config %s=%s: %v", clientInstance.GetName(), name, value, err)
 				value = ""
 				errorCnt++
+				lastErr = err
 			}
 		}
 		if showValuesOnly {
@@ -171,10 +205,7 @@ func clientctl(cmd *cobra.Command, args []string) error {
 			printOption(name, value, option, showRaw)
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(args)), lastErr)
 }
 
 func printOption(name string, value string, option Option, showRaw bool) {
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -19,28 +21,64 @@ import (
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/osutil"
 )
 
+// Annotation of cmds which do not use any secret of config file, e.g. "version".
+// Encrypted secrets of config file are not decrypted for these cmds, so they work without master password.
+const ANNOTATION_NO_SECRETS = "ptool-no-secrets"
+
+// Cobra built-in top-level cmds, which do not use any secret of config file.
+var builtinCommands = []string{"help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}
+
 // Root represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "ptool",
 	Short: "ptool is a command-line program which facilitates the use of private tracker sites and BitTorrent clients.",
 	Long: `ptool is a command-line program which facilitates the use of private tracker sites and BitTorrent clients.
 It's a free and open-source software released under the AGPL-3.0 license,
-visit https://github.com/sagan/ptool for source codes and other infomation.`,
+visit https://github.com/sagan/ptool for source codes and other infomation.
+
+Exit codes:
+  0 : success
+  1 : general error
+  2 : partial failure (some of the items processed by command failed)
+  3 : authentication error (e.g. wrong client password, site cookie expired)
+  4 : network error (e.g. client or site is unreachable)
+  5 : not found (e.g. client, site or torrent does not exist)`,
 	// Run: func(cmd *cobra.Command, args []string) { },
 	SilenceErrors:      true,
 	SilenceUsage:       true,
 	DisableSuggestions: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if config.InShell {
+			config.ReloadIfChanged()
+		}
+		if usesSecrets(cmd) {
+			if err := config.LoadError(); err != nil {
+				return err
+			}
+		}
 		if config.InShell && config.Get().ShellMaxHistory > 0 && (os.Args[1] != "exit" && os.Args[1] != "exitf") {
 			in := strings.Join(os.Args[1:], " ")
 			ShellHistory.Write(in)
 		}
+		return applyCommandDefaults(cmd)
 	},
 }
 
+// Return true if command (or any of it's parent cmds) is NOT annotated with ANNOTATION_NO_SECRETS.
+func usesSecrets(command *cobra.Command) bool {
+	for c := command; c != nil; c = c.Parent() {
+		if c.Annotations[ANNOTATION_NO_SECRETS] != "" ||
+			c.Parent() == c.Root() && slices.Contains(builtinCommands, c.Name()) {
+			return false
+		}
+	}
+	return true
+}
+
 var (
 	shellCompletions = map[string](func(document *prompt.Document) []prompt.Suggest){}
 	ShellHistory     *ShellHistoryStruct
@@ -127,10 +165,32 @@ func Execute() {
 		}
 		if err != nil {
 			fmt.Printf("Error: %v.\n", err)
-			Exit(1)
+			Exit(ExitCode(err))
 		}
 	}
-	Exit(0)
+	Exit(constants.EXIT_OK)
+}
+
+// Return the process exit code of the error returned by command, by it's typed error category.
+// A network error that is not explicitly typed (e.g. connection refused) is also detected.
+func ExitCode(err error) int {
+	var exitCodeErr *constants.ExitCodeError
+	switch {
+	case err == nil:
+		return constants.EXIT_OK
+	case errors.As(err, &exitCodeErr) && exitCodeErr.Code > 0:
+		return exitCodeErr.Code
+	case errors.Is(err, constants.ErrAuth):
+		return constants.EXIT_AUTH
+	case errors.Is(err, constants.ErrNetwork) || util.AsNetworkError(err):
+		return constants.EXIT_NETWORK
+	case errors.Is(err, constants.ErrNotFound):
+		return constants.EXIT_NOTFOUND
+	case errors.Is(err, constants.ErrPartial):
+		return constants.EXIT_PARTIAL
+	default:
+		return constants.EXIT_ERROR
+	}
 }
 
 func init() {
@@ -154,6 +214,9 @@ func init() {
 	// global flags
 	RootCmd.PersistentFlags().BoolVarP(&flags.DumpHeaders, "dump-headers", "", false,
 		`Dump HTTP headers to log (error level) - may contain sensitive info`)
+	RootCmd.PersistentFlags().BoolVarP(&flags.DryRun, "dry-run", "", false,
+		"Dry run. Commands that modify clients, sites or files will only display the intended actions "+
+			"without actually executing them")
 	RootCmd.PersistentFlags().BoolVarP(&config.Insecure, "insecure", "", false,
 		`Temporarily disable all TLS / https cert verifications during this session. `+
 			`To permanently disable TLS cert verifications, `+
@@ -171,6 +234,12 @@ func init() {
 		`Temporarily set the http / network request timeout during this session (seconds). `+
 			`To set timeout permanently, add "siteTimeout = 5" line to the top of ptool.toml config file. `+
 			`-1 == infinite`)
+	RootCmd.PersistentFlags().Int64VarP(&config.Concurrency, "concurrency", "", 0,
+		`Temporarily set the max concurrency of commands that process multiple sites or clients `+
+			`(e.g. "status", "search") during this session. -1 == unlimited`)
+	RootCmd.PersistentFlags().Int64VarP(&config.ItemTimeout, "item-timeout", "", 0,
+		`Temporarily set the timeout (seconds) of processing each site or client by commands that `+
+			`process multiple sites or clients during this session. -1 == infinite`)
 	RootCmd.PersistentFlags().StringVarP(&config.ConfigFile, "config", "", config.DefaultConfigFile,
 		"Config file ([ptool.toml])")
 	RootCmd.PersistentFlags().StringVarP(&config.LockFile, "lock", "", "",
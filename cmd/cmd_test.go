@@ -0,0 +1,45 @@
+package cmd_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+func TestExitCode(t *testing.T) {
+	authErr := fmt.Errorf("failed to login: %w", util.NewHttpStatusError(401))
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, constants.EXIT_OK},
+		{"general", fmt.Errorf("abort"), constants.EXIT_ERROR},
+		{"auth", authErr, constants.EXIT_AUTH},
+		{"forbidden", util.NewHttpStatusError(403), constants.EXIT_AUTH},
+		{"not found", util.NewHttpStatusError(404), constants.EXIT_NOTFOUND},
+		{"server error", util.NewHttpStatusError(502), constants.EXIT_NETWORK},
+		{"other status", util.NewHttpStatusError(400), constants.EXIT_ERROR},
+		{"net error", fmt.Errorf("failed: %w", &net.OpError{Op: "dial", Err: fmt.Errorf("refused")}),
+			constants.EXIT_NETWORK},
+		{"partial", fmt.Errorf("%w: %d errors", constants.ErrPartial, 1), constants.EXIT_PARTIAL},
+		{"items none failed", common.ItemsError(0, 3, authErr), constants.EXIT_OK},
+		{"items some failed", common.ItemsError(2, 3, authErr), constants.EXIT_PARTIAL},
+		{"items all failed", common.ItemsError(3, 3, authErr), constants.EXIT_AUTH},
+		{"items all failed without error", common.ItemsError(3, 3, nil), constants.EXIT_PARTIAL},
+		{"explicit code", fmt.Errorf("run: %w", &constants.ExitCodeError{Code: 42, Err: authErr}), 42},
+		{"explicit code not positive", &constants.ExitCodeError{Code: -1, Err: authErr}, constants.EXIT_AUTH},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := cmd.ExitCode(test.err); result != test.expected {
+				t.Errorf("expected %d, got %d (err: %v)", test.expected, result, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+// Pacer of torrents announcing to trackers. Clients announce a torrent to it's trackers immediately on adding,
+// adding a lot of torrents of the same tracker in a short time may trigger the rate limit of tracker.
+// The pacer staggers the adding of torrents so that the interval between two torrents of the same tracker domain
+// is at least "interval".
+type AnnouncePacer struct {
+	interval      time.Duration
+	lastAnnounces map[string]time.Time // tracker domain => last announce time
+}
+
+// interval: the "--announce-interval" flag value (seconds). If <= 0, the pacer does nothing.
+func NewAnnouncePacer(interval int64) *AnnouncePacer {
+	return &AnnouncePacer{
+		interval:      time.Duration(interval) * time.Second,
+		lastAnnounces: map[string]time.Time{},
+	}
+}
+
+// Wait until a torrent of trackers is allowed to be added to client (announced), and mark it's trackers
+// as announced. Should be called just before adding each torrent to client.
+func (pacer *AnnouncePacer) Wait(trackers []string) {
+	if pacer == nil || pacer.interval <= 0 || flags.DryRun {
+		return
+	}
+	var domains []string
+	for _, tracker := range trackers {
+		if domain := util.GetUrlDomain(tracker); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	wait := time.Duration(0)
+	for _, domain := range domains {
+		if lastAnnounce, ok := pacer.lastAnnounces[domain]; ok {
+			wait = max(wait, time.Until(lastAnnounce.Add(pacer.interval)))
+		}
+	}
+	if wait > 0 {
+		log.Infof("Announce pacing: wait %v before adding torrent of trackers %v", wait.Round(time.Second), domains)
+		time.Sleep(wait)
+	}
+	now := time.Now()
+	for _, domain := range domains {
+		pacer.lastAnnounces[domain] = now
+	}
+}
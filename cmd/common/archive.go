@@ -0,0 +1,253 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	ARCHIVE_MANIFEST_FILE = "manifest.json"
+	ARCHIVE_TORRENT_FILE  = "torrent.torrent"
+)
+
+// A torrent removed from client (with content files kept in place) by "ptool archive",
+// which can be re-added by "ptool unarchive". It records the metainfo and resume state of torrent.
+// The exported .torrent file and manifest are stored in "<archive_dir>/<client>/<infoHash>/".
+type ArchiveEntry struct {
+	Time               int64            `json:"time"`
+	Client             string           `json:"client"`
+	InfoHash           string           `json:"infoHash"`
+	Name               string           `json:"name"`
+	Size               int64            `json:"size"`
+	SizeCompleted      int64            `json:"sizeCompleted"`
+	Category           string           `json:"category"`
+	Tags               []string         `json:"tags"`
+	Paused             bool             `json:"paused"`
+	SavePath           string           `json:"savePath"`
+	Meta               map[string]int64 `json:"meta"`
+	Atime              int64            `json:"atime"`
+	Ctime              int64            `json:"ctime"`
+	Downloaded         int64            `json:"downloaded"`
+	Uploaded           int64            `json:"uploaded"`
+	DownloadSpeedLimit int64            `json:"downloadSpeedLimit"`
+	UploadSpeedLimit   int64            `json:"uploadSpeedLimit"`
+	SequentialDownload bool             `json:"sequentialDownload"`
+	Trackers           []string         `json:"trackers"`
+	SkippedFiles       []int64          `json:"skippedFiles"` // indexes of files that are not downloaded
+}
+
+// Return the dir of the archive entry that stores manifest and .torrent file.
+func (entry *ArchiveEntry) Dir() string {
+	return filepath.Join(config.GetArchiveDir(), entry.Client, entry.InfoHash)
+}
+
+// Return true if all (selected) files of the torrent were downloaded when it was archived.
+func (entry *ArchiveEntry) Complete() bool {
+	return entry.SizeCompleted >= entry.Size
+}
+
+// Archive the torrent of client: export the .torrent file and save the resume state to archive dir,
+// then delete the torrent (without files) from client.
+func ArchiveTorrent(clientInstance client.Client, torrent *client.Torrent) (entry *ArchiveEntry, err error) {
+	entry = &ArchiveEntry{
+		Time:               util.Now(),
+		Client:             clientInstance.GetName(),
+		InfoHash:           torrent.InfoHash,
+		Name:               torrent.Name,
+		Size:               torrent.Size,
+		SizeCompleted:      torrent.SizeCompleted,
+		Category:           torrent.Category,
+		Tags:               torrent.Tags,
+		Paused:             torrent.State == "paused",
+		SavePath:           torrent.SavePath,
+		Meta:               torrent.Meta,
+		Atime:              torrent.Atime,
+		Ctime:              torrent.Ctime,
+		Downloaded:         torrent.Downloaded,
+		Uploaded:           torrent.Uploaded,
+		DownloadSpeedLimit: torrent.DownloadSpeedLimit,
+		UploadSpeedLimit:   torrent.UploadedSpeedLimit,
+		SequentialDownload: torrent.SequentialDownload,
+	}
+	content, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export torrent: %w", err)
+	}
+	trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trackers: %w", err)
+	}
+	for _, tracker := range trackers {
+		entry.Trackers = append(entry.Trackers, tracker.Url)
+	}
+	contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	for _, file := range contents {
+		if file.Ignored {
+			entry.SkippedFiles = append(entry.SkippedFiles, file.Index)
+		}
+	}
+	if err = os.MkdirAll(entry.Dir(), constants.PERM_DIR); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(entry.Dir())
+		}
+	}()
+	if err = os.WriteFile(filepath.Join(entry.Dir(), ARCHIVE_TORRENT_FILE), content, constants.PERM); err != nil {
+		return nil, err
+	}
+	if err = entry.save(); err != nil {
+		return nil, err
+	}
+	if err = clientInstance.DeleteTorrents([]string{torrent.InfoHash}, false); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Re-add the archived torrent to client with it's original save path, category, tags, trackers,
+// file priorities and state. If the torrent was complete (and had no skipped files) when archived,
+// hash checking is skipped unless check is true. The archive entry is removed only if all steps succeed.
+func (entry *ArchiveEntry) Unarchive(clientInstance client.Client, check bool) error {
+	content, err := os.ReadFile(filepath.Join(entry.Dir(), ARCHIVE_TORRENT_FILE))
+	if err != nil {
+		return err
+	}
+	option := &client.TorrentOption{
+		Category: entry.Category,
+		Tags:     entry.Tags,
+		SavePath: entry.SavePath,
+		Pause:    entry.Paused || len(entry.SkippedFiles) > 0,
+		// Skipped files are not set until torrent is added, so to skip checking would make client treat them
+		// as downloaded.
+		SkipChecking:       !check && entry.Complete() && len(entry.SkippedFiles) == 0,
+		SequentialDownload: entry.SequentialDownload,
+	}
+	if entry.DownloadSpeedLimit > 0 {
+		option.DownloadSpeedLimit = entry.DownloadSpeedLimit
+	}
+	if entry.UploadSpeedLimit > 0 {
+		option.UploadSpeedLimit = entry.UploadSpeedLimit
+	}
+	if err = clientInstance.AddTorrent(content, option, entry.Meta); err != nil {
+		return fmt.Errorf("failed to add torrent: %w", err)
+	}
+	if err = SyncTorrentTrackers(clientInstance, entry.InfoHash, entry.Trackers, content); err != nil {
+		return fmt.Errorf("failed to restore trackers: %w", err)
+	}
+	// the torrent is added as paused, so that the skipped files are not downloaded before being set
+	if len(entry.SkippedFiles) > 0 {
+		waitTorrentAdded(clientInstance, entry.InfoHash)
+		if err = clientInstance.SetFilePriority(entry.InfoHash, entry.SkippedFiles, 0); err != nil {
+			return fmt.Errorf("failed to restore file priorities: %w", err)
+		}
+		if !entry.Paused {
+			if err = clientInstance.ResumeTorrents([]string{entry.InfoHash}); err != nil {
+				return fmt.Errorf("failed to resume torrent: %w", err)
+			}
+		}
+	}
+	return os.RemoveAll(entry.Dir())
+}
+
+func (entry *ArchiveEntry) save() error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry.Dir(), ARCHIVE_MANIFEST_FILE), data, constants.PERM)
+}
+
+// Load all archive entries of client, in time order.
+func LoadArchiveEntries(clientName string) ([]*ArchiveEntry, error) {
+	dir := filepath.Join(config.GetArchiveDir(), clientName)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries := []*ArchiveEntry{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		manifestFile := filepath.Join(dir, dirEntry.Name(), ARCHIVE_MANIFEST_FILE)
+		data, err := os.ReadFile(manifestFile)
+		if err != nil {
+			log.Warnf("Failed to read archive manifest %q: %v", manifestFile, err)
+			continue
+		}
+		entry := &ArchiveEntry{}
+		if err = json.Unmarshal(data, entry); err != nil || entry.InfoHash != dirEntry.Name() {
+			log.Warnf("Invalid archive manifest %q: %v", manifestFile, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+	return entries, nil
+}
+
+// Make the trackers of the just added torrent same as trackers,
+// which may differ from the trackers of .torrent file content (e.g. edited by user).
+func SyncTorrentTrackers(clientInstance client.Client, infoHash string, trackers []string, content []byte) error {
+	if len(trackers) == 0 {
+		return nil
+	}
+	tinfo, err := torrentutil.ParseTorrent(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	addTrackers := util.Filter(trackers, func(tracker string) bool {
+		return !slices.Contains(tinfo.Trackers, tracker)
+	})
+	removeTrackers := util.Filter(tinfo.Trackers, func(tracker string) bool {
+		return !slices.Contains(trackers, tracker)
+	})
+	if len(addTrackers) == 0 && len(removeTrackers) == 0 {
+		return nil
+	}
+	waitTorrentAdded(clientInstance, infoHash)
+	if len(addTrackers) > 0 {
+		if err := clientInstance.AddTorrentTrackers(infoHash, addTrackers, "", false); err != nil {
+			return err
+		}
+	}
+	if len(removeTrackers) > 0 {
+		if err := clientInstance.RemoveTorrentTrackers(infoHash, removeTrackers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait (at most 5 seconds) for client to finish adding torrent.
+func waitTorrentAdded(clientInstance client.Client, infoHash string) {
+	for i := 0; i < 5; i++ {
+		if torrent, err := clientInstance.GetTorrent(infoHash); err == nil && torrent != nil {
+			break
+		}
+		clientInstance.PurgeCache()
+		util.Sleep(1)
+	}
+}
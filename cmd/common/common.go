@@ -3,11 +3,11 @@ package common
 import (
 	"fmt"
 	"io"
-	"path"
 	"slices"
 	"strings"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/torrentutil"
 )
@@ -94,13 +94,13 @@ type PathMapper struct {
 }
 
 func (spm *PathMapper) Before2After(beforePath string) (afterPath string, match bool) {
-	beforePath = path.Clean(util.ToSlash(beforePath))
+	beforePath = util.CleanPath(beforePath)
 	for _, before := range spm.befores {
 		if before == "/" {
 			if strings.HasPrefix(beforePath, before) {
 				return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
 			}
-		} else if strings.HasPrefix(beforePath, before+"/") {
+		} else if beforePath == before || strings.HasPrefix(beforePath, before+"/") {
 			return spm.mapper[before] + strings.TrimPrefix(beforePath, before), true
 		}
 	}
@@ -108,14 +108,14 @@ func (spm *PathMapper) Before2After(beforePath string) (afterPath string, match
 }
 
 func (spm *PathMapper) After2Before(afterPath string) (beforePath string, match bool) {
-	afterPath = path.Clean(util.ToSlash(afterPath))
+	afterPath = util.CleanPath(afterPath)
 	for _, before := range spm.befores {
 		after := spm.mapper[before]
 		if after == "/" {
 			if strings.HasPrefix(afterPath, after) {
 				return before + strings.TrimPrefix(afterPath, after), true
 			}
-		} else if strings.HasPrefix(afterPath, after+"/") {
+		} else if afterPath == after || strings.HasPrefix(afterPath, after+"/") {
 			return before + strings.TrimPrefix(afterPath, after), true
 		}
 	}
@@ -127,20 +127,90 @@ func NewPathMapper(rules []string) (*PathMapper, error) {
 		mapper: map[string]string{},
 	}
 	for _, rule := range rules {
-		sep := "|"
-		// use ":" as sep only when "|" not exists and no Windows abs path (e.g. "E:\Downloads") exists
-		if !strings.Contains(rule, "|") && !strings.Contains(rule, `:\`) {
-			sep = ":"
+		before, after, err := util.ParsePathMapperRule(rule)
+		if err != nil {
+			return nil, err
 		}
-		before, after, found := strings.Cut(rule, sep)
-		if !found || before == "" || after == "" {
-			return nil, fmt.Errorf("invalid path mapper rule %q", rule)
-		}
-		before = path.Clean(util.ToSlash(before))
-		after = path.Clean(util.ToSlash(after))
 		pm.mapper[before] = after
 		pm.befores = append(pm.befores, before)
 	}
 	slices.SortFunc(pm.befores, func(a, b string) int { return len(b) - len(a) }) // longest first
 	return pm, nil
 }
+
+// Return the reversed mapper, which maps "after" paths to "before" paths of spm.
+func (spm *PathMapper) Reverse() *PathMapper {
+	pm := &PathMapper{
+		mapper: map[string]string{},
+	}
+	for before, after := range spm.mapper {
+		pm.mapper[after] = before
+		pm.befores = append(pm.befores, after)
+	}
+	slices.SortFunc(pm.befores, func(a, b string) int { return len(b) - len(a) })
+	return pm
+}
+
+// Return the path mapper created from the "pathMappings" of client in config file,
+// which maps client save paths to the ones of ptool's file system. Return nil if client has no path mappings.
+func GetClientPathMapper(clientName string) (*PathMapper, error) {
+	clientConfig := config.GetClientConfig(clientName)
+	if clientConfig == nil || len(clientConfig.PathMappings) == 0 {
+		return nil, nil
+	}
+	pm, err := NewPathMapper(clientConfig.PathMappings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pathMappings of client %s: %w", clientName, err)
+	}
+	return pm, nil
+}
+
+// Return the save path mapper of a command that accesses local files of client torrents:
+// created from the "--map-save-path" flag rules if set, otherwise from the "pathMappings" of client.
+// The mapper maps client save paths to ptool ones, unless localFirst is true, in which case the flag rules are in
+// "ptool_save_path|client_save_path" format and the mapper of client config is reversed. Return nil if none.
+func GetSavePathMapper(clientName string, rules []string, localFirst bool) (*PathMapper, error) {
+	if len(rules) > 0 {
+		pm, err := NewPathMapper(rules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid map-save-path(s): %w", err)
+		}
+		return pm, nil
+	}
+	pm, err := GetClientPathMapper(clientName)
+	if pm != nil && localFirst {
+		pm = pm.Reverse()
+	}
+	return pm, err
+}
+
+// Return the (sorted, deduplicated) save paths of client in client's view: default save path,
+// save paths of categories and torrents.
+func GetClientSavePaths(clientInstance client.Client, torrents []*client.Torrent) ([]string, error) {
+	defaultSavePath, err := clientInstance.GetConfig("save_path")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default save path: %w", err)
+	}
+	categories, err := clientInstance.GetCategories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	savePaths := []string{}
+	if defaultSavePath != "" {
+		savePaths = append(savePaths, util.CleanPath(defaultSavePath))
+	}
+	for _, category := range categories {
+		if category.SavePath != "" {
+			savePaths = append(savePaths, util.CleanPath(category.SavePath))
+		} else if clientInstance.GetClientConfig().Type == "qbittorrent" && defaultSavePath != "" {
+			savePaths = append(savePaths, util.JoinPath(defaultSavePath, category.Name))
+		}
+	}
+	for _, torrent := range torrents {
+		if torrent.SavePath != "" {
+			savePaths = append(savePaths, util.CleanPath(torrent.SavePath))
+		}
+	}
+	slices.Sort(savePaths)
+	return slices.Compact(savePaths), nil
+}
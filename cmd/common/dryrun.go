@@ -0,0 +1,34 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/flags"
+)
+
+// If --dry-run flag is set, display the action and return true, the caller should NOT actually perform it.
+func DryRun(format string, args ...any) bool {
+	if !flags.DryRun {
+		return false
+	}
+	fmt.Printf("Dry run. Would "+format+"\n", args...)
+	return true
+}
+
+// Similar to DryRun, but for the action applied to torrents of client. infoHashes == nil means all torrents.
+// E.g. DryRunTorrents(clientInstance, infoHashes, "pause").
+func DryRunTorrents(clientInstance client.Client, infoHashes []string, action string) bool {
+	if !flags.DryRun {
+		return false
+	}
+	if infoHashes == nil {
+		fmt.Printf("Dry run. Would %s all torrents of client %s\n", action, clientInstance.GetName())
+		return true
+	}
+	fmt.Printf("Dry run. Would %s %d torrents of client %s\n", action, len(infoHashes), clientInstance.GetName())
+	for _, infoHash := range infoHashes {
+		fmt.Printf("%s\n", infoHash)
+	}
+	return true
+}
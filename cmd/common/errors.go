@@ -0,0 +1,20 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/sagan/ptool/constants"
+)
+
+// Return the error of a cmd which processed total items and errorCnt of them failed, or nil if none failed.
+// err is the error of (any) failed item. If all items failed, the returned error wraps err
+// instead of constants.ErrPartial, so the process exits with the exit code of it's category (e.g. ErrAuth).
+func ItemsError(errorCnt int64, total int64, err error) error {
+	if errorCnt <= 0 {
+		return nil
+	}
+	if errorCnt >= total && err != nil {
+		return fmt.Errorf("%d errors, last error: %w", errorCnt, err)
+	}
+	return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+}
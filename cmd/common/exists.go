@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// Values of "--if-exists" flag: the action if a torrent to add already exists in client.
+const (
+	IF_EXISTS_SKIP           = "skip"
+	IF_EXISTS_FAIL           = "fail"
+	IF_EXISTS_MERGE_TRACKERS = "merge-trackers"
+)
+
+// skip|fail|merge-trackers
+var IfExistsFlag = &cmd.EnumFlag{
+	Description: "Action if the torrent already exists in client",
+	Options: [][2]string{
+		{IF_EXISTS_SKIP, "skip it"},
+		{IF_EXISTS_FAIL, "treat it as an error"},
+		{IF_EXISTS_MERGE_TRACKERS, "add trackers of torrent to the existing one"},
+	},
+}
+
+var ErrTorrentExists = errors.New("torrent already exists in client")
+
+// Checker of torrents that already exist in client, used before adding torrents to client.
+type ExistingTorrentChecker struct {
+	clientInstance client.Client
+	ifExists       string
+	checkContent   bool
+	index          *torrentutil.ContentIndex // client torrents content index. Loaded on demand
+}
+
+// ifExists: "--if-exists" flag value. If checkContent is true, a client torrent that has the identical contents
+// (same content fingerprint) but different info-hash is also treated as existing.
+func NewExistingTorrentChecker(clientInstance client.Client, ifExists string,
+	checkContent bool) *ExistingTorrentChecker {
+	return &ExistingTorrentChecker{
+		clientInstance: clientInstance,
+		ifExists:       ifExists,
+		checkContent:   checkContent,
+	}
+}
+
+// Check whether torrent (tinfo) already exists in client, and handle it according to "--if-exists" flag.
+// Return the existing client torrent, or nil if it does not exist. If it exists, err is ErrTorrentExists
+// in "fail" mode; in "merge-trackers" mode, the trackers of tinfo are added to the existing torrent,
+// which is not possible for a torrent of identical contents but different info-hash, so it's skipped.
+func (checker *ExistingTorrentChecker) Check(tinfo *torrentutil.TorrentMeta) (
+	existing *client.Torrent, err error) {
+	if existing, err = checker.clientInstance.GetTorrent(tinfo.InfoHash); err != nil {
+		return nil, fmt.Errorf("failed to get client torrent: %w", err)
+	}
+	sameInfoHash := existing != nil
+	if existing == nil && checker.checkContent {
+		if checker.index == nil {
+			torrents, err := checker.clientInstance.GetTorrents("", "", true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get client torrents: %w", err)
+			}
+			checker.index = torrentutil.NewContentIndex()
+			checker.index.AddClientTorrents(checker.clientInstance.GetName(), checker.clientInstance, torrents)
+		}
+		if entries := checker.index.Find(tinfo); len(entries) > 0 {
+			existing = entries[0].ClientTorrent
+		}
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	switch checker.ifExists {
+	case IF_EXISTS_FAIL:
+		err = ErrTorrentExists
+	case IF_EXISTS_MERGE_TRACKERS:
+		if sameInfoHash {
+			err = checker.mergeTrackers(existing, tinfo)
+		}
+	}
+	return existing, err
+}
+
+func (checker *ExistingTorrentChecker) mergeTrackers(existing *client.Torrent,
+	tinfo *torrentutil.TorrentMeta) error {
+	trackers, err := checker.clientInstance.GetTorrentTrackers(existing.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrent trackers: %w", err)
+	}
+	var newTrackers []string
+	for _, tracker := range tinfo.Trackers {
+		if !slices.ContainsFunc(trackers, func(t client.TorrentTracker) bool { return t.Url == tracker }) &&
+			!slices.Contains(newTrackers, tracker) {
+			newTrackers = append(newTrackers, tracker)
+		}
+	}
+	if len(newTrackers) == 0 || DryRun("add trackers %v to client torrent %s", newTrackers, existing.InfoHash) {
+		return nil
+	}
+	if err := checker.clientInstance.AddTorrentTrackers(existing.InfoHash, newTrackers, "", false); err != nil {
+		return fmt.Errorf("failed to add trackers to client torrent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+)
+
+// qBittorrent file priorities of filePriorities rules.
+var filePriorityValues = map[string]int64{
+	config.FILE_PRIORITY_SKIP:   0,
+	config.FILE_PRIORITY_NORMAL: 1,
+	config.FILE_PRIORITY_HIGH:   6,
+	config.FILE_PRIORITY_MAX:    7,
+}
+
+// A newly added torrent may not be available in client immediately, so it retries getting the contents.
+const (
+	FILE_PRIORITY_RETRIES  = 10
+	FILE_PRIORITY_INTERVAL = 500 * time.Millisecond
+)
+
+// Apply the filePriorities rules of config to the files of the torrent which was just added to client.
+// Each file uses the first matched rule. If all files would be skipped, the "skip" rules are ignored.
+// It does nothing if no rule applies to the client.
+func ApplyFilePriorityRules(clientInstance client.Client, infoHash string) error {
+	var rules []*config.FilePriorityConfigStruct
+	for _, rule := range config.Get().FilePriorities {
+		if !rule.Disabled && (len(rule.Clients) == 0 || slices.Contains(rule.Clients, clientInstance.GetName())) {
+			if _, ok := filePriorityValues[rule.Priority]; !ok {
+				return fmt.Errorf("invalid priority %q of filePriorities rule %q", rule.Priority, rule.Name)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	var files []*client.TorrentContentFile
+	var err error
+	for i := 0; i < FILE_PRIORITY_RETRIES; i++ {
+		if files, err = clientInstance.GetTorrentContents(infoHash); err == nil && len(files) > 0 {
+			break
+		}
+		time.Sleep(FILE_PRIORITY_INTERVAL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("torrent contents not available (metadata not downloaded yet?)")
+	}
+	fileIndexes := map[string][]int64{} // priority => indexes
+	for _, file := range files {
+		if rule := matchFilePriorityRule(rules, file.Path); rule != nil {
+			fileIndexes[rule.Priority] = append(fileIndexes[rule.Priority], file.Index)
+		}
+	}
+	if len(fileIndexes[config.FILE_PRIORITY_SKIP]) == len(files) {
+		log.Warnf("All files of torrent %s match skip rules, ignore them", infoHash)
+		delete(fileIndexes, config.FILE_PRIORITY_SKIP)
+	}
+	for priority, indexes := range fileIndexes {
+		value, ok := filePriorityValues[priority]
+		if !ok {
+			return fmt.Errorf("invalid priority %q", priority)
+		}
+		if err := clientInstance.SetFilePriority(infoHash, indexes, value); err != nil {
+			return fmt.Errorf("failed to set %s priority of %d files: %w", priority, len(indexes), err)
+		}
+		log.Infof("Set %s priority of %d files of torrent %s", priority, len(indexes), infoHash)
+	}
+	return nil
+}
+
+// Return the first rule that any pattern of it matches with the file. The match is case-insensitive,
+// patterns which contain "/" match with the full path of file in torrent, others match with the file name.
+func matchFilePriorityRule(rules []*config.FilePriorityConfigStruct, filepath string) *config.FilePriorityConfigStruct {
+	filepath = strings.ToLower(filepath)
+	name := path.Base(filepath)
+	for _, rule := range rules {
+		for _, pattern := range rule.Patterns {
+			pattern = strings.ToLower(pattern)
+			target := name
+			if strings.Contains(pattern, "/") {
+				target = filepath
+			}
+			if matched, _ := path.Match(pattern, target); matched {
+				return rule
+			}
+		}
+	}
+	return nil
+}
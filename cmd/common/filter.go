@@ -0,0 +1,101 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Advanced torrent filter flags shared by client torrents control cmds (pause / resume / delete...).
+type TorrentFilterFlags struct {
+	Tracker        string
+	State          string
+	MinRatio       float64
+	MaxRatio       float64
+	MinTorrentSize string
+	MaxTorrentSize string
+	NameRegex      string
+	AddedAfter     string
+	AddedBefore    string
+	Expr           string
+}
+
+func (tff *TorrentFilterFlags) AddFlags(command *cobra.Command) {
+	command.Flags().StringVarP(&tff.Tracker, "tracker", "", "", constants.HELP_ARG_TRACKER)
+	command.Flags().StringVarP(&tff.State, "state", "", "",
+		`Filter torrents by state. Comma-separated list of states or state filters, `+
+			`e.g. "seeding,paused", "_done". Torrent which is in any state of the list matches`)
+	command.Flags().Float64VarP(&tff.MinRatio, "min-ratio", "", -1,
+		"Skip torrent with ratio (uploaded / downloaded) smaller than (<) this value. -1 == no limit")
+	command.Flags().Float64VarP(&tff.MaxRatio, "max-ratio", "", -1,
+		"Skip torrent with ratio (uploaded / downloaded) larger than (>) this value. -1 == no limit")
+	command.Flags().StringVarP(&tff.MinTorrentSize, "min-torrent-size", "", "-1",
+		"Skip torrent with size smaller than (<) this value. -1 == no limit")
+	command.Flags().StringVarP(&tff.MaxTorrentSize, "max-torrent-size", "", "-1",
+		"Skip torrent with size larger than (>) this value. -1 == no limit")
+	command.Flags().StringVarP(&tff.NameRegex, "name-regex", "", "",
+		`Filter torrents by name using regular expression (Go RE2 syntax). E.g. "(?i)^clannad.*1080p"`)
+	command.Flags().StringVarP(&tff.AddedAfter, "added-after", "", "",
+		`Only select torrent that was added to client after (>=) this. `+constants.HELP_ARG_TIMES)
+	command.Flags().StringVarP(&tff.AddedBefore, "added-before", "", "",
+		`Only select torrent that was added to client before (<) this. `+constants.HELP_ARG_TIMES)
+	command.Flags().StringVarP(&tff.Expr, "expr", "", "", constants.HELP_ARG_EXPR)
+}
+
+// Parse flags and return the torrent filter. The returned filter could be empty but never be nil.
+func (tff *TorrentFilterFlags) Parse() (torrentFilter *client.TorrentFilter, err error) {
+	torrentFilter = client.NewTorrentFilter()
+	torrentFilter.Tracker = tff.Tracker
+	if tff.State != "" {
+		for _, state := range util.SplitCsv(tff.State) {
+			if !strings.HasPrefix(state, "_") {
+				state = "_" + state
+			}
+			if !client.IsValidStateFilter(state) {
+				return nil, fmt.Errorf("invalid state %q", state)
+			}
+			torrentFilter.States = append(torrentFilter.States, state)
+		}
+	}
+	torrentFilter.MinRatio = tff.MinRatio
+	torrentFilter.MaxRatio = tff.MaxRatio
+	if torrentFilter.MinSize, err = util.RAMInBytes(tff.MinTorrentSize); err != nil {
+		return nil, fmt.Errorf("invalid min-torrent-size: %w", err)
+	}
+	if torrentFilter.MaxSize, err = util.RAMInBytes(tff.MaxTorrentSize); err != nil {
+		return nil, fmt.Errorf("invalid max-torrent-size: %w", err)
+	}
+	if tff.NameRegex != "" {
+		if torrentFilter.NameRegex, err = regexp.Compile(tff.NameRegex); err != nil {
+			return nil, fmt.Errorf("invalid name-regex: %w", err)
+		}
+	}
+	now := time.Now()
+	if tff.AddedAfter != "" {
+		if torrentFilter.AddedAfter, err = util.ParseTimeWithNow(tff.AddedAfter, nil, now); err != nil {
+			return nil, fmt.Errorf("invalid added-after: %w", err)
+		}
+	}
+	if tff.AddedBefore != "" {
+		if torrentFilter.AddedBefore, err = util.ParseTimeWithNow(tff.AddedBefore, nil, now); err != nil {
+			return nil, fmt.Errorf("invalid added-before: %w", err)
+		}
+	}
+	if torrentFilter.AddedAfter > 0 && torrentFilter.AddedBefore > 0 &&
+		torrentFilter.AddedAfter >= torrentFilter.AddedBefore {
+		return nil, fmt.Errorf("--added-after must be before --added-before flag")
+	}
+	if tff.Expr != "" {
+		if torrentFilter.Expr, err = client.ParseTorrentExpr(tff.Expr); err != nil {
+			return nil, fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+	return torrentFilter, nil
+}
@@ -7,7 +7,8 @@ import (
 	"github.com/sagan/ptool/constants"
 )
 
-// "name", "size", "speed", "state", "time", "tracker", "none"
+// "name", "size", "speed", "state", "time", "activity-time", "tracker", "ratio", "uploaded", "seeds",
+// "category", "none"
 var ClientTorrentSortFlag = &cmd.EnumFlag{
 	Description: "Sort field of client torrents",
 	Options: [][2]string{
@@ -18,11 +19,15 @@ var ClientTorrentSortFlag = &cmd.EnumFlag{
 		{"time", ""},
 		{"activity-time", ""},
 		{"tracker", ""},
+		{"ratio", ""},
+		{"uploaded", ""},
+		{"seeds", ""},
+		{"category", ""},
 		{constants.NONE, ""},
 	},
 }
 
-// size|time|name|seeders|leechers|snatched|none
+// size|time|name|seeders|leechers|snatched|expiry|none
 var SiteTorrentSortFlag = &cmd.EnumFlag{
 	Description: "Sort field of site torrents",
 	Options: [][2]string{
@@ -32,6 +37,7 @@ var SiteTorrentSortFlag = &cmd.EnumFlag{
 		{"seeders", ""},
 		{"leechers", ""},
 		{"snatched", ""},
+		{"expiry", "discount end time (local sort)"},
 		{constants.NONE, ""},
 	},
 }
@@ -73,12 +79,15 @@ var pureFlags = []string{
 	"bindable",
 	"break",
 	"check",
+	"check-existing-content",
 	"check-quick",
 	"clients",
 	"data-order",
 	"dedupe",
 	"delete-added",
 	"delete-fail",
+	"delete-unregistered",
+	"dashboard",
 	"dense",
 	"dry-run",
 	"force",
@@ -103,10 +112,13 @@ var pureFlags = []string{
 	"private",
 	"public",
 	"raw",
+	"regex",
 	"remove-existing",
+	"rotate",
 	"rename-added",
 	"rename-fail",
 	"rename-ok",
+	"offline",
 	"one-page",
 	"original-order",
 	"save-append",
@@ -115,10 +127,12 @@ var pureFlags = []string{
 	"show-id-only",
 	"show-info-hash-only",
 	"show-names-only",
+	"show-torrents",
 	"show-trackers",
 	"show-values-only",
 	"sites",
 	"skip-check",
+	"skip-preferences",
 	"skip-existing",
 	"slow",
 	"strict",
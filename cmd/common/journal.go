@@ -0,0 +1,434 @@
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+// Step journal of compound commands (e.g. movedata, restore): each applied step is recorded before performing it,
+// so that if the command fails partway through, the applied steps can be rolled back or the command can be resumed
+// by "ptool journal". See Rollback for how steps are reverted.
+// Journals are stored in "<config_dir>/journals/<id>/"; the journal of a successful command is removed.
+// The journal file is append-only (JSON Lines): each change of the journal is appended as a record,
+// and the journal is loaded by replaying the records, so an interrupted write can only lose the last record.
+
+// Actions of journal steps.
+const (
+	JOURNAL_PAUSE          = "pause"         // torrent paused
+	JOURNAL_COPY_FILES     = "copyfiles"     // files copied to Dir
+	JOURNAL_DELETE_TORRENT = "deletetorrent" // torrent deleted (without files) from client
+	JOURNAL_ADD_TORRENT    = "addtorrent"    // torrent added to client
+	JOURNAL_SET_SAVE_PATH  = "setsavepath"   // torrent save path changed (files moved by client)
+	JOURNAL_SET_CONFIG     = "setconfig"     // client config changed
+	JOURNAL_CREATE_TAG     = "createtag"     // tags created in client
+	JOURNAL_MAKE_CATEGORY  = "makecategory"  // category created in client
+)
+
+const (
+	JOURNAL_DIR            = "journals"      // in config dir
+	JOURNAL_FILE           = "journal.jsonl" // in journal dir
+	JOURNAL_STATUS_RUNNING = "running"       // or the process was killed
+	JOURNAL_STATUS_FAILED  = "failed"
+)
+
+type JournalStep struct {
+	Seq      int64    `json:"seq"` // sequence number of step in journal
+	Action   string   `json:"action"`
+	Unit     string   `json:"unit,omitempty"` // steps of a unit are discarded together when it finishes. See End
+	Client   string   `json:"client"`
+	InfoHash string   `json:"infoHash,omitempty"`
+	Name     string   `json:"name,omitempty"`     // torrent name, tag, category or config name
+	Value    string   `json:"value,omitempty"`    // old value of config
+	SavePath string   `json:"savePath,omitempty"` // old save path of torrent
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Paused   bool     `json:"paused,omitempty"`
+	Dir      string   `json:"dir,omitempty"`   // the dir files are copied to
+	Files    []string `json:"files,omitempty"` // copied files, relative to Dir
+	Done     bool     `json:"done"`
+}
+
+func (step *JournalStep) String() string {
+	if step.Action == JOURNAL_COPY_FILES {
+		return fmt.Sprintf("%s (%d files to %q)", step.Action, len(step.Files), step.Dir)
+	}
+	target := step.Name
+	if step.InfoHash != "" {
+		target = step.InfoHash
+	}
+	return fmt.Sprintf("%s %s (client %s)", step.Action, target, step.Client)
+}
+
+type Journal struct {
+	Id      string         `json:"id"`
+	Command string         `json:"command"`
+	Args    []string       `json:"args"` // ptool args of the command, used to resume it
+	Time    int64          `json:"time"`
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Steps   []*JournalStep `json:"steps,omitempty"`
+	seq     int64          // the seq of last step
+}
+
+// Ops of journal file records.
+const (
+	journalOpStart  = "start"  // journal created. Journal is the header (without steps)
+	journalOpBegin  = "begin"  // Step begun
+	journalOpDone   = "done"   // steps of Seqs done
+	journalOpFiles  = "files"  // Files appended to Files of the step of Seqs[0]
+	journalOpRemove = "remove" // steps of Seqs ended or rolled back
+	journalOpFinish = "finish" // journal finished with Status and Error
+)
+
+// A record (line) of journal file.
+type journalRecord struct {
+	Op      string       `json:"op"`
+	Journal *Journal     `json:"journal,omitempty"`
+	Step    *JournalStep `json:"step,omitempty"`
+	Seqs    []int64      `json:"seqs,omitempty"`
+	Files   []string     `json:"files,omitempty"`
+	Status  string       `json:"status,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Create a new journal for the command. In dry run mode, it returns nil,
+// all methods of Journal are no-op for nil journal.
+func NewJournal(command string) *Journal {
+	if flags.DryRun {
+		return nil
+	}
+	journal := &Journal{
+		Id:      fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), command),
+		Command: command,
+		Args:    os.Args[1:],
+		Time:    util.Now(),
+		Status:  JOURNAL_STATUS_RUNNING,
+		Steps:   []*JournalStep{},
+	}
+	return journal
+}
+
+// Return the dir of journal, which stores the journal file and the .torrent files of deleted torrents.
+func (journal *Journal) Dir() string {
+	return filepath.Join(config.ConfigDir, JOURNAL_DIR, journal.Id)
+}
+
+func (journal *Journal) torrentFilename(infoHash string) string {
+	return filepath.Join(journal.Dir(), infoHash+".torrent")
+}
+
+// Record the step before performing it and return it. Call journal.Done(step) after it's performed.
+// The content is the .torrent file contents of JOURNAL_DELETE_TORRENT step, which is used to re-add the torrent.
+func (journal *Journal) Begin(step *JournalStep, content []byte) *JournalStep {
+	if journal == nil {
+		return step
+	}
+	if content != nil {
+		os.MkdirAll(journal.Dir(), constants.PERM_DIR)
+		if err := os.WriteFile(journal.torrentFilename(step.InfoHash), content, constants.PERM); err != nil {
+			log.Warnf("Failed to write journal torrent file: %v", err)
+		}
+	}
+	journal.seq++
+	step.Seq = journal.seq
+	journal.Steps = append(journal.Steps, step)
+	journal.write(&journalRecord{Op: journalOpBegin, Step: step})
+	return step
+}
+
+// Mark the steps as done. nil steps are ignored.
+func (journal *Journal) Done(steps ...*JournalStep) {
+	if journal == nil {
+		return
+	}
+	var seqs []int64
+	for _, step := range steps {
+		if step != nil {
+			step.Done = true
+			seqs = append(seqs, step.Seq)
+		}
+	}
+	if len(seqs) > 0 {
+		journal.write(&journalRecord{Op: journalOpDone, Seqs: seqs})
+	}
+}
+
+// Record the files which have been copied by the JOURNAL_COPY_FILES step.
+func (journal *Journal) AddFiles(step *JournalStep, files ...string) {
+	step.Files = append(step.Files, files...)
+	if journal == nil {
+		return
+	}
+	journal.write(&journalRecord{Op: journalOpFiles, Seqs: []int64{step.Seq}, Files: files})
+}
+
+// Mark the steps of unit as finished, so they will not be rolled back. E.g. after a torrent is
+// successfully moved to new save path, or the applied steps have been reverted by the command itself.
+func (journal *Journal) End(unit string) {
+	if journal == nil {
+		return
+	}
+	var seqs []int64
+	journal.Steps = util.Filter(journal.Steps, func(step *JournalStep) bool {
+		if step.Unit == unit {
+			seqs = append(seqs, step.Seq)
+			return false
+		}
+		return true
+	})
+	if len(seqs) > 0 {
+		journal.write(&journalRecord{Op: journalOpRemove, Seqs: seqs})
+	}
+}
+
+// Finish the command. If err is nil and there is no remaining (un-ended) steps, the journal is removed.
+// Otherwise it's kept as failed, and the hint of rolling back or resuming is displayed.
+func (journal *Journal) Finish(err error) {
+	if journal == nil {
+		return
+	}
+	if err == nil && len(journal.Steps) == 0 {
+		os.RemoveAll(journal.Dir())
+		return
+	}
+	journal.Status = JOURNAL_STATUS_FAILED
+	if err != nil {
+		journal.Error = err.Error()
+	}
+	if journal.write(&journalRecord{Op: journalOpFinish, Status: journal.Status, Error: journal.Error}) == nil {
+		fmt.Printf("Some steps failed. To roll back applied steps, run \"ptool journal --rollback %s\"; "+
+			"To resume, run \"ptool journal --resume %s\"\n", journal.Id, journal.Id)
+	}
+}
+
+// Append records to journal file. The file is created (with the journal header) on first write,
+// but only if there are steps to roll back. Failures are logged but do not abort the command.
+func (journal *Journal) write(records ...*journalRecord) (err error) {
+	filename := filepath.Join(journal.Dir(), JOURNAL_FILE)
+	exists := util.FileExists(filename)
+	// nothing to roll back, no need to write the file
+	if !exists && len(journal.Steps) == 0 {
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			log.Warnf("Failed to write journal %s: %v", journal.Id, err)
+		}
+	}()
+	if !exists {
+		header := *journal
+		header.Steps = nil
+		header.Status = JOURNAL_STATUS_RUNNING
+		header.Error = ""
+		records = append([]*journalRecord{{Op: journalOpStart, Journal: &header}}, records...)
+		if err = os.MkdirAll(journal.Dir(), constants.PERM_DIR); err != nil {
+			return err
+		}
+	}
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, constants.PERM)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Remove the journal.
+func (journal *Journal) Remove() error {
+	return os.RemoveAll(journal.Dir())
+}
+
+// Roll back all remaining steps, in reverse order. Each step is reverted only if it's still in effect,
+// so it's safe to roll back a step which is begun but not done (e.g. the process was killed).
+// Return the number of steps which failed to be rolled back; the journal is removed if all succeeded.
+func (journal *Journal) Rollback() (errorCnt int64) {
+	for i := len(journal.Steps) - 1; i >= 0; i-- {
+		step := journal.Steps[i]
+		if err := journal.rollbackStep(step); err != nil {
+			log.Errorf("✕ failed to roll back %s: %v", step, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("✓ rolled back %s\n", step)
+		journal.Steps = slices.Delete(journal.Steps, i, i+1)
+		journal.write(&journalRecord{Op: journalOpRemove, Seqs: []int64{step.Seq}})
+	}
+	if errorCnt == 0 {
+		journal.Remove()
+	}
+	return errorCnt
+}
+
+func (journal *Journal) rollbackStep(step *JournalStep) error {
+	if step.Action == JOURNAL_COPY_FILES {
+		for _, file := range step.Files {
+			if err := os.Remove(filepath.Join(step.Dir, file)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	clientInstance, err := client.CreateClient(step.Client)
+	if err != nil {
+		return err
+	}
+	var torrent *client.Torrent
+	if step.InfoHash != "" {
+		if torrent, err = clientInstance.GetTorrent(step.InfoHash); err != nil {
+			return err
+		}
+	}
+	switch step.Action {
+	case JOURNAL_PAUSE:
+		if torrent != nil {
+			return clientInstance.ResumeTorrents([]string{step.InfoHash})
+		}
+	case JOURNAL_DELETE_TORRENT:
+		if torrent == nil {
+			content, err := os.ReadFile(journal.torrentFilename(step.InfoHash))
+			if err != nil {
+				return err
+			}
+			return clientInstance.AddTorrent(content, &client.TorrentOption{
+				Name:     step.Name,
+				Category: step.Category,
+				Tags:     step.Tags,
+				SavePath: step.SavePath,
+				Pause:    step.Paused,
+			}, nil)
+		}
+	case JOURNAL_ADD_TORRENT:
+		if torrent != nil {
+			return clientInstance.DeleteTorrents([]string{step.InfoHash}, false)
+		}
+	case JOURNAL_SET_SAVE_PATH:
+		if torrent != nil && strings.TrimSuffix(util.ToSlash(torrent.SavePath), "/") != step.SavePath {
+			return clientInstance.SetTorrentsSavePath([]string{step.InfoHash}, step.SavePath)
+		}
+	case JOURNAL_SET_CONFIG:
+		return clientInstance.SetConfig(step.Name, step.Value)
+	case JOURNAL_CREATE_TAG:
+		return clientInstance.DeleteTags(step.Name)
+	case JOURNAL_MAKE_CATEGORY:
+		return clientInstance.DeleteCategories([]string{step.Name})
+	default:
+		return fmt.Errorf("unknown action")
+	}
+	return nil
+}
+
+// Load all journals, in time order.
+func LoadJournals() ([]*Journal, error) {
+	dir := filepath.Join(config.ConfigDir, JOURNAL_DIR)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	journals := []*Journal{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, dirEntry.Name(), JOURNAL_FILE)
+		journal, err := loadJournal(filename)
+		if err != nil || journal.Id != dirEntry.Name() {
+			log.Warnf("Invalid journal %q: %v", filename, err)
+			continue
+		}
+		journals = append(journals, journal)
+	}
+	sort.Slice(journals, func(i, j int) bool {
+		return journals[i].Time < journals[j].Time
+	})
+	return journals, nil
+}
+
+// Load journal by replaying the records of journal file. An invalid record (e.g. the last one which was
+// being written when the process was killed) and all records after it are ignored.
+func loadJournal(filename string) (*Journal, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var journal *Journal
+	steps := map[int64]*JournalStep{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, constants.BIG_FILE_SIZE)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		record := &journalRecord{}
+		err := json.Unmarshal(scanner.Bytes(), record)
+		if err != nil || journal == nil && record.Op != journalOpStart {
+			log.Warnf("Invalid journal %q record at line %d (%v), ignore it and following records",
+				filename, lineno, err)
+			break
+		}
+		switch record.Op {
+		case journalOpStart:
+			if record.Journal == nil {
+				return nil, fmt.Errorf("invalid journal header")
+			}
+			journal = record.Journal
+			journal.Steps = nil
+		case journalOpBegin:
+			if record.Step != nil {
+				journal.Steps = append(journal.Steps, record.Step)
+				steps[record.Step.Seq] = record.Step
+				journal.seq = max(journal.seq, record.Step.Seq)
+			}
+		case journalOpDone:
+			for _, seq := range record.Seqs {
+				if step := steps[seq]; step != nil {
+					step.Done = true
+				}
+			}
+		case journalOpFiles:
+			if len(record.Seqs) > 0 && steps[record.Seqs[0]] != nil {
+				steps[record.Seqs[0]].Files = append(steps[record.Seqs[0]].Files, record.Files...)
+			}
+		case journalOpRemove:
+			journal.Steps = util.Filter(journal.Steps, func(step *JournalStep) bool {
+				return !slices.Contains(record.Seqs, step.Seq)
+			})
+		case journalOpFinish:
+			journal.Status = record.Status
+			journal.Error = record.Error
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if journal == nil {
+		return nil, fmt.Errorf("empty journal")
+	}
+	return journal, nil
+}
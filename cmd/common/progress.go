@@ -0,0 +1,123 @@
+package common
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	PROGRESS_JSON = "json"
+	// Min interval of emitting progress records when only the processed bytes of current item changed.
+	PROGRESS_INTERVAL = time.Second
+)
+
+// The --progress flag of long-running commands: none|json
+var ProgressFlag = &cmd.EnumFlag{
+	Description: "Output machine-readable progress records",
+	Options: [][2]string{
+		{constants.NONE, ""},
+		{PROGRESS_JSON, "JSON Lines to stderr"},
+	},
+}
+
+// A progress record of a long-running operation. The operation processes items (e.g. torrents, chunks)
+// one by one, and each item may have multiple stages (e.g. "copy", "upload").
+type ProgressRecord struct {
+	Time       int64   `json:"time"`
+	Operation  string  `json:"operation"`       // name of command, e.g. "movedata"
+	Index      int64   `json:"index"`           // 1-based index of current item. 0 = not started
+	Total      int64   `json:"total"`           // number of items
+	Item       string  `json:"item,omitempty"`  // current item, e.g. torrent info hash or name
+	Stage      string  `json:"stage,omitempty"` // current stage of current item
+	Bytes      int64   `json:"bytes"`           // processed bytes of current item
+	TotalBytes int64   `json:"totalBytes"`      // total bytes of current item. 0 = unknown
+	Percent    float64 `json:"percent"`         // overall progress of operation, 0-100
+	Done       bool    `json:"done,omitempty"`  // the operation is finished
+	Error      string  `json:"error,omitempty"` // error of operation. Only in the "done" record
+	Errors     int64   `json:"errors"`          // number of failed items so far
+}
+
+// Progress reporter of a long-running operation. If --progress flag is "json", it emits a ProgressRecord
+// (in JSON Lines format) to stderr when starting an item or stage, periodically while processing an item,
+// and at the end. Otherwise all methods are no-op.
+type Progress struct {
+	enabled  bool
+	output   io.Writer
+	record   ProgressRecord
+	emitTime time.Time
+}
+
+func NewProgress(format string, operation string, total int64) *Progress {
+	return &Progress{
+		enabled: format == PROGRESS_JSON,
+		output:  os.Stderr,
+		record:  ProgressRecord{Operation: operation, Total: total},
+	}
+}
+
+// Start processing the index-th (1-based) item. totalBytes: total bytes of item, 0 if unknown.
+func (p *Progress) Start(index int64, item string, totalBytes int64) {
+	p.record.Index = index
+	p.record.Item = item
+	p.record.Stage = ""
+	p.record.Bytes = 0
+	p.record.TotalBytes = totalBytes
+	p.emit()
+}
+
+// Enter a new stage of current item.
+func (p *Progress) Stage(stage string) {
+	p.record.Stage = stage
+	p.emit()
+}
+
+// Update the processed bytes of current item. The record is emitted at most once per PROGRESS_INTERVAL.
+func (p *Progress) Update(bytes int64) {
+	p.record.Bytes = bytes
+	if time.Since(p.emitTime) >= PROGRESS_INTERVAL {
+		p.emit()
+	}
+}
+
+// Finish current item. err: the error of processing it.
+func (p *Progress) Finish(err error) {
+	if err != nil {
+		p.record.Errors++
+	}
+}
+
+// End the operation and emit the "done" record. err: the overall error of operation.
+func (p *Progress) End(err error) {
+	p.record.Done = true
+	p.record.Stage = ""
+	if err != nil {
+		p.record.Error = err.Error()
+	}
+	p.emit()
+}
+
+func (p *Progress) emit() {
+	if !p.enabled {
+		return
+	}
+	p.emitTime = time.Now()
+	record := p.record
+	record.Time = util.Now()
+	if record.Done {
+		record.Percent = 100
+	} else if record.Total > 0 && record.Index > 0 {
+		current := float64(0)
+		if record.TotalBytes > 0 {
+			current = min(float64(record.Bytes)/float64(record.TotalBytes), 1)
+		}
+		record.Percent = (float64(record.Index-1) + current) * 100 / float64(record.Total)
+	}
+	data, _ := json.Marshal(&record)
+	p.output.Write(append(data, '\n'))
+}
@@ -0,0 +1,184 @@
+package common
+
+// Season pack vs single episode conflict handling. See config.SeasonPackConfigStruct.
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+// Return the first enabled seasonPacks rule that applies to the torrents of category in client.
+// Return nil if none.
+func GetSeasonPackRule(clientName string, category string) *config.SeasonPackConfigStruct {
+	for _, rule := range config.Get().SeasonPacks {
+		if !rule.Disabled && (len(rule.Clients) == 0 || slices.Contains(rule.Clients, clientName)) &&
+			(len(rule.Categories) == 0 || slices.Contains(rule.Categories, category)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Report whether any enabled seasonPacks rule applies to client.
+func HasSeasonPackRules(clientName string) bool {
+	return slices.ContainsFunc(config.Get().SeasonPacks, func(rule *config.SeasonPackConfigStruct) bool {
+		return !rule.Disabled && (len(rule.Clients) == 0 || slices.Contains(rule.Clients, clientName))
+	})
+}
+
+// Report whether release is a season pack: a (whole) season of TV show, rather than a single episode.
+func IsSeasonPack(release *releasename.Release) bool {
+	return release.TV && release.Season > 0 && release.Episode == 0
+}
+
+// Report whether the season pack release supersedes the single episode release:
+// same show (normalized title, and year if both known) and season (see releasename.Release.Match),
+// and the same resolution, source and edition.
+func SupersedesEpisode(pack *releasename.Release, episode *releasename.Release) bool {
+	if !IsSeasonPack(pack) || !episode.TV || episode.Episode == 0 {
+		return false
+	}
+	// the season of episode
+	season := *episode
+	season.Episode = 0
+	if season.Year == 0 || pack.Year == 0 {
+		season.Year = pack.Year
+	}
+	return pack.Match(&season) && strings.EqualFold(pack.Resolution, episode.Resolution) &&
+		strings.EqualFold(pack.Source, episode.Source)
+}
+
+// Report whether any one of the files of season pack is the single episode.
+func PackContainsEpisode(packFiles []string, episode *releasename.Release) bool {
+	return slices.ContainsFunc(packFiles, func(file string) bool {
+		release := releasename.Parse(path.Base(strings.ReplaceAll(file, `\`, "/")))
+		return release.TV && release.Season == episode.Season && release.Episode == episode.Episode
+	})
+}
+
+// Return the single episode torrents of torrents that are superseded by the season pack torrent
+// (in the same category) and are included in the pack files. Return nil if pack is not a season pack.
+func FindSupersededEpisodes(pack *client.Torrent, packFiles []string, torrents []*client.Torrent) []*client.Torrent {
+	packRelease := releasename.Parse(pack.Name)
+	if !IsSeasonPack(packRelease) {
+		return nil
+	}
+	return util.Filter(torrents, func(torrent *client.Torrent) bool {
+		if torrent.InfoHash == pack.InfoHash || torrent.Category != pack.Category {
+			return false
+		}
+		episode := releasename.Parse(torrent.Name)
+		return SupersedesEpisode(packRelease, episode) && PackContainsEpisode(packFiles, episode)
+	})
+}
+
+// Report whether the superseded episode torrent must be kept seeding: it has the H&R tag, or it has not
+// been seeded for the minSeedingTime of rule (if set) since completion. A H&R torrent is kept until
+// it has been seeded for minSeedingTime; If minSeedingTime is not set, it's always kept.
+func KeepSeedingEpisode(rule *config.SeasonPackConfigStruct, episode *client.Torrent) bool {
+	minSeedingTime, _ := util.ParseTimeDuration(rule.MinSeedingTime)
+	if minSeedingTime <= 0 {
+		return episode.HasTag(config.HR_TAG)
+	}
+	return episode.Ctime <= 0 || util.Now()-episode.Ctime < minSeedingTime
+}
+
+// Return the season pack torrent of torrents in category that supersedes the single episode of name.
+// Return nil if not found or name is not a single episode.
+func FindSeasonPack(name string, category string, torrents []*client.Torrent) *client.Torrent {
+	episode := releasename.Parse(name)
+	if !episode.TV || episode.Episode == 0 {
+		return nil
+	}
+	for _, torrent := range torrents {
+		if torrent.Category == category && SupersedesEpisode(releasename.Parse(torrent.Name), episode) {
+			return torrent
+		}
+	}
+	return nil
+}
+
+// Apply the action of rule to the single episode torrents of client superseded by the (completed) season pack.
+// Return the superseded episode torrents. Episodes which must be kept seeding (see KeepSeedingEpisode)
+// are skipped. Episode files are kept unless the deleteFiles of rule is set;
+// Episodes which files are inside the content path of pack are always deleted without files.
+func HandleSeasonPack(clientInstance client.Client, rule *config.SeasonPackConfigStruct,
+	pack *client.Torrent) ([]*client.Torrent, error) {
+	torrents, err := clientInstance.GetTorrents("", pack.Category, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	packContents, err := clientInstance.GetTorrentContents(pack.InfoHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season pack contents: %w", err)
+	}
+	packFiles := util.Map(packContents, func(file *client.TorrentContentFile) string { return file.Path })
+	episodes := FindSupersededEpisodes(pack, packFiles, torrents)
+	if len(episodes) == 0 {
+		return nil, nil
+	}
+	action := rule.Action
+	if action == "" {
+		action = config.SEASON_PACK_ACTION_NONE
+	}
+	var infoHashes []string
+	for _, episode := range episodes {
+		if action != config.SEASON_PACK_ACTION_NONE && KeepSeedingEpisode(rule, episode) {
+			log.Infof("Client %s torrent %s (%s) is superseded by season pack %s (%s), "+
+				"but it's kept seeding for H&R / minSeedingTime", clientInstance.GetName(), episode.InfoHash,
+				episode.Name, pack.InfoHash, pack.Name)
+			continue
+		}
+		log.Infof("Client %s torrent %s (%s) is superseded by season pack %s (%s) (action: %s)",
+			clientInstance.GetName(), episode.InfoHash, episode.Name, pack.InfoHash, pack.Name, action)
+		infoHashes = append(infoHashes, episode.InfoHash)
+	}
+	if len(infoHashes) == 0 {
+		return episodes, nil
+	}
+	switch action {
+	case config.SEASON_PACK_ACTION_PAUSE:
+		if DryRunTorrents(clientInstance, infoHashes, "pause") {
+			return episodes, nil
+		}
+		err = clientInstance.PauseTorrents(infoHashes)
+	case config.SEASON_PACK_ACTION_DELETE:
+		if DryRunTorrents(clientInstance, infoHashes, "delete") {
+			return episodes, nil
+		}
+		var preserveFilesInfoHashes []string
+		deleteInfoHashes := infoHashes
+		infoHashes = nil
+		packPath := strings.ReplaceAll(pack.ContentPath, `\`, "/")
+		for _, episode := range episodes {
+			if !slices.Contains(deleteInfoHashes, episode.InfoHash) {
+				continue
+			}
+			episodePath := strings.ReplaceAll(episode.ContentPath, `\`, "/")
+			if !rule.DeleteFiles || packPath != "" &&
+				(episodePath == packPath || strings.HasPrefix(episodePath, packPath+"/")) {
+				preserveFilesInfoHashes = append(preserveFilesInfoHashes, episode.InfoHash)
+			} else {
+				infoHashes = append(infoHashes, episode.InfoHash)
+			}
+		}
+		if len(preserveFilesInfoHashes) > 0 {
+			if err = clientInstance.DeleteTorrents(preserveFilesInfoHashes, false); err != nil {
+				return episodes, fmt.Errorf("failed to delete torrents: %w", err)
+			}
+		}
+		if len(infoHashes) > 0 {
+			err = client.DeleteTorrentsAuto(clientInstance, infoHashes)
+		}
+	}
+	return episodes, err
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+)
+
+// Download slots of sites in a client, which enforce the "maxDownloadingTorrents" limit of site config:
+// no more than n incomplete torrents of a site exist in the client at once.
+// A nil *SiteSlots means no limit, all methods of it are nil-safe.
+type SiteSlots struct {
+	counts map[string]int64 // site name => count of incomplete torrents in client
+}
+
+// Count the incomplete torrents of each site in client. See GetTorrentSite for how the site of a torrent is found.
+// It returns nil if none of the sites has maxDownloadingTorrents limit, without fetching the client torrents.
+func LoadSiteSlots(clientInstance client.Client) (*SiteSlots, error) {
+	limited := false
+	for _, siteConfig := range config.Get().SitesEnabled {
+		if siteConfig.MaxDownloadingTorrents > 0 {
+			limited = true
+			break
+		}
+	}
+	if !limited {
+		return nil, nil
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	slots := &SiteSlots{counts: map[string]int64{}}
+	domainSiteMap := map[string]string{}
+	for _, torrent := range torrents {
+		if torrent.IsComplete() {
+			continue
+		}
+		if sitename := GetTorrentSite(torrent, domainSiteMap); sitename != "" {
+			slots.counts[sitename]++
+		}
+	}
+	log.Debugf("Incomplete torrents of sites in client %s: %v", clientInstance.GetName(), slots.counts)
+	return slots, nil
+}
+
+// Return the site of client torrent, which is read from it's "site:<name>" tag, or guessed from it's tracker domain.
+// domainSiteMap caches the guessed results, it can be shared by multiple calls.
+// Return empty string if site is unknown.
+func GetTorrentSite(torrent *client.Torrent, domainSiteMap map[string]string) string {
+	if sitename := torrent.GetSiteFromTag(); sitename != "" {
+		return sitename
+	}
+	domain := util.GetUrlDomain(torrent.Tracker)
+	if domain == "" {
+		return ""
+	}
+	sitename, ok := domainSiteMap[domain]
+	if !ok {
+		sitename, _ = tpl.GuessSiteByDomain(domain, "")
+		domainSiteMap[domain] = sitename
+	}
+	return sitename
+}
+
+// Return the count of available slots of site. -1 means unlimited.
+func (slots *SiteSlots) Available(sitename string) int64 {
+	siteConfig := config.GetSiteConfig(sitename)
+	if slots == nil || siteConfig == nil || siteConfig.MaxDownloadingTorrents <= 0 {
+		return -1
+	}
+	return max(siteConfig.MaxDownloadingTorrents-slots.counts[sitename], 0)
+}
+
+// Take a slot of site for a new torrent. Return false if the site has no available slot.
+func (slots *SiteSlots) Acquire(sitename string) bool {
+	available := slots.Available(sitename)
+	if available == 0 {
+		return false
+	}
+	if available > 0 {
+		slots.counts[sitename]++
+	}
+	return true
+}
+
+// Give back the slot of site, e.g. the torrent failed to be added to client.
+func (slots *SiteSlots) Release(sitename string) {
+	if slots != nil && slots.counts[sitename] > 0 {
+		slots.counts[sitename]--
+	}
+}
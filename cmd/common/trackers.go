@@ -0,0 +1,49 @@
+package common
+
+import (
+	"slices"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+// Tracker messages which indicate the torrent is not registered in (or deleted from) the tracker,
+// or the passkey is invalid.
+var unregisteredTrackerMsgs = []string{
+	"not registered",
+	"unregistered",
+	"not exists",
+	"unauthorized",
+	"require passkey",
+	"require authkey",
+	"already are downloading", // "You already are downloading the same torrent"
+	"种子不存在",
+	"该种子没有", // monikadesign 的 msg: "该种子没有在我们的 Tracker 上注册."
+	"下载相同种子",
+	"下載相同種子",
+}
+
+// Return true if none tracker of the torrent is working normally and some tracker reports that
+// the torrent is not registered (or the passkey is invalid).
+// It's used by reporting cmds (trackerstatus, prunereport) only,
+// unlike client.TorrentTrackers.SeemsInvalidTorrent which is used by auto deletion logic.
+func SeemsUnregisteredTorrent(trackers client.TorrentTrackers) bool {
+	hasUnregistered := false
+	for _, tracker := range trackers {
+		if tracker.Status == "working" && tracker.Msg == "" {
+			return false
+		}
+		if SeemsUnregisteredTracker(&tracker) {
+			hasUnregistered = true
+		}
+	}
+	return hasUnregistered
+}
+
+// Return true if the tracker reports that the torrent is not registered (or the passkey is invalid).
+func SeemsUnregisteredTracker(tracker *client.TorrentTracker) bool {
+	return (tracker.Status == "working" || tracker.Status == "error") && tracker.Msg != "" &&
+		slices.ContainsFunc(unregisteredTrackerMsgs, func(msg string) bool {
+			return util.ContainsI(tracker.Msg, msg)
+		})
+}
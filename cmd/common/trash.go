@@ -0,0 +1,284 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	// Content files of trashed torrent are moved to "<save_path>/.ptool_trash/<id>/",
+	// so that no data copying across file systems is needed.
+	TRASH_DATA_DIR      = ".ptool_trash"
+	TRASH_MANIFEST_FILE = "manifest.json"
+	TRASH_TORRENT_FILE  = "torrent.torrent"
+)
+
+// A torrent deleted from client into trash by "ptool delete --trash", which can be restored by "ptool undelete".
+// The exported .torrent file and manifest are stored in "<trash_dir>/<id>/".
+type TrashEntry struct {
+	Id            string   `json:"id"`
+	Time          int64    `json:"time"`
+	Client        string   `json:"client"`
+	InfoHash      string   `json:"infoHash"`
+	Name          string   `json:"name"`
+	Size          int64    `json:"size"`
+	Category      string   `json:"category"`
+	Tags          []string `json:"tags"`
+	Paused        bool     `json:"paused"`
+	SavePath      string   `json:"savePath"`      // save path in client
+	LocalSavePath string   `json:"localSavePath"` // save path in file system of ptool
+	Files         []string `json:"files"`         // moved top level files or folders, relative to save path
+}
+
+// Return the dir of the trash entry that stores manifest and .torrent file.
+func (entry *TrashEntry) Dir() string {
+	return filepath.Join(config.GetTrashDir(), entry.Id)
+}
+
+// Return the dir that stores the content files of the trash entry.
+func (entry *TrashEntry) DataDir() string {
+	return filepath.Join(entry.LocalSavePath, TRASH_DATA_DIR, entry.Id)
+}
+
+// Return true if the trash entry is beyond the retention time (trashRetention config) and should be purged.
+func (entry *TrashEntry) Expired(now int64) bool {
+	retention := config.Get().TrashRetention
+	if retention == "" {
+		retention = config.DEFAULT_TRASH_RETENTION
+	}
+	seconds, err := util.ParseTimeDuration(retention)
+	return err == nil && entry.Time+seconds < now
+}
+
+// Move the torrent of client to trash: export the .torrent file, move the content files to trash and
+// delete the torrent (without files) from client. If keepData is true, or other xseed torrents (of the same
+// content path) exist in client, the content files are left in place. savePathMapper maps client save path
+// to the one of ptool.
+func TrashTorrent(clientInstance client.Client, torrent *client.Torrent, savePathMapper *PathMapper,
+	keepData bool) (entry *TrashEntry, err error) {
+	entry = &TrashEntry{
+		Id:       fmt.Sprintf("%s-%.8s", time.Now().Format("20060102-150405"), torrent.InfoHash),
+		Time:     util.Now(),
+		Client:   clientInstance.GetName(),
+		InfoHash: torrent.InfoHash,
+		Name:     torrent.Name,
+		Size:     torrent.Size,
+		Category: torrent.Category,
+		Tags:     torrent.Tags,
+		Paused:   torrent.State == "paused",
+		SavePath: torrent.SavePath,
+	}
+	entry.LocalSavePath = torrent.SavePath
+	if savePathMapper != nil {
+		var match bool
+		if entry.LocalSavePath, match = savePathMapper.Before2After(torrent.SavePath); !match {
+			return nil, fmt.Errorf("save path %q does not match with any map-save-path rule", torrent.SavePath)
+		}
+	}
+	content, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export torrent: %w", err)
+	}
+	if !keepData {
+		// the same check of "delete --preserve-if-xseed-exist", against current client torrents
+		_, torrentsWithXseed, err := client.FilterTorrentsXseed(clientInstance, []*client.Torrent{torrent})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check xseed torrents: %w", err)
+		}
+		keepData = len(torrentsWithXseed) > 0
+	}
+	if !keepData {
+		contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get torrent contents: %w", err)
+		}
+		for _, file := range contents {
+			top, _, _ := strings.Cut(file.Path, "/")
+			if !slices.Contains(entry.Files, top) {
+				entry.Files = append(entry.Files, top)
+			}
+		}
+	}
+	if err = os.MkdirAll(entry.Dir(), constants.PERM_DIR); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(entry.Dir())
+		}
+	}()
+	if err = os.WriteFile(filepath.Join(entry.Dir(), TRASH_TORRENT_FILE), content, constants.PERM); err != nil {
+		return nil, err
+	}
+	if len(entry.Files) > 0 {
+		if err = clientInstance.PauseTorrents([]string{torrent.InfoHash}); err != nil {
+			return nil, fmt.Errorf("failed to pause torrent: %w", err)
+		}
+		if entry.Files, err = moveFiles(entry.LocalSavePath, entry.DataDir(), entry.Files); err != nil {
+			return nil, fmt.Errorf("failed to move files to trash: %w", err)
+		}
+	}
+	if err = entry.save(); err == nil {
+		err = clientInstance.DeleteTorrents([]string{torrent.InfoHash}, false)
+	}
+	if err != nil {
+		if _, err := moveFiles(entry.DataDir(), entry.LocalSavePath, entry.Files); err != nil {
+			log.Errorf("Failed to move trashed files back to %q: %v", entry.LocalSavePath, err)
+		} else if len(entry.Files) > 0 && !entry.Paused {
+			clientInstance.ResumeTorrents([]string{torrent.InfoHash})
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Restore the trash entry: move the content files back and re-add the torrent to client.
+// The trash entry is removed after restored.
+func (entry *TrashEntry) Restore(clientInstance client.Client, skipCheck bool) error {
+	if _, err := moveFiles(entry.DataDir(), entry.LocalSavePath, entry.Files); err != nil {
+		return fmt.Errorf("failed to move files back: %w", err)
+	}
+	content, err := os.ReadFile(filepath.Join(entry.Dir(), TRASH_TORRENT_FILE))
+	if err != nil {
+		return err
+	}
+	err = clientInstance.AddTorrent(content, &client.TorrentOption{
+		Category:     entry.Category,
+		Tags:         entry.Tags,
+		SavePath:     entry.SavePath,
+		Pause:        entry.Paused,
+		SkipChecking: skipCheck,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add torrent: %w", err)
+	}
+	return entry.remove()
+}
+
+// Permanently delete the trash entry, including it's content files.
+func (entry *TrashEntry) Purge() error {
+	for _, file := range entry.Files {
+		filename := filepath.Join(entry.DataDir(), file)
+		err := os.RemoveAll(filename)
+		audit.Log(audit.ACTION_REMOVE_FILE, filename, map[string]any{"trash": entry.Id}, err)
+		if err != nil {
+			return err
+		}
+	}
+	return entry.remove()
+}
+
+// Remove the manifest and .torrent file of trash entry, and the (empty) data dir.
+func (entry *TrashEntry) remove() error {
+	if len(entry.Files) > 0 {
+		os.Remove(entry.DataDir())
+		os.Remove(filepath.Join(entry.LocalSavePath, TRASH_DATA_DIR)) // only succeeds if dir is empty
+	}
+	return os.RemoveAll(entry.Dir())
+}
+
+func (entry *TrashEntry) save() error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entry.Dir(), TRASH_MANIFEST_FILE), data, constants.PERM)
+}
+
+// Load all trash entries, in time order.
+func LoadTrashEntries() ([]*TrashEntry, error) {
+	dirEntries, err := os.ReadDir(config.GetTrashDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries := []*TrashEntry{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		manifestFile := filepath.Join(config.GetTrashDir(), dirEntry.Name(), TRASH_MANIFEST_FILE)
+		data, err := os.ReadFile(manifestFile)
+		if err != nil {
+			log.Warnf("Failed to read trash manifest %q: %v", manifestFile, err)
+			continue
+		}
+		entry := &TrashEntry{}
+		if err = json.Unmarshal(data, entry); err != nil || entry.Id != dirEntry.Name() {
+			log.Warnf("Invalid trash manifest %q: %v", manifestFile, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+	return entries, nil
+}
+
+// Permanently delete all expired trash entries. Return the number of purged entries.
+func PurgeExpiredTrash() (cnt int64, err error) {
+	entries, err := LoadTrashEntries()
+	if err != nil {
+		return 0, err
+	}
+	now := util.Now()
+	for _, entry := range entries {
+		if !entry.Expired(now) {
+			continue
+		}
+		if err := entry.Purge(); err != nil {
+			log.Errorf("Failed to purge trash %s: %v", entry.Id, err)
+			continue
+		}
+		log.Infof("Purged expired trash %s (%s)", entry.Id, entry.Name)
+		cnt++
+	}
+	return cnt, nil
+}
+
+// Move the top level files or folders from src dir to dest dir, which the dest dir is created if not exists.
+// The non-existent files are skipped (e.g. already moved by a xseed torrent). Return the moved files.
+// If failed, the moved files are moved back.
+func moveFiles(srcDir string, destDir string, files []string) (moved []string, err error) {
+	if err = os.MkdirAll(destDir, constants.PERM_DIR); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		src := filepath.Join(srcDir, file)
+		dest := filepath.Join(destDir, file)
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		if _, err = os.Lstat(dest); err == nil {
+			err = fmt.Errorf("%q already exists", dest)
+		} else {
+			err = os.Rename(src, dest)
+		}
+		if err != nil {
+			for _, file := range moved {
+				os.Rename(filepath.Join(destDir, file), filepath.Join(srcDir, file))
+			}
+			return nil, err
+		}
+		moved = append(moved, file)
+	}
+	return moved, nil
+}
@@ -3,6 +3,10 @@ package all
 import (
 	_ "github.com/sagan/ptool/cmd/configcmd"
 	_ "github.com/sagan/ptool/cmd/configcmd/create"
+	_ "github.com/sagan/ptool/cmd/configcmd/decrypt"
+	_ "github.com/sagan/ptool/cmd/configcmd/encrypt"
 	_ "github.com/sagan/ptool/cmd/configcmd/example"
+	_ "github.com/sagan/ptool/cmd/configcmd/initcmd"
 	_ "github.com/sagan/ptool/cmd/configcmd/show"
+	_ "github.com/sagan/ptool/cmd/configcmd/verify"
 )
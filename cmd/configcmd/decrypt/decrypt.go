@@ -0,0 +1,60 @@
+package decrypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/configcmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util/crypto"
+)
+
+var command = &cobra.Command{
+	Use:         "decrypt {secret}...",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       `Decrypt secrets encrypted by "ptool config encrypt".`,
+	Long: fmt.Sprintf(`Decrypt secrets encrypted by "ptool config encrypt".
+It prints the plaintext of each secret, one per line.
+The master password is read from %s env. If the env is not set, it will be asked interactively.
+
+Use a single "-" as arg to read the secret from stdin, in which case the master password must be set in env.`,
+		config.ENV_MASTER_PASSWORD),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: decrypt,
+}
+
+func init() {
+	configcmd.Command.AddCommand(command)
+}
+
+func decrypt(cmd *cobra.Command, args []string) error {
+	secrets := args
+	if len(args) == 1 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		secrets = []string{strings.TrimSpace(string(data))}
+	}
+	if common.DryRun("decrypt %d secrets", len(secrets)) {
+		return nil
+	}
+	password, err := config.GetMasterPassword(false)
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		plaintext, err := crypto.DecryptSecret(password, secret)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", plaintext)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package encrypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/configcmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util/crypto"
+)
+
+var command = &cobra.Command{
+	Use:         "encrypt {value}...",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       "Encrypt secret values (passwords, passkeys, cookies) for use in config file.",
+	Long: fmt.Sprintf(`Encrypt secret values (passwords, passkeys, cookies) for use in config file.
+It prints the encrypted string of each value, one per line, which has an "%s" prefix.
+Use the encrypted string as the value of any string field in config file, e.g.:
+  cookie = "%s..."
+ptool decrypts the secrets when loading config file.
+
+Secrets are encrypted using AES-256-GCM, with key derived from the master password using scrypt.
+The master password is read from %s env. If the env is not set, it will be asked interactively.
+All secrets in config file must use the same master password.
+
+Use a single "-" as arg to read the value from stdin (the trailing line break is trimmed),
+in which case the master password must be set in env.`, crypto.SECRET_PREFIX, crypto.SECRET_PREFIX,
+		config.ENV_MASTER_PASSWORD),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: encrypt,
+}
+
+func init() {
+	configcmd.Command.AddCommand(command)
+}
+
+func encrypt(cmd *cobra.Command, args []string) error {
+	values, err := readValues(args)
+	if err != nil {
+		return err
+	}
+	if common.DryRun("encrypt %d values", len(values)) {
+		return nil
+	}
+	password, err := config.GetMasterPassword(true)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		if crypto.IsEncryptedSecret(value) {
+			return fmt.Errorf("value is already encrypted")
+		}
+		secret, err := crypto.EncryptSecret(password, value)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		fmt.Printf("%s\n", secret)
+	}
+	return nil
+}
+
+func readValues(args []string) ([]string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return []string{strings.TrimRight(string(data), "\r\n")}, nil
+	}
+	return args, nil
+}
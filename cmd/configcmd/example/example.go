@@ -7,16 +7,18 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/configcmd"
 	"github.com/sagan/ptool/config"
 )
 
 var command = &cobra.Command{
-	Use:   "example",
-	Short: "Display example config file contents.",
-	Long:  `Display example config file contents.`,
-	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
-	RunE:  example,
+	Use:         "example",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       "Display example config file contents.",
+	Long:        `Display example config file contents.`,
+	Args:        cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE:        example,
 }
 
 var (
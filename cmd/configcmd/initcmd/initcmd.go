@@ -0,0 +1,237 @@
+package initcmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/configcmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "init",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       "Create config file using an interactive wizard.",
+	Long: `Create config file using an interactive wizard.
+It asks for the BitTorrent client (qBittorrent or Transmission) url and credentials, and verifies them
+by connecting to the client. Then it asks for the PT sites and their cookies, and tests each cookie
+by fetching user info from site. Finally it writes a valid config file with the verified clients and sites.
+Other config items use the default values, edit the config file later to change them.
+
+For a config file with full examples of config items, use "ptool config create" instead.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: initcmd,
+}
+
+var (
+	force = false
+)
+
+var defaultClientUrls = map[string]string{
+	"qbittorrent":  "http://localhost:8080/",
+	"transmission": "http://localhost:9091/",
+}
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Overwrite existing config file without confirm")
+	configcmd.Command.AddCommand(command)
+}
+
+func initcmd(cmd *cobra.Command, args []string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("stdin is NOT tty, the interactive wizard is unavailable")
+	}
+	configFile := filepath.Join(config.ConfigDir, config.ConfigFile)
+	fmt.Printf("Config file: %s\n", configFile)
+	if util.FileExists(configFile) && !force && !askYesNo("Config file already exists. Overwrite it", false) {
+		return fmt.Errorf("abort")
+	}
+	globalConfig := &config.ConfigStruct{}
+	clients := []map[string]any{}
+	sites := []map[string]any{}
+
+	fmt.Printf("\n== BitTorrent clients ==\n")
+	for len(clients) == 0 || askYesNo("Add another client", false) {
+		clientConfig := askClient(len(clients) == 0)
+		if clientConfig == nil {
+			break
+		}
+		clients = append(clients, util.StructToMap(*clientConfig, true, true))
+	}
+
+	fmt.Printf("\n== PT sites ==\n")
+	for askYesNo("Add a site", len(sites) == 0) {
+		siteConfig := askSite(globalConfig)
+		if siteConfig != nil {
+			sites = append(sites, util.StructToMap(*siteConfig, true, true))
+		}
+	}
+
+	if err := os.MkdirAll(config.ConfigDir, constants.PERM_DIR); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	v := viper.New()
+	v.SetConfigType(config.ConfigType)
+	v.Set("clients", clients)
+	v.Set("sites", sites)
+	if err := v.WriteConfigAs(configFile); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(configFile, constants.PERM); err != nil {
+		return fmt.Errorf("failed to set config file permission: %w", err)
+	}
+	fmt.Printf("\nSuccessfully created config file %s with %d clients and %d sites.\n",
+		configFile, len(clients), len(sites))
+	fmt.Printf(`Run "ptool config verify" to check it, or "ptool status _all" to view clients & sites status.` + "\n")
+	return nil
+}
+
+// Ask client info and verify it by connecting to client. Return nil if user skips it.
+func askClient(first bool) *config.ClientConfigStruct {
+	for {
+		clientType := ask("Client type (qbittorrent / transmission)", "qbittorrent")
+		if _, ok := defaultClientUrls[clientType]; !ok {
+			fmt.Printf("Unsupported client type %q\n", clientType)
+			continue
+		}
+		defaultName := "local"
+		if !first {
+			defaultName = ""
+		}
+		clientConfig := &config.ClientConfigStruct{
+			Type:     clientType,
+			Name:     ask("Client name", defaultName),
+			Url:      ask("Client (Web UI / RPC) url", defaultClientUrls[clientType]),
+			Username: ask("Username", ""),
+			Password: askPassword("Password"),
+		}
+		if clientConfig.Name == "" {
+			fmt.Printf("Client name can not be empty\n")
+			continue
+		}
+		fmt.Printf("Connecting to client %s ...\n", clientConfig.Url)
+		if err := verifyClient(clientConfig); err != nil {
+			fmt.Printf("✕ Failed to connect to client: %v\n", err)
+			if askYesNo("Re-input client info", true) {
+				continue
+			}
+			if !askYesNo("Save the client config anyway", false) {
+				return nil
+			}
+		} else {
+			fmt.Printf("✓ Client connected\n")
+		}
+		return clientConfig
+	}
+}
+
+func verifyClient(clientConfig *config.ClientConfigStruct) error {
+	regInfo, err := client.Find(clientConfig.Type)
+	if err != nil {
+		return err
+	}
+	clientInstance, err := regInfo.Creator(clientConfig.Name, clientConfig, &config.ConfigStruct{})
+	if err != nil {
+		return err
+	}
+	defer clientInstance.Close()
+	_, err = clientInstance.GetStatus()
+	return err
+}
+
+// Ask site info and test the cookie by fetching user info from site. Return nil if user skips it.
+func askSite(globalConfig *config.ConfigStruct) *config.SiteConfigStruct {
+	for {
+		siteType := ask(`Site type (e.g. "mteam". Run "ptool sites" to see all supported sites)`, "")
+		if siteType == "" {
+			return nil
+		}
+		if tpl.SITES[siteType] == nil {
+			fmt.Printf("Site %q is not supported. Unsupported site can be added by editing config file manually\n",
+				siteType)
+			continue
+		}
+		siteConfig := &config.SiteConfigStruct{
+			Type:   siteType,
+			Cookie: askPassword("Site cookie (get it from browser devtools)"),
+		}
+		fmt.Printf("Testing site %s cookie ...\n", siteType)
+		if err := verifySite(siteConfig, globalConfig); err != nil {
+			fmt.Printf("✕ Site cookie test failed: %v\n", err)
+			if askYesNo("Re-input site info", true) {
+				continue
+			}
+			if !askYesNo("Save the site config anyway", false) {
+				return nil
+			}
+		} else {
+			fmt.Printf("✓ Site cookie is valid\n")
+		}
+		return siteConfig
+	}
+}
+
+func verifySite(siteConfig *config.SiteConfigStruct, globalConfig *config.ConfigStruct) error {
+	siteInstance, err := site.CreateSiteInternal(siteConfig.GetName(), siteConfig, globalConfig)
+	if err != nil {
+		return err
+	}
+	status, err := siteInstance.GetStatus()
+	if err != nil {
+		return err
+	}
+	if !status.IsOk() {
+		return fmt.Errorf("failed to get user info from site")
+	}
+	return nil
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func ask(prompt string, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", prompt, defaultValue)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+	input, _ := stdin.ReadString('\n')
+	if input = strings.TrimSpace(input); input == "" {
+		return defaultValue
+	}
+	return input
+}
+
+func askPassword(prompt string) string {
+	fmt.Printf("%s (input is hidden): ", prompt)
+	password, _ := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Printf("\n")
+	return strings.TrimSpace(string(password))
+}
+
+func askYesNo(prompt string, defaultValue bool) bool {
+	defaultInput := "no"
+	if defaultValue {
+		defaultInput = "yes"
+	}
+	for {
+		switch strings.ToLower(ask(prompt+"? (yes/no)", defaultInput)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
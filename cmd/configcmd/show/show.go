@@ -12,8 +12,9 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:   "show {site | client | group | cookiecloud_profile | alias}...",
-	Short: "Show effective config of config items.",
+	Use:         "show {site | client | group | cookiecloud_profile | alias}...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "config.show"},
+	Short:       "Show effective config of config items.",
 	Long: `Show effective config of config items.
 It prints output in toml format.`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
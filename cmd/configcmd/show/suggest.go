@@ -0,0 +1,21 @@
+package show
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("config.show", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex < 2 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		return suggest.ClientOrSiteOrGroupArg(info.MatchingPrefix)
+	})
+}
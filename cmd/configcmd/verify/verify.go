@@ -0,0 +1,202 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/configcmd"
+	"github.com/sagan/ptool/cmd/cookiecloud"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "verify [--offline] [--json]",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       "Verify config file and check connectivity of config items.",
+	Long: `Verify config file and check connectivity of config items.
+
+It parses the config file and statically checks:
+- Syntax errors, and unknown (e.g. misspelled) fields.
+- Empty, invalid or duplicate names of clients / sites / groups / aliases / cookiecloud profiles.
+- Unsupported client or site types, invalid urls or size values.
+- Groups and cookiecloud profiles which reference non-existent sites.
+- Non-existent commands or invalid flags in commandDefaults.
+
+Then, unless --offline flag is set or some fatal problem is found, it checks online:
+- Connectivity of each enabled client.
+- Cookie validity of each enabled (and not dead) site, by fetching the user status from site.
+- Connectivity of each enabled cookiecloud profile.
+The master password is only required by online checks, if config file contains encrypted secrets.
+
+It prints a report of all found problems, and exits with non-zero code if there is any problem.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: verify,
+}
+
+var (
+	offline  = false
+	showJson = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&offline, "offline", "", false, "Only check config file statically, do NOT check online")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	configcmd.Command.AddCommand(command)
+}
+
+func verify(cmd *cobra.Command, args []string) error {
+	configFile := filepath.Join(config.ConfigDir, config.ConfigFile)
+	problems, err := config.Verify(configFile, config.ConfigType)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", configFile, err)
+	}
+	fatal := slices.ContainsFunc(problems, func(problem *config.Problem) bool { return problem.Fatal })
+	if !fatal {
+		problems = append(problems, verifyTypes()...)
+		if !offline {
+			// online checks use the secrets of config file
+			if err := config.LoadError(); err != nil {
+				problems = append(problems, &config.Problem{Message: err.Error(), Fatal: true})
+			} else {
+				problems = append(problems, verifyOnline()...)
+			}
+		}
+	}
+	if showJson {
+		if err := util.PrintJson(os.Stdout, problems); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Config file: %s\n", configFile)
+		for _, problem := range problems {
+			level := "warning"
+			if problem.Fatal {
+				level = "fatal"
+			}
+			fmt.Printf("✕ [%s] %s\n", level, problem)
+		}
+		if fatal {
+			fmt.Printf("// Note: online checks are skipped as ptool is unable to load the config file\n")
+		}
+		if len(problems) == 0 {
+			fmt.Printf("✓ No problems found\n")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	return nil
+}
+
+func verifyTypes() (problems []*config.Problem) {
+	for i, clientConfig := range config.Get().Clients {
+		if clientConfig.Type != "" && !slices.ContainsFunc(client.Registry, func(regInfo *client.RegInfo) bool {
+			return regInfo.Name == clientConfig.Type
+		}) {
+			problems = append(problems, &config.Problem{
+				Item:    fmt.Sprintf("clients[%d] (%s)", i, clientConfig.Name),
+				Message: fmt.Sprintf("unsupported client type %q", clientConfig.Type),
+			})
+		}
+	}
+	for i, siteConfig := range config.Get().Sites {
+		if !site.TypeExists(siteConfig.Type) {
+			problems = append(problems, &config.Problem{
+				Item:    fmt.Sprintf("sites[%d] (%s)", i, siteConfig.GetName()),
+				Message: fmt.Sprintf("unsupported site type %q", siteConfig.Type),
+			})
+		}
+	}
+	for key, value := range config.Get().CommandDefaults {
+		item := fmt.Sprintf("commandDefaults (%s)", key)
+		command, remaining, err := cmd.RootCmd.Find(strings.Fields(key))
+		if err != nil || len(remaining) > 0 || command == cmd.RootCmd {
+			problems = append(problems, &config.Problem{Item: item, Message: fmt.Sprintf("command %q not found", key)})
+		} else if _, err := cmd.ParseDefaultFlags(command, value); err != nil {
+			problems = append(problems, &config.Problem{Item: item, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// Check clients, sites and cookiecloud profiles concurrently.
+func verifyOnline() (problems []*config.Problem) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	check := func(item string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				problems = append(problems, &config.Problem{Item: item, Message: err.Error()})
+				mu.Unlock()
+			}
+		}()
+	}
+	// create instances in main goroutine, as the creation is NOT concurrency safe
+	for _, clientConfig := range config.Get().ClientsEnabled {
+		item := "client " + clientConfig.Name
+		clientInstance, err := client.CreateClient(clientConfig.Name)
+		if err != nil {
+			problems = append(problems, &config.Problem{Item: item, Message: "failed to create client: " + err.Error()})
+			continue
+		}
+		check(item, func() error {
+			if _, err := clientInstance.GetStatus(); err != nil {
+				return fmt.Errorf("failed to connect: %w", err)
+			}
+			return nil
+		})
+	}
+	for _, siteConfig := range config.Get().SitesEnabled {
+		if siteConfig.Dead || !site.TypeExists(siteConfig.Type) {
+			continue
+		}
+		item := "site " + siteConfig.GetName()
+		siteInstance, err := site.CreateSite(siteConfig.GetName())
+		if err != nil {
+			problems = append(problems, &config.Problem{Item: item, Message: "failed to create site: " + err.Error()})
+			continue
+		}
+		check(item, func() error {
+			status, err := siteInstance.GetStatus()
+			if err != nil {
+				return fmt.Errorf("failed to get site status: %w", err)
+			}
+			if !status.IsOk() {
+				return fmt.Errorf("cookie seems invalid: failed to get user info from site")
+			}
+			return nil
+		})
+	}
+	for _, profile := range cookiecloud.ParseProfile("") {
+		check("cookiecloud "+util.ParseUrlHostname(profile.Server)+" (uuid "+profile.Uuid+")", func() error {
+			if _, err := cookiecloud.GetCookiecloudData(profile.Server, profile.Uuid, profile.Password,
+				config.GetProxy(profile.Proxy), util.FirstNonZeroIntegerArg(config.Timeout, profile.Timeout)); err != nil {
+				return fmt.Errorf("failed to get data: %w", err)
+			}
+			return nil
+		})
+	}
+	wg.Wait()
+	slices.SortFunc(problems, func(a, b *config.Problem) int {
+		if a.Item < b.Item {
+			return -1
+		} else if a.Item > b.Item {
+			return 1
+		}
+		return 0
+	})
+	return problems
+}
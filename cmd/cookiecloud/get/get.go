@@ -11,6 +11,7 @@ import (
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/cookiecloud"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -70,12 +71,14 @@ func get(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no cookiecloud profile specified or found")
 	}
 	cookiecloudDatas := []cookiecloud.Ccdata_struct{}
+	var lastErr error
 	for _, profile := range cookiecloudProfiles {
 		data, err := cookiecloud.GetCookiecloudData(profile.Server, profile.Uuid, profile.Password,
 			config.GetProxy(profile.Proxy), util.FirstNonZeroIntegerArg(config.Timeout, profile.Timeout))
 		if err != nil {
 			log.Errorf("Cookiecloud server %s (uuid %s) connection failed: %v\n", profile.Server, profile.Uuid, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			log.Infof("Cookiecloud server %s (uuid %s) connection ok: cookies of %d domains found\n",
 				profile.Server, profile.Uuid, len(data.Cookie_data))
@@ -87,7 +90,7 @@ func get(cmd *cobra.Command, args []string) error {
 		}
 	}
 	if len(cookiecloudDatas) == 0 {
-		return fmt.Errorf("no cookiecloud server can be connected")
+		return fmt.Errorf("no cookiecloud server can be connected: %w", lastErr)
 	}
 	siteOrDomainOrUrls := config.ParseGroupAndOtherNames(args...)
 
@@ -167,7 +170,7 @@ func get(cmd *cobra.Command, args []string) error {
 	}
 
 	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
 	}
 	return nil
 }
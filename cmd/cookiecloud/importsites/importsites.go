@@ -8,8 +8,11 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/cmd/cookiecloud"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/site/tpl"
 	"github.com/sagan/ptool/util"
@@ -60,12 +63,14 @@ func importsites(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no cookiecloud profile specified or found")
 	}
 	cookiecloudDatas := []cookiecloud.Ccdata_struct{}
+	var lastErr error
 	for _, profile := range cookiecloudProfiles {
 		data, err := cookiecloud.GetCookiecloudData(profile.Server, profile.Uuid, profile.Password,
 			config.GetProxy(profile.Proxy), util.FirstNonZeroIntegerArg(config.Timeout, profile.Timeout))
 		if err != nil {
 			log.Errorf("Cookiecloud server %s (uuid %s) connection failed: %v\n", profile.Server, profile.Uuid, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			log.Infof("Cookiecloud server %s (uuid %s) connection ok: cookies of %d domains found\n",
 				profile.Server, profile.Uuid, len(data.Cookie_data))
@@ -76,7 +81,7 @@ func importsites(cmd *cobra.Command, args []string) error {
 		}
 	}
 	if len(cookiecloudDatas) == 0 {
-		return fmt.Errorf("no cookiecloud server can be connected")
+		return fmt.Errorf("no cookiecloud server can be connected: %w", lastErr)
 	}
 
 	addSites := []*config.SiteConfigStruct{}
@@ -170,8 +175,11 @@ func importsites(cmd *cobra.Command, args []string) error {
 				configFile)) {
 			return fmt.Errorf("abort")
 		}
+		if common.DryRun("update config file %s", configFile) {
+			return nil
+		}
 		config.UpdateSites(addSites)
-		err := config.Set()
+		err := audit.SetConfig()
 		if err == nil {
 			fmt.Printf("Successfully update config file %s\n", configFile)
 			return nil
@@ -183,7 +191,7 @@ func importsites(cmd *cobra.Command, args []string) error {
 	}
 
 	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/cmd/cookiecloud"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/util"
@@ -31,6 +32,7 @@ func init() {
 
 func status(cmd *cobra.Command, args []string) error {
 	errorCnt := int64(0)
+	var lastErr error
 	cookiecloudProfiles := cookiecloud.ParseProfile(profile)
 	if len(cookiecloudProfiles) == 0 {
 		return fmt.Errorf("no cookiecloud profile specified or found")
@@ -42,13 +44,11 @@ func status(cmd *cobra.Command, args []string) error {
 			fmt.Printf("✕cookiecloud server %s (uuid %s) test failed: %v\n",
 				util.ParseUrlHostname(profile.Server), profile.Uuid, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			fmt.Printf("✓cookiecloud server %s (uuid %s) test ok: cookies of %d domains found\n",
 				util.ParseUrlHostname(profile.Server), profile.Uuid, len(data.Cookie_data))
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(cookiecloudProfiles)), lastErr)
 }
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -8,8 +9,11 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/cmd/cookiecloud"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -62,12 +66,14 @@ func sync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no cookiecloud profile specified or found")
 	}
 	cookiecloudDatas := []cookiecloud.Ccdata_struct{}
+	var lastErr error
 	for _, profile := range cookiecloudProfiles {
 		data, err := cookiecloud.GetCookiecloudData(profile.Server, profile.Uuid, profile.Password,
 			config.GetProxy(profile.Proxy), util.FirstNonZeroIntegerArg(config.Timeout, profile.Timeout))
 		if err != nil {
 			log.Errorf("Cookiecloud server %s (uuid %s) connection failed: %v\n", profile.Server, profile.Uuid, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			log.Infof("Cookiecloud server %s (uuid %s) connection ok: cookies of %d domains found\n",
 				profile.Server, profile.Uuid, len(data.Cookie_data))
@@ -79,7 +85,7 @@ func sync(cmd *cobra.Command, args []string) error {
 		}
 	}
 	if len(cookiecloudDatas) == 0 {
-		return fmt.Errorf("no cookiecloud server can be connected")
+		return fmt.Errorf("no cookiecloud server can be connected: %w", lastErr)
 	}
 	var sitenames []string
 	if siteFlag == "" {
@@ -97,72 +103,18 @@ func sync(cmd *cobra.Command, args []string) error {
 	// 5-该网站不使用 cookie 鉴权(跳过)。
 	var siteFlags = make(map[string]int)
 	var siteUrls = make(map[string]string)
-	ch := make(chan *site_test_result, len(sitenames))
-	for _, sitename := range sitenames {
-		go func(sitename string, ch chan<- *site_test_result) {
-			siteconfig := config.GetSiteConfig(sitename)
-			if siteconfig == nil {
-				ch <- &site_test_result{
-					sitename: sitename,
-					flag:     2,
-					msg:      "site not found in config",
-				}
-				return
-			}
-			if siteconfig.Dead || siteconfig.NoCookie {
-				ch <- &site_test_result{
-					sitename: sitename,
-					flag:     5,
-					msg:      "site is dead or does not use cookie",
-				}
-				return
-			}
-			siteInstance, err := site.CreateSiteInternal(sitename, siteconfig, config.Get())
-			if err != nil {
-				ch <- &site_test_result{
-					sitename: sitename,
-					flag:     3,
-					msg:      fmt.Sprintf("site current cookie is invalid (create instance err: %v)", err),
-				}
-				return
-			}
-			log.Tracef("Checking site %s", sitename)
-			sitestatus, err := siteInstance.GetStatus()
-			if err != nil {
-				if util.AsNetworkError(err) {
-					ch <- &site_test_result{
-						sitename: sitename,
-						url:      siteInstance.GetSiteConfig().Url,
-						flag:     2,
-						msg:      fmt.Sprintf("site is inaccessible currently (get status error: %v)", err),
-					}
-				} else {
-					ch <- &site_test_result{
-						sitename: sitename,
-						url:      siteInstance.GetSiteConfig().Url,
-						flag:     3,
-						msg:      fmt.Sprintf("site current cookie is invalid (get status error: %v)", err),
-					}
-				}
-			} else if !sitestatus.IsOk() {
-				ch <- &site_test_result{
-					sitename: sitename,
-					url:      siteInstance.GetSiteConfig().Url,
-					flag:     3,
-					msg:      "site status is not OK (cookie may be invalid)",
-				}
-			} else {
-				ch <- &site_test_result{
-					sitename: sitename,
-					url:      siteInstance.GetSiteConfig().Url,
-					flag:     1,
-					msg:      fmt.Sprintf("site current cookie is valid (username: %s)", sitestatus.UserName),
-				}
+	results, errs := util.ParallelMap(sitenames, config.GetConcurrency(), config.GetItemTimeout(),
+		func(_ context.Context, sitename string) (*site_test_result, error) {
+			return checkSite(sitename), nil
+		})
+	for i, result := range results {
+		if errs[i] != nil {
+			result = &site_test_result{
+				sitename: sitenames[i],
+				flag:     2,
+				msg:      fmt.Sprintf("site is inaccessible currently (%v)", errs[i]),
 			}
-		}(sitename, ch)
-	}
-	for i := 0; i < len(sitenames); i++ {
-		result := <-ch
+		}
 		symbol := ""
 		switch result.flag {
 		case 1:
@@ -176,7 +128,7 @@ func sync(cmd *cobra.Command, args []string) error {
 		}
 		siteFlags[result.sitename] = result.flag
 		siteUrls[result.sitename] = result.url
-		log.Infof("%s site %s: %s // remaining sites: %d", symbol, result.sitename, result.msg, len(sitenames)-i-1)
+		log.Infof("%s site %s: %s", symbol, result.sitename, result.msg)
 	}
 	nowStr := util.FormatTime(util.Now())
 	for _, sitename := range sitenames {
@@ -263,8 +215,11 @@ func sync(cmd *cobra.Command, args []string) error {
 			"Will update the config file (%s). Be aware that all existing comments will be LOST", configFile)) {
 			return fmt.Errorf("abort")
 		}
+		if common.DryRun("update config file %s", configFile) {
+			return nil
+		}
 		config.UpdateSites(updatesites)
-		err := config.Set()
+		err := audit.SetConfig()
 		if err == nil {
 			fmt.Printf("Successfully update config file %s\n", configFile)
 			return nil
@@ -276,7 +231,67 @@ func sync(cmd *cobra.Command, args []string) error {
 	}
 
 	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
 	}
 	return nil
 }
+
+// Check the current cookie of site.
+func checkSite(sitename string) *site_test_result {
+	siteconfig := config.GetSiteConfig(sitename)
+	if siteconfig == nil {
+		return &site_test_result{
+			sitename: sitename,
+			flag:     2,
+			msg:      "site not found in config",
+		}
+	}
+	if siteconfig.Dead || siteconfig.NoCookie {
+		return &site_test_result{
+			sitename: sitename,
+			flag:     5,
+			msg:      "site is dead or does not use cookie",
+		}
+	}
+	siteInstance, err := site.CreateSiteInternal(sitename, siteconfig, config.Get())
+	if err != nil {
+		return &site_test_result{
+			sitename: sitename,
+			flag:     3,
+			msg:      fmt.Sprintf("site current cookie is invalid (create instance err: %v)", err),
+		}
+	}
+	log.Tracef("Checking site %s", sitename)
+	sitestatus, err := siteInstance.GetStatus()
+	if err != nil {
+		if util.AsNetworkError(err) {
+			return &site_test_result{
+				sitename: sitename,
+				url:      siteInstance.GetSiteConfig().Url,
+				flag:     2,
+				msg:      fmt.Sprintf("site is inaccessible currently (get status error: %v)", err),
+			}
+		} else {
+			return &site_test_result{
+				sitename: sitename,
+				url:      siteInstance.GetSiteConfig().Url,
+				flag:     3,
+				msg:      fmt.Sprintf("site current cookie is invalid (get status error: %v)", err),
+			}
+		}
+	} else if !sitestatus.IsOk() {
+		return &site_test_result{
+			sitename: sitename,
+			url:      siteInstance.GetSiteConfig().Url,
+			flag:     3,
+			msg:      "site status is not OK (cookie may be invalid)",
+		}
+	} else {
+		return &site_test_result{
+			sitename: sitename,
+			url:      siteInstance.GetSiteConfig().Url,
+			flag:     1,
+			msg:      fmt.Sprintf("site current cookie is valid (username: %s)", sitestatus.UserName),
+		}
+	}
+}
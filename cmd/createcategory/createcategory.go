@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 )
 
@@ -38,6 +39,10 @@ func createcategory(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
+	if common.DryRun("create or update category %s (save path = %q) in client %s", category, savePath,
+		clientInstance.GetName()) {
+		return nil
+	}
 	err = clientInstance.MakeCategory(category, savePath)
 	if err != nil {
 		return fmt.Errorf("failed to create category: %w", err)
@@ -2,11 +2,13 @@ package createtags
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 )
 
 var command = &cobra.Command{
@@ -31,6 +33,9 @@ func createtags(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
+	if common.DryRun("create tags %s in client %s", strings.Join(tags, ","), clientInstance.GetName()) {
+		return nil
+	}
 	err = clientInstance.CreateTags(tags...)
 	if err != nil {
 		return fmt.Errorf("Failed to create tags: %w", err)
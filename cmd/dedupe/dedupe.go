@@ -0,0 +1,142 @@
+package dedupe
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+var command = &cobra.Command{
+	Use:         "dedupe {client | torrentFilename | dir}...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "dedupe"},
+	Short:       "Find torrents of identical contents in clients and local .torrent files.",
+	Long: `Find torrents of identical contents in clients and local .torrent files.
+Each arg is a client name, a local .torrent filename, or a local dir, of which all *.torrent files
+inside it (recursively) are read.
+
+Torrents are identical if they have the same content files (path & size), ignoring the root folder name.
+Their info-hashes could be different, e.g. the torrents of different sites (trackers, "source" flag)
+made for the same contents. The disk file contents themselves are NOT compared.
+
+It prints groups of identical torrents. Each group has at least 2 torrents.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: dedupe,
+}
+
+var (
+	showJson   = false
+	minSizeStr = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().StringVarP(&minSizeStr, "min-size", "", "-1",
+		"Skip torrent which contents size is smaller than (<) this value. -1 == no limit")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func dedupe(cmd *cobra.Command, args []string) error {
+	minSize, err := util.RAMInBytes(minSizeStr)
+	if err != nil {
+		return fmt.Errorf("invalid min-size: %w", err)
+	}
+	errorCnt := int64(0)
+	index := torrentutil.NewContentIndex()
+	for _, arg := range args {
+		if client.ClientExists(arg) {
+			clientInstance, err := client.CreateClient(arg)
+			if err != nil {
+				return fmt.Errorf("failed to create client %s: %w", arg, err)
+			}
+			torrents, err := clientInstance.GetTorrents("", "", true)
+			if err != nil {
+				return fmt.Errorf("failed to get client %s torrents: %w", arg, err)
+			}
+			index.AddClientTorrents(arg, clientInstance, util.Filter(torrents, func(t *client.Torrent) bool {
+				return t.SizeTotal >= minSize
+			}))
+			continue
+		}
+		filenames, err := getTorrentFilenames(arg)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			tinfo, _, err := helper.GetTorrentMeta(filename, "", true, false, nil)
+			if err != nil {
+				log.Errorf("%s: failed to parse: %v", filename, err)
+				errorCnt++
+				continue
+			}
+			if tinfo.Size >= minSize {
+				index.AddTorrentMeta(filename, tinfo)
+			}
+		}
+	}
+	groups, errs := index.Duplicates()
+	for _, err := range errs {
+		log.Errorf("%v", err)
+		errorCnt++
+	}
+	if showJson {
+		if groups == nil {
+			groups = [][]*torrentutil.ContentIndexEntry{}
+		}
+		if err := util.PrintJson(os.Stdout, groups); err != nil {
+			return err
+		}
+	} else {
+		tableColumns := []*util.TableColumn{
+			{Title: "Source"},
+			{Title: "InfoHash", Width: 40},
+			{Title: "Tracker"},
+			{Title: "Name"},
+		}
+		for i, group := range groups {
+			fmt.Printf("Identical contents #%d: size = %s (%d) ; %d torrents\n",
+				i+1, util.BytesSize(float64(group[0].Size)), group[0].Size, len(group))
+			util.PrintTable(os.Stdout, tableColumns, util.Map(group, func(entry *torrentutil.ContentIndexEntry) []string {
+				return []string{entry.Source, entry.InfoHash, util.ParseUrlHostname(entry.Tracker), entry.Name}
+			}), 0, false, true)
+		}
+		fmt.Printf("\nTotal: %d torrents checked, %d groups of identical torrents found\n",
+			len(index.Entries), len(groups))
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
+	return nil
+}
+
+// Return the .torrent files of arg. If arg is a dir, return all *.torrent files inside it recursively.
+func getTorrentFilenames(arg string) ([]string, error) {
+	if stat, err := os.Stat(arg); err != nil || !stat.IsDir() {
+		return helper.ParseFilenameArgs(arg), nil
+	}
+	var filenames []string
+	err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".torrent") {
+			filenames = append(filenames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %s: %w", arg, err)
+	}
+	return filenames, nil
+}
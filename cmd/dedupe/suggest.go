@@ -0,0 +1,18 @@
+package dedupe
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("dedupe", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIsFlag {
+			return nil
+		}
+		return append(suggest.ClientArg(info.MatchingPrefix), suggest.FileArg(info.MatchingPrefix, ".torrent", false)...)
+	})
+}
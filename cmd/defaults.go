@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/shlex"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/sagan/ptool/config"
+)
+
+// A flag value in "commandDefaults" config.
+type DefaultFlag struct {
+	Name  string
+	Value string
+}
+
+// Return the key of command in "commandDefaults" config, which is the command path without root name.
+// E.g. "status", "cookiecloud sync".
+func CommandDefaultsKey(command *cobra.Command) string {
+	return strings.TrimPrefix(command.CommandPath(), command.Root().Name()+" ")
+}
+
+// Parse the flags string of command in "commandDefaults" config. E.g. "--format json -a".
+// Only flags are allowed, positional args are not supported.
+func ParseDefaultFlags(command *cobra.Command, defaults string) ([]*DefaultFlag, error) {
+	args, err := shlex.Split(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", defaults, err)
+	}
+	defaultFlags := []*DefaultFlag{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			return nil, fmt.Errorf("invalid arg %q: only flags are allowed", arg)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var flag *pflag.Flag
+		if strings.HasPrefix(arg, "--") {
+			if flag = command.Flags().Lookup(name); flag == nil {
+				flag = command.InheritedFlags().Lookup(name)
+			}
+		} else if len(name) == 1 {
+			if flag = command.Flags().ShorthandLookup(name); flag == nil {
+				flag = command.InheritedFlags().ShorthandLookup(name)
+			}
+		}
+		if flag == nil {
+			return nil, fmt.Errorf("unknown flag %q", arg)
+		}
+		if !hasValue {
+			if flag.NoOptDefVal != "" {
+				value = flag.NoOptDefVal
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, fmt.Errorf("flag %q needs an argument", arg)
+			}
+		}
+		defaultFlags = append(defaultFlags, &DefaultFlag{Name: flag.Name, Value: value})
+	}
+	return defaultFlags, nil
+}
+
+// The flag annotation of cobra mutually exclusive flag groups. See cobra.Command.MarkFlagsMutuallyExclusive.
+const cobraMutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// Apply default flag values of command in "commandDefaults" config.
+// Flags that are explicitly set in command line take precedence and are not touched, and default flags that
+// are mutually exclusive with them are skipped. Default values are set without marking flags as changed,
+// so they do not count as explicitly set flags in flag groups validation or cmd compatibility checks.
+func applyCommandDefaults(command *cobra.Command) error {
+	defaults := config.Get().CommandDefaults[CommandDefaultsKey(command)]
+	if defaults == "" {
+		return nil
+	}
+	if err := applyDefaultFlags(command, defaults); err != nil {
+		return fmt.Errorf("invalid commandDefaults config of %q: %w", CommandDefaultsKey(command), err)
+	}
+	return nil
+}
+
+// Apply the default flags string (e.g. "--format json -a") to parsed command. See applyCommandDefaults.
+func applyDefaultFlags(command *cobra.Command, defaults string) error {
+	defaultFlags, err := ParseDefaultFlags(command, defaults)
+	if err != nil {
+		return err
+	}
+	changed := map[string]bool{}
+	command.Flags().Visit(func(flag *pflag.Flag) {
+		changed[flag.Name] = true
+	})
+	for _, defaultFlag := range defaultFlags {
+		if changed[defaultFlag.Name] {
+			continue
+		}
+		flag := command.Flags().Lookup(defaultFlag.Name)
+		if flag == nil {
+			flag = command.InheritedFlags().Lookup(defaultFlag.Name)
+		}
+		if name := exclusiveFlag(flag, changed); name != "" {
+			log.Debugf("Skip default flag --%s: mutually exclusive with --%s", defaultFlag.Name, name)
+			continue
+		}
+		log.Debugf("Apply default flag --%s=%s", defaultFlag.Name, defaultFlag.Value)
+		// flag.Value.Set, unlike FlagSet.Set, does not mark flag as changed
+		if err := flag.Value.Set(defaultFlag.Value); err != nil {
+			return fmt.Errorf("flag --%s: %w", defaultFlag.Name, err)
+		}
+	}
+	return nil
+}
+
+// Return the name of a flag in flags, which is in a mutually exclusive flag group with flag.
+// Return empty string if none.
+func exclusiveFlag(flag *pflag.Flag, flags map[string]bool) string {
+	for _, group := range flag.Annotations[cobraMutuallyExclusiveAnnotation] {
+		for _, name := range strings.Fields(group) {
+			if name != flag.Name && flags[name] {
+				return name
+			}
+		}
+	}
+	return ""
+}
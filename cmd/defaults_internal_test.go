@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyDefaultFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults string
+		args     []string
+		json     bool
+		table    bool
+		format   string
+		tags     []string
+		wantErr  bool
+	}{
+		{"default", "--json --format yaml", nil, true, false, "yaml", nil, false},
+		{"explicit flag takes precedence", "--format yaml", []string{"--format", "xml"}, false, false, "xml", nil,
+			false},
+		{"default with explicit conflicting flag", "--json", []string{"--table"}, false, true, "", nil, false},
+		{"short flag with explicit conflicting flag", "-j --format yaml", []string{"--table"}, false, true, "yaml",
+			nil, false},
+		{"slice", "--tag a --tag b", nil, false, false, "", []string{"a", "b"}, false},
+		{"explicit slice", "--tag a", []string{"--tag", "c"}, false, false, "", []string{"c"}, false},
+		{"explicit conflicting flags", "", []string{"--json", "--table"}, false, false, "", nil, true},
+		{"conflicting defaults", "--json --table", nil, false, false, "", nil, true},
+		{"invalid default", "--format", nil, false, false, "", nil, true},
+		{"unknown default", "--unknown", nil, false, false, "", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var json, table bool
+			var format string
+			var tags []string
+			command := &cobra.Command{
+				Use: "test",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+					return applyDefaultFlags(cmd, test.defaults)
+				},
+				RunE: func(cmd *cobra.Command, args []string) error {
+					// compatibility check of cmd on flag values
+					if json && table {
+						return fmt.Errorf("--json and --table flags are NOT compatible")
+					}
+					return nil
+				},
+				SilenceErrors: true,
+				SilenceUsage:  true,
+			}
+			command.Flags().BoolVarP(&json, "json", "j", false, "")
+			command.Flags().BoolVarP(&table, "table", "", false, "")
+			command.Flags().StringVarP(&format, "format", "", "", "")
+			command.Flags().StringArrayVarP(&tags, "tag", "", nil, "")
+			command.MarkFlagsMutuallyExclusive("json", "table")
+			command.SetArgs(test.args)
+			err := command.Execute()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if json != test.json || table != test.table || format != test.format || !slices.Equal(tags, test.tags) {
+				t.Errorf("expected json=%t, table=%t, format=%q, tags=%v; got json=%t, table=%t, format=%q, tags=%v",
+					test.json, test.table, test.format, test.tags, json, table, format, tags)
+			}
+			if command.Flags().Changed("json") != slices.Contains(test.args, "--json") {
+				t.Errorf("expected --json flag changed=%t", !command.Flags().Changed("json"))
+			}
+		})
+	}
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -22,21 +24,33 @@ var command = &cobra.Command{
 	Long: fmt.Sprintf(`Delete torrents from client.
 %s.
 
-It will ask for confirmation of deletion, unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+If --trash flag is set, the torrents are moved to trash instead: ptool exports the .torrent file
+and saves a restore manifest to trash dir (the "trashDir" config, default is "trash" in config dir),
+moves the content files to the ".ptool_trash" dir of save path (the content files of torrents which have
+other xseed torrents in client are kept in place, the same check of "--preserve-if-xseed-exist";
+if multiple deleted torrents have the same content, the files are moved with the last trashed one),
+then deletes the torrents (without files) from client.
+Use "ptool undelete" to list and restore trashed torrents. Trash entries are permanently deleted after
+the retention time ("trashRetention" config, default is "7d").
+It requires ptool has access to the save path of torrents, use "--map-save-path" if the client is running
+in a different file system (e.g. Docker).
+
+It will ask for confirmation of deletion, unless --force flag is set.
+With --dry-run flag, it only displays the torrents to delete without actually deleting them.`, constants.HELP_INFOHASH_ARGS),
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: delete,
 }
 
 var (
-	preserve          = false
-	preserveXseed     = false
-	force             = false
-	filter            = ""
-	category          = ""
-	tag               = ""
-	tracker           = ""
-	minTorrentSizeStr = ""
-	maxTorrentSizeStr = ""
+	preserve      = false
+	preserveXseed = false
+	force         = false
+	filter        = ""
+	category      = ""
+	tag           = ""
+	trash         = false
+	filterFlags   = &common.TorrentFilterFlags{}
+	mapSavePaths  []string
 )
 
 func init() {
@@ -45,14 +59,16 @@ func init() {
 	command.Flags().BoolVarP(&preserveXseed, "preserve-if-xseed-exist", "P", false,
 		"Preserve (don't delete) torrent content files on the disk if other xseed torrents exist")
 	command.Flags().BoolVarP(&force, "force", "", false, "Force deletion. Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&trash, "trash", "", false,
+		`Move torrents to trash instead of deleting them. Use "ptool undelete" to restore`)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
-	command.Flags().StringVarP(&tracker, "tracker", "", "", constants.HELP_ARG_TRACKER)
-	command.Flags().StringVarP(&minTorrentSizeStr, "min-torrent-size", "", "-1",
-		"Skip torrent with size smaller than (<) this value. -1 == no limit")
-	command.Flags().StringVarP(&maxTorrentSizeStr, "max-torrent-size", "", "-1",
-		"Skip torrent with size larger than (>) this value. -1 == no limit")
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Used with --trash. Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	filterFlags.AddFlags(command)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -60,16 +76,31 @@ func delete(cmd *cobra.Command, args []string) error {
 	if preserve && preserveXseed {
 		return fmt.Errorf("--preserve and --preserve-if-xseed-exist flags are NOT compatible")
 	}
+	if trash && (preserve || preserveXseed) {
+		return fmt.Errorf("--trash flag is NOT compatible with --preserve or --preserve-if-xseed-exist")
+	}
+	if !trash && len(mapSavePaths) > 0 {
+		return fmt.Errorf("--map-save-path must be used with --trash flag")
+	}
 	clientName := args[0]
 	infoHashes := args[1:]
+	var savePathMapper *common.PathMapper
+	if trash {
+		var err error
+		if savePathMapper, err = common.GetSavePathMapper(clientName, mapSavePaths, false); err != nil {
+			return err
+		}
+	}
+	torrentFilter, err := filterFlags.Parse()
+	if err != nil {
+		return err
+	}
 	clientInstance, err := client.CreateClient(clientName)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
-	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
 	infohashesOnly := true
-	if category != "" || tag != "" || filter != "" || tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 {
+	if category != "" || tag != "" || filter != "" || !torrentFilter.IsEmpty() {
 		infohashesOnly = false
 	} else {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
@@ -86,11 +117,11 @@ func delete(cmd *cobra.Command, args []string) error {
 	}
 
 	// the quick way, directly submit the deletion request to client
-	if infohashesOnly && !preserveXseed {
+	if infohashesOnly && !preserveXseed && !trash {
 		if len(infoHashes) == 0 {
 			return fmt.Errorf("no torrent to delete")
 		}
-		if force {
+		if force && !flags.DryRun {
 			if err = clientInstance.DeleteTorrents(infoHashes, !preserve); err != nil {
 				return fmt.Errorf("failed to delete torrents: %w", err)
 			}
@@ -101,19 +132,12 @@ func delete(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to fetch client torrents: %w", err)
 	}
-	if tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 {
-		torrents = util.Filter(torrents, func(t *client.Torrent) bool {
-			if tracker != "" && !t.MatchTracker(tracker) ||
-				minTorrentSize >= 0 && t.Size < minTorrentSize ||
-				maxTorrentSize >= 0 && t.Size > maxTorrentSize {
-				return false
-			}
-			return true
-		})
+	if !torrentFilter.IsEmpty() {
+		torrents = util.Filter(torrents, torrentFilter.Match)
 	}
 	// if preserve-xseed flag is set, the torrents which contains other-not-delete xseed torrents
 	var torrentsWithXseed []*client.Torrent
-	if preserveXseed {
+	if preserveXseed || trash {
 		torrents, torrentsWithXseed, err = client.FilterTorrentsXseed(clientInstance, torrents)
 		if err != nil {
 			return err
@@ -123,22 +147,38 @@ func delete(cmd *cobra.Command, args []string) error {
 		log.Infof("No matched torrents found")
 		return nil
 	}
-	if !force {
+	if !force || flags.DryRun {
 		if len(torrents) > 0 {
 			client.PrintTorrents(os.Stdout, torrents, "", 1, false)
-			fmt.Printf("Above %d torrents will be deteled (Delete disk files = %t)\n", len(torrents), !preserve)
+			if trash {
+				fmt.Printf("Above %d torrents will be moved to trash (with disk files)\n", len(torrents))
+			} else {
+				fmt.Printf("Above %d torrents will be deteled (Delete disk files = %t)\n", len(torrents), !preserve)
+			}
 			fmt.Printf("\n")
 		}
 		if len(torrentsWithXseed) > 0 {
 			client.PrintTorrents(os.Stdout, torrentsWithXseed, "", 1, false)
-			fmt.Printf("Above %d torrents will be deleted, they have none-delete xseed torrents exists,\n"+
-				"so their disk files will NOT be deleted.\n", len(torrentsWithXseed))
+			if trash {
+				fmt.Printf("Above %d torrents will be moved to trash, they have none-delete xseed torrents exists,\n"+
+					"so their disk files will be kept in place.\n", len(torrentsWithXseed))
+			} else {
+				fmt.Printf("Above %d torrents will be deleted, they have none-delete xseed torrents exists,\n"+
+					"so their disk files will NOT be deleted.\n", len(torrentsWithXseed))
+			}
 			fmt.Printf("\n")
 		}
+		if flags.DryRun {
+			fmt.Printf("Dry run. %d torrents would be deleted\n", len(torrents)+len(torrentsWithXseed))
+			return nil
+		}
 		if !helper.AskYesNoConfirm("") {
 			return fmt.Errorf("abort")
 		}
 	}
+	if trash {
+		return trashTorrents(clientInstance, torrents, torrentsWithXseed, savePathMapper)
+	}
 	if len(torrentsWithXseed) > 0 {
 		infoHashes := util.Map(torrentsWithXseed, func(t *client.Torrent) string { return t.InfoHash })
 		err = clientInstance.DeleteTorrents(infoHashes, false)
@@ -157,3 +197,33 @@ func delete(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+func trashTorrents(clientInstance client.Client, torrents []*client.Torrent, torrentsWithXseed []*client.Torrent,
+	savePathMapper *common.PathMapper) error {
+	if cnt, err := common.PurgeExpiredTrash(); err != nil {
+		log.Warnf("Failed to purge expired trash: %v", err)
+	} else if cnt > 0 {
+		fmt.Printf("%d expired trash entries purged.\n", cnt)
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	trashCnt := int64(0)
+	for i, torrent := range append(torrents, torrentsWithXseed...) {
+		entry, err := common.TrashTorrent(clientInstance, torrent, savePathMapper, i >= len(torrents))
+		if err != nil {
+			log.Errorf("Failed to move torrent %s (%s) to trash: %v", torrent.Name, torrent.InfoHash, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		if len(entry.Files) == 0 {
+			fmt.Printf("Torrent %s (%s) moved to trash (disk files kept in place): %s\n",
+				torrent.Name, torrent.InfoHash, entry.Id)
+		} else {
+			fmt.Printf("Torrent %s (%s) moved to trash: %s\n", torrent.Name, torrent.InfoHash, entry.Id)
+		}
+		trashCnt++
+	}
+	fmt.Printf("%d torrents moved to trash.\n", trashCnt)
+	return common.ItemsError(errorCnt, int64(len(torrents)+len(torrentsWithXseed)), lastErr)
+}
@@ -2,11 +2,13 @@ package deletecategories
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 )
 
 var command = &cobra.Command{
@@ -31,6 +33,10 @@ func deletecategories(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
+	if common.DryRun("delete categories %s from client %s", strings.Join(categories, ","),
+		clientInstance.GetName()) {
+		return nil
+	}
 	err = clientInstance.DeleteCategories(categories)
 	if err != nil {
 		return err
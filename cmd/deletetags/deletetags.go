@@ -2,11 +2,13 @@ package deletetags
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 )
 
 var command = &cobra.Command{
@@ -31,6 +33,9 @@ func deletetags(cmd *cobra.Command, args []string) error {
 
 	tags := args[1:]
 
+	if common.DryRun("delete tags %s from client %s", strings.Join(tags, ","), clientInstance.GetName()) {
+		return nil
+	}
 	err = clientInstance.DeleteTags(tags...)
 	if err != nil {
 		return fmt.Errorf("failed to delete tags: %w", err)
@@ -12,6 +12,7 @@ import (
 	"golang.org/x/term"
 
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -19,7 +20,7 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:         "dltorrent {torrentId | torrentUrl}... [--dir dir]",
+	Use:         "dltorrent {torrentId | torrentUrl}... [--download-dir dir | --stage-dir dir]",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "dltorrent"},
 	Short:       "Download site torrents to local.",
 	Long: `Download site torrents to local.
@@ -36,7 +37,12 @@ which supports the following variable placeholders:
 * [filename] : Original torrent filename without ".torrent" extension
 * [filename128] : The prefix of [filename] which is at max 128 bytes
 * [name] : Torrent name
-* [name128] : The prefix of torrent name which is at max 128 bytes`,
+* [name128] : The prefix of torrent name which is at max 128 bytes
+
+If "--stage-dir dir" flag is set, torrents are downloaded to the staging dir (instead of "--download-dir")
+and saved with an (empty) meta info in 'comment' field, then "--stage-hook" command (if set)
+is executed for each of them to validate it. Staged torrents can be added to client later by:
+  ptool addlocal <client> <dir> --use-comment-meta --rename-added`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: dltorrent,
 }
@@ -47,6 +53,8 @@ var (
 	downloadDir     = ""
 	rename          = ""
 	defaultSite     = ""
+	stageDir        = ""
+	stageHook       = ""
 	errSkipExisting = errors.New("skip existing torrent")
 )
 
@@ -60,6 +68,9 @@ func init() {
 	command.Flags().StringVarP(&downloadDir, "download-dir", "", ".", `Set the dir of downloaded torrents. `+
 		`Use "-" to directly output torrent content to stdout`)
 	command.Flags().StringVarP(&rename, "rename", "", "", "Rename downloaded torrents (supports variables)")
+	command.Flags().StringVarP(&stageDir, "stage-dir", "", "", "Download torrents to this staging dir. "+
+		`The staged torrents can be added to client later by "ptool addlocal <client> <dir> --use-comment-meta"`)
+	command.Flags().StringVarP(&stageHook, "stage-hook", "", "", constants.HELP_ARG_STAGE_HOOK)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -67,6 +78,7 @@ func init() {
 // to fix it the site.Site interface must be changed to separate torrent url parsing from downloading.
 func dltorrent(cmd *cobra.Command, args []string) error {
 	errorCnt := int64(0)
+	var lastErr error
 	torrents := args
 	if len(torrents) == 1 && torrents[0] == "-" {
 		if data, err := helper.ReadArgsFromStdin(); err != nil {
@@ -77,6 +89,14 @@ func dltorrent(cmd *cobra.Command, args []string) error {
 			torrents = data
 		}
 	}
+	if stageDir != "" {
+		if downloadDir != "." {
+			return fmt.Errorf("--download-dir and --stage-dir flags are NOT compatible")
+		}
+		downloadDir = stageDir
+	} else if stageHook != "" {
+		return fmt.Errorf("--stage-hook flag must be used with --stage-dir")
+	}
 	outputToStdout := false
 	if downloadDir == "-" {
 		if len(torrents) > 1 {
@@ -114,12 +134,14 @@ func dltorrent(cmd *cobra.Command, args []string) error {
 		if outputToStdout {
 			if err != nil {
 				errorCnt++
+				lastErr = err
 				fmt.Fprintf(os.Stderr, "Failed to download torrent: %v\n", err)
 			} else if term.IsTerminal(int(os.Stdout.Fd())) {
 				errorCnt++
 				fmt.Fprintf(os.Stderr, "%s\n", constants.HELP_TIP_TTY_BINARY_OUTPUT)
 			} else if _, err = os.Stdout.Write(content); err != nil {
 				errorCnt++
+				lastErr = err
 				fmt.Fprintf(os.Stderr, "Failed to output torrent content to stdout: %v\n", err)
 			}
 			continue
@@ -131,6 +153,7 @@ func dltorrent(cmd *cobra.Command, args []string) error {
 			} else {
 				fmt.Printf("✕ %s (site=%s): %v\n", torrent, sitename, err)
 				errorCnt++
+				lastErr = err
 			}
 			continue
 		}
@@ -142,16 +165,18 @@ func dltorrent(cmd *cobra.Command, args []string) error {
 		} else {
 			filename = torrentutil.RenameTorrent(rename, sitename, id, _filename, tinfo)
 		}
-		err = os.WriteFile(filepath.Join(downloadDir, filename), content, constants.PERM)
+		if stageDir != "" {
+			_, err = helper.StageTorrent(downloadDir, filename, content, tinfo, nil, stageHook)
+		} else {
+			err = os.WriteFile(filepath.Join(downloadDir, filename), content, constants.PERM)
+		}
 		if err != nil {
 			fmt.Printf("✕ %s (site=%s): failed to save to %s/: %v\n", filename, sitename, downloadDir, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			fmt.Printf("✓ %s (site=%s): saved to %s/\n", filename, sitename, downloadDir)
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
@@ -0,0 +1,378 @@
+package du
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+// Disk usage of a group of torrents.
+type Usage struct {
+	Group         string `json:"group"`
+	Torrents      int64  `json:"torrents"`
+	Size          int64  `json:"size"`          // size of selected files of torrents
+	SizeTotal     int64  `json:"sizeTotal"`     // size of all files of torrents (including unselected ones)
+	SizeCompleted int64  `json:"sizeCompleted"` // downloaded size reported by client
+	SizeUnique    int64  `json:"sizeUnique"`    // size of torrents of distinct content paths (xseed deduplicated)
+	Partial       int64  `json:"partial"`       // cnt of torrents that are partially selected for downloading
+	Incomplete    int64  `json:"incomplete"`    // cnt of torrents that are not fully downloaded
+	DiskSize      int64  `json:"diskSize"`      // size of content files existing on disk. -1: not checked
+	Missing       int64  `json:"missing"`       // cnt of torrents that have missing files on disk. -1: not checked
+	contentPaths  map[string]bool
+}
+
+// The result of checking local disk files of a torrent.
+type diskStat struct {
+	size         int64
+	missingFiles []string
+}
+
+var command = &cobra.Command{
+	Use: "du {client} [--by savepath|category|tag|tracker] [--category category] [--tag tag] " +
+		"[--filter filter] [infoHash]...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "du"},
+	Short:       "Show disk usage of client torrents, grouped by save path, category, tag or tracker.",
+	Long: fmt.Sprintf(`Show disk usage of client torrents, grouped by save path, category, tag or tracker.
+%s.
+If no torrent or filter is provided, all torrents in client are counted.
+
+It displays following fields for each group, ordered by size in desc order:
+- Torrents : Cnt of torrents.
+- Size : Total size of selected (for downloading) files of torrents.
+- Unique : Same as "Size", but torrents of the same content path (xseed torrents) are counted only once.
+- Downloaded : Total downloaded size reported by client.
+- Partial : Cnt of torrents that are only partially selected for downloading.
+- Incomplete : Cnt of torrents that are not fully downloaded.
+
+A torrent which have multiple tags is counted in the group of each tag.
+
+If "--check-files" flag is set, it also reads the local file system to check the content files of torrents,
+and displays following fields:
+- OnDisk : Total size of content files of torrents that exist on disk (xseed torrents counted only once).
+- Missing : Cnt of torrents that have downloaded files missing or size mismatched on disk.
+The torrents with missing files are listed at the end. It requires ptool has access to the save path
+of torrents. For qBittorrent, the files of incomplete torrents are also looked up in the folder for
+incomplete torrents ("temp_path"), if it's enabled, and with the ".!qB" extension.
+Use "--map-save-path" flag if the client is running in a different file system (e.g. Docker).`,
+		constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: du,
+}
+
+var (
+	checkFiles   = false
+	showJson     = false
+	groupBy      = ""
+	category     = ""
+	tag          = ""
+	filter       = ""
+	mapSavePaths []string
+)
+
+func init() {
+	cmd.AddEnumFlagP(command, &groupBy, "by", "", &cmd.EnumFlag{
+		Description: "Group torrents by",
+		Options: [][2]string{
+			{"savepath", "save path"},
+			{"category", ""},
+			{"tag", ""},
+			{"tracker", "tracker domain"},
+		},
+	})
+	command.Flags().BoolVarP(&checkFiles, "check-files", "", false,
+		"Check content files of torrents on local disk, find missing files")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Used with "--check-files". Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func du(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if !checkFiles && len(mapSavePaths) > 0 {
+		return fmt.Errorf("--map-save-path must be used with --check-files flag")
+	}
+	var savePathMapper *common.PathMapper
+	if checkFiles {
+		var err error
+		if savePathMapper, err = common.GetSavePathMapper(clientName, mapSavePaths, false); err != nil {
+			return err
+		}
+	}
+	if len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		infoHashes = []string{"_all"}
+	} else if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+
+	// qBittorrent: folder for incomplete torrents (mapped), "" if not enabled
+	tempPath := ""
+	if checkFiles && clientInstance.GetClientConfig().Type == "qbittorrent" {
+		if tempPath, err = getQbTempPath(clientInstance, savePathMapper); err != nil {
+			return err
+		}
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	// infoHash => disk stat
+	diskStats := map[string]*diskStat{}
+	var missingTorrents []*client.Torrent
+	if checkFiles {
+		for _, torrent := range torrents {
+			stat, err := checkTorrentFiles(clientInstance, torrent, savePathMapper, tempPath)
+			if err != nil {
+				log.Errorf("Failed to check torrent %s (%s) files: %v", torrent.InfoHash, torrent.Name, err)
+				errorCnt++
+				lastErr = err
+				continue
+			}
+			diskStats[torrent.InfoHash] = stat
+			if len(stat.missingFiles) > 0 {
+				missingTorrents = append(missingTorrents, torrent)
+			}
+		}
+	}
+
+	usages := map[string]*Usage{}
+	total := newUsage("Total")
+	for _, torrent := range torrents {
+		var groups []string
+		switch groupBy {
+		case "category":
+			groups = []string{torrent.Category}
+		case "tag":
+			groups = torrent.Tags
+			if len(groups) == 0 {
+				groups = []string{""}
+			}
+		case "tracker":
+			groups = []string{torrent.TrackerDomain}
+		default:
+			groups = []string{torrent.SavePath}
+		}
+		for _, group := range groups {
+			if usages[group] == nil {
+				usages[group] = newUsage(group)
+			}
+			usages[group].add(torrent, diskStats)
+		}
+		total.add(torrent, diskStats)
+	}
+	list := []*Usage{}
+	for _, usage := range usages {
+		list = append(list, usage)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].Group < list[j].Group
+	})
+
+	if showJson {
+		if err := util.PrintJson(os.Stdout, list); err != nil {
+			return err
+		}
+	} else {
+		printUsages(append(list, total))
+		if len(missingTorrents) > 0 {
+			fmt.Printf("\nTorrents with missing files (%d):\n", len(missingTorrents))
+			client.PrintTorrents(os.Stdout, missingTorrents, "", 0, false)
+			for _, torrent := range missingTorrents {
+				fmt.Printf("\n%s (%s) missing files:\n", torrent.InfoHash, torrent.Name)
+				for _, file := range diskStats[torrent.InfoHash].missingFiles {
+					fmt.Printf("  %s\n", file)
+				}
+			}
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+}
+
+func newUsage(group string) *Usage {
+	usage := &Usage{Group: group, contentPaths: map[string]bool{}}
+	if !checkFiles {
+		usage.DiskSize = -1
+		usage.Missing = -1
+	}
+	return usage
+}
+
+func (usage *Usage) add(torrent *client.Torrent, diskStats map[string]*diskStat) {
+	usage.Torrents++
+	usage.Size += torrent.Size
+	usage.SizeTotal += torrent.SizeTotal
+	usage.SizeCompleted += torrent.SizeCompleted
+	if torrent.Size < torrent.SizeTotal {
+		usage.Partial++
+	}
+	if torrent.SizeCompleted < torrent.Size {
+		usage.Incomplete++
+	}
+	unique := !usage.contentPaths[torrent.ContentPath]
+	if unique {
+		usage.contentPaths[torrent.ContentPath] = true
+		usage.SizeUnique += torrent.Size
+	}
+	if stat := diskStats[torrent.InfoHash]; stat != nil {
+		if unique {
+			usage.DiskSize += stat.size
+		}
+		if len(stat.missingFiles) > 0 {
+			usage.Missing++
+		}
+	}
+}
+
+// Return the (mapped) qBittorrent folder for incomplete torrents, or "" if it's not enabled.
+func getQbTempPath(clientInstance client.Client, savePathMapper *common.PathMapper) (string, error) {
+	enabled, err := clientInstance.GetConfig("qb_temp_path_enabled")
+	if err != nil {
+		return "", fmt.Errorf("failed to get client temp path config: %w", err)
+	}
+	if enabled != "true" {
+		return "", nil
+	}
+	tempPath, err := clientInstance.GetConfig("qb_temp_path")
+	if err != nil {
+		return "", fmt.Errorf("failed to get client temp path config: %w", err)
+	}
+	if tempPath == "" {
+		return "", nil
+	}
+	if savePathMapper != nil {
+		_tempPath, match := savePathMapper.Before2After(tempPath)
+		if !match {
+			log.Warnf("Client temp path %q does not match with any map-save-path rule, ignore it", tempPath)
+			return "", nil
+		}
+		tempPath = _tempPath
+	}
+	return tempPath, nil
+}
+
+// Check the selected content files of torrent on local disk.
+// A file is missing if it's (partially) downloaded but does not exist, or it's complete but size mismatches.
+// tempPath is the (qBittorrent) folder for incomplete torrents, if not empty, the files of an incomplete torrent
+// which do not exist in save path are looked up there.
+func checkTorrentFiles(clientInstance client.Client, torrent *client.Torrent,
+	savePathMapper *common.PathMapper, tempPath string) (*diskStat, error) {
+	savePath := torrent.SavePath
+	if savePathMapper != nil {
+		_savePath, match := savePathMapper.Before2After(savePath)
+		if !match {
+			return nil, fmt.Errorf("save path %q does not match with any map-save-path rule", savePath)
+		}
+		savePath = _savePath
+	}
+	files, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return nil, err
+	}
+	stat := &diskStat{}
+	for _, file := range files {
+		if file.Ignored {
+			continue
+		}
+		filename := path.Join(savePath, file.Path)
+		fileStat, err := os.Stat(filename)
+		if err != nil {
+			// qBittorrent: incomplete file may have ".!qB" extension,
+			// and files of incomplete torrent may be in the folder for incomplete torrents
+			var candidates []string
+			if !file.Complete {
+				candidates = append(candidates, filename+constants.FILENAME_SUFFIX_QB_INCOMPLETE)
+			}
+			if tempPath != "" && !torrent.IsComplete() {
+				tempFilename := path.Join(tempPath, file.Path)
+				candidates = append(candidates, tempFilename)
+				if !file.Complete {
+					candidates = append(candidates, tempFilename+constants.FILENAME_SUFFIX_QB_INCOMPLETE)
+				}
+			}
+			for _, candidate := range candidates {
+				if fileStat, err = os.Stat(candidate); err == nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			if file.Progress > 0 {
+				stat.missingFiles = append(stat.missingFiles, filename)
+			}
+			continue
+		}
+		stat.size += fileStat.Size()
+		if file.Complete && fileStat.Size() != file.Size {
+			stat.missingFiles = append(stat.missingFiles, fmt.Sprintf("%s (size %d != %d)",
+				filename, fileStat.Size(), file.Size))
+		}
+	}
+	return stat, nil
+}
+
+func printUsages(list []*Usage) {
+	columns := []*util.TableColumn{
+		{Title: "Group", MinWidth: 20},
+		{Title: "Torrents", Width: 8},
+		{Title: "Size", Width: 10},
+		{Title: "Unique", Width: 10},
+		{Title: "Downloaded", Width: 10},
+		{Title: "Partial", Width: 7},
+		{Title: "Incomplete", Width: 10},
+	}
+	if checkFiles {
+		columns = append(columns, &util.TableColumn{Title: "OnDisk", Width: 10},
+			&util.TableColumn{Title: "Missing", Width: 7})
+	}
+	rows := [][]string{}
+	for _, usage := range list {
+		group := usage.Group
+		if group == "" {
+			group = "-"
+		}
+		row := []string{
+			group,
+			fmt.Sprint(usage.Torrents),
+			util.BytesSize(float64(usage.Size)),
+			util.BytesSize(float64(usage.SizeUnique)),
+			util.BytesSize(float64(usage.SizeCompleted)),
+			fmt.Sprint(usage.Partial),
+			fmt.Sprint(usage.Incomplete),
+		}
+		if checkFiles {
+			row = append(row, util.BytesSize(float64(usage.DiskSize)), fmt.Sprint(usage.Missing))
+		}
+		rows = append(rows, row)
+	}
+	util.PrintTable(os.Stdout, columns, rows, 0, false, false)
+}
@@ -21,8 +21,10 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/torrentutil"
@@ -39,12 +41,10 @@ var command = &cobra.Command{
 	RunE:        dynamicseeding,
 }
 
-var (
-	dryRun = false
-)
+var ()
 
 func init() {
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false,
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
 		"Dry run. Do NOT actually add or delete torrent to / from client")
 	cmd.RootCmd.AddCommand(command)
 }
@@ -84,11 +84,13 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 		return err
 	}
 	result.Print(os.Stdout)
-	if dryRun {
+	if flags.DryRun {
 		log.Warnf("Dry-run. Exit")
 		return nil
 	}
 	errorCnt := int64(0)
+	total := int64(len(result.AddTorrents))
+	var lastErr error
 	// add
 	addedSize := int64(0)
 	tags := result.AddTorrentsOption.Tags
@@ -100,9 +102,11 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 		if contents, _, _, err := siteInstance.DownloadTorrent(torrent); err != nil {
 			log.Errorf("Failed to download site torrent %s", torrent)
 			errorCnt++
+			lastErr = err
 		} else if tinfo, err := torrentutil.ParseTorrent(contents); err != nil {
 			log.Errorf("Failed to download site torrent %s: is not a valid torrent: %v", torrent, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			var _tags []string
 			_tags = append(_tags, tags...)
@@ -122,6 +126,7 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 			if err := clientInstance.AddTorrent(contents, result.AddTorrentsOption, meta); err != nil {
 				log.Errorf("Failed to add site torrent %s to client: %v", torrent, err)
 				errorCnt++
+				lastErr = err
 			} else {
 				addedSize += result.AddTorrents[0].Size
 			}
@@ -146,10 +151,12 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 		result.DeleteTorrents = result.DeleteTorrents[1:]
 	}
 	if len(deleteInfoHashes) > 0 {
+		total++
 		err := client.DeleteTorrentsAuto(clientInstance, deleteInfoHashes)
 		log.Infof("Delete torrents result: %v", err)
 		if err != nil {
 			errorCnt++
+			lastErr = err
 		} else if len(deleteIds) > 0 {
 			ignores = append(ignores, deleteIds...)
 			if len(ignores) > IGNORE_FILE_SIZE {
@@ -160,8 +167,5 @@ func dynamicseeding(cmd *cobra.Command, args []string) (err error) {
 			ignoreFile.WriteString(strings.Join(ignores, "\n"))
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, total, lastErr)
 }
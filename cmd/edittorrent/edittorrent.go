@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -140,6 +141,7 @@ func edittorrent(cmd *cobra.Command, args []string) error {
 		}
 	}
 	errorCnt := int64(0)
+	var lastErr error
 	cntTorrents := int64(0)
 
 	if !force {
@@ -180,10 +182,11 @@ func edittorrent(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, torrent := range torrents {
-		_, tinfo, _, _, _, _, _, err := helper.GetTorrentContent(torrent, "", true, false, nil, false, nil)
+		tinfo, _, err := helper.GetTorrentMeta(torrent, "", true, false, nil)
 		if err != nil {
 			log.Errorf("Failed to parse %s: %v", torrent, err)
 			errorCnt++
+			lastErr = err
 			continue
 		}
 		var commentMeta *torrentutil.TorrentCommentMeta
@@ -273,6 +276,7 @@ func edittorrent(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			fmt.Printf("✕ %s : failed to update torrent: %v\n", torrent, err)
 			errorCnt++
+			lastErr = err
 			continue
 		}
 		if !changed {
@@ -283,23 +287,26 @@ func edittorrent(cmd *cobra.Command, args []string) error {
 			if err = tinfo.EncodeComment(commentMeta); err != nil {
 				fmt.Printf("✕ %s : failed to encode comment meta: %v\n", torrent, err)
 				errorCnt++
+				lastErr = err
 				continue
 			}
 		}
+		if common.DryRun("update torrent file %s", torrent) {
+			continue
+		}
 		if doBackup && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_BACKUP) {
 			if err := util.CopyFile(torrent, util.TrimAnySuffix(torrent,
 				constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_BACKUP); err != nil {
 				fmt.Printf("✕ %s : abort updating file due to failed to create backup file: %v\n", torrent, err)
 				errorCnt++
+				lastErr = err
 				continue
 			}
 		}
-		if data, err := tinfo.ToBytes(); err != nil {
-			fmt.Printf("✕ %s : failed to generate new contents: %v\n", torrent, err)
-			errorCnt++
-		} else if err := os.WriteFile(torrent, data, constants.PERM); err != nil {
+		if err := tinfo.WriteFile(torrent); err != nil {
 			fmt.Printf("✕ %s : failed to write new contents: %v\n", torrent, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			fmt.Printf("✓ %s : successfully updated\n", torrent)
 			cntTorrents++
@@ -307,8 +314,5 @@ func edittorrent(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "// Updated torrents: %d\n", cntTorrents)
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
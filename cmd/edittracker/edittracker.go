@@ -9,7 +9,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -87,7 +89,7 @@ func edittracker(cmd *cobra.Command, args []string) error {
 		log.Infof("No matched torrents found")
 		return nil
 	}
-	if !force {
+	if !force && !flags.DryRun {
 		client.PrintTorrents(os.Stdout, torrents, "", 1, false)
 		fmt.Printf("\n")
 		if !helper.AskYesNoConfirm(fmt.Sprintf(
@@ -103,16 +105,19 @@ If a torrent does NOT has the old tracker, it will NOT be updated`,
 		}
 	}
 	errorCnt := int64(0)
+	var lastErr error
 	for _, torrent := range torrents {
+		if common.DryRun("edit torrent %s (%s) tracker %s => %s", torrent.InfoHash, torrent.Name,
+			oldTracker, newTracker) {
+			continue
+		}
 		fmt.Printf("Edit torrent %s (%s) tracker\n", torrent.InfoHash, torrent.Name)
 		err := clientInstance.EditTorrentTracker(torrent.InfoHash, oldTracker, newTracker, replaceHost)
 		if err != nil {
 			log.Errorf("Failed to edit tracker: %v\n", err)
 			errorCnt++
+			lastErr = err
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "events {client}... [--interval seconds] [--event events]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "events"},
+	Short:       "Tail torrent state changes of clients as JSON lines.",
+	Long: `Tail torrent state changes of clients as JSON lines.
+It runs forever and polls clients every --interval seconds. For qBittorrent, each poll only fetches
+the changes since last poll (sync/maindata delta), so a short interval is cheap.
+Each event is printed to stdout as a single line JSON: {"time", "event", "client", "infoHash", "name", "state", "message"},
+which can be piped into other tools, e.g. "ptool events local | jq -r .name".
+
+Events:
+* added : a torrent was added to client.
+* completed : a torrent finished downloading.
+* stalled : a downloading torrent has not received any data for 5 minutes.
+* errored : a torrent became error state, e.g. it's files are missing.
+* trackererror : a torrent lost it's working tracker, "message" is the tracker message (qBittorrent only).
+* removed : a torrent was deleted from client.
+
+Existing torrents and their states when it starts do NOT trigger events.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: events,
+}
+
+var (
+	interval = int64(0)
+	event    = ""
+)
+
+func init() {
+	command.Flags().Int64VarP(&interval, "interval", "", 2, "Interval (seconds) between two polls of a client")
+	command.Flags().StringVarP(&event, "event", "", "",
+		"Comma-separated list. Only output these events. Available: "+fmt.Sprint(client.Events))
+	cmd.RootCmd.AddCommand(command)
+}
+
+func events(cmd *cobra.Command, args []string) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %d", interval)
+	}
+	var eventFilter []string
+	if event != "" {
+		eventFilter = util.SplitCsv(event)
+		for _, e := range eventFilter {
+			if !slices.Contains(client.Events, e) {
+				return fmt.Errorf("invalid event %q", e)
+			}
+		}
+	}
+	var watchers []*client.EventWatcher
+	for _, clientName := range args {
+		if config.GetClientConfig(clientName) == nil {
+			return fmt.Errorf("client %s not found", clientName)
+		}
+		clientInstance, err := client.CreateClient(clientName)
+		if err != nil {
+			return fmt.Errorf("failed to create client %s: %w", clientName, err)
+		}
+		watchers = append(watchers, client.NewEventWatcher(clientInstance))
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		for i, watcher := range watchers {
+			events, err := watcher.Poll()
+			if err != nil {
+				log.Errorf("Failed to poll client %s: %v", args[i], err)
+				continue
+			}
+			for _, e := range events {
+				if eventFilter != nil && !slices.Contains(eventFilter, e.Event) {
+					continue
+				}
+				encoder.Encode(e)
+			}
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
@@ -9,9 +9,11 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
 	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
 )
@@ -48,6 +50,7 @@ var (
 	category       = ""
 	tag            = ""
 	filter         = ""
+	expr           = ""
 	downloadDir    = ""
 	rename         = ""
 )
@@ -62,6 +65,7 @@ func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&expr, "expr", "", "", constants.HELP_ARG_EXPR)
 	command.Flags().StringVarP(&downloadDir, "download-dir", "", ".", `Set the download dir of exported torrents`)
 	command.Flags().StringVarP(&rename, "rename", "", config.DEFAULT_EXPORT_TORRENT_RENAME,
 		"Set the name of downloaded torrents (supports variables)")
@@ -71,7 +75,14 @@ func init() {
 func export(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	var torrentExpr *filterexpr.Expr
+	if expr != "" {
+		var err error
+		if torrentExpr, err = client.ParseTorrentExpr(expr); err != nil {
+			return fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+	if category == "" && tag == "" && filter == "" && torrentExpr == nil {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -90,7 +101,13 @@ func export(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if torrentExpr != nil {
+		torrents = util.Filter(torrents, func(t *client.Torrent) bool {
+			return t.MatchExpr(torrentExpr)
+		})
+	}
 	errorCnt := int64(0)
+	var lastErr error
 	cntAll := len(torrents)
 	for i, torrent := range torrents {
 		filename := ""
@@ -102,10 +119,11 @@ func export(cmd *cobra.Command, args []string) error {
 				continue
 			}
 		}
-		content, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+		content, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
 		if err != nil {
 			fmt.Printf("✕ %s : failed to export %s: %v (%d/%d)\n", torrent.InfoHash, torrent.Name, err, i+1, cntAll)
 			errorCnt++
+			lastErr = err
 			continue
 		}
 		if useCommentMeta {
@@ -126,6 +144,7 @@ func export(cmd *cobra.Command, args []string) error {
 			if useCommentErr != nil {
 				fmt.Printf("✕ %s : %v (%d/%d)\n", torrent.InfoHash, useCommentErr, i+1, cntAll)
 				errorCnt++
+				lastErr = useCommentErr
 				continue
 			}
 		}
@@ -136,12 +155,10 @@ func export(cmd *cobra.Command, args []string) error {
 		if err := os.WriteFile(filepath, content, constants.PERM); err != nil {
 			fmt.Printf("✕ %s : failed to save to %s: %v (%d/%d)\n", torrent.InfoHash, filepath, err, i+1, cntAll)
 			errorCnt++
+			lastErr = err
 		} else {
 			fmt.Printf("✓ %s : saved to %s (%d/%d)\n", torrent.InfoHash, filepath, i+1, cntAll)
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
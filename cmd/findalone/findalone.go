@@ -3,8 +3,8 @@ package findalone
 import (
 	"fmt"
 	"os"
-	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/shibumi/go-pathspec"
 	log "github.com/sirupsen/logrus"
@@ -24,7 +24,7 @@ type File struct {
 }
 
 var command = &cobra.Command{
-	Use:         "findalone {client} {save-path}...",
+	Use:         "findalone {client} {save-path}... [--auto-paths]",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "findalone"},
 	Short:       "Find alone files (no matched torrent exists in client) in save path(s).",
 	Long: `Find alone files (no matched torrent exists in client) in save path(s).
@@ -39,14 +39,23 @@ which can be done using "--map-save-path" flag. The flag can be set multiple tim
 If --all flag is set, it will list all files in save pathes instead of only "alone" files,
 and display each file's count of belonged torrents in client.
 
+If --auto-paths flag is set, the save paths to read are derived from the client itself instead (and appended to
+the provided {save-path} args, which become optional): the default save path of client, the save paths of all
+categories (qBittorrent: a category with an empty save path uses "<default save path>/<category name>")
+and the save paths of all torrents. The derived paths are in the client's view and are mapped back using
+the "--map-save-path" rules (if set; derived paths that don't match any rule are ignored).
+Derived paths that do not exist in local file system are skipped.
+The top-level entries of a save path which are (or contain) other save paths are never reported as "alone".
+
 It prints found "alone" files or dirs to stdout.`,
-	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: findalone,
 }
 
 var (
 	showAll       = false
 	originalOrder = false
+	autoPaths     = false
 	mapSavePaths  []string
 )
 
@@ -55,9 +64,12 @@ func init() {
 		"Show the list of all files in save pathes with the count of each file's belonged torrents in client")
 	command.Flags().BoolVarP(&originalOrder, "original-order", "", false,
 		`Used with "--all". Display the list in original (filename asc) order instead of count desc order`)
+	command.Flags().BoolVarP(&autoPaths, "auto-paths", "", false,
+		"Derive the save paths to read from client: default save path, category save paths and torrent save paths")
 	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
 		`Map save path that ptool sees to the one that the BitTorrent client sees. `+
-			`Format: "original_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS)
+			`Format: "original_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -65,20 +77,18 @@ func findalone(cmd *cobra.Command, args []string) error {
 	if !showAll && originalOrder {
 		return fmt.Errorf("--original-order must be used with --all flag")
 	}
+	if !autoPaths && len(args) < 2 {
+		return fmt.Errorf("at least one save-path must be provided, unless --auto-paths flag is set")
+	}
 	clientName := args[0]
-	savePathes := util.Map(args[1:], func(p string) string {
-		return path.Clean(util.ToSlash(p))
-	})
+	savePathes := util.Map(args[1:], util.CleanPath)
 	clientInstance, err := client.CreateClient(clientName)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
-	var savePathMapper *common.PathMapper
-	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
-		if err != nil {
-			return fmt.Errorf("invalid map-save-path(s): %w", err)
-		}
+	savePathMapper, err := common.GetSavePathMapper(clientName, mapSavePaths, true)
+	if err != nil {
+		return err
 	}
 
 	contentRootFiles := map[string]int64{}
@@ -86,8 +96,34 @@ func findalone(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get client torrents: %w", err)
 	}
+	if autoPaths {
+		clientSavePaths, err := common.GetClientSavePaths(clientInstance, torrents)
+		if err != nil {
+			return fmt.Errorf("failed to get client save paths: %w", err)
+		}
+		for _, clientSavePath := range clientSavePaths {
+			savePath := clientSavePath
+			if savePathMapper != nil {
+				var match bool
+				if savePath, match = savePathMapper.After2Before(clientSavePath); !match {
+					log.Debugf("Client save path %q does not match with any map-save-path rule, ignore it", clientSavePath)
+					continue
+				}
+			}
+			savePath = util.CleanPath(savePath)
+			if slices.Contains(savePathes, savePath) {
+				continue
+			}
+			if _, err := os.Stat(util.LongPath(savePath)); err != nil {
+				log.Debugf("Skip client save path %q which can not be accessed: %v", savePath, err)
+				continue
+			}
+			savePathes = append(savePathes, savePath)
+		}
+		log.Infof("Read save paths: %v", savePathes)
+	}
 	for _, torrent := range torrents {
-		contentPath := util.ToSlash(torrent.ContentPath)
+		contentPath := util.CleanPath(torrent.ContentPath)
 		if savePathMapper != nil {
 			if _contentPath, match := savePathMapper.After2Before(contentPath); !match {
 				log.Debugf("Torrent %s (%s) save path %q does not match with any map-save-path rule, ignore it",
@@ -102,11 +138,13 @@ func findalone(cmd *cobra.Command, args []string) error {
 
 	var files []File
 	errorCnt := int64(0)
+	var lastErr error
 	for _, savePath := range savePathes {
-		entries, err := os.ReadDir(savePath)
+		entries, err := os.ReadDir(util.LongPath(savePath))
 		if err != nil {
 			log.Errorf("Failed to read save-path %s: %v", savePath, err)
 			errorCnt++
+			lastErr = err
 			continue
 		}
 		for _, entry := range entries {
@@ -114,14 +152,17 @@ func findalone(cmd *cobra.Command, args []string) error {
 				log.Debugf("Skip ignored file %q", entry.Name())
 				continue
 			}
-			fullpath := path.Join(savePath, entry.Name())
-			if slices.Contains(savePathes, fullpath) {
+			fullpath := util.JoinPath(savePath, entry.Name())
+			// the entry is (or contains) another save path, e.g. "/downloads/movies" of "/downloads/movies/2024"
+			if slices.ContainsFunc(savePathes, func(sp string) bool {
+				return sp == fullpath || strings.HasPrefix(sp, fullpath+"/")
+			}) {
 				continue
 			}
 			if showAll {
-				files = append(files, File{filepath.Clean(fullpath), contentRootFiles[fullpath]})
+				files = append(files, File{filepath.FromSlash(fullpath), contentRootFiles[fullpath]})
 			} else if contentRootFiles[fullpath] == 0 {
-				fmt.Printf("%s\n", filepath.Clean(fullpath)) // output in host sep
+				fmt.Printf("%s\n", filepath.FromSlash(fullpath)) // output in host sep
 			}
 		}
 	}
@@ -133,8 +174,5 @@ func findalone(cmd *cobra.Command, args []string) error {
 			fmt.Printf("%-3d  %s\n", file.Count, file.Path)
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(savePathes)), lastErr)
 }
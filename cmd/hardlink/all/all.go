@@ -3,5 +3,6 @@ package all
 import (
 	_ "github.com/sagan/ptool/cmd/hardlink"
 	_ "github.com/sagan/ptool/cmd/hardlink/cp"
+	_ "github.com/sagan/ptool/cmd/hardlink/relocate"
 	_ "github.com/sagan/ptool/cmd/hardlink/torrent"
 )
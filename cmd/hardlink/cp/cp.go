@@ -49,7 +49,7 @@ func hardlinkcp(cmd *cobra.Command, args []string) error {
 	}
 	source = filepath.Clean(source)
 	dest = filepath.Clean(dest)
-	sourceStat, err := os.Stat(source)
+	sourceStat, err := os.Stat(util.LongPath(source))
 	if err != nil {
 		return fmt.Errorf("failed to access source %s: %w", source, err)
 	}
@@ -60,7 +60,7 @@ func hardlinkcp(cmd *cobra.Command, args []string) error {
 	} else if !sourceStat.Mode().IsRegular() {
 		return fmt.Errorf("source %s is NOT a dir or regular file", source)
 	}
-	if _, err := os.Stat(dest); err == nil {
+	if _, err := os.Stat(util.LongPath(dest)); err == nil {
 		return fmt.Errorf("dest %s already exists", dest)
 	} else if !os.IsNotExist(err) {
 		return fmt.Errorf("dest %s can NOT be accessed: %w", dest, err)
@@ -71,10 +71,10 @@ func hardlinkcp(cmd *cobra.Command, args []string) error {
 
 	if !sourceIsDir {
 		if sizeLimit >= 0 && sourceStat.Size() < sizeLimit {
-			return util.CopyFile(source, dest)
+			return util.CopyFile(util.LongPath(source), util.LongPath(dest))
 		}
-		return os.Link(source, dest)
+		return os.Link(util.LongPath(source), util.LongPath(dest))
 	}
 
-	return util.LinkDir(source, dest, sizeLimit)
+	return util.LinkDir(util.LongPath(source), util.LongPath(dest), sizeLimit)
 }
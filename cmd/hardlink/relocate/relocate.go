@@ -0,0 +1,215 @@
+package relocate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/hardlink"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "relocate {client} {savePath} [--category category] [--tag tag] [--filter filter] [infoHash]... " +
+		"[--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "hardlinkrelocate"},
+	Short:       "Relocate content of client torrents to new save path using hardlinks.",
+	Long: fmt.Sprintf(`Relocate content of client torrents to new save path using hardlinks.
+%s.
+
+For each torrent, it recreates the torrent content tree (root folder or single file; or all files of a
+multi-file torrent without root folder) in the new save path, using hardlinks if possible. If a file can NOT be hardlinked (e.g. the new save path is in a different
+file system), it falls back to reflink (copy-on-write clone, only for supported file systems in Linux)
+or plain copy. Then it updates the torrent's save path in client to the new one.
+The original content files are kept untouched.
+
+The torrent content MUST be accessible by ptool in local file system. If ptool and the BitTorrent client
+use different file system (e.g. the client runs in Docker), use "--map-save-path" flag to set the mapper rule.
+The {savePath} arg is the new save path that ptool sees.
+Use "--progress json" flag to emit machine-readable progress records (JSON Lines) to stderr.
+
+Examples:
+  ptool hardlink relocate local /mnt/disk2/Downloads --category movies --dry-run`, constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: relocate,
+}
+
+var (
+	force        = false
+	category     = ""
+	tag          = ""
+	filter       = ""
+	sizeLimitStr = ""
+	progressMode = ""
+	mapSavePaths []string
+)
+
+func init() {
+	cmd.AddEnumFlagP(command, &progressMode, "progress", "", common.ProgressFlag)
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only display what would be done, do NOT actually create files and update torrents")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&sizeLimitStr, "hardlink-min-size", "", "1MiB",
+		"File with size smaller than (<) this value will be copied instead of hardlinked. -1 == always hardlink")
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path that ptool sees to the one that the BitTorrent client sees. `+
+			`Format: "original_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	hardlink.Command.AddCommand(command)
+}
+
+func relocate(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	savePath := filepath.Clean(args[1])
+	infoHashes := args[2:]
+	sizeLimit, err := util.RAMInBytes(sizeLimitStr)
+	if err != nil {
+		return fmt.Errorf("invalid hardlink-min-size: %w", err)
+	}
+	if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	savePathMapper, err := common.GetSavePathMapper(clientName, mapSavePaths, true)
+	if err != nil {
+		return err
+	}
+	clientSavePath := savePath
+	if savePathMapper != nil {
+		if _clientSavePath, match := savePathMapper.Before2After(savePath); !match {
+			return fmt.Errorf("save path %q does not match with any map-save-path rule", savePath)
+		} else {
+			clientSavePath = _clientSavePath
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+	if len(torrents) == 0 {
+		log.Infof("No matched torrents found")
+		return nil
+	}
+	if !force && !flags.DryRun {
+		client.PrintTorrents(os.Stdout, torrents, "", 1, false)
+		fmt.Printf("\n")
+		if !helper.AskYesNoConfirm(fmt.Sprintf("Will relocate above %d torrents to %q (client save path: %q)",
+			len(torrents), savePath, clientSavePath)) {
+			return fmt.Errorf("abort")
+		}
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	progress := common.NewProgress(progressMode, "relocate", int64(len(torrents)))
+	for i, torrent := range torrents {
+		if util.CleanPath(torrent.SavePath) == util.CleanPath(clientSavePath) {
+			log.Debugf("Torrent %s (%s) is already in the save path, skip it", torrent.InfoHash, torrent.Name)
+			continue
+		}
+		progress.Start(int64(i+1), torrent.InfoHash, torrent.Size)
+		err := relocateTorrent(clientInstance, torrent, savePath, clientSavePath, savePathMapper, sizeLimit)
+		progress.Finish(err)
+		if err != nil {
+			log.Errorf("Failed to relocate torrent %s (%s): %v", torrent.InfoHash, torrent.Name, err)
+			errorCnt++
+			lastErr = err
+		}
+	}
+	err = common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+	progress.End(err)
+	return err
+}
+
+// Relocate the content of torrent to savePath, then set the torrent's save path in client to clientSavePath.
+func relocateTorrent(clientInstance client.Client, torrent *client.Torrent, savePath string, clientSavePath string,
+	savePathMapper *common.PathMapper, sizeLimit int64) error {
+	contentPath := util.CleanPath(torrent.ContentPath)
+	oldSavePath := util.CleanPath(torrent.SavePath)
+	if savePathMapper != nil {
+		var match bool
+		if contentPath, match = savePathMapper.After2Before(contentPath); !match {
+			return fmt.Errorf("content path %q does not match with any map-save-path rule", torrent.ContentPath)
+		}
+		oldSavePath, _ = savePathMapper.After2Before(oldSavePath)
+	}
+	relativePath, err := filepath.Rel(filepath.FromSlash(oldSavePath), filepath.FromSlash(contentPath))
+	if err != nil || relativePath != "." && !filepath.IsLocal(relativePath) {
+		return fmt.Errorf("content path %q is not inside save path %q", torrent.ContentPath, torrent.SavePath)
+	}
+	// relative path of files to relocate
+	relativePaths := []string{relativePath}
+	if relativePath == "." {
+		// multi-file torrent without root folder, its files are directly in save path
+		contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+		if err != nil {
+			return fmt.Errorf("failed to get torrent contents: %w", err)
+		}
+		relativePaths = relativePaths[:0]
+		for _, file := range contents {
+			filePath := filepath.FromSlash(file.Path)
+			if !filepath.IsLocal(filePath) {
+				return fmt.Errorf("invalid torrent content file path %q", file.Path)
+			}
+			relativePaths = append(relativePaths, filePath)
+		}
+	}
+	source := filepath.FromSlash(oldSavePath)
+	fmt.Printf("Relocate torrent %s (%s): %s => %s\n", torrent.InfoHash, torrent.Name,
+		filepath.Join(source, relativePath), filepath.Join(savePath, relativePath))
+	if flags.DryRun {
+		return nil
+	}
+	for _, relativePath := range relativePaths {
+		err := relocateContent(filepath.Join(source, relativePath), filepath.Join(savePath, relativePath), sizeLimit)
+		if err != nil {
+			return fmt.Errorf("failed to relocate content: %w", err)
+		}
+	}
+	if err := clientInstance.SetTorrentsSavePath([]string{torrent.InfoHash}, clientSavePath); err != nil {
+		return fmt.Errorf("failed to set save path: %w", err)
+	}
+	return nil
+}
+
+// Recreate source (dir or file) at dest, using hardlinks if possible.
+func relocateContent(source string, dest string, sizeLimit int64) error {
+	source, dest = util.LongPath(source), util.LongPath(dest)
+	sourceStat, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to access source %s: %w", source, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), constants.PERM_DIR); err != nil {
+		return fmt.Errorf("failed to create dest parent dir: %w", err)
+	}
+	if sourceStat.IsDir() {
+		return util.LinkOrCopyDir(source, dest, sizeLimit)
+	}
+	if util.FileExists(dest) {
+		return fmt.Errorf("dest %s already exists", dest)
+	}
+	if sizeLimit >= 0 && sourceStat.Size() < sizeLimit {
+		return util.CopyFile(source, dest)
+	}
+	_, err = util.LinkOrCopyFile(source, dest)
+	return err
+}
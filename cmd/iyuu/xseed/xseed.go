@@ -1,6 +1,7 @@
 package xseed
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"sort"
@@ -17,8 +18,10 @@ import (
 	"github.com/sagan/ptool/cmd/iyuu"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
 	"github.com/sagan/ptool/util/torrentutil"
 )
 
@@ -27,19 +30,29 @@ var command = &cobra.Command{
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "iyuu.xseed"},
 	Short:       "Cross seed using iyuu API.",
 	Long: `Cross seed using iyuu API.
-By default it will add xseed torrents from All sites unless --include-sites or --exclude-sites flag is set.`,
+By default it will add xseed torrents from All sites unless --include-sites or --exclude-sites flag is set.
+
+If a xseed torrent already exists in client, by default it's skipped (only the xseed and site tags are added
+to it). Set "--if-exists fail" to treat it as an error, or "--if-exists merge-trackers" to also download
+the xseed torrent and add it's trackers to the existing one.
+
+If "--stage-dir" flag is set, xseed torrents are downloaded to the staging dir instead of being added to client,
+with the category, tags & save path (the same as the target torrent in client) encoded into their 'comment' field.
+Only one client can be xseeded in this mode. If "--stage-hook" flag is also set, the command is executed for
+each staged torrent to validate it; rejected torrents are renamed to *.fail. Add staged torrents to client later by:
+  ptool addlocal <client> <dir> --use-comment-meta --skip-check --rename-added`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	RunE: xseed,
 }
 
 var (
-	dryRun                 = false
 	addPaused              = false
 	check                  = false
 	slowMode               = false
 	maxXseedTorrents       = int64(0)
 	iyuuRequestMaxTorrents = int64(0)
 	maxConsecutiveFail     = int64(0)
+	announceInterval       = int64(0)
 	includeSites           = ""
 	excludeSites           = ""
 	category               = ""
@@ -50,11 +63,14 @@ var (
 	minTorrentSizeStr      = ""
 	maxTorrentSizeStr      = ""
 	iyuuRequestServer      = ""
+	stageDir               = ""
+	stageHook              = ""
+	ifExists               = ""
 )
 
 func init() {
 	command.Flags().BoolVarP(&slowMode, "slow", "", false, "Slow mode. wait after handling each xseed torrent")
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually add xseed torrents to client")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Do NOT actually add xseed torrents to client")
 	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add xseed torrents to client in paused state")
 	command.Flags().BoolVarP(&check, "check", "", false, "Let client do hash checking when adding xseed torrents")
 	command.Flags().Int64VarP(&maxXseedTorrents, "max-torrents", "", -1,
@@ -64,6 +80,7 @@ func init() {
 	command.Flags().Int64VarP(&maxConsecutiveFail, "max-consecutive-fail", "", 3,
 		"After consecutive fails to download torrent from a site of this times, will skip that site afterwards. "+
 			"Note a 404 error does NOT count as a fail. -1 = no limit (never skip)")
+	command.Flags().Int64VarP(&announceInterval, "announce-interval", "", 0, constants.HELP_ARG_ANNOUNCE_INTERVAL)
 	command.Flags().StringVarP(&includeSites, "include-sites", "", "",
 		"Only add xseed torrents from these sites or groups (comma-separated)")
 	command.Flags().StringVarP(&excludeSites, "exclude-sites", "", "",
@@ -78,6 +95,9 @@ func init() {
 		"Torrents with size smaller than (<) this value will NOT be xseeded. -1 == no limit")
 	command.Flags().StringVarP(&maxTorrentSizeStr, "max-torrent-size", "", "-1",
 		"Torrents with size larger than (>) this value will NOT be xseeded. -1 == no limit")
+	command.Flags().StringVarP(&stageDir, "stage-dir", "", "", constants.HELP_ARG_STAGE_DIR)
+	command.Flags().StringVarP(&stageHook, "stage-hook", "", "", constants.HELP_ARG_STAGE_HOOK)
+	cmd.AddEnumFlagP(command, &ifExists, "if-exists", "", common.IfExistsFlag)
 	cmd.AddEnumFlagP(command, &iyuuRequestServer, "request-server", "",
 		common.YesNoAutoFlag("Whether or not send request to iyuu server to update local xseed db"))
 	iyuu.Command.AddCommand(command)
@@ -92,6 +112,11 @@ func xseed(cmd *cobra.Command, args []string) error {
 	includeSitesMode := false
 	includeSitesFlag := map[string]bool{}
 	excludeSitesFlag := map[string]bool{}
+	if stageDir != "" && len(args) > 1 {
+		return fmt.Errorf("--stage-dir flag can only be used with one client")
+	} else if stageDir == "" && stageHook != "" {
+		return fmt.Errorf("--stage-hook flag must be used with --stage-dir")
+	}
 	if includeSites != "" && excludeSites != "" {
 		return fmt.Errorf("--include-sites and --exclude-sites flags can NOT be both set")
 	}
@@ -124,6 +149,7 @@ func xseed(cmd *cobra.Command, args []string) error {
 	cntTargetTorrents := int64(0)
 	cntXseedTorrents := int64(0)
 	cntSucccessXseedTorrents := int64(0)
+	errorCnt := int64(0)
 
 	for _, clientName := range clientNames {
 		clientInstance, err := client.CreateClient(clientName)
@@ -248,10 +274,12 @@ func xseed(cmd *cobra.Command, args []string) error {
 
 	siteInstancesMap := map[string]site.Site{}
 	siteConsecutiveFails := map[string]int64{}
+	announcePacer := common.NewAnnouncePacer(announceInterval)
 mainloop:
 	for i, clientName := range clientNames {
 		log.Printf("Start xseeding client (%d/%d) %s", i+1, len(clientName), clientName)
 		clientInstance := clientInstanceMap[clientName]
+		existingChecker := common.NewExistingTorrentChecker(clientInstance, ifExists, false)
 		cnt := len(clientInfoHashesMap[clientName])
 		for i, infoHash := range clientInfoHashesMap[clientName] {
 			if i > 0 && slowMode {
@@ -298,7 +326,12 @@ mainloop:
 				}
 				if clientExistingTorrent != nil {
 					log.Tracef("xseed candidate %s already existed in client", xseedTorrent.InfoHash)
-					if !dryRun {
+					if ifExists == common.IF_EXISTS_FAIL {
+						log.Errorf("Xseed torrent %s: %v", xseedTorrent.InfoHash, common.ErrTorrentExists)
+						errorCnt++
+						continue
+					}
+					if !flags.DryRun {
 						tags := []string{}
 						removeTags := []string{}
 						if !clientExistingTorrent.HasTag(config.XSEED_TAG) {
@@ -319,7 +352,9 @@ mainloop:
 							}, nil)
 						}
 					}
-					continue
+					if ifExists != common.IF_EXISTS_MERGE_TRACKERS {
+						continue
+					}
 				}
 				if (includeSitesMode && !includeSitesFlag[sitename]) || (!includeSitesMode && excludeSitesFlag[sitename]) {
 					log.Tracef("skip site %s torrent", sitename)
@@ -341,13 +376,16 @@ mainloop:
 					xseedTorrent.Sid,
 					xseedTorrent.Tid,
 				)
-				if dryRun {
+				if flags.DryRun {
 					continue
 				}
-				xseedTorrentContent, _, err := siteInstance.DownloadTorrentById(fmt.Sprint(xseedTorrent.Tid))
+				xseedTorrentContent, xseedTorrentInfo := torrentutil.LoadCachedTorrent(xseedTorrent.InfoHash)
+				if xseedTorrentContent == nil {
+					xseedTorrentContent, _, err = siteInstance.DownloadTorrentById(fmt.Sprint(xseedTorrent.Tid))
+				}
 				if err != nil {
 					log.Errorf("Failed to download torrent from site: %v", err)
-					if !strings.Contains(err.Error(), "status=404") {
+					if !errors.Is(err, constants.ErrNotFound) {
 						siteConsecutiveFails[sitename]++
 						if maxConsecutiveFail >= 0 && siteConsecutiveFails[sitename] == maxConsecutiveFail {
 							log.Errorf("Site %s has consecutively failed (to download torrent) too many times, skip it from now",
@@ -359,9 +397,20 @@ mainloop:
 					continue
 				}
 				siteConsecutiveFails[sitename] = 0
-				xseedTorrentInfo, err := torrentutil.ParseTorrent(xseedTorrentContent)
-				if err != nil {
-					log.Errorf("Failed to parse xseed torrent contents: %v", err)
+				if xseedTorrentInfo == nil {
+					xseedTorrentInfo, err = torrentutil.ParseTorrent(xseedTorrentContent)
+					if err != nil {
+						log.Errorf("Failed to parse xseed torrent contents: %v", err)
+						continue
+					}
+					torrentutil.CacheTorrent(xseedTorrentContent, xseedTorrentInfo)
+				}
+				if clientExistingTorrent != nil {
+					_, err = existingChecker.Check(xseedTorrentInfo)
+					log.Infof("Merge xseed torrent %s trackers result: error=%v", xseedTorrent.InfoHash, err)
+					if err != nil {
+						errorCnt++
+					}
 					continue
 				}
 				compareResult := xseedTorrentInfo.XseedCheckWithClientTorrent(targetTorrentContentFiles)
@@ -387,15 +436,29 @@ mainloop:
 					tags = append(tags, config.PUBLIC_TAG)
 					ratioLimit = config.Get().PublicTorrentRatioLimit
 				}
-				err = clientInstance.AddTorrent(xseedTorrentContent, &client.TorrentOption{
-					SavePath:     targetTorrent.SavePath,
-					Category:     xseedTorrentCategory,
-					Tags:         tags,
-					Pause:        addPaused,
-					SkipChecking: !check,
-					RatioLimit:   ratioLimit,
-				}, nil)
-				log.Infof("Add xseed torrent %s result: error=%v", xseedTorrent.InfoHash, err)
+				if stageDir != "" {
+					filename := fmt.Sprintf("%s.%d.torrent", sitename, xseedTorrent.Tid)
+					filename, err = helper.StageTorrent(stageDir, filename, xseedTorrentContent, xseedTorrentInfo,
+						&torrentutil.TorrentCommentMeta{
+							Category: xseedTorrentCategory,
+							Tags:     tags,
+							SavePath: targetTorrent.SavePath,
+						}, stageHook)
+					log.Infof("Stage xseed torrent %s to %s result: error=%v", xseedTorrent.InfoHash, filename, err)
+				} else {
+					if !addPaused {
+						announcePacer.Wait(xseedTorrentInfo.Trackers)
+					}
+					err = clientInstance.AddTorrent(xseedTorrentContent, &client.TorrentOption{
+						SavePath:     targetTorrent.SavePath,
+						Category:     xseedTorrentCategory,
+						Tags:         tags,
+						Pause:        addPaused,
+						SkipChecking: !check,
+						RatioLimit:   ratioLimit,
+					}, nil)
+					log.Infof("Add xseed torrent %s result: error=%v", xseedTorrent.InfoHash, err)
+				}
 				if err == nil {
 					cntSucccessXseedTorrents++
 				}
@@ -407,6 +470,9 @@ mainloop:
 	}
 	fmt.Printf("Done xseed %d clients. Target / Xseed / SuccessXseed torrents: %d / %d / %d\n",
 		len(clientNames), cntTargetTorrents, cntXseedTorrents, cntSucccessXseedTorrents)
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
 	return nil
 }
 
@@ -0,0 +1,127 @@
+package journal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "journal [id] [--rollback | --resume | --remove]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "journal"},
+	Short:       "List, roll back or resume the failed compound commands (movedata, restore).",
+	Long: `List, roll back or resume the failed compound commands (movedata, restore).
+Compound commands record each step into a journal before performing it. If the command fails partway through
+(or the process is killed), the journal is kept in "journals" dir of config dir, so that:
+- "--rollback" : roll back the applied steps in reverse order: re-add the deleted torrents with old save path,
+  revert the save path changes, delete the re-added torrents (without files) and the copied files,
+  resume the paused torrents, revert the changed client preferences, delete the created categories and tags.
+- "--resume" : re-run the original command. As these commands skip the finished items
+  (e.g. moved or restored torrents), it continues from the failed step.
+- "--remove" : discard the journal.
+
+For "movedata", each torrent is a separate unit: the steps of a successfully moved torrent are discarded,
+so only the failed torrents are rolled back. For "restore", the whole restore is rolled back.
+
+If no [id] arg is provided, it lists all kept journals.`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	RunE: journal,
+}
+
+var (
+	rollback = false
+	resume   = false
+	remove   = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&rollback, "rollback", "", false, "Roll back the applied steps of journal")
+	command.Flags().BoolVarP(&resume, "resume", "", false, "Resume the command of journal by re-running it")
+	command.Flags().BoolVarP(&remove, "remove", "", false, "Discard the journal")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func journal(cmd *cobra.Command, args []string) error {
+	if util.CountNonZeroVariables(rollback, resume, remove) > 1 {
+		return fmt.Errorf("--rollback, --resume and --remove flags are NOT compatible")
+	}
+	journals, err := common.LoadJournals()
+	if err != nil {
+		return fmt.Errorf("failed to load journals: %w", err)
+	}
+	if len(args) == 0 {
+		if rollback || resume || remove {
+			return fmt.Errorf("journal id must be provided")
+		}
+		fmt.Printf("%-28s  %-19s  %-7s  %-5s  %s\n", "Id", "Time", "Status", "Steps", "Command")
+		for _, journal := range journals {
+			fmt.Printf("%-28s  %-19s  %-7s  %-5d  ptool %s\n", journal.Id, util.FormatTime(journal.Time),
+				journal.Status, len(journal.Steps), strings.Join(journal.Args, " "))
+		}
+		return nil
+	}
+	i := slices.IndexFunc(journals, func(journal *common.Journal) bool { return journal.Id == args[0] })
+	if i == -1 {
+		return fmt.Errorf("journal %s not found", args[0])
+	}
+	journal := journals[i]
+	switch {
+	case rollback:
+		if common.DryRun("roll back %d steps of journal %s", len(journal.Steps), journal.Id) {
+			for _, step := range journal.Steps {
+				fmt.Printf("%s\n", step)
+			}
+			return nil
+		}
+		if errorCnt := journal.Rollback(); errorCnt > 0 {
+			return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+		}
+		fmt.Printf("Journal %s rolled back\n", journal.Id)
+	case resume:
+		if common.DryRun("resume journal %s: ptool %s", journal.Id, strings.Join(journal.Args, " ")) {
+			return nil
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Resume: ptool %s\n", strings.Join(journal.Args, " "))
+		command := exec.Command(executable, journal.Args...)
+		command.Stdin = os.Stdin
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		if err := command.Run(); err != nil {
+			return fmt.Errorf("resumed command failed: %w", err)
+		}
+		// the resumed command has its own journal
+		return journal.Remove()
+	case remove:
+		if common.DryRun("remove journal %s", journal.Id) {
+			return nil
+		}
+		return journal.Remove()
+	default:
+		fmt.Printf("Journal %s (%s) created at %s: ptool %s\n", journal.Id, journal.Status,
+			util.FormatTime(journal.Time), strings.Join(journal.Args, " "))
+		if journal.Error != "" {
+			fmt.Printf("Error: %s\n", journal.Error)
+		}
+		for _, step := range journal.Steps {
+			done := "✕"
+			if step.Done {
+				done = "✓"
+			}
+			fmt.Printf("%s %s\n", done, step)
+		}
+	}
+	return nil
+}
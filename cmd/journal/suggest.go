@@ -0,0 +1,31 @@
+package journal
+
+import (
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("journal", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex != 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		journals, _ := common.LoadJournals()
+		suggestions := []prompt.Suggest{}
+		for _, journal := range journals {
+			if strings.HasPrefix(journal.Id, info.MatchingPrefix) {
+				suggestions = append(suggestions, prompt.Suggest{Text: journal.Id, Description: journal.Status})
+			}
+		}
+		return suggestions
+	})
+}
@@ -0,0 +1,136 @@
+package login
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "login {site | group}... [--relogin] [--force]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "login"},
+	Short:       "Login sites with username and password to refresh cookies.",
+	Long: `Login sites with username and password (and TOTP code if 2FA is enabled) to refresh cookies.
+Currently only NexusPHP sites are supported. Sites that require captcha to login are not supported.
+
+The "username" and "password" of site must be set in config file. If the 2FA (两步验证) of site account
+is enabled, also set the "totpSecret" of site, which is the Base32 TOTP secret key
+(or the "otpauth://" uri of the 2FA QR code) displayed when enabling the 2FA.
+These sensitive values could be encrypted using "ptool config encrypt" command.
+
+For each site, it first checks the current cookie. If it's still valid, the site is skipped,
+unless --relogin flag is set. Otherwise it logins the site and verifies the new cookie.
+The new cookies are written to config file, it asks for confirmation unless --force flag is set.
+Be aware that all existing comments in config file will be LOST when updating config file.
+
+Examples:
+  ptool login mysite
+  ptool login mysite1 mysite2 --force`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: login,
+}
+
+var (
+	force   = false
+	relogin = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&relogin, "relogin", "", false, "Login site even if the current cookie is still valid")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func login(cmd *cobra.Command, args []string) error {
+	sitenames := config.ParseGroupAndOtherNames(args...)
+	errorCnt := int64(0)
+	var lastErr error
+	updatesites := []*config.SiteConfigStruct{}
+	nowStr := util.FormatTime(util.Now())
+	for _, sitename := range sitenames {
+		siteconfig := config.GetSiteConfig(sitename)
+		if siteconfig == nil {
+			log.Errorf("✕ site %s: site not found in config", sitename)
+			errorCnt++
+			lastErr = constants.ErrNotFound
+			continue
+		}
+		if siteconfig.Dead || siteconfig.NoCookie {
+			log.Infof("- site %s: site is dead or does not use cookie", sitename)
+			continue
+		}
+		if siteconfig.Username == "" || siteconfig.Password == "" {
+			log.Infof("- site %s: username or password is not configured", sitename)
+			continue
+		}
+		siteInstance, err := site.CreateSiteInternal(sitename, siteconfig, config.Get())
+		if err != nil {
+			log.Errorf("✕ site %s: failed to create site instance: %v", sitename, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		if !relogin && siteconfig.Cookie != "" {
+			if sitestatus, err := siteInstance.GetStatus(); err == nil && sitestatus.IsOk() {
+				log.Infof("✓ site %s: current cookie is valid (username: %s)", sitename, sitestatus.UserName)
+				continue
+			}
+		}
+		newcookie, err := siteInstance.Login()
+		if err != nil {
+			log.Errorf("✕ site %s: %v", sitename, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		newsiteconfig := &config.SiteConfigStruct{}
+		util.Assign(newsiteconfig, siteconfig, nil)
+		newsiteconfig.Cookie = newcookie
+		newSiteInstance, err := site.CreateSiteInternal(sitename, newsiteconfig, config.Get())
+		if err != nil {
+			log.Errorf("✕ site %s: new cookie is invalid (create instance error: %v)", sitename, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		sitestatus, err := newSiteInstance.GetStatus()
+		if err != nil || !sitestatus.IsOk() {
+			log.Errorf("✕ site %s: new cookie is invalid (status error: %v)", sitename, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		log.Infof("✓✓ site %s: logined, new cookie is OK (username: %s)", sitename, sitestatus.UserName)
+		newsiteconfig.AutoComment = fmt.Sprintf(`cookie updated by "ptool login" at %s`, nowStr)
+		updatesites = append(updatesites, newsiteconfig)
+	}
+
+	if len(updatesites) > 0 {
+		configFile := fmt.Sprintf("%s/%s", config.ConfigDir, config.ConfigFile)
+		if flags.DryRun {
+			fmt.Printf("Dry run. Will update cookies of %d sites in config file (%s)\n", len(updatesites), configFile)
+		} else {
+			if !force && !helper.AskYesNoConfirm(fmt.Sprintf(
+				"Will update the config file (%s). Be aware that all existing comments will be LOST", configFile)) {
+				return fmt.Errorf("abort")
+			}
+			config.UpdateSites(updatesites)
+			if err := audit.SetConfig(); err != nil {
+				return fmt.Errorf("failed to update config file %s : %w", configFile, err)
+			}
+			fmt.Printf("Successfully update cookies of %d sites in config file %s\n", len(updatesites), configFile)
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(sitenames)), lastErr)
+}
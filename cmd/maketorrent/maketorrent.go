@@ -23,7 +23,9 @@ var command = &cobra.Command{
 By default, it saves created torrent to "{content-name}.torrent" file,
 where "{content-name}" is is folder or file name of "{content-path}".
 To manually set the output .torrent filename, use "--output" flag; set it to "-" to directly output to stdout.
-It creates BitTorrent v1 format torrent. V2 or hybrid format is NOT supported at this time.
+By default it creates BitTorrent v1 format torrent. To create a BitTorrent v2 (BEP 52) torrent, use "--format v2";
+To create a hybrid torrent which contains both v1 and v2 info (compatible with v1 only clients), use "--format hybrid".
+The piece length of v2 or hybrid torrent must be a power of 2 and >= 16KiB.
 
 Examples:
   ptool maketorrent ./MyVideos # output: ./MyVideos.torrent
@@ -57,6 +59,7 @@ var (
 	output                            = ""
 	createdBy                         = ""
 	creationDate                      = ""
+	format                            = ""
 	trackers                          []string
 	urlList                           []string
 	excludes                          []string
@@ -83,6 +86,8 @@ func init() {
 	command.Flags().StringVarP(&creationDate, "creation-date", "", "",
 		`Set the "creation date" field of torrent. E.g. "2024-01-20 15:00:00" (local timezone), `+
 			`or a unix timestamp integer (seconds). Default to now; To unset this field, set it to "`+constants.NONE+`"`)
+	command.Flags().StringVarP(&format, "format", "", torrentutil.TORRENT_FORMAT_V1,
+		"Set the format (meta version) of created torrent: "+strings.Join(torrentutil.TorrentFormats, " | "))
 	command.Flags().StringArrayVarP(&trackers, "tracker", "", nil,
 		`Set the trackers ("Announce" & "AnnounceList" field) of created torrent`)
 	command.Flags().StringArrayVarP(&excludes, "exclude", "", nil,
@@ -115,6 +120,7 @@ func maketorrent(cmd *cobra.Command, args []string) (err error) {
 		CreatedBy:                     createdBy,
 		CreationDate:                  creationDate,
 		AllowRestrictedCharInFilename: allowFilenameRestrictedCharacters,
+		Format:                        format,
 	}
 	if len(optoins.Trackers) == 0 && !optoins.Public {
 		log.Warnf(`Warning: the created .torrent file will NOT have any trackers. ` +
@@ -0,0 +1,484 @@
+package movedata
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/audit"
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	MODE_AUTO   = "auto"
+	MODE_CLIENT = "client"
+	MODE_COPY   = "copy"
+)
+
+var command = &cobra.Command{
+	Use: "movedata {client} --to {savePath} [--category category] [--tag tag] [--filter filter] " +
+		"[infoHash]...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "movedata"},
+	Short:       "Move content files of torrents in client to a new save path.",
+	Long: fmt.Sprintf(`Move content files of torrents in client to a new save path.
+%s.
+
+The "--mode" flag controls how the files are moved:
+- client : Use the "set location" (with files moving) feature of client. It waits until client finishes
+  moving files of each torrent, or fails if it does not finish in "--client-move-timeout" (default "6h").
+- copy : ptool moves the files itself: pause the torrent, copy the content files to new save path,
+  verify the copied files (sha1 hash), re-add the torrent to client with the new save path
+  (the category, tags and paused state are preserved, but the statistics in client of torrent,
+  like uploaded size, are reset), then delete the old files. The old files are preserved if they are
+  still used by other (xseed) torrents in client. It requires ptool has access to the save path of torrents,
+  use "--map-save-path" if the client is running in a different file system (e.g. Docker).
+- auto (default) : Use "copy" mode if the old and new save path are in different file systems
+  (crossing disks) and both are accessible by ptool; otherwise use "client" mode.
+  Checking the file system is only supported on Linux.
+
+The torrents which save path is already the new save path are skipped.
+Use "--progress json" flag to emit machine-readable progress records (JSON Lines) to stderr.
+If it fails partway through, use "ptool journal" to roll back the applied steps or resume it.`, constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: movedata,
+}
+
+var (
+	force        = false
+	toSavePath   = ""
+	mode         = ""
+	progressMode = ""
+	category     = ""
+	tag          = ""
+	filter       = ""
+	moveTimeout  = ""
+	mapSavePaths []string
+	progress     *common.Progress
+)
+
+func init() {
+	cmd.AddEnumFlagP(command, &mode, "mode", "", &cmd.EnumFlag{
+		Description: "Moving mode",
+		Options: [][2]string{
+			{MODE_AUTO, `"copy" if crossing file systems, otherwise "client"`},
+			{MODE_CLIENT, "use client set location"},
+			{MODE_COPY, "ptool copy + verify + re-add + delete"},
+		},
+	})
+	cmd.AddEnumFlagP(command, &progressMode, "progress", "", common.ProgressFlag)
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().StringVarP(&toSavePath, "to", "", "", "(Required) The new save path of torrents")
+	command.Flags().StringVarP(&moveTimeout, "client-move-timeout", "", "6h",
+		`The max time to wait for client to finish moving files of each torrent in "client" mode. E.g. "30m"`)
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	command.MarkFlagRequired("to")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func movedata(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	toSavePath = strings.TrimSuffix(util.ToSlash(strings.TrimSpace(toSavePath)), "/")
+	if toSavePath == "" {
+		return fmt.Errorf("--to savePath is empty")
+	}
+	clientMoveTimeout, err := util.ParseTimeDuration(moveTimeout)
+	if err != nil || clientMoveTimeout <= 0 {
+		return fmt.Errorf("invalid --client-move-timeout %q", moveTimeout)
+	}
+	savePathMapper, err := common.GetSavePathMapper(clientName, mapSavePaths, false)
+	if err != nil {
+		return err
+	}
+	if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+	torrents = util.Filter(torrents, func(t *client.Torrent) bool {
+		return strings.TrimSuffix(util.ToSlash(t.SavePath), "/") != toSavePath
+	})
+	if len(torrents) == 0 {
+		log.Infof("No torrents need to be moved")
+		return nil
+	}
+	if !force {
+		client.PrintTorrents(os.Stdout, torrents, "", 1, false)
+		fmt.Printf("\n")
+		if !helper.AskYesNoConfirm(fmt.Sprintf("Will move data of above %d torrents to %q (mode: %s)",
+			len(torrents), toSavePath, mode)) {
+			return fmt.Errorf("abort")
+		}
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	journal := common.NewJournal("movedata")
+	progress = common.NewProgress(progressMode, "movedata", int64(len(torrents)))
+	for i, torrent := range torrents {
+		fmt.Printf("(%d/%d) %s (%s): %s => %s\n", i+1, len(torrents), torrent.Name, torrent.InfoHash,
+			torrent.SavePath, toSavePath)
+		progress.Start(int64(i+1), torrent.InfoHash, torrent.Size)
+		torrentMode := mode
+		if torrentMode == MODE_AUTO {
+			torrentMode = MODE_CLIENT
+			if crossing, err := crossingFileSystems(torrent.SavePath, savePathMapper); err != nil {
+				log.Debugf("Failed to check file systems: %v", err)
+			} else if crossing {
+				torrentMode = MODE_COPY
+			}
+		}
+		if common.DryRun("move data of torrent %s (%s) from %q to %q (mode: %s)",
+			torrent.InfoHash, torrent.Name, torrent.SavePath, toSavePath, torrentMode) {
+			progress.Finish(nil)
+			continue
+		}
+		if torrentMode == MODE_COPY {
+			err = copyMove(clientInstance, torrent, savePathMapper, journal)
+		} else {
+			err = clientMove(clientInstance, torrent, journal, clientMoveTimeout)
+		}
+		progress.Finish(err)
+		if err != nil {
+			fmt.Printf("✕ failed to move: %v\n", err)
+			errorCnt++
+			lastErr = err
+		} else {
+			fmt.Printf("✓ moved (%s)\n", torrentMode)
+		}
+	}
+	err = common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+	journal.Finish(err)
+	progress.End(err)
+	return err
+}
+
+// Map a client path to the path in file system of ptool.
+func mapPath(clientPath string, savePathMapper *common.PathMapper) (string, error) {
+	if savePathMapper == nil {
+		return clientPath, nil
+	}
+	localPath, match := savePathMapper.Before2After(clientPath)
+	if !match {
+		return "", fmt.Errorf("path %q does not match with any map-save-path rule", clientPath)
+	}
+	return localPath, nil
+}
+
+// Check whether the file system of (local) old save path and new save path are different.
+// The new save path may not exist yet, in which case it's nearest existing parent dir is checked.
+func crossingFileSystems(savePath string, savePathMapper *common.PathMapper) (bool, error) {
+	oldPath, err := mapPath(savePath, savePathMapper)
+	if err != nil {
+		return false, err
+	}
+	newPath, err := mapPath(toSavePath, savePathMapper)
+	if err != nil {
+		return false, err
+	}
+	for {
+		if _, err := os.Stat(util.LongPath(newPath)); err == nil {
+			break
+		}
+		parent := filepath.Dir(newPath)
+		if parent == newPath {
+			return false, fmt.Errorf("new save path is not accessible")
+		}
+		newPath = parent
+	}
+	same, err := util.SameFileSystem(oldPath, newPath)
+	return !same, err
+}
+
+// Move torrent with the client "set location" feature, wait until it's finished or timeout (seconds).
+func clientMove(clientInstance client.Client, torrent *client.Torrent, journal *common.Journal,
+	timeout int64) error {
+	step := journal.Begin(&common.JournalStep{
+		Action:   common.JOURNAL_SET_SAVE_PATH,
+		Unit:     torrent.InfoHash,
+		Client:   clientInstance.GetName(),
+		InfoHash: torrent.InfoHash,
+		SavePath: strings.TrimSuffix(util.ToSlash(torrent.SavePath), "/"),
+	}, nil)
+	if err := clientInstance.SetTorrentsSavePath([]string{torrent.InfoHash}, toSavePath); err != nil {
+		journal.End(torrent.InfoHash)
+		return err
+	}
+	journal.Done(step)
+	progress.Stage("move")
+	startTime := time.Now()
+	for {
+		time.Sleep(time.Second * 2)
+		clientInstance.PurgeCache()
+		t, err := clientInstance.GetTorrent(torrent.InfoHash)
+		if err != nil {
+			return fmt.Errorf("failed to get torrent: %w", err)
+		}
+		if t == nil {
+			return fmt.Errorf("torrent not found in client")
+		}
+		if t.State == "error" {
+			return fmt.Errorf("torrent is in error state (%s)", t.LowLevelState)
+		}
+		if t.LowLevelState != "moving" && strings.TrimSuffix(util.ToSlash(t.SavePath), "/") == toSavePath {
+			break
+		}
+		if elapsed := int64(time.Since(startTime).Seconds()); elapsed >= timeout {
+			fmt.Printf("\n")
+			return fmt.Errorf("client does not finish moving files in %s (state: %s, save path: %s)",
+				util.FormatDuration(elapsed), t.LowLevelState, t.SavePath)
+		}
+		fmt.Printf("\rMoving by client ... %ds", int64(time.Since(startTime).Seconds()))
+		progress.Update(0)
+	}
+	fmt.Printf("\n")
+	journal.End(torrent.InfoHash)
+	return nil
+}
+
+// Copy files of torrent to new save path, verify them, re-add torrent to client with new save path,
+// and then delete old files.
+func copyMove(clientInstance client.Client, torrent *client.Torrent, savePathMapper *common.PathMapper,
+	journal *common.Journal) error {
+	oldPath, err := mapPath(torrent.SavePath, savePathMapper)
+	if err != nil {
+		return err
+	}
+	newPath, err := mapPath(toSavePath, savePathMapper)
+	if err != nil {
+		return err
+	}
+	contents, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	torrentContent, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
+	if err != nil {
+		return fmt.Errorf("failed to export torrent: %w", err)
+	}
+	paused := torrent.State == "paused"
+	if !paused {
+		step := journal.Begin(&common.JournalStep{
+			Action:   common.JOURNAL_PAUSE,
+			Unit:     torrent.InfoHash,
+			Client:   clientInstance.GetName(),
+			InfoHash: torrent.InfoHash,
+		}, nil)
+		if err := clientInstance.PauseTorrents([]string{torrent.InfoHash}); err != nil {
+			journal.End(torrent.InfoHash)
+			return fmt.Errorf("failed to pause torrent: %w", err)
+		}
+		journal.Done(step)
+	}
+	copyStep := journal.Begin(&common.JournalStep{
+		Action: common.JOURNAL_COPY_FILES,
+		Unit:   torrent.InfoHash,
+		Dir:    newPath,
+	}, nil)
+	var copiedFiles []string
+	copiedSize := int64(0)
+	progress.Stage("copy")
+	for _, file := range contents {
+		if file.Ignored {
+			continue
+		}
+		oldFilename := util.JoinPath(oldPath, file.Path)
+		if _, err := os.Stat(util.LongPath(oldFilename)); err != nil && os.IsNotExist(err) && file.Progress == 0 {
+			continue
+		}
+		if err = copyFile(oldFilename, util.JoinPath(newPath, file.Path), copiedSize); err != nil {
+			break
+		}
+		copiedSize += file.Size
+		copiedFiles = append(copiedFiles, file.Path)
+		journal.AddFiles(copyStep, file.Path)
+	}
+	if err != nil {
+		for _, file := range copiedFiles {
+			os.Remove(util.LongPath(util.JoinPath(newPath, file)))
+		}
+		if !paused {
+			clientInstance.ResumeTorrents([]string{torrent.InfoHash})
+		}
+		journal.End(torrent.InfoHash)
+		return fmt.Errorf("failed to copy files: %w", err)
+	}
+	journal.Done(copyStep)
+	progress.Stage("readd")
+
+	// switch the torrent save path in client by re-adding it.
+	step := journal.Begin(&common.JournalStep{
+		Action:   common.JOURNAL_DELETE_TORRENT,
+		Unit:     torrent.InfoHash,
+		Client:   clientInstance.GetName(),
+		InfoHash: torrent.InfoHash,
+		Name:     torrent.Name,
+		SavePath: torrent.SavePath,
+		Category: torrent.Category,
+		Tags:     torrent.Tags,
+		Paused:   paused,
+	}, torrentContent)
+	if err := clientInstance.DeleteTorrents([]string{torrent.InfoHash}, false); err != nil {
+		return fmt.Errorf("failed to delete old torrent from client (copied files are kept in %q): %w",
+			newPath, err)
+	}
+	journal.Done(step)
+	option := &client.TorrentOption{
+		Name:         torrent.Name,
+		Category:     torrent.Category,
+		SavePath:     toSavePath,
+		Tags:         torrent.Tags,
+		SkipChecking: torrent.SizeCompleted == torrent.Size,
+		Pause:        paused,
+	}
+	journal.Begin(&common.JournalStep{
+		Action:   common.JOURNAL_ADD_TORRENT,
+		Unit:     torrent.InfoHash,
+		Client:   clientInstance.GetName(),
+		InfoHash: torrent.InfoHash,
+	}, nil)
+	if err := clientInstance.AddTorrent(torrentContent, option, nil); err != nil {
+		return fmt.Errorf("failed to re-add torrent to client (old files are kept in %q): %w", oldPath, err)
+	}
+	// the torrent is switched to new save path, deleting old files below is not reverted.
+	journal.End(torrent.InfoHash)
+
+	clientInstance.PurgeCache()
+	if torrent.ContentPath != "" {
+		if sameContentPathTorrents, err := clientInstance.GetTorrentsByContentPath(torrent.ContentPath); err != nil {
+			return fmt.Errorf("failed to check xseed torrents, old files are kept: %w", err)
+		} else if len(sameContentPathTorrents) > 0 {
+			log.Warnf("Old files are kept as they are used by %d other torrents", len(sameContentPathTorrents))
+			return nil
+		}
+	}
+	dirs := map[string]bool{}
+	for _, file := range copiedFiles {
+		filename := util.JoinPath(oldPath, file)
+		if err := audit.RemoveFile(filename); err != nil {
+			log.Warnf("Failed to delete old file %q: %v", filename, err)
+		}
+		for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	// remove empty dirs, deepest first.
+	for len(dirs) > 0 {
+		deepest := ""
+		for dir := range dirs {
+			if strings.Count(dir, "/") >= strings.Count(deepest, "/") {
+				deepest = dir
+			}
+		}
+		delete(dirs, deepest)
+		os.Remove(util.LongPath(util.JoinPath(oldPath, deepest))) // only succeeds if dir is empty
+	}
+	return nil
+}
+
+// Copy file from source to dest with progress output, then verify the hash of dest file.
+// It fails if dest file already exists. offset: the copied bytes of torrent before this file, for progress.
+func copyFile(source string, dest string, offset int64) error {
+	name := path.Base(source)
+	source, dest = util.LongPath(source), util.LongPath(dest)
+	r, err := util.OpenCopySource(source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	stat, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dest), constants.PERM_DIR); err != nil {
+		return err
+	}
+	w, err := util.CreateCopyDest(dest, stat.Size(), os.O_EXCL, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	hash := sha1.New()
+	_, err = util.CopyData(io.MultiWriter(w, hash, &progressWriter{name: name, total: stat.Size(),
+		offset: offset}), r)
+	fmt.Printf("\n")
+	if c := w.Close(); err == nil {
+		err = c
+	}
+	if err == nil {
+		err = verifyFile(dest, hash.Sum(nil))
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
+
+func verifyFile(filename string, sha1sum []byte) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha1.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if !bytes.Equal(hash.Sum(nil), sha1sum) {
+		return fmt.Errorf("copied file %q hash mismatch", filename)
+	}
+	return nil
+}
+
+type progressWriter struct {
+	name      string
+	total     int64
+	offset    int64
+	written   int64
+	printTime time.Time
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.written += int64(len(p))
+	progress.Update(pw.offset + pw.written)
+	if time.Since(pw.printTime) >= time.Second || pw.written == pw.total {
+		pw.printTime = time.Now()
+		percent := float64(100)
+		if pw.total > 0 {
+			percent = float64(pw.written) * 100 / float64(pw.total)
+		}
+		fmt.Printf("\rCopying %s: %s / %s (%.1f%%)", pw.name, util.BytesSize(float64(pw.written)),
+			util.BytesSize(float64(pw.total)), percent)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,222 @@
+package nettest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+// The public port checking service used by Transmission. "<url><port>" returns "1" (open) or "0" (closed).
+// It checks the port of the public IP of the requester (ptool), not the client.
+const DEFAULT_PORT_CHECK_SERVICE = "https://portcheck.transmissionbt.com/"
+
+var command = &cobra.Command{
+	Use:         "nettest {client} [--probe host] [--interface name] [--address ip]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "nettest"},
+	Short:       "Check the listening port reachability and network binding of client.",
+	Long: fmt.Sprintf(`Check the listening port reachability and network binding of client.
+
+It checks whether the listening port (for incoming peer connections) of client is reachable from outside,
+using one of the following methods (the first one available):
+1. If "--probe host" flag is set, directly connect to "host:port" using TCP.
+2. If client is transmission, use it's builtin port test.
+3. Use the "--service" port checking service (default: %s). Note the service checks the port
+   of the public IP of ptool, so it's only accurate if ptool and client are in the same network.
+
+It also checks the network interface and IP address that client binds to (qBittorrent only).
+If "--interface" or "--address" flag is set, it's compared with the actual one and a warning is displayed
+on mismatch, e.g. to verify that client is bound to a VPN interface:
+  ptool nettest local --interface wg0
+
+If client runs on the same host as ptool, it also verifies the bound interface exists and
+has the bound address.
+
+It exits with error if any problem (unreachable port or mismatch) is found.`, DEFAULT_PORT_CHECK_SERVICE),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: nettest,
+}
+
+var (
+	timeout          = int64(0)
+	probe            = ""
+	expectInterface  = ""
+	expectAddress    = ""
+	portCheckService = ""
+)
+
+func init() {
+	command.Flags().Int64VarP(&timeout, "timeout", "", 10, "Timeout (seconds) of port probing")
+	command.Flags().StringVarP(&probe, "probe", "", "",
+		`Directly probe the listening port of this host (public IP or domain of client) using TCP`)
+	command.Flags().StringVarP(&expectInterface, "interface", "", "",
+		"The expected network interface that client binds to (e.g. VPN interface)")
+	command.Flags().StringVarP(&expectAddress, "address", "", "",
+		"The expected IP address that client binds to (e.g. VPN address)")
+	command.Flags().StringVarP(&portCheckService, "service", "", DEFAULT_PORT_CHECK_SERVICE,
+		`The port checking service url. The port is appended to it, it should return "1" if port is open`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func nettest(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	problemCnt := int64(0)
+	warn := func(format string, a ...any) {
+		fmt.Printf("✕ "+format+"\n", a...)
+		problemCnt++
+	}
+
+	portStr, err := clientInstance.GetConfig("listen_port")
+	if err != nil {
+		return fmt.Errorf("failed to get client listen port: %w", err)
+	}
+	port := util.ParseInt(portStr)
+	if port <= 0 {
+		return fmt.Errorf("failed to get client listen port: invalid port %q", portStr)
+	}
+	fmt.Printf("Client %s listen port: %d\n", clientInstance.GetName(), port)
+	open, method, err := testPort(clientInstance, port)
+	if err != nil {
+		warn("Failed to check listen port (%s): %v", method, err)
+	} else if !open {
+		warn("Listen port %d is NOT reachable from outside (%s)", port, method)
+	} else {
+		fmt.Printf("✓ Listen port %d is reachable from outside (%s)\n", port, method)
+	}
+
+	networkInterface, err := clientInstance.GetConfig("network_interface")
+	if err != nil {
+		return fmt.Errorf("failed to get client network interface: %w", err)
+	}
+	bindAddress, err := clientInstance.GetConfig("bind_address")
+	if err != nil {
+		return fmt.Errorf("failed to get client bind address: %w", err)
+	}
+	fmt.Printf("Client network interface: %s, bind address: %s\n",
+		displayBinding(networkInterface), displayBinding(bindAddress))
+	if expectInterface != "" && networkInterface != expectInterface {
+		warn("Client network interface mismatch: expect %s, actual %s",
+			expectInterface, displayBinding(networkInterface))
+	}
+	if expectAddress != "" && bindAddress != expectAddress {
+		warn("Client bind address mismatch: expect %s, actual %s", expectAddress, displayBinding(bindAddress))
+	}
+	if networkInterface != "" && isLocalClient(clientInstance) {
+		if addrs, err := getInterfaceAddrs(networkInterface); err != nil {
+			warn("Client network interface %s is unavailable on this host: %v", networkInterface, err)
+		} else if bindAddress != "" && !isAnyAddress(bindAddress) && !hasAddress(addrs, bindAddress) {
+			warn("Client bind address %s does NOT belong to network interface %s (addresses: %v)",
+				bindAddress, networkInterface, addrs)
+		} else {
+			fmt.Printf("✓ Client network interface %s is up (addresses: %v)\n", networkInterface, addrs)
+		}
+	}
+	if problemCnt > 0 {
+		return fmt.Errorf("%d problems found", problemCnt)
+	}
+	return nil
+}
+
+// Test listen port reachability. Return open, the method used, and error (if failed to test).
+func testPort(clientInstance client.Client, port int64) (open bool, method string, err error) {
+	if probe != "" {
+		method = "probe"
+		addr := net.JoinHostPort(probe, fmt.Sprint(port))
+		conn, err := net.DialTimeout("tcp", addr, time.Duration(timeout)*time.Second)
+		if err != nil {
+			log.Debugf("Failed to connect to %s: %v", addr, err)
+			return false, method, nil
+		}
+		conn.Close()
+		return true, method, nil
+	}
+	if clientInstance.GetClientConfig().Type == "transmission" {
+		method = "client"
+		value, err := clientInstance.GetConfig("listen_port_open")
+		if err != nil {
+			return false, method, err
+		}
+		return value == "true", method, nil
+	}
+	method = "service"
+	res, _, err := util.FetchUrl(portCheckService+fmt.Sprint(port), nil, nil)
+	if err != nil {
+		return false, method, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, method, err
+	}
+	return strings.TrimSpace(string(body)) == "1", method, nil
+}
+
+func displayBinding(value string) string {
+	if value == "" {
+		return "(any)"
+	}
+	return value
+}
+
+// Whether client runs on the same host of ptool.
+func isLocalClient(clientInstance client.Client) bool {
+	urlObj, err := url.Parse(clientInstance.GetClientConfig().Url)
+	if err != nil {
+		return false
+	}
+	hostname := urlObj.Hostname()
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+func getInterfaceAddrs(name string) ([]string, error) {
+	networkInterface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if networkInterface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("interface is down")
+	}
+	addrs, err := networkInterface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipnet.IP.String())
+		}
+	}
+	return ips, nil
+}
+
+func isAnyAddress(address string) bool {
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsUnspecified()
+}
+
+func hasAddress(addrs []string, address string) bool {
+	ip := net.ParseIP(address)
+	for _, addr := range addrs {
+		if ip != nil && ip.Equal(net.ParseIP(addr)) {
+			return true
+		}
+	}
+	return false
+}
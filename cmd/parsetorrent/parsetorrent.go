@@ -8,6 +8,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
@@ -16,14 +17,18 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:         "parsetorrent {torrentFilename | torrentId | torrentUrl}...",
+	Use:         "parsetorrent {torrentFilename | torrentId | torrentUrl | dir}...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "parsetorrent"},
 	Aliases:     []string{"parse", "parsetorrents"},
 	Short:       "Parse .torrent (metainfo) files and show their contents.",
 	Long: fmt.Sprintf(`Parse .torrent (metainfo) files and show their contents.
 %s.
+If an arg is a local dir, all *.torrent files inside it (not recursive) are parsed.
 
 By default it displays parsed infos of all provided torrents.
+If "--all" flag is set, it also displays the full infos (piece length, creation date, private flag,
+all trackers, BitTorrent v2 info hash if exists) and files of torrents.
+If "--tree" flag is set, the files are displayed as a tree, with the total size of each folder.
 If "--sum" flag is set, it only displays the summary of all torrents.
 
 It's also capable to work as a torrent files "filter", e.g. :
@@ -39,6 +44,7 @@ and rename these torrent files to *%s suffix.`,
 var (
 	dedupe            = false
 	showAll           = false
+	showTree          = false
 	showInfoHashOnly  = false
 	showJson          = false
 	forceLocal        = false
@@ -61,6 +67,7 @@ func init() {
 	command.Flags().BoolVarP(&dedupe, "dedupe", "", false,
 		"Treat duplicate torrent (has the same info-hash as previous parsed torrent) as fail (error)")
 	command.Flags().BoolVarP(&showAll, "all", "a", false, "Show all info")
+	command.Flags().BoolVarP(&showTree, "tree", "", false, `Show files of torrents as a tree. Implies "--all"`)
 	command.Flags().BoolVarP(&showInfoHashOnly, "show-info-hash-only", "", false, "Output torrents info hash only")
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all arg as local torrent filename")
@@ -74,6 +81,9 @@ func init() {
 }
 
 func parsetorrent(cmd *cobra.Command, args []string) error {
+	if showTree {
+		showAll = true
+	}
 	if util.CountNonZeroVariables(showInfoHashOnly, showAll, showSum) > 1 {
 		return fmt.Errorf("--all, --show-info-hash-only and --sum flags are NOT compatible")
 	}
@@ -87,6 +97,9 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if torrents, err = helper.ExpandTorrentDirs(torrents); err != nil {
+		return err
+	}
 	minTorrentSize, err := util.RAMInBytes(minTorrentSizeStr)
 	if err != nil {
 		return fmt.Errorf("invalid min-torrent-size: %w", err)
@@ -96,12 +109,12 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid max-torrent-size: %w", err)
 	}
 	errorCnt := int64(0)
+	var lastErr error
 	parsedTorrents := map[string]struct{}{}
 	statistics := common.NewTorrentsStatistics()
 
 	for _, torrent := range torrents {
-		_, tinfo, _, _, _, _, isLocal, err := helper.GetTorrentContent(torrent, defaultSite, forceLocal, false,
-			stdinTorrentContents, false, nil)
+		tinfo, isLocal, err := helper.GetTorrentMeta(torrent, defaultSite, forceLocal, false, stdinTorrentContents)
 		if err != nil {
 			statistics.UpdateTinfo(common.TORRENT_INVALID, nil)
 		} else {
@@ -122,6 +135,7 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 				fmt.Fprintf(os.Stderr, "✕ %s : failed to parse: %v\n", torrent, err)
 			}
 			errorCnt++
+			lastErr = err
 			if isLocal && torrent != "-" {
 				torrentTrim := util.TrimAnySuffix(torrent, constants.ProcessedFilenameSuffixes...)
 				isValidTarget := strings.HasSuffix(torrentTrim, ".torrent") && util.FileExists(torrent)
@@ -130,7 +144,7 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 						log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_FAIL, err)
 					}
 				} else if deleteFail && isValidTarget {
-					if err := os.Remove(torrent); err != nil {
+					if err := audit.RemoveFile(torrent); err != nil {
 						log.Debugf("Failed to delete %s: %v", torrent, err)
 					}
 				}
@@ -146,6 +160,7 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			if err := util.PrintJson(os.Stdout, tinfo); err != nil {
 				log.Errorf("%s: %v", torrent, err)
 				errorCnt++
+				lastErr = err
 			}
 			continue
 		} else if showInfoHashOnly {
@@ -153,7 +168,10 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			continue
 		}
 		tinfo.Fprint(os.Stdout, torrent, showAll)
-		if showAll {
+		if showTree {
+			tinfo.FprintFileTree(os.Stdout, false)
+			fmt.Printf("\n")
+		} else if showAll {
 			tinfo.FprintFiles(os.Stdout, true, false)
 			fmt.Printf("\n")
 		}
@@ -166,8 +184,5 @@ func parsetorrent(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
@@ -4,18 +4,28 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"time"
 
 	"github.com/shibumi/go-pathspec"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/rclone"
 	"github.com/sagan/ptool/util"
 )
 
+// Interval of checking the download progress of current chunk in --auto mode.
+const AUTO_POLL_INTERVAL = 30 * time.Second
+
 type Chunk struct {
 	Index int64
 	Files int64
@@ -78,7 +88,16 @@ but will NOT mark other files as no-download (Leave their download / no-download
 
 Use case of this command: You have a cloud VPS / Server with limited disk space, and you want to use this
 machine to download a large torrent. And then upload the downloaded torrent contents
-to cloud drive using rclone, for example. The above task is trivial using this command.`,
+to cloud drive using rclone, for example. The above task is trivial using this command.
+
+With --auto flag, ptool will do the whole task automatically: starting from the --chunk-index chunk, it marks files
+of the chunk as download (all other files as no-download), resumes the torrent and waits until the chunk is downloaded,
+then executes "rclone copy" to upload the files of the chunk to --remote (e.g. "gdrive:media") and
+"rclone check --one-way" to verify them (unless --no-check flag is set), then deletes the local files of the chunk
+and continues with the next chunk, until all chunks are done. Each file is uploaded to the same relative path
+as it's in the save path of torrent. ptool must have access to the save path of torrent, use --map-save-path
+if it's different from client's. Do NOT recheck the torrent during or after the task.
+Use "--progress json" flag to emit machine-readable progress records (JSON Lines) to stderr during the task.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
 	RunE: partialdownload,
 }
@@ -94,9 +113,17 @@ var (
 	originalOrder = false
 	includes      []string
 	excludes      []string
+	auto          = false
+	noCheck       = false
+	remote        = ""
+	rcloneBinary  = ""
+	rcloneFlags   = ""
+	progressMode  = ""
+	mapSavePaths  []string
 )
 
 func init() {
+	cmd.AddEnumFlagP(command, &progressMode, "progress", "", common.ProgressFlag)
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
 	command.Flags().BoolVarP(&showAll, "all", "a", false, "Show full chunks info and exit")
 	command.Flags().BoolVarP(&appendMode, "append", "", false,
@@ -121,6 +148,20 @@ func init() {
 		`Specifiy patterns of files that will be skipped. `+
 			`Use gitignore-style, checked against the file path in torrent. E.g. "*.txt". `+
 			"Skipped files will be be excluded from being splitting into chunks")
+	command.Flags().BoolVarP(&auto, "auto", "", false,
+		"Automatically download all chunks one by one, and upload the downloaded chunk to --remote using rclone "+
+			"then delete it's local files before download the next chunk")
+	command.Flags().BoolVarP(&noCheck, "no-check", "", false, "Used with --auto. Do not verify uploaded files")
+	command.Flags().StringVarP(&remote, "remote", "", "",
+		`Used with --auto. The rclone remote path to upload to. E.g. "gdrive:media"`)
+	command.Flags().StringVarP(&rcloneBinary, "rclone-binary", "", rclone.DEFAULT_BINARY,
+		"Used with --auto. The path of rclone binary")
+	command.Flags().StringVarP(&rcloneFlags, "rclone-flags", "", "",
+		`Used with --auto. The additional rclone flags. E.g. "--config rclone.conf"`)
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Used with --auto. Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -177,6 +218,12 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 	if chunkSize <= 0 {
 		return fmt.Errorf("invalid chunk size %d", chunkSize)
 	}
+	if auto && (remote == "" || appendMode || showAll) {
+		return fmt.Errorf("--auto flag requires --remote flag and is NOT compatible with --append or --all flags")
+	}
+	if !auto && progressMode != constants.NONE {
+		return fmt.Errorf("--progress flag must be used with --auto flag")
+	}
 	clientName := args[0]
 	infoHash := args[1]
 
@@ -209,6 +256,8 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 	currentChunkFilesCnt := int64(0)
 	downloadFileIndexes := []int64{}
 	noDownloadFileIndexes := []int64{}
+	skippedFileIndexes := []int64{}
+	chunkFiles := [][]*client.TorrentContentFile{{}} // chunk index => files
 	// For negative chunk-index, scan once to get total chunks count
 	if chunkIndex < 0 {
 		for i, file := range torrentFiles {
@@ -274,6 +323,7 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 			summary.SkippedFiles++
 			summary.SkippedSize += file.Size
 			noDownloadFileIndexes = append(noDownloadFileIndexes, file.Index)
+			skippedFileIndexes = append(skippedFileIndexes, file.Index)
 			continue
 		}
 		summary.TotalFiles++
@@ -287,9 +337,11 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 			currentChunkIndex++
 			currentChunkSize = 0
 			currentChunkFilesCnt = 0
+			chunkFiles = append(chunkFiles, []*client.TorrentContentFile{})
 		}
 		currentChunkSize += file.Size
 		currentChunkFilesCnt++
+		chunkFiles[currentChunkIndex] = append(chunkFiles[currentChunkIndex], file)
 		if currentChunkIndex == chunkIndex {
 			downloadFileIndexes = append(downloadFileIndexes, file.Index)
 		} else {
@@ -309,6 +361,21 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 		return fmt.Errorf("invalid chunkIndex %d. Torrent has %d chunks", chunkIndex, len(summary.Chunks))
 	}
 	summary.DownloadChunkIndex = chunkIndex
+	if auto {
+		return autoDownload(clientInstance, infoHash, chunkFiles, skippedFileIndexes)
+	}
+	if flags.DryRun {
+		if showJson {
+			return util.PrintJson(os.Stdout, summary)
+		}
+		summary.PrintSelf(os.Stdout)
+		if appendMode {
+			noDownloadFileIndexes = nil
+		}
+		fmt.Printf("Dry run. %d files would be marked as download, %d files would be marked as no-download\n",
+			len(downloadFileIndexes), len(noDownloadFileIndexes))
+		return nil
+	}
 	// mark file as download
 	if len(downloadFileIndexes) > 0 {
 		err = clientInstance.SetFilePriority(infoHash, downloadFileIndexes, 1)
@@ -337,3 +404,121 @@ func partialdownload(cmd *cobra.Command, args []string) (err error) {
 	summary.PrintSelf(os.Stdout)
 	return nil
 }
+
+// Download chunks one by one from chunkIndex, upload each downloaded chunk using rclone then delete it's local files.
+func autoDownload(clientInstance client.Client, infoHash string, chunkFiles [][]*client.TorrentContentFile,
+	skippedFileIndexes []int64) (err error) {
+	progress := common.NewProgress(progressMode, "partialdownload", int64(len(chunkFiles))-chunkIndex)
+	defer func() {
+		progress.End(err)
+	}()
+	savePathMapper, err := common.GetSavePathMapper(clientInstance.GetName(), mapSavePaths, false)
+	if err != nil {
+		return err
+	}
+	r, err := rclone.New(rcloneBinary, rcloneFlags)
+	if err != nil {
+		return err
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if torrent == nil {
+		return fmt.Errorf("torrent %s not found", infoHash)
+	}
+	savePath := torrent.SavePath
+	if savePathMapper != nil {
+		var match bool
+		if savePath, match = savePathMapper.Before2After(torrent.SavePath); !match {
+			return fmt.Errorf("save path %q does not match with any map-save-path rule", torrent.SavePath)
+		}
+	}
+	if common.DryRun("download chunks %d-%d of torrent %s one by one and upload them from %q to %q",
+		chunkIndex, len(chunkFiles)-1, infoHash, savePath, remote) {
+		return nil
+	}
+	// mark all files as no-download first
+	noDownloadFileIndexes := slices.Clone(skippedFileIndexes)
+	for _, files := range chunkFiles {
+		for _, file := range files {
+			noDownloadFileIndexes = append(noDownloadFileIndexes, file.Index)
+		}
+	}
+	if err := clientInstance.SetFilePriority(infoHash, noDownloadFileIndexes, 0); err != nil {
+		return fmt.Errorf("failed to mark files as no-download: %w", err)
+	}
+	for i := chunkIndex; i < int64(len(chunkFiles)); i++ {
+		var indexes []int64
+		var paths []string
+		size := int64(0)
+		for _, file := range chunkFiles[i] {
+			indexes = append(indexes, file.Index)
+			paths = append(paths, file.Path)
+			size += file.Size
+		}
+		fmt.Printf("Chunk %d / %d: download %d files (%s)\n", i, len(chunkFiles)-1, len(indexes),
+			util.BytesSize(float64(size)))
+		progress.Start(i-chunkIndex+1, fmt.Sprintf("chunk %d", i), size)
+		progress.Stage("download")
+		if err := clientInstance.SetFilePriority(infoHash, indexes, 1); err != nil {
+			return fmt.Errorf("failed to mark files of chunk %d as download: %w", i, err)
+		}
+		if err := clientInstance.ResumeTorrents([]string{infoHash}); err != nil {
+			return fmt.Errorf("failed to resume torrent: %w", err)
+		}
+		if err := waitFilesComplete(clientInstance, infoHash, indexes, progress); err != nil {
+			return fmt.Errorf("failed to download chunk %d: %w", i, err)
+		}
+		fmt.Printf("Chunk %d / %d: upload to %q\n", i, len(chunkFiles)-1, remote)
+		progress.Stage("upload")
+		if err := r.CopyFiles(savePath, remote, paths); err != nil {
+			return fmt.Errorf("failed to upload chunk %d: %w", i, err)
+		}
+		if !noCheck {
+			progress.Stage("check")
+			if err := r.CheckFiles(savePath, remote, paths, false); err != nil {
+				return fmt.Errorf("uploaded files of chunk %d verification failed: %w", i, err)
+			}
+		}
+		if err := clientInstance.SetFilePriority(infoHash, indexes, 0); err != nil {
+			return fmt.Errorf("failed to mark files of chunk %d as no-download: %w", i, err)
+		}
+		for _, path := range paths {
+			err := audit.RemoveFile(filepath.Join(savePath, filepath.FromSlash(path)))
+			if err != nil && !os.IsNotExist(err) {
+				log.Warnf("Failed to delete local file %q: %v", path, err)
+			}
+		}
+		fmt.Printf("✓ Chunk %d / %d: uploaded and local files deleted\n", i, len(chunkFiles)-1)
+		progress.Finish(nil)
+	}
+	return clientInstance.PauseTorrents([]string{infoHash})
+}
+
+// Wait until all the files (indexes) of torrent are completely downloaded.
+func waitFilesComplete(clientInstance client.Client, infoHash string, indexes []int64,
+	progress *common.Progress) error {
+	for {
+		files, err := clientInstance.GetTorrentContents(infoHash)
+		if err != nil {
+			return fmt.Errorf("failed to get torrent contents: %w", err)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("torrent not found")
+		}
+		completed, total := int64(0), int64(0)
+		for _, file := range files {
+			if slices.Contains(indexes, file.Index) {
+				total += file.Size
+				completed += int64(float64(file.Size) * file.Progress)
+			}
+		}
+		progress.Update(completed)
+		if completed >= total {
+			return nil
+		}
+		log.Infof("Downloading: %s / %s", util.BytesSize(float64(completed)), util.BytesSize(float64(total)))
+		time.Sleep(AUTO_POLL_INTERVAL)
+	}
+}
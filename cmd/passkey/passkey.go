@@ -0,0 +1,184 @@
+package passkey
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site/tpl"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use:         "passkey <site> [client]... [--rotate] [--old-passkey passkey] [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "passkey"},
+	Short:       "Show site passkey, or update client torrents after site passkey was rotated.",
+	Long: `Show site passkey, or update client torrents after site passkey was rotated.
+
+Without --rotate flag, it displays the passkey of the site in config file.
+
+With --rotate flag, it finds all torrents of the site in clients (by tracker domain),
+and updates their tracker announce urls to use the current passkey of the site in config file.
+So the workflow of passkey rotation is:
+1. Reset passkey in site web page.
+2. Update the "passkey" of the site in ptool.toml config file.
+3. Run "ptool passkey <site> --rotate".
+
+[client]...: the clients to update. If not provided, all clients in config file are used.
+
+By default, it replaces the value of "passkey" query parameter of tracker url,
+e.g. "https://tracker.example.com/announce.php?passkey=<passkey>".
+If the site puts passkey in other place of tracker url (e.g. "/announce/<passkey>"),
+use '--old-passkey' flag to set the old passkey, all occurrences of which in tracker url will be replaced.
+
+Examples:
+  ptool passkey mteam
+  ptool passkey mteam --rotate --dry-run
+  ptool passkey mteam local remote --rotate --old-passkey 0123456789abcdef
+
+It will list the affected torrents and ask for confirmation, unless --force flag is set.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: passkey,
+}
+
+var (
+	rotate     = false
+	force      = false
+	oldPasskey = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&rotate, "rotate", "", false,
+		"Update tracker urls of site torrents in clients to use the current passkey of site")
+	command.Flags().BoolVarP(&force, "force", "", false, "Force updating trackers. Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only list the affected torrents and trackers, do NOT actually update them")
+	command.Flags().StringVarP(&oldPasskey, "old-passkey", "", "",
+		"The old passkey. If set, replace all occurrences of it in tracker url with the new one")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func passkey(cmd *cobra.Command, args []string) error {
+	sitename := args[0]
+	clientnames := args[1:]
+	siteConfig := config.GetSiteConfig(sitename)
+	if siteConfig == nil {
+		return fmt.Errorf("site %s not found", sitename)
+	}
+	newPasskey := siteConfig.Passkey
+	if !rotate {
+		if newPasskey == "" {
+			return fmt.Errorf("passkey of site %s is not set in config file", sitename)
+		}
+		fmt.Printf("%s\n", newPasskey)
+		return nil
+	}
+	if newPasskey == "" {
+		return fmt.Errorf(`passkey of site %s is not set in config file. Add the 'passkey = "..."' line `+
+			`to the site config block of ptool.toml first`, sitename)
+	}
+	if oldPasskey == newPasskey {
+		return fmt.Errorf("--old-passkey is the same with current site passkey")
+	}
+	if len(clientnames) == 0 {
+		for _, clientConfig := range config.Get().ClientsEnabled {
+			clientnames = append(clientnames, clientConfig.Name)
+		}
+	}
+	clientnames = util.UniqueSlice(clientnames)
+	// domain => is site domain
+	siteDomains := map[string]bool{}
+	isSiteDomain := func(domain string) bool {
+		if domain == "" {
+			return false
+		}
+		if _, ok := siteDomains[domain]; !ok {
+			name, _ := tpl.GuessSiteByDomain(domain, sitename)
+			siteDomains[domain] = name == sitename
+		}
+		return siteDomains[domain]
+	}
+
+	errorCnt := int64(0)
+	for _, clientname := range clientnames {
+		clientInstance, err := client.CreateClient(clientname)
+		if err != nil {
+			log.Errorf("Failed to create client %s: %v", clientname, err)
+			errorCnt++
+			continue
+		}
+		torrents, err := client.QueryTorrents(clientInstance, "", "", "")
+		if err != nil {
+			log.Errorf("Failed to get client %s torrents: %v", clientname, err)
+			errorCnt++
+			continue
+		}
+		torrents = util.Filter(torrents, func(torrent *client.Torrent) bool {
+			return torrent.TrackerBaseDomain == "" || isSiteDomain(torrent.TrackerBaseDomain)
+		})
+		replacements, cnt := client.FindTrackerReplacements(clientInstance, torrents,
+			func(torrent *client.Torrent, trackerUrl string) string {
+				if !isSiteDomain(util.GetUrlDomain(trackerUrl)) {
+					return ""
+				}
+				return replacePasskey(trackerUrl, oldPasskey, newPasskey)
+			})
+		errorCnt += cnt
+		fmt.Printf("Client %s: %d trackers of site %s torrents need to be updated\n",
+			clientname, len(replacements), sitename)
+		if len(replacements) == 0 {
+			continue
+		}
+		client.PrintTrackerReplacements(os.Stdout, replacements)
+		fmt.Printf("\n")
+		if flags.DryRun {
+			continue
+		}
+		if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will replace above %d trackers in client %s",
+			len(replacements), clientname)) {
+			return fmt.Errorf("abort")
+		}
+		errorCnt += client.ApplyTrackerReplacements(clientInstance, replacements)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
+	return nil
+}
+
+// Return the tracker url with passkey replaced, or empty string if passkey is not found in it.
+func replacePasskey(trackerUrl string, oldPasskey string, newPasskey string) string {
+	if oldPasskey != "" {
+		if !strings.Contains(trackerUrl, oldPasskey) {
+			return ""
+		}
+		return strings.ReplaceAll(trackerUrl, oldPasskey, newPasskey)
+	}
+	urlObj, err := url.Parse(trackerUrl)
+	if err != nil {
+		return ""
+	}
+	query := urlObj.Query()
+	if !query.Has("passkey") || query.Get("passkey") == newPasskey {
+		return ""
+	}
+	// only replace the passkey value, keep other params as is (order and encoding)
+	params := strings.Split(urlObj.RawQuery, "&")
+	for i, param := range params {
+		if key, _, _ := strings.Cut(param, "="); key == "passkey" {
+			params[i] = key + "=" + url.QueryEscape(newPasskey)
+		}
+	}
+	urlObj.RawQuery = strings.Join(params, "&")
+	return urlObj.String()
+}
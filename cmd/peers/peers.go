@@ -0,0 +1,66 @@
+package peers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "peers {client} {infoHash} [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "peers"},
+	Short:       "Show connected peers of a torrent in client.",
+	Long: `Show connected peers of a torrent in client.
+Displayed info: address (ip:port), peer client name, progress, download speed (from peer),
+upload speed (to peer), downloaded (from peer), uploaded (to peer) and client specific connection flags.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: peers,
+}
+
+var (
+	showJson = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func peers(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHash := args[1]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	peers, err := clientInstance.GetTorrentPeers(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent peers: %w", err)
+	}
+	if showJson {
+		return util.PrintJson(os.Stdout, peers)
+	}
+	fmt.Printf("Peers (%d):\n", len(peers))
+	fmt.Printf("%-40s  %-20s  %-2s  %-6s  %-8s  %-8s  %-8s  %-8s  %s\n",
+		"Address", "Client", "CC", "Prog", "↓Spd/s", "↑Spd/s", "↓", "↑", "Flags")
+	for _, peer := range peers {
+		downloaded, uploaded := "-", "-"
+		if peer.Downloaded >= 0 {
+			downloaded = util.BytesSizeAround(float64(peer.Downloaded))
+		}
+		if peer.Uploaded >= 0 {
+			uploaded = util.BytesSizeAround(float64(peer.Uploaded))
+		}
+		fmt.Printf("%-40s  ", peer.Address)
+		util.PrintStringInWidth(os.Stdout, peer.Client, 20, true)
+		fmt.Printf("  %-2s  %-6s  %-8s  %-8s  %-8s  %-8s  %s\n", peer.Country,
+			fmt.Sprintf("%.1f%%", peer.Progress*100), util.BytesSizeAround(float64(peer.DownloadSpeed)),
+			util.BytesSizeAround(float64(peer.UploadSpeed)), downloaded, uploaded, peer.Flags)
+	}
+	return nil
+}
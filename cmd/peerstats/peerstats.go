@@ -0,0 +1,254 @@
+package peerstats
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "peerstats {client} [infoHash]... [--category category] [--tag tag] [--filter filter] " +
+		"[--geoip-db GeoLite2-Country.mmdb] [--asn-db GeoLite2-ASN.mmdb]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "peerstats"},
+	Short:       "Show statistics of connected peers of torrents in client, by country / ASN and client software.",
+	Long: fmt.Sprintf(`Show statistics of connected peers of torrents in client, by country / ASN and client software.
+%s.
+If no args or filter flags provided, the "_active" torrents are used.
+
+It aggregates the connected peers of all selected torrents and displays summary tables:
+number of peers, seeders (peers that have completed the torrent), download speed (from peers)
+and upload speed (to peers) of each country, ASN (if --asn-db is set) and client software (version stripped,
+unless --client-version flag is set). It's useful to diagnose leech-heavy swarms or VPN leaks.
+
+The country of peer is reported by client (qBittorrent only, requires "Resolve peer countries" option enabled).
+If --geoip-db flag is set, it's looked up in the MaxMind (or compatible, e.g. DB-IP) mmdb database instead,
+e.g. "GeoLite2-Country.mmdb" or "GeoLite2-City.mmdb". The --asn-db flag accepts "GeoLite2-ASN.mmdb" database.`,
+		constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: peerstats,
+}
+
+var (
+	showJson      = false
+	clientVersion = false
+	maxRows       = int64(0)
+	category      = ""
+	tag           = ""
+	filter        = ""
+	geoipDb       = ""
+	asnDb         = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&clientVersion, "client-version", "", false,
+		"Group peers by full client software name with version")
+	command.Flags().Int64VarP(&maxRows, "max-rows", "", 20, "Max rows of each table. -1 = no limit")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&geoipDb, "geoip-db", "", "", "GeoIP country (or city) mmdb database file")
+	command.Flags().StringVarP(&asnDb, "asn-db", "", "", "GeoIP ASN mmdb database file")
+	cmd.RootCmd.AddCommand(command)
+}
+
+// Aggregated stats of peers of a group (country, ASN or client software).
+type PeerStat struct {
+	Name          string `json:"name"`
+	Peers         int64  `json:"peers"`
+	Seeders       int64  `json:"seeders"`
+	Torrents      int64  `json:"torrents"` // number of torrents that have peers of this group
+	DownloadSpeed int64  `json:"downloadSpeed"`
+	UploadSpeed   int64  `json:"uploadSpeed"`
+	torrents      map[string]bool
+}
+
+type PeerStats struct {
+	Torrents  int64       `json:"torrents"`
+	Peers     int64       `json:"peers"`
+	Countries []*PeerStat `json:"countries"`
+	Asns      []*PeerStat `json:"asns,omitempty"`
+	Clients   []*PeerStat `json:"clients"`
+}
+
+// The record of GeoIP country / city database.
+type countryRecord struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// The record of GeoIP ASN database.
+type asnRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Match the version part of peer client software, e.g. "qBittorrent/4.5.2", "Transmission 3.00", "μTorrent 3.5.5".
+var clientVersionRegexp = regexp.MustCompile(`[\s/]+v?\d[\w.\-]*(\s.*)?$`)
+
+func peerstats(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if category == "" && tag == "" && filter == "" {
+		if len(infoHashes) == 0 {
+			infoHashes = []string{"_active"}
+		} else if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	var countryReader, asnReader *maxminddb.Reader
+	if geoipDb != "" {
+		var err error
+		if countryReader, err = maxminddb.Open(geoipDb); err != nil {
+			return fmt.Errorf("failed to open geoip db: %w", err)
+		}
+		defer countryReader.Close()
+	}
+	if asnDb != "" {
+		var err error
+		if asnReader, err = maxminddb.Open(asnDb); err != nil {
+			return fmt.Errorf("failed to open asn db: %w", err)
+		}
+		defer asnReader.Close()
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+
+	stats := &PeerStats{}
+	countries := map[string]*PeerStat{}
+	asns := map[string]*PeerStat{}
+	clients := map[string]*PeerStat{}
+	for _, torrent := range torrents {
+		peers, err := clientInstance.GetTorrentPeers(torrent.InfoHash)
+		if err != nil {
+			log.Errorf("Failed to get torrent %s peers: %v", torrent.InfoHash, err)
+			continue
+		}
+		if len(peers) > 0 {
+			stats.Torrents++
+		}
+		for _, peer := range peers {
+			stats.Peers++
+			ip := peerIp(peer.Address)
+			country := strings.ToUpper(peer.Country)
+			if countryReader != nil && ip != nil {
+				record := &countryRecord{}
+				if err := countryReader.Lookup(ip, record); err == nil {
+					country = record.Country.IsoCode
+				}
+			}
+			addPeer(countries, country, torrent.InfoHash, peer)
+			if asnReader != nil {
+				asn := ""
+				record := &asnRecord{}
+				if ip != nil && asnReader.Lookup(ip, record) == nil && record.AutonomousSystemNumber > 0 {
+					asn = fmt.Sprintf("AS%d %s", record.AutonomousSystemNumber, record.AutonomousSystemOrganization)
+				}
+				addPeer(asns, asn, torrent.InfoHash, peer)
+			}
+			software := strings.TrimSpace(peer.Client)
+			if !clientVersion {
+				software = clientVersionRegexp.ReplaceAllString(software, "")
+			}
+			addPeer(clients, software, torrent.InfoHash, peer)
+		}
+	}
+	stats.Countries = sortStats(countries)
+	stats.Clients = sortStats(clients)
+	if asnReader != nil {
+		stats.Asns = sortStats(asns)
+	}
+	if showJson {
+		return util.PrintJson(os.Stdout, stats)
+	}
+	fmt.Printf("Torrents: %d / %d (with peers / all); Peers: %d\n", stats.Torrents, len(torrents), stats.Peers)
+	printStats("Country", stats.Countries, stats.Peers)
+	if stats.Asns != nil {
+		printStats("ASN", stats.Asns, stats.Peers)
+	}
+	printStats("Client", stats.Clients, stats.Peers)
+	return nil
+}
+
+func addPeer(stats map[string]*PeerStat, name string, infoHash string, peer *client.TorrentPeer) {
+	if name == "" {
+		name = "?"
+	}
+	stat := stats[name]
+	if stat == nil {
+		stat = &PeerStat{Name: name, torrents: map[string]bool{}}
+		stats[name] = stat
+	}
+	stat.Peers++
+	if peer.Progress >= 1 {
+		stat.Seeders++
+	}
+	stat.DownloadSpeed += peer.DownloadSpeed
+	stat.UploadSpeed += peer.UploadSpeed
+	if !stat.torrents[infoHash] {
+		stat.torrents[infoHash] = true
+		stat.Torrents++
+	}
+}
+
+// Return stats sorted by peers desc.
+func sortStats(stats map[string]*PeerStat) []*PeerStat {
+	list := []*PeerStat{}
+	for _, stat := range stats {
+		list = append(list, stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Peers != list[j].Peers {
+			return list[i].Peers > list[j].Peers
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func printStats(title string, stats []*PeerStat, total int64) {
+	fmt.Printf("\n%-30s  %-6s  %-6s  %-7s  %-8s  %-8s  %s\n",
+		title, "Peers", "%", "Seeders", "↓Spd/s", "↑Spd/s", "Torrents")
+	for i, stat := range stats {
+		if maxRows >= 0 && int64(i) >= maxRows {
+			fmt.Printf("... and %d more\n", len(stats)-i)
+			break
+		}
+		util.PrintStringInWidth(os.Stdout, stat.Name, 30, true)
+		fmt.Printf("  %-6d  %-6s  %-7d  %-8s  %-8s  %d\n", stat.Peers,
+			fmt.Sprintf("%.1f%%", float64(stat.Peers)*100/float64(total)), stat.Seeders,
+			util.BytesSizeAround(float64(stat.DownloadSpeed)), util.BytesSizeAround(float64(stat.UploadSpeed)),
+			stat.Torrents)
+	}
+}
+
+// Parse the ip of peer address ("ip:port" or "[ipv6]:port"). Return nil if invalid.
+func peerIp(address string) net.IP {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = strings.Trim(address, "[]")
+	}
+	return net.ParseIP(host)
+}
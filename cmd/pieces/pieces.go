@@ -0,0 +1,131 @@
+package pieces
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "pieces {client} {infoHash}",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "pieces"},
+	Short:       "Show piece-level progress and file availability of a torrent in client.",
+	Long: `Show piece-level progress and file availability of a torrent in client.
+It renders a compact progress map of torrent pieces, in which each cell represents one or more pieces:
+  █ : all downloaded; ▒ : partially downloaded; ▓ : downloading; ░ : not downloaded.
+Then it displays the completion percentage and availability (percentage of file pieces currently
+available in swarm, qBittorrent only) of each file of the torrent.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: pieces,
+}
+
+var (
+	width   = int64(0)
+	maxRows = int64(0)
+)
+
+func init() {
+	command.Flags().Int64VarP(&width, "width", "", 64, "Count of cells per row of the progress map")
+	command.Flags().Int64VarP(&maxRows, "max-rows", "", 16, "Max rows of the progress map")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func pieces(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHash := args[1]
+	if width <= 0 || maxRows <= 0 {
+		return fmt.Errorf("--width and --max-rows must be positive")
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if torrent == nil {
+		return fmt.Errorf("torrent %s not found", infoHash)
+	}
+	states, err := clientInstance.GetTorrentPieceStates(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent piece states: %w", err)
+	}
+	files, err := clientInstance.GetTorrentContents(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+
+	fmt.Printf("Torrent: %s (%s)\n", torrent.Name, torrent.InfoHash)
+	downloaded, downloading := int64(0), int64(0)
+	for _, state := range states {
+		switch state {
+		case client.PIECE_DOWNLOADED:
+			downloaded++
+		case client.PIECE_DOWNLOADING:
+			downloading++
+		}
+	}
+	fmt.Printf("Pieces: %d total / %d downloaded / %d downloading / %d missing\n",
+		len(states), downloaded, downloading, int64(len(states))-downloaded-downloading)
+	printPieceMap(os.Stdout, states, width, maxRows)
+	fmt.Printf("\n")
+
+	fmt.Printf("%-5s  %-8s  %-8s  %-7s  %s\n", "Index", "Size", "Progress", "Avail", "Path")
+	for i, file := range files {
+		availability := "-"
+		if file.Availability >= 0 {
+			availability = fmt.Sprintf("%.1f%%", file.Availability*100)
+		}
+		progress := fmt.Sprintf("%.1f%%", file.Progress*100)
+		if file.Ignored {
+			progress = "ignored"
+		}
+		fmt.Printf("%-5d  %-8s  %-8s  %-7s  %s\n", i, util.BytesSizeAround(float64(file.Size)), progress,
+			availability, file.Path)
+	}
+	return nil
+}
+
+// Render piece states as a map of at most width * maxRows cells, each cell represents one or more pieces.
+func printPieceMap(output io.Writer, states []int64, width int64, maxRows int64) {
+	cnt := int64(len(states))
+	if cnt == 0 {
+		return
+	}
+	cells := min(cnt, width*maxRows)
+	var sb strings.Builder
+	for i := int64(0); i < cells; i++ {
+		start, end := i*cnt/cells, (i+1)*cnt/cells
+		downloaded, downloading := int64(0), int64(0)
+		for _, state := range states[start:end] {
+			switch state {
+			case client.PIECE_DOWNLOADED:
+				downloaded++
+			case client.PIECE_DOWNLOADING:
+				downloading++
+			}
+		}
+		switch {
+		case downloaded == end-start:
+			sb.WriteString("█")
+		case downloading > 0:
+			sb.WriteString("▓")
+		case downloaded > 0:
+			sb.WriteString("▒")
+		default:
+			sb.WriteString("░")
+		}
+		if (i+1)%width == 0 || i == cells-1 {
+			sb.WriteString("\n")
+		}
+	}
+	fmt.Fprint(output, sb.String())
+}
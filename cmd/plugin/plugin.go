@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/version"
+)
+
+// Executables named "ptool-<name>" in PATH are plugins, which are run as "ptool <name>" subcommand.
+const PLUGIN_PREFIX = "ptool-"
+
+// The context passed to plugin as json via stdin.
+type Context struct {
+	Version      string   `json:"version"`
+	Executable   string   `json:"executable"` // The full path of ptool executable
+	ConfigFile   string   `json:"configFile"` // The full path of ptool.toml config file
+	ConfigDir    string   `json:"configDir"`
+	Name         string   `json:"name"` // plugin name
+	Args         []string `json:"args"`
+	DryRun       bool     `json:"dryRun"`
+	VerboseLevel int      `json:"verboseLevel"`
+	InShell      bool     `json:"inShell"`
+	Clients      []string `json:"clients"` // names of all clients in config file
+	Sites        []string `json:"sites"`   // names of all sites in config file
+}
+
+var command = &cobra.Command{
+	Use:   "plugins",
+	Short: "List all installed plugins.",
+	Long: `List all installed plugins.
+Any executable named "ptool-<name>" in PATH is a plugin, which can be run as "ptool <name> [args]...",
+similar to git. A plugin is looked up only if no builtin command or alias of that name exists.
+
+A plugin is executed with the args, and inherits the stdout & stderr of ptool.
+The stdin of plugin is a json of ptool context, e.g.:
+  {"version":"v0.1.10","executable":"/usr/bin/ptool","configFile":"/root/.config/ptool/ptool.toml",
+  "configDir":"/root/.config/ptool","name":"foo","args":["bar"],"dryRun":false,"verboseLevel":0,
+  "inShell":false,"clients":["local"],"sites":["mteam"]}
+The following env variables are also set: PTOOL_BIN (ptool executable), PTOOL_CONFIG_FILE, PTOOL_CONFIG_DIR.
+Plugin could use them to run ptool commands (e.g. '$PTOOL_BIN --config $PTOOL_CONFIG_FILE status local').
+If plugin exits with a non-zero code, ptool exits with the same code.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: plugins,
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(command)
+}
+
+func plugins(cmd *cobra.Command, args []string) error {
+	fmt.Printf("%-20s  %s\n", "Name", "Path")
+	for _, plugin := range List() {
+		fmt.Printf("%-20s  %s\n", plugin[0], plugin[1])
+	}
+	return nil
+}
+
+// Find the plugin executable of name in PATH. Return "" if not found.
+func Find(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	path, err := exec.LookPath(PLUGIN_PREFIX + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// List all plugins in PATH. Return [name, path] list. If multiple plugins have the same name,
+// only the first one in PATH is returned.
+func List() (plugins [][2]string) {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			filename := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(filename, PLUGIN_PREFIX) {
+				continue
+			}
+			name := strings.TrimPrefix(filename, PLUGIN_PREFIX)
+			if runtime.GOOS == "windows" {
+				ext := filepath.Ext(name)
+				if !slices.Contains([]string{".exe", ".bat", ".cmd", ".com"}, strings.ToLower(ext)) {
+					continue
+				}
+				name = strings.TrimSuffix(name, ext)
+			} else if info, err := entry.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			if name == "" || slices.Contains(names, name) {
+				continue
+			}
+			names = append(names, name)
+			plugins = append(plugins, [2]string{name, filepath.Join(dir, filename)})
+		}
+	}
+	return plugins
+}
+
+// Run plugin executable (path) of name with args.
+func Run(name string, path string, args []string) error {
+	executable, _ := os.Executable()
+	configFile := filepath.Join(config.ConfigDir, config.ConfigFile)
+	context := &Context{
+		Version:      version.Version,
+		Executable:   executable,
+		ConfigFile:   configFile,
+		ConfigDir:    config.ConfigDir,
+		Name:         name,
+		Args:         args,
+		DryRun:       flags.DryRun,
+		VerboseLevel: config.VerboseLevel,
+		InShell:      config.InShell,
+		Clients:      []string{},
+		Sites:        []string{},
+	}
+	for _, clientConfig := range config.Get().Clients {
+		context.Clients = append(context.Clients, clientConfig.Name)
+	}
+	for _, siteConfig := range config.Get().Sites {
+		context.Sites = append(context.Sites, siteConfig.GetName())
+	}
+	contextData, err := json.Marshal(context)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugin context: %w", err)
+	}
+	log.Debugf("Run plugin %s: %s %v", name, path, args)
+	command := exec.Command(path, args...)
+	command.Stdin = bytes.NewReader(contextData)
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = append(os.Environ(),
+		"PTOOL_BIN="+executable,
+		"PTOOL_CONFIG_FILE="+configFile,
+		"PTOOL_CONFIG_DIR="+config.ConfigDir,
+	)
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &constants.ExitCodeError{
+				Code: exitErr.ExitCode(),
+				Err:  fmt.Errorf("plugin %s exited with code %d", name, exitErr.ExitCode()),
+			}
+		}
+		return fmt.Errorf("failed to run plugin %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package proxytest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Noooste/azuretls-client"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "proxytest [client | site | group]...",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "proxytest"},
+	Short:       "Test the reachability of clients and sites through their configured proxies.",
+	Long: `Test the reachability of clients and sites through their configured proxies.
+If no args provided, test all enabled clients and sites.
+
+For each client or site, it sends a http GET request to it's url using the effective proxy,
+following the orders: --proxy flag, client / site "proxy" config, global "clientProxy" / "siteProxy" config,
+HTTP_PROXY & HTTPS_PROXY envs. Any http response (whatever the status) is considered reachable.
+Site cookie is NOT sent, so the result does not indicate whether the cookie is valid.
+
+It displays the following info of each client or site:
+- Proxy : The effective proxy (password is redacted). "-" means direct connection.
+- Status : The http status of response, or "ERROR" if unreachable.
+- Time : Time spent on the request.`,
+	RunE: proxytest,
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(command)
+}
+
+// site.CreateSite is not concurrency-safe
+var createSiteMu sync.Mutex
+
+type result struct {
+	kind   string
+	name   string
+	proxy  string
+	status string
+	time   time.Duration
+	err    error
+}
+
+func proxytest(cmd *cobra.Command, args []string) error {
+	names := config.ParseGroupAndOtherNames(args...)
+	if len(names) == 0 {
+		for _, clientConfig := range config.Get().ClientsEnabled {
+			names = append(names, clientConfig.Name)
+		}
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if siteConfig.Dead {
+				continue
+			}
+			names = append(names, siteConfig.GetName())
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no clients or sites provided")
+	}
+	timeout := util.FirstNonZeroIntegerArg(config.Timeout, config.DEFAULT_TIMEOUT)
+	results := make([]*result, len(names))
+	ch := make(chan struct{}, len(names))
+	for i, name := range names {
+		go func() {
+			defer func() { ch <- struct{}{} }()
+			if clientConfig := config.GetClientConfig(name); clientConfig != nil {
+				results[i] = testClient(clientConfig, timeout)
+			} else if site.GetConfigSiteReginfo(name) != nil {
+				results[i] = testSite(name)
+			} else {
+				results[i] = &result{kind: "-", name: name, proxy: "-", status: "ERROR",
+					err: fmt.Errorf("not a client or site")}
+			}
+		}()
+	}
+	for range names {
+		<-ch
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	fmt.Printf("%-6s  %-15s  %-40s  %-6s  %-8s  %s\n", "Type", "Name", "Proxy", "Status", "Time", "Error")
+	for _, r := range results {
+		errStr := "-"
+		if r.err != nil {
+			errStr = r.err.Error()
+			errorCnt++
+			lastErr = r.err
+		}
+		fmt.Printf("%-6s  %-15s  %-40s  %-6s  %-8s  %s\n", r.kind, r.name, r.proxy, r.status,
+			r.time.Round(time.Millisecond), errStr)
+	}
+	return common.ItemsError(errorCnt, int64(len(results)), lastErr)
+}
+
+func testClient(clientConfig *config.ClientConfigStruct, timeout int64) *result {
+	r := &result{kind: "client", name: clientConfig.Name, status: "ERROR"}
+	proxy := config.GetProxy(clientConfig.Proxy, config.Get().ClientProxy)
+	if proxy == "" || proxy == constants.ENV_PROXY {
+		proxy = util.ParseProxyFromEnv(clientConfig.Url)
+	} else if proxy == constants.NONE {
+		proxy = ""
+	}
+	r.proxy = redactProxy(proxy)
+	transport, err := client.NewHttpTransport(clientConfig, config.Get())
+	if err != nil {
+		r.err = err
+		return r
+	}
+	httpClient := &http.Client{Transport: transport, Timeout: time.Duration(timeout) * time.Second}
+	start := time.Now()
+	res, err := httpClient.Get(clientConfig.Url)
+	r.time = time.Since(start)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	res.Body.Close()
+	r.status = fmt.Sprint(res.StatusCode)
+	return r
+}
+
+func testSite(name string) *result {
+	r := &result{kind: "site", name: name, proxy: "-", status: "ERROR"}
+	createSiteMu.Lock()
+	siteInstance, err := site.CreateSite(name)
+	createSiteMu.Unlock()
+	if err != nil {
+		r.err = err
+		return r
+	}
+	// the site config merged with internal site template, which has the default url
+	siteConfig := siteInstance.GetSiteConfig()
+	r.proxy = redactProxy(site.GetProxy(siteConfig, config.Get()))
+	session, _, err := site.CreateSiteHttpClient(siteConfig, config.Get())
+	if err != nil {
+		r.err = err
+		return r
+	}
+	start := time.Now()
+	res, err := session.Do(&azuretls.Request{Method: http.MethodGet, Url: siteConfig.Url, NoCookie: true})
+	r.time = time.Since(start)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.status = fmt.Sprint(res.StatusCode)
+	return r
+}
+
+// Return proxy with password redacted, or "-" if proxy is empty.
+func redactProxy(proxy string) string {
+	if proxy == "" {
+		return "-"
+	}
+	proxyUrl, err := util.ParseProxyUrl(proxy)
+	if err != nil {
+		return proxy
+	}
+	return proxyUrl.Redacted()
+}
@@ -0,0 +1,201 @@
+package prunereport
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "prunereport {client} [infoHash]... [--category category] [--tag tag] [--filter filter] " +
+		"[--idle 30d] [--max-seeders 3] [--unregistered]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "prunereport"},
+	Short:       "Report torrents of client that are candidates for pruning, with recoverable disk space.",
+	Long: fmt.Sprintf(`Report torrents of client that are candidates for pruning, with recoverable disk space.
+%s.
+If no args or filter flags provided, all torrents of client are checked.
+
+A torrent is reported if it meets any of the set conditions:
+--idle : has no activity (no data uploaded or downloaded) for at least this time, e.g. "30d".
+  Torrents added to client within this time are not reported.
+--max-seeders : has fewer seeders (reported by tracker) than this value.
+--unregistered : trackers report that the torrent is not registered (e.g. deleted by site).
+At least one condition must be set.
+
+It displays the reported torrents with the met conditions, then the total size of them and
+the estimated disk space recoverable if they are pruned: torrents that share content path with
+other (not reported) torrents of client (xseed torrents) are not counted, and the torrents of
+same content path are counted only once.
+
+Use --show-info-hash-only flag to output info hashes only, which can be piped to other commands, e.g.:
+  ptool prunereport local --idle 60d --max-seeders 3 --show-info-hash-only | ptool delete local --force -
+To remove such torrents periodically, define [[autoremoves]] strategies in config file (see "autoremove" command)
+with equivalent conditions, e.g. "minSeeders" or "expr = 'activity < 30d'".`,
+		constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: prunereport,
+}
+
+var (
+	unregistered     = false
+	showJson         = false
+	showInfoHashOnly = false
+	maxSeeders       = int64(0)
+	idle             = ""
+	category         = ""
+	tag              = ""
+	filter           = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&unregistered, "unregistered", "", false,
+		"Report torrents that are unregistered in trackers")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&showInfoHashOnly, "show-info-hash-only", "", false, "Output torrents info hash only")
+	command.Flags().Int64VarP(&maxSeeders, "max-seeders", "", 0,
+		"Report torrents that have fewer seeders than this value. 0 = disable")
+	command.Flags().StringVarP(&idle, "idle", "", "",
+		`Report torrents that have no activity for at least this time, e.g. "30d"`)
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+type PruneCandidate struct {
+	InfoHash    string   `json:"infoHash"`
+	Name        string   `json:"name"`
+	Size        int64    `json:"size"`
+	Seeders     int64    `json:"seeders"`
+	IdleTime    int64    `json:"idleTime"` // seconds since latest activity
+	ContentPath string   `json:"contentPath"`
+	Reasons     []string `json:"reasons"`
+}
+
+type PruneReport struct {
+	Torrents    []*PruneCandidate `json:"torrents"`
+	Size        int64             `json:"size"`        // total size of reported torrents
+	Recoverable int64             `json:"recoverable"` // estimated disk space recoverable if pruned
+}
+
+func prunereport(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if showJson && showInfoHashOnly {
+		return fmt.Errorf("--json and --show-info-hash-only flags are NOT compatible")
+	}
+	idleTime := int64(0)
+	if idle != "" {
+		var err error
+		if idleTime, err = util.ParseTimeDuration(idle); err != nil || idleTime <= 0 {
+			return fmt.Errorf("invalid idle time %q", idle)
+		}
+	}
+	if idleTime == 0 && maxSeeders <= 0 && !unregistered {
+		return fmt.Errorf("no condition set: at least one of --idle, --max-seeders and --unregistered flags must be set")
+	}
+	if len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		infoHashes = []string{"_all"}
+	} else if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+	allTorrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return err
+	}
+
+	now := util.Now()
+	report := &PruneReport{Torrents: []*PruneCandidate{}}
+	reported := map[string]bool{}
+	for _, torrent := range torrents {
+		if torrent.State == "checking" {
+			continue
+		}
+		candidate := &PruneCandidate{
+			InfoHash:    torrent.InfoHash,
+			Name:        torrent.Name,
+			Size:        torrent.Size,
+			Seeders:     torrent.Seeders,
+			IdleTime:    now - max(torrent.ActivityTime, torrent.Atime),
+			ContentPath: torrent.ContentPath,
+		}
+		if idleTime > 0 && candidate.IdleTime >= idleTime {
+			candidate.Reasons = append(candidate.Reasons, "idle")
+		}
+		if maxSeeders > 0 && torrent.Seeders < maxSeeders {
+			candidate.Reasons = append(candidate.Reasons, "seeders")
+		}
+		if unregistered {
+			if trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash); err != nil {
+				log.Errorf("Failed to get torrent %s trackers: %v", torrent.InfoHash, err)
+			} else if common.SeemsUnregisteredTorrent(trackers) {
+				candidate.Reasons = append(candidate.Reasons, "unregistered")
+			}
+		}
+		if len(candidate.Reasons) == 0 {
+			continue
+		}
+		reported[torrent.InfoHash] = true
+		report.Torrents = append(report.Torrents, candidate)
+		report.Size += candidate.Size
+	}
+	// content path => kept (not reported) torrents exist
+	kept := map[string]bool{}
+	for _, torrent := range allTorrents {
+		if !reported[torrent.InfoHash] {
+			kept[torrent.ContentPath] = true
+		}
+	}
+	counted := map[string]bool{}
+	for _, candidate := range report.Torrents {
+		if !kept[candidate.ContentPath] && !counted[candidate.ContentPath] {
+			counted[candidate.ContentPath] = true
+			report.Recoverable += candidate.Size
+		}
+	}
+	sort.SliceStable(report.Torrents, func(i, j int) bool {
+		return report.Torrents[i].Size > report.Torrents[j].Size
+	})
+
+	if showJson {
+		return util.PrintJson(os.Stdout, report)
+	}
+	if showInfoHashOnly {
+		for _, candidate := range report.Torrents {
+			fmt.Printf("%s\n", candidate.InfoHash)
+		}
+		return nil
+	}
+	fmt.Printf("%-40s  %-8s  %-7s  %-10s  %-22s  %s\n", "InfoHash", "Size", "Seeders", "Idle", "Reasons", "Name")
+	for _, candidate := range report.Torrents {
+		fmt.Printf("%-40s  %-8s  %-7d  %-10s  %-22s  %s\n", candidate.InfoHash,
+			util.BytesSize(float64(candidate.Size)), candidate.Seeders, util.FormatDuration(candidate.IdleTime),
+			strings.Join(candidate.Reasons, ","), candidate.Name)
+	}
+	fmt.Printf("\nTotal: %d / %d torrents, size %s; recoverable disk space: %s\n", len(report.Torrents),
+		len(torrents), util.BytesSize(float64(report.Size)), util.BytesSize(float64(report.Recoverable)))
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -31,11 +32,12 @@ const PUBLISHED_TORRENT_FILENAME = ".%s.torrent"
 const COVER = "cover"
 
 var command = &cobra.Command{
-	Use:   "publish --site {site} {--content-path {content-path} | --save-path {save-path} } --client {client}",
-	Short: "Publish (upload) torrent to site.",
-	Long:  `Publish (upload) torrent to site.`,
-	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
-	RunE:  publish,
+	Use:         "publish --site {site} {--content-path {content-path} | --save-path {save-path} } --client {client}",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "publish"},
+	Short:       "Publish (upload) torrent to site.",
+	Long:        `Publish (upload) torrent to site.`,
+	Args:        cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE:        publish,
 }
 
 var (
@@ -48,7 +50,6 @@ var (
 )
 
 var (
-	dryRun            = false
 	checkExisting     = false
 	showJson          = false
 	maxTorrents       = int64(0)
@@ -65,7 +66,7 @@ var (
 )
 
 func init() {
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually upload torrent to site")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Do NOT actually upload torrent to site")
 	command.Flags().BoolVarP(&checkExisting, "check-existing", "", false,
 		"Check whether same contents torrent already exists in site before publishing")
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
@@ -150,7 +151,7 @@ func publish(cmd *cobra.Command, args []string) (err error) {
 
 	if contentPath != "" {
 		id, err := publicTorrent(siteInstance, clientInstance,
-			contentPath, metaValues, false, checkExisting, savePathMapper, minTorrentSize, imageFiles, dryRun)
+			contentPath, metaValues, false, checkExisting, savePathMapper, minTorrentSize, imageFiles, flags.DryRun)
 		ok, _ := printResult(contentPath, id, err, sitename, clientname)
 		if !ok {
 			return err
@@ -160,6 +161,8 @@ func publish(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	errorCnt := int64(0)
+	total := int64(0)
+	var lastErr error
 	cntHandled := int64(0)
 	entries, err := os.ReadDir(savePath)
 	if err != nil {
@@ -170,11 +173,13 @@ func publish(cmd *cobra.Command, args []string) (err error) {
 			continue
 		}
 		contentPath := filepath.Join(savePath, entry.Name())
+		total++
 		id, err := publicTorrent(siteInstance, clientInstance,
-			contentPath, metaValues, true, checkExisting, savePathMapper, minTorrentSize, imageFiles, dryRun)
+			contentPath, metaValues, true, checkExisting, savePathMapper, minTorrentSize, imageFiles, flags.DryRun)
 		ok, published := printResult(contentPath, id, err, sitename, clientname)
 		if !ok {
 			errorCnt++
+			lastErr = err
 		}
 		if !ok || published {
 			cntHandled++
@@ -183,10 +188,7 @@ func publish(cmd *cobra.Command, args []string) (err error) {
 			break
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, total, lastErr)
 }
 
 // Read a yaml front matter style metafile. E.g.:
@@ -357,7 +359,7 @@ func publicTorrent(siteInstance site.Site, clientInstance client.Client, content
 	if err != nil {
 		return "", fmt.Errorf("failed to parse torrent: %w", err)
 	}
-	if ts, err := tinfo.Verify("", contentPath, 0); err != nil {
+	if ts, err := tinfo.Verify("", contentPath, 0, nil); err != nil {
 		return "", fmt.Errorf("content-path is NOT consistent with existing .torrent file")
 	} else if ts > torrentStat.ModTime().Unix() {
 		return "", fmt.Errorf("content-path files modification time is newer than existing .torrent file")
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "queue {client} [--category category] [--tag tag] [--filter filter] [--all]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "queue"},
+	Short:       "Show the queue settings and current queue order of torrents in client.",
+	Long: `Show the queue settings and current queue order of torrents in client.
+It displays the queueing settings of client (queueing_enabled, max_active_downloads, max_active_uploads),
+then the queued torrents in queue order (the "Queue" column is the position in queue, 1 is the top).
+If --all flag is set, the torrents not in queue are also displayed (after the queued ones).
+
+In qBittorrent, only the downloading torrents are queued, and only if queueing is enabled.
+In Transmission, all torrents have a position in the queue.
+
+To change queue settings, use "ptool clientctl", e.g. "ptool clientctl local max_active_downloads=3".
+To move a torrent in queue, use "ptool torrentctl", e.g. "ptool torrentctl local <infoHash> queue_position=top".`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: queue,
+}
+
+var (
+	showAll  = false
+	category = ""
+	tag      = ""
+	filter   = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showAll, "all", "a", false, "Also show torrents that are not in queue")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func queue(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	for _, name := range []string{"queueing_enabled", "max_active_downloads", "max_active_uploads"} {
+		value, err := clientInstance.GetConfig(name)
+		if err != nil {
+			log.Warnf("Failed to get client config %s: %v", name, err)
+			value = "?"
+		}
+		fmt.Printf("%s=%s\n", name, value)
+	}
+	fmt.Printf("\n")
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter)
+	if err != nil {
+		return err
+	}
+	if !showAll {
+		torrents = util.Filter(torrents, func(torrent *client.Torrent) bool {
+			return torrent.QueuePosition > 0
+		})
+	}
+	sort.SliceStable(torrents, func(i, j int) bool {
+		a, b := torrents[i].QueuePosition, torrents[j].QueuePosition
+		if a <= 0 || b <= 0 {
+			return a > 0 && b <= 0
+		}
+		return a < b
+	})
+	client.PrintTorrentsInColumns(os.Stdout, torrents, "", 1, false,
+		[]string{"queue", "name", "infohash", "size", "state", "dlspeed", "upspeed"}, "")
+	return nil
+}
@@ -0,0 +1,346 @@
+package ratioplan
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/stats"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// Days of recent brush traffic (in stats file) used to estimate the upload speed of site.
+const RECENT_DAYS = 7
+
+// Site freeleech torrents with less discount remaining time (seconds) than this are not recommended.
+const MIN_FREE_TIME = 3600
+
+var command = &cobra.Command{
+	Use:         "ratioplan {site | group}... [--target ratio] [--deadline time] [--client client] [--apply]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "ratioplan"},
+	Short:       "Plan the upload needed to reach target ratio of sites by a deadline.",
+	Long: `Plan the upload needed to reach target ratio of sites by a deadline.
+For each site, it reads the current uploaded / downloaded of user from site, and the target ratio
+("targetRatio" of site config, or --target flag), then calculates the upload needed to reach the target.
+It estimates the upload till the deadline by the average daily brush upload of site in recent ` +
+		fmt.Sprint(RECENT_DAYS) + ` days
+(from the "` + config.STATS_FILENAME + `" stats file, requires "brushEnableStats = true"), or by the current
+upload speed of site torrents in --client if no stats available. If the projected upload is not enough, it recommends:
+* Keep seeding: the site torrents in --client that are currently uploading (or have leechers),
+  in upload speed order. These torrents should NOT be removed before the deadline.
+* Grab freeleech: the free torrents of site latest torrents, in estimated upload order,
+  until the shortfall is covered. The estimated upload of a torrent is size * leechers / (seeders + 1),
+  which is only a rough reference.
+* Exchange bonus points for upload credit by "ptool bonus".
+
+With --apply flag (requires --client), it automatically adds the --keep-tag tag (default "keep") to the
+keep seeding torrents (which can be excluded by "excludedTags" of autoremove strategies),
+and adds the recommended freeleech torrents to client.
+
+Examples:
+  ptool ratioplan mysite --target 2 --deadline 14d --client local
+  ptool ratioplan mysite --client local --apply`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: ratioplan,
+}
+
+var (
+	apply       = false
+	addPaused   = false
+	target      = float64(0)
+	maxGrab     = int64(0)
+	deadline    = ""
+	clientName  = ""
+	keepTag     = ""
+	addCategory = ""
+	addTags     = ""
+	statsFile   = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&apply, "apply", "", false,
+		"Tag the keep seeding torrents and add the recommended freeleech torrents to client")
+	command.Flags().BoolVarP(&addPaused, "add-paused", "", false,
+		"Used with --apply. Add torrents to client in paused state")
+	command.Flags().Float64VarP(&target, "target", "", 0, `Target ratio. Overrides the "targetRatio" of site config`)
+	command.Flags().Int64VarP(&maxGrab, "max-grab", "", 10,
+		"Max count of recommended freeleech torrents of a site. -1 == no limit")
+	command.Flags().StringVarP(&deadline, "deadline", "", "7d",
+		`The deadline to reach target ratio. A time (e.g. "2024-01-01 00:00:00") or a time duration from now (e.g. "7d")`)
+	command.Flags().StringVarP(&clientName, "client", "", "",
+		"The client to find keep seeding torrents and to add freeleech torrents to")
+	command.Flags().StringVarP(&keepTag, "keep-tag", "", "keep", "Used with --apply. The tag of keep seeding torrents")
+	command.Flags().StringVarP(&addCategory, "add-category", "", "",
+		"Used with --apply. Set category of added torrents")
+	command.Flags().StringVarP(&addTags, "add-tags", "", "",
+		"Used with --apply. Add tags to added torrents (comma-separated)")
+	command.Flags().StringVarP(&statsFile, "stats-file", "", "",
+		"Manually specify stats file ("+config.STATS_FILENAME+") path")
+	cmd.RootCmd.AddCommand(command)
+}
+
+// A site freeleech torrent recommended to grab.
+type grabTorrent struct {
+	torrent        *site.Torrent
+	estimateUpload int64
+}
+
+func ratioplan(cmd *cobra.Command, args []string) error {
+	if apply && clientName == "" {
+		return fmt.Errorf("--apply flag must be used with --client")
+	}
+	if target < 0 {
+		return fmt.Errorf("invalid target %g", target)
+	}
+	now := util.Now()
+	deadlineTime, err := util.ParseFutureTime(deadline)
+	if err != nil {
+		if deadlineTime, err = util.ParseTime(deadline, nil); err != nil {
+			return fmt.Errorf("invalid deadline %q: %w", deadline, err)
+		}
+	}
+	if deadlineTime <= now {
+		return fmt.Errorf("deadline %s has passed", util.FormatTime(deadlineTime))
+	}
+	var statDb *stats.StatDb
+	if config.Get().BrushEnableStats {
+		if statsFile == "" {
+			statsFile = filepath.Join(config.ConfigDir, config.STATS_FILENAME)
+		}
+		if statDb, err = stats.NewDb(statsFile); err != nil {
+			return fmt.Errorf("failed to create stats db: %w", err)
+		}
+	}
+	var clientInstance client.Client
+	var clientTorrents []*client.Torrent
+	if clientName != "" {
+		if clientInstance, err = client.CreateClient(clientName); err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		if clientTorrents, err = clientInstance.GetTorrents("", "", true); err != nil {
+			return fmt.Errorf("failed to get client torrents: %w", err)
+		}
+	}
+	domainSiteMap := map[string]string{}
+	errorCnt := int64(0)
+	for i, sitename := range config.ParseGroupAndOtherNames(args...) {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			log.Errorf("Failed to create site %s: %v", sitename, err)
+			errorCnt++
+			continue
+		}
+		siteTarget := target
+		if siteTarget == 0 {
+			siteTarget = siteInstance.GetSiteConfig().TargetRatio
+		}
+		if siteTarget <= 0 {
+			log.Errorf("Site %s: no target ratio (targetRatio config or --target flag) set", sitename)
+			errorCnt++
+			continue
+		}
+		status, err := siteInstance.GetStatus()
+		if err != nil {
+			log.Errorf("Failed to get site %s status: %v", sitename, err)
+			errorCnt++
+			continue
+		}
+		ratio := float64(0)
+		if status.UserDownloaded > 0 {
+			ratio = float64(status.UserUploaded) / float64(status.UserDownloaded)
+		}
+		fmt.Printf("Site %s: ↑%s ↓%s, ratio %.2f; target %g by %s (%s later)\n", siteInstance.GetName(),
+			util.BytesSize(float64(status.UserUploaded)), util.BytesSize(float64(status.UserDownloaded)),
+			ratio, siteTarget, util.FormatTime(deadlineTime), util.GetDurationString(deadlineTime-now))
+		need := int64(siteTarget*float64(status.UserDownloaded)) - status.UserUploaded
+		if need <= 0 {
+			fmt.Printf("Target reached.\n")
+			continue
+		}
+
+		// site torrents in client
+		var siteTorrents []*client.Torrent
+		uploadSpeed := int64(0)
+		for _, torrent := range clientTorrents {
+			if common.GetTorrentSite(torrent, domainSiteMap) == siteInstance.GetName() {
+				siteTorrents = append(siteTorrents, torrent)
+				uploadSpeed += torrent.UploadSpeed
+			}
+		}
+		dailyUpload := int64(0)
+		if statDb != nil {
+			traffic := statDb.GetSiteTraffic("", siteInstance.GetName(), util.FormatDate(now-86400*RECENT_DAYS))
+			dailyUpload = traffic.Uploaded / RECENT_DAYS
+			fmt.Printf("Recent %dd brush upload: %s (%s/d)\n", RECENT_DAYS,
+				util.BytesSize(float64(traffic.Uploaded)), util.BytesSize(float64(dailyUpload)))
+		}
+		if dailyUpload == 0 && clientInstance != nil {
+			dailyUpload = uploadSpeed * 86400
+			fmt.Printf("Current upload speed of %d site torrents in client %s: %s/s\n", len(siteTorrents),
+				clientInstance.GetName(), util.BytesSize(float64(uploadSpeed)))
+		}
+		projected := dailyUpload * (deadlineTime - now) / 86400
+		shortfall := need - projected
+		fmt.Printf("Need upload: %s; Projected upload till deadline: %s", util.BytesSize(float64(need)),
+			util.BytesSize(float64(projected)))
+		if shortfall <= 0 {
+			fmt.Printf(". On track.\n")
+			continue
+		}
+		fmt.Printf("; Shortfall: %s\n", util.BytesSize(float64(shortfall)))
+
+		// keep seeding
+		keepTorrents := util.Filter(siteTorrents, func(torrent *client.Torrent) bool {
+			return torrent.IsComplete() && (torrent.UploadSpeed > 0 || torrent.Leechers > 0)
+		})
+		sort.SliceStable(keepTorrents, func(i, j int) bool {
+			if keepTorrents[i].UploadSpeed != keepTorrents[j].UploadSpeed {
+				return keepTorrents[i].UploadSpeed > keepTorrents[j].UploadSpeed
+			}
+			return keepTorrents[i].Leechers > keepTorrents[j].Leechers
+		})
+		if clientInstance != nil {
+			fmt.Printf("\nKeep seeding (%d torrents):\n", len(keepTorrents))
+			fmt.Printf("%-40s  %-8s  %-10s  %-8s  %s\n", "InfoHash", "Size", "↑Speed", "Leechers", "Name")
+			for _, torrent := range keepTorrents {
+				fmt.Printf("%-40s  %-8s  %-10s  %-8d  %s\n", torrent.InfoHash, util.BytesSize(float64(torrent.Size)),
+					util.BytesSize(float64(torrent.UploadSpeed))+"/s", torrent.Leechers, torrent.Name)
+			}
+		}
+
+		// grab freeleech
+		latestTorrents, err := siteInstance.GetLatestTorrents(true)
+		if err != nil {
+			log.Errorf("Failed to get site %s latest torrents: %v", sitename, err)
+			errorCnt++
+			continue
+		}
+		grabTorrents := selectGrabTorrents(latestTorrents, clientTorrents, shortfall, now)
+		estimateUpload := int64(0)
+		fmt.Printf("\nGrab freeleech (%d torrents):\n", len(grabTorrents))
+		fmt.Printf("%-10s  %-8s  %-8s  %-8s  %-10s  %s\n", "Id", "Size", "Seeders", "Leechers", "≈Upload", "Name")
+		for _, grab := range grabTorrents {
+			estimateUpload += grab.estimateUpload
+			fmt.Printf("%-10s  %-8s  %-8d  %-8d  %-10s  %s\n", grab.torrent.Id,
+				util.BytesSize(float64(grab.torrent.Size)), grab.torrent.Seeders, grab.torrent.Leechers,
+				util.BytesSize(float64(grab.estimateUpload)), grab.torrent.Name)
+		}
+		if estimateUpload < shortfall {
+			fmt.Printf("\nThe recommended torrents may not cover the shortfall. "+
+				"Consider exchanging bonus points: ptool bonus %s --exchange upload:%s\n",
+				siteInstance.GetName(), util.BytesSize(float64(shortfall-estimateUpload)))
+		}
+
+		if !apply {
+			continue
+		}
+		if keepTag != "" && len(keepTorrents) > 0 {
+			infoHashes := util.Map(keepTorrents, func(t *client.Torrent) string { return t.InfoHash })
+			if !common.DryRun("add tag %q to %d keep seeding torrents", keepTag, len(infoHashes)) {
+				if err := clientInstance.AddTagsToTorrents(infoHashes, []string{keepTag}); err != nil {
+					log.Errorf("Failed to tag keep seeding torrents: %v", err)
+					errorCnt++
+				}
+			}
+		}
+		errorCnt += addGrabTorrents(clientInstance, siteInstance, grabTorrents)
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
+	return nil
+}
+
+// Select the freeleech torrents which are not in client, in estimated upload order,
+// until the shortfall is covered or --max-grab reached.
+func selectGrabTorrents(torrents []*site.Torrent, clientTorrents []*client.Torrent, shortfall int64,
+	now int64) (grabTorrents []*grabTorrent) {
+	existing := map[string]bool{}
+	for _, torrent := range clientTorrents {
+		existing[torrent.InfoHash] = true
+	}
+	for _, torrent := range torrents {
+		if torrent.DownloadMultiplier != 0 || torrent.Paid && !torrent.Bought || torrent.HasHnR ||
+			torrent.IsActive || torrent.InfoHash != "" && existing[torrent.InfoHash] ||
+			torrent.DiscountEndTime > 0 && torrent.DiscountEndTime < now+MIN_FREE_TIME {
+			continue
+		}
+		grabTorrents = append(grabTorrents, &grabTorrent{
+			torrent:        torrent,
+			estimateUpload: torrent.Size * torrent.Leechers / (torrent.Seeders + 1),
+		})
+	}
+	sort.SliceStable(grabTorrents, func(i, j int) bool {
+		return grabTorrents[i].estimateUpload > grabTorrents[j].estimateUpload
+	})
+	estimateUpload := int64(0)
+	for i, grab := range grabTorrents {
+		if estimateUpload >= shortfall || maxGrab >= 0 && int64(i) >= maxGrab || grab.estimateUpload == 0 {
+			return grabTorrents[:i]
+		}
+		estimateUpload += grab.estimateUpload
+	}
+	return grabTorrents
+}
+
+// Add the grab torrents to client. Return the count of errors.
+func addGrabTorrents(clientInstance client.Client, siteInstance site.Site, grabTorrents []*grabTorrent) (
+	errorCnt int64) {
+	siteSlots, err := common.LoadSiteSlots(clientInstance)
+	if err != nil {
+		log.Errorf("Failed to get site download slots: %v", err)
+		return 1
+	}
+	tags := []string{client.GenerateTorrentTagFromSite(siteInstance.GetName())}
+	if addTags != "" {
+		tags = append(tags, util.SplitCsv(addTags)...)
+	}
+	for _, grab := range grabTorrents {
+		torrent := grab.torrent
+		if !siteSlots.Acquire(siteInstance.GetName()) {
+			log.Warnf("Site %s has no available download slots (maxDownloadingTorrents) in client. Stop adding",
+				siteInstance.GetName())
+			break
+		}
+		if flags.DryRun {
+			fmt.Printf("Add torrent %s (%s) to client (dry-run)\n", torrent.Id, torrent.Name)
+			continue
+		}
+		downloadUrl := torrent.DownloadUrl
+		if downloadUrl == "" {
+			downloadUrl = torrent.Id
+		}
+		content, _, _, err := siteInstance.DownloadTorrent(downloadUrl)
+		if err == nil {
+			if _, err = torrentutil.ParseTorrent(content); err == nil {
+				err = clientInstance.AddTorrent(content, &client.TorrentOption{
+					Category: addCategory,
+					Tags:     tags,
+					Pause:    addPaused,
+				}, nil)
+			}
+		}
+		if err != nil {
+			siteSlots.Release(siteInstance.GetName())
+			log.Errorf("Failed to add torrent %s (%s) to client: %v", torrent.Id, torrent.Name, err)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("Torrent %s (%s) added to client\n", torrent.Id, torrent.Name)
+	}
+	return errorCnt
+}
@@ -0,0 +1,24 @@
+package ratioplan
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("ratioplan", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex < 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			if info.LastArgFlag == "client" {
+				return suggest.ClientArg(info.MatchingPrefix)
+			}
+			return nil
+		}
+		return suggest.SiteOrGroupArg(info.MatchingPrefix)
+	})
+}
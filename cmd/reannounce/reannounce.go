@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -53,6 +54,9 @@ func reannounce(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, "reannounce") {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.ReannounceAllTorrents()
 		if err != nil {
@@ -8,7 +8,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -65,7 +67,7 @@ func recheck(cmd *cobra.Command, args []string) error {
 		if len(infoHashes) == 0 {
 			return fmt.Errorf("no torrent to recheck")
 		}
-		if force {
+		if force && !flags.DryRun {
 			if err = clientInstance.RecheckTorrents(infoHashes); err != nil {
 				return fmt.Errorf("failed to recheck torrents: %w", err)
 			}
@@ -81,6 +83,10 @@ func recheck(cmd *cobra.Command, args []string) error {
 		log.Infof("No matched torrents found")
 		return nil
 	}
+	infoHashes = util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
+	if common.DryRunTorrents(clientInstance, infoHashes, "recheck") {
+		return nil
+	}
 	if !force {
 		size := int64(0)
 		for _, torrent := range torrents {
@@ -92,6 +98,5 @@ func recheck(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("abort")
 		}
 	}
-	infoHashes = util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
 	return clientInstance.RecheckTorrents(infoHashes)
 }
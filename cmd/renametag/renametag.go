@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/util"
 )
 
@@ -41,6 +42,10 @@ func renametag(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to query client torrents of old-tag: %w", err)
 	}
+	if common.DryRun("rename tag %s to %s in client %s (%d torrents)", oldTag, newTag, clientInstance.GetName(),
+		len(torrents)) {
+		return nil
+	}
 	if len(torrents) > 0 {
 		infoHashes := util.Map(torrents, func(t *client.Torrent) string { return t.InfoHash })
 		err = clientInstance.AddTagsToTorrents(infoHashes, []string{newTag})
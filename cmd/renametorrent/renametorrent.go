@@ -0,0 +1,272 @@
+package renametorrent
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "renametorrent {client} [--category category] [--tag tag] [--filter filter] [infoHash]... " +
+		"--replace {pattern=>replacement}... [--scope name,root,files] [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "renametorrent"},
+	Short:       "Rename torrent name, content root folder and files in client using rules.",
+	Long: fmt.Sprintf(`Rename torrent name, content root folder and files in client using rules.
+%s.
+
+Each --replace rule is in "pattern=>replacement" format, where pattern is a regular expression (Go RE2 syntax)
+and replacement can reference capture groups ("$1", "${name}"). The flag can be set multiple times,
+all rules are applied in order. A empty replacement removes the matched part.
+
+The --scope flag (comma-separated list) sets what will be renamed:
+- name : the torrent (display) name in client.
+- root : the content root folder of multi-file torrent, or the file name of single-file torrent.
+- files : the basename of each file in torrent contents. The extension (e.g. ".mkv") is kept untouched.
+
+Renaming content root folder / files actually renames them on disk.
+qBittorrent supports all scopes. Transmission treats torrent name as the root folder / file name,
+so "name" and "root" scopes are equivalent in Transmission.
+
+Examples:
+  # strip release-group prefix, e.g. "[Group] Title" => "Title"
+  ptool renametorrent local _all --replace '^\[[^\]]*\]\s*=>' --dry-run
+  ptool renametorrent local --category anime --replace '\.=> ' --scope files
+
+It will list the renames and ask for confirmation, unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: renametorrent,
+}
+
+var (
+	force    = false
+	category = ""
+	tag      = ""
+	filter   = ""
+	scope    = ""
+	replaces []string
+)
+
+var scopes = []string{"name", "root", "files"}
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Only display the renames, do NOT apply them")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&scope, "scope", "", "name,root",
+		"Comma-separated list of what to rename: "+strings.Join(scopes, ", "))
+	command.Flags().StringArrayVarP(&replaces, "replace", "", nil,
+		`(Required) Rename rule, "pattern=>replacement" format. Can be set multiple times`)
+	command.MarkFlagRequired("replace")
+	cmd.RootCmd.AddCommand(command)
+}
+
+type rule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// A pending rename operation of a torrent.
+type rename struct {
+	torrent  *client.Torrent
+	kind     string // name / root / file
+	oldPath  string
+	newPath  string
+	isFolder bool
+}
+
+func renametorrent(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	rules, err := parseRules(replaces)
+	if err != nil {
+		return err
+	}
+	scopeList := util.SplitCsv(scope)
+	for _, s := range scopeList {
+		if !slices.Contains(scopes, s) {
+			return fmt.Errorf("invalid scope %q", s)
+		}
+	}
+	if len(scopeList) == 0 {
+		return fmt.Errorf("--scope can not be empty")
+	}
+	if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+	isTransmission := clientInstance.GetClientConfig().Type == "transmission"
+
+	errorCnt := int64(0)
+	var lastErr error
+	renames := []*rename{}
+	for _, torrent := range torrents {
+		var root string
+		var rootIsFolder bool
+		var files []*client.TorrentContentFile
+		if slices.Contains(scopeList, "root") || slices.Contains(scopeList, "files") {
+			if files, err = clientInstance.GetTorrentContents(torrent.InfoHash); err != nil {
+				log.Errorf("Failed to get torrent %s contents: %v", torrent.InfoHash, err)
+				errorCnt++
+				lastErr = err
+				continue
+			}
+			root, rootIsFolder = getContentRoot(files)
+		}
+		newRoot := root
+		if slices.Contains(scopeList, "root") && root != "" {
+			if newRoot = applyRules(rules, root); newRoot != root && newRoot != "" {
+				renames = append(renames, &rename{torrent, "root", root, newRoot, rootIsFolder})
+			} else {
+				newRoot = root
+			}
+		}
+		if slices.Contains(scopeList, "name") {
+			newName := applyRules(rules, torrent.Name)
+			// in transmission, torrent name is the root folder / file name
+			skip := isTransmission && newRoot != root && torrent.Name == root
+			if !skip && newName != torrent.Name && newName != "" {
+				renames = append(renames, &rename{torrent, "name", torrent.Name, newName, false})
+			}
+		}
+		// single-file torrent which root file already renamed
+		fileRenamed := root != "" && !rootIsFolder && newRoot != root
+		if slices.Contains(scopeList, "files") && !fileRenamed {
+			for _, file := range files {
+				dir, basename := path.Split(file.Path)
+				if rootIsFolder && newRoot != root {
+					dir = newRoot + strings.TrimPrefix(dir, root)
+				}
+				ext := path.Ext(basename)
+				newBasename := applyRules(rules, strings.TrimSuffix(basename, ext))
+				if newBasename == "" || newBasename+ext == basename {
+					continue
+				}
+				renames = append(renames, &rename{torrent, "file", dir + basename, dir + newBasename + ext, false})
+			}
+		}
+	}
+	if len(renames) == 0 {
+		log.Infof("Nothing to rename")
+		return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+	}
+	for _, r := range renames {
+		fmt.Printf("%s (%s) %s:\n  %s\n  => %s\n", r.torrent.InfoHash, r.torrent.Name, r.kind, r.oldPath, r.newPath)
+	}
+	fmt.Printf("\n")
+	if flags.DryRun {
+		fmt.Printf("Dry run. %d renames would be applied\n", len(renames))
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will apply above %d renames", len(renames))) {
+		return fmt.Errorf("abort")
+	}
+	// the torrents which contents can not be fetched, and the renames
+	total := errorCnt + int64(len(renames))
+	// transmission: infoHash => current file paths of torrents which root folder is renamed,
+	// re-read after the root rename (nil if it failed), as the file renames of these torrents use the new root.
+	renamedFiles := map[string]map[string]bool{}
+	for _, r := range renames {
+		var err error
+		if files, ok := renamedFiles[r.torrent.InfoHash]; ok && r.kind == "file" {
+			if files == nil {
+				err = fmt.Errorf("root folder is not renamed")
+			} else if !files[r.oldPath] {
+				err = fmt.Errorf("file not found in torrent after renaming root folder")
+			}
+		}
+		if err == nil {
+			if r.kind == "name" {
+				err = clientInstance.ModifyTorrent(r.torrent.InfoHash, &client.TorrentOption{Name: r.newPath}, nil)
+			} else {
+				err = clientInstance.RenameTorrentPath(r.torrent.InfoHash, r.oldPath, r.newPath, r.isFolder)
+			}
+		}
+		if isTransmission && r.kind == "root" && r.isFolder {
+			renamedFiles[r.torrent.InfoHash] = nil
+			if err == nil {
+				var files []*client.TorrentContentFile
+				if files, err = clientInstance.GetTorrentContents(r.torrent.InfoHash); err == nil {
+					renamedFiles[r.torrent.InfoHash] = map[string]bool{}
+					for _, file := range files {
+						renamedFiles[r.torrent.InfoHash][file.Path] = true
+					}
+				} else {
+					err = fmt.Errorf("failed to get torrent contents after renaming root folder: %w", err)
+				}
+			}
+		}
+		if err != nil {
+			log.Errorf("Failed to rename torrent %s %s %q: %v", r.torrent.InfoHash, r.kind, r.oldPath, err)
+			errorCnt++
+			lastErr = err
+		}
+	}
+	return common.ItemsError(errorCnt, total, lastErr)
+}
+
+func parseRules(replaces []string) (rules []*rule, err error) {
+	for _, replace := range replaces {
+		pattern, replacement, found := strings.Cut(replace, "=>")
+		if !found || pattern == "" {
+			return nil, fmt.Errorf(`invalid replace rule %q: must be in "pattern=>replacement" format`, replace)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replace rule %q: %w", replace, err)
+		}
+		rules = append(rules, &rule{pattern: re, replacement: replacement})
+	}
+	return rules, nil
+}
+
+func applyRules(rules []*rule, str string) string {
+	for _, rule := range rules {
+		str = rule.pattern.ReplaceAllString(str, rule.replacement)
+	}
+	return strings.TrimSpace(str)
+}
+
+// Return the root folder of multi-file torrent contents, or the file name of single-file torrent.
+// If files do NOT share a common root folder, return empty root.
+func getContentRoot(files []*client.TorrentContentFile) (root string, isFolder bool) {
+	if len(files) == 0 {
+		return "", false
+	}
+	if len(files) == 1 && !strings.Contains(files[0].Path, "/") {
+		return files[0].Path, false
+	}
+	for i, file := range files {
+		top, _, found := strings.Cut(file.Path, "/")
+		if !found || i > 0 && top != root {
+			return "", false
+		}
+		root = top
+	}
+	return root, true
+}
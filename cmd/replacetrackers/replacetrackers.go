@@ -0,0 +1,151 @@
+package replacetrackers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "replacetrackers <client> [--category category] [--tag tag] [--filter filter] [infoHash]... " +
+		"--old {url-or-host-or-regex} --new {url-or-host} [--regex] [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "replacetrackers"},
+	Short:       "Replace tracker url of all matched torrents in client.",
+	Long: fmt.Sprintf(`Replace tracker url of all matched torrents in client.
+Useful when a tracker changed it's domain, or the passkey of site was rotated.
+%s.
+If no infoHash or filter is provided, it iterates all torrents of client.
+
+It checks every tracker of each torrent, a tracker matches if:
+- --old is an url: tracker url equals to it. --new must be an url.
+- --old is a host (hostname[:port]): host of tracker url equals to it.
+  If --new is also a host, only the host part of tracker url is replaced; Otherwise it's replaced with --new url.
+- --regex flag is set: tracker url matches the --old regular expression (Go RE2 syntax).
+  The matched part is replaced with --new, which can reference capture groups ("$1", "${name}").
+
+Examples:
+  ptool replacetrackers <client> --old old-tracker.com --new new-tracker.com
+  ptool replacetrackers <client> --old "https://old.com/announce?passkey=123" --new "https://new.com/announce?passkey=456"
+  ptool replacetrackers <client> --regex --old "passkey=\w+" --new "passkey=123456" --dry-run
+
+It will list the affected torrents and ask for confirmation, unless --force flag is set.`,
+		constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: replacetrackers,
+}
+
+var (
+	force      = false
+	regexMode  = false
+	category   = ""
+	tag        = ""
+	filter     = ""
+	oldTracker = ""
+	newTracker = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&force, "force", "", false, "Force updating trackers. Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only list the affected torrents and trackers, do NOT actually update them")
+	command.Flags().BoolVarP(&regexMode, "regex", "", false,
+		"Regex mode. Treat --old as a regular expression which matches against tracker url")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&oldTracker, "old", "", "", "Set the old tracker url / host / regex")
+	command.Flags().StringVarP(&newTracker, "new", "", "", "Set the new tracker url / host")
+	command.MarkFlagRequired("old")
+	command.MarkFlagRequired("new")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func replacetrackers(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	var oldRegexp *regexp.Regexp
+	if regexMode {
+		var err error
+		if oldRegexp, err = regexp.Compile(oldTracker); err != nil {
+			return fmt.Errorf("invalid --old regexp: %w", err)
+		}
+	} else if util.IsUrl(oldTracker) && !util.IsUrl(newTracker) {
+		return fmt.Errorf("--new MUST be a valid URL ( 'http(s)://...' ) if --old is an URL")
+	}
+	if len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		infoHashes = []string{"_all"}
+	} else if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+	replacements, errorCnt := client.FindTrackerReplacements(clientInstance, torrents,
+		func(torrent *client.Torrent, trackerUrl string) string {
+			return replaceTrackerUrl(trackerUrl, oldTracker, newTracker, oldRegexp)
+		})
+	if len(replacements) == 0 {
+		log.Infof("No matched torrent trackers found")
+		if errorCnt > 0 {
+			return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+		}
+		return nil
+	}
+	client.PrintTrackerReplacements(os.Stdout, replacements)
+	fmt.Printf("\n")
+	if flags.DryRun {
+		fmt.Printf("Dry run. %d trackers would be replaced\n", len(replacements))
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will replace above %d trackers", len(replacements))) {
+		return fmt.Errorf("abort")
+	}
+	errorCnt += client.ApplyTrackerReplacements(clientInstance, replacements)
+	if errorCnt > 0 {
+		return fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+	}
+	return nil
+}
+
+// Return the new url of tracker, or empty string if tracker does not match old.
+func replaceTrackerUrl(trackerUrl string, old string, new string, oldRegexp *regexp.Regexp) string {
+	if oldRegexp != nil {
+		if !oldRegexp.MatchString(trackerUrl) {
+			return ""
+		}
+		return oldRegexp.ReplaceAllString(trackerUrl, new)
+	}
+	if !util.MatchUrlWithHostOrUrl(trackerUrl, old) {
+		return ""
+	}
+	if util.IsUrl(new) {
+		return new
+	}
+	urlObj, err := url.Parse(trackerUrl)
+	if err != nil {
+		return ""
+	}
+	urlObj.Host = new
+	return urlObj.String()
+}
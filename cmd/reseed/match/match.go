@@ -1,6 +1,7 @@
 package match
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/cmd/reseed"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
@@ -126,6 +128,7 @@ func match(cmd *cobra.Command, args []string) error {
 	cntSuccess := int64(0)
 	cntSkip := int64(0)
 	errorCnt := int64(0)
+	var lastErr error
 	siteConsecutiveFails := map[string]int64{}
 	for i, torrent := range torrents {
 		if torrent.Id == "" {
@@ -152,8 +155,9 @@ func match(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			fmt.Printf("✕ download %s (%d/%d): %v\n", torrent, i+1, cntAll, err)
 			errorCnt++
+			lastErr = err
 			if sitename != "" {
-				if !strings.Contains(err.Error(), "status=404") {
+				if !errors.Is(err, constants.ErrNotFound) {
 					siteConsecutiveFails[sitename]++
 					if maxConsecutiveFail >= 0 && siteConsecutiveFails[sitename] == maxConsecutiveFail {
 						log.Errorf("Site %s has consecutively failed (to download torrent) too many times, skip it from now",
@@ -188,6 +192,7 @@ func match(cmd *cobra.Command, args []string) error {
 			if useCommentErr != nil {
 				fmt.Printf("✕ %s (%d/%d): failed to update comment: %v\n", torrent, i+1, cntAll, err)
 				errorCnt++
+				lastErr = useCommentErr
 				continue
 			}
 		}
@@ -195,6 +200,7 @@ func match(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			fmt.Printf("✕ %s (%d/%d): failed to save to %s : %v\n", torrent, i+1, cntAll, downloadDir, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			cntSuccess++
 			fmt.Printf("✓ %s (%d/%d): saved to %s\n", torrent, i+1, cntAll, downloadDir)
@@ -210,8 +216,5 @@ To add downloaded torrents to local client as xseed torrents, run following comm
   ptool xseedadd <local-client> "%s/*.torrent"
 `, cntSuccess, downloadDir, errorCnt, cntSkip, downloadDir)
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
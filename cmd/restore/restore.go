@@ -0,0 +1,284 @@
+package restore
+
+import (
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+var command = &cobra.Command{
+	Use: "restore {client} {filename} [infoHash]... [--skip-preferences] [--skip-check] " +
+		"[--map-save-path backup_save_path|client_save_path]... [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "restore"},
+	Short:       `Restore torrents and settings to client from a backup archive created by "ptool backup".`,
+	Long: `Restore torrents and settings to client from a backup archive created by "ptool backup".
+The target client can be the same client which was backed up, or a different one (even of different type).
+[infoHash]...: only restore these torrents in backup. If not provided, all torrents in backup are restored.
+
+It restores client preferences (unless --skip-preferences flag is set), categories and tags,
+then adds the torrents to client with their original save path, category, tags, speed limits, trackers
+and state (paused or not). Torrents that already exist in client are skipped.
+The torrent content files are NOT included in backup, client will check the existing files in save path
+when adding torrents, unless --skip-check flag is set.
+
+If the target client uses different file system, use "--map-save-path" flag to map save path in backup
+to the one of target client. If it's not set and the backed up client exists in config file,
+save paths are translated using the "pathMappings" of both clients in config file:
+from the backed up client to the file system of ptool, then to the target client.
+
+Examples:
+  ptool restore local local-backup.tar.zst
+  ptool restore remote local-backup.tar.zst --map-save-path "/root/Downloads|/var/Downloads" --dry-run
+
+It will display the backup info and ask for confirmation, unless --force flag is set.
+If it fails partway through, use "ptool journal" to roll back the applied steps or resume it.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: restore,
+}
+
+var (
+	skipPreferences  = false
+	skipCheck        = false
+	force            = false
+	announceInterval = int64(0)
+	mapSavePaths     []string
+)
+
+func init() {
+	command.Flags().BoolVarP(&skipPreferences, "skip-preferences", "", false, "Do NOT restore client preferences")
+	command.Flags().BoolVarP(&skipCheck, "skip-check", "", false, "Skip hash checking when adding torrents")
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only display what would be restored, do NOT actually modify client")
+	command.Flags().Int64VarP(&announceInterval, "announce-interval", "", 0, constants.HELP_ARG_ANNOUNCE_INTERVAL)
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path in backup to the file system of target client. `+
+			`Format: "backup_save_path|client_save_path". `+constants.HELP_ARG_PATH_MAPPERS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func restore(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	filename := args[1]
+	infoHashes := args[2:]
+	var savePathMapper *common.PathMapper
+	if len(mapSavePaths) > 0 {
+		var err error
+		if savePathMapper, err = common.NewPathMapper(mapSavePaths); err != nil {
+			return fmt.Errorf("invalid map-save-path(s): %w", err)
+		}
+	}
+	manifest, torrentContents, err := client.ReadBackup(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	// backup save path => ptool save path => target client save path
+	var sourcePathMapper, targetPathMapper *common.PathMapper
+	if savePathMapper == nil && manifest.Client != clientName && config.GetClientConfig(manifest.Client) != nil {
+		if sourcePathMapper, err = common.GetClientPathMapper(manifest.Client); err != nil {
+			return err
+		}
+		if targetPathMapper, err = common.GetClientPathMapper(clientName); err != nil {
+			return err
+		}
+	}
+	mapSavePath := func(savePath string) string {
+		if savePathMapper != nil {
+			if newSavePath, match := savePathMapper.Before2After(savePath); match {
+				return newSavePath
+			}
+			return savePath
+		}
+		if sourcePathMapper != nil {
+			if newSavePath, match := sourcePathMapper.Before2After(savePath); match {
+				savePath = newSavePath
+			}
+		}
+		if targetPathMapper != nil {
+			if newSavePath, match := targetPathMapper.After2Before(savePath); match {
+				savePath = newSavePath
+			}
+		}
+		return savePath
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	errorCnt := int64(0)
+	backupTorrents := []*client.BackupTorrent{}
+	for _, backupTorrent := range manifest.Torrents {
+		if len(infoHashes) > 0 && !slices.Contains(infoHashes, backupTorrent.InfoHash) {
+			continue
+		}
+		if torrentContents[backupTorrent.InfoHash] == nil {
+			log.Errorf("Torrent %s (%s) .torrent file not found in backup", backupTorrent.InfoHash, backupTorrent.Name)
+			errorCnt++
+			continue
+		}
+		if torrent, err := clientInstance.GetTorrent(backupTorrent.InfoHash); err != nil {
+			log.Errorf("Failed to get torrent %s from client: %v", backupTorrent.InfoHash, err)
+			errorCnt++
+			continue
+		} else if torrent != nil {
+			log.Debugf("Torrent %s (%s) already exists in client, skip it", backupTorrent.InfoHash, backupTorrent.Name)
+			continue
+		}
+		backupTorrents = append(backupTorrents, backupTorrent)
+	}
+	fmt.Printf("Backup of client %s (%s) created at %s: %d torrents, %d categories, %d tags\n",
+		manifest.Client, manifest.ClientType, util.FormatTime(manifest.Time),
+		len(manifest.Torrents), len(manifest.Categories), len(manifest.Tags))
+	if !skipPreferences {
+		for name, value := range manifest.Preferences {
+			if name == "save_path" {
+				value = mapSavePath(value)
+			}
+			fmt.Printf("Preference: %s=%s\n", name, value)
+		}
+	}
+	fmt.Printf("Will add %d torrents to client %s\n", len(backupTorrents), clientName)
+	if flags.DryRun {
+		for _, backupTorrent := range backupTorrents {
+			fmt.Printf("%s (%s) => %s\n", backupTorrent.InfoHash, backupTorrent.Name, mapSavePath(backupTorrent.SavePath))
+		}
+		return nil
+	}
+	if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will restore above backup to client %s", clientName)) {
+		return fmt.Errorf("abort")
+	}
+
+	journal := common.NewJournal("restore")
+	if !skipPreferences {
+		for name, value := range manifest.Preferences {
+			if name == "save_path" {
+				value = mapSavePath(value)
+			}
+			var step *common.JournalStep
+			if oldValue, err := clientInstance.GetConfig(name); err == nil && oldValue != value {
+				step = journal.Begin(&common.JournalStep{
+					Action: common.JOURNAL_SET_CONFIG,
+					Client: clientName,
+					Name:   name,
+					Value:  oldValue,
+				}, nil)
+			}
+			if err := clientInstance.SetConfig(name, value); err != nil {
+				log.Errorf("Failed to set client preference %s=%s: %v", name, value, err)
+				errorCnt++
+			} else {
+				journal.Done(step)
+			}
+		}
+	}
+	// transmission uses labels to simulate categories, which are restored along with torrents
+	if len(manifest.Categories) > 0 && clientInstance.GetClientConfig().Type != "transmission" {
+		categories, err := clientInstance.GetCategories()
+		if err != nil {
+			err = fmt.Errorf("failed to get client categories: %w", err)
+			journal.Finish(err)
+			return err
+		}
+		for _, category := range manifest.Categories {
+			var step *common.JournalStep
+			if !slices.ContainsFunc(categories, func(c *client.TorrentCategory) bool { return c.Name == category.Name }) {
+				step = journal.Begin(&common.JournalStep{
+					Action: common.JOURNAL_MAKE_CATEGORY,
+					Client: clientName,
+					Name:   category.Name,
+				}, nil)
+			}
+			if err := clientInstance.MakeCategory(category.Name, mapSavePath(category.SavePath)); err != nil {
+				log.Errorf("Failed to create category %s: %v", category.Name, err)
+				errorCnt++
+			} else {
+				journal.Done(step)
+			}
+		}
+	}
+	if len(manifest.Tags) > 0 {
+		if tags, err := clientInstance.GetTags(); err != nil {
+			log.Errorf("Failed to get client tags: %v", err)
+			errorCnt++
+		} else if newTags := util.Filter(manifest.Tags, func(tag string) bool {
+			return !slices.Contains(tags, tag)
+		}); len(newTags) > 0 {
+			var steps []*common.JournalStep
+			for _, tag := range newTags {
+				steps = append(steps, journal.Begin(&common.JournalStep{
+					Action: common.JOURNAL_CREATE_TAG,
+					Client: clientName,
+					Name:   tag,
+				}, nil))
+			}
+			if err := clientInstance.CreateTags(newTags...); err != nil {
+				log.Errorf("Failed to create tags: %v", err)
+				errorCnt++
+			} else {
+				journal.Done(steps...)
+			}
+		}
+	}
+
+	cntAll := len(backupTorrents)
+	announcePacer := common.NewAnnouncePacer(announceInterval)
+	for i, backupTorrent := range backupTorrents {
+		content := torrentContents[backupTorrent.InfoHash]
+		option := &client.TorrentOption{
+			Category:           backupTorrent.Category,
+			SavePath:           mapSavePath(backupTorrent.SavePath),
+			Tags:               backupTorrent.Tags,
+			Pause:              backupTorrent.State == "paused",
+			SkipChecking:       skipCheck,
+			SequentialDownload: backupTorrent.SequentialDownload,
+		}
+		if backupTorrent.DownloadSpeedLimit > 0 {
+			option.DownloadSpeedLimit = backupTorrent.DownloadSpeedLimit
+		}
+		if backupTorrent.UploadSpeedLimit > 0 {
+			option.UploadSpeedLimit = backupTorrent.UploadSpeedLimit
+		}
+		if !option.Pause {
+			announcePacer.Wait(backupTorrent.Trackers)
+		}
+		step := journal.Begin(&common.JournalStep{
+			Action:   common.JOURNAL_ADD_TORRENT,
+			Client:   clientName,
+			InfoHash: backupTorrent.InfoHash,
+			Name:     backupTorrent.Name,
+		}, nil)
+		if err := clientInstance.AddTorrent(content, option, backupTorrent.Meta); err != nil {
+			fmt.Printf("✕ %s : failed to add %s: %v (%d/%d)\n", backupTorrent.InfoHash, backupTorrent.Name, err,
+				i+1, cntAll)
+			errorCnt++
+			continue
+		}
+		journal.Done(step)
+		if err := common.SyncTorrentTrackers(clientInstance, backupTorrent.InfoHash, backupTorrent.Trackers, content); err != nil {
+			fmt.Printf("✕ %s : failed to restore trackers: %v (%d/%d)\n", backupTorrent.InfoHash, err, i+1, cntAll)
+			errorCnt++
+			continue
+		}
+		fmt.Printf("✓ %s : restored %s (%d/%d)\n", backupTorrent.InfoHash, backupTorrent.Name, i+1, cntAll)
+	}
+	if errorCnt > 0 {
+		err = fmt.Errorf("%w: %d errors", constants.ErrPartial, errorCnt)
+		journal.Finish(err)
+		return err
+	}
+	journal.End("")
+	journal.Finish(nil)
+	return nil
+}
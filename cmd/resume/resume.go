@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -23,22 +24,28 @@ var command = &cobra.Command{
 }
 
 var (
-	category = ""
-	tag      = ""
-	filter   = ""
+	category    = ""
+	tag         = ""
+	filter      = ""
+	filterFlags = &common.TorrentFilterFlags{}
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	filterFlags.AddFlags(command)
 	cmd.RootCmd.AddCommand(command)
 }
 
 func resume(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	torrentFilter, err := filterFlags.Parse()
+	if err != nil {
+		return err
+	}
+	if category == "" && tag == "" && filter == "" && torrentFilter.IsEmpty() {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -50,10 +57,14 @@ func resume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
+	infoHashes, err = client.SelectTorrentsWithFilter(clientInstance, category, tag, filter, torrentFilter,
+		infoHashes...)
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, "resume") {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.ResumeAllTorrents()
 		if err != nil {
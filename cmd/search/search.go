@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -11,8 +12,11 @@ import (
 
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/metadata"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
 )
 
 type SearchResult struct {
@@ -44,7 +48,17 @@ The "Free" field displays some icon texts:
 The "P" (progress) field also displays some icon texts:
 - If you have never downloaded this torrent before, displays a "-".
 - If you had ever downloaded or seeded this torrent before, display a "✓".
-- If you are currently downloading or seeding this torrent, display a "*%".`,
+- If you are currently downloading or seeding this torrent, display a "*%".
+
+If "--details" flag is set, it fetches the details page of every found torrent (one request per torrent),
+and parses the structured media details (resolution, codecs, subtitles, MediaInfo, Douban / IMDb ids) from it,
+which are included in "--json" output and can be used in "--expr" filter expression.
+
+If "--metadata" flag is set, it resolves the movie / TV show metadata (IMDb / TMDb ids, ratings and year)
+of every found torrent from its name (or IMDb id in details, if "--details" flag is also set),
+using TMDb and / or OMDb api which requires "tmdbApiKey" / "omdbApiKey" in config file.
+Results are cached locally (see "metadataCacheDir" and "metadataCacheTtl" config).
+The metadata can be used in "--expr" filter expression, e.g. 'rating >= 7.5 && year >= 2010'.`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
 	RunE: search,
 }
@@ -63,8 +77,13 @@ var (
 	maxTorrentSizeStr = ""
 	publishedInStr    = ""
 	filter            = ""
+	expr              = ""
+	fetchDetails      = false
+	lookupMetadata    = false
 	includes          = []string{}
 	excludes          = ""
+	noPaid            = false
+	maxBonusCost      = float64(0)
 )
 
 func init() {
@@ -89,6 +108,13 @@ func init() {
 	command.Flags().StringVarP(&publishedInStr, "published-in", "", "",
 		`Time duration. Only showing torrent that was published in the past time of this value. E.g. "30d"`)
 	command.Flags().StringVarP(&filter, "filter", "", "", "Filter search result additionally by title or subtitle")
+	command.Flags().StringVarP(&expr, "expr", "", "", constants.HELP_ARG_SITE_TORRENT_EXPR)
+	command.Flags().BoolVarP(&fetchDetails, "details", "", false,
+		"Fetch and parse the details page of every found torrent. It sends one request per torrent")
+	command.Flags().BoolVarP(&lookupMetadata, "metadata", "", false,
+		"Resolve IMDb / TMDb metadata (ids, ratings and year) of every found torrent")
+	command.Flags().BoolVarP(&noPaid, "no-paid", "", false, "Skip paid (cost bonus points) torrent")
+	command.Flags().Float64VarP(&maxBonusCost, "max-bonus-cost", "", -1, constants.HELP_ARG_MAX_BONUS_COST)
 	command.Flags().StringArrayVarP(&includes, "include", "", nil,
 		"Comma-separated list that ONLY torrent which title or subtitle contains any one in the list will be included. "+
 			"Can be provided multiple times, in which case every list MUST be matched")
@@ -109,6 +135,18 @@ func search(cmd *cobra.Command, args []string) error {
 	if excludes != "" {
 		excludesList = util.SplitCsv(excludes)
 	}
+	var torrentExpr *filterexpr.Expr
+	if expr != "" {
+		var err error
+		if torrentExpr, err = site.ParseTorrentExpr(expr); err != nil {
+			return fmt.Errorf("invalid expr: %w", err)
+		}
+	}
+	if lookupMetadata {
+		if err := metadata.CheckProvider(); err != nil {
+			return err
+		}
+	}
 	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
 	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
 	publishedIn, _ := util.ParseTimeDuration(publishedInStr)
@@ -123,21 +161,19 @@ func search(cmd *cobra.Command, args []string) error {
 		siteInstancesMap[sitename] = siteInstance
 	}
 	now := util.Now()
-	ch := make(chan SearchResult, len(sitenames))
-	for _, sitename := range sitenames {
-		go func(sitename string) {
-			torrents, err := siteInstancesMap[sitename].SearchTorrents(keyword, baseUrl)
-			ch <- SearchResult{sitename, torrents, err}
-		}(sitename)
-	}
+	sitesTorrents, errs := util.ParallelMap(sitenames, config.GetConcurrency(), config.GetItemTimeout(),
+		func(_ context.Context, sitename string) ([]*site.Torrent, error) {
+			return siteInstancesMap[sitename].SearchTorrents(keyword, baseUrl)
+		})
 
 	torrents := []*site.Torrent{}
+	torrentSites := map[*site.Torrent]string{}
 	errorStr := ""
 	cntSuccessSites := int64(0)
 	cntNoResultSites := int64(0)
 	cntErrorSites := int64(0)
-	for i := 0; i < len(sitenames); i++ {
-		searchResult := <-ch
+	for i := range sitenames {
+		searchResult := SearchResult{sitenames[i], sitesTorrents[i], errs[i]}
 		if searchResult.err != nil {
 			cntErrorSites++
 			errorStr += fmt.Sprintf("failed to search site %s: %v", searchResult.site, searchResult.err)
@@ -162,14 +198,53 @@ func search(cmd *cobra.Command, args []string) error {
 					minTorrentSize > 0 && torrent.Size < minTorrentSize ||
 					maxTorrentSize > 0 && torrent.Size > maxTorrentSize ||
 					publishedIn > 0 && now-torrent.Time > publishedIn ||
+					noPaid && torrent.Paid && !torrent.Bought ||
+					maxBonusCost >= 0 && torrent.ExceedsBonusCost(maxBonusCost) ||
 					filter != "" && !torrent.MatchFilter(filter) ||
 					!torrent.MatchFiltersAndOr(includesList) ||
 					torrent.MatchFiltersOr(excludesList) {
 					continue
 				}
 				torrents = append(torrents, torrent)
+				torrentSites[torrent] = searchResult.site
+			}
+		}
+	}
+	if fetchDetails && len(torrents) > 0 {
+		// results are assigned here instead of in fn, which may still run in background after timeout
+		detailsList, errs := util.ParallelMap(torrents, config.GetConcurrency(), config.GetItemTimeout(),
+			func(_ context.Context, torrent *site.Torrent) (*site.TorrentDetails, error) {
+				return siteInstancesMap[torrentSites[torrent]].GetTorrentDetails(torrent.ID())
+			})
+		for i, err := range errs {
+			torrents[i].Details = detailsList[i]
+			if err != nil {
+				errorStr += fmt.Sprintf("failed to get site %s torrent %s details: %v",
+					torrentSites[torrents[i]], torrents[i].ID(), err)
+			}
+		}
+	}
+	if lookupMetadata && len(torrents) > 0 {
+		metadataList, errs := util.ParallelMap(torrents, config.GetConcurrency(), config.GetItemTimeout(),
+			func(_ context.Context, torrent *site.Torrent) (*site.TorrentMetadata, error) {
+				return metadata.Lookup(torrent)
+			})
+		for i, err := range errs {
+			torrents[i].Metadata = metadataList[i]
+			if err != nil {
+				log.Debugf("Failed to get metadata of site %s torrent %s: %v",
+					torrentSites[torrents[i]], torrents[i].ID(), err)
+			}
+		}
+	}
+	if torrentExpr != nil {
+		matchedTorrents := []*site.Torrent{}
+		for _, torrent := range torrents {
+			if torrent.MatchExpr(torrentExpr) {
+				matchedTorrents = append(matchedTorrents, torrent)
 			}
 		}
+		torrents = matchedTorrents
 	}
 	if largestFlag {
 		sort.Slice(torrents, func(i, j int) bool {
@@ -0,0 +1,128 @@
+package selfupdate
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/selfupdate"
+	"github.com/sagan/ptool/version"
+)
+
+var command = &cobra.Command{
+	Use:   "selfupdate [--channel stable|dev] [--check] [--force]",
+	Short: "Update ptool to the latest release on GitHub.",
+	Long: `Update ptool to the latest release on GitHub.
+It gets the latest release of the channel (set by --channel flag, or "selfUpdateChannel" in config file):
+- stable (default): the latest release.
+- dev: the latest release, including prereleases.
+
+If the release is newer than current version, it downloads the release archive of current os & arch,
+verifies its sha256 checksum against the "checksums.txt" of release, then replaces the running ptool binary
+atomically. If "selfUpdatePublicKey" (minisign public key) is set in config file,
+the signature of "checksums.txt" ("checksums.txt.minisig" of release) is also verified.
+
+On Windows, the old binary is renamed to "ptool.exe.old" (it can not be deleted while running),
+which is removed in next update.
+
+It asks for confirmation before updating, unless --force flag is set.
+In dry run mode (--dry-run), the release archive is downloaded and verified, but the binary is not replaced.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: selfupdateCmd,
+}
+
+var (
+	check   = false
+	force   = false
+	channel = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&check, "check", "", false, "Only check whether a new version is available")
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().StringVarP(&channel, "channel", "", "",
+		`Update channel: "stable" or "dev". Default: "selfUpdateChannel" in config file, or "stable"`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func selfupdateCmd(cmd *cobra.Command, args []string) error {
+	if channel == "" {
+		channel = config.Get().SelfUpdateChannel
+	}
+	if channel == "" {
+		channel = selfupdate.CHANNEL_STABLE
+	}
+	if channel != selfupdate.CHANNEL_STABLE && channel != selfupdate.CHANNEL_DEV {
+		return fmt.Errorf("invalid channel %q", channel)
+	}
+	httpClient := &http.Client{
+		Timeout: time.Duration(util.FirstNonZeroIntegerArg(config.Timeout, selfupdate.DEFAULT_TIMEOUT)) * time.Second,
+	}
+	if proxy := config.GetProxy(); proxy != "" && proxy != constants.NONE {
+		transport, err := util.NewProxyTransport(proxy)
+		if err != nil {
+			return fmt.Errorf("failed to create proxy transport: %w", err)
+		}
+		httpClient.Transport = transport
+	}
+	updater, err := selfupdate.New(httpClient, config.Get().SelfUpdatePublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid selfUpdatePublicKey: %w", err)
+	}
+	release, err := updater.GetLatestRelease(channel)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Current version: %s (%s/%s)\n", version.Version, runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("Latest %s release: %s (published at %s) %s\n",
+		channel, release.TagName, release.PublishedAt, release.HtmlUrl)
+	if selfupdate.CompareVersion(release.TagName, version.Version) <= 0 {
+		fmt.Printf("ptool is up to date\n")
+		return nil
+	}
+	assetName := selfupdate.AssetName(release.TagName, runtime.GOOS, runtime.GOARCH)
+	if release.GetAsset(assetName) == nil {
+		return fmt.Errorf("release %s does not have a build for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	if check {
+		fmt.Printf("New version available: %s\n", release.TagName)
+		return nil
+	}
+	target, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate ptool binary: %w", err)
+	}
+	if target, err = filepath.EvalSymlinks(target); err != nil {
+		return fmt.Errorf("failed to locate ptool binary: %w", err)
+	}
+	if !force && !flags.DryRun && !helper.AskYesNoConfirm(fmt.Sprintf("Will update %s from %s to %s",
+		target, version.Version, release.TagName)) {
+		return fmt.Errorf("abort")
+	}
+	log.Infof("Downloading %s", assetName)
+	contents, err := updater.Download(release, assetName)
+	if err != nil {
+		return err
+	}
+	if common.DryRun("replace ptool binary %s with %s (%d bytes)", target, release.TagName, len(contents)) {
+		return nil
+	}
+	if err = selfupdate.Apply(contents, target); err != nil {
+		return fmt.Errorf("failed to replace ptool binary %s: %w", target, err)
+	}
+	fmt.Printf("Updated ptool to %s\n", release.TagName)
+	return nil
+}
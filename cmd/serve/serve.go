@@ -0,0 +1,65 @@
+package serve
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+)
+
+const DEFAULT_LISTEN = "127.0.0.1:9118"
+
+var command = &cobra.Command{
+	Use:   "serve --torznab [--listen address] [--apikey key]",
+	Short: "Run a http server that exposes ptool functions as web services.",
+	Long: `Run a http server that exposes ptool functions as web services.
+Currently the only supported service is Torznab (enabled by --torznab flag).
+
+Torznab service exposes every configured site as a Torznab indexer, so Sonarr / Radarr / etc. can
+search the site through ptool, which handles site cookie, impersonation, proxy and rate limiter.
+The Torznab url of a site is: http://<listen>/torznab/<site>/api . Use --apikey value as the API key.
+The torrent download links in results also point to ptool and are only valid for torrents of that site.
+
+Config file changes are detected and reloaded automatically before handling next request.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: serve,
+}
+
+var (
+	enableTorznab = false
+	listen        = ""
+	apikey        = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&enableTorznab, "torznab", "", false, "Enable Torznab service")
+	command.Flags().StringVarP(&listen, "listen", "", DEFAULT_LISTEN, "Listen address of http server")
+	command.Flags().StringVarP(&apikey, "apikey", "", "",
+		"API key that clients must provide. If not set, no authentication is required")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func serve(cmd *cobra.Command, args []string) error {
+	if !enableTorznab {
+		return fmt.Errorf("no service enabled, use --torznab flag")
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address: %w", err)
+	}
+	if apikey == "" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			log.Warnf("Server listens on a non-loopback address without --apikey, anyone can access your sites")
+		}
+	}
+	config.WatchConfig()
+	mux := http.NewServeMux()
+	mux.HandleFunc(TORZNAB_PATH_PREFIX, torznabHandler)
+	log.Warnf("Listening on %s. Torznab url: http://%s%s<site>/api", listen, listen, TORZNAB_PATH_PREFIX)
+	return http.ListenAndServe(listen, mux)
+}
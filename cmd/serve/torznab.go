@@ -0,0 +1,324 @@
+package serve
+
+// Torznab server. Spec: https://torznab.github.io/spec-1.3-draft/torznab/Specification-v1.3.html .
+
+import (
+	"crypto/subtle"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/version"
+)
+
+const (
+	TORZNAB_PATH_PREFIX = "/torznab/"
+	// max results count of a response
+	TORZNAB_MAX_LIMIT = 100
+	// category of results, if client does not request any
+	TORZNAB_DEFAULT_CATEGORY = "8000"
+)
+
+// Torznab error codes.
+const (
+	TORZNAB_ERROR_CREDENTIALS   = 100
+	TORZNAB_ERROR_MISSING_PARAM = 200
+	TORZNAB_ERROR_NO_FUNCTION   = 202
+	TORZNAB_ERROR_NO_ITEM       = 300
+	TORZNAB_ERROR_UNKNOWN       = 900
+)
+
+type torznabError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabItem struct {
+	Title       string   `xml:"title"`
+	Guid        string   `xml:"guid"`
+	Link        string   `xml:"link"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Size        int64    `xml:"size"`
+	Description string   `xml:"description,omitempty"`
+	Category    []string `xml:"category"`
+	Enclosure   struct {
+		Url    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	Attrs []*torznabAttr `xml:"torznab:attr"`
+}
+
+type torznabRss struct {
+	XMLName      xml.Name `xml:"rss"`
+	Version      string   `xml:"version,attr"`
+	XmlnsTorznab string   `xml:"xmlns:torznab,attr"`
+	Channel      struct {
+		Title string         `xml:"title"`
+		Items []*torznabItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabSearchType struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type torznabCategory struct {
+	Id   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type torznabCaps struct {
+	XMLName xml.Name `xml:"caps"`
+	Server  struct {
+		Title   string `xml:"title,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"server"`
+	Limits struct {
+		Max     int `xml:"max,attr"`
+		Default int `xml:"default,attr"`
+	} `xml:"limits"`
+	Searching struct {
+		Search      torznabSearchType `xml:"search"`
+		TvSearch    torznabSearchType `xml:"tv-search"`
+		MovieSearch torznabSearchType `xml:"movie-search"`
+	} `xml:"searching"`
+	Categories struct {
+		Categories []*torznabCategory `xml:"category"`
+	} `xml:"categories"`
+}
+
+// Sites do not provide Torznab categories, so only the top level standard ones are declared.
+var torznabCategories = []*torznabCategory{
+	{Id: "2000", Name: "Movies"},
+	{Id: "3000", Name: "Audio"},
+	{Id: "5000", Name: "TV"},
+	{Id: "7000", Name: "Books"},
+	{Id: "8000", Name: "Other"},
+}
+
+// A site instance used by server. Requests to the same site are serialized.
+type serverSite struct {
+	mu       sync.Mutex
+	instance site.Site
+}
+
+var (
+	serverSites   = map[string]*serverSite{}
+	serverSitesMu sync.Mutex
+)
+
+func init() {
+	// drop site instances created from old config, e.g. with expired cookie
+	config.OnReload(func() {
+		serverSitesMu.Lock()
+		defer serverSitesMu.Unlock()
+		serverSites = map[string]*serverSite{}
+	})
+}
+
+// Return the site instance of name, or nil if it's not a configured site.
+func getServerSite(name string) (*serverSite, error) {
+	serverSitesMu.Lock()
+	defer serverSitesMu.Unlock()
+	if serverSites[name] != nil {
+		return serverSites[name], nil
+	}
+	if site.GetConfigSiteReginfo(name) == nil {
+		return nil, nil
+	}
+	siteInstance, err := site.CreateSite(name)
+	if err != nil {
+		return nil, err
+	}
+	serverSites[name] = &serverSite{instance: siteInstance}
+	return serverSites[name], nil
+}
+
+// Handle "/torznab/<site>/api" and "/torznab/<site>/download" requests.
+func torznabHandler(w http.ResponseWriter, r *http.Request) {
+	sitename, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, TORZNAB_PATH_PREFIX), "/")
+	query := r.URL.Query()
+	log.Infof("Torznab request: site=%s, action=%s, t=%s, q=%s", sitename, action, query.Get("t"), query.Get("q"))
+	if apikey != "" && subtle.ConstantTimeCompare([]byte(query.Get("apikey")), []byte(apikey)) != 1 {
+		writeTorznabError(w, http.StatusUnauthorized, TORZNAB_ERROR_CREDENTIALS, "Incorrect user credentials")
+		return
+	}
+	if action != "api" && action != "download" {
+		writeTorznabError(w, http.StatusNotFound, TORZNAB_ERROR_NO_FUNCTION, "No such function")
+		return
+	}
+	config.ReloadIfChanged()
+	serverSite, err := getServerSite(sitename)
+	if err != nil {
+		writeTorznabError(w, http.StatusInternalServerError, TORZNAB_ERROR_UNKNOWN,
+			fmt.Sprintf("failed to create site: %v", err))
+		return
+	}
+	if serverSite == nil {
+		writeTorznabError(w, http.StatusNotFound, TORZNAB_ERROR_NO_ITEM, fmt.Sprintf("site %s not found", sitename))
+		return
+	}
+	serverSite.mu.Lock()
+	defer serverSite.mu.Unlock()
+	if action == "download" {
+		torznabDownload(w, serverSite.instance, query.Get("link"))
+		return
+	}
+	switch query.Get("t") {
+	case "caps":
+		writeTorznabXml(w, http.StatusOK, newTorznabCaps())
+	case "search", "tvsearch", "movie":
+		torznabSearch(w, r, serverSite.instance)
+	case "":
+		writeTorznabError(w, http.StatusBadRequest, TORZNAB_ERROR_MISSING_PARAM, "Missing parameter (t)")
+	default:
+		writeTorznabError(w, http.StatusBadRequest, TORZNAB_ERROR_NO_FUNCTION, "No such function")
+	}
+}
+
+func newTorznabCaps() *torznabCaps {
+	caps := &torznabCaps{}
+	caps.Server.Title = "ptool"
+	caps.Server.Version = version.Version
+	caps.Limits.Max = TORZNAB_MAX_LIMIT
+	caps.Limits.Default = TORZNAB_MAX_LIMIT
+	caps.Searching.Search = torznabSearchType{Available: "yes", SupportedParams: "q"}
+	caps.Searching.TvSearch = torznabSearchType{Available: "yes", SupportedParams: "q,season,ep"}
+	caps.Searching.MovieSearch = torznabSearchType{Available: "yes", SupportedParams: "q"}
+	caps.Categories.Categories = torznabCategories
+	return caps
+}
+
+func torznabSearch(w http.ResponseWriter, r *http.Request, siteInstance site.Site) {
+	query := r.URL.Query()
+	keyword := query.Get("q")
+	if query.Get("t") == "tvsearch" && keyword != "" {
+		if season := util.ParseInt(query.Get("season")); season > 0 {
+			keyword += fmt.Sprintf(" S%02d", season)
+			if ep := util.ParseInt(query.Get("ep")); ep > 0 {
+				keyword += fmt.Sprintf("E%02d", ep)
+			}
+		}
+	}
+	var torrents []*site.Torrent
+	var err error
+	if keyword == "" {
+		// rss feed request of the latest torrents
+		torrents, err = siteInstance.GetLatestTorrents(false)
+	} else {
+		torrents, err = siteInstance.SearchTorrents(keyword, "")
+	}
+	if err != nil {
+		writeTorznabError(w, http.StatusInternalServerError, TORZNAB_ERROR_UNKNOWN, err.Error())
+		return
+	}
+	offset := min(max(util.ParseInt(query.Get("offset")), 0), int64(len(torrents)))
+	limit := util.ParseInt(query.Get("limit"))
+	if limit <= 0 || limit > TORZNAB_MAX_LIMIT {
+		limit = TORZNAB_MAX_LIMIT
+	}
+	torrents = torrents[offset:min(offset+limit, int64(len(torrents)))]
+
+	category := TORZNAB_DEFAULT_CATEGORY
+	if cat, _, _ := strings.Cut(query.Get("cat"), ","); cat != "" {
+		category = cat
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	downloadUrl := fmt.Sprintf("%s://%s%s%s/download?", scheme, r.Host, TORZNAB_PATH_PREFIX, siteInstance.GetName())
+	rss := &torznabRss{Version: "2.0", XmlnsTorznab: "http://torznab.com/schemas/2015/feed"}
+	rss.Channel.Title = siteInstance.GetName()
+	rss.Channel.Items = []*torznabItem{}
+	for _, torrent := range torrents {
+		link := torrent.Id
+		if link == "" {
+			link = torrent.DownloadUrl
+		}
+		params := url.Values{"link": {link}}
+		if apikey != "" {
+			params.Set("apikey", apikey)
+		}
+		item := &torznabItem{
+			Title:       torrent.Name,
+			Guid:        link,
+			Link:        downloadUrl + params.Encode(),
+			Size:        torrent.Size,
+			Description: torrent.Description,
+			Category:    []string{category},
+		}
+		if torrent.Time > 0 {
+			item.PubDate = time.Unix(torrent.Time, 0).Format(time.RFC1123Z)
+		}
+		item.Enclosure.Url = item.Link
+		item.Enclosure.Length = torrent.Size
+		item.Enclosure.Type = "application/x-bittorrent"
+		item.Attrs = []*torznabAttr{
+			{Name: "category", Value: category},
+			{Name: "seeders", Value: fmt.Sprint(torrent.Seeders)},
+			{Name: "peers", Value: fmt.Sprint(torrent.Seeders + torrent.Leechers)},
+			{Name: "grabs", Value: fmt.Sprint(torrent.Snatched)},
+			{Name: "downloadvolumefactor", Value: fmt.Sprint(torrent.DownloadMultiplier)},
+			{Name: "uploadvolumefactor", Value: fmt.Sprint(torrent.UploadMultiplier)},
+		}
+		if torrent.InfoHash != "" {
+			item.Attrs = append(item.Attrs, &torznabAttr{Name: "infohash", Value: torrent.InfoHash})
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+	writeTorznabXml(w, http.StatusOK, rss)
+}
+
+// link is the site torrent id or download url.
+func torznabDownload(w http.ResponseWriter, siteInstance site.Site, link string) {
+	if link == "" {
+		writeTorznabError(w, http.StatusBadRequest, TORZNAB_ERROR_MISSING_PARAM, "Missing parameter (link)")
+		return
+	}
+	// do not send site cookie to other domains
+	if util.IsUrl(link) && !config.MatchSite(util.GetUrlDomain(link), siteInstance.GetSiteConfig()) {
+		writeTorznabError(w, http.StatusForbidden, TORZNAB_ERROR_NO_ITEM, "link does not belong to this site")
+		return
+	}
+	content, filename, _, err := siteInstance.DownloadTorrent(link)
+	if err != nil {
+		writeTorznabError(w, http.StatusInternalServerError, TORZNAB_ERROR_UNKNOWN, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-bittorrent")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Write(content)
+}
+
+func writeTorznabError(w http.ResponseWriter, status int, code int, description string) {
+	log.Warnf("Torznab error %d: %s", code, description)
+	writeTorznabXml(w, status, &torznabError{Code: code, Description: description})
+}
+
+func writeTorznabXml(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed to write torznab response: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -56,6 +57,9 @@ func setcategory(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, fmt.Sprintf("set category %q of", cat)) {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.SetAllTorrentsCatetory(cat)
 		if err != nil {
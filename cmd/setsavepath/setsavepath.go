@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util/helper"
 )
@@ -54,6 +55,9 @@ func setsavepath(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, fmt.Sprintf("set save path %q of", savePath)) {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.SetAllTorrentsSavePath(savePath)
 		if err != nil {
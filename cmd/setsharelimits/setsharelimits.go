@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
@@ -15,7 +16,7 @@ import (
 var command = &cobra.Command{
 	Use: "setsharelimits {client} [--category category] [--tag tag] [--filter filter] " +
 		"{--ratio-limit limit} {--seeding-time-limit limit} [infoHash]...",
-	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "setcategory"},
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "setsharelimits"},
 	Short:       "Set share limits of torrents in client.",
 	Long: fmt.Sprintf(`Set share limits of torrents in client.
 %s.
@@ -69,6 +70,10 @@ func setsharelimits(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if common.DryRunTorrents(clientInstance, infoHashes, fmt.Sprintf("set share limits (ratio limit = %.2f, seeding time limit = %d) of",
+		ratioLimit, seedingTimeLimit)) {
+		return nil
+	}
 	if infoHashes == nil {
 		err = clientInstance.SetAllTorrentsShareLimits(ratioLimit, seedingTimeLimit)
 		if err != nil {
@@ -11,7 +11,9 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/cmd/shell/suggest"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -184,6 +186,7 @@ var purgeCmd = &cobra.Command{
 If no args provided, the cache of ALL clients and sites will be purged`,
 	RunE: func(command *cobra.Command, args []string) error {
 		errorCnt := int64(0)
+		var lastErr error
 		if len(args) == 0 {
 			client.Purge("")
 			site.Purge("")
@@ -196,13 +199,11 @@ If no args provided, the cache of ALL clients and sites will be purged`,
 				} else {
 					log.Errorf("%s is not a client nor site", name)
 					errorCnt++
+					lastErr = constants.ErrNotFound
 				}
 			}
 		}
-		if errorCnt > 0 {
-			return fmt.Errorf("%d errors", errorCnt)
-		}
-		return nil
+		return common.ItemsError(errorCnt, int64(len(args)), lastErr)
 	},
 }
 
@@ -10,6 +10,7 @@ import (
 	cobraprompt "github.com/stromland/cobra-prompt"
 
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
 	"github.com/sagan/ptool/config"
 )
 
@@ -40,6 +41,7 @@ Ctrl + L	Clear the screen`,
 
 const simpleHelp = `Type "<command> -h" to see full help of any command
 Note client data will be cached in shell, run "purge [client]..." to purge cache
+Config file changes are detected and reloaded automatically before running next command
 Use "exit" or Ctrl + D (in new line) to exit shell
 To disable suggestions panel, add "shellMaxSuggestions = 0" line to the top of ptool.toml config file`
 
@@ -58,7 +60,7 @@ var ptoolPrompt = &cobraprompt.CobraPrompt{
 		prompt.OptionPrefix("> "),
 		prompt.OptionShowCompletionAtStart(),
 	},
-	DynamicSuggestionsFunc: cmd.ShellDynamicSuggestionsFunc,
+	DynamicSuggestionsFunc: suggest.DynamicSuggestions,
 	OnErrorFunc: func(err error) {
 		// error already printed in RootCmd
 		// cmd.RootCmd.PrintErrln(err)
@@ -104,6 +106,7 @@ func shell(command *cobra.Command, args []string) error {
 		fmt.Printf(`For full help of ptool shell, type "shell -h"` + "\n")
 		fmt.Printf(`To mute this message, add "hushshell = true" line to the top of ptool.toml config file` + "\n")
 	}
+	config.WatchConfig()
 	ptoolPrompt.Run()
 	return nil
 }
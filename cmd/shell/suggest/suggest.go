@@ -2,6 +2,7 @@ package suggest
 
 import (
 	"os"
+	"slices"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -41,7 +42,8 @@ func DirArg(prefix string) []prompt.Suggest {
 	return FileArg(prefix, "", true)
 }
 
-// any dir is included as it may be an intermediate dir
+// Suggest the files (which name has the suffix, if not empty) and dirs in the dir of prefix that match prefix.
+// Dirs are always suggested regardless of suffix as they may be intermediate dirs.
 func FileArg(prefix string, suffix string, dirOnly bool) []prompt.Suggest {
 	dirprefix := ""
 	dir := "."
@@ -63,7 +65,7 @@ func FileArg(prefix string, suffix string, dirOnly bool) []prompt.Suggest {
 		if !file.IsDir() && suffix != "" && !strings.HasSuffix(file.Name(), suffix) {
 			continue
 		}
-		if file.IsDir() || strings.HasPrefix(file.Name(), prefix) {
+		if strings.HasPrefix(file.Name(), prefix) {
 			desc := ""
 			if file.IsDir() {
 				desc = "<dir>"
@@ -146,6 +148,58 @@ func SiteOrGroupArg(prefix string) []prompt.Suggest {
 	return suggestions
 }
 
+// Names of flags which value is a site / client name or comma-separated site names. Common in all commands.
+var (
+	siteFlags     = []string{"site"}
+	clientFlags   = []string{"client", "add-client", "map-client"}
+	siteListFlags = []string{"include-sites", "exclude-sites"}
+)
+
+// The dynamic suggestions func of shell. It returns the suggestions of command registered by cmd.AddShellCompletion;
+// if none, it suggests the value of common site / client flags (see FlagArg).
+// For the "--name=value" style flag input, the "--name=" part is kept in the suggestions.
+func DynamicSuggestions(name string, document *prompt.Document) []prompt.Suggest {
+	suggestions := cmd.ShellDynamicSuggestionsFunc(name, document)
+	info := Parse(document)
+	if len(suggestions) == 0 && info.LastArgIsFlag {
+		suggestions = FlagArg(info.LastArgFlag, info.MatchingPrefix)
+	}
+	if word := document.GetWordBeforeCursor(); info.LastArgIsFlag && strings.HasPrefix(word, "-") {
+		if flagPart, _, found := strings.Cut(word, "="); found {
+			for i := range suggestions {
+				suggestions[i].Text = flagPart + "=" + suggestions[i].Text
+			}
+		}
+	}
+	return suggestions
+}
+
+// Suggest the value of common site / client flags (e.g. "--site", "--add-client"). Return nil for other flags.
+func FlagArg(flag string, prefix string) []prompt.Suggest {
+	switch {
+	case slices.Contains(siteFlags, flag):
+		return SiteArg(prefix)
+	case slices.Contains(clientFlags, flag):
+		return ClientArg(prefix)
+	case slices.Contains(siteListFlags, flag):
+		return SiteListArg(prefix)
+	}
+	return nil
+}
+
+// Suggest the last site name of comma-separated site names, e.g. "mteam,hd" => "mteam,hdsky".
+func SiteListArg(prefix string) []prompt.Suggest {
+	listPrefix := ""
+	if i := strings.LastIndex(prefix, ","); i != -1 {
+		listPrefix, prefix = prefix[:i+1], prefix[i+1:]
+	}
+	suggestions := SiteArg(prefix)
+	for i := range suggestions {
+		suggestions[i].Text = listPrefix + suggestions[i].Text
+	}
+	return suggestions
+}
+
 // parse current inputing ptool command (from start to cursor).
 // everything after cursor is ignored.
 // this func requires external info to discriminate whether a flag is pure or not
@@ -275,6 +329,10 @@ func InfoHashOrFilterArg(prefix string, clientName string) []prompt.Suggest {
 	return InfoHashArg(prefix, clientName)
 }
 
+// Suggest the infohashes of client torrents which infohash or name (case-insensitive) starts with prefix.
+// Torrents whose infohash matches are suggested first.
+// It only suggests if torrents of client are already cached (e.g. by a previous command),
+// a network fetch would freeze the prompt.
 func InfoHashArg(prefix string, clientName string) []prompt.Suggest {
 	if clientName == "" {
 		return nil
@@ -291,14 +349,18 @@ func InfoHashArg(prefix string, clientName string) []prompt.Suggest {
 		return nil
 	}
 	suggestions := []prompt.Suggest{}
+	nameSuggestions := []prompt.Suggest{}
+	lowerPrefix := strings.ToLower(prefix)
 	for _, torrent := range torrents {
-		if !strings.HasPrefix(torrent.InfoHash, prefix) {
-			continue
-		}
-		suggestions = append(suggestions, prompt.Suggest{
+		suggestion := prompt.Suggest{
 			Text:        torrent.InfoHash,
 			Description: torrent.StateIconText() + " " + torrent.Name,
-		})
+		}
+		if strings.HasPrefix(torrent.InfoHash, lowerPrefix) {
+			suggestions = append(suggestions, suggestion)
+		} else if strings.HasPrefix(strings.ToLower(torrent.Name), lowerPrefix) {
+			nameSuggestions = append(nameSuggestions, suggestion)
+		}
 	}
-	return suggestions
+	return append(suggestions, nameSuggestions...)
 }
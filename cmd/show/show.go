@@ -16,6 +16,7 @@ import (
 	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
 )
 
 var command = &cobra.Command{
@@ -34,6 +35,14 @@ The "Name" field by default displays the truncated prefix of the torrent name in
 If "--dense" flag is set, it will instead display the full name of the torrent,
 as well as it's category & tags & content path infos.
 
+Use "--columns" flag to select the displayed fields, e.g. "--columns name,size,ratio,tracker,category,added".
+Available fields: name, infohash, size, state, dlspeed, upspeed, seeds, peers, tracker, ratio,
+uploaded, downloaded, category, tags, added, completed, activity, savepath, queue.
+Use "--narrow" flag to display less fields which fit in small terminal width;
+use "--wide" flag to display more fields (ratio, category, added) and the untruncated name.
+
+Use "--page-size" & "--page" flags to display only one page of (sorted) torrents list.
+
 The "State" field displays some icon texts:
 * ✓ : Torrent is downloaded completely (finished).
 * - : Torrent is paused and incomplete (unfinished).
@@ -72,11 +81,17 @@ var (
 	maxTorrentSizeStr  = ""
 	maxTotalSizeStr    = ""
 	excludes           = ""
+	expr               = ""
 	dense              = false
 	showAll            = false
 	showRaw            = false
 	showJson           = false
 	showSum            = false
+	wide               = false
+	narrow             = false
+	columns            = ""
+	page               = int64(0)
+	pageSize           = int64(0)
 	sortFlag           string
 	orderFlag          string
 )
@@ -123,8 +138,16 @@ func init() {
 		"Skip torrent with size smaller than (<) this value. -1 == no limit")
 	command.Flags().StringVarP(&maxTorrentSizeStr, "max-torrent-size", "", "-1",
 		"Skip torrent with size larger than (>) this value. -1 == no limit")
+	command.Flags().StringVarP(&expr, "expr", "", "", constants.HELP_ARG_EXPR)
 	command.Flags().StringVarP(&excludes, "exclude", "", "",
 		"Comma-separated list that torrent which name contains any one in the list will be skipped")
+	command.Flags().BoolVarP(&wide, "wide", "", false, "Wide layout: show more fields and untruncated name")
+	command.Flags().BoolVarP(&narrow, "narrow", "", false, "Narrow layout: show less fields")
+	command.Flags().StringVarP(&columns, "columns", "", "",
+		`Comma-separated displayed fields of torrents list, e.g. "name,size,ratio,tracker,category,added"`)
+	command.Flags().Int64VarP(&page, "page", "", 1, `Display the page of torrents list. Requires "--page-size"`)
+	command.Flags().Int64VarP(&pageSize, "page-size", "", -1,
+		"Display at most this number of torrents per page. -1 == no limit")
 	cmd.AddEnumFlagP(command, &sortFlag, "sort", "", common.ClientTorrentSortFlag)
 	cmd.AddEnumFlagP(command, &orderFlag, "order", "", common.OrderFlag)
 	cmd.RootCmd.AddCommand(command)
@@ -149,6 +172,22 @@ func show(cmd *cobra.Command, args []string) error {
 	if largestFlag && newestFlag {
 		return fmt.Errorf("--largest and --newest flags are NOT compatible")
 	}
+	if wide && narrow {
+		return fmt.Errorf("--wide and --narrow flags are NOT compatible")
+	}
+	if page < 1 {
+		return fmt.Errorf("invalid page: %d", page)
+	}
+	layout := ""
+	if wide {
+		layout = client.TORRENTS_LAYOUT_WIDE
+	} else if narrow {
+		layout = client.TORRENTS_LAYOUT_NARROW
+	}
+	torrentColumns, err := client.ParseTorrentColumns(columns, layout)
+	if err != nil {
+		return err
+	}
 	if largestFlag {
 		sortFlag = "size"
 		orderFlag = "desc"
@@ -208,10 +247,16 @@ func show(cmd *cobra.Command, args []string) error {
 	if excludes != "" {
 		excludesList = util.SplitCsv(excludes)
 	}
+	var torrentExpr *filterexpr.Expr
+	if expr != "" {
+		if torrentExpr, err = client.ParseTorrentExpr(expr); err != nil {
+			return fmt.Errorf("invalid expr: %w", err)
+		}
+	}
 
 	hasFilterCondition := savePath != "" || savePathPrefix != "" || contentPath != "" ||
 		tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 || addedAfter > 0 || completedBefore > 0 ||
-		activeSince > 0 || notActiveSince > 0 || partial || excludes != ""
+		activeSince > 0 || notActiveSince > 0 || partial || excludes != "" || torrentExpr != nil
 	noConditionFlags := category == "" && tag == "" && filter == "" && !hasFilterCondition
 	var torrents []*client.Torrent
 	if showAll {
@@ -273,7 +318,8 @@ func show(cmd *cobra.Command, args []string) error {
 				completedBefore > 0 && (t.Ctime <= 0 || t.Ctime >= completedBefore) ||
 				activeSince > 0 && t.ActivityTime < activeSince ||
 				notActiveSince > 0 && t.ActivityTime >= notActiveSince ||
-				partial && t.Size == t.SizeTotal {
+				partial && t.Size == t.SizeTotal ||
+				torrentExpr != nil && !t.MatchExpr(torrentExpr) {
 				return false
 			}
 			return true
@@ -303,6 +349,17 @@ func show(cmd *cobra.Command, args []string) error {
 					return torrents[i].TrackerDomain < torrents[j].TrackerDomain
 				}
 				return torrents[i].Atime < torrents[j].Atime
+			case "ratio":
+				return torrents[i].Ratio() < torrents[j].Ratio()
+			case "uploaded":
+				return torrents[i].Uploaded < torrents[j].Uploaded
+			case "seeds":
+				return torrents[i].Seeders < torrents[j].Seeders
+			case "category":
+				if torrents[i].Category != torrents[j].Category {
+					return torrents[i].Category < torrents[j].Category
+				}
+				return torrents[i].Atime < torrents[j].Atime
 			}
 			return i < j
 		})
@@ -327,6 +384,10 @@ func show(cmd *cobra.Command, args []string) error {
 		}
 		torrents = torrents[:i]
 	}
+	if pageSize >= 0 {
+		start := min((page-1)*pageSize, int64(len(torrents)))
+		torrents = torrents[start:min(start+pageSize, int64(len(torrents)))]
+	}
 
 	if showJson {
 		return util.PrintJson(os.Stdout, torrents)
@@ -357,7 +418,7 @@ func show(cmd *cobra.Command, args []string) error {
 		if showSum {
 			showSummary = 2
 		}
-		client.PrintTorrents(os.Stdout, torrents, "", showSummary, dense)
+		client.PrintTorrentsInColumns(os.Stdout, torrents, "", showSummary, dense, torrentColumns, layout)
 	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package sitecheck
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "sitecheck [{site | group}...] [--expire-warning duration] [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "sitecheck"},
+	Short:       "Check sites cookie and login status.",
+	Long: `Check sites cookie and login status.
+If no args provided, check all enabled (and not dead) sites.
+
+It fetches user status from each site using current cookie (or other credentials) of site config,
+and reports one of the following status for each site:
+* ok : The cookie is valid.
+* expiring : The cookie is valid, but will expire within --expire-warning duration.
+* login_required : The cookie is invalid or has expired.
+* banned : The user account of site is banned or disabled.
+* down : The site is inaccessible currently (network error or site server error).
+
+The cookie expiration time is read from "cookieExpire" of site config (e.g. "2026-12-31"),
+or the "exp" of JWT (JSON Web Token) values in cookie if any. Otherwise it's unknown.
+
+It exits with error if any site status is not ok. To get notified when site status changes,
+configure [[hooks]] of "site" event and run "ptool watch" command.
+
+Examples:
+  ptool sitecheck
+  ptool sitecheck mysite1 mygroup --expire-warning 3d`,
+	Args: cobra.MatchAll(cobra.ArbitraryArgs, cobra.OnlyValidArgs),
+	RunE: sitecheck,
+}
+
+var (
+	showJson      = false
+	expireWarning = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().StringVarP(&expireWarning, "expire-warning", "", "7d",
+		`Report "expiring" status if cookie will expire within this time`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func sitecheck(cmd *cobra.Command, args []string) error {
+	warning, err := util.ParseTimeDuration(expireWarning)
+	if err != nil {
+		return fmt.Errorf("invalid --expire-warning: %w", err)
+	}
+	var sitenames []string
+	if len(args) > 0 {
+		sitenames = config.ParseGroupAndOtherNames(args...)
+		for _, sitename := range sitenames {
+			if config.GetSiteConfig(sitename) == nil {
+				return fmt.Errorf("site %s not found", sitename)
+			}
+		}
+	}
+	results := site.CheckSites(sitenames, warning)
+	errorCnt := int64(0)
+	for _, result := range results {
+		if !result.IsOk() {
+			errorCnt++
+		}
+	}
+	if showJson {
+		if err := util.PrintJson(os.Stdout, results); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%-15s  %-14s  %-19s  %s\n", "Site", "Status", "CookieExpire", "Message")
+		for _, result := range results {
+			cookieExpire := "-"
+			if result.CookieExpire > 0 {
+				cookieExpire = util.FormatTime(result.CookieExpire)
+			}
+			fmt.Printf("%-15s  %-14s  %-19s  %s\n", result.Site, result.Status, cookieExpire, result.Message)
+		}
+	}
+	if errorCnt > 0 {
+		return fmt.Errorf("%d sites are not ok", errorCnt)
+	}
+	return nil
+}
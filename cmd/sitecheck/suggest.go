@@ -0,0 +1,18 @@
+package sitecheck
+
+import (
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("sitecheck", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIsFlag {
+			return nil
+		}
+		return suggest.SiteOrGroupArg(info.MatchingPrefix)
+	})
+}
@@ -0,0 +1,140 @@
+package sitemsg
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "sitemsg {site} [--all] [--read id] [--mark-read] [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "sitemsg"},
+	Short:       "List or read the private messages in site inbox.",
+	Long: `List or read the private messages (including system notifications) in site inbox.
+Currently only NexusPHP sites are supported.
+
+Without --read flag, it lists the unread messages in inbox (only the first page).
+Use --all flag to list all (read and unread) messages instead.
+Use --mark-read flag to mark the listed unread messages as read after listing them.
+
+Use --read flag to display the contents of a message (by id). Site marks the message as read when it's read.
+
+To get notified of new unread messages, configure [[hooks]] of "message" event and run "ptool watch" command.
+
+Examples:
+  ptool sitemsg mysite
+  ptool sitemsg mysite --all
+  ptool sitemsg mysite --read 123
+  ptool sitemsg mysite --mark-read`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: sitemsg,
+}
+
+var (
+	showAll  = false
+	markRead = false
+	showJson = false
+	readId   = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showAll, "all", "a", false, "List all messages, including read ones")
+	command.Flags().BoolVarP(&markRead, "mark-read", "", false, "Mark listed unread messages as read")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().StringVarP(&readId, "read", "", "", "Read (display the contents of) the message of this id")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func sitemsg(cmd *cobra.Command, args []string) error {
+	sitename := args[0]
+	if readId != "" && (showAll || markRead) {
+		return fmt.Errorf("--read flag is NOT compatible with --all or --mark-read flags")
+	}
+	siteInstance, err := site.CreateSite(sitename)
+	if err != nil {
+		return fmt.Errorf("failed to create site: %w", err)
+	}
+
+	if readId != "" {
+		message, err := siteInstance.GetMessage(readId)
+		if err != nil {
+			return fmt.Errorf("failed to read message %s: %w", readId, err)
+		}
+		if showJson {
+			return util.PrintJson(os.Stdout, message)
+		}
+		fmt.Printf("Subject: %s\n", message.Subject)
+		fmt.Printf("Sender: %s\n", senderName(message))
+		if message.Time > 0 {
+			fmt.Printf("Time: %s\n", util.FormatTime(message.Time))
+		}
+		fmt.Printf("\n%s\n", message.Content)
+		return nil
+	}
+
+	messages, err := siteInstance.GetMessages(showAll)
+	if err != nil {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+	if showJson {
+		if err := util.PrintJson(os.Stdout, messages); err != nil {
+			return err
+		}
+	} else {
+		columns := []*util.TableColumn{
+			{Title: "Id"},
+			{Title: "Time", Width: 19},
+			{Title: "Unread"},
+			{Title: "Sender"},
+			{Title: "Subject"},
+		}
+		var rows [][]string
+		for _, message := range messages {
+			timeStr, unread := "-", ""
+			if message.Time > 0 {
+				timeStr = util.FormatTime(message.Time)
+			}
+			if message.Unread {
+				unread = "*"
+			}
+			rows = append(rows, []string{message.Id, timeStr, unread, senderName(message), message.Subject})
+		}
+		fmt.Printf("Site %s: %d messages\n", sitename, len(messages))
+		util.PrintTable(os.Stdout, columns, rows, 0, false, false)
+	}
+
+	if markRead {
+		var ids []string
+		for _, message := range messages {
+			if message.Unread {
+				ids = append(ids, message.Id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if flags.DryRun {
+			log.Warnf("Dry-run: mark %d messages as read", len(ids))
+			return nil
+		}
+		if err := siteInstance.MarkMessagesRead(ids); err != nil {
+			return fmt.Errorf("failed to mark messages as read: %w", err)
+		}
+		log.Warnf("Marked %d messages as read", len(ids))
+	}
+	return nil
+}
+
+func senderName(message *site.Message) string {
+	if message.Sender == "" {
+		return "<system>"
+	}
+	return message.Sender
+}
@@ -0,0 +1,234 @@
+package speedtest
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+// The result of a client speed test.
+type Result struct {
+	Client          string `json:"client"`
+	InfoHash        string `json:"infoHash"`
+	Name            string `json:"name"`
+	Duration        int64  `json:"duration"`        // seconds
+	Downloaded      int64  `json:"downloaded"`      // downloaded size during test
+	AverageSpeed    int64  `json:"averageSpeed"`    // bytes/s
+	MaxSpeed        int64  `json:"maxSpeed"`        // max download speed reported by client, bytes/s
+	MaxPeers        int64  `json:"maxPeers"`        // max cnt of connected peers
+	Completed       bool   `json:"completed"`       // whether the test torrent finished downloading before timeout
+	TimeToFirstByte int64  `json:"timeToFirstByte"` // seconds before the first byte is downloaded. -1: never
+}
+
+var command = &cobra.Command{
+	Use:         "speedtest {client} [--torrent torrent] [--duration seconds]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "speedtest"},
+	Short:       "Test the download speed of client using a public test torrent.",
+	Long: fmt.Sprintf(`Test the download speed of client using a public test torrent.
+It adds the test torrent to client, measures the download speed for "--duration" seconds
+(or until the torrent finishes downloading), then deletes the torrent and it's downloaded files from client,
+and reports the result. It's useful to validate a new seedbox or VPN setup.
+
+The test torrent could be set by "--torrent" flag, or the "speedtestTorrent" of config file,
+it could be a torrent url, a local .torrent filename or a site torrent id.
+Default test torrent: %s .
+(The default one may become unavailable after a new Ubuntu point release, set "speedtestTorrent" in that case)
+
+The test torrent is downloaded to a dedicated temporary folder ("ptool-speedtest-<time>") inside the
+"--save-path" (or client default save path), so deleting it's files in the end will not touch any other data.
+The test torrent must NOT already exist in client, and it's contents must not already exist in the temporary folder
+(ptool will refuse to test in these cases). Press Ctrl + C to stop the test early, the test torrent is
+also deleted in that case.`, config.DEFAULT_SPEEDTEST_TORRENT),
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: speedtest,
+}
+
+var (
+	showJson = false
+	duration = int64(0)
+	interval = int64(0)
+	torrent  = ""
+	savePath = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show result in json format")
+	command.Flags().Int64VarP(&duration, "duration", "", 60, "Test duration (seconds)")
+	command.Flags().Int64VarP(&interval, "interval", "", 5, "Interval (seconds) of displaying test progress")
+	command.Flags().StringVarP(&torrent, "torrent", "", "",
+		`Test torrent. If not set, use "speedtestTorrent" of config file or the default test torrent`)
+	command.Flags().StringVarP(&savePath, "save-path", "", "",
+		"Set the parent folder of test torrent temporary save path in client. Default is the client default save path")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func speedtest(command *cobra.Command, args []string) error {
+	clientName := args[0]
+	if duration <= 0 || interval <= 0 {
+		return fmt.Errorf("invalid duration or interval")
+	}
+	if torrent == "" {
+		if torrent = config.Get().SpeedtestTorrent; torrent == "" {
+			torrent = config.DEFAULT_SPEEDTEST_TORRENT
+		}
+	}
+	if util.IsPureTorrentUrl(torrent) {
+		return fmt.Errorf("magnet link test torrent is not supported")
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	content, tinfo, _, _, _, _, _, err := helper.GetTorrentContent(torrent, "", false, false, nil, false, nil)
+	if err != nil {
+		if torrent == config.DEFAULT_SPEEDTEST_TORRENT {
+			return fmt.Errorf("failed to get default test torrent (set another one by --torrent flag "+
+				`or "speedtestTorrent" of config file): %w`, err)
+		}
+		return fmt.Errorf("failed to get test torrent: %w", err)
+	}
+	if existing, err := clientInstance.GetTorrent(tinfo.InfoHash); err != nil {
+		return fmt.Errorf("failed to get client torrent: %w", err)
+	} else if existing != nil {
+		return fmt.Errorf("test torrent %s already exists in client, refuse to test", tinfo.InfoHash)
+	}
+	parentSavePath := savePath
+	if parentSavePath == "" {
+		if parentSavePath, err = clientInstance.GetConfig("save_path"); err != nil {
+			return fmt.Errorf("failed to get client default save path: %w", err)
+		} else if parentSavePath == "" {
+			return fmt.Errorf("client default save path is unknown, set --save-path flag")
+		}
+	}
+	testSavePath := util.JoinPath(parentSavePath, fmt.Sprintf("ptool-speedtest-%d", util.Now()))
+	savePathMapper, err := common.GetSavePathMapper(clientName, nil, false)
+	if err != nil {
+		return err
+	}
+	localSavePath := testSavePath
+	if savePathMapper != nil {
+		if mapped, match := savePathMapper.Before2After(testSavePath); match {
+			localSavePath = mapped
+		}
+	}
+	localContentPath := util.JoinPath(localSavePath, tinfo.ContentPath)
+	if _, err := os.Lstat(localContentPath); err == nil || !os.IsNotExist(err) {
+		return fmt.Errorf("contents %q already exists (or can not be accessed: %v), refuse to test",
+			localContentPath, err)
+	}
+	if common.DryRun("add test torrent %s (%s) to client %s with save path %q, then delete it and it's files",
+		tinfo.InfoHash, tinfo.ContentPath, clientName, testSavePath) {
+		return nil
+	}
+	err = clientInstance.AddTorrent(content, &client.TorrentOption{
+		Name:     "ptool-speedtest-" + tinfo.ContentPath,
+		SavePath: testSavePath,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add test torrent to client: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Added test torrent %s (%s, %s) to client (save path: %s). Testing for %d seconds\n",
+		tinfo.InfoHash, tinfo.ContentPath, util.BytesSize(float64(tinfo.Size)), testSavePath, duration)
+	cleanup := func() error {
+		if err := clientInstance.DeleteTorrents([]string{tinfo.InfoHash}, true); err != nil {
+			log.Errorf("Failed to delete test torrent %s from client: %v", tinfo.InfoHash, err)
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Deleted test torrent %s and it's files from client\n", tinfo.InfoHash)
+		// Best effort: remove the (empty) temporary save path folder if it's accessible locally.
+		time.Sleep(time.Second)
+		os.Remove(localSavePath)
+		return nil
+	}
+	sigs := make(chan os.Signal, 1)
+	go func() {
+		sig := <-sigs
+		log.Debugf("Received signal %v", sig)
+		cleanup()
+		cmd.Exit(1)
+	}()
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	result := &Result{
+		Client:          clientName,
+		InfoHash:        tinfo.InfoHash,
+		Name:            tinfo.ContentPath,
+		TimeToFirstByte: -1,
+	}
+	startTime := time.Now()
+	initialCompleted := int64(-1)
+	lastPrintTime := startTime
+	for {
+		time.Sleep(time.Second)
+		elapsed := time.Since(startTime)
+		clientInstance.PurgeCache()
+		clientTorrent, err := clientInstance.GetTorrent(tinfo.InfoHash)
+		if err != nil || clientTorrent == nil {
+			log.Debugf("Failed to get test torrent from client: %v", err)
+		} else {
+			if initialCompleted < 0 {
+				initialCompleted = clientTorrent.SizeCompleted
+			}
+			result.Downloaded = clientTorrent.SizeCompleted - initialCompleted
+			result.MaxSpeed = max(result.MaxSpeed, clientTorrent.DownloadSpeed)
+			if peers, err := clientInstance.GetTorrentPeers(tinfo.InfoHash); err == nil {
+				result.MaxPeers = max(result.MaxPeers, int64(len(peers)))
+			}
+			if result.TimeToFirstByte < 0 && clientTorrent.SizeCompleted > initialCompleted {
+				result.TimeToFirstByte = int64(elapsed.Seconds())
+			}
+			if clientTorrent.IsComplete() {
+				result.Completed = true
+			}
+			if time.Since(lastPrintTime) >= time.Duration(interval)*time.Second {
+				lastPrintTime = time.Now()
+				fmt.Fprintf(os.Stderr, "%3ds: ↓S %s/s, downloaded %s (%.1f%%)\n",
+					int64(elapsed.Seconds()), util.BytesSize(float64(clientTorrent.DownloadSpeed)),
+					util.BytesSize(float64(clientTorrent.SizeCompleted)),
+					float64(clientTorrent.SizeCompleted)*100/float64(max(clientTorrent.Size, 1)))
+			}
+		}
+		if result.Completed || elapsed >= time.Duration(duration)*time.Second {
+			result.Duration = max(int64(elapsed.Seconds()), 1)
+			break
+		}
+	}
+	signal.Stop(sigs)
+	result.AverageSpeed = result.Downloaded / result.Duration
+	cleanupErr := cleanup()
+
+	if showJson {
+		if err := util.PrintJson(os.Stdout, result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Client: %s\n", result.Client)
+		fmt.Printf("Test torrent: %s (%s)\n", result.Name, result.InfoHash)
+		fmt.Printf("Duration: %ds (completed: %t)\n", result.Duration, result.Completed)
+		fmt.Printf("Downloaded: %s\n", util.BytesSize(float64(result.Downloaded)))
+		fmt.Printf("Average speed: %s/s\n", util.BytesSize(float64(result.AverageSpeed)))
+		fmt.Printf("Max speed: %s/s\n", util.BytesSize(float64(result.MaxSpeed)))
+		fmt.Printf("Max connected peers: %d\n", result.MaxPeers)
+		if result.TimeToFirstByte >= 0 {
+			fmt.Printf("Time to first byte: %ds\n", result.TimeToFirstByte)
+		} else {
+			fmt.Printf("Time to first byte: - (nothing downloaded)\n")
+		}
+	}
+	if cleanupErr != nil {
+		return fmt.Errorf("failed to delete test torrent from client: %w", cleanupErr)
+	}
+	return nil
+}
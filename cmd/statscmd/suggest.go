@@ -8,7 +8,7 @@ import (
 )
 
 func init() {
-	cmd.AddShellCompletion("statscmd", func(document *prompt.Document) []prompt.Suggest {
+	cmd.AddShellCompletion("stats", func(document *prompt.Document) []prompt.Suggest {
 		info := suggest.Parse(document)
 		if info.LastArgIndex < 1 {
 			return nil
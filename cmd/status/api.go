@@ -22,15 +22,14 @@ type StatusResponse struct {
 }
 
 func fetchClientStatus(clientInstance client.Client, showTorrents bool, showAllTorrents bool,
-	category string, ch chan *StatusResponse) {
+	category string) *StatusResponse {
 	response := &StatusResponse{Name: clientInstance.GetName(), Kind: 1}
 
 	clientStatus, err := clientInstance.GetStatus()
 	response.ClientStatus = clientStatus
 	if err != nil {
 		response.Error = fmt.Errorf("cann't get client %s status: error=%w", clientInstance.GetName(), err)
-		ch <- response
-		return
+		return response
 	}
 
 	if showTorrents {
@@ -52,22 +51,20 @@ func fetchClientStatus(clientInstance client.Client, showTorrents bool, showAllT
 			response.Error = fmt.Errorf("cann't get client %s torrents: %w", clientInstance.GetName(), err)
 		}
 	}
-	ch <- response
+	return response
 }
 
-func fetchSiteStatus(siteInstance site.Site, showTorrents bool, full bool, showScore bool, ch chan *StatusResponse) {
+func fetchSiteStatus(siteInstance site.Site, showTorrents bool, full bool, showScore bool) *StatusResponse {
 	response := &StatusResponse{Name: siteInstance.GetName(), Kind: 2}
 	// if siteInstance.GetSiteConfig().Dead {
 	// 	response.Error = fmt.Errorf("skip site %s: site is dead", siteInstance.GetName())
-	// 	ch <- response
-	// 	return
+	// 	return response
 	// }
 	SiteStatus, err := siteInstance.GetStatus()
 	response.SiteStatus = SiteStatus
 	if err != nil {
 		response.Error = fmt.Errorf("cann't get site %s status: error=%w", siteInstance.GetName(), err)
-		ch <- response
-		return
+		return response
 	}
 
 	if showTorrents {
@@ -76,16 +73,19 @@ func fetchSiteStatus(siteInstance site.Site, showTorrents bool, full bool, showS
 			response.Error = fmt.Errorf("cann't get site %s torrents: %w", siteInstance.GetName(), err)
 		} else {
 			if showScore {
-				brushSiteOption := strategy.GetBrushSiteOptions(siteInstance, util.Now())
-				scores := map[string]float64{}
-				for _, torrent := range siteTorrents {
-					scores[torrent.Id], _, _ = strategy.RateSiteTorrent(torrent, brushSiteOption)
+				if brushSiteOption, err := strategy.GetBrushSiteOptions(siteInstance, util.Now()); err != nil {
+					response.Error = fmt.Errorf("cann't get site %s brush options: %w", siteInstance.GetName(), err)
+				} else {
+					scores := map[string]float64{}
+					for _, torrent := range siteTorrents {
+						scores[torrent.Id], _, _ = strategy.RateSiteTorrent(torrent, brushSiteOption)
+					}
+					response.SiteTorrentScores = scores
 				}
-				response.SiteTorrentScores = scores
 			}
 			response.SiteTorrents = siteTorrents
 		}
 	}
 
-	ch <- response
+	return response
 }
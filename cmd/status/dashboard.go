@@ -0,0 +1,185 @@
+package status
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sagan/ptool/util"
+)
+
+type DashboardClient struct {
+	Name               string
+	Error              string
+	UploadSpeed        int64
+	DownloadSpeed      int64
+	UploadSpeedLimit   int64
+	DownloadSpeedLimit int64
+	FreeSpaceOnDisk    int64
+	Torrents           int64
+	TorrentStates      map[string]int64 // state => count
+}
+
+type DashboardSite struct {
+	Name           string
+	Error          string // if not empty, site status check failed (e.g. cookie expired)
+	UserName       string
+	UserUploaded   int64
+	UserDownloaded int64
+	UserClass      string
+	UnreadMessages int64
+	Warned         bool
+	HnRs           int64
+	HnRDeadline    int64 // unix timestamp. 0 if unknown
+}
+
+// Aggregate info of multiple clients and sites.
+type Dashboard struct {
+	Clients             []*DashboardClient
+	Sites               []*DashboardSite
+	ClientsSuccess      int64
+	ClientsFailed       int64
+	SitesSuccess        int64
+	SitesFailed         int64
+	TotalUploadSpeed    int64
+	TotalDownloadSpeed  int64
+	TotalFreeSpace      int64
+	TotalTorrents       int64
+	TotalTorrentStates  map[string]int64
+	TotalUserUploaded   int64
+	TotalUserDownloaded int64
+	TotalUnreadMessages int64
+	TotalHnRs           int64
+	SitesWarned         int64
+}
+
+func NewDashboard(responses []*StatusResponse) *Dashboard {
+	dashboard := &Dashboard{
+		Clients:            []*DashboardClient{},
+		Sites:              []*DashboardSite{},
+		TotalTorrentStates: map[string]int64{},
+	}
+	for _, response := range responses {
+		errorStr := ""
+		if response.Error != nil {
+			errorStr = response.Error.Error()
+		}
+		switch response.Kind {
+		case 1:
+			dc := &DashboardClient{
+				Name:            response.Name,
+				Error:           errorStr,
+				FreeSpaceOnDisk: -1,
+				TorrentStates:   map[string]int64{},
+			}
+			if response.ClientStatus != nil {
+				dashboard.ClientsSuccess++
+				dc.UploadSpeed = response.ClientStatus.UploadSpeed
+				dc.DownloadSpeed = response.ClientStatus.DownloadSpeed
+				dc.UploadSpeedLimit = response.ClientStatus.UploadSpeedLimit
+				dc.DownloadSpeedLimit = response.ClientStatus.DownloadSpeedLimit
+				dc.FreeSpaceOnDisk = response.ClientStatus.FreeSpaceOnDisk
+				dashboard.TotalUploadSpeed += dc.UploadSpeed
+				dashboard.TotalDownloadSpeed += dc.DownloadSpeed
+				if dc.FreeSpaceOnDisk > 0 {
+					dashboard.TotalFreeSpace += dc.FreeSpaceOnDisk
+				}
+			} else {
+				dashboard.ClientsFailed++
+			}
+			for _, torrent := range response.ClientTorrents {
+				dc.Torrents++
+				dc.TorrentStates[torrent.State]++
+				dashboard.TotalTorrents++
+				dashboard.TotalTorrentStates[torrent.State]++
+			}
+			dashboard.Clients = append(dashboard.Clients, dc)
+		case 2:
+			ds := &DashboardSite{
+				Name:  response.Name,
+				Error: errorStr,
+			}
+			if response.SiteStatus != nil {
+				dashboard.SitesSuccess++
+				ds.UserName = response.SiteStatus.UserName
+				ds.UserUploaded = response.SiteStatus.UserUploaded
+				ds.UserDownloaded = response.SiteStatus.UserDownloaded
+				ds.UserClass = response.SiteStatus.UserClass
+				ds.UnreadMessages = response.SiteStatus.UnreadMessages
+				ds.Warned = response.SiteStatus.Warned
+				ds.HnRs = response.SiteStatus.HnRs
+				ds.HnRDeadline = response.SiteStatus.HnRDeadline
+				dashboard.TotalUserUploaded += ds.UserUploaded
+				dashboard.TotalUserDownloaded += ds.UserDownloaded
+				dashboard.TotalUnreadMessages += ds.UnreadMessages
+				dashboard.TotalHnRs += ds.HnRs
+				if ds.Warned {
+					dashboard.SitesWarned++
+				}
+			} else {
+				dashboard.SitesFailed++
+			}
+			dashboard.Sites = append(dashboard.Sites, ds)
+		}
+	}
+	return dashboard
+}
+
+// Print the dashboard in tables.
+// Torrent state columns are the same with the summary of "ptool show" cmd:
+// ↓ downloading, - paused, ↑ seeding, ✓ completed, + others.
+func (dashboard *Dashboard) Print(output io.Writer) {
+	clientFmt := "%-15s  %-8s  %-8s  %-8s  %-6s  %-6s  %-6s  %-6s  %-6s  %-6s  %s\n"
+	fmt.Fprintf(output, "Clients: %d success / %d failed\n", dashboard.ClientsSuccess, dashboard.ClientsFailed)
+	fmt.Fprintf(output, clientFmt, "Name", "↑Spd/s", "↓Spd/s", "FreeSpc", "Total", "↓", "-", "↑", "✓", "+", "Error")
+	printClient := func(name string, upSpeed, downSpeed, freeSpace int64, cnt int64, states map[string]int64,
+		err string) {
+		freeSpaceStr := "-"
+		if freeSpace >= 0 {
+			freeSpaceStr = util.BytesSizeAround(float64(freeSpace))
+		}
+		others := cnt - states["downloading"] - states["paused"] - states["seeding"] - states["completed"]
+		fmt.Fprintf(output, clientFmt, name, util.BytesSizeAround(float64(upSpeed)),
+			util.BytesSizeAround(float64(downSpeed)), freeSpaceStr, fmt.Sprint(cnt),
+			fmt.Sprint(states["downloading"]), fmt.Sprint(states["paused"]), fmt.Sprint(states["seeding"]),
+			fmt.Sprint(states["completed"]), fmt.Sprint(others), err)
+	}
+	for _, dc := range dashboard.Clients {
+		printClient(dc.Name, dc.UploadSpeed, dc.DownloadSpeed, dc.FreeSpaceOnDisk, dc.Torrents, dc.TorrentStates,
+			dc.Error)
+	}
+	printClient("<total>", dashboard.TotalUploadSpeed, dashboard.TotalDownloadSpeed, dashboard.TotalFreeSpace,
+		dashboard.TotalTorrents, dashboard.TotalTorrentStates, "")
+	fmt.Fprintf(output, "\n")
+
+	// Class: user class, with "!" suffix if warned. PM: unread messages. H&R: pending H&R count (earliest deadline).
+	siteFmt := "%-15s  %-20s  %-15s  %-8s  %-8s  %-4s  %-12s  %s\n"
+	fmt.Fprintf(output, "Sites: %d success / %d failed\n", dashboard.SitesSuccess, dashboard.SitesFailed)
+	fmt.Fprintf(output, siteFmt, "Name", "UserName", "Class", "↑", "↓", "PM", "H&R", "Error")
+	now := util.Now()
+	for _, ds := range dashboard.Sites {
+		uploaded, downloaded, messages, hnrs := "-", "-", "-", "-"
+		if ds.Error == "" {
+			uploaded = util.BytesSizeAround(float64(ds.UserUploaded))
+			downloaded = util.BytesSizeAround(float64(ds.UserDownloaded))
+			messages = fmt.Sprint(ds.UnreadMessages)
+			hnrs = fmt.Sprint(ds.HnRs)
+			if left := ds.HnRDeadline - now; ds.HnRs > 0 && ds.HnRDeadline > 0 && left > 0 {
+				hnrs += fmt.Sprintf(" (%s)", util.FormatDuration(left))
+			} else if ds.HnRs > 0 && ds.HnRDeadline > 0 {
+				hnrs += " (overdue)"
+			}
+		}
+		class := ds.UserClass
+		if ds.Warned {
+			class += "!"
+		}
+		fmt.Fprintf(output, siteFmt, ds.Name, ds.UserName, class, uploaded, downloaded, messages, hnrs, ds.Error)
+	}
+	totalClass := ""
+	if dashboard.SitesWarned > 0 {
+		totalClass = fmt.Sprintf("%d warned", dashboard.SitesWarned)
+	}
+	fmt.Fprintf(output, siteFmt, "<total>", "", totalClass, util.BytesSizeAround(float64(dashboard.TotalUserUploaded)),
+		util.BytesSizeAround(float64(dashboard.TotalUserDownloaded)), fmt.Sprint(dashboard.TotalUnreadMessages),
+		fmt.Sprint(dashboard.TotalHnRs), "")
+}
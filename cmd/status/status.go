@@ -1,6 +1,7 @@
 package status
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"slices"
@@ -11,10 +12,12 @@ import (
 
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/filterexpr"
 )
 
 var (
@@ -26,19 +29,25 @@ var (
 	showAllClients = false
 	showAllSites   = false
 	showScore      = false
+	showDashboard  = false
+	showJson       = false
 	largestFlag    = false
 	newestFlag     = false
+	wide           = false
+	narrow         = false
+	columns        = ""
 	filter         = ""
 	category       = ""
+	expr           = ""
 )
 
 var command = &cobra.Command{
-	Use: "status [client | site | group]... [-a | -c | -s]",
+	Use: "status [client | site | group | _all]... [-a | -c | -s]",
 	// Args:  cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "status"},
 	Short:       "Show clients or sites status.",
 	Long: `Show clients or sites status.
-[client | site | group]: name of a client, site or group.
+[client | site | group | _all]: name of a client, site or group. "_all" means all clients and sites (same as "-a").
 
 For client, display following status info:
 - ↑Spd/Lmt : Current uploading speed / limit.
@@ -50,10 +59,20 @@ For client, display following status info:
 For site, display following status info:
 - ↑: : Current uploading statistics.
 - ↓: : Current downloading statstics.
+- UserName, Class (user class), PM (unread private messages), Warned (the account has been warned),
+  H&R (pending H&R torrents count and the earliest deadline), if available.
+  To get notified of new unread PMs, configure [[hooks]] of "message" event and run "ptool watch" command.
 
 If "-t" flag is set, it will also show the active / latest torrents list of client / site.
 For the list format of client torrents, see help of "ptool show" command.
-For the list format of site torrents, see help of "ptool search" command.`,
+The "--columns", "--wide" and "--narrow" flags of "ptool show" command are also supported.
+For the list format of site torrents, see help of "ptool search" command.
+
+If "--dashboard" flag is set, it queries all provided clients and sites concurrently
+and displays an aggregate dashboard instead: the speeds, free disk space and torrent counts
+of each state of every client, the user infos (including class, unread PMs, warning and H&R) of every site,
+the totals, and all failures (e.g. site cookie expired). Use "--json" flag to output the dashboard in json format.
+The torrent counts of dashboard are also filtered by "--category" and "--expr" flags, if set.`,
 	RunE: status,
 }
 
@@ -68,10 +87,19 @@ func init() {
 		"Show torrents (active torrents for client / latest torrents for site)")
 	command.Flags().BoolVarP(&showFull, "full", "f", false, "Show full info of each client or site")
 	command.Flags().BoolVarP(&showScore, "score", "", false, "Show brush score of site torrents")
+	command.Flags().BoolVarP(&showDashboard, "dashboard", "", false,
+		"Show an aggregate dashboard of all provided clients and sites")
+	command.Flags().BoolVarP(&showJson, "json", "", false, `Show dashboard in json format. Implies "--dashboard"`)
 	command.Flags().BoolVarP(&largestFlag, "largest", "l", false, `Sort torrents by size in desc order"`)
 	command.Flags().BoolVarP(&newestFlag, "newest", "n", false, `Sort torrents by time in desc order"`)
+	command.Flags().BoolVarP(&wide, "wide", "", false, "Wide layout: show more fields of client torrents")
+	command.Flags().BoolVarP(&narrow, "narrow", "", false, "Narrow layout: show less fields of client torrents")
+	command.Flags().StringVarP(&columns, "columns", "", "",
+		`Comma-separated displayed fields of client torrents, e.g. "name,size,ratio,tracker,category,added"`)
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", "Filter client torrents by category")
+	command.Flags().StringVarP(&expr, "expr", "", "", "Filter client torrents by expression. "+
+		`See help of "ptool show" command for the expression syntax`)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -80,8 +108,31 @@ func status(cmd *cobra.Command, args []string) error {
 	if largestFlag && newestFlag {
 		return fmt.Errorf("--largest and --newest flags are NOT compatible")
 	}
+	if wide && narrow {
+		return fmt.Errorf("--wide and --narrow flags are NOT compatible")
+	}
+	layout := ""
+	if wide {
+		layout = client.TORRENTS_LAYOUT_WIDE
+	} else if narrow {
+		layout = client.TORRENTS_LAYOUT_NARROW
+	}
+	torrentColumns, err := client.ParseTorrentColumns(columns, layout)
+	if err != nil {
+		return err
+	}
+	if len(names) == 1 && names[0] == "_all" {
+		names = nil
+		showAll = true
+	}
+	if showJson {
+		showDashboard = true
+	}
+	if showDashboard && showTorrents {
+		return fmt.Errorf("--dashboard and --torrents flags are NOT compatible")
+	}
 	if showAll || showAllClients || showAllSites {
-		if len(args) > 0 {
+		if len(names) > 0 {
 			return fmt.Errorf("--all, --clients, --sites flags cann't be used with site or client names")
 		}
 		if showAll || showAllClients {
@@ -99,15 +150,27 @@ func status(cmd *cobra.Command, args []string) error {
 		}
 	}
 	names = config.ParseGroupAndOtherNames(names...)
+	var torrentExpr *filterexpr.Expr
+	if expr != "" {
+		var err error
+		if torrentExpr, err = client.ParseTorrentExpr(expr); err != nil {
+			return fmt.Errorf("invalid expr: %w", err)
+		}
+	}
 
 	if len(names) == 0 {
 		return fmt.Errorf("no sites or clients provided")
 	}
 	now := util.Now()
 	errorCnt := int64(0)
+	var lastErr error
 	doneFlag := map[string]bool{}
-	cnt := int64(0)
-	ch := make(chan *StatusResponse, len(names))
+	type statusTask struct {
+		name  string
+		kind  int64
+		fetch func() *StatusResponse
+	}
+	var tasks []*statusTask
 	for _, name := range names {
 		if name == "_" || doneFlag[name] {
 			continue
@@ -118,22 +181,29 @@ func status(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				log.Errorf("Error: failed to create client %s: %v\n", name, err)
 				errorCnt++
+				lastErr = err
 				continue
 			}
-			go fetchClientStatus(clientInstance, showTorrents, showFull, category, ch)
-			cnt++
+			tasks = append(tasks, &statusTask{name, 1, func() *StatusResponse {
+				// dashboard counts all torrents of client
+				return fetchClientStatus(clientInstance, showTorrents || showDashboard, showFull || showDashboard,
+					category)
+			}})
 		} else if site.GetConfigSiteReginfo(name) != nil {
 			siteInstance, err := site.CreateSite(name)
 			if err != nil {
 				log.Errorf("Error: failed to create site %s: %v\n", name, err)
 				errorCnt++
+				lastErr = err
 				continue
 			}
-			go fetchSiteStatus(siteInstance, showTorrents, showFull, showScore, ch)
-			cnt++
+			tasks = append(tasks, &statusTask{name, 2, func() *StatusResponse {
+				return fetchSiteStatus(siteInstance, showTorrents, showFull, showScore)
+			}})
 		} else {
 			log.Errorf("Error: %s is not a client or site\n", name)
 			errorCnt++
+			lastErr = constants.ErrNotFound
 		}
 	}
 
@@ -146,9 +216,16 @@ func status(cmd *cobra.Command, args []string) error {
 	successSitesUploaded := int64(0)
 	successSitesDownloaded := int64(0)
 
-	responses := []*StatusResponse{}
-	for i := int64(0); i < cnt; i++ {
-		responses = append(responses, <-ch)
+	responses, errs := util.ParallelMap(tasks, config.GetConcurrency(), config.GetItemTimeout(),
+		func(_ context.Context, task *statusTask) (*StatusResponse, error) {
+			return task.fetch(), nil
+		})
+	for i, err := range errs {
+		// timeout
+		if err != nil {
+			responses[i] = &StatusResponse{Name: tasks[i].name, Kind: tasks[i].kind,
+				Error: fmt.Errorf("cann't get %s status: error=%w", tasks[i].name, err)}
+		}
 	}
 	if dataOrder {
 		sort.SliceStable(responses, func(i, j int) bool {
@@ -186,6 +263,28 @@ func status(cmd *cobra.Command, args []string) error {
 		})
 	}
 
+	if torrentExpr != nil {
+		for _, response := range responses {
+			if response.Kind == 1 && response.ClientTorrents != nil {
+				response.ClientTorrents = slices.DeleteFunc(response.ClientTorrents, func(t *client.Torrent) bool {
+					return !t.MatchExpr(torrentExpr)
+				})
+			}
+		}
+	}
+	if showDashboard {
+		dashboard := NewDashboard(responses)
+		if showJson {
+			if err := util.PrintJson(os.Stdout, dashboard); err != nil {
+				return err
+			}
+		} else {
+			dashboard.Print(os.Stdout)
+		}
+		errorCnt += dashboard.ClientsFailed + dashboard.SitesFailed
+		return common.ItemsError(errorCnt, int64(len(names)), lastErr)
+	}
+
 	errorsStr := ""
 	for _, response := range responses {
 		if response.Kind == 1 {
@@ -193,6 +292,7 @@ func status(cmd *cobra.Command, args []string) error {
 			if response.Error != nil {
 				errorsStr += fmt.Sprintf("Error get client %s status: error=%v\n", response.Name, response.Error)
 				errorCnt++
+				lastErr = response.Error
 			}
 			if response.ClientStatus != nil {
 				cntSuccessClients++
@@ -217,7 +317,8 @@ func status(cmd *cobra.Command, args []string) error {
 						return response.ClientTorrents[i].Atime > response.ClientTorrents[j].Atime
 					})
 				}
-				client.PrintTorrents(os.Stdout, response.ClientTorrents, filter, 0, dense)
+				client.PrintTorrentsInColumns(os.Stdout, response.ClientTorrents, filter, 0, dense,
+					torrentColumns, layout)
 				fmt.Printf("\n")
 			}
 		} else if response.Kind == 2 {
@@ -225,12 +326,16 @@ func status(cmd *cobra.Command, args []string) error {
 			if response.Error != nil {
 				errorsStr += fmt.Sprintf("Error get site %s status: error=%v\n", response.Name, response.Error)
 				errorCnt++
+				lastErr = response.Error
 			}
 			if response.SiteStatus != nil {
 				cntSuccessSites++
 				successSitesUploaded += response.SiteStatus.UserUploaded
 				successSitesDownloaded += response.SiteStatus.UserDownloaded
 				additionalInfo := fmt.Sprintf("UserName: %s", response.SiteStatus.UserName)
+				if userInfo := response.SiteStatus.UserInfo(); userInfo != "" {
+					additionalInfo += "; " + userInfo
+				}
 				if len(response.SiteTorrents) > 0 {
 					additionalInfo += fmt.Sprintf("; Torrents: %d", len(response.SiteTorrents))
 				}
@@ -272,8 +377,5 @@ func status(cmd *cobra.Command, args []string) error {
 		fmt.Printf("\nErrors:\n%s", errorsStr)
 	}
 
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(names)), lastErr)
 }
@@ -0,0 +1,347 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	DEFAULT_LISTEN = "127.0.0.1:9119"
+	// Piece states are re-fetched from client at most once per this interval
+	PIECE_STATES_INTERVAL = time.Second
+)
+
+var command = &cobra.Command{
+	Use:         "stream {client} {infoHash} [fileIndex]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "stream"},
+	Short:       "Preview a file of an incomplete torrent in client via local http server.",
+	Long: `Preview a file of an incomplete torrent in client via local http server.
+It enables sequential download and first & last piece priority of the torrent, sets the selected file
+to maximal priority (qBittorrent only), waits until the beginning (--buffer size) and the last piece
+of the file are downloaded, then serves the file at http://<listen>/ so it can be opened by a media player.
+Range requests are supported; a read of not-yet-downloaded data blocks until the pieces are downloaded.
+
+[fileIndex] is the index of file in torrent (as displayed by "ptool pieces"). Default is the largest file.
+It requires ptool has access to the save path of torrent, use "--map-save-path" if the client is running
+in a different file system. Note the "incomplete torrents save path" option of client is NOT supported.
+
+The changed sequential download and file priority settings are kept after exit.`,
+	Args: cobra.MatchAll(cobra.RangeArgs(2, 3), cobra.OnlyValidArgs),
+	RunE: stream,
+}
+
+var (
+	listen       = ""
+	buffer       = ""
+	mapSavePaths []string
+)
+
+func init() {
+	command.Flags().StringVarP(&listen, "listen", "", DEFAULT_LISTEN, "Listen address of http server")
+	command.Flags().StringVarP(&buffer, "buffer", "", "10MiB",
+		"Size of the beginning of file that must be downloaded before serving it")
+	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
+		`Map save path from BitTorrent client to the file system of ptool. `+
+			`Format: "client_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS+" "+
+			constants.HELP_ARG_CLIENT_PATH_MAPPINGS)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func stream(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHash := args[1]
+	bufferSize, err := util.RAMInBytes(buffer)
+	if err != nil || bufferSize < 0 {
+		return fmt.Errorf("invalid buffer size %q", buffer)
+	}
+	savePathMapper, err := common.GetSavePathMapper(clientName, mapSavePaths, false)
+	if err != nil {
+		return err
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if torrent == nil {
+		return fmt.Errorf("torrent %s not found", infoHash)
+	}
+	files, err := clientInstance.GetTorrentContents(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("torrent has no files")
+	}
+	fileIndex := int64(0)
+	if len(args) > 2 {
+		if fileIndex, err = strconv.ParseInt(args[2], 10, 64); err != nil ||
+			fileIndex < 0 || fileIndex >= int64(len(files)) {
+			return fmt.Errorf("invalid fileIndex %q, torrent has %d files", args[2], len(files))
+		}
+	} else {
+		for i, file := range files {
+			if file.Size > files[fileIndex].Size {
+				fileIndex = int64(i)
+			}
+		}
+	}
+	file := files[fileIndex]
+	content, err := torrentutil.ExportClientTorrent(clientInstance, torrent)
+	if err != nil {
+		return fmt.Errorf("failed to export torrent: %w", err)
+	}
+	tinfo, err := torrentutil.ParseTorrent(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	offset, err := getFileOffset(tinfo, fileIndex, file.Size)
+	if err != nil {
+		return err
+	}
+	savePath := torrent.SavePath
+	if savePathMapper != nil {
+		var match bool
+		if savePath, match = savePathMapper.Before2After(torrent.SavePath); !match {
+			return fmt.Errorf("save path %q does not match with any map-save-path rule", torrent.SavePath)
+		}
+	}
+	reader := &pieceReader{
+		clientInstance: clientInstance,
+		infoHash:       infoHash,
+		pieceLength:    tinfo.Info.PieceLength,
+		offset:         offset,
+		size:           file.Size,
+		filename:       filepath.Join(savePath, filepath.FromSlash(file.Path)),
+	}
+	fmt.Printf("Torrent: %s (%s)\n", torrent.Name, torrent.InfoHash)
+	fmt.Printf("File: %d %s (%s)\n", fileIndex, file.Path, util.BytesSize(float64(file.Size)))
+
+	if common.DryRun("enable sequential download of torrent and serve file at http://%s/", listen) {
+		return nil
+	}
+	if err = clientInstance.SetTorrentsSequentialDownload([]string{infoHash}, true); err != nil {
+		return fmt.Errorf("failed to enable sequential download: %w", err)
+	}
+	if err = clientInstance.SetTorrentsFirstLastPiecePrio([]string{infoHash}, true); err != nil {
+		log.Warnf("Failed to enable first & last piece priority: %v", err)
+	}
+	if err = clientInstance.SetFilePriority(infoHash, []int64{fileIndex}, 7); err != nil {
+		log.Debugf("Failed to set file priority: %v", err)
+	}
+	if torrent.State == "paused" {
+		if err = clientInstance.ResumeTorrents([]string{infoHash}); err != nil {
+			return fmt.Errorf("failed to resume torrent: %w", err)
+		}
+	}
+
+	for {
+		states, err := reader.states()
+		if err != nil {
+			return fmt.Errorf("failed to get torrent piece states: %w", err)
+		}
+		from, to := reader.pieces(0, min(bufferSize, file.Size))
+		lastFrom, lastTo := reader.pieces(file.Size-1, file.Size)
+		buffered, total := countDownloaded(states, from, to), to-from
+		if lastFrom >= to {
+			buffered += countDownloaded(states, lastFrom, lastTo)
+			total += lastTo - lastFrom
+		}
+		fmt.Printf("\rBuffering: %d / %d pieces", buffered, total)
+		if buffered == total {
+			fmt.Printf("\n")
+			break
+		}
+		time.Sleep(PIECE_STATES_INTERVAL)
+	}
+
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address: %w", err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		log.Warnf("Server listens on a non-loopback address, anyone can access the file")
+	}
+	modTime := time.Now()
+	name := path.Base(file.Path)
+	// Use a local mux, so that the cmd can be run multiple times in shell.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		log.Infof("%s %s %s", r.RemoteAddr, r.Method, r.Header.Get("Range"))
+		f, err := reader.open(r)
+		if err != nil {
+			log.Errorf("Failed to open file: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		http.ServeContent(w, r, name, modTime, f)
+	})
+	fmt.Printf("Serving file at http://%s/ . Press Ctrl+C to stop\n", listen)
+	server := &http.Server{Addr: listen, Handler: mux}
+	return server.ListenAndServe()
+}
+
+// Return the offset of file of fileIndex in torrent data. It's the sum of sizes of all previous files,
+// including the BEP 47 pad files, which are not included in client file list.
+func getFileOffset(tinfo *torrentutil.TorrentMeta, fileIndex int64, size int64) (int64, error) {
+	if tinfo.Info.PieceLength <= 0 || len(tinfo.Info.Pieces) == 0 {
+		return 0, fmt.Errorf("BitTorrent v2 only torrent is not supported")
+	}
+	offset, index := int64(0), int64(0)
+	for _, metafile := range tinfo.Info.UpvertedFiles() {
+		if len(metafile.Path) > 0 && metafile.Path[0] == ".pad" {
+			offset += metafile.Length
+			continue
+		}
+		if index == fileIndex {
+			if metafile.Length != size {
+				return 0, fmt.Errorf("size of file %d mismatches with the .torrent file", fileIndex)
+			}
+			return offset, nil
+		}
+		offset += metafile.Length
+		index++
+	}
+	return 0, fmt.Errorf("file %d not found in the .torrent file", fileIndex)
+}
+
+func countDownloaded(states []int64, from int64, to int64) (cnt int64) {
+	for i := from; i < to && i < int64(len(states)); i++ {
+		if states[i] == client.PIECE_DOWNLOADED {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// Reads the file of torrent, blocking until the pieces of read range are downloaded.
+// The piece states are shared by all requests.
+type pieceReader struct {
+	clientInstance client.Client
+	infoHash       string
+	pieceLength    int64
+	offset         int64 // offset of file in torrent data
+	size           int64
+	filename       string
+	mu             sync.Mutex
+	pieceStates    []int64
+	updated        time.Time
+}
+
+// Return the piece states, which are re-fetched if outdated.
+func (reader *pieceReader) states() ([]int64, error) {
+	reader.mu.Lock()
+	defer reader.mu.Unlock()
+	if reader.pieceStates == nil || time.Since(reader.updated) >= PIECE_STATES_INTERVAL {
+		states, err := reader.clientInstance.GetTorrentPieceStates(reader.infoHash)
+		if err != nil {
+			return nil, err
+		}
+		reader.pieceStates = states
+		reader.updated = time.Now()
+	}
+	return reader.pieceStates, nil
+}
+
+// Return the [from, to) piece indexes that cover the [start, end) range of file.
+func (reader *pieceReader) pieces(start int64, end int64) (from int64, to int64) {
+	if end <= start {
+		return 0, 0
+	}
+	return (reader.offset + start) / reader.pieceLength, (reader.offset+end-1)/reader.pieceLength + 1
+}
+
+func (reader *pieceReader) open(r *http.Request) (*pieceFile, error) {
+	f, err := os.Open(reader.filename)
+	if os.IsNotExist(err) {
+		f, err = os.Open(reader.filename + constants.FILENAME_SUFFIX_QB_INCOMPLETE)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pieceFile{reader: reader, file: f, request: r}, nil
+}
+
+// An io.ReadSeeker of the file for one http request.
+type pieceFile struct {
+	reader  *pieceReader
+	file    *os.File
+	request *http.Request
+	pos     int64
+}
+
+func (f *pieceFile) Read(p []byte) (int, error) {
+	reader := f.reader
+	if f.pos >= reader.size {
+		return 0, io.EOF
+	}
+	// read at most to the end of current piece, so it only waits for one piece
+	pieceEnd := ((reader.offset+f.pos)/reader.pieceLength+1)*reader.pieceLength - reader.offset
+	if end := min(pieceEnd, reader.size); int64(len(p)) > end-f.pos {
+		p = p[:end-f.pos]
+	}
+	from, to := reader.pieces(f.pos, f.pos+int64(len(p)))
+	for {
+		states, err := reader.states()
+		if err != nil {
+			return 0, err
+		}
+		if countDownloaded(states, from, to) == to-from {
+			break
+		}
+		select {
+		case <-f.request.Context().Done():
+			return 0, f.request.Context().Err()
+		case <-time.After(PIECE_STATES_INTERVAL):
+		}
+	}
+	n, err := f.file.ReadAt(p, f.pos)
+	f.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *pieceFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.reader.size
+	default:
+		return 0, fmt.Errorf("invalid whence")
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position")
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *pieceFile) Close() error {
+	return f.file.Close()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/site/public"
 	"github.com/sagan/ptool/site/tpl"
 	"github.com/sagan/ptool/util"
@@ -31,7 +32,6 @@ The above cmd changes the category of all torrents in "local" client that has ta
 }
 
 var (
-	dryRun      = false
 	filter      = ""
 	category    = ""
 	tag         = ""
@@ -42,7 +42,7 @@ func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify torrents to client")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Do NOT actually modify torrents to client")
 	command.Flags().Int64VarP(&maxTorrents, "max-torrents", "", -1, "Number limit of modified torrents. -1 == no limit")
 	cmd.RootCmd.AddCommand(command)
 }
@@ -96,7 +96,7 @@ func tidyup(cmd *cobra.Command, args []string) error {
 			}
 			fmt.Printf("Modify (%d/%d) torrent %s - %s: addTags=%v; removeTags=%v\n", i+1, len(torrents),
 				torrent.InfoHash, torrent.Name, addTags, remopveTags)
-			if dryRun {
+			if flags.DryRun {
 				continue
 			}
 			var err error
@@ -0,0 +1,36 @@
+package torrentctl
+
+import (
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("torrentctl", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex < 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		if info.LastArgIndex == 1 {
+			return suggest.ClientArg(info.MatchingPrefix)
+		}
+		if info.LastArgIndex == 2 {
+			return suggest.InfoHashArg(info.MatchingPrefix, info.Args[1])
+		}
+		if strings.HasPrefix(info.MatchingPrefix, "=") {
+			return nil
+		}
+		commpletions := [][2]string{}
+		for _, option := range allOptions {
+			commpletions = append(commpletions, [2]string{option.Name, option.Description})
+		}
+		return suggest.EnumArg(info.MatchingPrefix, commpletions)
+	})
+}
@@ -0,0 +1,188 @@
+package torrentctl
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+)
+
+type Option struct {
+	Name        string
+	Readonly    bool
+	Description string
+}
+
+var command = &cobra.Command{
+	Use:         "torrentctl {client} {infoHash} [{variable}[={value}] ...]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "torrentctl"},
+	Short:       "Get or set per-torrent options of a torrent in client.",
+	Long: `Get or set per-torrent options of a torrent in client.
+If '[={value}]' part is present, set the option, otherwise get current value.
+{variable}: snake_case style option name. e.g. sequential_download
+{value}: the value of option to set. For option of boolean type, use literal "false" or "true".
+
+Examples:
+  ptool torrentctl local 31a615d5984cb63c6f999f72bb3961dce49c194a
+  ptool torrentctl local 31a615d5984cb63c6f999f72bb3961dce49c194a sequential_download=true first_last_piece_prio=true
+
+qBittorrent supports all options natively. Transmission does NOT support sequential download or
+first-last piece priority, ptool emulates them by setting file priorities (sequential_download:
+files in path order get high -> normal -> low priorities; first_last_piece_prio: the first and last files
+get high priority, other files are not touched). Setting them to false resets the touched files to normal priority.
+In Transmission, the current values of these options can NOT be read.
+
+The queue_position option can be set to "top", "bottom", "up" or "down" to move the torrent in client's queue,
+e.g. "queue_position=top". qBittorrent requires queueing to be enabled ("ptool clientctl <client> queueing_enabled=true").
+Use "ptool queue <client>" to display the queue order.
+
+For list of all supported variables, run 'ptool torrentctl --parameters'`,
+	RunE: torrentctl,
+}
+
+var (
+	allOptions = []Option{
+		{"name", true, "Torrent name"},
+		{"state", true, "Torrent state"},
+		{"sequential_download", false, "Download pieces in sequential order (for streaming)"},
+		{"first_last_piece_prio", false, "Download first and last pieces of each file first (for streaming)"},
+		{"queue_position", false, "Position in download / seed queue, 1 is the top. " +
+			"Set to top, bottom, up or down to move it in queue"},
+	}
+	showValuesOnly = false
+	showParameters = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&showParameters, "parameters", "", false, "Print all parameters list and exit")
+	command.Flags().BoolVarP(&showValuesOnly, "show-values-only", "", false, "Show option value data only")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func torrentctl(cmd *cobra.Command, args []string) error {
+	if showParameters {
+		fmt.Printf("%-30s %-5s %s\n", "Name", "Type", "Description")
+		for _, option := range allOptions {
+			permission := "rw"
+			if option.Readonly {
+				permission = "r"
+			}
+			fmt.Printf("%-30s %-5s %s\n", option.Name, permission, option.Description)
+		}
+		return nil
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("<client> or <infoHash> not provided")
+	}
+	clientName := args[0]
+	infoHash := args[1]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return err
+	}
+	torrent, err := clientInstance.GetTorrent(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if torrent == nil {
+		return fmt.Errorf("torrent %s not found", infoHash)
+	}
+	isTransmission := clientInstance.GetClientConfig().Type == "transmission"
+	args = args[2:]
+	if len(args) == 0 {
+		for _, option := range allOptions {
+			args = append(args, option.Name)
+		}
+	}
+
+	errorCnt := int64(0)
+	var lastErr error
+	for _, variable := range args {
+		name, value, isSet := strings.Cut(variable, "=")
+		index := slices.IndexFunc(allOptions, func(o Option) bool { return o.Name == name })
+		if index == -1 {
+			return fmt.Errorf("unrecognized parameter: %s", name)
+		}
+		option := allOptions[index]
+		if isSet {
+			if option.Readonly {
+				log.Errorf("Error set torrent %s option %s: read-only", infoHash, name)
+				errorCnt++
+				continue
+			}
+			if name == "queue_position" {
+				if !slices.Contains(client.QueueActions, value) {
+					log.Errorf("Error set torrent %s option %s: invalid value %q, must be one of %v",
+						infoHash, name, value, client.QueueActions)
+					errorCnt++
+					continue
+				}
+				if common.DryRun("move torrent %s to %s of queue", infoHash, value) {
+					continue
+				}
+				if err := clientInstance.MoveTorrentsInQueue([]string{infoHash}, value); err != nil {
+					log.Errorf("Error set torrent %s option %s=%s: %v", infoHash, name, value, err)
+					errorCnt++
+					lastErr = err
+					continue
+				}
+				if torrent, err := clientInstance.GetTorrent(infoHash); err == nil && torrent != nil {
+					value = fmt.Sprint(torrent.QueuePosition)
+				}
+			} else {
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					log.Errorf("Error set torrent %s option %s: invalid bool value %q", infoHash, name, value)
+					errorCnt++
+					lastErr = err
+					continue
+				}
+				if common.DryRun("set torrent %s option %s=%t", infoHash, name, enabled) {
+					continue
+				}
+				switch name {
+				case "sequential_download":
+					err = clientInstance.SetTorrentsSequentialDownload([]string{infoHash}, enabled)
+				case "first_last_piece_prio":
+					err = clientInstance.SetTorrentsFirstLastPiecePrio([]string{infoHash}, enabled)
+				}
+				if err != nil {
+					log.Errorf("Error set torrent %s option %s=%s: %v", infoHash, name, value, err)
+					errorCnt++
+					lastErr = err
+					continue
+				}
+				value = fmt.Sprint(enabled)
+			}
+		} else {
+			switch name {
+			case "name":
+				value = torrent.Name
+			case "state":
+				value = torrent.State
+			case "sequential_download":
+				value = fmt.Sprint(torrent.SequentialDownload)
+			case "first_last_piece_prio":
+				value = fmt.Sprint(torrent.FirstLastPiecePrio)
+			case "queue_position":
+				value = fmt.Sprint(torrent.QueuePosition)
+			}
+			if isTransmission && (name == "sequential_download" || name == "first_last_piece_prio") {
+				value = "unknown"
+			}
+		}
+		if showValuesOnly {
+			fmt.Printf("%s\n", value)
+		} else {
+			fmt.Printf("%s=%s\n", name, value)
+		}
+	}
+	return common.ItemsError(errorCnt, int64(len(args)), lastErr)
+}
@@ -0,0 +1,68 @@
+package trackers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "trackers {client} {infoHash} [--json]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "trackers"},
+	Short:       "Show trackers status of a torrent in client.",
+	Long: `Show trackers status of a torrent in client.
+Displayed info: status (working|notcontacted|error|updating|disabled|unknown), tier,
+seeders & leechers reported by tracker, next announce time (transmission only), tracker message and url.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: trackers,
+}
+
+var (
+	showJson = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func trackers(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHash := args[1]
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	trackers, err := clientInstance.GetTorrentTrackers(infoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent trackers: %w", err)
+	}
+	if showJson {
+		return util.PrintJson(os.Stdout, trackers)
+	}
+	now := util.Now()
+	fmt.Printf("Trackers (%d):\n", len(trackers))
+	fmt.Printf("%-12s  %-4s  %-6s  %-6s  %-8s  %-40s  %s\n",
+		"Status", "Tier", "Seeds", "Leechs", "Next", "Msg", "Url")
+	for _, tracker := range trackers {
+		seeders, leechers, next := "-", "-", "-"
+		if tracker.Seeders >= 0 {
+			seeders = fmt.Sprint(tracker.Seeders)
+		}
+		if tracker.Leechers >= 0 {
+			leechers = fmt.Sprint(tracker.Leechers)
+		}
+		if tracker.NextAnnounce > now {
+			next = util.FormatDuration(tracker.NextAnnounce - now)
+		}
+		fmt.Printf("%-12s  %-4d  %-6s  %-6s  %-8s  ", tracker.Status, tracker.Tier, seeders, leechers, next)
+		util.PrintStringInWidth(os.Stdout, tracker.Msg, 40, true)
+		fmt.Printf("  %s\n", tracker.Url)
+	}
+	return nil
+}
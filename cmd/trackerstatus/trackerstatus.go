@@ -0,0 +1,283 @@
+package trackerstatus
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/helper"
+)
+
+const (
+	STATUS_OK           = "ok"
+	STATUS_UNREGISTERED = "unregistered"
+	STATUS_TIMEOUT      = "timeout"
+	STATUS_ERROR        = "error"
+	STATUS_UNKNOWN      = "unknown"
+)
+
+type DomainStatus struct {
+	Domain       string
+	Total        int64
+	Ok           int64
+	Unregistered int64
+	Timeout      int64
+	Error        int64
+	Unknown      int64
+	Msgs         map[string]int64 // tracker error msg => count of torrents
+}
+
+var command = &cobra.Command{
+	Use: "trackerstatus {client} [--category category] [--tag tag] [--filter filter] [infoHash]... " +
+		"[--delete-unregistered] [--dry-run]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "trackerstatus"},
+	Short:       "Show aggregated tracker status of torrents in client, grouped by tracker domain.",
+	Long: fmt.Sprintf(`Show aggregated tracker status of torrents in client, grouped by tracker domain.
+%s.
+If no torrent or filter is provided, all torrents in client are checked.
+
+It fetches trackers of every torrent and classifies the torrent into one of below status:
+- ok : at least one tracker is working.
+- unregistered : tracker reports that the torrent does not exist or is unauthorized (e.g. deleted by site).
+- timeout : no tracker is working and the announce request timed out.
+- error : no tracker is working and tracker reports other error.
+- unknown : tracker is not contacted yet, updating or disabled.
+
+The count of torrents of each status and the tracker error messages are displayed for every tracker domain.
+
+If --delete-unregistered flag is set, the unregistered (dead) torrents will be deleted from client
+(with their content files, unless --preserve flag is also set). It will ask for confirmation,
+unless --force flag is set.`, constants.HELP_INFOHASH_ARGS),
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: trackerstatus,
+}
+
+var (
+	deleteUnregistered = false
+	preserve           = false
+	showTorrents       = false
+	showJson           = false
+	force              = false
+	category           = ""
+	tag                = ""
+	filter             = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&deleteUnregistered, "delete-unregistered", "", false,
+		"Delete unregistered torrents from client")
+	command.Flags().BoolVarP(&preserve, "preserve", "p", false,
+		"Used with --delete-unregistered. Preserve (do NOT delete) content files of deleted torrents")
+	command.Flags().BoolVarP(&showTorrents, "show-torrents", "", false, "Also list the torrents with problems")
+	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
+	command.Flags().BoolVarP(&force, "force", "", false, "Do NOT prompt for confirm")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Used with --delete-unregistered, only list the unregistered torrents, do NOT delete them")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func trackerstatus(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if showJson && deleteUnregistered {
+		return fmt.Errorf("--json and --delete-unregistered flags are NOT compatible")
+	}
+	if len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		infoHashes = []string{"_all"}
+	} else if category == "" && tag == "" && filter == "" {
+		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
+			return err
+		} else {
+			infoHashes = _infoHashes
+		}
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+	if err != nil {
+		return err
+	}
+
+	errorCnt := int64(0)
+	total := int64(len(torrents))
+	var lastErr error
+	domainStatuses := map[string]*DomainStatus{}
+	// status => torrents
+	problemTorrents := map[string][]*client.Torrent{}
+	for _, torrent := range torrents {
+		trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+		if err != nil {
+			log.Errorf("Failed to get torrent %s trackers: %v", torrent.InfoHash, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		domain := torrent.TrackerDomain
+		if domain == "" && len(trackers) > 0 {
+			domain = util.GetUrlDomain(trackers[0].Url)
+		}
+		domainStatus := domainStatuses[domain]
+		if domainStatus == nil {
+			domainStatus = &DomainStatus{Domain: domain, Msgs: map[string]int64{}}
+			domainStatuses[domain] = domainStatus
+		}
+		domainStatus.Total++
+		status := getStatus(trackers)
+		switch status {
+		case STATUS_OK:
+			domainStatus.Ok++
+		case STATUS_UNREGISTERED:
+			domainStatus.Unregistered++
+		case STATUS_TIMEOUT:
+			domainStatus.Timeout++
+		case STATUS_ERROR:
+			domainStatus.Error++
+		default:
+			domainStatus.Unknown++
+		}
+		if status == STATUS_OK {
+			continue
+		}
+		problemTorrents[status] = append(problemTorrents[status], torrent)
+		msgs := []string{}
+		for _, tracker := range trackers {
+			if tracker.Msg != "" && tracker.Status != "working" && !slices.Contains(msgs, tracker.Msg) {
+				msgs = append(msgs, tracker.Msg)
+			}
+		}
+		for _, msg := range msgs {
+			domainStatus.Msgs[msg]++
+		}
+	}
+
+	list := []*DomainStatus{}
+	for _, domainStatus := range domainStatuses {
+		list = append(list, domainStatus)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Domain < list[j].Domain
+	})
+	if showJson {
+		if err := util.PrintJson(os.Stdout, list); err != nil {
+			return err
+		}
+		return common.ItemsError(errorCnt, total, lastErr)
+	}
+	printDomainStatuses(list)
+	if showTorrents {
+		for _, status := range []string{STATUS_UNREGISTERED, STATUS_TIMEOUT, STATUS_ERROR, STATUS_UNKNOWN} {
+			if len(problemTorrents[status]) == 0 {
+				continue
+			}
+			fmt.Printf("\nTorrents of %s status (%d):\n", status, len(problemTorrents[status]))
+			client.PrintTorrents(os.Stdout, problemTorrents[status], "", 1, false)
+		}
+	}
+
+	if deleteUnregistered {
+		unregisteredTorrents := problemTorrents[STATUS_UNREGISTERED]
+		fmt.Printf("\n")
+		if len(unregisteredTorrents) == 0 {
+			log.Infof("No unregistered torrents found")
+		} else {
+			if !showTorrents {
+				client.PrintTorrents(os.Stdout, unregisteredTorrents, "", 1, false)
+				fmt.Printf("\n")
+			}
+			if flags.DryRun {
+				fmt.Printf("Dry run. %d unregistered torrents would be deleted\n", len(unregisteredTorrents))
+			} else {
+				if !force && !helper.AskYesNoConfirm(fmt.Sprintf("Will delete above %d unregistered torrents "+
+					"(preserve files: %t)", len(unregisteredTorrents), preserve)) {
+					return fmt.Errorf("abort")
+				}
+				infoHashes := util.Map(unregisteredTorrents, func(t *client.Torrent) string { return t.InfoHash })
+				total++
+				if err := clientInstance.DeleteTorrents(infoHashes, !preserve); err != nil {
+					log.Errorf("Failed to delete unregistered torrents: %v", err)
+					errorCnt++
+					lastErr = err
+				} else {
+					fmt.Printf("Deleted %d unregistered torrents\n", len(infoHashes))
+				}
+			}
+		}
+	}
+	return common.ItemsError(errorCnt, total, lastErr)
+}
+
+// Classify the torrent by the status of it's trackers.
+func getStatus(trackers client.TorrentTrackers) string {
+	if common.SeemsUnregisteredTorrent(trackers) {
+		return STATUS_UNREGISTERED
+	}
+	hasTimeout, hasError := false, false
+	for _, tracker := range trackers {
+		if tracker.Status == "working" {
+			return STATUS_OK
+		}
+		if tracker.SeemsTimeout() {
+			hasTimeout = true
+		} else if tracker.Status == "error" {
+			hasError = true
+		}
+	}
+	if hasTimeout {
+		return STATUS_TIMEOUT
+	}
+	if hasError {
+		return STATUS_ERROR
+	}
+	return STATUS_UNKNOWN
+}
+
+func printDomainStatuses(list []*DomainStatus) {
+	fmt.Printf("%-30s  %-6s  %-6s  %-12s  %-7s  %-6s  %-7s\n",
+		"Domain", "Total", "Ok", "Unregistered", "Timeout", "Error", "Unknown")
+	for _, domainStatus := range list {
+		domain := domainStatus.Domain
+		if domain == "" {
+			domain = "-"
+		}
+		util.PrintStringInWidth(os.Stdout, domain, 30, true)
+		fmt.Printf("  %-6d  %-6d  %-12d  %-7d  %-6d  %-7d\n", domainStatus.Total, domainStatus.Ok,
+			domainStatus.Unregistered, domainStatus.Timeout, domainStatus.Error, domainStatus.Unknown)
+	}
+	for _, domainStatus := range list {
+		if len(domainStatus.Msgs) == 0 {
+			continue
+		}
+		msgs := []string{}
+		for msg := range domainStatus.Msgs {
+			msgs = append(msgs, msg)
+		}
+		sort.Slice(msgs, func(i, j int) bool {
+			if domainStatus.Msgs[msgs[i]] != domainStatus.Msgs[msgs[j]] {
+				return domainStatus.Msgs[msgs[i]] > domainStatus.Msgs[msgs[j]]
+			}
+			return msgs[i] < msgs[j]
+		})
+		fmt.Printf("\nTracker messages of %s:\n", domainStatus.Domain)
+		for _, msg := range msgs {
+			fmt.Printf("  %-6d  %s\n", domainStatus.Msgs[msg], msg)
+		}
+	}
+}
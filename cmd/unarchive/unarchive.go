@@ -0,0 +1,120 @@
+package unarchive
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use: "unarchive {client} [infoHash]... [--all] [--category category] [--tag tag] [--filter filter] " +
+		"[--check]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "unarchive"},
+	Short:       `List or re-add torrents archived by "ptool archive" to client.`,
+	Long: `List or re-add torrents archived by "ptool archive" to client.
+If no [infoHash] args or condition flags are provided, it lists all archived torrents of client.
+Otherwise it re-adds the matched archived torrents (use --all flag to match all) to client
+with the original save path, category, tags, trackers, skipped files, speed limits and state (paused or not),
+then removes them from archive dir ("archiveDir" config, default is "` + config.DEFAULT_ARCHIVE_DIR + `" in config dir).
+
+The content files must still be in the original save path. Torrents that were completely downloaded
+(without skipped files) when archived are added with hash checking skipped, unless --check flag is set.
+Torrents that already exist in client are skipped. If any step of re-adding a torrent fails,
+it's archive entry is kept.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: unarchive,
+}
+
+var (
+	all      = false
+	check    = false
+	category = ""
+	tag      = ""
+	filter   = ""
+)
+
+func init() {
+	command.Flags().BoolVarP(&all, "all", "a", false, "Re-add all archived torrents of client")
+	command.Flags().BoolVarP(&check, "check", "", false,
+		"Do hash checking when re-adding torrents, even for those that were complete when archived")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false,
+		"Dry run. Only display torrents that would be re-added, do NOT actually modify client")
+	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
+	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
+	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func unarchive(cmd *cobra.Command, args []string) error {
+	clientName := args[0]
+	infoHashes := args[1:]
+	if config.GetClientConfig(clientName) == nil {
+		return fmt.Errorf("client %s not found", clientName)
+	}
+	entries, err := common.LoadArchiveEntries(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to load archive: %w", err)
+	}
+	if !all && len(infoHashes) == 0 && category == "" && tag == "" && filter == "" {
+		fmt.Printf("%-40s  %-19s  %-8s  %-8s  %-10s  %s\n", "InfoHash", "Archived", "Size", "Uploaded", "Category", "Name")
+		for _, entry := range entries {
+			fmt.Printf("%-40s  %-19s  %-8s  %-8s  %-10s  %s\n", entry.InfoHash, util.FormatTime(entry.Time),
+				util.BytesSize(float64(entry.Size)), util.BytesSize(float64(entry.Uploaded)), entry.Category, entry.Name)
+		}
+		return nil
+	}
+	entries = util.Filter(entries, func(entry *common.ArchiveEntry) bool {
+		if len(infoHashes) > 0 && !slices.Contains(infoHashes, entry.InfoHash) {
+			return false
+		}
+		if category != "" && entry.Category != category && !(category == constants.NONE && entry.Category == "") {
+			return false
+		}
+		if tag != "" {
+			torrent := &client.Torrent{Tags: entry.Tags}
+			if tag == constants.NONE && len(entry.Tags) > 0 || tag != constants.NONE && !torrent.HasAnyTag(tag) {
+				return false
+			}
+		}
+		return filter == "" || util.ContainsI(entry.Name, filter)
+	})
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	cntAll := len(entries)
+	for i, entry := range entries {
+		if torrent, err := clientInstance.GetTorrent(entry.InfoHash); err != nil {
+			fmt.Printf("✕ %s : failed to get torrent from client: %v (%d/%d)\n", entry.InfoHash, err, i+1, cntAll)
+			errorCnt++
+			lastErr = err
+			continue
+		} else if torrent != nil {
+			fmt.Printf("- %s : %s already exists in client, skip it (%d/%d)\n", entry.InfoHash, entry.Name, i+1, cntAll)
+			continue
+		}
+		if common.DryRun("re-add torrent %s (%s) to client %s, save path %q", entry.InfoHash, entry.Name,
+			clientName, entry.SavePath) {
+			continue
+		}
+		if err := entry.Unarchive(clientInstance, check); err != nil {
+			fmt.Printf("✕ %s : failed to unarchive %s: %v (%d/%d)\n", entry.InfoHash, entry.Name, err, i+1, cntAll)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		fmt.Printf("✓ %s : unarchived %s (%d/%d)\n", entry.InfoHash, entry.Name, i+1, cntAll)
+	}
+	return common.ItemsError(errorCnt, int64(len(entries)), lastErr)
+}
@@ -0,0 +1,31 @@
+package undelete
+
+import (
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/cmd/shell/suggest"
+)
+
+func init() {
+	cmd.AddShellCompletion("undelete", func(document *prompt.Document) []prompt.Suggest {
+		info := suggest.Parse(document)
+		if info.LastArgIndex < 1 {
+			return nil
+		}
+		if info.LastArgIsFlag {
+			return nil
+		}
+		entries, _ := common.LoadTrashEntries()
+		suggestions := []prompt.Suggest{}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Id, info.MatchingPrefix) {
+				suggestions = append(suggestions, prompt.Suggest{Text: entry.Id, Description: entry.Name})
+			}
+		}
+		return suggestions
+	})
+}
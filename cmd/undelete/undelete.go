@@ -0,0 +1,118 @@
+package undelete
+
+import (
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:         "undelete [id]... [--skip-check] [--purge]",
+	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "undelete"},
+	Short:       `List or restore torrents moved to trash by "ptool delete --trash".`,
+	Long: `List or restore torrents moved to trash by "ptool delete --trash".
+If no [id] args are provided, it lists all trash entries. Otherwise it restores the trash entries of [id]...
+(the id or info hash of trash entry): move the content files back to the original save path
+and re-add the torrents to the original client with the original category, tags and state (paused or not).
+
+Trash entries are permanently deleted (including the content files) after the retention time
+("trashRetention" config of ptool.toml, default is "` + config.DEFAULT_TRASH_RETENTION + `"),
+when running "ptool delete --trash" or "ptool undelete --purge".
+With --purge flag, it permanently deletes the trash entries of [id]... instead of restoring them;
+If no [id] args are provided, it purges all expired trash entries.`,
+	RunE: undelete,
+}
+
+var (
+	skipCheck = false
+	purge     = false
+)
+
+func init() {
+	command.Flags().BoolVarP(&skipCheck, "skip-check", "", false, "Skip hash checking when re-adding torrents")
+	command.Flags().BoolVarP(&purge, "purge", "", false,
+		"Permanently delete trash entries (including content files) instead of restoring them")
+	cmd.RootCmd.AddCommand(command)
+}
+
+func undelete(cmd *cobra.Command, args []string) error {
+	entries, err := common.LoadTrashEntries()
+	if err != nil {
+		return fmt.Errorf("failed to load trash: %w", err)
+	}
+	if len(args) == 0 {
+		if purge {
+			if common.DryRun("purge expired trash entries") {
+				return nil
+			}
+			cnt, err := common.PurgeExpiredTrash()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d expired trash entries purged.\n", cnt)
+			return nil
+		}
+		now := util.Now()
+		fmt.Printf("%-24s  %-19s  %-10s  %-8s  %-7s  %s\n", "Id", "Time", "Client", "Size", "Expired", "Name")
+		for _, entry := range entries {
+			fmt.Printf("%-24s  %-19s  %-10s  %-8s  %-7t  %s\n", entry.Id, util.FormatTime(entry.Time), entry.Client,
+				util.BytesSize(float64(entry.Size)), entry.Expired(now), entry.Name)
+		}
+		return nil
+	}
+	errorCnt := int64(0)
+	var lastErr error
+	for _, id := range args {
+		i := slices.IndexFunc(entries, func(entry *common.TrashEntry) bool {
+			return entry.Id == id || entry.InfoHash == id
+		})
+		if i == -1 {
+			log.Errorf("Trash entry %s not found", id)
+			errorCnt++
+			lastErr = constants.ErrNotFound
+			continue
+		}
+		entry := entries[i]
+		if purge {
+			if common.DryRun("permanently delete trash entry %s (%s)", entry.Id, entry.Name) {
+				continue
+			}
+			if err := entry.Purge(); err != nil {
+				log.Errorf("Failed to purge trash entry %s: %v", entry.Id, err)
+				errorCnt++
+				lastErr = err
+				continue
+			}
+			fmt.Printf("Trash entry %s (%s) purged\n", entry.Id, entry.Name)
+			continue
+		}
+		clientInstance, err := client.CreateClient(entry.Client)
+		if err != nil {
+			log.Errorf("Failed to create client %s: %v", entry.Client, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		if common.DryRun("restore torrent %s (%s) to client %s, save path %q", entry.Name, entry.InfoHash,
+			entry.Client, entry.SavePath) {
+			continue
+		}
+		if err := entry.Restore(clientInstance, skipCheck); err != nil {
+			log.Errorf("Failed to restore trash entry %s: %v", entry.Id, err)
+			errorCnt++
+			lastErr = err
+			continue
+		}
+		fmt.Printf("Torrent %s (%s) restored to client %s\n", entry.Name, entry.InfoHash, entry.Client)
+	}
+	return common.ItemsError(errorCnt, int64(len(args)), lastErr)
+}
@@ -0,0 +1,91 @@
+package verifytorrent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+// The max relative size difference between local contents and site torrent if site torrent size is not accurate.
+const SEARCH_SIZE_TOLERANCE = 0.01
+
+// Search sites for candidate torrents of the contents in savePath.
+// Each top-level entry (folder or file) of savePath is searched by it's parsed release title (and year),
+// the site torrents of the same size are returned, in "sitename.id" format.
+func searchCandidates(savePath string, sitenames []string) ([]string, error) {
+	entries, err := os.ReadDir(savePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read save path: %w", err)
+	}
+	siteInstances := map[string]site.Site{}
+	for _, sitename := range sitenames {
+		siteInstance, err := site.CreateSite(sitename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create site %s: %w", sitename, err)
+		}
+		siteInstances[sitename] = siteInstance
+	}
+	var candidates []string
+	for _, entry := range entries {
+		size, err := getContentsSize(filepath.Join(savePath, entry.Name()))
+		if err != nil || size == 0 {
+			log.Debugf("Skip %q: size=%d, err=%v", entry.Name(), size, err)
+			continue
+		}
+		keyword := entry.Name()
+		if release := releasename.Parse(entry.Name()); release.Title != "" {
+			keyword = release.Title
+			if release.Year > 0 {
+				keyword += fmt.Sprintf(" %d", release.Year)
+			}
+		}
+		sitesTorrents, errs := util.ParallelMap(sitenames, config.GetConcurrency(), config.GetItemTimeout(),
+			func(_ context.Context, sitename string) ([]*site.Torrent, error) {
+				return siteInstances[sitename].SearchTorrents(keyword, "")
+			})
+		for i, sitename := range sitenames {
+			if errs[i] != nil {
+				log.Warnf("Failed to search site %s for %q: %v", sitename, keyword, errs[i])
+				continue
+			}
+			for _, torrent := range sitesTorrents[i] {
+				if torrent.Size != size && (torrent.IsSizeAccurate ||
+					math.Abs(float64(torrent.Size-size)) > float64(size)*SEARCH_SIZE_TOLERANCE) {
+					continue
+				}
+				log.Infof("Found candidate torrent %s.%s (%s) of %q",
+					sitename, torrent.ID(), torrent.Name, entry.Name())
+				candidates = append(candidates, sitename+"."+torrent.ID())
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// Return the total size of all files in path (a folder or a single file).
+func getContentsSize(path string) (size int64, err error) {
+	err = filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
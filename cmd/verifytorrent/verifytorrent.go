@@ -13,16 +13,18 @@ import (
 
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/rclone"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
 )
 
 var command = &cobra.Command{
 	Use: "verifytorrent {torrentFilename | torrentId | torrentUrl}... " +
 		"{--save-path dir | --content-path path | --use-comment-meta | --rclone-lsjson-file file | " +
-		"--rclone-save-path path} [--check | --check-quick]",
+		"--rclone-save-path path} [--check | --check-quick] [--match [--min-match percent] [--search sites]]",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "verifytorrent"},
 	Aliases:     []string{"verify", "verifytorrents"},
 	Short:       "Verify .torrent (metainfo) files are consistent with local disk contents.",
@@ -41,6 +43,7 @@ Exact one (not less or more) of the following flags must be set.
   It can only be used with single torrent arg.
 * --use-comment-meta : extract save path from torrent's comment field and use it.
   The "ptool export" and some other cmds can use the same flag to write save path to generated torrent files.
+  The save path is the one of client, use "--map-save-path" or "--map-client" to map it to ptool's file system.
 * --rclone-lsjson-file : The filename of index contents that "rclone lsjson --recursive <path>" outputs
   ptool treats <path> as the save path of torrent contents and verify torrents against the index contents.
   For more, see https://github.com/rclone/rclone and https://rclone.org/commands/rclone_lsjson/ .
@@ -49,8 +52,22 @@ Exact one (not less or more) of the following flags must be set.
   and use it's output as index contents. E.g. "remote:Downloads".
 
 By default it will only examine file meta infos (file path & size).
-If --check flag is set, it will also do the hash checking.`, constants.HELP_TORRENT_ARGS),
-	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+If --check flag is set, it will also do the hash checking. The contents files are read sequentially
+with read-ahead (--hash-read-ahead, default "64MiB") and the pieces are hashed concurrently by multiple workers
+(--hash-workers, default number of CPUs). Set "hashWorkers" and "hashReadAhead" in config file to change the defaults.
+Use "--progress json" flag to emit machine-readable progress records (JSON Lines) to stderr.
+
+If --match flag is set, instead of failing on the first mismatch, it reports how much of each torrent's contents
+match with local disk contents (percentage), which is useful to find the reseed candidates of local data (no client
+required). A file matches if it exists with the same size (and contents, if --check flag is also set). With --check,
+the percentage of v1 torrent is the percentage of matched pieces; Otherwise it's the percentage of matched size.
+A torrent with match percentage >= --min-match (default 100) is considered as success.
+Use "--search sites" flag (comma-separated list of sites or groups) together with --match and --save-path
+to search the sites for candidate torrents of each top-level file or folder of save path (by it's release title),
+the site torrents of the same size are added to torrent args, e.g.:
+  ptool verifytorrent --match --min-match 90 --save-path /root/Downloads --search mteam,hdsky`,
+		constants.HELP_TORRENT_ARGS),
+	Args: cobra.MatchAll(cobra.ArbitraryArgs, cobra.OnlyValidArgs),
 	RunE: verifytorrent,
 }
 
@@ -63,6 +80,8 @@ var (
 	checkQuick           = false
 	forceLocal           = false
 	showAll              = false
+	matchMode            = false
+	minMatch             = float64(0)
 	contentPath          = ""
 	defaultSite          = ""
 	savePath             = ""
@@ -70,17 +89,21 @@ var (
 	rcloneSavePath       = ""
 	rcloneBinary         = ""
 	rcloneFlags          = ""
+	searchSites          = ""
+	progressMode         = ""
+	mapClient            = ""
 	mapSavePaths         []string
 )
 
 func init() {
+	cmd.AddEnumFlagP(command, &progressMode, "progress", "", common.ProgressFlag)
 	command.Flags().BoolVarP(&showSum, "sum", "", false, "Show torrents summary only")
 	command.Flags().BoolVarP(&renameOk, "rename-ok", "", false,
 		"Rename verification successed .torrent file to *"+constants.FILENAME_SUFFIX_OK+
-			" unless it's name already has that suffix")
+			" unless it's name already has that suffix. If used with --match, --min-match must be 100")
 	command.Flags().BoolVarP(&renameFail, "rename-fail", "", false,
 		"Rename verification failed .torrent file to *"+constants.FILENAME_SUFFIX_FAIL+
-			" unless it's name already has that suffix")
+			" unless it's name already has that suffix. If used with --match, --min-match must be 100")
 	command.Flags().BoolVarP(&useCommentMeta, "use-comment-meta", "", false,
 		`Extract save path from "comment" field of .torrent file and verify against it`)
 	command.Flags().BoolVarP(&checkHash, "check", "", false, "Do hash checking when verifying torrent files")
@@ -89,6 +112,17 @@ func init() {
 			"only the first and last piece of each file will do hash computing")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all arg as local torrent filename")
 	command.Flags().BoolVarP(&showAll, "all", "a", false, "Show all info")
+	command.Flags().BoolVarP(&matchMode, "match", "", false,
+		"Report the match percentage of torrent contents with disk contents instead of pass / fail")
+	command.Flags().Float64VarP(&minMatch, "min-match", "", 100,
+		"Used with --match. The min match percentage (0-100) of torrent contents to be considered as success")
+	command.Flags().StringVarP(&searchSites, "search", "", "",
+		"Used with --match and --save-path. Comma-separated list of sites or groups to search candidate torrents "+
+			"of save path contents")
+	command.Flags().Int64VarP(&config.HashWorkers, "hash-workers", "", 0,
+		"Used with --check or --check-quick. Number of workers of hashing pieces. 0 = use config or number of CPUs")
+	command.Flags().StringVarP(&config.HashReadAhead, "hash-read-ahead", "", "",
+		`Used with --check or --check-quick. Size of contents data read ahead for hashing, e.g. "256MiB"`)
 	command.Flags().StringVarP(&contentPath, "content-path", "", "",
 		"The path of torrent content. Can only be used with single torrent arg")
 	command.Flags().StringVarP(&defaultSite, "site", "", "", "Set default site of torrent url")
@@ -105,6 +139,9 @@ func init() {
 	command.Flags().StringArrayVarP(&mapSavePaths, "map-save-path", "", nil,
 		`Used with "--use-comment-meta". Map save path from torrent comment to the file system of ptool. `+
 			`Format: "comment_save_path|ptool_save_path". `+constants.HELP_ARG_PATH_MAPPERS)
+	command.Flags().StringVarP(&mapClient, "map-client", "", "",
+		`Used with "--use-comment-meta". Map save path from torrent comment to the file system of ptool `+
+			`using the "pathMappings" of this client in config file, if "--map-save-path" is not set`)
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -113,12 +150,41 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("exact one (not less or more) of the --use-comment-meta, --save-path, --content-path, " +
 			"--rclone-save-path and --rclone-lsjson-file flags must be set")
 	}
-	if !useCommentMeta && len(mapSavePaths) > 0 {
-		return fmt.Errorf("--map-save-path must be used with --use-comment-meta flag")
+	if !useCommentMeta && (len(mapSavePaths) > 0 || mapClient != "") {
+		return fmt.Errorf("--map-save-path and --map-client must be used with --use-comment-meta flag")
+	}
+	if mapClient != "" && config.GetClientConfig(mapClient) == nil {
+		return fmt.Errorf("client %s not found", mapClient)
 	}
 	if showSum && showAll {
 		return fmt.Errorf("--sum and --all flags are NOT compatible")
 	}
+	if config.HashWorkers < 0 {
+		return fmt.Errorf("invalid --hash-workers %d", config.HashWorkers)
+	}
+	if config.HashReadAhead != "" {
+		if size, err := util.RAMInBytes(config.HashReadAhead); err != nil || size <= 0 {
+			return fmt.Errorf("invalid --hash-read-ahead %q", config.HashReadAhead)
+		}
+	}
+	if matchMode {
+		if rcloneSavePath != "" || rcloneLsjsonFilename != "" || checkQuick {
+			return fmt.Errorf("--match flag can NOT be used with --rclone-* or --check-quick flags")
+		}
+		if minMatch < 0 || minMatch > 100 {
+			return fmt.Errorf("invalid --min-match %g", minMatch)
+		}
+		// a partially matched torrent is not verified
+		if renameOk && minMatch < 100 {
+			return fmt.Errorf("--rename-ok flag can only be used with --match if --min-match is 100")
+		}
+	}
+	if searchSites != "" && (!matchMode || savePath == "") {
+		return fmt.Errorf("--search flag must be used with --match and --save-path flags")
+	}
+	if len(args) == 0 && searchSites == "" {
+		return fmt.Errorf("at least one torrent arg is required")
+	}
 	if rcloneSavePath != "" || rcloneLsjsonFilename != "" {
 		if checkHash || checkQuick {
 			return fmt.Errorf("--rclone-* can NOT be used with --check or --check-quick flags")
@@ -132,10 +198,19 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if searchSites != "" {
+		candidates, err := searchCandidates(savePath, config.ParseGroupAndOtherNames(util.SplitCsv(searchSites)...))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Found %d candidate torrents in sites\n", len(candidates))
+		torrents = append(torrents, candidates...)
+	}
 	if len(torrents) > 1 && contentPath != "" {
 		return fmt.Errorf("--content-path flag can only be used to verify single torrent")
 	}
 	errorCnt := int64(0)
+	var lastErr error
 	checkMode := int64(0)
 	checkModeStr := constants.NONE
 	if checkQuick {
@@ -173,16 +248,15 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to parse rclone lsjson file: %w", err)
 		}
 	}
-	var savePathMapper *common.PathMapper
-	if len(mapSavePaths) > 0 {
-		savePathMapper, err = common.NewPathMapper(mapSavePaths)
-		if err != nil {
-			return fmt.Errorf("invalid map-save-path (s): %w", err)
-		}
+	savePathMapper, err := common.GetSavePathMapper(mapClient, mapSavePaths, false)
+	if err != nil {
+		return err
 	}
 
 	statistics := common.NewTorrentsStatistics()
-	for _, torrent := range torrents {
+	progress := common.NewProgress(progressMode, "verifytorrent", int64(len(torrents)))
+	for i, torrent := range torrents {
+		progress.Start(int64(i+1), torrent, 0)
 		_, tinfo, _, _, _, _, isLocal, err :=
 			helper.GetTorrentContent(torrent, defaultSite, forceLocal, false, stdinTorrentContents, false, nil)
 		if err != nil {
@@ -191,8 +265,11 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			}
 			statistics.UpdateTinfo(common.TORRENT_INVALID, nil)
 			errorCnt++
+			lastErr = err
+			progress.Finish(err)
 			continue
 		}
+		progress.Stage("verify")
 		if showAll {
 			tinfo.Fprint(os.Stdout, torrent, true)
 		}
@@ -218,16 +295,34 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 				}
 				statistics.UpdateTinfo(common.TORRENT_FAILURE, tinfo)
 				errorCnt++
+				lastErr = err
+				progress.Finish(err)
 				continue
 			}
 		}
-		if rcloneSavePathFs != nil {
+		if matchMode {
+			log.Infof("Matching %s (savepath=%s, contentpath=%s, checkhash=%t)",
+				torrent, savePath, contentPath, checkHash)
+			var result *torrentutil.MatchResult
+			if result, err = tinfo.Match(savePath, contentPath, checkHash); err == nil && result.Percent() < minMatch {
+				err = fmt.Errorf("%.1f%% matched (files %d/%d, size %s/%s)", result.Percent(), result.MatchedFiles,
+					result.Files, util.BytesSize(float64(result.MatchedSize)), util.BytesSize(float64(result.Size)))
+			} else if err == nil && !showSum {
+				fmt.Printf("✓ torrent %s: %.1f%% matched (files %d/%d, size %s/%s, hash check = %s)\n",
+					torrent, result.Percent(), result.MatchedFiles, result.Files,
+					util.BytesSize(float64(result.MatchedSize)), util.BytesSize(float64(result.Size)), checkModeStr)
+			}
+		} else if rcloneSavePathFs != nil {
 			log.Infof("Verifying %s against rclone lsjson output", torrent)
 			err = tinfo.VerifyAgaintSavePathFs(rcloneSavePathFs)
 		} else {
 			log.Infof("Verifying %s (savepath=%s, contentpath=%s, checkhash=%t)", torrent, savePath, contentPath, checkHash)
-			_, err = tinfo.Verify(savePath, contentPath, checkMode)
+			_, err = tinfo.Verify(savePath, contentPath, checkMode, &torrentutil.HashOptions{
+				Workers:   config.GetHashWorkers(),
+				ReadAhead: config.GetHashReadAhead(),
+			})
 		}
+		progress.Finish(err)
 		if err != nil {
 			if !showSum {
 				fmt.Printf("X torrent %s: contents do NOT match with disk content(s) (hash check = %s): %v\n",
@@ -235,7 +330,9 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			}
 			statistics.UpdateTinfo(common.TORRENT_FAILURE, tinfo)
 			errorCnt++
-			if isLocal && torrent != "-" && renameFail && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_FAIL) {
+			lastErr = err
+			if isLocal && torrent != "-" && renameFail && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_FAIL) &&
+				!common.DryRun("rename %s to *%s", torrent, constants.FILENAME_SUFFIX_FAIL) {
 				if err := os.Rename(torrent, util.TrimAnySuffix(torrent,
 					constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_FAIL); err != nil {
 					log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_FAIL, err)
@@ -243,13 +340,14 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 			}
 		} else {
 			statistics.UpdateTinfo(common.TORRENT_SUCCESS, tinfo)
-			if isLocal && torrent != "-" && renameOk && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_OK) {
+			if isLocal && torrent != "-" && renameOk && !strings.HasSuffix(torrent, constants.FILENAME_SUFFIX_OK) &&
+				!common.DryRun("rename %s to *%s", torrent, constants.FILENAME_SUFFIX_OK) {
 				if err := os.Rename(torrent, util.TrimAnySuffix(torrent,
 					constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_OK); err != nil {
 					log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_OK, err)
 				}
 			}
-			if !showSum {
+			if !showSum && !matchMode {
 				fmt.Printf("✓ torrent %s: contents match with disk content(s) (hash check = %s)\n", torrent, checkModeStr)
 			}
 		}
@@ -259,8 +357,7 @@ func verifytorrent(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("\n")
 	statistics.Print(os.Stdout)
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	err = common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
+	progress.End(err)
+	return err
 }
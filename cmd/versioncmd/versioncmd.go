@@ -17,21 +17,25 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:   "version",
-	Short: "Display ptool version.",
-	Long:  `Display ptool version.`,
-	Args:  cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
-	RunE:  versioncmd,
+	Use:         "version",
+	Annotations: map[string]string{cmd.ANNOTATION_NO_SECRETS: "true"},
+	Short:       "Display ptool version.",
+	Long:        `Display ptool version.`,
+	Args:        cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE:        versioncmd,
 }
 
 var (
-	showJson    = false
-	impersonate string
+	showJson         = false
+	listImpersonates = false
+	impersonate      string
 )
 
 func init() {
 	command.Flags().BoolVarP(&showJson, "json", "", false, "Show output in json format")
 	command.Flags().StringVarP(&impersonate, "show-impersonate", "", "", "Show details of specified impersonate and exit")
+	command.Flags().BoolVarP(&listImpersonates, "list-impersonates", "", false,
+		"List all supported impersonates (including custom ones defined in config file) and exit")
 	cmd.RootCmd.AddCommand(command)
 }
 
@@ -44,6 +48,21 @@ func versioncmd(cmd *cobra.Command, args []string) error {
 		impersonateProfile.Print()
 		return nil
 	}
+	if listImpersonates {
+		fmt.Printf("<impersonates (default: *; custom: C)>\n")
+		fmt.Printf("%-15s  %-9s  %-5s  %s\n", "Name", "Navigator", "Flags", "Comment")
+		for _, p := range impersonateutil.GetAllProfiles() {
+			flags := ""
+			if p.Name == impersonateutil.DEFAULT_IMPERSONATE {
+				flags += "*"
+			}
+			if p.Custom {
+				flags += "C"
+			}
+			fmt.Printf("%-15s  %-9s  %-5s  %s\n", p.Name, p.Navigator, flags, p.Comment)
+		}
+		return nil
+	}
 	configFile := filepath.Join(config.ConfigDir, config.ConfigFile)
 	if showJson {
 		versionStr, versionLabel, _ := strings.Cut(strings.TrimPrefix(version.Version, "v"), "-")
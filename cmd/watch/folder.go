@@ -0,0 +1,131 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/common"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const (
+	// sub folder of watch folder that processed files are moved to
+	WATCH_FOLDER_DONE   = "done"
+	WATCH_FOLDER_FAILED = "failed"
+	// files modified within this time may still be being written and are skipped in daemon mode
+	WATCH_FOLDER_MIN_AGE = 3 * time.Second
+)
+
+// Add every .torrent file and .magnet file (text file of a magnet link) in the folder to it's client,
+// then move the file to "done" or "failed" sub folder. Files added failed due to network error are left as is.
+// Return the count of files failed to add.
+func processWatchFolder(folder *config.WatchFolderConfigStruct, once bool) (errorCnt int64) {
+	entries, err := os.ReadDir(folder.Dir)
+	if err != nil {
+		log.Errorf("Failed to read watch folder %s: %v", folder.Dir, err)
+		return 1
+	}
+	var clientInstance client.Client
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".torrent" && ext != ".magnet") {
+			continue
+		}
+		if !once {
+			if info, err := entry.Info(); err != nil || time.Since(info.ModTime()) < WATCH_FOLDER_MIN_AGE {
+				continue
+			}
+		}
+		filename := filepath.Join(folder.Dir, entry.Name())
+		if clientInstance == nil {
+			if clientInstance, err = client.CreateClient(folder.Client); err != nil {
+				log.Errorf("Failed to create client %s: %v", folder.Client, err)
+				return errorCnt + 1
+			}
+		}
+		err := addWatchFolderFile(clientInstance, folder, filename, ext)
+		if err != nil {
+			fmt.Printf("✕ %s => %s: %v\n", filename, folder.Client, err)
+			errorCnt++
+			if util.AsNetworkError(err) {
+				continue
+			}
+		} else {
+			fmt.Printf("✓ %s => %s\n", filename, folder.Client)
+		}
+		if flags.DryRun {
+			continue
+		}
+		subFolder := WATCH_FOLDER_DONE
+		if err != nil {
+			subFolder = WATCH_FOLDER_FAILED
+		}
+		if err := moveWatchFolderFile(filename, filepath.Join(folder.Dir, subFolder)); err != nil {
+			log.Errorf("Failed to move processed file %s: %v", filename, err)
+		}
+	}
+	return errorCnt
+}
+
+func addWatchFolderFile(clientInstance client.Client, folder *config.WatchFolderConfigStruct,
+	filename string, ext string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if ext == ".magnet" {
+		content = []byte(strings.TrimSpace(string(content)))
+		if !util.IsPureTorrentUrl(string(content)) {
+			return fmt.Errorf("invalid magnet file")
+		}
+	}
+	var tinfo *torrentutil.TorrentMeta
+	if ext != ".magnet" {
+		if tinfo, err = torrentutil.ParseTorrent(content); err != nil {
+			return fmt.Errorf("invalid torrent: %w", err)
+		}
+	}
+	if flags.DryRun {
+		return nil
+	}
+	option := &client.TorrentOption{
+		Category: folder.Category,
+		SavePath: folder.SavePath,
+		Pause:    folder.Paused,
+	}
+	if folder.Tags != "" {
+		option.Tags = util.SplitCsv(folder.Tags)
+	}
+	if err = clientInstance.AddTorrent(content, option, nil); err != nil {
+		return err
+	}
+	if tinfo != nil {
+		if err := common.ApplyFilePriorityRules(clientInstance, tinfo.InfoHash); err != nil {
+			log.Warnf("Failed to apply file priority rules to torrent %s: %v", tinfo.InfoHash, err)
+		}
+	}
+	return nil
+}
+
+// Move file to dir, creating dir if not exists. Existing file of same name in dir is NOT overwritten,
+// the moved file is renamed with a timestamp suffix instead.
+func moveWatchFolderFile(filename string, dir string) error {
+	if err := os.MkdirAll(dir, constants.PERM_DIR); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, filepath.Base(filename))
+	if _, err := os.Stat(target); err == nil {
+		target += fmt.Sprintf(".%d", time.Now().Unix())
+	}
+	return os.Rename(filename, target)
+}
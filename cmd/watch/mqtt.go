@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	DEFAULT_MQTT_STATUS_TOPIC = "ptool/{client}/status"
+	DEFAULT_MQTT_EVENT_TOPIC  = "ptool/{client}/event"
+	MQTT_TIMEOUT              = 10 * time.Second
+	// "At least once" delivery. Both topics need it: the retained status is the state that the broker
+	// keeps for new subscribers (dashboards), and the events are one-shot.
+	MQTT_QOS = byte(1)
+)
+
+// Publish client status and torrent events of watched clients to the "mqtt" broker of config,
+// e.g. for Home Assistant dashboards. A nil *mqttPublisher means MQTT is not enabled, all methods of it are nil-safe.
+type mqttPublisher struct {
+	client      mqtt.Client
+	statusTopic string
+	eventTopic  string
+}
+
+// The payload of status topic.
+type mqttClientStatus struct {
+	Time               int64  `json:"time"`
+	Client             string `json:"client"`
+	DownloadSpeed      int64  `json:"downloadSpeed"`
+	UploadSpeed        int64  `json:"uploadSpeed"`
+	DownloadSpeedLimit int64  `json:"downloadSpeedLimit"` // <= 0 means no limit
+	UploadSpeedLimit   int64  `json:"uploadSpeedLimit"`   // <= 0 means no limit
+	FreeSpaceOnDisk    int64  `json:"freeSpaceOnDisk"`    // -1 means unknown
+	UnfinishedSize     int64  `json:"unfinishedSize"`
+}
+
+// Connect to the broker. The connection is retried and re-established in background if it fails.
+func newMqttPublisher(broker string) (*mqttPublisher, error) {
+	hostname, _ := os.Hostname()
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(fmt.Sprintf("ptool-%s-%d", hostname, os.Getpid())).
+		SetConnectTimeout(MQTT_TIMEOUT).
+		SetAutoReconnect(true).
+		SetConnectRetry(true)
+	options.OnConnectionLost = func(_ mqtt.Client, err error) {
+		log.Warnf("MQTT connection lost: %v", err)
+	}
+	mqttClient := mqtt.NewClient(options)
+	if token := mqttClient.Connect(); token.WaitTimeout(MQTT_TIMEOUT) && token.Error() != nil {
+		return nil, token.Error()
+	}
+	publisher := &mqttPublisher{
+		client:      mqttClient,
+		statusTopic: config.Get().MqttStatusTopic,
+		eventTopic:  config.Get().MqttEventTopic,
+	}
+	if publisher.statusTopic == "" {
+		publisher.statusTopic = DEFAULT_MQTT_STATUS_TOPIC
+	}
+	if publisher.eventTopic == "" {
+		publisher.eventTopic = DEFAULT_MQTT_EVENT_TOPIC
+	}
+	return publisher, nil
+}
+
+// Publish the current status of client (retained).
+func (publisher *mqttPublisher) publishStatus(clientName string) {
+	if publisher == nil {
+		return
+	}
+	clientInstance, err := client.CreateClient(clientName)
+	if err != nil {
+		log.Errorf("Failed to create client %s: %v", clientName, err)
+		return
+	}
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		log.Errorf("Failed to get client %s status: %v", clientName, err)
+		return
+	}
+	publisher.publish(publisher.statusTopic, clientName, true, &mqttClientStatus{
+		Time:               util.Now(),
+		Client:             clientName,
+		DownloadSpeed:      status.DownloadSpeed,
+		UploadSpeed:        status.UploadSpeed,
+		DownloadSpeedLimit: status.DownloadSpeedLimit,
+		UploadSpeedLimit:   status.UploadSpeedLimit,
+		FreeSpaceOnDisk:    status.FreeSpaceOnDisk,
+		UnfinishedSize:     status.UnfinishedSize,
+	})
+}
+
+// Publish torrent events of client, with the same payload as the output of "events" command.
+func (publisher *mqttPublisher) publishEvents(clientName string, events []*client.TorrentEvent) {
+	if publisher == nil {
+		return
+	}
+	for _, event := range events {
+		publisher.publish(publisher.eventTopic, clientName, false, event)
+	}
+}
+
+func (publisher *mqttPublisher) publish(topic string, clientName string, retained bool, payload any) {
+	topic = strings.ReplaceAll(topic, "{client}", clientName)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("Failed to marshal MQTT payload: %v", err)
+		return
+	}
+	if flags.DryRun {
+		log.Warnf("Dry-run: publish MQTT topic %s: %s", topic, data)
+		return
+	}
+	token := publisher.client.Publish(topic, MQTT_QOS, retained, data)
+	if !token.WaitTimeout(MQTT_TIMEOUT) {
+		log.Warnf("Publish MQTT topic %s timeout", topic)
+	} else if token.Error() != nil {
+		log.Errorf("Failed to publish MQTT topic %s: %v", topic, token.Error())
+	}
+}
+
+func (publisher *mqttPublisher) close() {
+	if publisher != nil {
+		publisher.client.Disconnect(250)
+	}
+}
@@ -0,0 +1,169 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+// The state of client in quiet hours, persisted in config dir, so the torrents paused by ptool
+// can still be resumed after the quiet hours if ptool is restarted in the middle of it.
+// The state file exists only during the quiet hours.
+type quietHoursState struct {
+	Time       int64    `json:"time"`
+	Client     string   `json:"client"`
+	Action     string   `json:"action"`
+	InfoHashes []string `json:"infoHashes"` // torrents paused by ptool, for "pause" action
+}
+
+func quietHoursStateFile(clientName string) string {
+	return filepath.Join(config.ConfigDir, fmt.Sprintf("quiethours-%s.json", clientName))
+}
+
+// Return nil if client is not in quiet hours (state file does not exist).
+func loadQuietHoursState(clientName string) (*quietHoursState, error) {
+	data, err := os.ReadFile(quietHoursStateFile(clientName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &quietHoursState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (state *quietHoursState) save() error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(quietHoursStateFile(state.Client), data, constants.PERM)
+}
+
+// Return true if client has quiet hours configured, or is currently in quiet hours
+// (e.g. the quietHours config was removed during it), which must be ended by watch.
+func hasQuietHours(clientName string) bool {
+	if clientConfig := config.GetClientConfig(clientName); clientConfig != nil && clientConfig.QuietHours != "" {
+		return true
+	}
+	_, err := os.Stat(quietHoursStateFile(clientName))
+	return err == nil
+}
+
+// Return true if now (in it's location) is in any of the quietHours time ranges, e.g. "23:00-07:00".
+func isQuietHours(quietHours string, now time.Time) (bool, error) {
+	ranges, err := util.ParseDailyTimeRanges(quietHours)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(ranges, func(r *util.DailyTimeRange) bool { return r.Contains(now) }), nil
+}
+
+// Start or end the quiet hours of client, if now enters or leaves the "quietHours" of client.
+// At the start, it pauses all active torrents of client, or enables the alt speed limits of client,
+// depending on the "quietHoursAction" config. At the end, it resumes the torrents paused by it
+// (those paused by user are left untouched), or disables the alt speed limits.
+func checkQuietHours(clientInstance client.Client, now time.Time) error {
+	clientConfig := clientInstance.GetClientConfig()
+	inQuietHours := false
+	if clientConfig.QuietHours != "" {
+		var err error
+		if inQuietHours, err = isQuietHours(clientConfig.QuietHours, now); err != nil {
+			return fmt.Errorf("invalid quietHours: %w", err)
+		}
+	}
+	state, err := loadQuietHoursState(clientConfig.Name)
+	if err != nil {
+		return fmt.Errorf("failed to load quiet hours state: %w", err)
+	}
+	if inQuietHours == (state != nil) {
+		return nil
+	}
+	if inQuietHours {
+		action := clientConfig.QuietHoursAction
+		if action == "" {
+			action = config.QUIET_HOURS_ACTION_PAUSE
+		}
+		if flags.DryRun {
+			log.Warnf("Dry-run: start quiet hours of client %s (%s)", clientConfig.Name, action)
+			return nil
+		}
+		state = &quietHoursState{Time: util.Now(), Client: clientConfig.Name, Action: action}
+		if action == config.QUIET_HOURS_ACTION_ALTSPEED {
+			if err = clientInstance.SetAltSpeedEnabled(true); err != nil {
+				return fmt.Errorf("failed to enable alt speed limits: %w", err)
+			}
+		} else {
+			torrents, err := clientInstance.GetTorrents("", "", true)
+			if err != nil {
+				return fmt.Errorf("failed to get torrents: %w", err)
+			}
+			for _, torrent := range torrents {
+				// "completed": paused seeding torrent
+				if torrent.State != "paused" && torrent.State != "completed" {
+					state.InfoHashes = append(state.InfoHashes, torrent.InfoHash)
+				}
+			}
+		}
+		// save state before pausing, so no torrent would be left paused forever if ptool is killed in between
+		if err = state.save(); err != nil {
+			return fmt.Errorf("failed to save quiet hours state: %w", err)
+		}
+		if len(state.InfoHashes) > 0 {
+			if err = clientInstance.PauseTorrents(state.InfoHashes); err != nil {
+				return fmt.Errorf("failed to pause torrents: %w", err)
+			}
+		}
+		log.Warnf("Client %s quiet hours started (%s), paused %d torrents", clientConfig.Name, action,
+			len(state.InfoHashes))
+		return nil
+	}
+	if flags.DryRun {
+		log.Warnf("Dry-run: end quiet hours of client %s (%s)", clientConfig.Name, state.Action)
+		return nil
+	}
+	if state.Action == config.QUIET_HOURS_ACTION_ALTSPEED {
+		if err = clientInstance.SetAltSpeedEnabled(false); err != nil {
+			return fmt.Errorf("failed to disable alt speed limits: %w", err)
+		}
+	}
+	if len(state.InfoHashes) > 0 {
+		// skip torrents that have been deleted during the quiet hours
+		torrents, err := clientInstance.GetTorrents("", "", true)
+		if err != nil {
+			return fmt.Errorf("failed to get torrents: %w", err)
+		}
+		infoHashes := []string{}
+		for _, torrent := range torrents {
+			if slices.Contains(state.InfoHashes, torrent.InfoHash) {
+				infoHashes = append(infoHashes, torrent.InfoHash)
+			}
+		}
+		if len(infoHashes) > 0 {
+			if err = clientInstance.ResumeTorrents(infoHashes); err != nil {
+				return fmt.Errorf("failed to resume torrents: %w", err)
+			}
+		}
+	}
+	if err = os.Remove(quietHoursStateFile(clientConfig.Name)); err != nil {
+		return fmt.Errorf("failed to remove quiet hours state: %w", err)
+	}
+	log.Warnf("Client %s quiet hours ended (%s), resumed %d torrents", clientConfig.Name, state.Action,
+		len(state.InfoHashes))
+	return nil
+}
@@ -0,0 +1,42 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsQuietHours(t *testing.T) {
+	location := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		name       string
+		quietHours string
+		now        time.Time
+		expected   bool
+		wantErr    bool
+	}{
+		{"overnight", "23:00-07:00", time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), true, false},
+		{"daytime", "23:00-07:00", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false, false},
+		{"second range", "23:00-07:00,12:00-13:30", time.Date(2024, 1, 1, 13, 29, 59, 0, time.UTC), true, false},
+		{"after all ranges", "23:00-07:00,12:00-13:30", time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC), false, false},
+		// the ranges are in the location of now: 18:00 UTC is 02:00 in UTC+8
+		{"location", "23:00-07:00", time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC).In(location), true, false},
+		{"invalid", "23:00", time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := isQuietHours(test.quietHours, test.now)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %t", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"slices"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	// The minimal upload speed limit set to a torrent by upload throttling.
+	THROTTLE_MIN_LIMIT = 10 * 1024
+	// A torrent uploading at >= this ratio of it's speed limit is considered to be capped by the limit.
+	THROTTLE_SATURATED_RATIO = 0.9
+	// The upload speed limit of a torrent is updated only if the new one differs by more than this ratio.
+	THROTTLE_UPDATE_RATIO = 0.1
+)
+
+// Return enabled [[uploadThrottles]] rules of client.
+func getUploadThrottles(clientName string) []*config.UploadThrottleConfigStruct {
+	return util.Filter(config.Get().UploadThrottles, func(rule *config.UploadThrottleConfigStruct) bool {
+		return !rule.Disabled && (len(rule.Clients) == 0 || slices.Contains(rule.Clients, clientName))
+	})
+}
+
+// Enforce the aggregate upload speed limit of each rule on the active torrents of matched trackers,
+// by re-allocating the (per-torrent) upload speed limits of them, based on their current upload speeds.
+// Each torrent uses the first matched rule.
+func throttleUploads(clientInstance client.Client, rules []*config.UploadThrottleConfigStruct) error {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return err
+	}
+	throttled := map[string]bool{}
+	for _, rule := range rules {
+		limit, _ := util.RAMInBytes(rule.UploadSpeedLimit)
+		if limit <= 0 {
+			continue
+		}
+		matched := util.Filter(torrents, func(torrent *client.Torrent) bool {
+			return !throttled[torrent.InfoHash] && (torrent.State == "seeding" || torrent.State == "downloading") &&
+				slices.ContainsFunc(rule.Trackers, torrent.MatchTrackerOrSubdomain)
+		})
+		if len(matched) == 0 {
+			continue
+		}
+		speed := int64(0)
+		for _, torrent := range matched {
+			throttled[torrent.InfoHash] = true
+			speed += torrent.UploadSpeed
+		}
+		limits := allocateUploadSpeed(matched, limit)
+		updated := 0
+		for i, torrent := range matched {
+			current := torrent.UploadedSpeedLimit
+			diff := limits[i] - current
+			if diff < 0 {
+				diff = -diff
+			}
+			if current > 0 && float64(diff) <= float64(current)*THROTTLE_UPDATE_RATIO {
+				continue
+			}
+			if flags.DryRun {
+				log.Warnf("Dry-run: set torrent %s (%s) upload speed limit to %s/s", torrent.InfoHash, torrent.Name,
+					util.BytesSize(float64(limits[i])))
+				continue
+			}
+			if err := clientInstance.ModifyTorrent(torrent.InfoHash,
+				&client.TorrentOption{UploadSpeedLimit: limits[i]}, nil); err != nil {
+				log.Errorf("Failed to set torrent %s upload speed limit: %v", torrent.InfoHash, err)
+				continue
+			}
+			updated++
+		}
+		if updated > 0 {
+			log.Infof("Client %s upload throttle %s: %d torrents uploading at %s/s (limit %s/s), updated %d limits",
+				clientInstance.GetName(), rule.Name, len(matched), util.BytesSize(float64(speed)),
+				util.BytesSize(float64(limit)), updated)
+		}
+	}
+	return nil
+}
+
+// Allocate total upload speed to torrents by max-min fairness ("water filling") of their demands,
+// returning the upload speed limit of each torrent. A torrent capped by it's current limit may upload faster,
+// so it demands all it can get; other torrents demand their current speed with some headroom.
+// Any leftover (when all demands are satisfied) is shared evenly.
+// The sum of returned limits never exceeds total: the minimal limit of a torrent is lowered below
+// THROTTLE_MIN_LIMIT if total is not enough for all torrents.
+func allocateUploadSpeed(torrents []*client.Torrent, total int64) []int64 {
+	minLimit := max(min(THROTTLE_MIN_LIMIT, total/int64(len(torrents))), 1)
+	demands := make([]int64, len(torrents))
+	indexes := make([]int, len(torrents))
+	for i, torrent := range torrents {
+		indexes[i] = i
+		if torrent.UploadedSpeedLimit > 0 &&
+			float64(torrent.UploadSpeed) >= float64(torrent.UploadedSpeedLimit)*THROTTLE_SATURATED_RATIO {
+			demands[i] = total
+		} else {
+			demands[i] = max(torrent.UploadSpeed+torrent.UploadSpeed/4, minLimit)
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return demands[indexes[i]] < demands[indexes[j]]
+	})
+	// Each torrent gets at least min(demand, total/n) >= minLimit, as the average remaining share never decreases.
+	limits := make([]int64, len(torrents))
+	remaining := total
+	for k, i := range indexes {
+		limits[i] = min(demands[i], remaining/int64(len(indexes)-k))
+		remaining -= limits[i]
+	}
+	share := remaining / int64(len(limits))
+	for i := range limits {
+		limits[i] = max(limits[i]+share, minLimit)
+	}
+	return limits
+}
@@ -0,0 +1,39 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/client"
+)
+
+func TestAllocateUploadSpeed(t *testing.T) {
+	tests := []struct {
+		name   string
+		speeds []int64 // current upload speeds; all torrents are capped by their current limits
+		total  int64
+	}{
+		{"plenty", []int64{100 * 1024, 200 * 1024}, 10 * 1024 * 1024},
+		{"saturated", []int64{1024 * 1024, 1024 * 1024, 1024 * 1024}, 1024 * 1024},
+		{"below min limit", []int64{1024, 1024, 1024, 1024}, 20 * 1024},
+		{"tiny total", []int64{1024, 1024, 1024}, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var torrents []*client.Torrent
+			for _, speed := range test.speeds {
+				torrents = append(torrents, &client.Torrent{UploadSpeed: speed, UploadedSpeedLimit: speed})
+			}
+			limits := allocateUploadSpeed(torrents, test.total)
+			sum := int64(0)
+			for _, limit := range limits {
+				if limit <= 0 {
+					t.Errorf("expected positive limit, got %d", limit)
+				}
+				sum += limit
+			}
+			if sum > test.total {
+				t.Errorf("expected sum of limits <= %d, got %d (%v)", test.total, sum, limits)
+			}
+		})
+	}
+}
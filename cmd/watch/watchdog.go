@@ -0,0 +1,88 @@
+package watch
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/flags"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	// Consecutive failed polls before a client is considered down and "client" event hooks are run.
+	CLIENT_DOWN_FAILURES = 3
+	// Max interval (seconds) between two polls of a failing client.
+	CLIENT_MAX_BACKOFF = 1800
+)
+
+// Client connection status of "client" event.
+const (
+	CLIENT_STATUS_DOWN = "down"
+	CLIENT_STATUS_UP   = "up"
+)
+
+// The connection health of a watched client. The polls of a failing client are backed off exponentially
+// (interval * 2^n, at most CLIENT_MAX_BACKOFF), and it's considered down after CLIENT_DOWN_FAILURES failures.
+type clientHealth struct {
+	name     string
+	failures int64
+	down     bool
+	nextPoll int64 // timestamp. Polls before it are skipped
+}
+
+func (health *clientHealth) shouldPoll() bool {
+	return util.Now() >= health.nextPoll
+}
+
+// Record a successful poll of client. Run hooks if client was down.
+func (health *clientHealth) success(hooks []*config.HookConfigStruct) {
+	if health.down {
+		log.Warnf("Client %s recovered after %d failed polls", health.name, health.failures)
+		runClientHooks(hooks, health.name, CLIENT_STATUS_UP, "")
+	}
+	health.failures = 0
+	health.down = false
+	health.nextPoll = 0
+}
+
+// Record a failed poll of client and back off. Run hooks if client becomes down.
+func (health *clientHealth) failure(hooks []*config.HookConfigStruct, err error) {
+	health.failures++
+	backoff := min(interval<<min(health.failures-1, 16), max(interval, CLIENT_MAX_BACKOFF))
+	health.nextPoll = util.Now() + backoff
+	log.Errorf("Failed to poll client %s (%d consecutive failures, next poll in %ds): %v",
+		health.name, health.failures, backoff, err)
+	if !health.down && health.failures >= CLIENT_DOWN_FAILURES {
+		health.down = true
+		runClientHooks(hooks, health.name, CLIENT_STATUS_DOWN, err.Error())
+	}
+}
+
+func runClientHooks(hooks []*config.HookConfigStruct, clientName string, status string, message string) {
+	for _, hook := range hooks {
+		if len(hook.Clients) == 0 || slices.Contains(hook.Clients, clientName) {
+			go runClientHook(hook, clientName, status, message)
+		}
+	}
+}
+
+func runClientHook(hook *config.HookConfigStruct, clientName string, status string, message string) {
+	if flags.DryRun {
+		log.Warnf("Dry-run: run hook %s for client %s status %s", hook.Name, clientName, status)
+		return
+	}
+	execHook(hook, "client "+clientName, []string{
+		"PTOOL_CLIENT=" + clientName,
+		"PTOOL_CLIENT_STATUS=" + status,
+		"PTOOL_EVENT_MESSAGE=" + message,
+	}, &hookPayload{
+		Event:   hook.Event,
+		Hook:    hook.Name,
+		Time:    util.Now(),
+		Client:  clientName,
+		Status:  status,
+		Message: message,
+	})
+}
@@ -3,18 +3,23 @@ package xseedadd
 import (
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/sagan/ptool/audit"
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/cmd/common"
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/flags"
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
+	"github.com/sagan/ptool/util/torrentutil"
 )
 
 var command = &cobra.Command{
@@ -41,7 +46,6 @@ var (
 	deleteAdded = false
 	addPaused   = false
 	check       = false
-	dryRun      = false
 	forceLocal  = false
 	addCategory = ""
 	addTags     = ""
@@ -58,7 +62,7 @@ func init() {
 	command.Flags().BoolVarP(&deleteAdded, "delete-added", "", false, "Delete successfully added torrent file")
 	command.Flags().BoolVarP(&addPaused, "add-paused", "", false, "Add xseed torrents to client in paused state")
 	command.Flags().BoolVarP(&check, "check", "", false, "Let client do hash checking when adding xseed torrents")
-	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Dry run. Do NOT actually add xseed torrents to client")
+	command.Flags().BoolVarP(&flags.DryRun, "dry-run", "d", false, "Dry run. Do NOT actually add xseed torrents to client")
 	command.Flags().BoolVarP(&forceLocal, "force-local", "", false, "Force treat all args as local torrent filename")
 	command.Flags().StringVarP(&defaultSite, "site", "", "", "Set default site of torrent url")
 	command.Flags().StringVarP(&addCategory, "add-category", "", "",
@@ -113,13 +117,18 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 		}
 		return clientTorrents[i].InfoHash < clientTorrents[j].InfoHash
 	})
+	// the files of client torrents are fetched on demand and cached in index
+	index := torrentutil.NewContentIndex()
+	index.AddClientTorrents(clientName, clientInstance, clientTorrents)
 	errorCnt := int64(0)
+	var lastErr error
 	for _, torrent := range torrents {
 		content, tinfo, _, sitename, _, _, isLocal, err :=
 			helper.GetTorrentContent(torrent, defaultSite, forceLocal, false, stdinTorrentContents, false, nil)
 		if err != nil {
 			fmt.Printf("X%s: failed to get: %v\n", torrent, err)
 			errorCnt++
+			lastErr = err
 			continue
 		}
 		if t, _ := clientInstance.GetTorrent(tinfo.InfoHash); t != nil {
@@ -127,18 +136,21 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 			continue
 		}
 		var matchClientTorrent *client.Torrent
-		for _, clientTorrent := range clientTorrents {
-			if clientTorrent.Size > tinfo.Size {
-				continue
-			} else if clientTorrent.Size < tinfo.Size {
-				break
+		// client torrents of the identical contents (fingerprint) are checked first,
+		// then the ones of the same size and release (e.g. same title, episode and group, but renamed folder).
+		candidates := index.Find(tinfo)
+		for _, entry := range index.FindBySizeAndRelease(tinfo) {
+			if !slices.Contains(candidates, entry) {
+				candidates = append(candidates, entry)
 			}
-			clientTorrentContents, err := clientInstance.GetTorrentContents(clientTorrent.InfoHash)
-			if err != nil {
+		}
+		for _, entry := range candidates {
+			clientTorrent := entry.ClientTorrent
+			if _, err := entry.Fingerprint(); err != nil {
 				log.Debugf("failed to get client torrent contents info: %v", err)
 				continue
 			}
-			compareResult := tinfo.XseedCheckWithClientTorrent(clientTorrentContents)
+			compareResult := tinfo.XseedCheckWithClientTorrent(entry.Contents)
 			if compareResult == 0 {
 				log.Debugf("Torrent %s has the same contents with client %s torrent.\n", torrent, clientName)
 			} else if compareResult == 1 {
@@ -159,7 +171,7 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 			errorCnt++
 			continue
 		}
-		if dryRun {
+		if flags.DryRun {
 			fmt.Printf("✓%s: matches with client torrent %s (%s) (dry-run)\n",
 				torrent, matchClientTorrent.InfoHash, matchClientTorrent.Name)
 			continue
@@ -192,6 +204,7 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 			fmt.Printf("X%s: matched with client torrent %s (%s), but failed to add to client: %v\n",
 				torrent, matchClientTorrent.InfoHash, matchClientTorrent.Name, err)
 			errorCnt++
+			lastErr = err
 		} else {
 			fmt.Printf("✓%s: matched with client torrent %s (%s), added to client, save path: %s\n",
 				torrent, matchClientTorrent.InfoHash, matchClientTorrent.Name, matchClientTorrent.SavePath)
@@ -202,15 +215,12 @@ func xseedadd(cmd *cobra.Command, args []string) error {
 						log.Debugf("Failed to rename %s to *%s: %v", torrent, constants.FILENAME_SUFFIX_ADDED, err)
 					}
 				} else if deleteAdded {
-					if err := os.Remove(torrent); err != nil {
+					if err := audit.RemoveFile(torrent); err != nil {
 						log.Debugf("Failed to delete %s: %v", torrent, err)
 					}
 				}
 			}
 		}
 	}
-	if errorCnt > 0 {
-		return fmt.Errorf("%d errors", errorCnt)
-	}
-	return nil
+	return common.ItemsError(errorCnt, int64(len(torrents)), lastErr)
 }
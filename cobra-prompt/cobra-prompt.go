@@ -196,14 +196,19 @@ func findSuggestions(co *CobraPrompt, d *prompt.Document) []prompt.Suggest {
 		}
 	}
 
+	// mod by ptool
+	// Dynamic suggestions are NOT filtered, the DynamicSuggestionsFunc should do it itself.
+	// It may match the input with other than the prefix of suggestion text,
+	// e.g. suggest the infohash of torrent which name starts with the input.
+	var dynamicSuggestions []prompt.Suggest
 	annotation := command.Annotations[DynamicSuggestionsAnnotation]
 	if co.DynamicSuggestionsFunc != nil && annotation != "" {
-		suggestions = append(suggestions, co.DynamicSuggestionsFunc(annotation, d)...)
+		dynamicSuggestions = co.DynamicSuggestionsFunc(annotation, d)
 	}
 
 	if co.SuggestionFilter != nil {
-		return co.SuggestionFilter(suggestions, d)
+		return append(co.SuggestionFilter(suggestions, d), dynamicSuggestions...)
 	}
 
-	return prompt.FilterHasPrefix(suggestions, d.GetWordBeforeCursor(), true)
+	return append(prompt.FilterHasPrefix(suggestions, d.GetWordBeforeCursor(), true), dynamicSuggestions...)
 }
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Config sections that can be defined in included files. Other (global) settings are only read from main config file.
+var includableSections = []string{"clients", "sites", "groups", "aliases", "cookieclouds"}
+
+// A config file included by main config file.
+type includedFile struct {
+	filename string
+	v        *viper.Viper
+	data     *ConfigStruct
+}
+
+// Return the absolute glob patterns of include config. Relative patterns are relative to config dir.
+func includePatterns(include []string) []string {
+	patterns := []string{}
+	for _, pattern := range include {
+		if pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(ConfigDir, pattern)
+		}
+		patterns = append(patterns, filepath.Clean(pattern))
+	}
+	return patterns
+}
+
+// Return true if filename matches any of the include patterns.
+func isIncludedFile(include []string, filename string) bool {
+	filename = filepath.Clean(filename)
+	for _, pattern := range includePatterns(include) {
+		if matched, _ := filepath.Match(pattern, filename); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Read all config files matched by include patterns, in order of patterns then filenames.
+// The main config file itself and files matched multiple times are skipped.
+// Nested includes are not supported.
+func readIncludedFiles(include []string) ([]*includedFile, error) {
+	mainFile := filepath.Join(ConfigDir, ConfigFile)
+	files := []*includedFile{}
+	for _, pattern := range includePatterns(include) {
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		for _, filename := range filenames {
+			if filename == mainFile || slices.ContainsFunc(files, func(file *includedFile) bool {
+				return file.filename == filename
+			}) {
+				continue
+			}
+			v := viper.New()
+			v.SetConfigFile(filename)
+			if filepath.Ext(filename) == "" {
+				v.SetConfigType(ConfigType)
+			}
+			if err := v.ReadInConfig(); err != nil {
+				return nil, fmt.Errorf("failed to read included config file %s: %w", filename, err)
+			}
+			var data *ConfigStruct
+			if err := v.Unmarshal(&data); err != nil {
+				return nil, fmt.Errorf("failed to parse included config file %s: %w", filename, err)
+			}
+			if data == nil {
+				data = &ConfigStruct{}
+			}
+			files = append(files, &includedFile{filename: filename, v: v, data: data})
+		}
+	}
+	return files, nil
+}
+
+// Append clients, sites, groups, aliases and cookieclouds of included files to configData.
+func mergeIncludedFiles(configData *ConfigStruct, files []*includedFile) {
+	for _, file := range files {
+		for _, site := range file.data.Sites {
+			site.configFile = file.filename
+		}
+		configData.Clients = append(configData.Clients, file.data.Clients...)
+		configData.Sites = append(configData.Sites, file.data.Sites...)
+		configData.Groups = append(configData.Groups, file.data.Groups...)
+		configData.Aliases = append(configData.Aliases, file.data.Aliases...)
+		configData.Cookieclouds = append(configData.Cookieclouds, file.data.Cookieclouds...)
+	}
+}
+
+// Return top-level keys of included file settings which are not includable sections.
+func ignoredIncludeKeys(settings map[string]any) (keys []string) {
+	for key := range settings {
+		if !slices.Contains(includableSections, strings.ToLower(key)) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	reloadHooks   []func()
+	configChanged atomic.Bool
+	watchOnce     sync.Once
+	watcher       *fsnotify.Watcher
+	watchMu       sync.Mutex
+	watchInclude  []string // include patterns of current config
+)
+
+// Register a function that will be called after config file is reloaded.
+// Packages which cache instances created from config (e.g. clients & sites) use it to reset their cache.
+func OnReload(fn func()) {
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Watch config file (and included config files) for changes in background.
+// Once it's changed, the next ReloadIfChanged call reloads it. Used in long-running modes: shell, serve and watch.
+func WatchConfig() {
+	watchOnce.Do(func() {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			log.Warnf("Failed to watch config file: %v", err)
+			return
+		}
+		// watch the dir instead of file, as many editors save file by replacing it
+		if err := watcher.Add(ConfigDir); err != nil {
+			log.Warnf("Failed to watch config dir %s: %v", ConfigDir, err)
+			watcher.Close()
+			watcher = nil
+			return
+		}
+		watchIncludes(Get().Include)
+		go func() {
+			for {
+				select {
+				case event, ok := <-watcher.Events:
+					if !ok {
+						return
+					}
+					if !event.Has(fsnotify.Write | fsnotify.Create | fsnotify.Rename | fsnotify.Remove) {
+						continue
+					}
+					watchMu.Lock()
+					include := watchInclude
+					watchMu.Unlock()
+					if event.Name == filepath.Join(ConfigDir, ConfigFile) || isIncludedFile(include, event.Name) {
+						log.Debugf("Config file changed: %s", event)
+						configChanged.Store(true)
+					}
+				case err, ok := <-watcher.Errors:
+					if !ok {
+						return
+					}
+					log.Debugf("Config file watcher error: %v", err)
+				}
+			}
+		}()
+	})
+}
+
+// Add the dirs of include patterns to watcher. Dirs that do not exist are ignored.
+func watchIncludes(include []string) {
+	if watcher == nil {
+		return
+	}
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	watchInclude = include
+	for _, pattern := range includePatterns(include) {
+		dir := filepath.Dir(pattern)
+		if dir == ConfigDir || slices.Contains(watcher.WatchList(), dir) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			log.Debugf("Failed to watch included config dir %s: %v", dir, err)
+		}
+	}
+}
+
+// Reload config file if it's changed since last load. See WatchConfig.
+// Return true if config file is (successfully) reloaded.
+func ReloadIfChanged() bool {
+	if !configChanged.Swap(false) {
+		return false
+	}
+	summary, err := Reload()
+	if err != nil {
+		log.Errorf("Failed to reload config file, keep using the current one: %v", err)
+		return false
+	}
+	log.Warnf("Config file reloaded: %s", summary)
+	return true
+}
+
+// Reload config file and reset all cached instances (clients, sites) created from old config.
+// If the new config file has fatal problems, it returns an error and the current config is kept.
+// Return a summary of what changed.
+func Reload() (summary string, err error) {
+	problems, err := Verify(filepath.Join(ConfigDir, ConfigFile), ConfigType)
+	if err != nil {
+		return "", err
+	}
+	if index := slices.IndexFunc(problems, func(problem *Problem) bool { return problem.Fatal }); index != -1 {
+		return "", fmt.Errorf("invalid config file: %s", problems[index])
+	}
+	oldData := Get()
+	oldClients, oldSites, oldAliases, oldGroups, oldCookieclouds :=
+		clientsConfigMap, sitesConfigMap, aliasesConfigMap, groupsConfigMap, cookiecloudsConfigMap
+	oldSecrets, oldLoadErr := decryptedSecrets, loadErr
+	configData = nil
+	clientsConfigMap = map[string]*ClientConfigStruct{}
+	sitesConfigMap = map[string]*SiteConfigStruct{}
+	aliasesConfigMap = map[string]*AliasConfigStruct{}
+	groupsConfigMap = map[string]*GroupConfigStruct{}
+	cookiecloudsConfigMap = map[string]*CookiecloudConfigStruct{}
+	once = sync.Once{}
+	newData := Get()
+	if err := LoadError(); err != nil {
+		// keep using the current config
+		configData = oldData
+		clientsConfigMap, sitesConfigMap, aliasesConfigMap, groupsConfigMap, cookiecloudsConfigMap =
+			oldClients, oldSites, oldAliases, oldGroups, oldCookieclouds
+		decryptedSecrets, loadErr = oldSecrets, oldLoadErr
+		return "", err
+	}
+	watchIncludes(newData.Include)
+	for _, fn := range reloadHooks {
+		fn()
+	}
+	return diffConfig(oldData, newData), nil
+}
+
+// Return a summary of changes between two configs, e.g. "clients: +remote; sites: ~mteam".
+func diffConfig(oldData *ConfigStruct, newData *ConfigStruct) string {
+	changes := []string{}
+	if change := diffItems(oldData.Clients, newData.Clients,
+		func(c *ClientConfigStruct) string { return c.Name }); change != "" {
+		changes = append(changes, "clients: "+change)
+	}
+	if change := diffItems(oldData.Sites, newData.Sites, (*SiteConfigStruct).GetName); change != "" {
+		changes = append(changes, "sites: "+change)
+	}
+	if change := diffItems(oldData.Groups, newData.Groups,
+		func(g *GroupConfigStruct) string { return g.Name }); change != "" {
+		changes = append(changes, "groups: "+change)
+	}
+	if change := diffItems(oldData.Aliases, newData.Aliases,
+		func(a *AliasConfigStruct) string { return a.Name }); change != "" {
+		changes = append(changes, "aliases: "+change)
+	}
+	if !reflect.DeepEqual(oldData.Cookieclouds, newData.Cookieclouds) {
+		changes = append(changes, "cookieclouds changed")
+	}
+	oldGlobal, newGlobal := *oldData, *newData
+	for _, global := range []*ConfigStruct{&oldGlobal, &newGlobal} {
+		global.Clients, global.Sites, global.Groups, global.Aliases, global.Cookieclouds = nil, nil, nil, nil, nil
+		global.ClientsEnabled, global.SitesEnabled, global.Include = nil, nil, nil
+	}
+	if !reflect.DeepEqual(oldGlobal, newGlobal) {
+		changes = append(changes, "global settings changed")
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// Return changed items in "+added -removed ~modified" format, or empty string if nothing changed.
+func diffItems[T any](oldItems []*T, newItems []*T, getName func(*T) string) string {
+	changes := []string{}
+	for _, newItem := range newItems {
+		index := slices.IndexFunc(oldItems, func(oldItem *T) bool { return getName(oldItem) == getName(newItem) })
+		if index == -1 {
+			changes = append(changes, "+"+getName(newItem))
+		} else if !reflect.DeepEqual(oldItems[index], newItem) {
+			changes = append(changes, "~"+getName(newItem))
+		}
+	}
+	for _, oldItem := range oldItems {
+		if !slices.ContainsFunc(newItems, func(newItem *T) bool { return getName(oldItem) == getName(newItem) }) {
+			changes = append(changes, "-"+getName(oldItem))
+		}
+	}
+	return strings.Join(changes, " ")
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/sagan/ptool/util/crypto"
+)
+
+// The env of master password, which is used to encrypt / decrypt secrets in config file.
+const ENV_MASTER_PASSWORD = "PTOOL_MASTER_PASSWORD"
+
+// The master password. Set if config file contains encrypted secrets.
+var MasterPassword = ""
+
+// The site config fields which are always written encrypted by Set if config file contains encrypted secrets.
+var sensitiveSiteFields = []string{"cookie", "passkey", "password", "totpSecret", "apiKey"}
+
+var (
+	// The field paths (e.g. "sites[0].cookie") of all secrets which are encrypted in config file.
+	// Set by decryptSecrets.
+	decryptedSecrets = map[string]bool{}
+	// The error of decrypting secrets of config file. See LoadError.
+	loadErr     error
+	decryptOnce sync.Once
+)
+
+// Decrypt the encrypted secrets of config file, if not yet, and return the error of loading config file,
+// e.g. the secrets can not be decrypted. Get does not decrypt secrets, so cmds that do not use them
+// (e.g. "version") work without the master password; all other cmds call it before running.
+func LoadError() error {
+	configData := Get()
+	decryptOnce.Do(func() {
+		if err := decryptSecrets(configData); err != nil {
+			loadErr = fmt.Errorf("failed to decrypt secrets in config file: %w", err)
+		}
+	})
+	return loadErr
+}
+
+// Get master password from env, or read it from interactive prompt if stdin is tty.
+// If confirm is true, ask user to input the password twice when reading from prompt.
+func GetMasterPassword(confirm bool) (string, error) {
+	if MasterPassword != "" {
+		return MasterPassword, nil
+	}
+	if password := os.Getenv(ENV_MASTER_PASSWORD); password != "" {
+		MasterPassword = password
+		return password, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("master password is required. Set it in %s env", ENV_MASTER_PASSWORD)
+	}
+	fmt.Fprintf(os.Stderr, "Enter master password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintf(os.Stderr, "\n")
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("master password can not be empty")
+	}
+	if confirm {
+		fmt.Fprintf(os.Stderr, "Confirm master password: ")
+		password2, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintf(os.Stderr, "\n")
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		if string(password) != string(password2) {
+			return "", fmt.Errorf("passwords do NOT match")
+		}
+	}
+	MasterPassword = string(password)
+	return MasterPassword, nil
+}
+
+// Decrypt all encrypted secrets (string values with "enc:v1:" prefix) of config data in place.
+// The master password is asked only if there is any encrypted secret.
+func decryptSecrets(configData *ConfigStruct) error {
+	return walkStrings(reflect.ValueOf(configData), "", func(path string, value reflect.Value) error {
+		if !crypto.IsEncryptedSecret(value.String()) {
+			return nil
+		}
+		password, err := GetMasterPassword(false)
+		if err != nil {
+			return fmt.Errorf("config file contains encrypted secrets: %w", err)
+		}
+		plaintext, err := crypto.DecryptSecret(password, value.String())
+		if err != nil {
+			return err
+		}
+		decryptedSecrets[path] = true
+		value.SetString(plaintext)
+		return nil
+	})
+}
+
+// Return a copy of value (a string, or slice of strings / string slices) of field path with secrets encrypted.
+// A string is a secret if it's field was encrypted in config file (see decryptSecrets), or if all is true.
+// Values of other types are returned as is.
+func encryptSecrets(value any, path string, all bool) (any, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		str := v.String()
+		if str == "" || crypto.IsEncryptedSecret(str) || !all && !decryptedSecrets[path] {
+			return value, nil
+		}
+		secret, err := crypto.EncryptSecret(MasterPassword, str)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(secret).Convert(v.Type()).Interface(), nil
+	case reflect.Slice:
+		if k := v.Type().Elem().Kind(); k != reflect.String && k != reflect.Slice {
+			return value, nil
+		}
+		newValue := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := encryptSecrets(v.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i), all)
+			if err != nil {
+				return nil, err
+			}
+			newValue.Index(i).Set(reflect.ValueOf(item))
+		}
+		return newValue.Interface(), nil
+	}
+	return value, nil
+}
+
+// Call fn with every settable string value inside v (struct, pointer, slice or string) and it's field path,
+// e.g. "sites[0].cookie". The yaml tag (or Go name if absent) of struct field is used in path.
+func walkStrings(v reflect.Value, path string, fn func(path string, value reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = field.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			if err := walkStrings(v.Field(i), name, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if v.CanSet() {
+			return fn(path, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/util/crypto"
+)
+
+func TestDecryptAndEncryptSecrets(t *testing.T) {
+	const password = "password"
+	const value = "same value"
+	secret, err := crypto.EncryptSecret(password, value)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	oldPassword, oldSecrets := MasterPassword, decryptedSecrets
+	defer func() { MasterPassword, decryptedSecrets = oldPassword, oldSecrets }()
+	MasterPassword = password
+	decryptedSecrets = map[string]bool{}
+	configData := &ConfigStruct{Sites: []*SiteConfigStruct{
+		{Name: "a", Comment: value},
+		{Name: "b", Cookie: secret, Comment: value, Passkey: value},
+	}}
+	if err := decryptSecrets(configData); err != nil {
+		t.Fatalf("failed to decrypt secrets: %v", err)
+	}
+	if configData.Sites[1].Cookie != value {
+		t.Errorf("expected decrypted cookie %q, got %q", value, configData.Sites[1].Cookie)
+	}
+	tests := []struct {
+		path      string
+		all       bool
+		encrypted bool
+	}{
+		{"sites[1].cookie", false, true},
+		// other fields of the same value as a secret are NOT secrets
+		{"sites[1].comment", false, false},
+		{"sites[0].comment", false, false},
+		{"sites[0].cookie", false, false},
+		{"sites[1].passkey", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			result, err := encryptSecrets(value, test.path, test.all)
+			if err != nil {
+				t.Fatalf("failed to encrypt secrets: %v", err)
+			}
+			if encrypted := crypto.IsEncryptedSecret(result.(string)); encrypted != test.encrypted {
+				t.Errorf("expected encrypted=%t, got %q", test.encrypted, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/transfer"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/impersonateutil"
+	"github.com/sagan/ptool/util/selfupdate"
+)
+
+// A problem found in config file.
+type Problem struct {
+	Item    string `json:"item"` // e.g. "sites[1] (mteam)". Empty for top-level config
+	Message string `json:"message"`
+	Fatal   bool   `json:"fatal"` // if true, ptool will refuse to load the config file
+}
+
+func (problem *Problem) String() string {
+	if problem.Item == "" {
+		return problem.Message
+	}
+	return problem.Item + ": " + problem.Message
+}
+
+// Statically verify the config file, without loading it as the current config.
+// It checks syntax, unknown fields, invalid or duplicate names, and invalid values.
+// err is returned only if the config file can not be read.
+func Verify(filename string, configType string) (problems []*Problem, err error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	v.SetConfigType(configType)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigParseError); ok {
+			return []*Problem{{Message: fmt.Sprintf("syntax error: %v", err), Fatal: true}}, nil
+		}
+		return nil, err
+	}
+	var data *ConfigStruct
+	if err := v.Unmarshal(&data); err != nil {
+		return []*Problem{{Message: fmt.Sprintf("invalid config: %v", err), Fatal: true}}, nil
+	}
+	addProblem := func(item string, fatal bool, format string, args ...any) {
+		problems = append(problems, &Problem{Item: item, Message: fmt.Sprintf(format, args...), Fatal: fatal})
+	}
+
+	// relative path of script file is resolved against the dir of config file
+	checkScript := func(item string, field string, script string) {
+		if script == "" {
+			return
+		}
+		if !filepath.IsAbs(script) {
+			script = filepath.Join(filepath.Dir(filename), script)
+		}
+		if !util.FileExists(script) {
+			addProblem(item, false, "%s file %q not found", field, script)
+		}
+	}
+
+	settings := v.AllSettings()
+	for _, key := range unknownFields(settings, reflect.TypeOf(ConfigStruct{})) {
+		addProblem("", false, "unknown field %q", key)
+	}
+	sections := map[string]reflect.Type{
+		"clients":         reflect.TypeOf(ClientConfigStruct{}),
+		"sites":           reflect.TypeOf(SiteConfigStruct{}),
+		"groups":          reflect.TypeOf(GroupConfigStruct{}),
+		"aliases":         reflect.TypeOf(AliasConfigStruct{}),
+		"cookieclouds":    reflect.TypeOf(CookiecloudConfigStruct{}),
+		"impersonates":    reflect.TypeOf(ImpersonateConfigStruct{}),
+		"hooks":           reflect.TypeOf(HookConfigStruct{}),
+		"watchfolders":    reflect.TypeOf(WatchFolderConfigStruct{}),
+		"autoremoves":     reflect.TypeOf(AutoremoveConfigStruct{}),
+		"autotags":        reflect.TypeOf(AutotagConfigStruct{}),
+		"filepriorities":  reflect.TypeOf(FilePriorityConfigStruct{}),
+		"seasonpacks":     reflect.TypeOf(SeasonPackConfigStruct{}),
+		"arrs":            reflect.TypeOf(ArrConfigStruct{}),
+		"uploadthrottles": reflect.TypeOf(UploadThrottleConfigStruct{}),
+	}
+	// prefix: the file name for included files, empty for main config file
+	checkSections := func(prefix string, settings map[string]any) {
+		for _, section := range includableSections {
+			items, _ := settings[section].([]any)
+			for i, item := range items {
+				fields, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				name, _ := fields["name"].(string)
+				if name == "" && section == "sites" {
+					name, _ = fields["type"].(string)
+				}
+				for _, key := range unknownFields(fields, sections[section]) {
+					addProblem(fmt.Sprintf("%s%s[%d] (%s)", prefix, section, i, name), false, "unknown field %q", key)
+				}
+			}
+		}
+	}
+	checkSections("", settings)
+	// not includable sections
+	for _, section := range []string{"impersonates", "hooks", "watchfolders", "autoremoves", "autotags",
+		"filepriorities", "seasonpacks", "arrs", "uploadthrottles"} {
+		items, _ := settings[section].([]any)
+		for i, item := range items {
+			if fields, ok := item.(map[string]any); ok {
+				name, _ := fields["name"].(string)
+				if name == "" && section == "watchfolders" {
+					name, _ = fields["dir"].(string)
+				}
+				for _, key := range unknownFields(fields, sections[section]) {
+					addProblem(fmt.Sprintf("%s[%d] (%s)", section, i, name), false, "unknown field %q", key)
+				}
+			}
+		}
+	}
+
+	files, err := readIncludedFiles(data.Include)
+	if err != nil {
+		return append(problems, &Problem{Message: err.Error(), Fatal: true}), nil
+	}
+	for _, file := range files {
+		fileSettings := file.v.AllSettings()
+		for _, key := range ignoredIncludeKeys(fileSettings) {
+			addProblem(file.filename, false, "setting %q in included file is ignored", key)
+		}
+		checkSections(file.filename+": ", fileSettings)
+	}
+	mergeIncludedFiles(data, files)
+
+	checkProxy := func(item string, proxy string) {
+		if proxy == "" || proxy == constants.NONE || proxy == constants.ENV_PROXY {
+			return
+		}
+		if _, err := util.ParseProxyUrl(proxy); err != nil {
+			addProblem(item, true, "%v", err)
+		}
+	}
+	checkProxy("siteProxy", data.SiteProxy)
+	checkProxy("clientProxy", data.ClientProxy)
+	impersonates := map[string]bool{}
+	for i, impersonate := range data.Impersonates {
+		item := fmt.Sprintf("impersonates[%d] (%s)", i, impersonate.Name)
+		if _, err := impersonate.ToProfile(); err != nil {
+			addProblem(item, true, "%v", err)
+		}
+		if impersonates[impersonate.Name] {
+			addProblem(item, true, "duplicate impersonate name %s", impersonate.Name)
+		}
+		impersonates[impersonate.Name] = true
+	}
+	checkImpersonate := func(item string, impersonate string) {
+		if impersonate != "" && impersonate != constants.NONE && !impersonates[impersonate] &&
+			impersonateutil.GetBuiltinProfile(impersonate) == nil {
+			addProblem(item, false, "impersonate %s not supported", impersonate)
+		}
+	}
+	checkImpersonate("siteImpersonate", data.SiteImpersonate)
+
+	// name => [itemType, item], of all clients & sites & groups & aliases
+	names := map[string][2]string{}
+	checkName := func(itemType string, item string, name string) {
+		if name == "" {
+			addProblem(item, true, "%s name can not be empty", itemType)
+			return
+		}
+		if strings.ContainsAny(name, `,.:;'"/\<>[]{}|`) {
+			addProblem(item, true, "%s name %s contains invalid characters", itemType, name)
+		}
+		if existing, ok := names[name]; ok {
+			addProblem(item, existing[0] == itemType, "duplicate name %s, which is also used by %s",
+				name, existing[1])
+		} else {
+			names[name] = [2]string{itemType, item}
+		}
+	}
+	for i, client := range data.Clients {
+		item := fmt.Sprintf("clients[%d] (%s)", i, client.Name)
+		checkName("client", item, client.Name)
+		if client.Type == "" {
+			addProblem(item, false, "client type is not set")
+		}
+		if client.Url != "" {
+			if _, err := url.Parse(client.Url); err != nil {
+				addProblem(item, true, "invalid url %q: %v", client.Url, err)
+			} else if !util.IsUrl(client.Url) {
+				addProblem(item, false, "invalid url %q: must start with 'http(s)://'", client.Url)
+			}
+		}
+		checkProxy(item, client.Proxy)
+		for _, value := range []string{client.BrushMinDiskSpace, client.BrushSlowUploadSpeedTier,
+			client.BrushDefaultUploadSpeedLimit} {
+			if value != "" {
+				if _, err := util.RAMInBytes(value); err != nil {
+					addProblem(item, false, "invalid size value %q", value)
+				}
+			}
+		}
+		checkScript(item, "brushScript", client.BrushScript)
+		if client.QuietHours != "" {
+			if _, err := util.ParseDailyTimeRanges(client.QuietHours); err != nil {
+				addProblem(item, true, "invalid quietHours %q: %v", client.QuietHours, err)
+			}
+		}
+		if client.QuietHoursAction != "" && client.QuietHoursAction != QUIET_HOURS_ACTION_PAUSE &&
+			client.QuietHoursAction != QUIET_HOURS_ACTION_ALTSPEED {
+			addProblem(item, true, "invalid quietHoursAction %q: must be %q or %q", client.QuietHoursAction,
+				QUIET_HOURS_ACTION_PAUSE, QUIET_HOURS_ACTION_ALTSPEED)
+		}
+		for _, rule := range client.PathMappings {
+			if _, _, err := util.ParsePathMapperRule(rule); err != nil {
+				addProblem(item, true, "invalid pathMappings: %v", err)
+			}
+		}
+	}
+	for i, site := range data.Sites {
+		item := fmt.Sprintf("sites[%d] (%s)", i, site.GetName())
+		checkName("site", item, site.GetName())
+		if site.Url != "" {
+			if _, err := url.Parse(site.Url); err != nil {
+				addProblem(item, true, "invalid url %q: %v", site.Url, err)
+			}
+		}
+		checkProxy(item, site.Proxy)
+		checkImpersonate(item, site.Impersonate)
+		if site.DynamicSeedingSize != "" {
+			if v, err := util.RAMInBytes(site.DynamicSeedingSize); err != nil || v < 0 {
+				addProblem(item, true, "invalid dynamicSeedingSize value %q", site.DynamicSeedingSize)
+			}
+		}
+		for field, value := range map[string]string{
+			"dynamicSeedingTorrentMaxSize": site.DynamicSeedingTorrentMaxSize,
+			"dynamicSeedingTorrentMinSize": site.DynamicSeedingTorrentMinSize,
+		} {
+			if value != "" {
+				if _, err := util.RAMInBytes(value); err != nil {
+					addProblem(item, true, "invalid %s value %q", field, value)
+				}
+			}
+		}
+		if site.BrushAllowAddTorrentsPercent < 0 || site.BrushAllowAddTorrentsPercent > 100 {
+			addProblem(item, true, "invalid brushAllowAddTorrentsPercent value %d, should between [0, 100]",
+				site.BrushAllowAddTorrentsPercent)
+		}
+		checkScript(item, "brushScript", site.BrushScript)
+	}
+	isSite := func(name string) bool {
+		return slices.ContainsFunc(data.Sites, func(site *SiteConfigStruct) bool { return site.GetName() == name })
+	}
+	for i, group := range data.Groups {
+		item := fmt.Sprintf("groups[%d] (%s)", i, group.Name)
+		checkName("group", item, group.Name)
+		for _, sitename := range group.Sites {
+			if !isSite(sitename) {
+				addProblem(item, false, "site %s not found", sitename)
+			}
+		}
+	}
+	for i, alias := range data.Aliases {
+		item := fmt.Sprintf("aliases[%d] (%s)", i, alias.Name)
+		checkName("alias", item, alias.Name)
+		if alias.Name == "alias" {
+			addProblem(item, true, "alias name can not be 'alias' itself")
+		}
+		if alias.Cmd == "" {
+			addProblem(item, false, "alias cmd is not set")
+		}
+	}
+	cookiecloudNames := map[string]bool{}
+	for i, cookiecloud := range data.Cookieclouds {
+		item := fmt.Sprintf("cookieclouds[%d] (%s)", i, cookiecloud.Name)
+		if cookiecloud.Name != "" {
+			if cookiecloudNames[cookiecloud.Name] {
+				addProblem(item, true, "duplicate cookiecloud name %s", cookiecloud.Name)
+			}
+			cookiecloudNames[cookiecloud.Name] = true
+		}
+		if cookiecloud.Server == "" || cookiecloud.Uuid == "" || cookiecloud.Password == "" {
+			addProblem(item, false, "server, uuid and password must all be set")
+		}
+		for _, sitename := range cookiecloud.Sites {
+			if !isSite(sitename) {
+				addProblem(item, false, "site %s not found", sitename)
+			}
+		}
+	}
+	isClient := func(name string) bool {
+		return slices.ContainsFunc(data.Clients, func(client *ClientConfigStruct) bool { return client.Name == name })
+	}
+	for i, hook := range data.Hooks {
+		item := fmt.Sprintf("hooks[%d] (%s)", i, hook.Name)
+		if hook.Event != HOOK_EVENT_SITE && hook.Event != HOOK_EVENT_MESSAGE && hook.Event != HOOK_EVENT_CLIENT &&
+			!slices.Contains(HookTorrentEvents, hook.Event) {
+			addProblem(item, true, "unsupported hook event %q", hook.Event)
+		}
+		if hook.Command == "" && hook.Webhook == "" && hook.Upload == "" && hook.Arr == "" {
+			addProblem(item, true, "command, webhook, upload or arr must be set")
+		}
+		if hook.Arr != "" {
+			if hook.Event != HOOK_EVENT_COMPLETE {
+				addProblem(item, true, "arr can only be used with %q event", HOOK_EVENT_COMPLETE)
+			}
+			if !slices.ContainsFunc(data.Arrs, func(arr *ArrConfigStruct) bool { return arr.Name == hook.Arr }) {
+				addProblem(item, true, "arr %s not found", hook.Arr)
+			}
+		}
+		if hook.Upload != "" {
+			if hook.Event != HOOK_EVENT_COMPLETE {
+				addProblem(item, true, "upload can only be used with %q event", HOOK_EVENT_COMPLETE)
+			}
+			if !transfer.IsTargetUrl(hook.Upload) {
+				addProblem(item, true, "invalid upload target %q", hook.Upload)
+			}
+		}
+		if hook.Webhook != "" && !util.IsUrl(hook.Webhook) {
+			addProblem(item, true, "invalid webhook url %q", hook.Webhook)
+		}
+		if hook.WebhookPayload != "" {
+			if hook.Webhook == "" {
+				addProblem(item, false, "webhookPayload is set but webhook is not set")
+			}
+			if _, err := hook.ParseWebhookPayload(); err != nil {
+				addProblem(item, true, "invalid webhookPayload template: %v", err)
+			}
+		}
+		for _, clientname := range hook.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+		for _, sitename := range hook.Sites {
+			if !isSite(sitename) {
+				addProblem(item, false, "site %s not found", sitename)
+			}
+		}
+	}
+	for i, watchFolder := range data.WatchFolders {
+		item := fmt.Sprintf("watchFolders[%d] (%s)", i, watchFolder.Dir)
+		if watchFolder.Dir == "" {
+			addProblem(item, true, "dir must be set")
+		}
+		if !isClient(watchFolder.Client) {
+			addProblem(item, true, "client %s not found", watchFolder.Client)
+		}
+	}
+	if data.Mqtt != "" {
+		if u, err := url.Parse(data.Mqtt); err != nil || u.Host == "" ||
+			!slices.Contains([]string{"tcp", "ssl", "tls", "mqtt", "mqtts", "ws", "wss"}, u.Scheme) {
+			addProblem("", true, "invalid mqtt broker url %q", data.Mqtt)
+		}
+	}
+	if data.SelfUpdateChannel != "" && data.SelfUpdateChannel != selfupdate.CHANNEL_STABLE &&
+		data.SelfUpdateChannel != selfupdate.CHANNEL_DEV {
+		addProblem("", true, "invalid selfUpdateChannel %q", data.SelfUpdateChannel)
+	}
+	if data.SelfUpdatePublicKey != "" {
+		if _, err := selfupdate.ParsePublicKey(data.SelfUpdatePublicKey); err != nil {
+			addProblem("", true, "invalid selfUpdatePublicKey: %v", err)
+		}
+	}
+	if data.HashWorkers < 0 {
+		addProblem("", true, "invalid hashWorkers %d", data.HashWorkers)
+	}
+	if data.HashReadAhead != "" {
+		if size, err := util.RAMInBytes(data.HashReadAhead); err != nil || size <= 0 {
+			addProblem("", true, "invalid hashReadAhead %q", data.HashReadAhead)
+		}
+	}
+	if data.FileCopyBufferSize != "" {
+		if size, err := util.RAMInBytes(data.FileCopyBufferSize); err != nil || size < 0 {
+			addProblem("", true, "invalid fileCopyBufferSize %q", data.FileCopyBufferSize)
+		}
+	}
+	if data.TorrentCacheTtl != "" {
+		if ttl, err := util.ParseTimeDuration(data.TorrentCacheTtl); err != nil || ttl <= 0 {
+			addProblem("", true, "invalid torrentCacheTtl %q", data.TorrentCacheTtl)
+		}
+	}
+	if data.TorrentCacheMaxSize != "" {
+		if size, err := util.RAMInBytes(data.TorrentCacheMaxSize); err != nil || size <= 0 {
+			addProblem("", true, "invalid torrentCacheMaxSize %q", data.TorrentCacheMaxSize)
+		}
+	}
+	if data.MetadataCacheTtl != "" {
+		if _, err := util.ParseTimeDuration(data.MetadataCacheTtl); err != nil {
+			addProblem("", true, "invalid metadataCacheTtl %q", data.MetadataCacheTtl)
+		}
+	}
+	if data.TrashRetention != "" {
+		if _, err := util.ParseTimeDuration(data.TrashRetention); err != nil {
+			addProblem("", true, "invalid trashRetention %q", data.TrashRetention)
+		}
+	}
+	for i, autoremove := range data.Autoremoves {
+		item := fmt.Sprintf("autoremoves[%d] (%s)", i, autoremove.Name)
+		if autoremove.Logic != "" && autoremove.Logic != AUTOREMOVE_LOGIC_AND &&
+			autoremove.Logic != AUTOREMOVE_LOGIC_OR {
+			addProblem(item, true, "invalid logic %q", autoremove.Logic)
+		}
+		if autoremove.MinSeedingTime != "" {
+			if _, err := util.ParseTimeDuration(autoremove.MinSeedingTime); err != nil {
+				addProblem(item, true, "invalid minSeedingTime %q", autoremove.MinSeedingTime)
+			}
+		}
+		if autoremove.MinRatio <= 0 && autoremove.MinSeedingTime == "" && autoremove.MinSeeders <= 0 &&
+			autoremove.Expr == "" && autoremove.Script == "" {
+			addProblem(item, true, "no removal condition (minRatio / minSeedingTime / minSeeders / expr / script) set")
+		}
+		checkScript(item, "script", autoremove.Script)
+		for _, clientname := range autoremove.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+	}
+	for i, autotag := range data.Autotags {
+		item := fmt.Sprintf("autotags[%d] (%s)", i, autotag.Name)
+		if len(autotag.Trackers) == 0 && len(autotag.Groups) == 0 && len(autotag.Resolutions) == 0 &&
+			len(autotag.Sources) == 0 {
+			addProblem(item, true, "trackers (or groups / resolutions / sources) must be set")
+		}
+		if len(autotag.Tags) == 0 && autotag.Category == "" {
+			addProblem(item, true, "no tags or category set")
+		}
+		for _, clientname := range autotag.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+	}
+	for i, rule := range data.UploadThrottles {
+		item := fmt.Sprintf("uploadThrottles[%d] (%s)", i, rule.Name)
+		if len(rule.Trackers) == 0 {
+			addProblem(item, true, "trackers must be set")
+		}
+		if limit, err := util.RAMInBytes(rule.UploadSpeedLimit); err != nil || limit <= 0 {
+			addProblem(item, true, "invalid uploadSpeedLimit %q", rule.UploadSpeedLimit)
+		}
+		for _, clientname := range rule.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+	}
+	for i, rule := range data.FilePriorities {
+		item := fmt.Sprintf("filePriorities[%d] (%s)", i, rule.Name)
+		if len(rule.Patterns) == 0 {
+			addProblem(item, true, "patterns must be set")
+		}
+		for _, pattern := range rule.Patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				addProblem(item, true, "invalid pattern %q", pattern)
+			}
+		}
+		switch rule.Priority {
+		case FILE_PRIORITY_SKIP, FILE_PRIORITY_NORMAL, FILE_PRIORITY_HIGH, FILE_PRIORITY_MAX:
+		default:
+			addProblem(item, true, "invalid priority %q", rule.Priority)
+		}
+		for _, clientname := range rule.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+	}
+	for i, rule := range data.SeasonPacks {
+		item := fmt.Sprintf("seasonPacks[%d] (%s)", i, rule.Name)
+		switch rule.Action {
+		case "", SEASON_PACK_ACTION_NONE, SEASON_PACK_ACTION_PAUSE, SEASON_PACK_ACTION_DELETE:
+		default:
+			addProblem(item, true, "invalid action %q", rule.Action)
+		}
+		if rule.MinSeedingTime != "" {
+			if _, err := util.ParseTimeDuration(rule.MinSeedingTime); err != nil {
+				addProblem(item, true, "invalid minSeedingTime %q: %v", rule.MinSeedingTime, err)
+			}
+		}
+		if rule.PreserveFiles {
+			addProblem(item, false, "preserveFiles is deprecated, episode files are kept by default (see deleteFiles)")
+		}
+		for _, clientname := range rule.Clients {
+			if !isClient(clientname) {
+				addProblem(item, false, "client %s not found", clientname)
+			}
+		}
+	}
+	arrNames := map[string]bool{}
+	for i, arr := range data.Arrs {
+		item := fmt.Sprintf("arrs[%d] (%s)", i, arr.Name)
+		if arr.Name == "" {
+			addProblem(item, true, "name must be set")
+		} else if arrNames[arr.Name] {
+			addProblem(item, true, "duplicate arr name %s", arr.Name)
+		}
+		arrNames[arr.Name] = true
+		if arr.Type != ARR_TYPE_SONARR && arr.Type != ARR_TYPE_RADARR {
+			addProblem(item, true, "invalid type %q (must be %q or %q)", arr.Type, ARR_TYPE_SONARR, ARR_TYPE_RADARR)
+		}
+		if !util.IsUrl(arr.Url) {
+			addProblem(item, true, "invalid url %q", arr.Url)
+		}
+		if arr.ApiKey == "" {
+			addProblem(item, true, "apiKey must be set")
+		}
+		if arr.ImportMode != "" && arr.ImportMode != "Copy" && arr.ImportMode != "Move" {
+			addProblem(item, true, "invalid importMode %q", arr.ImportMode)
+		}
+	}
+	return problems, nil
+}
+
+// Return the keys of fields that does NOT match with any (yaml tagged) field of structType.
+func unknownFields(fields map[string]any, structType reflect.Type) (keys []string) {
+	knownFields := map[string]bool{}
+	for i := 0; i < structType.NumField(); i++ {
+		if tag := structType.Field(i).Tag.Get("yaml"); tag != "" {
+			knownFields[strings.ToLower(strings.Split(tag, ",")[0])] = true
+		}
+	}
+	for key := range fields {
+		if !knownFields[strings.ToLower(key)] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
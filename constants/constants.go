@@ -20,13 +20,17 @@ const ENV_PROXY = "env"
 const FILENAME_INVALID_CHARS_REGEX = `[<>:"/\|\?\*]+`
 const FILEPATH_INVALID_CHARS_REGEX = `[<>:"|\?\*]+`
 
-const PERM = 0600 // 程序创建的所有文件的 PERM
+const PERM = 0600     // 程序创建的所有文件的 PERM
+const PERM_DIR = 0755 // 程序创建的文件夹的 PERM
 
 const FILENAME_SUFFIX_ADDED = ".added"
 const FILENAME_SUFFIX_OK = ".ok"
 const FILENAME_SUFFIX_FAIL = ".fail"
 const FILENAME_SUFFIX_BACKUP = ".bak"
 
+// qBittorrent "Append .!qB extension to incomplete files" option
+const FILENAME_SUFFIX_QB_INCOMPLETE = ".!qB"
+
 // Some funcs require a (positive) timeout parameter. Use a very long value to emulate infinite. (Seconds)
 const INFINITE_TIMEOUT = 86400 * 365 * 100
 
@@ -133,6 +137,44 @@ var DefaultIgnorePatterns = []string{
 // Returned if the action is not processed due to in dry run mode
 var ErrDryRun = fmt.Errorf("dry run")
 
+// Typed error categories. Errors of client / site layers wrap them (check with errors.Is),
+// so the process exits with the distinct exit code of the category, see EXIT_* constants.
+var (
+	// Authentication failed, e.g. wrong client username / password or site cookie expired.
+	ErrAuth = fmt.Errorf("authentication failed")
+	// Network error, e.g. client / site is unreachable, or the server responds 5xx status.
+	ErrNetwork = fmt.Errorf("network error")
+	// The requested resource (e.g. client, site, torrent) is not found.
+	ErrNotFound = fmt.Errorf("not found")
+	// Some of the items processed by command failed.
+	ErrPartial = fmt.Errorf("partial failure")
+)
+
+// An error which carries an explicit process exit code, e.g. the non-zero exit code of a plugin.
+// It takes precedence over the typed error categories.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// Process exit codes.
+const (
+	EXIT_OK       = 0
+	EXIT_ERROR    = 1 // general error
+	EXIT_PARTIAL  = 2 // ErrPartial
+	EXIT_AUTH     = 3 // ErrAuth
+	EXIT_NETWORK  = 4 // ErrNetwork
+	EXIT_NOTFOUND = 5 // ErrNotFound
+)
+
 func init() {
 	args := []string{}
 	for old, new := range FilepathRestrictedCharacterReplacement {
@@ -42,4 +42,41 @@ const HELP_ARG_TIMES = `Time string (local timezone). ` +
 const HELP_ARG_PATH_MAPPERS = `E.g. ` +
 	`"/root/Downloads|/var/Downloads" will map "/root/Downloads" or "/root/Downloads/..." path to ` +
 	`"/var/Downloads" or "/var/Downloads/...". You can also use ":" instead of "|" as the separator ` +
-	`if both pathes do not contain ":" char.`
+	`if both pathes do not contain ":" char. Windows UNC (e.g. "\\nas\share") and extended-length ` +
+	`(e.g. "\\?\D:\Downloads") pathes are supported.`
+const HELP_ARG_CLIENT_PATH_MAPPINGS = `If not set, the "pathMappings" of client in config file is used.`
+const HELP_ARG_EXPR = `Filter torrents by expression. ` +
+	`E.g. 'ratio < 1 && size > 10GiB && tracker =~ "hdsky"'. ` +
+	`Operators: || && ! () == != < <= > >= =~ (regexp match) !~. ` +
+	`String fields: name, hash, category, tracker, state, save_path, content_path; ` +
+	`List field: tags; Size fields: size, size_total, downloaded, uploaded, dlspeed, upspeed; ` +
+	`Number fields: ratio, progress, seeders, leechers; ` +
+	`Time fields (value could be a time or duration e.g. "5d"): added, completed, activity; ` +
+	`Bool fields: complete, partial`
+const HELP_ARG_SITE_TORRENT_EXPR = `Filter torrents by expression. ` +
+	`E.g. 'seeders > 10 && resolution == 2160p && subtitles == Chinese'. ` +
+	`Operators: || && ! () == != < <= > >= =~ (regexp match) !~. ` +
+	`String fields: name, description, id, hash; List field: tags; Size field: size; ` +
+	`Number fields: seeders, leechers, snatched; Time field (value could be a time or duration e.g. "5d"): time; ` +
+	`Bool fields: free, hr, paid, neutral; ` +
+	`Release fields (parsed from name): source, group (string), season, episode (number); ` +
+	`Details fields (requires "--details" flag; resolution and codecs fall back to the ones parsed from name): ` +
+	`resolution, video_codec, audio_codec, mediainfo, ` +
+	`douban_id, imdb_id (string), subtitles (list); ` +
+	`Metadata fields (requires "--metadata" flag): title (string), year, rating, imdb_rating, tmdb_rating, ` +
+	`tmdb_id (number). E.g. 'rating >= 7.5 && year >= 2010'`
+const HELP_ARG_MAX_BONUS_COST = `Skip paid (cost bonus points) torrent which price is higher than this. ` +
+	`Paid torrent which price is unknown is also skipped. -1 == no limit`
+const HELP_ARG_DISCOUNT = `Comma-separated list. Only select torrents of these discount types. ` +
+	`Types: none, free, 2xfree, 2xup, notraffic (0x download & 0x upload), ` +
+	`"<percent>%" (e.g. "50%", "30%") and "2x<percent>%" (e.g. "2x50%"). E.g. "free,2xfree,50%"`
+const HELP_ARG_STAGE_DIR = `Save downloaded torrents to this staging dir instead of adding them to client. ` +
+	`The category, tags & save path of adding are encoded into the "comment" field of saved .torrent files. ` +
+	`Add them to client later by "ptool addlocal <client> <dir> --use-comment-meta"`
+const HELP_ARG_STAGE_HOOK = `Used with "--stage-dir". Validation command executed for each staged torrent, ` +
+	`with env variables: PTOOL_TORRENT_FILE, PTOOL_TORRENT_INFOHASH, PTOOL_TORRENT_NAME, PTOOL_TORRENT_SIZE, ` +
+	`PTOOL_TORRENT_TRACKER, PTOOL_TORRENT_CATEGORY, PTOOL_TORRENT_TAGS (comma-separated), ` +
+	`PTOOL_TORRENT_SAVE_PATH. If it exits with non-zero code, the staged file is renamed to *` + FILENAME_SUFFIX_FAIL
+const HELP_ARG_ANNOUNCE_INTERVAL = `Announce pacing. Minimal interval (seconds) between adding two torrents ` +
+	`of the same tracker domain to client (torrent is announced to tracker on adding), ` +
+	`to avoid being rate-limited or banned by tracker. 0 == no limit`
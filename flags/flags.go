@@ -3,4 +3,7 @@ package flags
 
 var (
 	DumpHeaders = false
+	// Dry run: commands that modify clients / sites / files only print intended actions without executing them.
+	// Set by --dry-run flag, which is a global flag and also a local flag (with "-d" shorthand) of some commands.
+	DryRun = false
 )
@@ -0,0 +1,258 @@
+// Package metadata resolves the movie / TV show metadata (IMDb / TMDb ids, ratings and year) of site torrents
+// from their (messy) release names, using TMDb and / or OMDb api. Results are cached in local metadata cache dir
+// (see config.GetMetadataCacheDir) as "<key>.json" files.
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/crypto"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+const (
+	TYPE_MOVIE = "movie"
+	TYPE_TV    = "tv"
+)
+
+// A lookup query. If imdbId is set, other fields are ignored.
+type query struct {
+	title     string
+	year      int64
+	mediaType string // TYPE_MOVIE, TYPE_TV or "" (unknown, treated as movie)
+	imdbId    string
+}
+
+type cacheEntry struct {
+	Time     int64                 `json:"time"`
+	Metadata *site.TorrentMetadata `json:"metadata"` // nil: not found
+}
+
+var (
+	ErrNoProvider = fmt.Errorf("no metadata provider configured (set tmdbApiKey or omdbApiKey in config file)")
+
+	memoryCache = map[string]*site.TorrentMetadata{}
+	mu          sync.Mutex
+)
+
+// Lookup the metadata of torrent. It uses the IMDb id in torrent details (if fetched) or parses the title
+// and year from the torrent name (see releasename.Parse).
+// Return an error that wraps constants.ErrNotFound if not found.
+func Lookup(torrent *site.Torrent) (*site.TorrentMetadata, error) {
+	q := &query{}
+	if torrent.Details != nil && torrent.Details.ImdbId != "" {
+		q.imdbId = torrent.Details.ImdbId
+	} else {
+		release := releasename.Parse(torrent.Name)
+		q.title, q.year, q.mediaType = release.Title, release.Year, TYPE_MOVIE
+		if release.TV {
+			q.mediaType = TYPE_TV
+		}
+		if q.title == "" {
+			return nil, fmt.Errorf("no title found in torrent name %q: %w", torrent.Name, constants.ErrNotFound)
+		}
+	}
+	return lookup(q)
+}
+
+// Lookup the metadata of the movie (or TV show, if mediaType is TYPE_TV) by title and (optional) year.
+func LookupTitle(title string, year int64, mediaType string) (*site.TorrentMetadata, error) {
+	return lookup(&query{title: title, year: year, mediaType: mediaType})
+}
+
+// Check whether any metadata provider is configured. Return ErrNoProvider if none.
+func CheckProvider() error {
+	if config.Get().TmdbApiKey == "" && config.Get().OmdbApiKey == "" {
+		return ErrNoProvider
+	}
+	return nil
+}
+
+func lookup(q *query) (*site.TorrentMetadata, error) {
+	key := q.key()
+	mu.Lock()
+	metadata, ok := memoryCache[key]
+	mu.Unlock()
+	if !ok {
+		var err error
+		if metadata, ok = loadCache(key); !ok {
+			if metadata, err = fetch(q); err != nil {
+				return nil, err
+			}
+			saveCache(key, metadata)
+		}
+		mu.Lock()
+		memoryCache[key] = metadata
+		mu.Unlock()
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("no metadata found for %s: %w", q, constants.ErrNotFound)
+	}
+	// return a copy as the result may be modified by caller
+	result := *metadata
+	return &result, nil
+}
+
+// Fetch metadata from providers. TMDb is preferred if configured, and OMDb is used to fetch IMDb rating.
+// Return nil metadata if not found.
+func fetch(q *query) (metadata *site.TorrentMetadata, err error) {
+	if err = CheckProvider(); err != nil {
+		return nil, err
+	}
+	tmdbApiKey := config.Get().TmdbApiKey
+	omdbApiKey := config.Get().OmdbApiKey
+	httpClient, err := newHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	if tmdbApiKey != "" {
+		if metadata, err = tmdbLookup(httpClient, tmdbApiKey, q); err != nil {
+			return nil, fmt.Errorf("tmdb: %w", err)
+		}
+		if metadata == nil {
+			return nil, nil
+		}
+	}
+	if omdbApiKey != "" {
+		omdbQuery := q
+		if metadata != nil {
+			if metadata.ImdbId == "" {
+				return metadata, nil
+			}
+			omdbQuery = &query{imdbId: metadata.ImdbId}
+		}
+		omdbMetadata, err := omdbLookup(httpClient, omdbApiKey, omdbQuery)
+		if err != nil {
+			if metadata != nil {
+				log.Warnf("Failed to get IMDb rating of %s from omdb: %v", metadata.ImdbId, err)
+				return metadata, nil
+			}
+			return nil, fmt.Errorf("omdb: %w", err)
+		}
+		if metadata == nil {
+			metadata = omdbMetadata
+		} else if omdbMetadata != nil {
+			metadata.ImdbRating = omdbMetadata.ImdbRating
+		}
+	}
+	return metadata, nil
+}
+
+func newHttpClient() (*http.Client, error) {
+	httpClient := &http.Client{
+		Timeout: time.Duration(util.FirstNonZeroIntegerArg(config.Timeout, config.DEFAULT_TIMEOUT)) * time.Second,
+	}
+	if proxy := config.GetProxy(); proxy != "" && proxy != constants.NONE {
+		transport, err := util.NewProxyTransport(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create proxy transport: %w", err)
+		}
+		httpClient.Transport = transport
+	}
+	return httpClient, nil
+}
+
+// Fetch json of api url which contains apiKey (in query string).
+// The apiKey is redacted from the returned error, which may contain the url.
+func fetchJson(apiUrl string, apiKey string, v any, httpClient *http.Client) error {
+	if err := util.FetchJson(apiUrl, v, httpClient, nil); err != nil {
+		return &redactedError{err: err, secret: apiKey}
+	}
+	return nil
+}
+
+// An error with a secret (e.g. api key) redacted from it's message.
+type redactedError struct {
+	err    error
+	secret string
+}
+
+func (e *redactedError) Error() string {
+	msg := e.err.Error()
+	if e.secret == "" {
+		return msg
+	}
+	msg = strings.ReplaceAll(msg, e.secret, "<redacted>")
+	return strings.ReplaceAll(msg, url.QueryEscape(e.secret), "<redacted>")
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func (q *query) String() string {
+	if q.imdbId != "" {
+		return q.imdbId
+	}
+	if q.year > 0 {
+		return fmt.Sprintf("%q (%d)", q.title, q.year)
+	}
+	return fmt.Sprintf("%q", q.title)
+}
+
+// Return the cache key of query, which is a valid filename.
+func (q *query) key() string {
+	if q.imdbId != "" {
+		return q.imdbId
+	}
+	mediaType := q.mediaType
+	if mediaType == "" {
+		mediaType = TYPE_MOVIE
+	}
+	return mediaType + "-" + crypto.Md5String(strings.ToLower(q.title), "|", fmt.Sprint(q.year))
+}
+
+// Load cached metadata of key. ok is false if it's not cached or expired.
+// A nil metadata with ok == true means it's (cached as) not found.
+func loadCache(key string) (metadata *site.TorrentMetadata, ok bool) {
+	dir := config.GetMetadataCacheDir()
+	if dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || util.Now()-entry.Time > config.GetMetadataCacheTtl() {
+		return nil, false
+	}
+	log.Tracef("Use cached metadata %s", key)
+	return entry.Metadata, true
+}
+
+// Save metadata to local cache. Errors are only logged as cache is optional.
+func saveCache(key string, metadata *site.TorrentMetadata) {
+	dir := config.GetMetadataCacheDir()
+	if dir == "" {
+		return
+	}
+	data, err := json.Marshal(&cacheEntry{Time: util.Now(), Metadata: metadata})
+	if err == nil {
+		if err = os.MkdirAll(dir, constants.PERM_DIR); err == nil {
+			err = os.WriteFile(filepath.Join(dir, key+".json"), data, constants.PERM)
+		}
+	}
+	if err != nil {
+		log.Debugf("Failed to cache metadata %s: %v", key, err)
+	}
+}
+
+// Return url with query string params.
+func apiUrl(baseUrl string, params url.Values) string {
+	return baseUrl + "?" + params.Encode()
+}
@@ -0,0 +1,45 @@
+package metadata
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+func TestFetchJsonRedactsApiKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverUrl := server.URL
+	server.Close() // make requests fail with network error, which message contains the url
+	retry := util.HttpRetry
+	util.HttpRetry = &util.HttpRetryPolicy{}
+	defer func() { util.HttpRetry = retry }()
+	tests := []struct {
+		name   string
+		apiKey string
+	}{
+		{"plain", "0123456789abcdef"},
+		{"escaped", "a+b/c=="},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiUrl := serverUrl + "/?" + url.Values{"api_key": {test.apiKey}}.Encode()
+			err := fetchJson(apiUrl, test.apiKey, &struct{}{}, nil)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			msg := err.Error()
+			if strings.Contains(msg, test.apiKey) || strings.Contains(msg, url.QueryEscape(test.apiKey)) {
+				t.Errorf("error contains api key: %v", err)
+			}
+			if !errors.Is(err, constants.ErrNetwork) {
+				t.Errorf("expected network error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package metadata
+
+// OMDb api. See https://www.omdbapi.com/ .
+// GET /?apikey=key&i=tt0133093
+// GET /?apikey=key&t=The+Matrix&y=1999&type=movie
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const OMDB_API = "https://www.omdbapi.com/"
+
+type omdbResponse struct {
+	Response   string `json:"Response"` // "True" or "False"
+	Error      string `json:"Error"`    // e.g. "Movie not found!"
+	Title      string `json:"Title"`
+	Year       string `json:"Year"` // e.g. "1999", "1994–2004" (TV series)
+	Type       string `json:"Type"` // "movie", "series" or "episode"
+	ImdbId     string `json:"imdbID"`
+	ImdbRating string `json:"imdbRating"` // e.g. "8.7", "N/A"
+}
+
+// Lookup metadata from OMDb. Return nil if not found.
+func omdbLookup(httpClient *http.Client, apiKey string, q *query) (*site.TorrentMetadata, error) {
+	params := url.Values{"apikey": {apiKey}}
+	if q.imdbId != "" {
+		params.Set("i", q.imdbId)
+	} else {
+		params.Set("t", q.title)
+		if q.year > 0 {
+			params.Set("y", fmt.Sprint(q.year))
+		}
+		if q.mediaType == TYPE_TV {
+			params.Set("type", "series")
+		} else {
+			params.Set("type", "movie")
+		}
+	}
+	res := &omdbResponse{}
+	if err := fetchJson(apiUrl(OMDB_API, params), apiKey, res, httpClient); err != nil {
+		return nil, err
+	}
+	if res.Response != "True" {
+		if res.ImdbId == "" && res.Error != "" && res.Error != "Movie not found!" && res.Error != "Series not found!" &&
+			res.Error != "Incorrect IMDb ID." {
+			return nil, fmt.Errorf("api error: %s", res.Error)
+		}
+		return nil, nil
+	}
+	metadata := &site.TorrentMetadata{
+		Title:  res.Title,
+		Type:   TYPE_MOVIE,
+		ImdbId: res.ImdbId,
+	}
+	if res.Type == "series" || res.Type == "episode" {
+		metadata.Type = TYPE_TV
+	}
+	if len(res.Year) >= 4 {
+		metadata.Year = util.ParseInt(res.Year[:4])
+	}
+	if rating, err := strconv.ParseFloat(res.ImdbRating, 64); err == nil {
+		metadata.ImdbRating = rating
+	}
+	return metadata, nil
+}
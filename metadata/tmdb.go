@@ -0,0 +1,110 @@
+package metadata
+
+// TMDb api v3. See https://developer.themoviedb.org/reference/intro/getting-started .
+// GET /3/search/movie?query=The+Matrix&year=1999
+// GET /3/search/tv?query=Friends&first_air_date_year=1994
+// GET /3/find/tt0133093?external_source=imdb_id
+// GET /3/movie/603/external_ids
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const TMDB_API = "https://api.themoviedb.org/3/"
+
+type tmdbResult struct {
+	Id            int64   `json:"id"`
+	Title         string  `json:"title"` // movie
+	Name          string  `json:"name"`  // tv
+	ReleaseDate   string  `json:"release_date"`
+	FirstAirDate  string  `json:"first_air_date"`
+	VoteAverage   float64 `json:"vote_average"`
+	OriginalTitle string  `json:"original_title"`
+}
+
+type tmdbSearchResponse struct {
+	Results []*tmdbResult `json:"results"`
+}
+
+type tmdbFindResponse struct {
+	MovieResults []*tmdbResult `json:"movie_results"`
+	TvResults    []*tmdbResult `json:"tv_results"`
+}
+
+type tmdbExternalIdsResponse struct {
+	ImdbId string `json:"imdb_id"`
+}
+
+// Lookup metadata from TMDb. Return nil if not found.
+func tmdbLookup(httpClient *http.Client, apiKey string, q *query) (*site.TorrentMetadata, error) {
+	var result *tmdbResult
+	mediaType := q.mediaType
+	if q.imdbId != "" {
+		res := &tmdbFindResponse{}
+		err := fetchJson(apiUrl(TMDB_API+"find/"+url.PathEscape(q.imdbId), url.Values{
+			"api_key":         {apiKey},
+			"external_source": {"imdb_id"},
+		}), apiKey, res, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		if len(res.MovieResults) > 0 {
+			result, mediaType = res.MovieResults[0], TYPE_MOVIE
+		} else if len(res.TvResults) > 0 {
+			result, mediaType = res.TvResults[0], TYPE_TV
+		}
+	} else {
+		if mediaType != TYPE_TV {
+			mediaType = TYPE_MOVIE
+		}
+		params := url.Values{"api_key": {apiKey}, "query": {q.title}}
+		if q.year > 0 {
+			if mediaType == TYPE_TV {
+				params.Set("first_air_date_year", fmt.Sprint(q.year))
+			} else {
+				params.Set("year", fmt.Sprint(q.year))
+			}
+		}
+		res := &tmdbSearchResponse{}
+		if err := fetchJson(apiUrl(TMDB_API+"search/"+mediaType, params), apiKey, res, httpClient); err != nil {
+			return nil, err
+		}
+		if len(res.Results) > 0 {
+			result = res.Results[0]
+		}
+	}
+	if result == nil {
+		return nil, nil
+	}
+	metadata := &site.TorrentMetadata{
+		Title:      result.Title,
+		Type:       mediaType,
+		ImdbId:     q.imdbId,
+		TmdbId:     result.Id,
+		TmdbRating: result.VoteAverage,
+	}
+	date := result.ReleaseDate
+	if mediaType == TYPE_TV {
+		metadata.Title = result.Name
+		date = result.FirstAirDate
+	}
+	if year, _, found := strings.Cut(date, "-"); found {
+		metadata.Year = util.ParseInt(year)
+	}
+	if metadata.ImdbId == "" {
+		res := &tmdbExternalIdsResponse{}
+		err := fetchJson(apiUrl(fmt.Sprintf("%s%s/%d/external_ids", TMDB_API, mediaType, result.Id),
+			url.Values{"api_key": {apiKey}}), apiKey, res, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get external ids: %w", err)
+		}
+		metadata.ImdbId = res.ImdbId
+	}
+	return metadata, nil
+}
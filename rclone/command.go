@@ -0,0 +1,76 @@
+package rclone
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+	log "github.com/sirupsen/logrus"
+)
+
+const DEFAULT_BINARY = "rclone"
+
+// Runner of rclone (https://rclone.org/) commands, which shells out to the rclone binary.
+type Rclone struct {
+	Binary string   // path of rclone binary
+	Flags  []string // additional flags of every command, e.g. ["--config", "rclone.conf"]
+}
+
+// Create a runner. The rcloneFlags is a (shell-quoted) string of additional rclone flags.
+func New(binary string, rcloneFlags string) (*Rclone, error) {
+	if binary == "" {
+		binary = DEFAULT_BINARY
+	}
+	r := &Rclone{Binary: binary}
+	if rcloneFlags != "" {
+		var err error
+		if r.Flags, err = shlex.Split(rcloneFlags); err != nil {
+			return nil, fmt.Errorf("failed to parse rclone flags: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Run a rclone command. The stdin is fed to the command, and the stdout of it is returned,
+// the stderr is displayed.
+func (r *Rclone) Run(stdin []byte, args ...string) ([]byte, error) {
+	args = append(args, r.Flags...)
+	log.Infof("Run %s with args %v", r.Binary, args)
+	cmd := exec.Command(r.Binary, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("rclone %s failed: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// Copy the files (paths relative to srcDir, "/" separated) from local srcDir to dst (e.g. "remote:path").
+// Each file is copied to the same relative path in dst. Files which are identical in dst are skipped.
+func (r *Rclone) CopyFiles(srcDir string, dst string, files []string) error {
+	_, err := r.Run(filesFrom(files), "copy", srcDir, dst, "--files-from-raw", "-", "--no-traverse")
+	return err
+}
+
+// Check that the files (paths relative to srcDir) in dst are identical to the ones in srcDir,
+// by size and hash (if both sides support a common hash type). If download is true,
+// the files in dst are downloaded and compared byte by byte, which works with any remote but is slow.
+func (r *Rclone) CheckFiles(srcDir string, dst string, files []string, download bool) error {
+	args := []string{"check", srcDir, dst, "--files-from-raw", "-", "--one-way"}
+	if download {
+		args = append(args, "--download")
+	}
+	_, err := r.Run(filesFrom(files), args...)
+	return err
+}
+
+// Return the list of files in "--files-from-raw" format.
+func filesFrom(files []string) []byte {
+	return []byte(strings.Join(files, "\n") + "\n")
+}
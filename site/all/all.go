@@ -1,6 +1,7 @@
 package all
 
 import (
+	_ "github.com/sagan/ptool/site/custom"
 	_ "github.com/sagan/ptool/site/discuz"
 	_ "github.com/sagan/ptool/site/gazelle"
 	_ "github.com/sagan/ptool/site/gazellepw"
@@ -8,6 +9,7 @@ import (
 	_ "github.com/sagan/ptool/site/nexusphp"
 	_ "github.com/sagan/ptool/site/tnode"
 	_ "github.com/sagan/ptool/site/torrenttrader"
+	_ "github.com/sagan/ptool/site/torznab"
 	_ "github.com/sagan/ptool/site/tpl"
 	_ "github.com/sagan/ptool/site/unit3d"
 )
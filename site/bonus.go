@@ -0,0 +1,120 @@
+package site
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Types of site bonus points exchange options.
+const (
+	BONUS_EXCHANGE_UPLOAD = "upload" // upload credit. Value is the bytes
+	BONUS_EXCHANGE_INVITE = "invite" // invite. Value is the count of invites
+)
+
+var (
+	bonusExchangeSizeRegexp   = regexp.MustCompile(`(?i)\d+(\.\d+)?\s*[KMGTP]i?B`)
+	bonusExchangeInviteRegexp = regexp.MustCompile(`(\d+)\s*(个|個)?\s*(邀请|邀請|invite)`)
+)
+
+// A bonus points exchange option provided by site.
+type BonusExchangeOption struct {
+	Id    string     // site internal id of option, e.g. "3"
+	Name  string     // e.g. "10.0 GB上传量"
+	Type  string     // BONUS_EXCHANGE_*. Empty if it's other type, e.g. custom title or VIP
+	Value int64      // what (bytes of upload; count of invite) one exchange gets
+	Price float64    // bonus points cost of one exchange
+	Form  url.Values `json:"-"` // site type specific data that is submitted when exchanging
+}
+
+// Guess the type and value of exchange option from its name, e.g. "10.0 GB上传量", "1个邀请名额".
+func ParseBonusExchangeOptionName(name string) (exchangeType string, value int64) {
+	lowerName := strings.ToLower(name)
+	if strings.Contains(lowerName, "上传") || strings.Contains(lowerName, "上傳") ||
+		strings.Contains(lowerName, "upload") {
+		if m := bonusExchangeSizeRegexp.FindString(name); m != "" {
+			if value, _ = util.RAMInBytes(strings.ReplaceAll(m, " ", "")); value > 0 {
+				return BONUS_EXCHANGE_UPLOAD, value
+			}
+		}
+	}
+	if strings.Contains(lowerName, "邀请") || strings.Contains(lowerName, "邀請") ||
+		strings.Contains(lowerName, "invite") {
+		value = 1
+		if m := bonusExchangeInviteRegexp.FindStringSubmatch(lowerName); m != nil {
+			value = max(util.ParseInt(m[1]), 1)
+		}
+		return BONUS_EXCHANGE_INVITE, value
+	}
+	return "", 0
+}
+
+// Parse an exchange target, e.g. "upload:10GiB", "invite", "invite:2".
+// Return the type and the total value wanted.
+func ParseBonusExchange(str string) (exchangeType string, value int64, err error) {
+	exchangeType, valueStr, _ := strings.Cut(strings.ToLower(strings.TrimSpace(str)), ":")
+	switch exchangeType {
+	case BONUS_EXCHANGE_UPLOAD:
+		if value, err = util.RAMInBytes(valueStr); err != nil || value <= 0 {
+			return "", 0, fmt.Errorf("invalid upload size %q", valueStr)
+		}
+	case BONUS_EXCHANGE_INVITE:
+		value = 1
+		if valueStr != "" {
+			if value = util.ParseInt(valueStr); value <= 0 {
+				return "", 0, fmt.Errorf("invalid invite count %q", valueStr)
+			}
+		}
+	default:
+		return "", 0, fmt.Errorf("invalid exchange type %q. Supported types: %s, %s",
+			exchangeType, BONUS_EXCHANGE_UPLOAD, BONUS_EXCHANGE_INVITE)
+	}
+	return
+}
+
+// Plan the exchanges to get exactly value of exchangeType, using the options of larger value first.
+// The returned list may contain the same option multiple times.
+func PlanBonusExchanges(options []*BonusExchangeOption, exchangeType string, value int64) (
+	exchanges []*BonusExchangeOption, err error) {
+	options = util.Filter(options, func(option *BonusExchangeOption) bool {
+		return option.Type == exchangeType && option.Value > 0
+	})
+	if len(options) == 0 {
+		return nil, fmt.Errorf("site does not provide %s exchange option", exchangeType)
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		if options[i].Value != options[j].Value {
+			return options[i].Value > options[j].Value
+		}
+		return options[i].Price < options[j].Price
+	})
+	remaining := value
+	for _, option := range options {
+		for remaining >= option.Value {
+			exchanges = append(exchanges, option)
+			remaining -= option.Value
+		}
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("can not exchange exactly %s of %s with available options (at least %s per exchange)",
+			formatBonusExchangeValue(exchangeType, value), exchangeType,
+			formatBonusExchangeValue(exchangeType, options[len(options)-1].Value))
+	}
+	return exchanges, nil
+}
+
+// Return human readable value of exchange option, e.g. "10GiB" (upload), "1" (invite).
+func (option *BonusExchangeOption) ValueString() string {
+	return formatBonusExchangeValue(option.Type, option.Value)
+}
+
+func formatBonusExchangeValue(exchangeType string, value int64) string {
+	if exchangeType == BONUS_EXCHANGE_UPLOAD {
+		return util.BytesSize(float64(value))
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,100 @@
+package site
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Per-site anti-bot challenge solver using FlareSolverr. The clearance is cached and shared by all requests
+// to the same site in current process.
+type challengeSolver struct {
+	siteConfig *config.SiteConfigStruct
+	endpoint   string // FlareSolverr API url
+	proxy      string
+	mu         sync.Mutex // only one request of a site solves challenge at the same time
+	clearance  *util.Clearance
+}
+
+type siteChallengeSolvers struct{}
+
+var (
+	challengeSolvers   = map[string]*challengeSolver{} // site name => solver
+	challengeSolversMu sync.Mutex
+)
+
+func init() {
+	util.SiteChallengeSolver = siteChallengeSolvers{}
+}
+
+// Register the challenge solver of site, if FlareSolverr is configured and it's not already registered.
+func registerChallengeSolver(siteConfig *config.SiteConfigStruct, globalConfig *config.ConfigStruct) {
+	endpoint := siteConfig.FlareSolverr
+	if endpoint == "" {
+		endpoint = globalConfig.SiteFlareSolverr
+	}
+	if endpoint == "" || endpoint == constants.NONE {
+		return
+	}
+	challengeSolversMu.Lock()
+	defer challengeSolversMu.Unlock()
+	if challengeSolvers[siteConfig.GetName()] != nil {
+		return
+	}
+	challengeSolvers[siteConfig.GetName()] = &challengeSolver{
+		siteConfig: siteConfig,
+		endpoint:   endpoint,
+		proxy:      GetProxy(siteConfig, globalConfig),
+	}
+}
+
+func getChallengeSolver(url string) *challengeSolver {
+	domain := util.GetUrlDomain(url)
+	challengeSolversMu.Lock()
+	defer challengeSolversMu.Unlock()
+	for _, solver := range challengeSolvers {
+		if config.MatchSite(domain, solver.siteConfig) {
+			return solver
+		}
+	}
+	return nil
+}
+
+func (siteChallengeSolvers) Clearance(url string) *util.Clearance {
+	solver := getChallengeSolver(url)
+	if solver == nil {
+		return nil
+	}
+	solver.mu.Lock()
+	defer solver.mu.Unlock()
+	if solver.clearance != nil && time.Now().Before(solver.clearance.Expires) {
+		return solver.clearance
+	}
+	return nil
+}
+
+func (siteChallengeSolvers) Solve(url string, cookie string, stale *util.Clearance) (*util.Clearance, error) {
+	solver := getChallengeSolver(url)
+	if solver == nil {
+		return nil, nil
+	}
+	solver.mu.Lock()
+	defer solver.mu.Unlock()
+	if solver.clearance != nil && solver.clearance != stale && time.Now().Before(solver.clearance.Expires) {
+		return solver.clearance, nil
+	}
+	clearance, err := util.SolveChallengeWithFlareSolverr(solver.endpoint, url, cookie, solver.proxy,
+		time.Duration(config.DEFAULT_FLARESOLVERR_TIMEOUT)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Site %s challenge solved, clearance expires at %s", solver.siteConfig.GetName(),
+		clearance.Expires.Format(time.DateTime))
+	solver.clearance = clearance
+	return clearance, nil
+}
@@ -0,0 +1,174 @@
+package site
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// Site check status. See CheckSite.
+const (
+	CHECK_STATUS_OK             = "ok"
+	CHECK_STATUS_EXPIRING       = "expiring"       // cookie is valid but will expire soon
+	CHECK_STATUS_LOGIN_REQUIRED = "login_required" // cookie is invalid or has expired
+	CHECK_STATUS_BANNED         = "banned"         // user account is banned or disabled
+	CHECK_STATUS_DOWN           = "down"           // site is inaccessible currently
+)
+
+// Default time (seconds) before cookie expiration that site check reports "expiring" status.
+const DEFAULT_COOKIE_EXPIRE_WARNING = 86400 * 7
+
+var (
+	bannedRegexp = regexp.MustCompile(
+		`(?i)\b(account|user)\b[^.]{0,40}\b(banned|disabled|suspended)\b|(账号|帐号|账户|帳號|用户)[^。，,]{0,10}(禁用|停用|封禁)`)
+	statusRegexp = regexp.MustCompile(`\bstatus=(?P<status>\d{3})\b`)
+)
+
+type CheckResult struct {
+	Site         string `json:"site"`
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	UserName     string `json:"userName"`
+	CookieExpire int64  `json:"cookieExpire"` // unix timestamp. 0 if unknown
+	// count of unread private messages. Only available if user status is fetched successfully
+	UnreadMessages int64 `json:"unreadMessages"`
+}
+
+func (result *CheckResult) IsOk() bool {
+	return result.Status == CHECK_STATUS_OK
+}
+
+// Return true if text (e.g. site page content or error message) indicates the account is banned or disabled.
+func IsBannedText(text string) bool {
+	return bannedRegexp.MatchString(text)
+}
+
+// Check site status using current cookie (or other credentials) of site config.
+// It fetches user status from site and classifies the result: the cookie is ok / expiring within
+// expireWarning seconds / invalid (login required), the account is banned, or the site is down.
+// It always creates a new site instance so that the cached data of previous checks is not used.
+func CheckSite(sitename string, expireWarning int64) *CheckResult {
+	result := &CheckResult{Site: sitename}
+	siteConfig := config.GetSiteConfig(sitename)
+	if siteConfig == nil {
+		result.Status = CHECK_STATUS_DOWN
+		result.Message = "site not found in config"
+		return result
+	}
+	result.CookieExpire = GetCookieExpire(siteConfig)
+	siteInstance, err := CreateSiteInternal(sitename, siteConfig, config.Get())
+	if err != nil {
+		result.Status = CHECK_STATUS_LOGIN_REQUIRED
+		result.Message = fmt.Sprintf("failed to create site instance: %v", err)
+		return result
+	}
+	status, err := siteInstance.GetStatus()
+	if err == nil {
+		result.UnreadMessages = status.UnreadMessages
+	}
+	now := util.Now()
+	switch {
+	case err != nil && (errors.Is(err, ErrBanned) || IsBannedText(err.Error())):
+		result.Status = CHECK_STATUS_BANNED
+		result.Message = err.Error()
+	case err != nil && (util.AsNetworkError(err) || getErrorHttpStatus(err) >= 500):
+		result.Status = CHECK_STATUS_DOWN
+		result.Message = err.Error()
+	case err != nil:
+		result.Status = CHECK_STATUS_LOGIN_REQUIRED
+		result.Message = err.Error()
+	case !status.IsOk():
+		result.Status = CHECK_STATUS_LOGIN_REQUIRED
+		result.Message = "site status is not OK (cookie may be invalid)"
+	case result.CookieExpire > 0 && result.CookieExpire <= now:
+		result.Status = CHECK_STATUS_LOGIN_REQUIRED
+		result.UserName = status.UserName
+		result.Message = fmt.Sprintf("cookie has expired at %s", util.FormatTime(result.CookieExpire))
+	case result.CookieExpire > 0 && result.CookieExpire-now <= expireWarning:
+		result.Status = CHECK_STATUS_EXPIRING
+		result.UserName = status.UserName
+		result.Message = fmt.Sprintf("cookie will expire at %s", util.FormatTime(result.CookieExpire))
+	default:
+		result.Status = CHECK_STATUS_OK
+		result.UserName = status.UserName
+		result.Message = fmt.Sprintf("cookie is valid (username: %s)", status.UserName)
+	}
+	return result
+}
+
+// Return the cookie expiration time (unix timestamp) of site, or 0 if unknown.
+// It's the "cookieExpire" of site config if set. Otherwise, it's the earliest "exp" of
+// the JWT (JSON Web Token) values in cookie, which many sites use as login token.
+func GetCookieExpire(siteConfig *config.SiteConfigStruct) int64 {
+	if siteConfig.CookieExpire != "" {
+		if ts, err := util.ParseTime(siteConfig.CookieExpire, nil); err == nil {
+			return ts
+		}
+	}
+	expire := int64(0)
+	for _, cookie := range util.ParseCookie(siteConfig.Cookie) {
+		if exp := getJwtExpire(cookie[1]); exp > 0 && (expire == 0 || exp < expire) {
+			expire = exp
+		}
+	}
+	return expire
+}
+
+// Parse token as JWT and return it's "exp" claim. Return 0 if token is not a JWT or has no exp.
+func getJwtExpire(token string) int64 {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "eyJ") {
+		return 0
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return 0
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil {
+		return 0
+	}
+	exp, _ := claims.Exp.Float64()
+	return int64(exp)
+}
+
+// Extract http status of err (e.g. "status=502"). Return 0 if not found.
+func getErrorHttpStatus(err error) int {
+	if m := statusRegexp.FindStringSubmatch(err.Error()); m != nil {
+		status, _ := strconv.Atoi(m[statusRegexp.SubexpIndex("status")])
+		return status
+	}
+	return 0
+}
+
+// Check sites concurrently (see config.GetConcurrency). Return the results in the same order of sitenames.
+// If sitenames is nil, all enabled and not dead sites are checked.
+func CheckSites(sitenames []string, expireWarning int64) []*CheckResult {
+	if sitenames == nil {
+		for _, siteConfig := range config.Get().SitesEnabled {
+			if !siteConfig.Dead {
+				sitenames = append(sitenames, siteConfig.GetName())
+			}
+		}
+	}
+	results, errs := util.ParallelMap(sitenames, config.GetConcurrency(), config.GetItemTimeout(),
+		func(_ context.Context, sitename string) (*CheckResult, error) {
+			return CheckSite(sitename, expireWarning), nil
+		})
+	for i, err := range errs {
+		if err != nil {
+			results[i] = &CheckResult{Site: sitenames[i], Status: CHECK_STATUS_DOWN, Message: err.Error()}
+		}
+	}
+	return results
+}
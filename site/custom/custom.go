@@ -0,0 +1,415 @@
+package custom
+
+// custom is a generic site type for sites that ptool has no built-in support for.
+// The torrents list, search and user info pages are fetched from the url templates
+// and parsed by the (CSS) selectors defined in the site config. E.g.:
+// [[sites]]
+// type = "custom"
+// url = "https://example.com/"
+// torrentsUrl = "torrents.php?page={page}"
+// searchUrl = "torrents.php?search=%s"
+// selectorTorrentBlock = "table.torrents > tbody > tr:not(:first-child)"
+// selectorTorrent = "a.title"
+// torrentDownloadUrl = "download.php?id={id}"
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Noooste/azuretls-client"
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	// torrents list page url page number placeholder
+	PAGE_PLACEHOLDER = "{page}"
+	// default torrent title selector of torrent block
+	SELECTOR_TORRENT = `a[href*="details"]`
+)
+
+type Site struct {
+	Name        string
+	Location    *time.Location
+	SiteConfig  *config.SiteConfigStruct
+	Config      *config.ConfigStruct
+	HttpClient  *azuretls.Session
+	HttpHeaders [][]string
+	idRegexp    *regexp.Regexp
+}
+
+// PublishTorrent implements site.Site.
+func (csite *Site) PublishTorrent(contents []byte, metadata url.Values) (id string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+func (csite *Site) GetDefaultHttpHeaders() [][]string {
+	return csite.HttpHeaders
+}
+
+func (csite *Site) PurgeCache() {
+}
+
+func (csite *Site) GetName() string {
+	return csite.Name
+}
+
+// GetBonusExchangeOptions implements site.Site.
+func (csite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (csite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (csite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (csite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (csite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (csite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (csite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
+func (csite *Site) GetSiteConfig() *config.SiteConfigStruct {
+	return csite.SiteConfig
+}
+
+func (csite *Site) GetStatus() (*site.Status, error) {
+	if csite.SiteConfig.SelectorUserInfoUserName == "" {
+		return nil, fmt.Errorf("selectorUserInfoUserName is not configured")
+	}
+	userInfoUrl := csite.SiteConfig.Url
+	if csite.SiteConfig.UserInfoUrl != "" {
+		userInfoUrl = csite.SiteConfig.ParseSiteUrl(csite.SiteConfig.UserInfoUrl, false)
+	}
+	doc, err := csite.getDoc(userInfoUrl)
+	if err != nil {
+		return nil, err
+	}
+	userName := util.DomSelectorText(doc.Selection, csite.SiteConfig.SelectorUserInfoUserName)
+	if userName == "" {
+		return nil, fmt.Errorf("failed to get user name: %w", site.ErrNotLogined)
+	}
+	status := &site.Status{UserName: userName}
+	if csite.SiteConfig.SelectorUserInfoUploaded != "" {
+		status.UserUploaded, _ = util.ExtractSizeStr(
+			util.DomSelectorText(doc.Selection, csite.SiteConfig.SelectorUserInfoUploaded))
+	}
+	if csite.SiteConfig.SelectorUserInfoDownloaded != "" {
+		status.UserDownloaded, _ = util.ExtractSizeStr(
+			util.DomSelectorText(doc.Selection, csite.SiteConfig.SelectorUserInfoDownloaded))
+	}
+	status.ParseUserInfoDom(doc.Selection, csite.SiteConfig)
+	return status, nil
+}
+
+// pageMarker is the page number of torrents list page. If baseUrl is not empty, it's used as torrentsUrl.
+// If torrentsUrl has no {page} placeholder, only the first page is returned.
+func (csite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
+	torrents []*site.Torrent, nextPageMarker string, err error) {
+	if sort != "" && sort != constants.NONE {
+		return nil, "", fmt.Errorf("custom site does not support sorting")
+	}
+	if baseUrl == "" {
+		baseUrl = csite.SiteConfig.TorrentsUrl
+	}
+	if baseUrl == "" {
+		return nil, "", fmt.Errorf("torrentsUrl is not configured")
+	}
+	page := int64(1)
+	if csite.SiteConfig.TorrentsPageZeroBased {
+		page = 0
+	}
+	if pageMarker != "" {
+		page = util.ParseInt(pageMarker)
+	}
+	torrents, err = csite.getTorrents(strings.ReplaceAll(baseUrl, PAGE_PLACEHOLDER, fmt.Sprint(page)))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(torrents) > 0 && strings.Contains(baseUrl, PAGE_PLACEHOLDER) {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return
+}
+
+func (csite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
+	torrents, _, err := csite.GetAllTorrents("", false, "", "")
+	return torrents, err
+}
+
+func (csite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
+	if baseUrl == "" {
+		if csite.SiteConfig.SearchUrl != "" {
+			baseUrl = csite.SiteConfig.SearchUrl
+		} else if csite.SiteConfig.TorrentsUrl != "" {
+			baseUrl = csite.SiteConfig.TorrentsUrl
+		} else {
+			return nil, fmt.Errorf("searchUrl is not configured")
+		}
+	}
+	baseUrl = firstPageUrl(baseUrl, csite.SiteConfig.TorrentsPageZeroBased)
+	if !strings.Contains(baseUrl, "%s") {
+		searchQueryVariable := "search"
+		if csite.SiteConfig.SearchQueryVariable != "" {
+			searchQueryVariable = csite.SiteConfig.SearchQueryVariable
+		}
+		baseUrl = util.AppendUrlQueryStringDelimiter(baseUrl) + searchQueryVariable + "=%s"
+	}
+	return csite.getTorrents(strings.Replace(baseUrl, "%s", url.PathEscape(keyword), 1))
+}
+
+func (csite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
+	if !util.IsUrl(torrentUrl) {
+		id = strings.TrimPrefix(torrentUrl, csite.GetName()+".")
+		content, filename, err = csite.DownloadTorrentById(id)
+		return
+	}
+	id = csite.parseTorrentIdFromUrl(torrentUrl)
+	content, filename, err = site.DownloadTorrentByUrl(csite, csite.HttpClient, torrentUrl, id)
+	return
+}
+
+func (csite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
+	if csite.SiteConfig.TorrentDownloadUrl == "" {
+		return nil, "", fmt.Errorf("torrentDownloadUrl is not configured")
+	}
+	torrentUrl := csite.SiteConfig.ParseSiteUrl(
+		strings.ReplaceAll(csite.SiteConfig.TorrentDownloadUrl, "{id}", id), false)
+	return site.DownloadTorrentByUrl(csite, csite.HttpClient, torrentUrl, id)
+}
+
+func (csite *Site) getDoc(pageUrl string) (*goquery.Document, error) {
+	doc, res, err := util.GetUrlDocWithAzuretls(pageUrl, csite.HttpClient,
+		csite.SiteConfig.Cookie, site.GetUa(csite), csite.GetDefaultHttpHeaders())
+	if !csite.SiteConfig.AcceptAnyHttpStatus && err != nil || doc == nil {
+		return nil, fmt.Errorf("failed to fetch site page dom: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login") {
+		return nil, site.ErrNotLogined
+	}
+	return doc, nil
+}
+
+func (csite *Site) getTorrents(pageUrl string) ([]*site.Torrent, error) {
+	pageUrl = csite.SiteConfig.ParseSiteUrl(pageUrl, false)
+	doc, err := csite.getDoc(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+	return csite.parseTorrents(doc, pageUrl)
+}
+
+func (csite *Site) parseTorrents(doc *goquery.Document, pageUrl string) ([]*site.Torrent, error) {
+	siteConfig := csite.SiteConfig
+	baseUrlObj, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page url: %w", err)
+	}
+	// resolve relative url in page to absolute url
+	resolveUrl := func(href string) string {
+		if href == "" {
+			return ""
+		}
+		urlObj, err := url.Parse(href)
+		if err != nil {
+			return ""
+		}
+		return baseUrlObj.ResolveReference(urlObj).String()
+	}
+	selectorTorrent := siteConfig.SelectorTorrent
+	if selectorTorrent == "" {
+		selectorTorrent = SELECTOR_TORRENT
+	}
+	torrents := []*site.Torrent{}
+	doc.Find(siteConfig.SelectorTorrentBlock).Each(func(i int, s *goquery.Selection) {
+		titleEl := s.Find(selectorTorrent).First()
+		name := util.DomSanitizedText(titleEl)
+		if name == "" {
+			return
+		}
+		detailsUrl := ""
+		if siteConfig.SelectorTorrentDetailsLink != "" {
+			detailsUrl = resolveUrl(s.Find(siteConfig.SelectorTorrentDetailsLink).First().AttrOr("href", ""))
+		} else {
+			detailsUrl = resolveUrl(titleEl.AttrOr("href", ""))
+		}
+		id := ""
+		if detailsUrl != "" {
+			id = csite.parseTorrentIdFromUrl(detailsUrl)
+		}
+		downloadUrl := ""
+		if siteConfig.SelectorTorrentDownloadLink != "" {
+			downloadUrl = resolveUrl(s.Find(siteConfig.SelectorTorrentDownloadLink).First().AttrOr("href", ""))
+			if id == "" && downloadUrl != "" {
+				id = csite.parseTorrentIdFromUrl(downloadUrl)
+			}
+		}
+		if downloadUrl == "" && id != "" && siteConfig.TorrentDownloadUrl != "" {
+			downloadUrl = siteConfig.ParseSiteUrl(strings.ReplaceAll(siteConfig.TorrentDownloadUrl, "{id}", id), false)
+		}
+		if downloadUrl == "" && id == "" {
+			return
+		}
+		torrent := &site.Torrent{
+			Name:               name,
+			DownloadUrl:        downloadUrl,
+			DownloadMultiplier: 1,
+			UploadMultiplier:   1,
+			HasHnR:             siteConfig.GlobalHnR,
+		}
+		if id != "" {
+			torrent.Id = csite.GetName() + "." + id
+		}
+		if siteConfig.SelectorTorrentTime != "" {
+			torrent.Time, _ = util.ExtractTime(util.DomSelectorText(s, siteConfig.SelectorTorrentTime), csite.Location)
+		}
+		if siteConfig.SelectorTorrentSize != "" {
+			torrent.Size, _ = util.RAMInBytes(util.DomSelectorText(s, siteConfig.SelectorTorrentSize))
+		}
+		if siteConfig.SelectorTorrentSeeders != "" {
+			torrent.Seeders = util.ParseInt(util.DomSelectorText(s, siteConfig.SelectorTorrentSeeders))
+		}
+		if siteConfig.SelectorTorrentLeechers != "" {
+			torrent.Leechers = util.ParseInt(util.DomSelectorText(s, siteConfig.SelectorTorrentLeechers))
+		}
+		if siteConfig.SelectorTorrentSnatched != "" {
+			torrent.Snatched = util.ParseInt(util.DomSelectorText(s, siteConfig.SelectorTorrentSnatched))
+		}
+		if siteConfig.SelectorTorrentHnR != "" && s.Find(siteConfig.SelectorTorrentHnR).Length() > 0 {
+			torrent.HasHnR = true
+		}
+		if siteConfig.SelectorTorrentFree != "" && s.Find(siteConfig.SelectorTorrentFree).Length() > 0 {
+			torrent.DownloadMultiplier = 0
+		}
+		if siteConfig.SelectorTorrentPaid != "" && s.Find(siteConfig.SelectorTorrentPaid).Length() > 0 {
+			torrent.Paid = true
+		}
+		if siteConfig.SelectorTorrentBonusCost != "" {
+			if cost := site.ParseBonusCost(util.DomSelectorText(s, siteConfig.SelectorTorrentBonusCost)); cost > 0 {
+				torrent.Paid = true
+				torrent.BonusCost = cost
+			}
+		}
+		if siteConfig.SelectorTorrentNeutral != "" && s.Find(siteConfig.SelectorTorrentNeutral).Length() > 0 {
+			torrent.DownloadMultiplier = 0
+			torrent.UploadMultiplier = 0
+			torrent.Neutral = true
+		} else if siteConfig.SelectorTorrentNoTraffic != "" && s.Find(siteConfig.SelectorTorrentNoTraffic).Length() > 0 {
+			torrent.DownloadMultiplier = 0
+			torrent.UploadMultiplier = 0
+		}
+		if siteConfig.SelectorTorrentCurrentActive != "" &&
+			s.Find(siteConfig.SelectorTorrentCurrentActive).Length() > 0 {
+			torrent.IsActive = true
+			torrent.IsCurrentActive = true
+		} else if siteConfig.SelectorTorrentActive != "" && s.Find(siteConfig.SelectorTorrentActive).Length() > 0 {
+			torrent.IsActive = true
+		}
+		if siteConfig.SelectorTorrentDiscountEndTime != "" {
+			torrent.DiscountEndTime, _ = util.ParseFutureTime(
+				util.DomRemovedSpecialCharsText(s.Find(siteConfig.SelectorTorrentDiscountEndTime)))
+		}
+		torrents = append(torrents, torrent)
+	})
+	return torrents, nil
+}
+
+func (csite *Site) parseTorrentIdFromUrl(torrentUrl string) (id string) {
+	if csite.idRegexp != nil {
+		if m := csite.idRegexp.FindStringSubmatch(torrentUrl); m != nil {
+			id = m[csite.idRegexp.SubexpIndex("id")]
+		}
+	}
+	if id == "" {
+		if urlObj, err := url.Parse(torrentUrl); err == nil {
+			id = urlObj.Query().Get("id")
+		}
+	}
+	return
+}
+
+// Replace the {page} placeholder of torrents list page url with first page number.
+func firstPageUrl(pageUrl string, zeroBased bool) string {
+	if zeroBased {
+		return strings.ReplaceAll(pageUrl, PAGE_PLACEHOLDER, "0")
+	}
+	return strings.ReplaceAll(pageUrl, PAGE_PLACEHOLDER, "1")
+}
+
+func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
+	if siteConfig.Url == "" {
+		return nil, fmt.Errorf("url is not configured")
+	}
+	if siteConfig.SelectorTorrentBlock == "" {
+		return nil, fmt.Errorf("selectorTorrentBlock is not configured")
+	}
+	if siteConfig.Cookie == "" {
+		log.Warnf("Site %s has no cookie provided", name)
+	}
+	location, err := time.LoadLocation(siteConfig.GetTimezone())
+	if err != nil {
+		return nil, fmt.Errorf("invalid site timezone %s: %w", siteConfig.GetTimezone(), err)
+	}
+	var idRegexp *regexp.Regexp
+	if siteConfig.TorrentUrlIdRegexp != "" {
+		if idRegexp, err = regexp.Compile(siteConfig.TorrentUrlIdRegexp); err != nil {
+			return nil, fmt.Errorf("invalid torrentUrlIdRegexp: %w", err)
+		}
+		if idRegexp.SubexpIndex("id") < 0 {
+			return nil, fmt.Errorf("invalid torrentUrlIdRegexp: no (?P<id>...) named group")
+		}
+	}
+	httpClient, httpHeaders, err := site.CreateSiteHttpClient(siteConfig, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create site http client: %w", err)
+	}
+	site := &Site{
+		Name:        name,
+		Location:    location,
+		SiteConfig:  siteConfig,
+		Config:      config,
+		HttpClient:  httpClient,
+		HttpHeaders: httpHeaders,
+		idRegexp:    idRegexp,
+	}
+	return site, nil
+}
+
+func init() {
+	site.Register(&site.RegInfo{
+		Name:    "custom",
+		Creator: NewSite,
+	})
+}
+
+var (
+	_ site.Site = (*Site)(nil)
+)
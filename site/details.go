@@ -0,0 +1,235 @@
+package site
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+// Structured (media) details of a torrent, parsed from it's details (description) page.
+// Fields that could not be found are left empty.
+type TorrentDetails struct {
+	Resolution string   // normalized video resolution, e.g. "2160p", "1080p", "720p"
+	VideoCodec string   // normalized video codec, e.g. "HEVC", "AVC", "AV1"
+	AudioCodec string   // normalized (main) audio codec, e.g. "TrueHD", "DTS-HD MA", "DDP", "AAC"
+	Subtitles  []string // subtitle languages, e.g. "Chinese", "English"
+	MediaInfo  string   // the raw MediaInfo (or BDInfo) report block
+	DoubanId   string   // e.g. "1292052"
+	ImdbId     string   // e.g. "tt0111161"
+}
+
+// Metadata of the movie or TV show of a torrent, resolved from the torrent name (or the IMDb id in details)
+// by metadata providers (TMDb / OMDb).
+type TorrentMetadata struct {
+	Title      string  // canonical title
+	Year       int64   // release year (first air year of TV show)
+	Type       string  // "movie" or "tv"
+	ImdbId     string  // e.g. "tt0111161"
+	TmdbId     int64   // e.g. 278
+	ImdbRating float64 // 0-10. 0 = unknown
+	TmdbRating float64 // 0-10. 0 = unknown
+}
+
+// Return the IMDb rating, or TMDb rating if the former is unknown.
+func (metadata *TorrentMetadata) Rating() float64 {
+	if metadata.ImdbRating > 0 {
+		return metadata.ImdbRating
+	}
+	return metadata.TmdbRating
+}
+
+var (
+	// The first line of a MediaInfo / BDInfo report.
+	mediaInfoStartRegexp = regexp.MustCompile(`(?im)^[ \t]*(General|DISC INFO:?|概览|概要)[ \t]*$`)
+	// Section header lines of a MediaInfo / BDInfo report, e.g. "Video", "Audio #1", "Text #2", "AUDIO:".
+	mediaInfoSectionRegexp = regexp.MustCompile(`(?i)^(General|Video|Audio|Text|Menu|Chapters?|Image|Other|` +
+		`概览|概要|视频|音频|文本|字幕|菜单|章节|DISC INFO|PLAYLIST REPORT|SUBTITLES|FILES):?( #\d+)?$`)
+	// "Key : Value" lines of a MediaInfo report, or table lines of a BDInfo report.
+	mediaInfoFieldRegexp = regexp.MustCompile(`^[^:：]{1,80}[:：]|^[-\s]{3,}$|^\S.*\s{2,}\S`)
+	doubanIdRegexp       = regexp.MustCompile(`douban\.com/(?:movie/)?subject/(\d+)`)
+	imdbIdRegexp         = regexp.MustCompile(`\b(tt\d{7,8})\b`)
+	// Chinese section / field names of MediaInfo report => English ones
+	mediaInfoNames = map[string]string{
+		"视频": "video", "音频": "audio", "文本": "text", "字幕": "text", "subtitles": "text",
+		"格式": "format", "高度": "height", "语言": "language", "商业名称": "commercial name",
+	}
+	// Subtitle keywords in description text => language
+	subtitleKeywords = [][2]string{
+		{"中字", "Chinese"}, {"中文字幕", "Chinese"}, {"简中", "Chinese"}, {"繁中", "Chinese"}, {"简体", "Chinese"},
+		{"繁体", "Chinese"}, {"简繁", "Chinese"}, {"中英", "Chinese"}, {"中英", "English"}, {"英字", "English"},
+		{"英文字幕", "English"}, {"日字", "Japanese"}, {"日文字幕", "Japanese"},
+	}
+)
+
+// Parse torrent details from the text of torrent details page (title, description, MediaInfo report,
+// and the urls of links in it, which are required for finding Douban / IMDb ids).
+// mediaInfo is the MediaInfo report, if the site provides it separately; otherwise it's extracted from text.
+// Values found in the MediaInfo report take precedence over the ones found in other text.
+func ParseTorrentDetails(text string, mediaInfo string) *TorrentDetails {
+	details := &TorrentDetails{}
+	mediaInfo = strings.TrimSpace(mediaInfo)
+	if mediaInfo == "" {
+		mediaInfo = ExtractMediaInfo(text)
+	}
+	details.MediaInfo = mediaInfo
+	if mediaInfo != "" {
+		for _, section := range parseMediaInfoSections(mediaInfo) {
+			switch section.name {
+			case "video":
+				if details.VideoCodec == "" {
+					details.VideoCodec = releasename.ParseVideoCodec(section.fields["format"])
+				}
+				if details.Resolution == "" {
+					// e.g. "1 080 pixels"
+					height := strings.Map(func(r rune) rune {
+						if r >= '0' && r <= '9' {
+							return r
+						}
+						return -1
+					}, section.fields["height"])
+					details.Resolution = releasename.ResolutionByHeight(util.ParseInt(height))
+				}
+			case "audio":
+				if details.AudioCodec == "" {
+					details.AudioCodec = releasename.ParseAudioCodec(
+						section.fields["format"] + " " + section.fields["commercial name"])
+				}
+			case "text":
+				language := section.fields["language"]
+				if language != "" && !slices.Contains(details.Subtitles, language) {
+					details.Subtitles = append(details.Subtitles, language)
+				}
+			}
+		}
+		// BDInfo report, which lists streams in tables
+		if details.VideoCodec == "" {
+			details.VideoCodec = releasename.ParseVideoCodec(mediaInfo)
+		}
+		if details.AudioCodec == "" {
+			details.AudioCodec = releasename.ParseAudioCodec(mediaInfo)
+		}
+		if details.Resolution == "" {
+			details.Resolution = releasename.ParseResolution(mediaInfo)
+		}
+	}
+	otherText := strings.Replace(text, mediaInfo, "", 1)
+	if details.Resolution == "" {
+		details.Resolution = releasename.ParseResolution(otherText)
+	}
+	if details.VideoCodec == "" {
+		details.VideoCodec = releasename.ParseVideoCodec(otherText)
+	}
+	if details.AudioCodec == "" {
+		details.AudioCodec = releasename.ParseAudioCodec(otherText)
+	}
+	if len(details.Subtitles) == 0 {
+		for _, keyword := range subtitleKeywords {
+			if strings.Contains(otherText, keyword[0]) && !slices.Contains(details.Subtitles, keyword[1]) {
+				details.Subtitles = append(details.Subtitles, keyword[1])
+			}
+		}
+	}
+	if m := doubanIdRegexp.FindStringSubmatch(text); m != nil {
+		details.DoubanId = m[1]
+	}
+	if m := imdbIdRegexp.FindStringSubmatch(text); m != nil {
+		details.ImdbId = m[1]
+	}
+	return details
+}
+
+// Similar to ParseTorrentDetails, but parse the text and links of the DOM elements of torrent details page.
+// If mediaInfoEl is not empty, it's text is used as the MediaInfo report.
+func ParseTorrentDetailsDom(el *goquery.Selection, mediaInfoEl *goquery.Selection) *TorrentDetails {
+	texts := []string{}
+	el.Each(func(i int, s *goquery.Selection) {
+		texts = append(texts, util.DomMultilineText(s))
+	})
+	el.Find("a[href]").AddSelection(el.Filter("a[href]")).Each(func(i int, s *goquery.Selection) {
+		texts = append(texts, s.AttrOr("href", ""))
+	})
+	mediaInfo := ""
+	if mediaInfoEl != nil && mediaInfoEl.Length() > 0 {
+		mediaInfo = util.DomMultilineText(mediaInfoEl.First())
+	}
+	return ParseTorrentDetails(strings.Join(texts, "\n"), mediaInfo)
+}
+
+// Extract the first MediaInfo (or BDInfo) report block from text. Return empty string if not found.
+// The block ends at the first line that is neither a section header nor a field (after a blank line,
+// only a section header is allowed).
+func ExtractMediaInfo(text string) string {
+	loc := mediaInfoStartRegexp.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+	lines := strings.Split(text[loc[0]:], "\n")
+	end := 0
+	fields := 0
+	blank := false
+	header := false
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if mediaInfoSectionRegexp.MatchString(line) {
+			header = true
+		} else if i > 0 && (blank && !header || !mediaInfoFieldRegexp.MatchString(line)) {
+			break
+		} else {
+			header = false
+			fields++
+		}
+		blank = false
+		end = i + 1
+	}
+	// a valid report has at least several fields
+	if fields < 3 {
+		return ""
+	}
+	return strings.Join(lines[:end], "\n")
+}
+
+type mediaInfoSection struct {
+	name   string            // lower case section name without index, e.g. "video", "audio"
+	fields map[string]string // lower case field name => value (of the first field with that name)
+}
+
+func parseMediaInfoSections(mediaInfo string) []*mediaInfoSection {
+	sections := []*mediaInfoSection{}
+	var section *mediaInfoSection
+	for _, line := range strings.Split(mediaInfo, "\n") {
+		line = strings.TrimSpace(line)
+		if mediaInfoSectionRegexp.MatchString(line) {
+			name, _, _ := strings.Cut(strings.ToLower(strings.TrimSuffix(line, ":")), " ")
+			if mediaInfoNames[name] != "" {
+				name = mediaInfoNames[name]
+			}
+			section = &mediaInfoSection{name: name, fields: map[string]string{}}
+			sections = append(sections, section)
+			continue
+		}
+		if section == nil {
+			continue
+		}
+		key, value, found := strings.Cut(strings.ReplaceAll(line, "：", ":"), ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if mediaInfoNames[key] != "" {
+			key = mediaInfoNames[key]
+		}
+		if _, ok := section.fields[key]; !ok {
+			section.fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,192 @@
+package site_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/site"
+)
+
+const testMediaInfo = `General
+Unique ID                                : 123456789
+Complete name                            : The.Movie.2020.2160p.UHD.BluRay.x265-GROUP.mkv
+Format                                   : Matroska
+
+Video
+ID                                       : 1
+Format                                   : HEVC
+Width                                    : 3 840 pixels
+Height                                   : 2 160 pixels
+
+Audio #1
+ID                                       : 2
+Format                                   : MLP FBA 16-ch
+Commercial name                          : Dolby TrueHD with Dolby Atmos
+
+Audio #2
+ID                                       : 3
+Format                                   : AC-3
+
+Text #1
+ID                                       : 4
+Format                                   : PGS
+Language                                 : Chinese
+
+Text #2
+ID                                       : 5
+Format                                   : PGS
+Language                                 : English
+
+Text #3
+ID                                       : 6
+Language                                 : Chinese`
+
+const testMediaInfoChinese = `概览
+文件名                                     : movie.mkv
+格式                                       : Matroska
+
+视频
+格式                                       : AVC
+宽度                                       : 1 920 像素
+高度                                       : 800 像素
+
+音频
+格式                                       : E-AC-3
+
+文本
+语言                                       : Chinese`
+
+const testBDInfo = `DISC INFO:
+
+Disc Title:     THE_MOVIE
+Disc Size:      61,234,567,890 bytes
+Protection:     AACS2
+
+PLAYLIST REPORT:
+
+Name:                   00800.MPLS
+Length:                 2:10:00.000
+
+VIDEO:
+
+Codec                   Bitrate             Description
+-----                   -------             -----------
+MPEG-H HEVC Video       60000 kbps          2160p / 23.976 fps / 16:9 / Main 10 @ Level 5.1 @ High
+
+AUDIO:
+
+Codec                           Language        Bitrate         Description
+-----                           --------        -------         -----------
+Dolby TrueHD/Atmos Audio        English         4000 kbps       7.1 / 48 kHz`
+
+func TestParseTorrentDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		mediaInfo string
+		expected  site.TorrentDetails
+	}{
+		{
+			name: "mediainfo in text",
+			text: "The Movie 2020 1080p BluRay x264 DTS 中字\n" +
+				"https://movie.douban.com/subject/1292052/\nhttps://www.imdb.com/title/tt0111161/\n\n" +
+				testMediaInfo + "\n\nScreenshots:",
+			expected: site.TorrentDetails{
+				Resolution: "2160p",
+				VideoCodec: "HEVC",
+				AudioCodec: "TrueHD",
+				Subtitles:  []string{"Chinese", "English"},
+				MediaInfo:  testMediaInfo,
+				DoubanId:   "1292052",
+				ImdbId:     "tt0111161",
+			},
+		},
+		{
+			name:      "separate mediainfo",
+			text:      "The Movie 2020 720p WEB-DL AAC\nhttps://www.douban.com/subject/26752088",
+			mediaInfo: "\n" + testMediaInfoChinese + "\n",
+			expected: site.TorrentDetails{
+				Resolution: "1080p",
+				VideoCodec: "AVC",
+				AudioCodec: "DDP",
+				Subtitles:  []string{"Chinese"},
+				MediaInfo:  testMediaInfoChinese,
+				DoubanId:   "26752088",
+			},
+		},
+		{
+			name:      "bdinfo",
+			text:      "The Movie 2020 BluRay",
+			mediaInfo: testBDInfo,
+			expected: site.TorrentDetails{
+				Resolution: "2160p",
+				VideoCodec: "HEVC",
+				AudioCodec: "TrueHD",
+				MediaInfo:  testBDInfo,
+			},
+		},
+		{
+			name: "no mediainfo",
+			text: "The.Movie.2020.1080p.BluRay.x264.DTS-HD.MA.5.1-GROUP\n简繁中英字幕\nIMDb: tt1234567",
+			expected: site.TorrentDetails{
+				Resolution: "1080p",
+				VideoCodec: "AVC",
+				AudioCodec: "DTS-HD MA",
+				Subtitles:  []string{"Chinese", "English"},
+				ImdbId:     "tt1234567",
+			},
+		},
+		{
+			name:     "empty",
+			expected: site.TorrentDetails{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			details := site.ParseTorrentDetails(test.text, test.mediaInfo)
+			if details.Resolution != test.expected.Resolution {
+				t.Errorf("expected resolution %q, got %q", test.expected.Resolution, details.Resolution)
+			}
+			if details.VideoCodec != test.expected.VideoCodec {
+				t.Errorf("expected video codec %q, got %q", test.expected.VideoCodec, details.VideoCodec)
+			}
+			if details.AudioCodec != test.expected.AudioCodec {
+				t.Errorf("expected audio codec %q, got %q", test.expected.AudioCodec, details.AudioCodec)
+			}
+			if !slices.Equal(details.Subtitles, test.expected.Subtitles) {
+				t.Errorf("expected subtitles %v, got %v", test.expected.Subtitles, details.Subtitles)
+			}
+			if details.MediaInfo != test.expected.MediaInfo {
+				t.Errorf("expected mediainfo %q, got %q", test.expected.MediaInfo, details.MediaInfo)
+			}
+			if details.DoubanId != test.expected.DoubanId {
+				t.Errorf("expected douban id %q, got %q", test.expected.DoubanId, details.DoubanId)
+			}
+			if details.ImdbId != test.expected.ImdbId {
+				t.Errorf("expected imdb id %q, got %q", test.expected.ImdbId, details.ImdbId)
+			}
+		})
+	}
+}
+
+func TestExtractMediaInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"followed by text", "Intro\n\n" + testMediaInfo + "\n\nThanks to the uploader!\n", testMediaInfo},
+		{"followed by text line", testMediaInfoChinese + "\n感谢原作者", testMediaInfoChinese},
+		{"bdinfo", "Quote:\n" + testBDInfo + "\n\n\nhttps://example.com/screenshot.png", testBDInfo},
+		{"too few fields", "General\nFormat : Matroska\nDuration : 2 h\n\nEnjoy", ""},
+		{"none", "The Movie 2020 1080p BluRay x264", ""},
+		{"empty", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := site.ExtractMediaInfo(test.text); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
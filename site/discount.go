@@ -0,0 +1,99 @@
+package site
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Normalized discount types of site torrents, derived from the download / upload multipliers.
+// Besides these, a partial download discount is "<percent>%" (e.g. "50%", "30%"),
+// and "2x<percent>%" (e.g. "2x50%") if upload is also doubled.
+const (
+	DISCOUNT_NONE      = "none"      // 1x download, 1x upload
+	DISCOUNT_FREE      = "free"      // 0x download
+	DISCOUNT_2XFREE    = "2xfree"    // 0x download, 2x (or more) upload
+	DISCOUNT_2XUP      = "2xup"      // 1x download, 2x (or more) upload
+	DISCOUNT_NOTRAFFIC = "notraffic" // 0x download, 0x upload. E.g. neutral torrents
+)
+
+var (
+	discountPercentRegexp     = regexp.MustCompile(`^(2x)?(\d{1,2})%$`)
+	discountTextPercentRegexp = regexp.MustCompile(`(^|\D)(\d{1,2})%`) // not match "100%"
+)
+
+// Return the normalized discount type of torrent.
+func (torrent *Torrent) DiscountType() string {
+	down, up := torrent.DownloadMultiplier, torrent.UploadMultiplier
+	switch {
+	case down == 0 && up == 0:
+		return DISCOUNT_NOTRAFFIC
+	case down == 0 && up >= 2:
+		return DISCOUNT_2XFREE
+	case down == 0:
+		return DISCOUNT_FREE
+	case down < 1 && up >= 2:
+		return fmt.Sprintf("2x%d%%", int64(math.Round(down*100)))
+	case down < 1:
+		return fmt.Sprintf("%d%%", int64(math.Round(down*100)))
+	case up >= 2:
+		return DISCOUNT_2XUP
+	default:
+		return DISCOUNT_NONE
+	}
+}
+
+// Return true if torrent has a discount type of the list. discountTypes should be lowercase.
+func (torrent *Torrent) MatchDiscountTypes(discountTypes []string) bool {
+	return slices.Contains(discountTypes, torrent.DiscountType())
+}
+
+// Return remaining time (seconds) of time-limited discount. Return -1 if discount is not time-limited.
+func (torrent *Torrent) DiscountRemainingTime(now int64) int64 {
+	if torrent.DiscountEndTime <= 0 {
+		return -1
+	}
+	return max(torrent.DiscountEndTime-now, 0)
+}
+
+// Parse and validate comma-separated discount types list, e.g. "free,2xfree,50%".
+func ParseDiscountTypes(str string) ([]string, error) {
+	discountTypes := util.SplitCsv(strings.ToLower(str))
+	for _, discountType := range discountTypes {
+		switch discountType {
+		case DISCOUNT_NONE, DISCOUNT_FREE, DISCOUNT_2XFREE, DISCOUNT_2XUP, DISCOUNT_NOTRAFFIC:
+			continue
+		}
+		if !discountPercentRegexp.MatchString(discountType) {
+			return nil, fmt.Errorf("invalid discount type %q", discountType)
+		}
+	}
+	return discountTypes, nil
+}
+
+// Parse a discount label text of site (e.g. "Free", "2X Free", "50%", "2X 50%", "2X", "免费", "2x免费")
+// into download and upload multipliers. ok is false if text is not recognized.
+func ParseDiscountText(text string) (downloadMultiplier float64, uploadMultiplier float64, ok bool) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), ""))
+	if text == "" {
+		return 1, 1, false
+	}
+	downloadMultiplier, uploadMultiplier = 1, 1
+	if strings.HasPrefix(text, "2x") || strings.HasPrefix(text, "2up") || strings.Contains(text, "2xup") ||
+		strings.Contains(text, "双倍") || strings.Contains(text, "雙倍") {
+		uploadMultiplier = 2
+		ok = true
+	}
+	if strings.Contains(text, "free") || strings.Contains(text, "免费") || strings.Contains(text, "免費") {
+		downloadMultiplier = 0
+		ok = true
+	} else if m := discountTextPercentRegexp.FindStringSubmatch(text); m != nil {
+		downloadMultiplier = float64(util.ParseInt(m[2])) / 100
+		ok = true
+	}
+	return
+}
@@ -0,0 +1,108 @@
+package site_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sagan/ptool/site"
+)
+
+func TestParseDiscountText(t *testing.T) {
+	tests := []struct {
+		text               string
+		downloadMultiplier float64
+		uploadMultiplier   float64
+		ok                 bool
+	}{
+		{"Free", 0, 1, true},
+		{" FREE ", 0, 1, true},
+		{"2X Free", 0, 2, true},
+		{"2xfree", 0, 2, true},
+		{"50%", 0.5, 1, true},
+		{"30%", 0.3, 1, true},
+		{"2X 50%", 0.5, 2, true},
+		{"2X", 1, 2, true},
+		{"2xUp", 1, 2, true},
+		{"2up", 1, 2, true},
+		{"免费", 0, 1, true},
+		{"免費", 0, 1, true},
+		{"2x免费", 0, 2, true},
+		{"双倍上传", 1, 2, true},
+		{"雙倍上傳", 1, 2, true},
+		{"50%下载", 0.5, 1, true},
+		{"100%", 1, 1, false},
+		{"普通", 1, 1, false},
+		{"", 1, 1, false},
+		{"   ", 1, 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			down, up, ok := site.ParseDiscountText(test.text)
+			if down != test.downloadMultiplier || up != test.uploadMultiplier || ok != test.ok {
+				t.Errorf("expected (%v, %v, %t), got (%v, %v, %t)",
+					test.downloadMultiplier, test.uploadMultiplier, test.ok, down, up, ok)
+			}
+		})
+	}
+}
+
+func TestDiscountType(t *testing.T) {
+	tests := []struct {
+		downloadMultiplier float64
+		uploadMultiplier   float64
+		expected           string
+	}{
+		{1, 1, site.DISCOUNT_NONE},
+		{0, 1, site.DISCOUNT_FREE},
+		{0, 2, site.DISCOUNT_2XFREE},
+		{0, 3, site.DISCOUNT_2XFREE},
+		{1, 2, site.DISCOUNT_2XUP},
+		{0, 0, site.DISCOUNT_NOTRAFFIC},
+		{0.5, 1, "50%"},
+		{0.3, 1, "30%"},
+		{0.5, 2, "2x50%"},
+		{0.25, 1, "25%"},
+		{1.5, 1, site.DISCOUNT_NONE},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			torrent := &site.Torrent{
+				DownloadMultiplier: test.downloadMultiplier,
+				UploadMultiplier:   test.uploadMultiplier,
+			}
+			if result := torrent.DiscountType(); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDiscountTypes(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected []string
+		wantErr  bool
+	}{
+		{"free,2xfree,50%", []string{"free", "2xfree", "50%"}, false},
+		{"FREE,2X50%", []string{"free", "2x50%"}, false},
+		{"none,2xup,notraffic", []string{"none", "2xup", "notraffic"}, false},
+		{"", nil, false},
+		{"free,bogus", nil, true},
+		{"100%", nil, true},
+		{"3x50%", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			result, err := site.ParseDiscountTypes(test.str)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", result)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(result, test.expected) {
+				t.Errorf("expected %v, got %v (err=%v)", test.expected, result, err)
+			}
+		})
+	}
+}
@@ -46,6 +46,41 @@ func (dzsite *Site) GetName() string {
 	return dzsite.Name
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (dzsite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (dzsite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (dzsite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (dzsite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (dzsite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (dzsite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (dzsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
 func (dzsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return dzsite.SiteConfig
 }
@@ -0,0 +1,151 @@
+package site
+
+import (
+	"github.com/sagan/ptool/util/filterexpr"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+// Available fields of site torrent in filter expression.
+// The release fields (source, group, season, episode) are parsed from torrent name.
+// The details fields (resolution, video_codec, ...) are only fully available if the torrent details has been
+// fetched (see Site.GetTorrentDetails), otherwise resolution and codecs are parsed from torrent name
+// and the others are empty.
+// The metadata fields (title, year, rating, ...) are only available if the torrent metadata has been resolved
+// (see metadata.Lookup), otherwise they are empty or 0.
+var TorrentExprSchema = filterexpr.Schema{
+	"name":        filterexpr.String,
+	"description": filterexpr.String,
+	"id":          filterexpr.String,
+	"hash":        filterexpr.String,
+	"tags":        filterexpr.StringList,
+	"size":        filterexpr.Size,
+	"time":        filterexpr.Time,
+	"seeders":     filterexpr.Number,
+	"leechers":    filterexpr.Number,
+	"snatched":    filterexpr.Number,
+	"free":        filterexpr.Bool,
+	"hr":          filterexpr.Bool,
+	"paid":        filterexpr.Bool,
+	"neutral":     filterexpr.Bool,
+	"source":      filterexpr.String,
+	"group":       filterexpr.String,
+	"season":      filterexpr.Number,
+	"episode":     filterexpr.Number,
+	"resolution":  filterexpr.String,
+	"video_codec": filterexpr.String,
+	"audio_codec": filterexpr.String,
+	"subtitles":   filterexpr.StringList,
+	"mediainfo":   filterexpr.String,
+	"douban_id":   filterexpr.String,
+	"imdb_id":     filterexpr.String,
+	"title":       filterexpr.String,
+	"year":        filterexpr.Number,
+	"rating":      filterexpr.Number,
+	"imdb_rating": filterexpr.Number,
+	"tmdb_rating": filterexpr.Number,
+	"tmdb_id":     filterexpr.Number,
+}
+
+// Compile a site torrent filter expression. See TorrentExprSchema for available fields.
+func ParseTorrentExpr(src string) (*filterexpr.Expr, error) {
+	return filterexpr.Compile(src, TorrentExprSchema)
+}
+
+// Return the release info parsed from torrent name. The result is cached.
+func (torrent *Torrent) Release() *releasename.Release {
+	if torrent.release == nil {
+		torrent.release = releasename.Parse(torrent.Name)
+	}
+	return torrent.release
+}
+
+func (torrent *Torrent) MatchExpr(expr *filterexpr.Expr) bool {
+	return expr.Match(torrent.ExprField)
+}
+
+// Return the value of torrent field in filter expression.
+func (torrent *Torrent) ExprField(name string) any {
+	switch name {
+	case "name":
+		return torrent.Name
+	case "description":
+		return torrent.Description
+	case "id":
+		return torrent.Id
+	case "hash":
+		return torrent.InfoHash
+	case "tags":
+		return torrent.Tags
+	case "size":
+		return torrent.Size
+	case "time":
+		return torrent.Time
+	case "seeders":
+		return torrent.Seeders
+	case "leechers":
+		return torrent.Leechers
+	case "snatched":
+		return torrent.Snatched
+	case "free":
+		return torrent.DownloadMultiplier == 0
+	case "hr":
+		return torrent.HasHnR
+	case "paid":
+		return torrent.Paid
+	case "neutral":
+		return torrent.Neutral
+	case "source":
+		return torrent.Release().Source
+	case "group":
+		return torrent.Release().Group
+	case "season":
+		return torrent.Release().Season
+	case "episode":
+		return torrent.Release().Episode
+	}
+	details := torrent.Details
+	if details == nil {
+		release := torrent.Release()
+		details = &TorrentDetails{
+			Resolution: release.Resolution,
+			VideoCodec: release.VideoCodec,
+			AudioCodec: release.AudioCodec,
+		}
+	}
+	metadata := torrent.Metadata
+	if metadata == nil {
+		metadata = &TorrentMetadata{}
+	}
+	switch name {
+	case "resolution":
+		return details.Resolution
+	case "video_codec":
+		return details.VideoCodec
+	case "audio_codec":
+		return details.AudioCodec
+	case "subtitles":
+		return details.Subtitles
+	case "mediainfo":
+		return details.MediaInfo
+	case "douban_id":
+		return details.DoubanId
+	case "imdb_id":
+		if details.ImdbId == "" {
+			return metadata.ImdbId
+		}
+		return details.ImdbId
+	case "title":
+		return metadata.Title
+	case "year":
+		return metadata.Year
+	case "rating":
+		return metadata.Rating()
+	case "imdb_rating":
+		return metadata.ImdbRating
+	case "tmdb_rating":
+		return metadata.TmdbRating
+	case "tmdb_id":
+		return metadata.TmdbId
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package gazelle
+
+// Gazelle JSON API. See https://github.com/OPSnet/Gazelle/wiki/JSON-API-Documentation .
+// Authenticated by cookie or the "Authorization: <apiKey>" header.
+// GET ajax.php?action=index : user info
+// GET ajax.php?action=browse&searchstr=keyword&page=1&order_by=time&order_way=desc : torrents
+// GET ajax.php?action=download&id=<torrentId> : download torrent (api key only)
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const API_URL = "ajax.php"
+
+type apiResponse struct {
+	Status   string          `json:"status"` // "success" or "failure"
+	Error    string          `json:"error"`
+	Response json.RawMessage `json:"response"`
+}
+
+type apiIndex struct {
+	Username      string `json:"username"`
+	Notifications struct {
+		Messages int64 `json:"messages"` // unread private messages
+	} `json:"notifications"`
+	Userstats struct {
+		Uploaded   int64  `json:"uploaded"`
+		Downloaded int64  `json:"downloaded"`
+		Class      string `json:"class"`
+	} `json:"userstats"`
+}
+
+// A json number or string value, e.g. ID, year. Some Gazelle forks use string for these fields.
+type anyString string
+
+func (as *anyString) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*as = anyString(str)
+		return nil
+	}
+	if string(data) == "null" {
+		*as = ""
+		return nil
+	}
+	*as = anyString(data)
+	return nil
+}
+
+func (as anyString) String() string {
+	return string(as)
+}
+
+type apiArtist struct {
+	Name string `json:"name"`
+}
+
+type apiTorrent struct {
+	TorrentId      anyString    `json:"torrentId"`
+	Artists        []*apiArtist `json:"artists"`
+	Remastered     bool         `json:"remastered"`
+	RemasterYear   anyString    `json:"remasterYear"`
+	RemasterTitle  string       `json:"remasterTitle"`
+	Media          string       `json:"media"`    // e.g. "CD", "WEB"
+	Format         string       `json:"format"`   // e.g. "FLAC", "MP3"
+	Encoding       string       `json:"encoding"` // e.g. "Lossless", "24bit Lossless", "320"
+	HasLog         bool         `json:"hasLog"`
+	LogScore       int64        `json:"logScore"`
+	HasCue         bool         `json:"hasCue"`
+	Scene          bool         `json:"scene"`
+	Time           string       `json:"time"` // e.g. "2016-05-21 21:27:46"
+	Size           int64        `json:"size"`
+	Snatches       int64        `json:"snatches"`
+	Seeders        int64        `json:"seeders"`
+	Leechers       int64        `json:"leechers"`
+	IsFreeleech    bool         `json:"isFreeleech"`
+	IsNeutralLeech bool         `json:"isNeutralLeech"`
+	IsPersonalFree bool         `json:"isPersonalFreeleech"`
+	Category       string       `json:"category"` // non-music torrent only
+}
+
+// A torrent group (album). Non-music groups have no "torrents" and are the torrent themselves.
+type apiGroup struct {
+	apiTorrent
+	GroupId     anyString     `json:"groupId"`
+	GroupName   string        `json:"groupName"`
+	Artist      string        `json:"artist"`
+	Tags        []string      `json:"tags"`
+	GroupYear   anyString     `json:"groupYear"`
+	ReleaseType string        `json:"releaseType"`
+	GroupTime   anyString     `json:"groupTime"` // unix timestamp
+	Torrents    []*apiTorrent `json:"torrents"`
+}
+
+type apiBrowse struct {
+	CurrentPage int64       `json:"currentPage"`
+	Pages       int64       `json:"pages"`
+	Results     []*apiGroup `json:"results"`
+}
+
+// Request ajax.php api with params and parse the response into v.
+func (gzsite *Site) fetchApi(params url.Values, v any) error {
+	apiUrl := gzsite.SiteConfig.ParseSiteUrl(API_URL, false) + "?" + params.Encode()
+	res, _, err := util.FetchUrlWithAzuretls(apiUrl, gzsite.HttpClient,
+		gzsite.SiteConfig.Cookie, site.GetUa(gzsite), gzsite.GetDefaultHttpHeaders())
+	if res == nil {
+		return err
+	}
+	// error response could have any http status, e.g. 401 {"status":"failure","error":"bad credentials"}
+	apiRes := &apiResponse{}
+	if json.Unmarshal(res.Body, apiRes) == nil && apiRes.Status == "failure" {
+		return fmt.Errorf("api error: %s", apiRes.Error)
+	}
+	if err != nil {
+		return err
+	}
+	if apiRes.Status != "success" {
+		return fmt.Errorf("invalid api response (cookie or apiKey may be invalid) (%w)", constants.ErrAuth)
+	}
+	if err := json.Unmarshal(apiRes.Response, v); err != nil {
+		return fmt.Errorf("failed to parse api response: %w", err)
+	}
+	return nil
+}
+
+// Browse torrents. Each torrent of groups is returned as a site torrent.
+func (gzsite *Site) browse(params url.Values) (torrents []*site.Torrent, browse *apiBrowse, err error) {
+	params.Set("action", "browse")
+	browse = &apiBrowse{}
+	if err = gzsite.fetchApi(params, browse); err != nil {
+		return nil, nil, err
+	}
+	torrents = []*site.Torrent{}
+	for _, group := range browse.Results {
+		groupTorrents := group.Torrents
+		if len(groupTorrents) == 0 && group.TorrentId != "" {
+			groupTorrents = []*apiTorrent{&group.apiTorrent}
+		}
+		for _, gt := range groupTorrents {
+			torrents = append(torrents, gzsite.convertTorrent(group, gt))
+		}
+	}
+	return torrents, browse, nil
+}
+
+// Convert api torrent of group to site torrent. Name is "Artist - Album (Year) [Media / Format / Encoding]",
+// e.g. "Pink Floyd - The Wall (1979) [CD / FLAC / Lossless]".
+func (gzsite *Site) convertTorrent(group *apiGroup, gt *apiTorrent) *site.Torrent {
+	name := html.UnescapeString(group.GroupName)
+	artist := html.UnescapeString(group.Artist)
+	if artist == "" && len(gt.Artists) > 0 {
+		artist = html.UnescapeString(gt.Artists[0].Name)
+	}
+	if artist != "" {
+		name = artist + " - " + name
+	}
+	if year := group.GroupYear.String(); year != "" && year != "0" {
+		name += " (" + year + ")"
+	}
+	formats := util.Filter([]string{gt.Media, gt.Format, gt.Encoding}, func(s string) bool { return s != "" })
+	if len(formats) > 0 {
+		name += " [" + strings.Join(formats, " / ") + "]"
+	}
+	description := []string{}
+	if gt.Remastered {
+		if year := gt.RemasterYear.String(); year != "" && year != "0" {
+			description = append(description, year)
+		}
+		if gt.RemasterTitle != "" {
+			description = append(description, html.UnescapeString(gt.RemasterTitle))
+		}
+	}
+	if gt.HasLog {
+		description = append(description, fmt.Sprintf("Log (%d%%)", gt.LogScore))
+	}
+	if gt.HasCue {
+		description = append(description, "Cue")
+	}
+	if gt.Scene {
+		description = append(description, "Scene")
+	}
+	id := gt.TorrentId.String()
+	torrent := &site.Torrent{
+		Name:               name,
+		Description:        strings.Join(description, " / "),
+		Id:                 gzsite.GetName() + "." + id,
+		DownloadUrl:        gzsite.getDownloadUrl(id),
+		Size:               gt.Size,
+		IsSizeAccurate:     true,
+		Seeders:            gt.Seeders,
+		Leechers:           gt.Leechers,
+		Snatched:           gt.Snatches,
+		DownloadMultiplier: 1,
+		UploadMultiplier:   1,
+		HasHnR:             gzsite.SiteConfig.GlobalHnR,
+	}
+	torrent.Time, _ = util.ParseTime(gt.Time, gzsite.Location)
+	if torrent.Time == 0 {
+		torrent.Time = util.ParseInt(group.GroupTime.String())
+	}
+	if gt.IsNeutralLeech {
+		torrent.DownloadMultiplier = 0
+		torrent.UploadMultiplier = 0
+		torrent.Neutral = true
+	} else if gt.IsFreeleech || gt.IsPersonalFree {
+		torrent.DownloadMultiplier = 0
+	}
+	if group.ReleaseType != "" {
+		torrent.Tags = append(torrent.Tags, group.ReleaseType)
+	} else if gt.Category != "" {
+		torrent.Tags = append(torrent.Tags, gt.Category)
+	}
+	torrent.Tags = append(torrent.Tags, group.Tags...)
+	return torrent
+}
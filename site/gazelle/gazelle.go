@@ -5,6 +5,9 @@ package gazelle
 // 种子下载链接：https://dicmusic.club/torrents.php?action=download&id=&authkey=&torrent_pass=
 // (如果cookie有效，authkey 和 torrent_pass 可省略)
 // 注意下载时的 id 与 torrent.php 页面url里的 id 不同，后者是当前音乐专辑的 id
+// Torrents are fetched from the JSON API (ajax.php), which is authenticated by cookie or apiKey.
+// If apiKey is configured (e.g. OPS, RED), it's sent as the "Authorization" header of all requests,
+// and torrents are downloaded by api (ajax.php?action=download&id=) without cookie.
 
 import (
 	"fmt"
@@ -16,6 +19,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -38,8 +42,18 @@ const (
 	SELECTOR_USERNAME        = "#nav_userinfo"
 	SELECTOR_USER_UPLOADED   = "#stats_seeding"
 	SELECTOR_USER_DOWNLOADED = "#stats_leeching"
+	SELECTOR_ALERTS          = "#alerts"
 )
 
+// ptool sort field => Gazelle browse order_by
+var sortFields = map[string]string{
+	"time":     "time",
+	"size":     "size",
+	"seeders":  "seeders",
+	"leechers": "leechers",
+	"snatched": "snatched",
+}
+
 func (gzsite *Site) GetDefaultHttpHeaders() [][]string {
 	return gzsite.HttpHeaders
 }
@@ -51,11 +65,59 @@ func (gzsite *Site) GetName() string {
 	return gzsite.Name
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (gzsite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (gzsite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (gzsite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (gzsite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (gzsite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (gzsite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (gzsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
 func (gzsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return gzsite.SiteConfig
 }
 
 func (gzsite *Site) GetStatus() (*site.Status, error) {
+	if gzsite.SiteConfig.ApiKey != "" {
+		index := &apiIndex{}
+		if err := gzsite.fetchApi(url.Values{"action": {"index"}}, index); err != nil {
+			return nil, err
+		}
+		return &site.Status{
+			UserName:       index.Username,
+			UserUploaded:   index.Userstats.Uploaded,
+			UserDownloaded: index.Userstats.Downloaded,
+			UserClass:      index.Userstats.Class,
+			UnreadMessages: index.Notifications.Messages,
+		}, nil
+	}
 	doc, _, err := util.GetUrlDocWithAzuretls(gzsite.SiteConfig.Url+"torrents.php", gzsite.HttpClient,
 		gzsite.GetSiteConfig().Cookie, site.GetUa(gzsite), gzsite.GetDefaultHttpHeaders())
 	if err != nil {
@@ -78,24 +140,58 @@ func (gzsite *Site) GetStatus() (*site.Status, error) {
 	downloadedEl := doc.Find(userDownloadedSelector)
 	userUploaded, _ := util.ExtractSizeStr(util.DomSanitizedText(uploadedEl))
 	userDownloaded, _ := util.ExtractSizeStr(util.DomSanitizedText(downloadedEl))
-	return &site.Status{
+	status := &site.Status{
 		UserName:       util.DomSanitizedText(usernameEl),
 		UserUploaded:   userUploaded,
 		UserDownloaded: userDownloaded,
-	}, nil
+	}
+	status.ParseUserInfoDom(doc.Selection, gzsite.SiteConfig)
+	// e.g. "You have 2 new messages" alert
+	status.ParseUserInfoText(doc.Find(SELECTOR_ALERTS).Text())
+	return status, nil
 }
 
+// pageMarker is the page number. baseUrl is the torrents.php (or ajax.php) url or it's query string
+// (e.g. "?filter_cat[1]=1&format=FLAC"), whose params are used as browse filters.
 func (gzsite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	if sort != "" && sort != constants.NONE && sortFields[sort] == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	params := browseParams(baseUrl)
+	if sort != "" && sort != constants.NONE {
+		params.Set("order_by", sortFields[sort])
+		if desc {
+			params.Set("order_way", "desc")
+		} else {
+			params.Set("order_way", "asc")
+		}
+	}
+	page := int64(1)
+	if pageMarker != "" && pageMarker != constants.NONE {
+		page = util.ParseInt(pageMarker)
+	}
+	params.Set("page", fmt.Sprint(page))
+	torrents, browse, err := gzsite.browse(params)
+	if err != nil {
+		return nil, "", err
+	}
+	if browse.CurrentPage < browse.Pages {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return torrents, nextPageMarker, nil
 }
 
 func (gzsite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	torrents, _, err := gzsite.browse(url.Values{"order_by": {"time"}, "order_way": {"desc"}})
+	return torrents, err
 }
 
 func (gzsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	params := browseParams(baseUrl)
+	params.Set("searchstr", keyword)
+	torrents, _, err := gzsite.browse(params)
+	return torrents, err
 }
 
 func (gzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
@@ -112,13 +208,34 @@ func (gzsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename
 }
 
 func (gzsite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
-	torrentUrl := gzsite.SiteConfig.Url + "torrents.php?action=download&id=" + id
-	return site.DownloadTorrentByUrl(gzsite, gzsite.HttpClient, torrentUrl, id)
+	return site.DownloadTorrentByUrl(gzsite, gzsite.HttpClient, gzsite.getDownloadUrl(id), id)
+}
+
+// Return the download url of torrent. Use api download url if apiKey is configured.
+func (gzsite *Site) getDownloadUrl(id string) string {
+	if gzsite.SiteConfig.ApiKey != "" {
+		return gzsite.SiteConfig.ParseSiteUrl(API_URL+"?action=download&id="+id, false)
+	}
+	return gzsite.SiteConfig.ParseSiteUrl("torrents.php?action=download&id="+id, false)
+}
+
+// Return the browse api params from the query string of baseUrl.
+func browseParams(baseUrl string) url.Values {
+	if baseUrl == "" || baseUrl == constants.NONE {
+		return url.Values{}
+	}
+	if urlObj, err := url.Parse(baseUrl); err == nil {
+		params := urlObj.Query()
+		params.Del("action")
+		params.Del("page")
+		return params
+	}
+	return url.Values{}
 }
 
 func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
-	if siteConfig.Cookie == "" {
-		log.Warnf("Site %s has no cookie provided", name)
+	if siteConfig.Cookie == "" && siteConfig.ApiKey == "" {
+		log.Warnf("Site %s has no cookie or apiKey provided", name)
 	}
 	location, err := time.LoadLocation(siteConfig.GetTimezone())
 	if err != nil {
@@ -128,6 +245,9 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 	if err != nil {
 		return nil, fmt.Errorf("failed to create site http client: %w", err)
 	}
+	if siteConfig.ApiKey != "" {
+		httpHeaders = append(httpHeaders, []string{"Authorization", siteConfig.ApiKey})
+	}
 	site := &Site{
 		Name:        name,
 		Location:    location,
@@ -45,6 +45,41 @@ func (gpwsite *Site) GetName() string {
 	return gpwsite.Name
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (gpwsite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (gpwsite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (gpwsite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (gpwsite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (gpwsite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (gpwsite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (gpwsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
 func (gpwsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return gpwsite.SiteConfig
 }
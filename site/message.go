@@ -0,0 +1,11 @@
+package site
+
+// A private message (or system notification) in the inbox of site user.
+type Message struct {
+	Id      string `json:"id"`      // site internal id of message, e.g. "123"
+	Subject string `json:"subject"` // title of message
+	Sender  string `json:"sender"`  // user name of sender. Empty if it's a system message
+	Time    int64  `json:"time"`    // unix timestamp. 0 if unknown
+	Unread  bool   `json:"unread"`
+	Content string `json:"content,omitempty"` // text contents. Only available if fetched by GetMessage
+}
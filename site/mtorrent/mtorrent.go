@@ -1,10 +1,18 @@
 package mtorrent
 
+// M-Team (馒头) new site REST API. It uses token authentication, cookie is NOT supported.
+// The api token is created in "控制台 - 實驗室 - 存取令牌" page of site, and can be configured as apiKey
+// (sent as the "x-api-key" header). Torrents are searched in the "mode" (section) of site,
+// e.g. "normal" (综合), "adult" (成人), which are configured by mtorrentModes.
+// Torrent details page url: https://kp.m-team.cc/detail/12345 .
+
 import (
 	"encoding/json"
 	"fmt"
 	"net/http"
 	neturl "net/url"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/Noooste/azuretls-client"
@@ -18,6 +26,7 @@ import (
 const (
 	APIPath_GenerateDownloadToken = "/api/torrent/genDlToken"
 	APIPath_TorrentSearch         = "/api/torrent/search"
+	APIPath_TorrentDetail         = "/api/torrent/detail"
 	APIPath_Profile               = "/api/member/profile"
 )
 
@@ -40,6 +49,24 @@ var (
 		"_2X_FREE":       2,
 		"_2X_PERCENT_50": 2,
 	}
+
+	searchModes = []string{
+		TorrentSearchMode_Normal,
+		TorrentSearchMode_Adult,
+		TorrentSearchMode_Movie,
+		TorrentSearchMode_Music,
+		TorrentSearchMode_TvShow,
+		TorrentSearchMode_Waterfall,
+	}
+
+	// legacy site torrents list pages => search mode
+	legacyPageModes = map[string]string{
+		"torrents.php": TorrentSearchMode_Normal,
+		"adult.php":    TorrentSearchMode_Adult,
+		"music.php":    TorrentSearchMode_Music,
+	}
+
+	detailUrlRegexp = regexp.MustCompile(`/detail/(?P<id>\d+)\b`)
 )
 
 var _ site.Site = (*Site)(nil)
@@ -69,7 +96,7 @@ func (m *Site) GetSiteConfig() *config.SiteConfigStruct {
 }
 
 // DownloadTorrent download torrent by url like `https://kp.m-team.cc/api/rss/dl?credential=xxx`
-// if url is id, find real url by this id then call DownloadTorrentById
+// if url is id or details page url, find real url by this id then call DownloadTorrentById
 func (m *Site) DownloadTorrent(url string) (content []byte, filename string, id string, err error) {
 	if !util.IsUrl(url) {
 		// not url, try id
@@ -77,6 +104,10 @@ func (m *Site) DownloadTorrent(url string) (content []byte, filename string, id
 		content, filename, err = m.DownloadTorrentById(id)
 		return
 	}
+	if id = parseDetailUrl(url); id != "" {
+		content, filename, err = m.DownloadTorrentById(id)
+		return
+	}
 
 	content, filename, err = site.DownloadTorrentByUrl(m, m.HttpClient, url, id)
 	return
@@ -97,10 +128,14 @@ func (m *Site) DownloadTorrentById(id string) (content []byte, filename string,
 	return
 }
 
+// If mtorrentModes is not configured, it gets torrents of "normal" mode, and also "adult" mode if full is true.
 func (m *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	modes := []string{TorrentSearchMode_Normal}
-	if full {
-		modes = append(modes, TorrentSearchMode_Adult)
+	modes := m.SiteConfig.MtorrentModes
+	if len(modes) == 0 {
+		modes = []string{TorrentSearchMode_Normal}
+		if full {
+			modes = append(modes, TorrentSearchMode_Adult)
+		}
 	}
 
 	var mergedTorrents []*site.Torrent
@@ -138,7 +173,8 @@ func (m *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl
 		return
 	}
 
-	list, err := m.search(WithSortDirection(desc),
+	list, err := m.search(WithMode(m.getMode(baseUrl)),
+		WithSortDirection(desc),
 		WithSortField(sortFields[sort]),
 		WithPageNumber(pageNumber))
 	if err != nil {
@@ -152,12 +188,57 @@ func (m *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl
 	return
 }
 
+// If keyword is a torrent details page url, it returns that torrent.
+// It searches in the mode of baseUrl, or all mtorrentModes (default "normal") if baseUrl is empty.
 func (m *Site) SearchTorrents(keyword string, baseUrl string) (torrents []*site.Torrent, err error) {
-	list, err := m.search(WithKeyword(keyword))
-	if err == nil {
-		torrents = m.convertTorrents(list)
+	if id := parseDetailUrl(keyword); id != "" {
+		torrent, err := m.GetTorrentDetail(id)
+		if err != nil {
+			return nil, err
+		}
+		return []*site.Torrent{torrent}, nil
 	}
-	return
+	modes := m.SiteConfig.MtorrentModes
+	if baseUrl != "" && baseUrl != constants.NONE || len(modes) == 0 {
+		modes = []string{m.getMode(baseUrl)}
+	}
+	for _, mode := range modes {
+		list, err := m.search(WithMode(mode), WithKeyword(keyword))
+		if err != nil {
+			return nil, err
+		}
+		torrents = append(torrents, m.convertTorrents(list)...)
+	}
+	return torrents, nil
+}
+
+// GetTorrentDetail get the torrent by id.
+func (m *Site) GetTorrentDetail(id string) (*site.Torrent, error) {
+	torrent, err := m.getTorrentDetail(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.convertTorrent(torrent), nil
+}
+
+// GetTorrentDetails implements site.Site. The detail api provides description and MediaInfo of torrent.
+func (m *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	torrent, err := m.getTorrentDetail(id)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.Join([]string{torrent.Name, torrent.Description, torrent.Descr, torrent.Imdb, torrent.Douban}, "\n")
+	return site.ParseTorrentDetails(text, torrent.MediaInfo), nil
+}
+
+func (m *Site) getTorrentDetail(id string) (*Torrent, error) {
+	q := make(neturl.Values)
+	q.Add("id", id)
+	var resp TorrentDetailResponse
+	if err := m.do(APIPath_TorrentDetail, q, nil, &resp); err != nil {
+		return nil, fmt.Errorf("%s error: %w", APIPath_TorrentDetail, err)
+	}
+	return &resp.Data, nil
 }
 
 func (m *Site) GetStatus() (*site.Status, error) {
@@ -189,12 +270,14 @@ func (m *Site) do(path string, query neturl.Values, body any, result any) error
 	}
 
 	reqHeaders := util.GetHttpReqHeaders(m.GetDefaultHttpHeaders(), m.GetSiteConfig().Cookie, site.GetUa(m))
+	done := util.LimitRequest(fullPath)
 	res, err := m.HttpClient.Do(&azuretls.Request{
 		Method:   http.MethodPost,
 		Url:      fullPath,
 		Body:     body,
 		NoCookie: true, // disable azuretls internal cookie jar
 	}, reqHeaders)
+	done()
 	if err != nil {
 		return fmt.Errorf("failed to fetch url: %w", err)
 	}
@@ -214,6 +297,36 @@ func (m *Site) do(path string, query neturl.Values, body any, result any) error
 	return nil
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (m *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (m *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (m *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (m *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (m *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (m *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
 func (m *Site) search(options ...TorrentSearchRequestOption) (list *TorrentList, err error) {
 	req := NewTorrentSearchRequest(options...)
 	var resp TorrentSearchResponse
@@ -227,33 +340,77 @@ func (m *Site) search(options ...TorrentSearchRequestOption) (list *TorrentList,
 
 func (m *Site) convertTorrents(list *TorrentList) []*site.Torrent {
 	var torrents []*site.Torrent
-	for _, torrent := range list.Data {
-		torrents = append(torrents, &site.Torrent{
-			Name:               torrent.Name,
-			Description:        torrent.Description,
-			Id:                 fmt.Sprintf("%s.%s", m.Name, torrent.Id),
-			InfoHash:           "",
-			DownloadUrl:        torrent.Id, // can't get download url in list, must call /api/torrent/genDlToken after
-			DownloadMultiplier: getMultiplier(downloadMultipliers, torrent.Status.Discount),
-			UploadMultiplier:   getMultiplier(uploadMultipliers, torrent.Status.Discount),
-			DiscountEndTime:    torrent.Status.DiscountEndTime.UnixWithDefault(-1),
-			Time:               torrent.CreateDate.Unix(),
-			Size:               torrent.Size.Value(),
-			IsSizeAccurate:     true,
-			Seeders:            torrent.Status.Seeders.Value(),
-			Leechers:           torrent.Status.Leechers.Value(),
-			Snatched:           0,
-			HasHnR:             false,
-			IsActive:           false, // TODO: maybe torrent.clientList[*].downloaded > 0?
-			Paid:               false,
-			Bought:             false,
-			Neutral:            false,
-		})
+	for i := range list.Data {
+		torrents = append(torrents, m.convertTorrent(&list.Data[i]))
 	}
 
 	return torrents
 }
 
+func (m *Site) convertTorrent(torrent *Torrent) *site.Torrent {
+	return &site.Torrent{
+		Name:               torrent.Name,
+		Description:        torrent.Description,
+		Id:                 fmt.Sprintf("%s.%s", m.Name, torrent.Id),
+		InfoHash:           "",
+		DownloadUrl:        torrent.Id, // can't get download url in list, must call /api/torrent/genDlToken after
+		DownloadMultiplier: getMultiplier(downloadMultipliers, torrent.Status.Discount),
+		UploadMultiplier:   getMultiplier(uploadMultipliers, torrent.Status.Discount),
+		DiscountEndTime:    torrent.Status.DiscountEndTime.UnixWithDefault(-1),
+		Time:               torrent.CreateDate.UnixWithDefault(0),
+		Size:               torrent.Size.Value(),
+		IsSizeAccurate:     true,
+		Seeders:            torrent.Status.Seeders.Value(),
+		Leechers:           torrent.Status.Leechers.Value(),
+		Snatched:           torrent.Status.TimesCompleted.Value(),
+		HasHnR:             false,
+		IsActive:           false, // TODO: maybe torrent.clientList[*].downloaded > 0?
+		Paid:               false,
+		Bought:             false,
+		Neutral:            false,
+		Tags:               torrent.Labels,
+	}
+}
+
+// Return the search mode of baseUrl, which could be a mode (e.g. "adult"), legacy site page (e.g. "adult.php"),
+// or query string (e.g. "?mode=adult"). If baseUrl is empty, return the first of mtorrentModes or "normal".
+func (m *Site) getMode(baseUrl string) string {
+	if baseUrl != "" && baseUrl != constants.NONE {
+		if slices.Contains(searchModes, baseUrl) {
+			return baseUrl
+		}
+		if urlObj, err := neturl.Parse(baseUrl); err == nil {
+			if mode := urlObj.Query().Get("mode"); mode != "" {
+				return mode
+			}
+			if mode := legacyPageModes[urlObj.Path[strings.LastIndex(urlObj.Path, "/")+1:]]; mode != "" {
+				return mode
+			}
+		}
+	}
+	if len(m.SiteConfig.MtorrentModes) > 0 {
+		return m.SiteConfig.MtorrentModes[0]
+	}
+	return TorrentSearchMode_Normal
+}
+
+// Return the torrent id of details page url, e.g. "https://kp.m-team.cc/detail/12345",
+// or legacy site details / download page url, e.g. "https://kp.m-team.cc/details.php?id=12345".
+// Return empty string if it's not a details page url.
+func parseDetailUrl(url string) string {
+	if !util.IsUrl(url) {
+		return ""
+	}
+	if m := detailUrlRegexp.FindStringSubmatch(url); m != nil {
+		return m[detailUrlRegexp.SubexpIndex("id")]
+	}
+	if urlObj, err := neturl.Parse(url); err == nil &&
+		(strings.HasSuffix(urlObj.Path, "/details.php") || strings.HasSuffix(urlObj.Path, "/download.php")) {
+		return urlObj.Query().Get("id")
+	}
+	return ""
+}
+
 func getMultiplier(config map[string]float64, discount string) float64 {
 	if v, ok := config[discount]; ok {
 		return v
@@ -263,10 +420,22 @@ func getMultiplier(config map[string]float64, discount string) float64 {
 }
 
 func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
+	for _, mode := range siteConfig.MtorrentModes {
+		if !slices.Contains(searchModes, mode) {
+			return nil, fmt.Errorf("invalid mtorrentModes mode %q. Valid modes: %s", mode, strings.Join(searchModes, ", "))
+		}
+	}
 	httpClient, httpHeaders, err := site.CreateSiteHttpClient(siteConfig, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create site http client: %w", err)
 	}
+	if siteConfig.ApiKey != "" {
+		httpHeaders = append(httpHeaders, []string{"x-api-key", siteConfig.ApiKey})
+	} else if !slices.ContainsFunc(httpHeaders, func(header []string) bool {
+		return len(header) > 0 && (strings.EqualFold(header[0], "x-api-key") || strings.EqualFold(header[0], "Authorization"))
+	}) {
+		log.Warnf("Site %s has no apiKey (api token) provided", name)
+	}
 	s := &Site{
 		Name:        name,
 		SiteConfig:  siteConfig,
@@ -3,8 +3,12 @@ package mtorrent
 import "fmt"
 
 const (
-	TorrentSearchMode_Normal = "normal"
-	TorrentSearchMode_Adult  = "adult"
+	TorrentSearchMode_Normal    = "normal"
+	TorrentSearchMode_Adult     = "adult"
+	TorrentSearchMode_Movie     = "movie"
+	TorrentSearchMode_Music     = "music"
+	TorrentSearchMode_TvShow    = "tvshow"
+	TorrentSearchMode_Waterfall = "waterfall"
 
 	TorrentSearchDirection_Asc  = "ASC"
 	TorrentSearchDirection_Desc = "DESC"
@@ -92,6 +96,7 @@ type TorrentStatus struct {
 	DiscountEndTime *Time  `json:"discountEndTime"`
 	Leechers        Int64  `json:"leechers"`
 	Seeders         Int64  `json:"seeders"`
+	TimesCompleted  Int64  `json:"timesCompleted"`
 	Status          string `json:"status"`
 }
 
@@ -103,7 +108,18 @@ type Torrent struct {
 	Description      string        `json:"smallDescr"`
 	Category         string        `json:"category"`
 	Size             Int64         `json:"size"`
+	Labels           []string      `json:"labelsNew"` // e.g. "中字", "4k"
 	Status           TorrentStatus `json:"status"`
+	// Only in detail api
+	Descr     string `json:"descr"`     // description, bbcode
+	MediaInfo string `json:"mediainfo"` // MediaInfo report
+	Imdb      string `json:"imdb"`      // IMDb url
+	Douban    string `json:"douban"`    // Douban url
+}
+
+type TorrentDetailResponse struct {
+	ResponseCode
+	Data Torrent `json:"data"`
 }
 
 type TorrentList struct {
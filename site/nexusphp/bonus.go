@@ -0,0 +1,97 @@
+package nexusphp
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// NexusPHP bonus points exchange page.
+// See https://github.com/xiaomlove/nexusphp/blob/php8/public/mybonus.php .
+// Each exchange option is a table row with a form: option id (the hidden "option" input), name & description,
+// price, and the submit button. Exchange: POST mybonus.php?action=exchange with option=<id>.
+// On success, it redirects to mybonus.php?do=<type>.
+const (
+	BONUS_URL          = "mybonus.php"
+	BONUS_EXCHANGE_URL = "mybonus.php?action=exchange"
+)
+
+var (
+	// E.g. "用你的魔力值（当前1,234.5）换东西！", "Karma Points (current 1,234.5)", "魔力值 [使用]: 1,234.5".
+	bonusBalanceRegexp = regexp.MustCompile(`(?i)(魔力值|魔力|karma points|bonus)[^\d]{0,12}?(\d+(,\d{3})*(\.\d+)?)`)
+	bonusErrorRegexp   = regexp.MustCompile(`(?i)(错误|錯誤|失败|失敗|不足|不够|不夠|error|failed)`)
+)
+
+func (npclient *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	doc, res, err := util.GetUrlDocWithAzuretls(npclient.SiteConfig.ParseSiteUrl(BONUS_URL, false),
+		npclient.HttpClient, npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get bonus page: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return 0, nil, site.ErrNotLogined
+	}
+	return parseBonusPage(doc)
+}
+
+func (npclient *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	exchangeUrl := npclient.SiteConfig.ParseSiteUrl(BONUS_EXCHANGE_URL, false)
+	res, err := util.PostUrlFormWithAzuretls(exchangeUrl, option.Form, npclient.HttpClient,
+		npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return fmt.Errorf("failed to exchange bonus: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return site.ErrNotLogined
+	}
+	if urlObj, err := url.Parse(res.Request.Url); err == nil && urlObj.Query().Get("do") != "" {
+		return nil
+	}
+	// not redirected. the page is likely an error message. e.g. "你的魔力值不够"
+	if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(res.Body)); err == nil {
+		if msg := strings.TrimSpace(doc.Find("h2").First().Text()); bonusErrorRegexp.MatchString(msg) {
+			return fmt.Errorf("site error: %s %s", msg, util.DomSanitizedText(doc.Find("td.text").First()))
+		}
+	}
+	return fmt.Errorf("unknown exchange result: site does not redirect to the success page (%s)", res.Request.Url)
+}
+
+func parseBonusPage(doc *goquery.Document) (bonus float64, options []*site.BonusExchangeOption, err error) {
+	if m := bonusBalanceRegexp.FindStringSubmatch(util.DomSanitizedText(doc.Find("body"))); m != nil {
+		bonus = site.ParseBonusCost(m[2])
+	}
+	// The form element can not be parsed as the parent of inputs in table row, so the row is used instead.
+	doc.Find(`tr:has(input[name="option"])`).Each(func(i int, s *goquery.Selection) {
+		form := url.Values{}
+		s.Find(`input[type="hidden"],input[type="submit"]`).Each(func(i int, input *goquery.Selection) {
+			if name := input.AttrOr("name", ""); name != "" {
+				form.Set(name, input.AttrOr("value", ""))
+			}
+		})
+		tds := s.ChildrenFiltered("td")
+		name := strings.TrimSpace(s.Find("h1").First().Text())
+		if name == "" {
+			name = util.DomSanitizedText(tds.Eq(1))
+		}
+		exchangeType, value := site.ParseBonusExchangeOptionName(name)
+		options = append(options, &site.BonusExchangeOption{
+			Id:    form.Get("option"),
+			Name:  name,
+			Type:  exchangeType,
+			Value: value,
+			Price: site.ParseBonusCost(util.DomSanitizedText(tds.Eq(2))),
+			Form:  form,
+		})
+	})
+	if len(options) == 0 {
+		return bonus, nil, fmt.Errorf("no exchange options found in bonus page")
+	}
+	return bonus, options, nil
+}
@@ -0,0 +1,168 @@
+package nexusphp
+
+// Detection of the columns (fields) layout of NexusPHP torrents list.
+// Sites of different NexusPHP versions and skins (including customized ones) display the columns in
+// different orders, sometimes with extra columns (e.g. comments, uploader), and label the header cells
+// with texts in different languages, images or sort links. The layout is detected in this order:
+// 1. The "torrentsListColumns" of site config, if set, is used as is.
+// 2. The header cells: label texts, image alt / title, sort link.
+// 3. For size & time fields not found in header, the cells of the first torrent whose content looks like them.
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Fields of torrents list columns.
+var columnFields = []string{"category", "name", "time", "size", "seeders", "leechers", "snatched", "process"}
+
+// Header label texts of fields, in lower case.
+var columnHeaderTexts = map[string][]string{
+	"category": {"类型", "類型", "分类", "分類", "type", "category"},
+	"process":  {"进度", "進度", "progress"},
+	"name":     {"标题", "標題", "名称", "名稱", "name", "title"},
+	"size":     {"大小", "size"},
+	"time":     {"时间", "時間", "存活", "发布时间", "發佈時間", "添加时间", "time", "added", "age"},
+	"seeders":  {"上传", "上傳", "种子", "種子", "做种", "做種", "做种数", "seeders", "seeds"},
+	"leechers": {"下载", "下載", "下载数", "leechers"},
+	"snatched": {"完成", "完成数", "snatched", "completed"},
+}
+
+// Times before this (2000-01-01) are not considered as torrent time,
+// e.g. a number of seeders would be parsed as unix timestamp.
+const MIN_TORRENT_TIME = 946684800
+
+var columnSizeRegexp = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*[KMGTPE]i?B$`)
+
+// Return true if field is a valid torrents list column field.
+func IsValidColumnField(field string) bool {
+	return slices.Contains(columnFields, field)
+}
+
+// Detect columns layout of torrents list. Return field => column index (-1 if not found).
+func detectColumns(containerEl *goquery.Selection, torrentBlocks *goquery.Selection,
+	option *TorrentsParserOption) (fieldColumIndex map[string]int, err error) {
+	fieldColumIndex = map[string]int{}
+	for _, field := range columnFields {
+		fieldColumIndex[field] = -1
+	}
+	if len(option.columns) > 0 {
+		for i, field := range option.columns {
+			if _, ok := fieldColumIndex[field]; ok {
+				fieldColumIndex[field] = i
+			}
+		}
+		log.Tracef("np parse fieldColumIndex (config): %v", fieldColumIndex)
+		return fieldColumIndex, nil
+	}
+
+	var headerEl *goquery.Selection
+	if option.selectorTorrentsListHeader != "" {
+		headerEl = containerEl.Find(option.selectorTorrentsListHeader).First()
+	} else {
+		headerEl = containerEl.Children().First()
+		if headerEl.Find(option.selectorTorrent).Length() > 0 {
+			// it's not header
+			el := containerEl
+			for el.Parent().Length() > 0 && el.Prev().Length() == 0 {
+				el = el.Parent()
+			}
+			headerEl = el.Prev()
+		}
+		if headerEl.Length() > 0 {
+			log.Tracef("nptr: header node=%v, id=%v, class=%v\n",
+				headerEl.Get(0),
+				headerEl.AttrOr("id", ""),
+				headerEl.AttrOr("class", ""),
+			)
+		}
+	}
+	for headerEl.Length() > 0 && headerEl.Children().Length() == 1 {
+		headerEl = headerEl.Children()
+	}
+	headerEl.Children().Each(func(i int, s *goquery.Selection) {
+		text := strings.ToLower(util.DomSanitizedText(s))
+		for field, texts := range columnHeaderTexts {
+			if slices.Contains(texts, text) {
+				fieldColumIndex[field] = i
+				return
+			}
+		}
+		iconTitle := strings.ToLower(s.Find(`img[title]`).First().AttrOr("title", ""))
+		for _, field := range columnFields {
+			if s.Find(`img[alt="`+field+`"],`+
+				`img[alt="`+strings.ToUpper(field)+`"],`+
+				`img[alt="`+util.Capitalize(field)+`"]`).Length() > 0 {
+				fieldColumIndex[field] = i
+				break
+			}
+			if iconTitle != "" && slices.Contains(columnHeaderTexts[field], iconTitle) {
+				fieldColumIndex[field] = i
+				break
+			}
+			if sortFields[field] != "" && s.Find(`a[href*="?sort=`+sortFields[field]+`&"],`+
+				`a[href*="&sort=`+sortFields[field]+`&"]`).Length() == 1 {
+				fieldColumIndex[field] = i
+				break
+			}
+		}
+	})
+
+	if fieldColumIndex["size"] == -1 || fieldColumIndex["time"] == -1 {
+		detectColumnsByContent(torrentBlocks, option, fieldColumIndex)
+	}
+	log.Tracef("np parse fieldColumIndex: %v", fieldColumIndex)
+	if headerEl.Length() == 0 && fieldColumIndex["size"] == -1 && fieldColumIndex["time"] == -1 {
+		return nil, fmt.Errorf("cann't find headerEl")
+	}
+	return fieldColumIndex, nil
+}
+
+// Find the size & time columns from the cells of first torrent, if they are not found yet.
+func detectColumnsByContent(torrentBlocks *goquery.Selection, option *TorrentsParserOption,
+	fieldColumIndex map[string]int) {
+	torrentEl := torrentBlocks.FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Find(option.selectorTorrent).Length() > 0
+	}).First()
+	usedColumns := map[int]bool{}
+	for _, index := range fieldColumIndex {
+		usedColumns[index] = true
+	}
+	torrentEl.Children().Each(func(i int, s *goquery.Selection) {
+		if usedColumns[i] || s.Find(option.selectorTorrent).Length() > 0 {
+			return
+		}
+		if fieldColumIndex["size"] == -1 && columnSizeRegexp.MatchString(util.DomSanitizedText(s)) {
+			fieldColumIndex["size"] = i
+		} else if fieldColumIndex["time"] == -1 && parseColumnTime(s, option) > MIN_TORRENT_TIME {
+			fieldColumIndex["time"] = i
+		}
+	})
+}
+
+// Parse time of torrents list time column cell, using the torrentTimeFormat of site config if it's set.
+func parseColumnTime(s *goquery.Selection, option *TorrentsParserOption) int64 {
+	if option.timeFormat == "" {
+		return util.DomTime(s, option.location)
+	}
+	return domTimeWithLayout(s, option.timeFormat, option.location)
+}
+
+// Parse the time of element (its text, or title attribute of it or its descendant) using Go time layout.
+func domTimeWithLayout(s *goquery.Selection, layout string, location *time.Location) int64 {
+	for _, str := range []string{util.DomSanitizedText(s), s.AttrOr("title", ""),
+		s.Find("*[title]").AttrOr("title", "")} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(str), location); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package nexusphp
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// NexusPHP login. See https://github.com/xiaomlove/nexusphp/blob/php8/public/login.php .
+// The login form of login.php is posted to takelogin.php with username, password and
+// two_step_code (the TOTP code, if 2FA is enabled). On success, it sets the c_secure_* cookies
+// and redirects to index.php; otherwise it displays an error page.
+// Sites that require captcha (imagestring) to login are not supported.
+const (
+	LOGIN_URL = "login.php"
+	// the input of 2FA code in login form
+	DEFAULT_TWO_STEP_FIELD = "two_step_code"
+)
+
+func (npclient *Site) Login() (cookie string, err error) {
+	siteConfig := npclient.SiteConfig
+	if siteConfig.Username == "" || siteConfig.Password == "" {
+		return "", fmt.Errorf("username or password is not configured")
+	}
+	loginUrl := siteConfig.ParseSiteUrl(LOGIN_URL, false)
+	res, _, err := util.FetchUrlWithAzuretls(loginUrl, npclient.HttpClient, "", site.GetUa(npclient),
+		npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return "", fmt.Errorf("failed to get login page: %w", err)
+	}
+	for name, value := range res.Cookies {
+		cookie = util.MergeCookie(cookie, [][]string{{name, value}})
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(res.Body))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse login page: %w", err)
+	}
+	form := doc.Find(`form[action*="takelogin"]`).First()
+	if form.Length() == 0 {
+		return "", fmt.Errorf("login form not found in login page")
+	}
+	if form.Find(`input[name="imagestring"]`).Length() > 0 {
+		return "", fmt.Errorf("site requires captcha to login, which is not supported. Use cookie instead")
+	}
+	data := url.Values{}
+	twoStepField := DEFAULT_TWO_STEP_FIELD
+	form.Find("input[name]").Each(func(i int, input *goquery.Selection) {
+		name := input.AttrOr("name", "")
+		switch input.AttrOr("type", "text") {
+		case "hidden":
+			data.Set(name, input.AttrOr("value", ""))
+		case "checkbox", "radio":
+			if _, checked := input.Attr("checked"); checked {
+				data.Set(name, input.AttrOr("value", "on"))
+			}
+		case "text", "number":
+			if strings.Contains(name, "two_step") || strings.Contains(name, "otp") {
+				twoStepField = name
+			}
+		}
+	})
+	data.Set("username", siteConfig.Username)
+	data.Set("password", siteConfig.Password)
+	if siteConfig.TotpSecret != "" {
+		code, err := util.GenerateCurrentTotp(siteConfig.TotpSecret)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate 2FA code: %w", err)
+		}
+		data.Set(twoStepField, code)
+	}
+	actionUrl := siteConfig.ParseSiteUrl(form.AttrOr("action", "takelogin.php"), false)
+	cookie, res, err = util.PostUrlFormForCookieWithAzuretls(actionUrl, data, npclient.HttpClient,
+		cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return "", fmt.Errorf("failed to login: %w", err)
+	}
+	location := res.Header.Get("Location")
+	if res.StatusCode >= 300 && res.StatusCode < 400 && !strings.Contains(location, "login.php") &&
+		strings.Contains(cookie, "c_secure_pass=") {
+		return cookie, nil
+	}
+	// not redirected. the page is likely an error message. e.g. "登录失败！ 用户名或密码不正确！"
+	msg := ""
+	if doc, err := goquery.NewDocumentFromReader(bytes.NewReader(res.Body)); err == nil {
+		msg = strings.TrimSpace(doc.Find("h2").First().Text() + " " + util.DomSanitizedText(doc.Find("td.text").First()))
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("status=%d, location=%s", res.StatusCode, location)
+	}
+	return "", fmt.Errorf("failed to login: %s (%w)", msg, constants.ErrAuth)
+}
@@ -0,0 +1,103 @@
+package nexusphp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// NexusPHP private messages. See https://github.com/xiaomlove/nexusphp/blob/php8/public/messages.php .
+// The inbox (messages.php?action=viewmailbox) lists the messages in a table, each row has the unread icon
+// (img.unreadpm), subject link (messages.php?action=viewmessage&id=<id>), sender (userdetails link,
+// or none for system messages), time and checkbox (messages[]). Viewing a message marks it as read.
+// Mark read: POST messages.php with action=moveordel, markread=<any> and messages[]=<id>... .
+const (
+	MESSAGES_URL         = "messages.php"
+	MESSAGES_INBOX_URL   = "messages.php?action=viewmailbox&box=1"
+	MESSAGE_URL          = "messages.php?action=viewmessage&id="
+	SELECTOR_MESSAGE     = `a[href*="action=viewmessage"]`
+	SELECTOR_UNREAD_ICON = `img.unreadpm,img[alt="Unread"]`
+	SELECTOR_SENDER      = `a[href*="userdetails.php"]`
+	SELECTOR_MESSAGE_BOX = `td.text`
+)
+
+func (npclient *Site) GetMessages(all bool) ([]*site.Message, error) {
+	doc, err := npclient.getMessagesDoc(MESSAGES_INBOX_URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbox page: %w", err)
+	}
+	messages := []*site.Message{}
+	doc.Find(SELECTOR_MESSAGE).Each(func(i int, link *goquery.Selection) {
+		id := parseTorrentIdFromUrl(link.AttrOr("href", ""), nil)
+		row := link.Closest("tr")
+		if id == "" || row.Length() == 0 {
+			return
+		}
+		message := &site.Message{
+			Id:      id,
+			Subject: util.DomSanitizedText(link),
+			Sender:  util.DomSanitizedText(row.Find(SELECTOR_SENDER)),
+			Time:    util.DomTime(row.Find("span[title]"), npclient.Location),
+			Unread:  row.Find(SELECTOR_UNREAD_ICON).Length() > 0,
+		}
+		if all || message.Unread {
+			messages = append(messages, message)
+		}
+	})
+	return messages, nil
+}
+
+func (npclient *Site) GetMessage(id string) (*site.Message, error) {
+	doc, err := npclient.getMessagesDoc(MESSAGE_URL + url.QueryEscape(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message page: %w", err)
+	}
+	box := doc.Find(SELECTOR_MESSAGE_BOX).First()
+	if box.Length() == 0 {
+		return nil, fmt.Errorf("message %s not found", id)
+	}
+	return &site.Message{
+		Id:      id,
+		Subject: util.DomSanitizedText(doc.Find("h1")),
+		Sender:  util.DomSanitizedText(box.Find(SELECTOR_SENDER)),
+		Time:    util.DomTime(box.Find("span[title]"), npclient.Location),
+		Content: util.DomMultilineText(box),
+	}, nil
+}
+
+func (npclient *Site) MarkMessagesRead(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	data := url.Values{
+		"action":     {"moveordel"},
+		"markread":   {"1"},
+		"messages[]": ids,
+	}
+	res, err := util.PostUrlFormWithAzuretls(npclient.SiteConfig.ParseSiteUrl(MESSAGES_URL, false), data,
+		npclient.HttpClient, npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return site.ErrNotLogined
+	}
+	return nil
+}
+
+func (npclient *Site) getMessagesDoc(pageUrl string) (*goquery.Document, error) {
+	doc, res, err := util.GetUrlDocWithAzuretls(npclient.SiteConfig.ParseSiteUrl(pageUrl, false),
+		npclient.HttpClient, npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return nil, site.ErrNotLogined
+	}
+	return doc, nil
+}
@@ -33,6 +33,8 @@ type Site struct {
 	digitHashPasskey     string
 	digitHashErr         error
 	torrentsParserOption *TorrentsParserOption
+	hnrUrl               string // the H&R list page url of user info bar
+	hnrDeadlineFetched   bool
 }
 
 // Nexusphp default upload torrent form data:
@@ -158,7 +160,7 @@ func (npclient *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.To
 		return nil, fmt.Errorf("failed to parse site page dom: %w", err)
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return nil, fmt.Errorf("not logined (cookie may has expired)")
+		return nil, site.ErrNotLogined
 	}
 	return npclient.parseTorrentsFromDoc(doc, util.Now())
 }
@@ -257,11 +259,40 @@ func (npclient *Site) getDigithash(id string) (string, error) {
 	return passkey, nil
 }
 
+// Parse torrent details from details page (/details.php?id=12345): title, description (#kdescr),
+// MediaInfo (the separate MediaInfo row of newer NexusPHP versions) and IMDb / Douban links.
+func (npclient *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	detailsUrl := npclient.SiteConfig.ParseSiteUrl("details.php?id="+url.QueryEscape(id), false)
+	doc, res, err := util.GetUrlDocWithAzuretls(detailsUrl, npclient.HttpClient,
+		npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrent details page: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login.php") {
+		return nil, site.ErrNotLogined
+	}
+	descriptionEl := doc.Find(SELECTOR_DETAILS_DESCRIPTION)
+	if descriptionEl.Length() == 0 {
+		return nil, fmt.Errorf("no torrent description found in details page")
+	}
+	return site.ParseTorrentDetailsDom(doc.Find(SELECTOR_DETAILS_TITLE).AddSelection(descriptionEl).
+		AddSelection(doc.Find(SELECTOR_DETAILS_EXTERNAL_LINKS)), doc.Find(SELECTOR_DETAILS_MEDIAINFO)), nil
+}
+
 func (npclient *Site) GetStatus() (*site.Status, error) {
 	err := npclient.sync()
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch site data: %w", err)
 	}
+	// only fetched when status is requested, as it requires an extra request
+	if !npclient.hnrDeadlineFetched && npclient.hnrUrl != "" && npclient.siteStatus.HnRs > 0 {
+		npclient.hnrDeadlineFetched = true
+		deadline, err := npclient.getHnRDeadline(npclient.hnrUrl)
+		if err != nil {
+			log.Debugf("Failed to get site %s H&R deadline: %v", npclient.Name, err)
+		}
+		npclient.siteStatus.HnRDeadline = deadline
+	}
 	return npclient.siteStatus, nil
 }
 
@@ -344,7 +375,7 @@ func (npclient *Site) GetAllTorrents(sort string, desc bool, pageMarker string,
 		return
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return nil, "", fmt.Errorf("not logined (cookie may has expired)")
+		return nil, "", site.ErrNotLogined
 	}
 
 	lastPage := int64(0)
@@ -373,7 +404,7 @@ labelLastPage:
 			return
 		}
 		if strings.Contains(res.Request.Url, "/login.php") {
-			err = fmt.Errorf("not logined (cookie may has expired)")
+			err = site.ErrNotLogined
 			return
 		}
 	}
@@ -429,7 +460,11 @@ func (npclient *Site) sync() error {
 		return fmt.Errorf("failed to get site page dom: %w", err)
 	}
 	if strings.Contains(res.Request.Url, "/login.php") {
-		return fmt.Errorf("not logined (cookie may has expired)")
+		// some sites display the reason in login page, e.g. "该账号已被禁用"
+		if site.IsBannedText(doc.Text()) {
+			return fmt.Errorf("not logined: %w", site.ErrBanned)
+		}
+		return site.ErrNotLogined
 	}
 	html := doc.Find("html")
 	npclient.datatime = util.Now()
@@ -485,6 +520,7 @@ func (npclient *Site) sync() error {
 		siteStatus.UserName = doc.Find(`*[href*="userdetails.php?"]`).First().Text()
 	}
 	siteStatus.UserName = strings.TrimSpace(siteStatus.UserName)
+	npclient.parseUserInfo(siteStatus, doc, infoTr, infoTxt)
 
 	// possibly parsing error or some problem
 	if !siteStatus.IsOk() {
@@ -516,7 +552,7 @@ func (npclient *Site) syncExtra() error {
 			continue
 		}
 		if strings.Contains(res.Request.Url, "/login.php") {
-			return fmt.Errorf("not logined (cookie may has expired)")
+			return site.ErrNotLogined
 		}
 		torrents, err := npclient.parseTorrentsFromDoc(doc, util.Now())
 		if err != nil {
@@ -538,6 +574,12 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 	if err != nil {
 		return nil, fmt.Errorf("invalid site timezone %s: %w", siteConfig.GetTimezone(), err)
 	}
+	for _, field := range siteConfig.TorrentsListColumns {
+		if field != "" && field != "-" && !IsValidColumnField(field) {
+			return nil, fmt.Errorf("invalid torrentsListColumns field %q. Valid fields: %s",
+				field, strings.Join(columnFields, ", "))
+		}
+	}
 	httpClient, httpHeaders, err := site.CreateSiteHttpClient(siteConfig, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create site http client: %w", err)
@@ -555,6 +597,8 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 			globalHr:                       siteConfig.GlobalHnR,
 			npletdown:                      !siteConfig.NexusphpNoLetDown,
 			torrentDownloadUrl:             siteConfig.TorrentDownloadUrl,
+			columns:                        siteConfig.TorrentsListColumns,
+			timeFormat:                     siteConfig.TorrentTimeFormat,
 			selectorTorrentsListHeader:     siteConfig.SelectorTorrentsListHeader,
 			selectorTorrentsList:           siteConfig.SelectorTorrentsList,
 			selectorTorrentBlock:           siteConfig.SelectorTorrentBlock,
@@ -573,6 +617,7 @@ func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.Co
 			selectorTorrentNeutral:         siteConfig.SelectorTorrentNeutral,
 			selectorTorrentNoTraffic:       siteConfig.SelectorTorrentNoTraffic,
 			selectorTorrentPaid:            siteConfig.SelectorTorrentPaid,
+			selectorTorrentBonusCost:       siteConfig.SelectorTorrentBonusCost,
 			selectorTorrentDiscountEndTime: siteConfig.SelectorTorrentDiscountEndTime,
 		},
 	}
@@ -21,6 +21,11 @@ const (
 	SELECTOR_DOWNLOAD_LINK         = `a[href^="download.php?"],a[href^="download?"]`
 	SELECTOR_DETAILS_LINK          = `a[href^="details.php?"],a[href^="details_"]`
 	SELECTOR_TORRENTS_LIST_DEFAULT = `table.torrents > tbody`
+	// torrent details page elements
+	SELECTOR_DETAILS_TITLE          = `h1#top`
+	SELECTOR_DETAILS_DESCRIPTION    = `#kdescr`
+	SELECTOR_DETAILS_MEDIAINFO      = `.nexus-media-info-raw,#kmediainfo`
+	SELECTOR_DETAILS_EXTERNAL_LINKS = `td.rowfollow a[href*="imdb.com/title/"],td.rowfollow a[href*="douban.com/"]`
 	// xiaomlove/nexusphp paid torrent feature.
 	// see https://github.com/xiaomlove/nexusphp/blob/php8/app/Repositories/TorrentRepository.php .
 	// function getPaidIcon.
@@ -29,6 +34,9 @@ const (
 	LETDOWN_QUERYSTRING = "letdown=1"
 )
 
+// Price of paid torrent, in the title of paid icon or the torrent row. E.g. "价格: 1,000".
+var bonusCostRegexp = regexp.MustCompile(`(?i)(价格|價格|售价|售價|price)\s*[:：]?\s*(\d+(,\d{3})*(\.\d+)?)`)
+
 type TorrentsParserOption struct {
 	location                       *time.Location
 	idRegexp                       *regexp.Regexp
@@ -54,7 +62,10 @@ type TorrentsParserOption struct {
 	selectorTorrentNoTraffic       string
 	selectorTorrentNeutral         string
 	selectorTorrentPaid            string
+	selectorTorrentBonusCost       string
 	selectorTorrentDiscountEndTime string
+	columns                        []string // fields of torrents list columns
+	timeFormat                     string   // Go time layout of torrent time
 }
 
 func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
@@ -186,91 +197,16 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 		containerEl.AttrOr("class", ""),
 	)
 
-	fieldColumIndex := map[string]int{
-		"category": -1,
-		"time":     -1,
-		"size":     -1,
-		"seeders":  -1,
-		"leechers": -1,
-		"snatched": -1,
-		"name":     -1,
-		"process":  -1,
-	}
-	var headerEl *goquery.Selection
-	if option.selectorTorrentsListHeader != "" {
-		headerEl = containerEl.Find(option.selectorTorrentsListHeader).First()
-	} else {
-		headerEl = containerEl.Children().First()
-		if headerEl.Find(option.selectorTorrent).Length() > 0 {
-			// it's not header
-			el := containerEl
-			for el.Parent().Length() > 0 && el.Prev().Length() == 0 {
-				el = el.Parent()
-			}
-			headerEl = el.Prev()
-		}
-		if headerEl.Length() == 0 {
-			err = fmt.Errorf("cann't find headerEl")
-			return
-		}
-		log.Tracef("nptr: header node=%v, id=%v, class=%v\n",
-			headerEl.Get(0),
-			headerEl.AttrOr("id", ""),
-			headerEl.AttrOr("class", ""),
-		)
-	}
-	for headerEl != nil && headerEl.Children().Length() == 1 {
-		headerEl = headerEl.Children()
-	}
-	if headerEl != nil {
-		headerEl.Children().Each(func(i int, s *goquery.Selection) {
-			text := util.DomSanitizedText(s)
-			if text == "类型" || text == "類型" || text == "分類" || text == "分类" {
-				fieldColumIndex["category"] = i
-				return
-			} else if text == "進度" || text == "进度" {
-				fieldColumIndex["process"] = i
-				return
-			} else if text == "標題" || text == "标题" || text == "Name" {
-				fieldColumIndex["name"] = i
-				return
-			} else if text == "大小" {
-				fieldColumIndex["size"] = i
-				return
-			} else if text == "时间" || text == "存活" {
-				fieldColumIndex["time"] = i
-				return
-			} else if text == "上传" || text == "种子" {
-				fieldColumIndex["seeders"] = i
-				return
-			} else if text == "下载" {
-				fieldColumIndex["leechers"] = i
-				return
-			} else if text == "完成" {
-				fieldColumIndex["snatched"] = i
-				return
-			}
-			for field := range fieldColumIndex {
-				if s.Find(`img[alt="`+field+`"],`+
-					`img[alt="`+strings.ToUpper(field)+`"],`+
-					`img[alt="`+util.Capitalize(field)+`"]`).Length() > 0 {
-					fieldColumIndex[field] = i
-					break
-				}
-				if sortFields[field] != "" && s.Find(`a[href*="?sort=`+sortFields[field]+`&"],a[href*="&sort=`+sortFields[field]+`&"]`).Length() == 1 {
-					fieldColumIndex[field] = i
-					break
-				}
-			}
-		})
-	}
-	log.Tracef("np parse fieldColumIndex: %v", fieldColumIndex)
 	var torrentBlocks *goquery.Selection
 	if option.selectorTorrentBlock != "" {
 		torrentBlocks = containerEl.Find(option.selectorTorrentBlock)
 	} else {
 		torrentBlocks = containerEl.Children()
 	}
+	fieldColumIndex, err := detectColumns(containerEl, torrentBlocks, option)
+	if err != nil {
+		return
+	}
 	torrentBlocks.Each(func(i int, s *goquery.Selection) {
 		if s.Find(option.selectorTorrent).Length() == 0 {
 			return
@@ -292,6 +228,7 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 		isActive := false
 		isCurrentActive := false
 		paid := false
+		bonusCost := 0.0
 		neutral := false
 		processValueRegexp := regexp.MustCompile(`\d+(\.\d+)?%`)
 		text := util.DomSanitizedText(s)
@@ -335,7 +272,7 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 				case "snatched":
 					snatched = parseCountString(text)
 				case "time":
-					time = util.DomTime(s, option.location)
+					time = parseColumnTime(s, option)
 				case "name":
 					titleEl = s.Find(option.selectorTorrentDetailsLink)
 				}
@@ -411,7 +348,9 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 			downloadUrl = option.siteurl + generateTorrentDownloadUrl(id, option.torrentDownloadUrl, option.npletdown)
 		}
 		if fieldColumIndex["time"] == -1 || time == 0 {
-			if option.selectorTorrentTime != "" {
+			if option.selectorTorrentTime != "" && option.timeFormat != "" {
+				time = domTimeWithLayout(s.Find(option.selectorTorrentTime).First(), option.timeFormat, option.location)
+			} else if option.selectorTorrentTime != "" {
 				time, _ = util.ExtractTime(util.DomSelectorText(s, option.selectorTorrentTime), option.location)
 			} else {
 				time, _ = util.ExtractTime(text, option.location)
@@ -435,7 +374,12 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 		} else if option.selectorTorrentHnR != "" && s.Find(option.selectorTorrentHnR).Length() > 0 {
 			hnr = true
 		}
-		if s.Find(`*[alt="2X Free"]`).Length() > 0 {
+		// standard NexusPHP promotion icons: pro_free, pro_2up, pro_free2up, pro_50pctdown, pro_50pctdown2up,
+		// pro_30pctdown, with alt texts "Free", "2X", "2X Free", "50%", "2X 50%", "30%"
+		if down, up, ok := site.ParseDiscountText(s.Find(`img[class^="pro_"]`).First().AttrOr("alt", "")); ok {
+			downloadMultiplier = down
+			uploadMultiplier = up
+		} else if s.Find(`*[alt="2X Free"]`).Length() > 0 {
 			downloadMultiplier = 0
 			uploadMultiplier = 2
 		} else if s.Find(`*[title="免费"],*[title="免費"],*[alt="Free"],*[alt="FREE"],*[alt="free"]`).Length() > 0 ||
@@ -449,6 +393,16 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 		}
 		if option.selectorTorrentPaid != "" && s.Find(option.selectorTorrentPaid).Length() > 0 {
 			paid = true
+			if m := bonusCostRegexp.FindStringSubmatch(s.Find(option.selectorTorrentPaid).AttrOr("title", "") +
+				" " + util.DomSanitizedText(s)); m != nil {
+				bonusCost = site.ParseBonusCost(m[2])
+			}
+		}
+		if option.selectorTorrentBonusCost != "" {
+			if cost := site.ParseBonusCost(util.DomSelectorText(s, option.selectorTorrentBonusCost)); cost > 0 {
+				paid = true
+				bonusCost = cost
+			}
 		}
 		if option.selectorTorrentNeutral != "" && s.Find(option.selectorTorrentNeutral).Length() > 0 {
 			downloadMultiplier = 0
@@ -472,11 +426,12 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 		if option.selectorTorrentDiscountEndTime != "" {
 			discountEndTime, _ = util.ParseFutureTime(util.DomRemovedSpecialCharsText(s.Find(option.selectorTorrentDiscountEndTime)))
 		} else {
-			re := regexp.MustCompile(`(?i)(?P<free>(^|\s)(免费|免費|FREE)\s*)?(剩余|剩餘|限时|限時)(时间|時間)?\s*(?P<time>\d[\sYMDHMSymdhms年月周天小时時分种鐘秒\d]+[YMDHMSymdhms年月周天小时時分种鐘秒])`)
+			re := regexp.MustCompile(`(?i)(?P<free>(^|\s)((2X\s*)?(免费|免費|FREE)|(2X\s*)?\d{1,2}%)\s*)?(剩余|剩餘|限时|限時)(时间|時間)?\s*(?P<time>\d[\sYMDHMSymdhms年月周天小时時分种鐘秒\d]+[YMDHMSymdhms年月周天小时時分种鐘秒])`)
 			m := re.FindStringSubmatch(util.DomRemovedSpecialCharsText(s))
 			if m != nil {
-				if m[re.SubexpIndex("free")] != "" {
-					downloadMultiplier = 0
+				if down, up, ok := site.ParseDiscountText(m[re.SubexpIndex("free")]); ok {
+					downloadMultiplier = down
+					uploadMultiplier = max(uploadMultiplier, up)
 				}
 				discountEndTime, _ = util.ParseFutureTime(m[re.SubexpIndex("time")])
 			}
@@ -508,6 +463,7 @@ func parseTorrents(doc *goquery.Document, option *TorrentsParserOption,
 				IsActive:           isActive,
 				IsCurrentActive:    isCurrentActive,
 				Paid:               paid,
+				BonusCost:          bonusCost,
 				Neutral:            neutral,
 				Tags:               tags,
 			})
@@ -0,0 +1,107 @@
+package nexusphp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// NexusPHP user info bar. See https://github.com/xiaomlove/nexusphp/blob/php8/include/functions.php ,
+// function stdhead. The username link has the css class of user class (e.g. "PowerUser_Name"),
+// the inbox link (messages.php) displays the unread messages count, the warned icon is displayed
+// if user is warned, and the H&R plugin displays the pending H&R count which links to myhr.php.
+const (
+	SELECTOR_USER_INFO_NAME     = `a[href*="userdetails.php?"]`
+	SELECTOR_USER_INFO_MESSAGES = `a[href*="messages.php"]`
+	SELECTOR_USER_INFO_INBOXNEW = `img.inboxnew,img[alt="inbox new"]`
+	SELECTOR_USER_INFO_WARNED   = `img.warned,img.leechwarned,img[alt="Warned"],img[alt="Leechwarned"]`
+	SELECTOR_USER_INFO_HNR      = `a[href*="myhr.php"]`
+)
+
+var (
+	// the "remaining time" column of H&R list, e.g. "剩余考察时间", "Inspect time left"
+	hnrTimeLeftHeaderRegexp = regexp.MustCompile(`(?i)剩余|剩餘|time left|remaining`)
+	// e.g. "6天23:59:59", "2d 03:00:00", "12:00:00"
+	clockDurationRegexp = regexp.MustCompile(
+		`^(?:(?P<d>\d+)\s*(?:天|d|days?)\s*)?(?P<h>\d+):(?P<m>\d{2})(?::(?P<s>\d{2}))?$`)
+)
+
+// Parse the user class, unread messages, warning and H&R count of the user info bar (infoTr) of site page.
+// The user info selectors of site config take precedence.
+func (npclient *Site) parseUserInfo(siteStatus *site.Status, doc *goquery.Document,
+	infoTr *goquery.Selection, infoTxt string) {
+	siteConfig := npclient.SiteConfig
+	siteStatus.ParseUserInfoDom(doc.Selection, siteConfig)
+	if siteConfig.SelectorUserInfoUserClass == "" {
+		for _, class := range strings.Fields(doc.Find(SELECTOR_USER_INFO_NAME).First().AttrOr("class", "")) {
+			if name, found := strings.CutSuffix(class, "_Name"); found {
+				siteStatus.UserClass = strings.ReplaceAll(name, "_", " ")
+				break
+			}
+		}
+	}
+	if siteConfig.SelectorUserInfoWarned == "" {
+		siteStatus.Warned = infoTr.Find(SELECTOR_USER_INFO_WARNED).Length() > 0
+	}
+	siteStatus.ParseUserInfoText(infoTxt)
+	if siteConfig.SelectorUserInfoMessages == "" {
+		// e.g. the "你有 3 条新短讯！点击查看" alert above info bar
+		siteStatus.ParseUserInfoText(doc.Find(SELECTOR_USER_INFO_MESSAGES).Text())
+		if siteStatus.UnreadMessages == 0 && infoTr.Find(SELECTOR_USER_INFO_INBOXNEW).Length() > 0 {
+			siteStatus.UnreadMessages = 1
+		}
+	}
+	// the H&R deadline is fetched lazily by GetStatus
+	npclient.hnrUrl = infoTr.Find(SELECTOR_USER_INFO_HNR).First().AttrOr("href", "")
+	npclient.hnrDeadlineFetched = false
+}
+
+// Fetch the H&R list page (myhr.php) and return the earliest deadline of pending H&R torrents,
+// which is calculated from the "remaining time" column. Return 0 if not found.
+func (npclient *Site) getHnRDeadline(hnrUrl string) (int64, error) {
+	doc, _, err := util.GetUrlDocWithAzuretls(npclient.SiteConfig.ParseSiteUrl(hnrUrl, false), npclient.HttpClient,
+		npclient.SiteConfig.Cookie, site.GetUa(npclient), npclient.GetDefaultHttpHeaders())
+	if err != nil {
+		return 0, err
+	}
+	now := util.Now()
+	deadline := int64(0)
+	doc.Find("table").Each(func(i int, table *goquery.Selection) {
+		rows := table.Find("tr")
+		column := -1
+		rows.First().Children().EachWithBreak(func(j int, cell *goquery.Selection) bool {
+			if hnrTimeLeftHeaderRegexp.MatchString(cell.Text()) {
+				column = j
+				return false
+			}
+			return true
+		})
+		if column == -1 {
+			return
+		}
+		rows.Slice(1, goquery.ToEnd).Each(func(j int, row *goquery.Selection) {
+			if left := parseTimeLeft(util.DomSanitizedText(row.Children().Eq(column))); left > 0 &&
+				(deadline == 0 || now+left < deadline) {
+				deadline = now + left
+			}
+		})
+	})
+	return deadline, nil
+}
+
+// Parse the remaining time text to seconds. Return 0 if invalid. Clock format text (e.g. "6天23:59:59")
+// is parsed by clockDurationRegexp, others (e.g. "6天23时", "2d3h") by util.ParseTimeDuration.
+func parseTimeLeft(str string) int64 {
+	if m := clockDurationRegexp.FindStringSubmatch(str); m != nil {
+		return util.ParseInt(m[clockDurationRegexp.SubexpIndex("d")])*86400 +
+			util.ParseInt(m[clockDurationRegexp.SubexpIndex("h")])*3600 +
+			util.ParseInt(m[clockDurationRegexp.SubexpIndex("m")])*60 +
+			util.ParseInt(m[clockDurationRegexp.SubexpIndex("s")])
+	}
+	seconds, _ := util.ParseTimeDuration(str)
+	return seconds
+}
@@ -0,0 +1,32 @@
+package nexusphp
+
+import (
+	"testing"
+)
+
+func TestParseTimeLeft(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected int64
+	}{
+		{"6天23:59:59", 6*86400 + 23*3600 + 59*60 + 59},
+		{"2d 03:00:00", 2*86400 + 3*3600},
+		{"2 days 03:00", 2*86400 + 3*3600},
+		{"12:00:00", 12 * 3600},
+		{"1:30", 3600 + 30*60},
+		{"6天23时", 6*86400 + 23*3600},
+		{"6天23小时", 6*86400 + 23*3600},
+		{"23时59分", 23*3600 + 59*60},
+		{"2d3h", 2*86400 + 3*3600},
+		{"", 0},
+		{"已完成", 0},
+		{"--", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			if result := parseTimeLeft(test.str); result != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package site
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+// Per-site request rate & concurrency limiter. It's shared by all instances (commands) of the same site
+// in current process, so brush, search, batchdl and other commands can not accidentally hammer a site.
+type rateLimiter struct {
+	siteConfig *config.SiteConfigStruct
+	interval   time.Duration // min interval between the starts of two requests. 0 == unlimited
+	slots      chan struct{} // concurrency slots. nil == unlimited
+	mu         sync.Mutex
+	next       time.Time // the earliest time the next request can start
+}
+
+var (
+	rateLimiters   = map[string]*rateLimiter{} // site name => limiter
+	rateLimitersMu sync.Mutex
+)
+
+func init() {
+	util.RequestLimiter = limitRequest
+}
+
+// Register the limiter of site, if it's not already registered.
+// Sites are not limited by default, unless the limits are set in config.
+func registerRateLimiter(siteConfig *config.SiteConfigStruct, globalConfig *config.ConfigStruct) {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if rateLimiters[siteConfig.GetName()] != nil {
+		return
+	}
+	requestsPerMinute := util.FirstNonZeroIntegerArg(siteConfig.RequestsPerMinute, globalConfig.SiteRequestsPerMinute)
+	maxConcurrentRequests := util.FirstNonZeroIntegerArg(siteConfig.MaxConcurrentRequests,
+		globalConfig.SiteMaxConcurrentRequests)
+	if requestsPerMinute <= 0 && maxConcurrentRequests <= 0 {
+		return
+	}
+	limiter := &rateLimiter{siteConfig: siteConfig}
+	if requestsPerMinute > 0 {
+		limiter.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	if maxConcurrentRequests > 0 {
+		limiter.slots = make(chan struct{}, maxConcurrentRequests)
+	}
+	log.Tracef("Site %s rate limit: %d requests / minute, %d concurrent requests", siteConfig.GetName(),
+		requestsPerMinute, maxConcurrentRequests)
+	rateLimiters[siteConfig.GetName()] = limiter
+}
+
+// Wait until a request to url is allowed by the limiter of the site which url belongs to.
+// Requests to urls not belonging to any (created) site are not limited.
+func limitRequest(url string) (done func()) {
+	domain := util.GetUrlDomain(url)
+	var limiter *rateLimiter
+	rateLimitersMu.Lock()
+	for _, l := range rateLimiters {
+		if config.MatchSite(domain, l.siteConfig) {
+			limiter = l
+			break
+		}
+	}
+	rateLimitersMu.Unlock()
+	if limiter == nil {
+		return func() {}
+	}
+	if limiter.slots != nil {
+		limiter.slots <- struct{}{}
+	}
+	if limiter.interval > 0 {
+		limiter.mu.Lock()
+		now := time.Now()
+		start := now
+		if limiter.next.After(now) {
+			start = limiter.next
+		}
+		limiter.next = start.Add(limiter.interval)
+		limiter.mu.Unlock()
+		if wait := start.Sub(now); wait > 0 {
+			log.Debugf("Site %s rate limit: wait %v before requesting %s", limiter.siteConfig.GetName(), wait, url)
+			time.Sleep(wait)
+		}
+	}
+	return func() {
+		if limiter.slots != nil {
+			<-limiter.slots
+		}
+	}
+}
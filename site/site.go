@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"net/url"
 	"os"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -23,6 +25,7 @@ import (
 	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/crypto"
 	"github.com/sagan/ptool/util/impersonateutil"
+	"github.com/sagan/ptool/util/releasename"
 )
 
 // @todo: considering changing it to interface
@@ -46,8 +49,14 @@ type Torrent struct {
 	IsCurrentActive    bool     // true if torrent is currently downloading / seeding. If true, so will be IsActive
 	Paid               bool     // "付费"种子: (第一次)下载或汇报种子时扣除魔力/积分
 	Bought             bool     // 适用于付费种子：已购买
+	BonusCost          float64  // 适用于付费种子：下载种子需要扣除的魔力/积分。0 表示未知
 	Neutral            bool     // 中性种子：不计算上传、下载、做种魔力
 	Tags               []string // labels, e.g. category and other meta infos.
+	// Structured details parsed from torrent details page. Only available if fetched by GetTorrentDetails.
+	Details *TorrentDetails
+	// Movie / TV show metadata of torrent. Only available if resolved by metadata.Lookup.
+	Metadata *TorrentMetadata
+	release  *releasename.Release // parsed from Name on demand. See Release()
 }
 
 type Status struct {
@@ -56,6 +65,11 @@ type Status struct {
 	UserUploaded        int64
 	TorrentsSeedingCnt  int64
 	TorrentsLeechingCnt int64
+	UserClass           string // user class (level), e.g. "Power User". Empty if unknown
+	UnreadMessages      int64  // count of unread private messages (PMs)
+	Warned              bool   // user account has been warned
+	HnRs                int64  // count of pending (not yet satisfied) HnR torrents
+	HnRDeadline         int64  // unix timestamp. The earliest deadline of pending HnR torrents. 0 if unknown
 }
 
 type Site interface {
@@ -78,6 +92,22 @@ type Site interface {
 	// Specially. if metadata contains "_dryrun", use dry run mode.
 	PublishTorrent(contents []byte, metadata url.Values) (id string, err error)
 	GetStatus() (*Status, error)
+	// Get current bonus points of user and the bonus points exchange options of site.
+	GetBonusExchangeOptions() (bonus float64, options []*BonusExchangeOption, err error)
+	// Exchange bonus points for upload credit, invite or others, using the option.
+	ExchangeBonus(option *BonusExchangeOption) error
+	// Get the structured (media) details of torrent (by id, e.g. "12345"), parsed from it's details page.
+	GetTorrentDetails(id string) (*TorrentDetails, error)
+	// Get the private messages (including system notifications) in the inbox of user.
+	// If all is false, only unread messages are returned.
+	GetMessages(all bool) ([]*Message, error)
+	// Get the message (by id, e.g. "123") with it's contents. Site may mark the message as read.
+	GetMessage(id string) (*Message, error)
+	// Mark the messages (by ids) as read.
+	MarkMessagesRead(ids []string) error
+	// Login with the username, password (and TOTP code of totpSecret, if 2FA is enabled) of site config.
+	// Return the new cookie of logined session.
+	Login() (cookie string, err error)
 	PurgeCache()
 }
 
@@ -92,6 +122,10 @@ type SiteCreator func(*RegInfo) (Site, error)
 var (
 	// Error that indicates the feature is not implemented in current site.
 	ErrUnimplemented = fmt.Errorf("not implemented yet")
+	// Error that indicates the user account of site is banned or disabled.
+	ErrBanned = fmt.Errorf("account is banned or disabled (%w)", constants.ErrAuth)
+	// Error that indicates the site is not logined (cookie is invalid or expired).
+	ErrNotLogined = fmt.Errorf("not logined (cookie may has expired) (%w)", constants.ErrAuth)
 )
 
 var (
@@ -101,6 +135,8 @@ var (
 	mu           sync.Mutex
 )
 
+var bonusCostRegexp = regexp.MustCompile(`\d+(,\d{3})*(\.\d+)?`)
+
 func (ss *Status) Print(f io.Writer, name string, additionalInfo string) {
 	fmt.Printf(constants.STATUS_FMT, "Site", name, fmt.Sprintf("↑: %s", util.BytesSizeAround(float64(ss.UserUploaded))),
 		fmt.Sprintf("↓: %s", util.BytesSizeAround(float64(ss.UserDownloaded))), additionalInfo)
@@ -174,6 +210,47 @@ func (torrent *Torrent) MatchFiltersAndOr(filtersArray [][]string) bool {
 	return matched
 }
 
+// Return true if downloading torrent would spend more bonus points than maxBonusCost.
+// A paid (and not bought) torrent which price is unknown is always considered as exceeding.
+func (torrent *Torrent) ExceedsBonusCost(maxBonusCost float64) bool {
+	if !torrent.Paid || torrent.Bought {
+		return false
+	}
+	return torrent.BonusCost <= 0 || torrent.BonusCost > maxBonusCost
+}
+
+// Return the fields of torrent as the value passed to script functions.
+func (torrent *Torrent) ScriptValue() map[string]any {
+	return map[string]any{
+		"name":                torrent.Name,
+		"description":         torrent.Description,
+		"id":                  torrent.Id,
+		"hash":                torrent.InfoHash,
+		"size":                torrent.Size,
+		"time":                torrent.Time,
+		"seeders":             torrent.Seeders,
+		"leechers":            torrent.Leechers,
+		"snatched":            torrent.Snatched,
+		"download_multiplier": torrent.DownloadMultiplier,
+		"upload_multiplier":   torrent.UploadMultiplier,
+		"discount_end_time":   torrent.DiscountEndTime,
+		"free":                torrent.DownloadMultiplier == 0,
+		"hr":                  torrent.HasHnR,
+		"paid":                torrent.Paid,
+		"bought":              torrent.Bought,
+		"bonus_cost":          torrent.BonusCost,
+		"neutral":             torrent.Neutral,
+		"tags":                torrent.Tags,
+	}
+}
+
+// Parse the first number in text (e.g. "价格: 1,000.5 魔力") as bonus points. Return 0 if not found.
+func ParseBonusCost(text string) float64 {
+	m := bonusCostRegexp.FindString(text)
+	cost, _ := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64)
+	return cost
+}
+
 func Register(regInfo *RegInfo) {
 	registryMap[regInfo.Name] = regInfo
 	for _, alias := range regInfo.Aliases {
@@ -181,12 +258,19 @@ func Register(regInfo *RegInfo) {
 	}
 }
 
+// Return true if the site type (or template name) is supported.
+func TypeExists(siteType string) bool {
+	return registryMap[siteType] != nil
+}
+
 func CreateSiteInternal(name string,
 	siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (Site, error) {
 	regInfo := registryMap[siteConfig.Type]
 	if regInfo == nil {
 		return nil, fmt.Errorf("unsupported site type %s", name)
 	}
+	registerRateLimiter(siteConfig, config)
+	registerChallengeSolver(siteConfig, config)
 	return regInfo.Creator(name, siteConfig, config)
 }
 
@@ -210,7 +294,7 @@ func CreateSite(name string) (Site, error) {
 	}
 	siteConfig := config.GetSiteConfig(name)
 	if siteConfig == nil {
-		return nil, fmt.Errorf("site %s not found", name)
+		return nil, fmt.Errorf("site %s %w", name, constants.ErrNotFound)
 	}
 	siteInstance, err := CreateSiteInternal(name, siteConfig, config.Get())
 	if err != nil {
@@ -255,6 +339,8 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, now int
 		}
 		if torrent.DownloadMultiplier == 0 {
 			freeStr += "✓"
+		} else if torrent.DownloadMultiplier < 1 {
+			freeStr += fmt.Sprintf("%d%%", int64(math.Round(torrent.DownloadMultiplier*100)))
 		} else {
 			freeStr += "✕"
 		}
@@ -419,10 +505,7 @@ func CreateSiteHttpClient(siteConfig *config.SiteConfigStruct, globalConfig *con
 	if siteConfig.HttpHeaders != nil {
 		httpHeaders = append(httpHeaders, siteConfig.HttpHeaders...)
 	}
-	proxy := config.GetProxy(siteConfig.Proxy, globalConfig.SiteProxy)
-	if proxy == "" || proxy == constants.ENV_PROXY {
-		proxy = util.ParseProxyFromEnv(siteConfig.Url)
-	}
+	proxy := GetProxy(siteConfig, globalConfig)
 	insecure := config.Insecure || !siteConfig.Secure && (siteConfig.Insecure || globalConfig.SiteInsecure)
 	timeout := util.FirstNonZeroIntegerArg(config.Timeout, siteConfig.Timeout, globalConfig.SiteTimeout)
 	if timeout == 0 {
@@ -436,9 +519,6 @@ func CreateSiteHttpClient(siteConfig *config.SiteConfigStruct, globalConfig *con
 	if h2fingerprint == constants.NONE {
 		h2fingerprint = ""
 	}
-	if proxy == constants.NONE {
-		proxy = ""
-	}
 	sep := "\n"
 	specs := fmt.Sprint(ja3, sep, h2fingerprint, sep, proxy, sep, insecure, sep, timeout)
 	log.Tracef("Create site %s http client with specs %s", siteConfig.GetName(), specs)
@@ -481,6 +561,19 @@ func CreateSiteHttpClient(siteConfig *config.SiteConfigStruct, globalConfig *con
 	return session, httpHeaders, nil
 }
 
+// Return the effective proxy of site, following the orders:
+// --proxy flag, site proxy, global siteProxy, HTTP_PROXY & HTTPS_PROXY envs. Return empty string if no proxy.
+func GetProxy(siteConfig *config.SiteConfigStruct, globalConfig *config.ConfigStruct) string {
+	proxy := config.GetProxy(siteConfig.Proxy, globalConfig.SiteProxy)
+	if proxy == "" || proxy == constants.ENV_PROXY {
+		proxy = util.ParseProxyFromEnv(siteConfig.Url)
+	}
+	if proxy == constants.NONE {
+		proxy = ""
+	}
+	return proxy
+}
+
 // return site ua from siteConfig and globalConfig
 func GetUa(siteInstance Site) string {
 	ua := siteInstance.GetSiteConfig().UserAgent
@@ -637,6 +730,7 @@ func UploadTorrent(siteInstance Site, httpClient *azuretls.Session, uploadUrl st
 }
 
 func init() {
+	config.OnReload(Reset)
 }
 
 // called by main codes on program exit. clean resources
@@ -646,6 +740,17 @@ func Exit() {
 	resourcesWaitGroup.Wait()
 }
 
+// Remove all created site instances, so that they will be re-created using current config.
+func Reset() {
+	sites = map[string]Site{}
+	rateLimitersMu.Lock()
+	rateLimiters = map[string]*rateLimiter{}
+	rateLimitersMu.Unlock()
+	challengeSolversMu.Lock()
+	challengeSolvers = map[string]*challengeSolver{}
+	challengeSolversMu.Unlock()
+}
+
 // Purge site cache
 func Purge(sitename string) {
 	if sitename == "" {
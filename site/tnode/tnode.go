@@ -62,6 +62,41 @@ func (tnsite *Site) GetName() string {
 	return tnsite.Name
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (tnsite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (tnsite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (tnsite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (tnsite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (tnsite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (tnsite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (tnsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
 func (tnsite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return tnsite.SiteConfig
 }
@@ -0,0 +1,352 @@
+package torznab
+
+// torznab is a site type that searches torrents from indexers of Jackett or Prowlarr using the Torznab API.
+// Spec: https://torznab.github.io/spec-1.3-draft/torznab/Specification-v1.3.html .
+// Site url is the torznab feed url of a indexer (or the aggregate "all" indexer), e.g.:
+// Jackett: http://localhost:9117/api/v2.0/indexers/all/results/torznab/
+// Prowlarr: http://localhost:9696/1/api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Noooste/azuretls-client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	// results count per page of GetAllTorrents
+	PAGE_SIZE = 100
+)
+
+type Site struct {
+	Name        string
+	SiteConfig  *config.SiteConfigStruct
+	Config      *config.ConfigStruct
+	HttpClient  *azuretls.Session
+	HttpHeaders [][]string
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type torznabItem struct {
+	Title           string `xml:"title"`
+	Guid            string `xml:"guid"`
+	Link            string `xml:"link"`
+	Comments        string `xml:"comments"`
+	PubDate         string `xml:"pubDate"`
+	Size            int64  `xml:"size"`
+	Description     string `xml:"description"`
+	JackettIndexer  string `xml:"jackettindexer"`
+	ProwlarrIndexer string `xml:"prowlarrindexer"`
+	Enclosure       struct {
+		Url    string `xml:"url,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+	Attrs []*torznabAttr `xml:"attr"`
+}
+
+type torznabRss struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Items []*torznabItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type torznabCaps struct {
+	XMLName xml.Name `xml:"caps"`
+	Server  struct {
+		Title   string `xml:"title,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"server"`
+}
+
+type torznabError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        string   `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+// PublishTorrent implements site.Site.
+func (tsite *Site) PublishTorrent(contents []byte, metadata url.Values) (id string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+func (tsite *Site) GetDefaultHttpHeaders() [][]string {
+	return tsite.HttpHeaders
+}
+
+func (tsite *Site) PurgeCache() {
+}
+
+func (tsite *Site) GetName() string {
+	return tsite.Name
+}
+
+// GetBonusExchangeOptions implements site.Site.
+func (tsite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (tsite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (tsite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (tsite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (tsite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (tsite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// GetTorrentDetails implements site.Site.
+func (tsite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	return nil, site.ErrUnimplemented
+}
+
+func (tsite *Site) GetSiteConfig() *config.SiteConfigStruct {
+	return tsite.SiteConfig
+}
+
+// Torznab has no user info. It requests the capabilities of indexer,
+// and returns the server title (e.g. "Jackett") as the user name.
+func (tsite *Site) GetStatus() (*site.Status, error) {
+	caps := &torznabCaps{}
+	if err := tsite.fetch(url.Values{"t": {"caps"}}, caps); err != nil {
+		return nil, err
+	}
+	userName := caps.Server.Title
+	if userName == "" {
+		userName = tsite.Name
+	}
+	return &site.Status{UserName: userName}, nil
+}
+
+// pageMarker is the offset of results. Sorting is not supported.
+// baseUrl is ignored. Note some indexers do not support offset and always return the first page.
+func (tsite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
+	torrents []*site.Torrent, nextPageMarker string, err error) {
+	if sort != "" && sort != constants.NONE {
+		return nil, "", fmt.Errorf("torznab site does not support sorting")
+	}
+	offset := int64(0)
+	if pageMarker != "" {
+		offset = util.ParseInt(pageMarker)
+	}
+	torrents, err = tsite.search(url.Values{
+		"t":      {"search"},
+		"offset": {fmt.Sprint(offset)},
+		"limit":  {fmt.Sprint(PAGE_SIZE)},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(torrents) >= PAGE_SIZE {
+		nextPageMarker = fmt.Sprint(offset + int64(len(torrents)))
+	}
+	return
+}
+
+func (tsite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
+	return tsite.search(url.Values{"t": {"search"}})
+}
+
+// baseUrl is ignored.
+func (tsite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
+	return tsite.search(url.Values{"t": {"search"}, "q": {keyword}})
+}
+
+// Torznab results have no site torrent id, torrent can only be downloaded by it's (Jackett / Prowlarr) url.
+func (tsite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
+	if !util.IsUrl(torrentUrl) {
+		content, filename, err = tsite.DownloadTorrentById(torrentUrl)
+		return
+	}
+	content, filename, err = site.DownloadTorrentByUrl(tsite, tsite.HttpClient, torrentUrl, "")
+	return
+}
+
+func (tsite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
+	return nil, "", site.ErrUnimplemented
+}
+
+// Return the torznab api url with params.
+// Site url and it's query string (e.g. "cat=2000") are kept, the "/api" path is appended if missing.
+func (tsite *Site) apiUrl(params url.Values) (string, error) {
+	urlObj, err := url.Parse(tsite.SiteConfig.Url)
+	if err != nil {
+		return "", fmt.Errorf("invalid site url: %w", err)
+	}
+	if !strings.HasSuffix(urlObj.Path, "/api") {
+		urlObj.Path = strings.TrimSuffix(urlObj.Path, "/") + "/api"
+	}
+	query := urlObj.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if tsite.SiteConfig.ApiKey != "" {
+		query.Set("apikey", tsite.SiteConfig.ApiKey)
+	}
+	urlObj.RawQuery = query.Encode()
+	return urlObj.String(), nil
+}
+
+// Request torznab api and parse the xml response into v.
+func (tsite *Site) fetch(params url.Values, v any) error {
+	apiUrl, err := tsite.apiUrl(params)
+	if err != nil {
+		return err
+	}
+	res, _, err := util.FetchUrlWithAzuretls(apiUrl, tsite.HttpClient,
+		tsite.SiteConfig.Cookie, site.GetUa(tsite), tsite.GetDefaultHttpHeaders())
+	if res == nil {
+		return err
+	}
+	// torznab error response could have any http status
+	apiErr := &torznabError{}
+	if xml.Unmarshal(res.Body, apiErr) == nil {
+		return fmt.Errorf("torznab error %s: %s", apiErr.Code, apiErr.Description)
+	}
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(res.Body, v); err != nil {
+		return fmt.Errorf("failed to parse torznab response: %w", err)
+	}
+	return nil
+}
+
+func (tsite *Site) search(params url.Values) ([]*site.Torrent, error) {
+	rss := &torznabRss{}
+	if err := tsite.fetch(params, rss); err != nil {
+		return nil, err
+	}
+	torrents := []*site.Torrent{}
+	for _, item := range rss.Channel.Items {
+		torrent := &site.Torrent{
+			Name:               item.Title,
+			Description:        item.Description,
+			DownloadUrl:        item.Link,
+			Size:               item.Size,
+			IsSizeAccurate:     item.Size > 0,
+			DownloadMultiplier: 1,
+			UploadMultiplier:   1,
+			HasHnR:             tsite.SiteConfig.GlobalHnR,
+		}
+		if torrent.DownloadUrl == "" {
+			torrent.DownloadUrl = item.Enclosure.Url
+		}
+		if torrent.Size <= 0 && item.Enclosure.Length > 0 {
+			torrent.Size = item.Enclosure.Length
+			torrent.IsSizeAccurate = true
+		}
+		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+			torrent.Time = t.Unix()
+		} else if t, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
+			torrent.Time = t.Unix()
+		}
+		if item.JackettIndexer != "" {
+			torrent.Tags = append(torrent.Tags, item.JackettIndexer)
+		} else if item.ProwlarrIndexer != "" {
+			torrent.Tags = append(torrent.Tags, item.ProwlarrIndexer)
+		}
+		peers := int64(-1)
+		for _, attr := range item.Attrs {
+			switch attr.Name {
+			case "seeders":
+				torrent.Seeders = util.ParseInt(attr.Value)
+			case "peers":
+				peers = util.ParseInt(attr.Value)
+			case "grabs":
+				torrent.Snatched = util.ParseInt(attr.Value)
+			case "size":
+				if torrent.Size <= 0 {
+					torrent.Size = util.ParseInt(attr.Value)
+					torrent.IsSizeAccurate = true
+				}
+			case "infohash":
+				torrent.InfoHash = strings.ToLower(attr.Value)
+			case "downloadvolumefactor":
+				if value, err := strconv.ParseFloat(attr.Value, 64); err == nil {
+					torrent.DownloadMultiplier = value
+				}
+			case "uploadvolumefactor":
+				if value, err := strconv.ParseFloat(attr.Value, 64); err == nil {
+					torrent.UploadMultiplier = value
+				}
+			case "minimumseedtime":
+				if util.ParseInt(attr.Value) > 0 {
+					torrent.HasHnR = true
+				}
+			}
+		}
+		// torznab "peers" is the count of both seeders and leechers
+		if peers >= torrent.Seeders {
+			torrent.Leechers = peers - torrent.Seeders
+		}
+		if torrent.Name == "" || torrent.DownloadUrl == "" {
+			log.Tracef("Skip invalid torznab item: %v", item)
+			continue
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, nil
+}
+
+func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
+	if siteConfig.Url == "" {
+		return nil, fmt.Errorf("url is not configured")
+	}
+	if siteConfig.ApiKey == "" {
+		log.Warnf("Site %s has no apiKey provided", name)
+	}
+	httpClient, httpHeaders, err := site.CreateSiteHttpClient(siteConfig, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create site http client: %w", err)
+	}
+	site := &Site{
+		Name:        name,
+		SiteConfig:  siteConfig,
+		Config:      config,
+		HttpClient:  httpClient,
+		HttpHeaders: httpHeaders,
+	}
+	return site, nil
+}
+
+func init() {
+	site.Register(&site.RegInfo{
+		Name:    "torznab",
+		Creator: NewSite,
+	})
+}
+
+var (
+	_ site.Site = (*Site)(nil)
+)
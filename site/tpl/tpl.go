@@ -450,7 +450,7 @@ https://www.dlsite.com/maniax/work/=/product_id/{{number | regex_search("\\bRJ\\
 			Aliases:             []string{"m-team", "mt"},
 			Url:                 "https://api.m-team.cc/", // @todo: add a separated ApiUrl (or similar) field.
 			Domains:             []string{"m-team.io"},
-			NoCookie:            true, // @todo: add a "Token" field for API authentication.
+			NoCookie:            true, // 使用 apiKey (api token) 鉴权
 			TorrentsExtraUrls:   []string{"adult.php", "music.php"},
 			BrushExcludes:       []string{"[原盤首發]"}, // 馒头原盘首发限速，刷流效果极差
 			FlowControlInterval: 30,                 // 馒头流控极为严格。很容易出“休息120秒”页面
@@ -480,6 +480,13 @@ https://www.dlsite.com/maniax/work/=/product_id/{{number | regex_search("\\bRJ\\
 			Url:     "https://oldtoons.world/",
 			Comment: "Old Toons World",
 		},
+		"orpheus": {
+			Type:     "gazelle",
+			Aliases:  []string{"ops"},
+			Url:      "https://orpheus.network/",
+			Timezone: "UTC",
+			Comment:  "Orpheus",
+		},
 		"oshen": {
 			Type:    "nexusphp",
 			Url:     "https://www.oshen.win/",
@@ -558,6 +565,13 @@ https://www.dlsite.com/maniax/work/=/product_id/{{number | regex_search("\\bRJ\\
 			Timezone:                   "UTC",
 			Comment:                    "Pro Wrestling Torrents",
 		},
+		"redacted": {
+			Type:     "gazelle",
+			Aliases:  []string{"red"},
+			Url:      "https://redacted.sh/",
+			Timezone: "UTC",
+			Comment:  "Redacted",
+		},
 		"rousi": {
 			Type:              "nexusphp",
 			Url:               "https://rousi.zip/",
@@ -0,0 +1,165 @@
+package unit3d
+
+// UNIT3D API. See https://github.com/HDInnovations/UNIT3D-Community-Edition/wiki/Torrent-API-(UNIT3D-v8.x.x) .
+// Authenticated by the user's API token (设置 - API Key).
+// GET /api/torrents/filter?name=keyword&sortField=created_at&sortDirection=desc&perPage=100&page=1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	API_TORRENTS_URL = "api/torrents/filter"
+	// GET /api/torrents/12345
+	API_TORRENT_URL = "api/torrents/"
+	// results count per page of api
+	API_PAGE_SIZE = 100
+)
+
+type apiTorrent struct {
+	Id         json.Number `json:"id"`
+	Attributes struct {
+		Name           string          `json:"name"`
+		Category       string          `json:"category"`
+		Type           string          `json:"type"`
+		Resolution     string          `json:"resolution"`
+		InfoHash       string          `json:"info_hash"`
+		Size           int64           `json:"size"`
+		Freeleech      json.RawMessage `json:"freeleech"` // e.g. "100%", "0%". Some versions use number
+		DoubleUpload   bool            `json:"double_upload"`
+		Featured       bool            `json:"featured"`
+		Seeders        int64           `json:"seeders"`
+		Leechers       int64           `json:"leechers"`
+		TimesCompleted int64           `json:"times_completed"`
+		CreatedAt      string          `json:"created_at"` // e.g. "2023-08-12T10:52:42.000000Z"
+		DownloadLink   string          `json:"download_link"`
+		DetailsLink    string          `json:"details_link"`
+	} `json:"attributes"`
+}
+
+type apiTorrentDetails struct {
+	Attributes struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"` // bbcode
+		MediaInfo   string      `json:"media_info"`
+		BdInfo      string      `json:"bd_info"`
+		ImdbId      json.Number `json:"imdb_id"` // the number part of IMDb id, e.g. 111161
+	} `json:"attributes"`
+}
+
+// Some versions wrap the torrent resource in "data".
+type apiTorrentResponse struct {
+	apiTorrentDetails
+	Data *apiTorrentDetails `json:"data"`
+}
+
+type apiTorrentsResponse struct {
+	Data  []*apiTorrent `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// Request torrents from api. Return the torrents and the marker of next page ("" if it's the last page).
+// pageMarker is the "page" (or "cursor", for versions using cursor pagination) of results.
+func (usite *Site) getApiTorrents(params url.Values, pageMarker string) (
+	torrents []*site.Torrent, nextPageMarker string, err error) {
+	params.Set("perPage", fmt.Sprint(API_PAGE_SIZE))
+	if pageMarker != "" {
+		if _, err := strconv.Atoi(pageMarker); err == nil {
+			params.Set("page", pageMarker)
+		} else {
+			params.Set("cursor", pageMarker)
+		}
+	}
+	apiUrl := usite.SiteConfig.ParseSiteUrl(API_TORRENTS_URL, false) + "?" + params.Encode()
+	headers := append([][]string{{"Authorization", "Bearer " + usite.SiteConfig.ApiKey}},
+		usite.GetDefaultHttpHeaders()...)
+	res := &apiTorrentsResponse{}
+	if err = util.FetchJsonWithAzuretls(apiUrl, res, usite.HttpClient, "", site.GetUa(usite), headers); err != nil {
+		return nil, "", fmt.Errorf("failed to request api: %w", err)
+	}
+	torrents = []*site.Torrent{}
+	for _, item := range res.Data {
+		attrs := &item.Attributes
+		torrent := &site.Torrent{
+			Name:               attrs.Name,
+			Id:                 usite.GetName() + "." + item.Id.String(),
+			InfoHash:           strings.ToLower(attrs.InfoHash),
+			DownloadUrl:        attrs.DownloadLink,
+			Size:               attrs.Size,
+			IsSizeAccurate:     true,
+			Seeders:            attrs.Seeders,
+			Leechers:           attrs.Leechers,
+			Snatched:           attrs.TimesCompleted,
+			DownloadMultiplier: 1,
+			UploadMultiplier:   1,
+			HasHnR:             usite.SiteConfig.GlobalHnR,
+		}
+		if torrent.DownloadUrl == "" {
+			torrent.DownloadUrl = usite.getDownloadUrl(item.Id.String())
+		}
+		if t, err := time.Parse(time.RFC3339Nano, attrs.CreatedAt); err == nil {
+			torrent.Time = t.Unix()
+		} else {
+			torrent.Time, _ = util.ParseTime(attrs.CreatedAt, usite.Location)
+		}
+		if free := parseFreePercent(strings.Trim(string(attrs.Freeleech), `"`)); free > 0 {
+			torrent.DownloadMultiplier = 1 - free/100
+		}
+		if attrs.DoubleUpload {
+			torrent.UploadMultiplier = 2
+		}
+		// featured torrents are 100% free and double upload
+		if attrs.Featured {
+			torrent.DownloadMultiplier = 0
+			torrent.UploadMultiplier = 2
+		}
+		for _, tag := range []string{attrs.Category, attrs.Type, attrs.Resolution} {
+			if tag != "" {
+				torrent.Tags = append(torrent.Tags, tag)
+			}
+		}
+		torrents = append(torrents, torrent)
+	}
+	if res.Links.Next != "" && len(torrents) > 0 {
+		if urlObj, err := url.Parse(res.Links.Next); err == nil {
+			if nextPageMarker = urlObj.Query().Get("cursor"); nextPageMarker == "" {
+				nextPageMarker = urlObj.Query().Get("page")
+			}
+		}
+	}
+	return torrents, nextPageMarker, nil
+}
+
+// Get torrent details from torrent api.
+func (usite *Site) getApiTorrentDetails(id string) (*site.TorrentDetails, error) {
+	apiUrl := usite.SiteConfig.ParseSiteUrl(API_TORRENT_URL+url.PathEscape(id), false)
+	headers := append([][]string{{"Authorization", "Bearer " + usite.SiteConfig.ApiKey}},
+		usite.GetDefaultHttpHeaders()...)
+	res := &apiTorrentResponse{}
+	if err := util.FetchJsonWithAzuretls(apiUrl, res, usite.HttpClient, "", site.GetUa(usite), headers); err != nil {
+		return nil, fmt.Errorf("failed to request api: %w", err)
+	}
+	attrs := &res.Attributes
+	if res.Data != nil {
+		attrs = &res.Data.Attributes
+	}
+	texts := []string{attrs.Name, attrs.Description}
+	if imdbId, err := attrs.ImdbId.Int64(); err == nil && imdbId > 0 {
+		texts = append(texts, fmt.Sprintf("https://www.imdb.com/title/tt%07d/", imdbId))
+	}
+	mediaInfo := attrs.MediaInfo
+	if mediaInfo == "" {
+		mediaInfo = attrs.BdInfo
+	}
+	return site.ParseTorrentDetails(strings.Join(texts, "\n"), mediaInfo), nil
+}
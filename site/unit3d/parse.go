@@ -0,0 +1,122 @@
+package unit3d
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/site"
+	"github.com/sagan/ptool/util"
+)
+
+// Torrents list page (/torrents) dom. Selectors of both new (v7+, "torrent-search--list__*")
+// and old (v6 and earlier, "torrent-listings-*") versions are included.
+const (
+	SELECTOR_TORRENT_BLOCK    = `tr.torrent-search--list__row, tr:has(a.torrent-listings-name)`
+	SELECTOR_TORRENT_NAME     = `a.torrent-search--list__name, a.torrent-listings-name`
+	SELECTOR_TORRENT_SIZE     = `.torrent-search--list__size, .torrent-listings-size`
+	SELECTOR_TORRENT_SEEDERS  = `.torrent-search--list__seeders, .torrent-listings-seeders`
+	SELECTOR_TORRENT_LEECHERS = `.torrent-search--list__leechers, .torrent-listings-leechers`
+	SELECTOR_TORRENT_SNATCHED = `.torrent-search--list__completed, .torrent-listings-completed`
+	SELECTOR_TORRENT_TIME     = `.torrent-search--list__age, .torrent-listings-age`
+	SELECTOR_TORRENT_CATEGORY = `.torrent-search--list__category, .torrent-listings-category`
+	SELECTOR_TORRENT_FREE     = `.torrent-icons__freeleech, .torrent-listings-freeleech, .torrent-icons__featured`
+	SELECTOR_TORRENT_2XUP     = `.torrent-icons__double-upload, .torrent-listings-double-upload, .torrent-icons__featured`
+)
+
+// Torrent details page (/torrents/12345) dom.
+const (
+	SELECTOR_DETAILS_TITLE          = `.torrent__name`
+	SELECTOR_DETAILS_DESCRIPTION    = `.torrent__description .bbcode-rendered, .torrent-description .bbcode-rendered`
+	SELECTOR_DETAILS_MEDIAINFO      = `.torrent-mediainfo-dump code, .torrent-mediainfo-dump`
+	SELECTOR_DETAILS_EXTERNAL_LINKS = `a[href*="imdb.com/title/"], a[href*="douban.com/"]`
+)
+
+var (
+	torrentIdRegexp   = regexp.MustCompile(`/torrents/(?P<id>\d+)\b`)
+	freePercentRegexp = regexp.MustCompile(`(\d+(\.\d+)?)\s*%`)
+)
+
+// Parse the percent of free (download) from text, e.g. "100% Freeleech", "50%". Return 0 if not found.
+func parseFreePercent(text string) float64 {
+	if m := freePercentRegexp.FindStringSubmatch(text); m != nil {
+		if value, err := strconv.ParseFloat(m[1], 64); err == nil {
+			return min(value, 100)
+		}
+	}
+	return 0
+}
+
+func (usite *Site) parseTorrents(doc *goquery.Document) []*site.Torrent {
+	torrents := []*site.Torrent{}
+	doc.Find(SELECTOR_TORRENT_BLOCK).Each(func(i int, s *goquery.Selection) {
+		titleEl := s.Find(SELECTOR_TORRENT_NAME).First()
+		name := util.DomSanitizedText(titleEl)
+		id := ""
+		if m := torrentIdRegexp.FindStringSubmatch(titleEl.AttrOr("href", "")); m != nil {
+			id = m[torrentIdRegexp.SubexpIndex("id")]
+		}
+		if name == "" || id == "" {
+			return
+		}
+		torrent := &site.Torrent{
+			Name:               name,
+			Id:                 usite.GetName() + "." + id,
+			DownloadUrl:        usite.getDownloadUrl(id),
+			Seeders:            util.ParseInt(util.DomSelectorText(s, SELECTOR_TORRENT_SEEDERS)),
+			Leechers:           util.ParseInt(util.DomSelectorText(s, SELECTOR_TORRENT_LEECHERS)),
+			Snatched:           util.ParseInt(util.DomSelectorText(s, SELECTOR_TORRENT_SNATCHED)),
+			DownloadMultiplier: 1,
+			UploadMultiplier:   1,
+			HasHnR:             usite.SiteConfig.GlobalHnR,
+		}
+		torrent.Size, _ = util.ExtractSizeStr(util.DomSelectorText(s, SELECTOR_TORRENT_SIZE))
+		timeEl := s.Find(SELECTOR_TORRENT_TIME).First()
+		if datetime := timeEl.Find("time[datetime]").AttrOr("datetime", ""); datetime != "" {
+			torrent.Time, _ = util.ParseTime(datetime, usite.Location)
+		}
+		if torrent.Time == 0 {
+			torrent.Time = util.DomTime(timeEl, usite.Location)
+		}
+		if freeEl := s.Find(SELECTOR_TORRENT_FREE).First(); freeEl.Length() > 0 {
+			free := parseFreePercent(freeEl.AttrOr("title", "") + " " + util.DomSanitizedText(freeEl))
+			if free == 0 {
+				// e.g. "Global Freeleech", "Featured"
+				free = 100
+			}
+			torrent.DownloadMultiplier = 1 - free/100
+		}
+		if s.Find(SELECTOR_TORRENT_2XUP).Length() > 0 {
+			torrent.UploadMultiplier = 2
+		}
+		if category := util.DomSelectorText(s, SELECTOR_TORRENT_CATEGORY); category != "" {
+			torrent.Tags = append(torrent.Tags, category)
+		}
+		torrents = append(torrents, torrent)
+	})
+	return torrents
+}
+
+// Return the torrents list page url with params. Params of baseUrl (if not empty) are kept.
+func (usite *Site) torrentsPageUrl(baseUrl string, params url.Values) string {
+	if baseUrl == "" {
+		baseUrl = "torrents"
+	}
+	pageUrl := usite.SiteConfig.ParseSiteUrl(baseUrl, false)
+	if strings.HasPrefix(baseUrl, "?") {
+		pageUrl = usite.SiteConfig.ParseSiteUrl("torrents"+baseUrl, false)
+	}
+	urlObj, err := url.Parse(pageUrl)
+	if err != nil {
+		return pageUrl
+	}
+	query := urlObj.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	urlObj.RawQuery = query.Encode()
+	return urlObj.String()
+}
@@ -3,6 +3,9 @@ package unit3d
 // UNIT3D ( https://github.com/HDInnovations/UNIT3D-Community-Edition )
 // JptvClub、莫妮卡、普斯特等站使用架构
 // 种子下载链接格式：https://jptv.club/torrents/download/39683
+// 使用 RSS key (passkey) 的下载链接格式(无需 Cookie)：https://jptv.club/torrent/download/39683.<rsskey>
+// If apiKey (the API token of user) is configured, torrents are fetched from api; otherwise from the
+// torrents list page (/torrents) using cookie.
 
 import (
 	"fmt"
@@ -12,9 +15,11 @@ import (
 	"time"
 
 	"github.com/Noooste/azuretls-client"
+	"github.com/PuerkitoBio/goquery"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site"
 	"github.com/sagan/ptool/util"
 )
@@ -37,8 +42,20 @@ const (
 	SELECTOR_USERNAME        = ".top-nav__username"
 	SELECTOR_USER_UPLOADED   = ".ratio-bar__uploaded"
 	SELECTOR_USER_DOWNLOADED = ".ratio-bar__downloaded"
+	// results count per page of torrents list page
+	PAGE_SIZE = 100
 )
 
+// ptool sort field => UNIT3D sortField
+var sortFields = map[string]string{
+	"name":     "name",
+	"time":     "created_at",
+	"size":     "size",
+	"seeders":  "seeders",
+	"leechers": "leechers",
+	"snatched": "times_completed",
+}
+
 func (usite *Site) GetDefaultHttpHeaders() [][]string {
 	return usite.HttpHeaders
 }
@@ -50,19 +67,70 @@ func (usite *Site) GetName() string {
 	return usite.Name
 }
 
+// GetBonusExchangeOptions implements site.Site.
+func (usite *Site) GetBonusExchangeOptions() (bonus float64, options []*site.BonusExchangeOption, err error) {
+	return 0, nil, site.ErrUnimplemented
+}
+
+// ExchangeBonus implements site.Site.
+func (usite *Site) ExchangeBonus(option *site.BonusExchangeOption) error {
+	return site.ErrUnimplemented
+}
+
+// GetMessages implements site.Site.
+func (usite *Site) GetMessages(all bool) ([]*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// GetMessage implements site.Site.
+func (usite *Site) GetMessage(id string) (*site.Message, error) {
+	return nil, site.ErrUnimplemented
+}
+
+// MarkMessagesRead implements site.Site.
+func (usite *Site) MarkMessagesRead(ids []string) error {
+	return site.ErrUnimplemented
+}
+
+// Login implements site.Site.
+func (usite *Site) Login() (cookie string, err error) {
+	return "", site.ErrUnimplemented
+}
+
+// Parse torrent details from the torrent api (if only apiKey is configured) or details page.
+func (usite *Site) GetTorrentDetails(id string) (*site.TorrentDetails, error) {
+	if usite.SiteConfig.Cookie == "" && usite.SiteConfig.ApiKey != "" {
+		return usite.getApiTorrentDetails(id)
+	}
+	doc, err := usite.getDoc(usite.SiteConfig.ParseSiteUrl("torrents/"+id, false))
+	if err != nil {
+		return nil, err
+	}
+	titleEl := doc.Find(SELECTOR_DETAILS_TITLE)
+	if titleEl.Length() == 0 {
+		return nil, fmt.Errorf("no torrent found in details page")
+	}
+	return site.ParseTorrentDetailsDom(titleEl.First().AddSelection(doc.Find(SELECTOR_DETAILS_DESCRIPTION)).
+		AddSelection(doc.Find(SELECTOR_DETAILS_EXTERNAL_LINKS)), doc.Find(SELECTOR_DETAILS_MEDIAINFO)), nil
+}
+
 func (usite *Site) GetSiteConfig() *config.SiteConfigStruct {
 	return usite.SiteConfig
 }
 
+// If only apiKey is configured, which has no user info api, it verifies the api token
+// and returns the site name as the user name.
 func (usite *Site) GetStatus() (*site.Status, error) {
-	doc, res, err := util.GetUrlDocWithAzuretls(usite.SiteConfig.Url+"torrents", usite.HttpClient,
-		usite.GetSiteConfig().Cookie, site.GetUa(usite), usite.GetDefaultHttpHeaders())
+	if usite.SiteConfig.Cookie == "" && usite.SiteConfig.ApiKey != "" {
+		if _, _, err := usite.getApiTorrents(url.Values{}, ""); err != nil {
+			return nil, err
+		}
+		return &site.Status{UserName: usite.Name}, nil
+	}
+	doc, err := usite.getDoc(usite.SiteConfig.Url + "torrents")
 	if err != nil {
 		return nil, err
 	}
-	if strings.Contains(res.Request.Url, "/login") {
-		return nil, fmt.Errorf("not logined (cookie may has expired)")
-	}
 	userNameSelector := SELECTOR_USERNAME
 	userUploadedSelector := SELECTOR_USER_UPLOADED
 	userDownloadedSelector := SELECTOR_USER_DOWNLOADED
@@ -87,17 +155,42 @@ func (usite *Site) GetStatus() (*site.Status, error) {
 	}, nil
 }
 
+// baseUrl is the torrents list page url or it's query string (e.g. "?free[]=100"), whose params are
+// used as filters. When using api, the params are passed to the filter api.
 func (usite *Site) GetAllTorrents(sort string, desc bool, pageMarker string, baseUrl string) (
 	torrents []*site.Torrent, nextPageMarker string, err error) {
-	return nil, "", site.ErrUnimplemented
+	if sort != "" && sort != constants.NONE && sortFields[sort] == "" {
+		return nil, "", fmt.Errorf("unsupported sort field: %s", sort)
+	}
+	if pageMarker == constants.NONE {
+		pageMarker = ""
+	}
+	if baseUrl == constants.NONE {
+		baseUrl = ""
+	}
+	params := url.Values{}
+	if sort != "" && sort != constants.NONE {
+		params.Set("sortField", sortFields[sort])
+		if desc {
+			params.Set("sortDirection", "desc")
+		} else {
+			params.Set("sortDirection", "asc")
+		}
+	}
+	return usite.getTorrents(baseUrl, params, pageMarker)
 }
 
 func (usite *Site) GetLatestTorrents(full bool) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	torrents, _, err := usite.getTorrents("", url.Values{
+		"sortField":     {"created_at"},
+		"sortDirection": {"desc"},
+	}, "")
+	return torrents, err
 }
 
 func (usite *Site) SearchTorrents(keyword string, baseUrl string) ([]*site.Torrent, error) {
-	return nil, site.ErrUnimplemented
+	torrents, _, err := usite.getTorrents(baseUrl, url.Values{"name": {keyword}}, "")
+	return torrents, err
 }
 
 func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename string, id string, err error) {
@@ -123,13 +216,70 @@ func (usite *Site) DownloadTorrent(torrentUrl string) (content []byte, filename
 }
 
 func (usite *Site) DownloadTorrentById(id string) ([]byte, string, error) {
-	torrentUrl := usite.SiteConfig.Url + "torrents/download/" + id
-	return site.DownloadTorrentByUrl(usite, usite.HttpClient, torrentUrl, id)
+	return site.DownloadTorrentByUrl(usite, usite.HttpClient, usite.getDownloadUrl(id), id)
+}
+
+// Return the download url of torrent. Use the rss key download url if passkey is configured.
+func (usite *Site) getDownloadUrl(id string) string {
+	if usite.SiteConfig.TorrentDownloadUrl != "" {
+		return usite.SiteConfig.ParseSiteUrl(strings.ReplaceAll(usite.SiteConfig.TorrentDownloadUrl, "{id}", id), false)
+	}
+	if usite.SiteConfig.Passkey != "" {
+		return usite.SiteConfig.ParseSiteUrl("torrent/download/"+id+"."+usite.SiteConfig.Passkey, false)
+	}
+	return usite.SiteConfig.ParseSiteUrl("torrents/download/"+id, false)
+}
+
+// Get torrents from api (if apiKey is configured) or torrents list page.
+func (usite *Site) getTorrents(baseUrl string, params url.Values, pageMarker string) (
+	torrents []*site.Torrent, nextPageMarker string, err error) {
+	if baseUrl == "" {
+		baseUrl = usite.SiteConfig.TorrentsUrl
+	}
+	if usite.SiteConfig.ApiKey != "" {
+		if baseUrl != "" {
+			if urlObj, err := url.Parse(baseUrl); err == nil {
+				for key, values := range urlObj.Query() {
+					if params.Get(key) == "" {
+						params[key] = values
+					}
+				}
+			}
+		}
+		return usite.getApiTorrents(params, pageMarker)
+	}
+	page := int64(1)
+	if pageMarker != "" {
+		page = util.ParseInt(pageMarker)
+	}
+	params.Set("page", fmt.Sprint(page))
+	params.Set("perPage", fmt.Sprint(PAGE_SIZE))
+	doc, err := usite.getDoc(usite.torrentsPageUrl(baseUrl, params))
+	if err != nil {
+		return nil, "", err
+	}
+	torrents = usite.parseTorrents(doc)
+	if len(torrents) > 0 {
+		nextPageMarker = fmt.Sprint(page + 1)
+	}
+	return torrents, nextPageMarker, nil
+}
+
+func (usite *Site) getDoc(pageUrl string) (*goquery.Document, error) {
+	doc, res, err := util.GetUrlDocWithAzuretls(pageUrl, usite.HttpClient,
+		usite.SiteConfig.Cookie, site.GetUa(usite), usite.GetDefaultHttpHeaders())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch site page dom: %w", err)
+	}
+	if strings.Contains(res.Request.Url, "/login") {
+		return nil, site.ErrNotLogined
+	}
+	return doc, nil
 }
 
 func NewSite(name string, siteConfig *config.SiteConfigStruct, config *config.ConfigStruct) (site.Site, error) {
-	if siteConfig.Cookie == "" {
-		log.Warnf("Site %s has no cookie provided", name)
+	if siteConfig.Cookie == "" && siteConfig.ApiKey == "" {
+		log.Warnf("Site %s has no cookie or apiKey provided", name)
 	}
 	location, err := time.LoadLocation(siteConfig.GetTimezone())
 	if err != nil {
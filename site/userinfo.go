@@ -0,0 +1,95 @@
+package site
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/util"
+)
+
+var (
+	unreadMessagesRegexps = []*regexp.Regexp{
+		// e.g. "你有 3 条新短讯", "You have 3 new messages"
+		regexp.MustCompile(
+			`(?i)(?P<n>\d+)\s*(条|條|封)?\s*(新短讯|新短訊|新消息|新私信|新信件|new (private )?messages?)`),
+		// the inbox in NexusPHP user info bar, e.g. "12 (3 新)"
+		regexp.MustCompile(`(?i)\(\s*(?P<n>\d+)\s*(新|new)\s*\)`),
+	}
+	// e.g. "等级: VIP", "Class: Power User"
+	userClassRegexp = regexp.MustCompile(
+		`(?i:用户等级|用戶等級|等级|等級|\bclass)\s*[：:]\s*(?P<s>[^\s:：|]+( [A-Z][a-z][^\s:：|]*)*)`)
+	// e.g. "H&R: 2", "H&R: [2/5]" (the pending count comes first)
+	hnrRegexp    = regexp.MustCompile(`(?i)\b(H&R|HnR)\s*[：:]?\s*\[?\s*(?P<n>\d+)`)
+	numberRegexp = regexp.MustCompile(`\d+`)
+)
+
+// Parse the user info of doc (site page) using the "selectorUserInfoUserClass", "selectorUserInfoMessages",
+// "selectorUserInfoWarned" and "selectorUserInfoHnR" selectors of site config. Unset selectors are skipped.
+// The first number of the messages or HnR element text is used as the count.
+func (status *Status) ParseUserInfoDom(doc *goquery.Selection, siteConfig *config.SiteConfigStruct) {
+	if siteConfig.SelectorUserInfoUserClass != "" {
+		status.UserClass = util.DomSelectorText(doc, siteConfig.SelectorUserInfoUserClass)
+	}
+	if siteConfig.SelectorUserInfoMessages != "" {
+		status.UnreadMessages = util.ParseInt(
+			numberRegexp.FindString(util.DomSelectorText(doc, siteConfig.SelectorUserInfoMessages)))
+	}
+	if siteConfig.SelectorUserInfoWarned != "" {
+		status.Warned = doc.Find(siteConfig.SelectorUserInfoWarned).Length() > 0
+	}
+	if siteConfig.SelectorUserInfoHnR != "" {
+		status.HnRs = util.ParseInt(numberRegexp.FindString(util.DomSelectorText(doc, siteConfig.SelectorUserInfoHnR)))
+	}
+}
+
+// Parse the user info text (e.g. the user info bar text of site page) and set the found user class,
+// unread messages count and HnR count of status. The already set (non-zero) fields are not changed.
+func (status *Status) ParseUserInfoText(text string) {
+	if status.UnreadMessages == 0 {
+		for _, re := range unreadMessagesRegexps {
+			if m := re.FindStringSubmatch(text); m != nil {
+				status.UnreadMessages = util.ParseInt(m[re.SubexpIndex("n")])
+				break
+			}
+		}
+	}
+	if status.UserClass == "" {
+		if m := userClassRegexp.FindStringSubmatch(text); m != nil {
+			status.UserClass = m[userClassRegexp.SubexpIndex("s")]
+		}
+	}
+	if status.HnRs == 0 {
+		if m := hnrRegexp.FindStringSubmatch(text); m != nil {
+			status.HnRs = util.ParseInt(m[hnrRegexp.SubexpIndex("n")])
+		}
+	}
+}
+
+// Return the user info summary of status, e.g. "Class: Power User; PM: 3; Warned; H&R: 2 (deadline: 1d2h)".
+// Return empty string if no user info is available.
+func (status *Status) UserInfo() string {
+	var infos []string
+	if status.UserClass != "" {
+		infos = append(infos, "Class: "+status.UserClass)
+	}
+	if status.UnreadMessages > 0 {
+		infos = append(infos, fmt.Sprintf("PM: %d", status.UnreadMessages))
+	}
+	if status.Warned {
+		infos = append(infos, "Warned")
+	}
+	if status.HnRs > 0 {
+		info := fmt.Sprintf("H&R: %d", status.HnRs)
+		if left := status.HnRDeadline - util.Now(); status.HnRDeadline > 0 && left > 0 {
+			info += fmt.Sprintf(" (deadline: %s)", util.FormatDuration(left))
+		} else if status.HnRDeadline > 0 {
+			info += " (overdue)"
+		}
+		infos = append(infos, info)
+	}
+	return strings.Join(infos, "; ")
+}
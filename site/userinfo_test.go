@@ -0,0 +1,75 @@
+package site_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/site"
+)
+
+func TestParseUserInfoText(t *testing.T) {
+	tests := []struct {
+		text           string
+		unreadMessages int64
+		userClass      string
+		hnrs           int64
+	}{
+		{"你有 3 条新短讯！点击查看", 3, "", 0},
+		{"你有3條新短訊", 3, "", 0},
+		{"You have 2 new messages", 2, "", 0},
+		{"You have 1 new private message", 1, "", 0},
+		{"收件箱 12 (3 新)", 3, "", 0},
+		{"Inbox 12 (4 New)", 4, "", 0},
+		{"等级: VIP 魔力值: 1,234", 0, "VIP", 0},
+		{"用户等级：精英", 0, "精英", 0},
+		{"Class: Power User | Bonus: 12", 0, "Power User", 0},
+		{"classic: no", 0, "", 0},
+		{"H&R: 2", 0, "", 2},
+		{"H&R: [2/5]", 0, "", 2},
+		{"HnR 3", 0, "", 3},
+		{"Class: Elite User H&R: [1/3] 你有 5 条新短讯", 5, "Elite User", 1},
+		{"分享率: 1.5 上传量: 1 TB", 0, "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			status := &site.Status{}
+			status.ParseUserInfoText(test.text)
+			if status.UnreadMessages != test.unreadMessages {
+				t.Errorf("unread messages: expected %d, got %d", test.unreadMessages, status.UnreadMessages)
+			}
+			if status.UserClass != test.userClass {
+				t.Errorf("user class: expected %q, got %q", test.userClass, status.UserClass)
+			}
+			if status.HnRs != test.hnrs {
+				t.Errorf("H&Rs: expected %d, got %d", test.hnrs, status.HnRs)
+			}
+		})
+	}
+}
+
+func TestParseUserInfoTextKeepsSetFields(t *testing.T) {
+	status := &site.Status{UnreadMessages: 1, UserClass: "User", HnRs: 1}
+	status.ParseUserInfoText("Class: VIP H&R: 5 你有 9 条新短讯")
+	if status.UnreadMessages != 1 || status.UserClass != "User" || status.HnRs != 1 {
+		t.Errorf("set fields are changed: %+v", status)
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   *site.Status
+		expected string
+	}{
+		{"empty", &site.Status{}, ""},
+		{"all", &site.Status{UserClass: "Power User", UnreadMessages: 3, Warned: true, HnRs: 2},
+			"Class: Power User; PM: 3; Warned; H&R: 2"},
+		{"overdue", &site.Status{HnRs: 1, HnRDeadline: 1}, "H&R: 1 (overdue)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.status.UserInfo(); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
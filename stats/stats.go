@@ -16,6 +16,12 @@ import (
 	"github.com/sagan/ptool/util"
 )
 
+// Types of stat records.
+const (
+	EVENT_TORRENT_DELETE = int64(1) // brush torrent deleted from client
+	EVENT_BONUS_EXCHANGE = int64(2) // site bonus points exchanged
+)
+
 type TorrentTraffic struct {
 	Client     string `gorm:"primaryKey"`
 	Day        string `gorm:"primaryKey"`
@@ -36,10 +42,22 @@ type TorrentStat struct {
 	Downloaded int64  `json:"downloaded"`
 	Msg        string `json:"msg"`
 }
+
+// A site bonus points exchange.
+type BonusExchangeStat struct {
+	Site   string  `json:"site"`
+	Option string  `json:"option"` // name of exchange option, e.g. "10.0 GB上传量"
+	Type   string  `json:"type"`   // "upload", "invite" or ""
+	Value  int64   `json:"value"`
+	Price  float64 `json:"price"`
+	Bonus  float64 `json:"bonus"` // bonus points before exchanging
+}
+
 type Stat struct {
-	Ts    int64        `json:"ts"`
-	Event int64        `json:"event"`
-	Data  *TorrentStat `json:"data"`
+	Ts       int64              `json:"ts"`
+	Event    int64              `json:"event"`
+	Data     *TorrentStat       `json:"data,omitempty"`
+	Exchange *BonusExchangeStat `json:"exchange,omitempty"`
 }
 type Statistics struct {
 	Downloaded int64
@@ -50,6 +68,7 @@ type StatDb struct {
 	file         *os.File
 	mu           sync.Mutex
 	sqldb        *gorm.DB
+	exchanges    []*Stat
 }
 
 func (db *StatDb) AddTorrentStats(ts int64, event int64, torrentStats []*TorrentStat) {
@@ -64,6 +83,44 @@ func (db *StatDb) AddTorrentStats(ts int64, event int64, torrentStats []*Torrent
 	}
 }
 
+func (db *StatDb) AddBonusExchangeStat(ts int64, exchangeStat *BonusExchangeStat) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	stat := &Stat{
+		Ts:       ts,
+		Event:    EVENT_BONUS_EXCHANGE,
+		Exchange: exchangeStat,
+	}
+	json.NewEncoder(db.file).Encode(stat)
+	db.exchanges = append(db.exchanges, stat)
+}
+
+// Return bonus points exchange records of site, in time order. If site is empty, return records of all sites.
+func (db *StatDb) GetBonusExchangeStats(site string) []*Stat {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return util.Filter(db.exchanges, func(stat *Stat) bool {
+		return site == "" || stat.Exchange.Site == site
+	})
+}
+
+// Return the brush traffic of site since the day (e.g. "2024-01-01", inclusive).
+// If client is empty, return the traffic of all clients. If startday is empty, return all time traffic.
+func (db *StatDb) GetSiteTraffic(client string, site string, startday string) *Statistics {
+	statistics := &Statistics{}
+	tx := db.sqldb.Table("torrent_traffics").
+		Select("ifnull(sum(downloaded),0) as downloaded", "ifnull(sum(uploaded),0) as uploaded").
+		Where("site = ?", site)
+	if client != "" {
+		tx = tx.Where("client = ?", client)
+	}
+	if startday != "" {
+		tx = tx.Where("day >= ?", startday)
+	}
+	tx.Find(statistics)
+	return statistics
+}
+
 func (db *StatDb) ShowTrafficStats(client string) {
 	now := util.Now()
 	today := util.FormatDate(now)
@@ -216,7 +273,11 @@ func NewDb(statFilename string) (*StatDb, error) {
 	for fileScanner.Scan() {
 		statRecord := Stat{}
 		err := json.Unmarshal([]byte(fileScanner.Text()), &statRecord)
-		if err != nil || statRecord.Event != 1 {
+		if err == nil && statRecord.Event == EVENT_BONUS_EXCHANGE && statRecord.Exchange != nil {
+			db.exchanges = append(db.exchanges, &statRecord)
+			continue
+		}
+		if err != nil || statRecord.Event != EVENT_TORRENT_DELETE || statRecord.Data == nil {
 			continue
 		}
 		timespan := statRecord.Ts - statRecord.Data.Atime
@@ -0,0 +1,138 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const SFTP_DIAL_TIMEOUT = 30 * time.Second
+
+// SFTP target. It's resumable.
+type sftpTarget struct {
+	root      string // root path in remote server
+	sshClient *ssh.Client
+	client    *sftp.Client
+}
+
+func newSftpTarget(u *url.URL) (*sftpTarget, error) {
+	config := &ssh.ClientConfig{
+		Timeout: SFTP_DIAL_TIMEOUT,
+	}
+	if u.User != nil {
+		config.User = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			config.Auth = append(config.Auth, ssh.Password(password))
+		}
+	}
+	if keyFile := u.Query().Get("key"); keyFile != "" {
+		if strings.HasPrefix(keyFile, "~/") {
+			home, _ := os.UserHomeDir()
+			keyFile = filepath.Join(home, keyFile[2:])
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key file: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if len(config.Auth) == 0 {
+		return nil, fmt.Errorf("no password or key file provided")
+	}
+	if u.Query().Get("insecure") == "1" {
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		home, _ := os.UserHomeDir()
+		callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known_hosts (use insecure=1 to skip host key check): %w", err)
+		}
+		config.HostKeyCallback = callback
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+	sshClient, err := ssh.Dial("tcp", host, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp session: %w", err)
+	}
+	root := u.Path
+	if root == "" {
+		root = "."
+	} else if strings.HasPrefix(root, "/~/") {
+		// sftp://host/~/path : relative to home dir
+		root = root[3:]
+	}
+	return &sftpTarget{root: root, sshClient: sshClient, client: client}, nil
+}
+
+func (target *sftpTarget) path(name string) string {
+	return path.Join(target.root, name)
+}
+
+func (target *sftpTarget) Stat(name string) (int64, error) {
+	stat, err := target.client.Stat(target.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return -1, nil
+		}
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+func (target *sftpTarget) Write(name string, offset int64, reader io.Reader, size int64) error {
+	filename := target.path(name)
+	if err := target.client.MkdirAll(path.Dir(filename)); err != nil {
+		return fmt.Errorf("failed to create dir: %w", err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	f, err := target.client.OpenFile(filename, flag)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = f.ReadFrom(io.LimitReader(reader, size)); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (target *sftpTarget) Open(name string) (io.ReadCloser, error) {
+	return target.client.Open(target.path(name))
+}
+
+func (target *sftpTarget) Resumable() bool {
+	return true
+}
+
+func (target *sftpTarget) Close() error {
+	target.client.Close()
+	return target.sshClient.Close()
+}
@@ -0,0 +1,148 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// Timeout of connecting (including TLS handshake) to WebDAV server
+	WEBDAV_DIAL_TIMEOUT = 30 * time.Second
+	// Timeout of waiting for the response headers after the request (including body) is fully written.
+	// The data transfer of a request itself is not time limited, as the file may be large.
+	WEBDAV_RESPONSE_TIMEOUT = 5 * time.Minute
+)
+
+// WebDAV target. It uses plain http PUT / GET / HEAD / MKCOL requests. Standard WebDAV does not support
+// partial PUT, so it's not resumable.
+type webdavTarget struct {
+	baseUrl  *url.URL // http(s)://host/path
+	username string
+	password string
+	client   *http.Client
+	dirs     map[string]bool // created dirs
+}
+
+func newWebdavTarget(u *url.URL) (*webdavTarget, error) {
+	target := &webdavTarget{
+		baseUrl: &url.URL{Scheme: "http", Host: u.Host, Path: strings.TrimSuffix(u.Path, "/")},
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: WEBDAV_DIAL_TIMEOUT}).DialContext,
+				TLSHandshakeTimeout:   WEBDAV_DIAL_TIMEOUT,
+				ResponseHeaderTimeout: WEBDAV_RESPONSE_TIMEOUT,
+				IdleConnTimeout:       90 * time.Second,
+			},
+		},
+		dirs: map[string]bool{},
+	}
+	if u.Scheme == "webdavs" {
+		target.baseUrl.Scheme = "https"
+	}
+	if u.User != nil {
+		target.username = u.User.Username()
+		target.password, _ = u.User.Password()
+	}
+	return target, nil
+}
+
+func (target *webdavTarget) request(method string, name string, body io.Reader, size int64) (*http.Response, error) {
+	u := *target.baseUrl
+	u.Path = path.Join("/", u.Path, name)
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if target.username != "" || target.password != "" {
+		req.SetBasicAuth(target.username, target.password)
+	}
+	return target.client.Do(req)
+}
+
+func (target *webdavTarget) Stat(name string) (int64, error) {
+	res, err := target.request(http.MethodHead, name, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return -1, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HEAD status=%d", res.StatusCode)
+	}
+	return res.ContentLength, nil
+}
+
+func (target *webdavTarget) Write(name string, offset int64, reader io.Reader, size int64) error {
+	if offset != 0 {
+		return fmt.Errorf("webdav target does not support resuming")
+	}
+	if err := target.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+	// the body is closed by http client, wrap it so that the reader is not closed
+	res, err := target.request(http.MethodPut, name, io.LimitReader(reader, size), size)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated &&
+		res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("PUT status=%d", res.StatusCode)
+	}
+	return nil
+}
+
+// Create dir and it's parents. Existing dirs are ignored.
+func (target *webdavTarget) mkdirAll(dir string) error {
+	if dir == "." || dir == "/" || dir == "" || target.dirs[dir] {
+		return nil
+	}
+	if err := target.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	res, err := target.request("MKCOL", dir, nil, 0)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	// 405: already exists
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusMethodNotAllowed &&
+		res.StatusCode != http.StatusOK {
+		return fmt.Errorf("MKCOL %s status=%d", dir, res.StatusCode)
+	}
+	target.dirs[dir] = true
+	return nil
+}
+
+func (target *webdavTarget) Open(name string) (io.ReadCloser, error) {
+	res, err := target.request(http.MethodGet, name, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET status=%d", res.StatusCode)
+	}
+	return res.Body, nil
+}
+
+func (target *webdavTarget) Resumable() bool {
+	return false
+}
+
+func (target *webdavTarget) Close() error {
+	target.client.CloseIdleConnections()
+	return nil
+}
@@ -44,6 +44,7 @@ type AdvancedConfig struct {
 	HTTPTimeout time.Duration
 	UserAgent   string
 	Debug       bool
+	Transport   http.RoundTripper // if set, it's used as the transport of http client
 }
 
 // New returns an initialized and ready to use Controller
@@ -96,6 +97,9 @@ func New(host, user, password string, conf *AdvancedConfig) (c *Client, err erro
 		debug:     conf.Debug,
 	}
 	c.httpC.Timeout = conf.HTTPTimeout
+	if conf.Transport != nil {
+		c.httpC.Transport = conf.Transport
+	}
 	return
 }
 
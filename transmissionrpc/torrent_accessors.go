@@ -60,6 +60,27 @@ func (c *Client) TorrentGetHashes(ctx context.Context, fields []string, hashes [
 	return c.torrentGetHash(ctx, fields, hashes)
 }
 
+// TorrentGetRecentlyActive returns the given of fields (mandatory) for torrents that have been active
+// in the last 60 seconds (including newly added ones), and the ids of recently removed torrents.
+// https://github.com/transmission/transmission/blob/3.00/extras/rpc-spec.txt#L114
+func (c *Client) TorrentGetRecentlyActive(ctx context.Context, fields []string) (torrents []Torrent,
+	removed []int64, err error) {
+	if err = c.validateTorrentFields(fields); err != nil {
+		return
+	}
+	var result torrentGetResults
+	if err = c.rpcCall(ctx, "torrent-get", &torrentGetRecentlyActiveParams{
+		Fields: fields,
+		IDs:    "recently-active",
+	}, &result); err != nil {
+		err = fmt.Errorf("'torrent-get' rpc method failed: %w", err)
+		return
+	}
+	torrents = result.Torrents
+	removed = result.Removed
+	return
+}
+
 func (c *Client) validateTorrentFields(fields []string) (err error) {
 	// Validate fields
 	var fieldInvalid bool
@@ -116,8 +137,14 @@ type torrentGetHashParams struct {
 	Hashes []string `json:"ids,omitempty"`
 }
 
+type torrentGetRecentlyActiveParams struct {
+	Fields []string `json:"fields"`
+	IDs    string   `json:"ids"`
+}
+
 type torrentGetResults struct {
 	Torrents []Torrent `json:"torrents"`
+	Removed  []int64   `json:"removed"` // only for "recently-active" request
 }
 
 // Torrent represents all the possible fields of data for a torrent.
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Prefix of encrypted secret string.
+const SECRET_PREFIX = "enc:v1:"
+
+const (
+	secretSaltLen = 16
+	// scrypt parameters recommended for interactive logins (2017)
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// all secrets encrypted in current process share the same salt (thus the same key), while each uses
+	// it's own random nonce. It makes decryption much faster as key derivation (scrypt) is slow by design.
+	secretSalt     []byte
+	secretSaltErr  error
+	secretSaltOnce sync.Once
+	secretAeads    = map[string]cipher.AEAD{} // password + salt => aead
+	secretMu       sync.Mutex
+)
+
+// Return true if str is a secret encrypted by EncryptSecret.
+func IsEncryptedSecret(str string) bool {
+	return strings.HasPrefix(str, SECRET_PREFIX)
+}
+
+// Encrypt plaintext using AES-256-GCM, with key derived from password using scrypt.
+// Return "enc:v1:" + base64 of [16 bytes salt] + [12 bytes random nonce] + [ciphertext].
+func EncryptSecret(password string, plaintext string) (string, error) {
+	secretSaltOnce.Do(func() {
+		secretSalt = make([]byte, secretSaltLen)
+		_, secretSaltErr = rand.Read(secretSalt)
+	})
+	if secretSaltErr != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", secretSaltErr)
+	}
+	salt := secretSalt
+	aead, err := newSecretAead(password, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(append([]byte{}, salt...), nonce...)
+	data = aead.Seal(data, nonce, []byte(plaintext), nil)
+	return SECRET_PREFIX + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decrypt a secret encrypted by EncryptSecret.
+func DecryptSecret(password string, secret string) (string, error) {
+	if !IsEncryptedSecret(secret) {
+		return "", fmt.Errorf("invalid secret: no %q prefix", SECRET_PREFIX)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(secret[len(SECRET_PREFIX):])
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	if len(data) < secretSaltLen {
+		return "", fmt.Errorf("invalid secret: too short")
+	}
+	aead, err := newSecretAead(password, data[:secretSaltLen])
+	if err != nil {
+		return "", err
+	}
+	data = data[secretSaltLen:]
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid secret: too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret (wrong password?): %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newSecretAead(password string, salt []byte) (cipher.AEAD, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	cacheKey := password + "\x00" + string(salt)
+	if aead := secretAeads[cacheKey]; aead != nil {
+		return aead, nil
+	}
+	key, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, aes256KeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	secretAeads[cacheKey] = aead
+	return aead, nil
+}
@@ -0,0 +1,113 @@
+package crypto_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/util/crypto"
+)
+
+const testPassword = "correct horse battery staple"
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"cookie", "c_secure_uid=MTIz; c_secure_pass=abcdef"},
+		{"passkey", "0123456789abcdef0123456789abcdef"},
+		{"unicode", "密码 パスワード"},
+		{"empty", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secret, err := crypto.EncryptSecret(testPassword, test.plaintext)
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+			if !crypto.IsEncryptedSecret(secret) {
+				t.Errorf("expected %q prefix, got %q", crypto.SECRET_PREFIX, secret)
+			}
+			if test.plaintext != "" && strings.Contains(secret, test.plaintext) {
+				t.Errorf("secret %q contains plaintext", secret)
+			}
+			plaintext, err := crypto.DecryptSecret(testPassword, secret)
+			if err != nil {
+				t.Fatalf("failed to decrypt: %v", err)
+			}
+			if plaintext != test.plaintext {
+				t.Errorf("expected %q, got %q", test.plaintext, plaintext)
+			}
+			// each secret uses it's own random nonce
+			if secret2, err := crypto.EncryptSecret(testPassword, test.plaintext); err != nil {
+				t.Errorf("failed to encrypt again: %v", err)
+			} else if secret2 == secret {
+				t.Errorf("expected different secrets of the same plaintext, got %q twice", secret)
+			}
+		})
+	}
+}
+
+func TestDecryptSecretErrors(t *testing.T) {
+	secret, err := crypto.EncryptSecret(testPassword, "passkey")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(secret, crypto.SECRET_PREFIX))
+	if err != nil {
+		t.Fatalf("invalid secret %q: %v", secret, err)
+	}
+	// flip a bit of salt, nonce or ciphertext (including the GCM tag)
+	tamper := func(index int) string {
+		tampered := append([]byte{}, data...)
+		tampered[index] ^= 0x01
+		return crypto.SECRET_PREFIX + base64.RawURLEncoding.EncodeToString(tampered)
+	}
+	tests := []struct {
+		name     string
+		password string
+		secret   string
+	}{
+		{"wrong password", "wrong password", secret},
+		{"empty password", "", secret},
+		{"tampered salt", testPassword, tamper(0)},
+		{"tampered nonce", testPassword, tamper(16)},
+		{"tampered ciphertext", testPassword, tamper(16 + 12)},
+		{"tampered tag", testPassword, tamper(len(data) - 1)},
+		{"truncated", testPassword, secret[:len(secret)-4]},
+		{"too short", testPassword, crypto.SECRET_PREFIX + base64.RawURLEncoding.EncodeToString(data[:20])},
+		{"not base64", testPassword, crypto.SECRET_PREFIX + "!!!"},
+		{"no prefix", testPassword, strings.TrimPrefix(secret, crypto.SECRET_PREFIX)},
+		{"empty", testPassword, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if plaintext, err := crypto.DecryptSecret(test.password, test.secret); err == nil {
+				t.Errorf("expected error, got %q", plaintext)
+			}
+		})
+	}
+}
+
+func TestIsEncryptedSecret(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected bool
+	}{
+		{"enc:v1:abc", true},
+		{"enc:v1:", true},
+		{"enc:v2:abc", false},
+		{"ENC:V1:abc", false},
+		{" enc:v1:abc", false},
+		{"cookie=enc:v1:abc", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			if result := crypto.IsEncryptedSecret(test.str); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
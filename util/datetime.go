@@ -180,3 +180,51 @@ func ParseTimeDuration(str string) (int64, error) {
 	}
 	return 0, error
 }
+
+// A daily time range, in minutes of day: [Start, End). If End <= Start, the range spans midnight.
+type DailyTimeRange struct {
+	Start int64
+	End   int64
+}
+
+// Parse comma-separated daily time ranges, e.g. "23:00-07:00,12:00-13:30".
+func ParseDailyTimeRanges(str string) ([]*DailyTimeRange, error) {
+	var ranges []*DailyTimeRange
+	for _, rangeStr := range SplitCsv(str) {
+		startStr, endStr, found := strings.Cut(rangeStr, "-")
+		if !found {
+			return nil, fmt.Errorf("invalid time range %q: must be in format 'HH:mm-HH:mm'", rangeStr)
+		}
+		start, err := parseMinutesOfDay(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time range %q: %w", rangeStr, err)
+		}
+		end, err := parseMinutesOfDay(endStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time range %q: %w", rangeStr, err)
+		}
+		ranges = append(ranges, &DailyTimeRange{Start: start, End: end})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no time range")
+	}
+	return ranges, nil
+}
+
+// Parse "HH:mm" (24-hour) to minutes of day.
+func parseMinutesOfDay(str string) (int64, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(str))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", str)
+	}
+	return int64(t.Hour()*60 + t.Minute()), nil
+}
+
+// Return true if t (in it's location) is in the time range.
+func (r *DailyTimeRange) Contains(t time.Time) bool {
+	minutes := int64(t.Hour()*60 + t.Minute())
+	if r.Start < r.End {
+		return minutes >= r.Start && minutes < r.End
+	}
+	return minutes >= r.Start || minutes < r.End
+}
@@ -0,0 +1,80 @@
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+func TestParseDailyTimeRanges(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected []util.DailyTimeRange
+		wantErr  bool
+	}{
+		{"23:00-07:00", []util.DailyTimeRange{{Start: 23 * 60, End: 7 * 60}}, false},
+		{"12:00-13:30, 0:05-1:00",
+			[]util.DailyTimeRange{{Start: 12 * 60, End: 13*60 + 30}, {Start: 5, End: 60}}, false},
+		{" 08:00 - 09:00 ", []util.DailyTimeRange{{Start: 8 * 60, End: 9 * 60}}, false},
+		{"00:00-23:59", []util.DailyTimeRange{{Start: 0, End: 23*60 + 59}}, false},
+		{"", nil, true},
+		{"23:00", nil, true},
+		{"23:00-24:00", nil, true},
+		{"23:60-07:00", nil, true},
+		{"11pm-7am", nil, true},
+		{"12:00-13:00,abc", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			ranges, err := util.ParseDailyTimeRanges(test.str)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", ranges)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(ranges) != len(test.expected) {
+				t.Fatalf("expected %d ranges, got %d", len(test.expected), len(ranges))
+			}
+			for i, r := range ranges {
+				if *r != test.expected[i] {
+					t.Errorf("range %d: expected %v, got %v", i, test.expected[i], *r)
+				}
+			}
+		})
+	}
+}
+
+func TestDailyTimeRangeContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        util.DailyTimeRange
+		time     string
+		expected bool
+	}{
+		{"in range", util.DailyTimeRange{Start: 12 * 60, End: 13 * 60}, "12:30", true},
+		{"start inclusive", util.DailyTimeRange{Start: 12 * 60, End: 13 * 60}, "12:00", true},
+		{"end exclusive", util.DailyTimeRange{Start: 12 * 60, End: 13 * 60}, "13:00", false},
+		{"before range", util.DailyTimeRange{Start: 12 * 60, End: 13 * 60}, "11:59", false},
+		{"overnight before midnight", util.DailyTimeRange{Start: 23 * 60, End: 7 * 60}, "23:30", true},
+		{"overnight after midnight", util.DailyTimeRange{Start: 23 * 60, End: 7 * 60}, "06:59", true},
+		{"overnight end exclusive", util.DailyTimeRange{Start: 23 * 60, End: 7 * 60}, "07:00", false},
+		{"overnight daytime", util.DailyTimeRange{Start: 23 * 60, End: 7 * 60}, "12:00", false},
+		{"whole day", util.DailyTimeRange{Start: 0, End: 0}, "15:00", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now, err := time.Parse("15:04", test.time)
+			if err != nil {
+				t.Fatalf("invalid time %q: %v", test.time, err)
+			}
+			if result := test.r.Contains(now); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The alignment of buffer address, size and file offset required by direct IO.
+const DIRECT_IO_ALIGNMENT = 4096
+
+// IO options of copying (large) files by CopyFile and other file moving operations (e.g. "ptool movedata").
+type FileCopyOptions struct {
+	// Size of read / write buffer. 0 == let kernel copy data directly between files if possible
+	// (copy_file_range / sendfile on Linux, which could also be a server side copy on network file systems),
+	// otherwise use the default 32KiB buffer. A large buffer (e.g. 4MiB) reduces the round trips of
+	// network file systems (e.g. SMB).
+	BufferSize int64
+	// Preallocate disk space of dest file (fallocate) before copying, which reduces fragmentation.
+	// Only supported on Linux. Ignored if not supported by file system.
+	Preallocate bool
+	// Read & write files with direct IO (O_DIRECT), bypassing the page cache of OS. Only supported on Linux.
+	// Fallback to normal IO if not supported by file system.
+	DirectIO bool
+}
+
+var DefaultFileCopy = FileCopyOptions{}
+
+// Current file copy options. It's set by config package from config file.
+var FileCopy = &DefaultFileCopy
+
+// A dest file of copying, created by CreateCopyDest. Writes to it with an unaligned size (the tail of file)
+// are handled in direct IO mode.
+type CopyDestFile struct {
+	*os.File
+	direct bool
+}
+
+func (f *CopyDestFile) Write(p []byte) (int, error) {
+	if f.direct && len(p)%DIRECT_IO_ALIGNMENT != 0 {
+		f.direct = false
+		if err := disableDirectIO(f.File); err != nil {
+			return 0, err
+		}
+	}
+	return f.File.Write(p)
+}
+
+// Open source file of copying for reading, using direct IO if FileCopy.DirectIO is set.
+func OpenCopySource(name string) (*os.File, error) {
+	if FileCopy.DirectIO {
+		if f, err := openDirect(name, os.O_RDONLY, 0); err == nil {
+			return f, nil
+		} else {
+			log.Debugf("Failed to open %s with direct IO, fallback to normal IO: %v", name, err)
+		}
+	}
+	return os.Open(name)
+}
+
+// Create (open) dest file of copying for writing, using direct IO if FileCopy.DirectIO is set.
+// flag is added to os.O_WRONLY|os.O_CREATE, e.g. os.O_EXCL or os.O_TRUNC.
+// If FileCopy.Preallocate is set, size bytes disk space of file is preallocated.
+func CreateCopyDest(name string, size int64, flag int, perm fs.FileMode) (*CopyDestFile, error) {
+	flag |= os.O_WRONLY | os.O_CREATE
+	var file *CopyDestFile
+	if FileCopy.DirectIO {
+		if f, err := openDirect(name, flag, perm); err == nil {
+			file = &CopyDestFile{File: f, direct: true}
+		} else {
+			log.Debugf("Failed to open %s with direct IO, fallback to normal IO: %v", name, err)
+		}
+	}
+	if file == nil {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+		file = &CopyDestFile{File: f}
+	}
+	if FileCopy.Preallocate && size > 0 {
+		if err := preallocate(file.File, size); err != nil {
+			log.Debugf("Failed to preallocate %s: %v", name, err)
+		}
+	}
+	return file, nil
+}
+
+// Copy data from r (source file) to w (dest file, or a writer of it, e.g. io.MultiWriter) using the buffer size
+// of FileCopy. r must be opened by OpenCopySource and the dest file created by CreateCopyDest if direct IO is used.
+func CopyData(w io.Writer, r io.Reader) (int64, error) {
+	if FileCopy.BufferSize <= 0 && !FileCopy.DirectIO {
+		if dest, ok := w.(*CopyDestFile); ok {
+			// use *os.File ReadFrom, which copies in kernel
+			return io.Copy(dest.File, r)
+		}
+		return io.Copy(w, r)
+	}
+	buf := alignedBuffer(max(FileCopy.BufferSize, DIRECT_IO_ALIGNMENT))
+	written := int64(0)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// Return a buffer of at least size (rounded up to DIRECT_IO_ALIGNMENT) bytes,
+// which address is aligned to DIRECT_IO_ALIGNMENT.
+func alignedBuffer(size int64) []byte {
+	size = (size + DIRECT_IO_ALIGNMENT - 1) / DIRECT_IO_ALIGNMENT * DIRECT_IO_ALIGNMENT
+	buf := make([]byte, size+DIRECT_IO_ALIGNMENT)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buf[0])) % DIRECT_IO_ALIGNMENT); remainder != 0 {
+		offset = DIRECT_IO_ALIGNMENT - remainder
+	}
+	return buf[offset : offset+int(size)]
+}
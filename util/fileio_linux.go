@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func openDirect(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag|unix.O_DIRECT, perm)
+}
+
+// Turn off direct IO of the opened file.
+func disableDirectIO(f *os.File) error {
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFL, 0)
+	if err != nil {
+		return err
+	}
+	_, err = unix.FcntlInt(f.Fd(), unix.F_SETFL, flags&^unix.O_DIRECT)
+	return err
+}
+
+func preallocate(f *os.File, size int64) error {
+	return unix.Fallocate(int(f.Fd()), 0, 0, size)
+}
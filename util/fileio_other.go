@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Placeholder. Direct IO is only supported on Linux for now.
+func openDirect(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func disableDirectIO(f *os.File) error {
+	return nil
+}
+
+// Placeholder. Preallocation is only supported on Linux for now.
+func preallocate(f *os.File, size int64) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,392 @@
+// A small filter expression language, which is used to select items (e.g. client torrents) by their fields.
+//
+// Syntax examples:
+//
+//	ratio < 1 && size > 10GiB && tracker =~ "hdsky"
+//	(state == seeding || state == paused) && !(tags == "keep")
+//	added < 30d && category != "_brush"
+//
+// Operators: "||", "&&", "!", "(...)", and comparisons: "==" (or "="), "!=", "<", "<=", ">", ">=",
+// "=~" (matches regexp), "!~" (does NOT match regexp). A bare field name evaluates a bool field.
+// Values could be quoted ("..." or '...') or unquoted words. The type of value is determined by the field:
+//   - String: case-insensitive equality, or regexp matching.
+//   - StringList: "==" / "!=" tests whether the list contains (any) element equals to value (case-insensitive);
+//     "=~" / "!~" tests whether any element matches the regexp.
+//   - Number: an int or float number.
+//   - Size: a size string, e.g. "10GiB", "500M", "1024".
+//   - Time: a time string, e.g. "2024-01-01", or a time duration (e.g. "5d", references a past time point from now).
+//     "added < 5d" means added earlier than 5 days ago.
+//   - Bool: "true" or "false".
+package filterexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+type FieldType int
+
+const (
+	String FieldType = iota
+	StringList
+	Number
+	Size
+	Time
+	Bool
+)
+
+// Name => type of all available fields.
+type Schema map[string]FieldType
+
+// Get value of field name of the item. Returned value type must match the FieldType of field in schema:
+// String: string; StringList: []string; Number: float64 or int64; Size & Time: int64; Bool: bool.
+type Getter func(name string) any
+
+type Expr struct {
+	src  string
+	root node
+}
+
+type node interface {
+	eval(get Getter) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ child node }
+type boolNode struct {
+	field string
+	value bool // bool field == value
+}
+type stringNode struct {
+	field  string
+	op     string
+	value  string
+	regexp *regexp.Regexp
+	list   bool // the field is a StringList
+}
+type numberNode struct {
+	field string
+	op    string
+	value float64
+}
+
+func (n *andNode) eval(get Getter) bool { return n.left.eval(get) && n.right.eval(get) }
+func (n *orNode) eval(get Getter) bool  { return n.left.eval(get) || n.right.eval(get) }
+func (n *notNode) eval(get Getter) bool { return !n.child.eval(get) }
+
+func (n *boolNode) eval(get Getter) bool {
+	v, _ := get(n.field).(bool)
+	return v == n.value
+}
+
+func (n *stringNode) eval(get Getter) bool {
+	var values []string
+	if n.list {
+		values, _ = get(n.field).([]string)
+	} else {
+		v, _ := get(n.field).(string)
+		values = []string{v}
+	}
+	matched := false
+	for _, v := range values {
+		if n.regexp != nil && n.regexp.MatchString(v) || n.regexp == nil && strings.EqualFold(v, n.value) {
+			matched = true
+			break
+		}
+	}
+	if n.op == "!=" || n.op == "!~" {
+		return !matched
+	}
+	return matched
+}
+
+func (n *numberNode) eval(get Getter) bool {
+	var v float64
+	switch value := get(n.field).(type) {
+	case int64:
+		v = float64(value)
+	case float64:
+		v = value
+	case int:
+		v = float64(value)
+	}
+	switch n.op {
+	case "==":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "<":
+		return v < n.value
+	case "<=":
+		return v <= n.value
+	case ">":
+		return v > n.value
+	case ">=":
+		return v >= n.value
+	}
+	return false
+}
+
+// Compile source expression using schema.
+func Compile(src string, schema Schema) (*Expr, error) {
+	return CompileWithNow(src, schema, time.Now())
+}
+
+// Similar to Compile, but use provided now as the reference time point of time durations.
+func CompileWithNow(src string, schema Schema, now time.Time) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, schema: schema, now: now}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Test whether the item matches the expression. A nil expression matches everything.
+func (e *Expr) Match(get Getter) bool {
+	if e == nil {
+		return true
+	}
+	return e.root.eval(get)
+}
+
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota // field name or unquoted value
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "(", ")", "!", "<", ">", "="}
+
+func tokenize(src string) (tokens []token, err error) {
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+			continue
+		}
+		if c == '"' || c == '\'' {
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' && c == '"' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			text := src[i+1 : j]
+			if c == '"' {
+				if text, err = strconv.Unquote(src[i : j+1]); err != nil {
+					return nil, fmt.Errorf("invalid string at position %d: %w", i+1, err)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = j + 1
+			continue
+		}
+		found := false
+		for _, op := range operators {
+			if strings.HasPrefix(src[i:], op) {
+				tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+				i += len(op)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		j := i
+		for j < len(src) && !strings.ContainsRune(" \t\r\n\"'&|=!<>()", rune(src[j])) {
+			j++
+		}
+		tokens = append(tokens, token{kind: tokenWord, text: src[i:j], pos: i})
+		i = j
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	schema Schema
+	now    time.Time
+}
+
+func (p *parser) peekOp(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if p.peekOp("!") {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child}, nil
+	}
+	if p.peekOp("(") {
+		p.pos++
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekOp(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return child, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	fieldToken := p.tokens[p.pos]
+	if fieldToken.kind != tokenWord {
+		return nil, fmt.Errorf("expect field name at position %d, got %q", fieldToken.pos+1, fieldToken.text)
+	}
+	field := strings.ToLower(fieldToken.text)
+	fieldType, ok := p.schema[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", fieldToken.text)
+	}
+	p.pos++
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOp || p.peekOp("&&") || p.peekOp("||") ||
+		p.peekOp(")") {
+		if fieldType != Bool {
+			return nil, fmt.Errorf("field %q is not a bool field and must be compared with a value", field)
+		}
+		return &boolNode{field: field, value: true}, nil
+	}
+	op := p.tokens[p.pos].text
+	if op == "=" {
+		op = "=="
+	}
+	p.pos++
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind == tokenOp {
+		return nil, fmt.Errorf("missing value of field %q", field)
+	}
+	value := p.tokens[p.pos].text
+	p.pos++
+	switch fieldType {
+	case String, StringList:
+		n := &stringNode{field: field, op: op, value: value, list: fieldType == StringList}
+		switch op {
+		case "==", "!=":
+		case "=~", "!~":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp %q of field %q: %w", value, field, err)
+			}
+			n.regexp = re
+		default:
+			return nil, fmt.Errorf("operator %q is not supported by string field %q", op, field)
+		}
+		return n, nil
+	case Bool:
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("operator %q is not supported by bool field %q", op, field)
+		}
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value %q of field %q", value, field)
+		}
+		return &boolNode{field: field, value: v == (op == "==")}, nil
+	default:
+		if op == "=~" || op == "!~" || op == "!" {
+			return nil, fmt.Errorf("operator %q is not supported by field %q", op, field)
+		}
+		n := &numberNode{field: field, op: op}
+		switch fieldType {
+		case Number:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q of field %q", value, field)
+			}
+			n.value = v
+		case Size:
+			v, err := util.RAMInBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid size %q of field %q: %w", value, field, err)
+			}
+			n.value = float64(v)
+		case Time:
+			v, err := util.ParseTimeWithNow(value, nil, p.now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %q of field %q: %w", value, field, err)
+			}
+			n.value = float64(v)
+		}
+		return n, nil
+	}
+}
@@ -0,0 +1,78 @@
+package filterexpr_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/util/filterexpr"
+)
+
+var schema = filterexpr.Schema{
+	"name":     filterexpr.String,
+	"tags":     filterexpr.StringList,
+	"size":     filterexpr.Size,
+	"ratio":    filterexpr.Number,
+	"added":    filterexpr.Time,
+	"complete": filterexpr.Bool,
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	item := map[string]any{
+		"name":     "[HDSky] Clannad 1080p",
+		"tags":     []string{"site:hdsky", "keep"},
+		"size":     int64(20 * 1024 * 1024 * 1024),
+		"ratio":    0.5,
+		"added":    now.Unix() - 86400*10,
+		"complete": true,
+	}
+	get := func(name string) any { return item[name] }
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `ratio < 1 && size > 10GiB`, expected: true},
+		{expr: `ratio >= 1 || size > 30GiB`, expected: false},
+		{expr: `name =~ "(?i)clannad"`, expected: true},
+		{expr: `name !~ "Clannad"`, expected: false},
+		{expr: `name == "[hdsky] clannad 1080p"`, expected: true},
+		{expr: `tags == keep && !(tags == "foo")`, expected: true},
+		{expr: `tags =~ "^site:"`, expected: true},
+		{expr: `added < 5d`, expected: true},
+		{expr: `added > 5d`, expected: false},
+		{expr: `complete && ratio != 0.5`, expected: false},
+		{expr: `complete == false || (size < 1G && ratio < 1)`, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := filterexpr.CompileWithNow(test.expr, schema, now)
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+			if result := expr.Match(get); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []string{
+		``,
+		`foo == 1`,
+		`ratio =~ "1"`,
+		`name > "a"`,
+		`size > abc`,
+		`(ratio < 1`,
+		`ratio < 1 &&`,
+		`name`,
+		`name == "unterminated`,
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			if _, err := filterexpr.Compile(test, schema); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
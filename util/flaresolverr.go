@@ -0,0 +1,178 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Clearance cookies & user agent got by solving an anti-bot (e.g. Cloudflare) challenge.
+// The cookies should be merged into the original cookie of request.
+// They are only valid when used with the same user agent (and ip).
+type Clearance struct {
+	Cookie    string
+	UserAgent string
+	Expires   time.Time
+}
+
+// Anti-bot challenge solver of sites.
+type ChallengeSolver interface {
+	// Return the cached valid clearance of the site which url belongs to. Return nil if none.
+	Clearance(url string) *Clearance
+	// Solve the challenge of url and return the new clearance. cookie is the original cookie of request.
+	// stale is the clearance used by the challenged request, if the cached clearance is not stale
+	// (solved by another request meanwhile), it's returned directly without solving again.
+	// Return nil (without error) if the site which url belongs to has no solver.
+	Solve(url string, cookie string, stale *Clearance) (*Clearance, error)
+}
+
+// Set by site package.
+var SiteChallengeSolver ChallengeSolver
+
+// Return true if http response is a Cloudflare (JS / managed / turnstile) challenge page.
+func IsChallengeResponse(status int, header http.Header, body []byte) bool {
+	if status != http.StatusForbidden && status != http.StatusServiceUnavailable {
+		return false
+	}
+	if header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	if !strings.Contains(strings.ToLower(header.Get("Server")), "cloudflare") {
+		return false
+	}
+	return bytes.Contains(body, []byte("<title>Just a moment...</title>")) ||
+		bytes.Contains(body, []byte("/cdn-cgi/challenge-platform/"))
+}
+
+type flareSolverrCookie struct {
+	Name    string  `json:"name"`
+	Value   string  `json:"value"`
+	Expires float64 `json:"expires,omitempty"` // unix timestamp. -1 for session cookie
+}
+
+type flareSolverrRequest struct {
+	Cmd        string                `json:"cmd"`
+	Url        string                `json:"url"`
+	MaxTimeout int64                 `json:"maxTimeout"` // ms
+	Cookies    []*flareSolverrCookie `json:"cookies,omitempty"`
+	Proxy      *struct {
+		Url string `json:"url"`
+	} `json:"proxy,omitempty"`
+}
+
+type flareSolverrResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Solution *struct {
+		Url       string                `json:"url"`
+		Status    int                   `json:"status"`
+		Cookies   []*flareSolverrCookie `json:"cookies"`
+		UserAgent string                `json:"userAgent"`
+	} `json:"solution"`
+}
+
+// Solve the challenge of url using FlareSolverr ( https://github.com/FlareSolverr/FlareSolverr ).
+// endpoint is the FlareSolverr API url, e.g. "http://localhost:8191/v1".
+// cookie is sent to FlareSolverr along with the request. proxy (optional) is used by FlareSolverr to access url,
+// it should be the same one used by the site requests, as clearance cookies are bound to ip.
+// The returned clearance cookie only contains the (new) cookies set by site during solving.
+func SolveChallengeWithFlareSolverr(endpoint string, url string, cookie string, proxy string,
+	timeout time.Duration) (*Clearance, error) {
+	req := &flareSolverrRequest{
+		Cmd:        "request.get",
+		Url:        url,
+		MaxTimeout: timeout.Milliseconds(),
+	}
+	for _, c := range ParseCookie(cookie) {
+		req.Cookies = append(req.Cookies, &flareSolverrCookie{Name: c[0], Value: c[1]})
+	}
+	if proxy != "" {
+		req.Proxy = &struct {
+			Url string `json:"url"`
+		}{Url: proxy}
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Solve challenge of %s using FlareSolverr %s", url, endpoint)
+	// leave some time for FlareSolverr to return a timeout error itself
+	client := &http.Client{Timeout: timeout + time.Second*10}
+	res, err := client.Post(endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to request FlareSolverr: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FlareSolverr response: %w", err)
+	}
+	var data *flareSolverrResponse
+	if err := json.Unmarshal(body, &data); err != nil || data == nil {
+		return nil, fmt.Errorf("invalid FlareSolverr response (status=%d): %w", res.StatusCode, err)
+	}
+	if data.Status != "ok" || data.Solution == nil {
+		return nil, fmt.Errorf("FlareSolverr failed to solve challenge: status=%s, message=%s",
+			data.Status, data.Message)
+	}
+	// default ttl if no clearance cookie expiration is provided. Cloudflare default is 30 minutes
+	expires := time.Now().Add(time.Minute * 30)
+	cookies := [][]string{}
+	for _, c := range data.Solution.Cookies {
+		if slices.ContainsFunc(req.Cookies, func(sent *flareSolverrCookie) bool {
+			return sent.Name == c.Name && sent.Value == c.Value
+		}) {
+			continue
+		}
+		cookies = append(cookies, []string{c.Name, c.Value})
+		if c.Name == "cf_clearance" && c.Expires > 0 {
+			expires = time.Unix(int64(c.Expires), 0)
+		}
+	}
+	return &Clearance{
+		Cookie:    MergeCookie("", cookies),
+		UserAgent: data.Solution.UserAgent,
+		Expires:   expires,
+	}, nil
+}
+
+// Parse a "Cookie" http request header value to [name, value] pairs.
+func ParseCookie(cookie string) (cookies [][]string) {
+	for _, str := range strings.Split(cookie, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(str), "=")
+		if name == "" {
+			continue
+		}
+		cookies = append(cookies, []string{name, value})
+	}
+	return cookies
+}
+
+// Merge [name, value] pairs into a "Cookie" http request header value, existing cookies of same names are replaced.
+func MergeCookie(cookie string, cookies [][]string) string {
+	merged := ParseCookie(cookie)
+	for _, c := range cookies {
+		replaced := false
+		for _, m := range merged {
+			if m[0] == c[0] {
+				m[1] = c[1]
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, c)
+		}
+	}
+	strs := []string{}
+	for _, c := range merged {
+		strs = append(strs, c[0]+"="+c[1])
+	}
+	return strings.Join(strs, "; ")
+}
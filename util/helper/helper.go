@@ -37,9 +37,35 @@ var (
 	ErrGetTorrentStdoutOutputNotSupportInShell = fmt.Errorf(`"-" arg can not be used in shell`)
 )
 
+// Parse a torrent and return it's meta. The params are the same as GetTorrentContent.
+// Unlike GetTorrentContent, big local .torrent files are parsed by torrentutil.ParseTorrentFile in streaming mode,
+// which does not load the whole file (and the pieces hashes) into memory.
+// So the returned meta can be inspected and edited (and written back by tinfo.WriteFile), but not be used
+// to verify piece hashes.
+func GetTorrentMeta(torrent string, defaultSite string, forceLocal bool, forceRemote bool, stdin []byte) (
+	tinfo *torrentutil.TorrentMeta, isLocal bool, err error) {
+	isLocal = isLocalTorrent(torrent, forceLocal, forceRemote)
+	if isLocal && torrent != "-" {
+		if stat, err := os.Stat(torrent); err == nil && stat.Size() >= constants.BIG_FILE_SIZE {
+			if tinfo, err = torrentutil.ParseTorrentFile(torrent); err != nil {
+				err = fmt.Errorf("%s: failed to parse torrent: %w", torrent, err)
+			}
+			return tinfo, isLocal, err
+		}
+	}
+	_, tinfo, _, _, _, _, isLocal, err = GetTorrentContent(torrent, defaultSite, forceLocal, forceRemote, stdin,
+		false, nil)
+	return tinfo, isLocal, err
+}
+
+func isLocalTorrent(torrent string, forceLocal bool, forceRemote bool) bool {
+	return !forceRemote && (forceLocal || torrent == "-" || !util.IsUrl(torrent) && (strings.HasSuffix(
+		util.TrimAnySuffix(torrent, constants.ProcessedFilenameSuffixes...), ".torrent")))
+}
+
 // Read a torrent and return it's contents. torrent could be: local filename (e.g. abc.torrent),
 // site torrent id (e.g. mteam.1234) or url (e.g. https://kp.m-team.cc/details.php?id=488424),
-// or "-" to read torrent contents from os.Stdin.
+// or "-" to read torrent contents from os.Stdin. Site torrents of id are cached locally (see torrentutil).
 // Params:
 // forceLocal: force treat torrent as local filename. forceRemote: force treat torrent as site torrent id or url.
 // ignoreParsingError: ignore torrent parsing error, in which case the returned tinfo may by nil.
@@ -55,8 +81,7 @@ func GetTorrentContent(torrent string, defaultSite string,
 	beforeDownload func(sitename string, id string) error) (
 	content []byte, tinfo *torrentutil.TorrentMeta, siteInstance site.Site, sitename string,
 	filename string, id string, isLocal bool, err error) {
-	isLocal = !forceRemote && (forceLocal || torrent == "-" || !util.IsUrl(torrent) && (strings.HasSuffix(
-		util.TrimAnySuffix(torrent, constants.ProcessedFilenameSuffixes...), ".torrent")))
+	isLocal = isLocalTorrent(torrent, forceLocal, forceRemote)
 	// site torrent id or url
 	if !isLocal {
 		if util.IsPureTorrentUrl(torrent) {
@@ -148,6 +173,17 @@ func GetTorrentContent(torrent string, defaultSite string,
 				err = fmt.Errorf("failed to create site %s: %w", sitename, err)
 				return
 			}
+			if id != "" {
+				// the passkey of site may be changed since the torrent was cached
+				passkey := siteInstance.GetSiteConfig().Passkey
+				if content, tinfo, filename = torrentutil.LoadCachedSiteTorrent(sitename, id); content != nil &&
+					(passkey == "" || slices.ContainsFunc(tinfo.Trackers, func(tracker string) bool {
+						return strings.Contains(tracker, passkey)
+					})) {
+					return
+				}
+				content, tinfo, filename = nil, nil, ""
+			}
 			content, filename, id, err = siteInstance.DownloadTorrent(torrent)
 			siteDownloadTimeMap[sitename] = time.Now().UnixMilli()
 		}
@@ -200,6 +236,9 @@ func GetTorrentContent(torrent string, defaultSite string,
 		}
 		return
 	}
+	if siteInstance != nil && id != "" {
+		torrentutil.CacheSiteTorrent(sitename, id, filename, content, tinfo)
+	}
 	if sitename == "" {
 		if _sitename, err := tpl.GuessSiteByTrackers(tinfo.Trackers, defaultSite); err != nil {
 			log.Warnf("Failed to find match site for %s by trackers: %v", torrent, err)
@@ -348,6 +387,28 @@ func ParseTorrentsFromArgs(args []string) (torrents []string, stdinTorrentConten
 	return
 }
 
+// Replace local dir args with the *.torrent files inside them (not recursive).
+// Other args are kept as is.
+func ExpandTorrentDirs(torrents []string) ([]string, error) {
+	var result []string
+	for _, torrent := range torrents {
+		if stat, err := os.Stat(torrent); torrent == "-" || err != nil || !stat.IsDir() {
+			result = append(result, torrent)
+			continue
+		}
+		entries, err := os.ReadDir(torrent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dir %s: %w", torrent, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".torrent") {
+				result = append(result, filepath.Join(torrent, entry.Name()))
+			}
+		}
+	}
+	return result, nil
+}
+
 // Parse info-hash list from args. If args is a single "-", read the list from stdin instead.
 // It returns an error if parsed info-hash list is empty.
 func ParseInfoHashesFromArgs(args []string) (infoHashes []string, err error) {
@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/shlex"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// Save a downloaded torrent to the staging dir, to be added to client later in a second pass by
+// "ptool add <client> <dir> --use-comment-meta". The category, tags & save path that should be used
+// when adding the torrent are encoded into the "comment" field of the saved .torrent file.
+// If hook is not empty, it's executed after the file is saved, with PTOOL_TORRENT_* env variables.
+// If the hook fails (e.g. exits with non-zero code), the saved file is renamed to *.fail and an error is returned.
+// Return the full path of the saved file.
+func StageTorrent(dir string, filename string, content []byte, tinfo *torrentutil.TorrentMeta,
+	commentMeta *torrentutil.TorrentCommentMeta, hook string) (string, error) {
+	if tinfo == nil {
+		var err error
+		if tinfo, err = torrentutil.ParseTorrent(content); err != nil {
+			return "", fmt.Errorf("failed to parse torrent: %w", err)
+		}
+	}
+	if commentMeta == nil {
+		commentMeta = &torrentutil.TorrentCommentMeta{}
+	}
+	if err := tinfo.EncodeComment(commentMeta); err != nil {
+		return "", fmt.Errorf("failed to encode comment meta: %w", err)
+	}
+	data, err := tinfo.ToBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate torrent: %w", err)
+	}
+	filename = filepath.Join(dir, filename)
+	if err := os.WriteFile(filename, data, constants.PERM); err != nil {
+		return "", fmt.Errorf("failed to save torrent: %w", err)
+	}
+	if hook == "" {
+		return filename, nil
+	}
+	if err := runStageHook(hook, filename, tinfo, commentMeta); err != nil {
+		if err := os.Rename(filename, util.TrimAnySuffix(filename,
+			constants.ProcessedFilenameSuffixes...)+constants.FILENAME_SUFFIX_FAIL); err != nil {
+			log.Debugf("Failed to rename %s to *%s: %v", filename, constants.FILENAME_SUFFIX_FAIL, err)
+		}
+		return "", fmt.Errorf("rejected by stage hook: %w", err)
+	}
+	return filename, nil
+}
+
+func runStageHook(hook string, filename string, tinfo *torrentutil.TorrentMeta,
+	commentMeta *torrentutil.TorrentCommentMeta) error {
+	args, err := shlex.Split(hook)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("invalid command %q: %w", hook, err)
+	}
+	tracker := ""
+	if len(tinfo.Trackers) > 0 {
+		tracker = tinfo.Trackers[0]
+	}
+	command := exec.Command(args[0], args[1:]...)
+	command.Env = append(os.Environ(),
+		"PTOOL_TORRENT_FILE="+filename,
+		"PTOOL_TORRENT_INFOHASH="+tinfo.InfoHash,
+		"PTOOL_TORRENT_NAME="+tinfo.Info.Name,
+		"PTOOL_TORRENT_SIZE="+fmt.Sprint(tinfo.Size),
+		"PTOOL_TORRENT_TRACKER="+tracker,
+		"PTOOL_TORRENT_CATEGORY="+commentMeta.Category,
+		"PTOOL_TORRENT_TAGS="+strings.Join(commentMeta.Tags, ","),
+		"PTOOL_TORRENT_SAVE_PATH="+commentMeta.SavePath,
+	)
+	output, err := command.CombinedOutput()
+	log.Debugf("Stage hook of %s output: %s", filename, output)
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return err
+}
@@ -35,6 +35,23 @@ func DomSanitizedText(el *goquery.Selection) string {
 	return SanitizeText(el.First().Text())
 }
 
+// Return the text of (first) element, preserving the line breaks of <br> and block elements.
+// Lines are trimmed and consecutive blank lines are merged into one.
+func DomMultilineText(el *goquery.Selection) string {
+	el = el.First().Clone()
+	el.Find("br").ReplaceWithHtml("\n")
+	el.Find("p,div,li,tr,pre,fieldset,legend,blockquote,table,h1,h2,h3,h4,h5,h6").AppendHtml("\n")
+	lines := []string{}
+	for _, line := range strings.Split(el.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 // DIY 了几个选择器语法（附加在标准CSS选择器字符串末尾）.
 // @text 用于选择某个 Element 里的第一个 TEXT_NODE.
 // @after 用于选择某个 Element 后面的 TEXT_NODE.
@@ -2,25 +2,32 @@ package impersonateutil
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/sagan/ptool/util"
 )
 
 type Profile struct {
+	Name string
 	// "chrome", "firefox", "opera", "safari", "edge", "ios", "android"
-	Name          string
 	Navigator     string
 	Ja3           string
 	H2fingerprint string
 	Headers       [][]string // use "\n" as placeholder for order; use "" (empty) to delete a header
 	Comment       string
+	Custom        bool // defined in config file
 }
 
 var (
 	// all supported impersonate names
 	profilesMap = map[string]*Profile{}
+	// custom profiles defined in config file
+	customProfiles []*Profile
 )
 
+var navigators = []string{"chrome", "firefox", "opera", "safari", "edge", "ios", "android"}
+
 // GetProfile get impersonate profile by name. If name is empty, return default profile.
 // If name is not empty but corresponding profile does not exists, return nil.
 func GetProfile(name string) *Profile {
@@ -30,20 +37,114 @@ func GetProfile(name string) *Profile {
 	return profilesMap[name]
 }
 
+// Get built-in impersonate profile by name. Return nil if not exists.
+func GetBuiltinProfile(name string) *Profile {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
 // Get all available impersonate profile names, joined by ","
 func GetAllProfileNames() (names string) {
 	sep := ""
-	for _, p := range profiles {
+	for _, p := range GetAllProfiles() {
 		names += fmt.Sprintf("%s%s", sep, p.Name)
 		sep = ", "
 	}
 	return
 }
 
-func init() {
+// Get all available impersonate profiles: built-in ones followed by custom ones.
+// Built-in profiles overridden by custom ones of the same name are excluded.
+func GetAllProfiles() []*Profile {
+	all := []*Profile{}
+	for _, p := range profiles {
+		if profilesMap[p.Name] == p {
+			all = append(all, p)
+		}
+	}
+	return append(all, customProfiles...)
+}
+
+// Create a custom profile which inherits from the base built-in profile (default to DEFAULT_IMPERSONATE).
+// Non-empty navigator, ja3, h2fingerprint & userAgent override the ones of base profile.
+// headers are merged into the headers of base profile: existing headers of same names are replaced in place,
+// others are appended; use "" (empty) value to delete a header.
+func NewCustomProfile(name string, base string, navigator string, ja3 string, h2fingerprint string,
+	userAgent string, headers [][]string, comment string) (*Profile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("impersonate name can not be empty")
+	}
+	if base == "" {
+		base = DEFAULT_IMPERSONATE
+	}
+	baseProfile := GetBuiltinProfile(base)
+	if baseProfile == nil {
+		return nil, fmt.Errorf("impersonate %s: base impersonate %s is not a built-in one", name, base)
+	}
+	profile := &Profile{
+		Name:          name,
+		Navigator:     baseProfile.Navigator,
+		Ja3:           baseProfile.Ja3,
+		H2fingerprint: baseProfile.H2fingerprint,
+		Headers:       [][]string{},
+		Comment:       comment,
+		Custom:        true,
+	}
+	if navigator != "" {
+		if !slices.Contains(navigators, navigator) {
+			return nil, fmt.Errorf("impersonate %s: invalid navigator %s, valid values: %s",
+				name, navigator, strings.Join(navigators, ", "))
+		}
+		profile.Navigator = navigator
+	}
+	if ja3 != "" {
+		profile.Ja3 = ja3
+	}
+	if h2fingerprint != "" {
+		profile.H2fingerprint = h2fingerprint
+	}
+	if profile.Comment == "" {
+		profile.Comment = fmt.Sprintf("Custom (based on %s)", base)
+	}
+	for _, header := range baseProfile.Headers {
+		profile.Headers = append(profile.Headers, []string{header[0], header[1]})
+	}
+	if userAgent != "" {
+		headers = append(headers, []string{"User-Agent", userAgent})
+	}
+	for _, header := range headers {
+		if len(header) != 2 {
+			return nil, fmt.Errorf("impersonate %s: invalid header %v, must be [name, value]", name, header)
+		}
+		index := slices.IndexFunc(profile.Headers, func(h []string) bool { return strings.EqualFold(h[0], header[0]) })
+		if index == -1 {
+			profile.Headers = append(profile.Headers, []string{header[0], header[1]})
+		} else {
+			profile.Headers[index][1] = header[1]
+		}
+	}
+	return profile, nil
+}
+
+// Set custom profiles (defined in config file), replacing the previous ones.
+// A custom profile overrides the built-in one of the same name.
+func SetCustomProfiles(custom []*Profile) {
+	customProfiles = custom
+	profilesMap = map[string]*Profile{}
 	for _, impersonateProfile := range profiles {
 		profilesMap[impersonateProfile.Name] = impersonateProfile
 	}
+	for _, impersonateProfile := range customProfiles {
+		profilesMap[impersonateProfile.Name] = impersonateProfile
+	}
+}
+
+func init() {
+	SetCustomProfiles(nil)
 }
 
 func (p *Profile) Print() {
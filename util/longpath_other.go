@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+// Return the form of path p that can be passed to os functions. See the Windows version.
+// On other platforms, it returns p as is.
+func LongPath(p string) string {
+	return p
+}
@@ -0,0 +1,29 @@
+//go:build windows
+// +build windows
+
+package util
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Return the absolute extended-length form of path p in native separators, which can be passed to os functions
+// even if it's (or the paths derived from it are) longer than MAX_PATH (260) chars,
+// e.g. `\\?\D:\Downloads\...` or `\\?\UNC\nas\share\...`.
+// Go only does this automatically for long absolute non-UNC paths, not for UNC or relative ones.
+// Device paths (`\\.\`) are returned as is.
+func LongPath(p string) string {
+	p = filepath.FromSlash(p)
+	if p == "" || strings.HasPrefix(p, `\\?\`) || strings.HasPrefix(p, `\\.\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if IsUncPath(abs) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
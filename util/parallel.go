@@ -0,0 +1,64 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Call fn with each of items concurrently, using at most concurrency workers (<= 0: unlimited).
+// If timeout (seconds) > 0, an item of which fn does not return in time gets a timeout error,
+// the ctx passed to fn is cancelled and its worker is released to process next item.
+// fn should return as soon as possible after ctx is done, its result is discarded anyway.
+// Return the results and errors of items, in the same order of items.
+func ParallelMap[T any, R any](items []T, concurrency int64, timeout int64,
+	fn func(ctx context.Context, item T) (R, error)) (results []R, errs []error) {
+	results = make([]R, len(items))
+	errs = make([]error, len(items))
+	if concurrency <= 0 || concurrency > int64(len(items)) {
+		concurrency = int64(len(items))
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = callWithTimeout(items[i], timeout, fn)
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results, errs
+}
+
+func callWithTimeout[T any, R any](item T, timeout int64,
+	fn func(ctx context.Context, item T) (R, error)) (R, error) {
+	if timeout <= 0 {
+		return fn(context.Background(), item)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	type result struct {
+		value R
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		value, err := fn(ctx, item)
+		ch <- result{value, err}
+	}()
+	select {
+	case res := <-ch:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero R
+		return zero, fmt.Errorf("timeout after %ds: %w", timeout, ctx.Err())
+	}
+}
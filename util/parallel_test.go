@@ -0,0 +1,76 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+func TestParallelMap(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int64
+		count       int
+	}{
+		{"bounded", 3, 10},
+		{"unlimited", 0, 5},
+		{"more workers than items", 10, 2},
+		{"empty", 3, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := make([]int, test.count)
+			for i := range items {
+				items[i] = i
+			}
+			var running, maxRunning atomic.Int64
+			results, errs := util.ParallelMap(items, test.concurrency, 0, func(_ context.Context, i int) (int, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				return i * 2, nil
+			})
+			for i := range items {
+				if errs[i] != nil || results[i] != i*2 {
+					t.Errorf("item %d: expected %d, got %d (err=%v)", i, i*2, results[i], errs[i])
+				}
+			}
+			if test.concurrency > 0 && maxRunning.Load() > test.concurrency {
+				t.Errorf("expected at most %d concurrent workers, got %d", test.concurrency, maxRunning.Load())
+			}
+		})
+	}
+}
+
+func TestParallelMapTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	_, errs := util.ParallelMap([]int{0, 1}, 2, 1, func(ctx context.Context, i int) (int, error) {
+		if i == 0 {
+			return i, nil
+		}
+		<-ctx.Done()
+		close(cancelled)
+		return i, ctx.Err()
+	})
+	if errs[0] != nil {
+		t.Errorf("item 0: unexpected error %v", errs[0])
+	}
+	if !errors.Is(errs[1], context.DeadlineExceeded) {
+		t.Errorf("item 1: expected timeout error, got %v", errs[1])
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("ctx of timed out item is not cancelled")
+	}
+}
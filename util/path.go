@@ -0,0 +1,65 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Slash-form path helpers that are aware of Windows UNC paths (e.g. `\\nas\share\dir`) and
+// extended-length paths (e.g. `\\?\D:\dir`, `\\?\UNC\nas\share\dir`).
+// Note path.Clean and path.Join collapse the leading "//" of UNC path, which makes it a local path.
+
+// Report whether p is a Windows UNC path (`\\server\share...` or `//server/share...`).
+// Extended-length (`\\?\`) or device (`\\.\`) paths are not UNC paths.
+func IsUncPath(p string) bool {
+	return len(p) > 2 && isSlash(p[0]) && isSlash(p[1]) && !isSlash(p[2]) && p[2] != '?' &&
+		!(p[2] == '.' && (len(p) == 3 || isSlash(p[3])))
+}
+
+// Return the cleaned slash-form of path p. The Windows extended-length path prefix is removed and
+// the leading "//" of UNC path, as well as the "//./" prefix of Windows device path, is preserved.
+// E.g. `\\?\UNC\nas\share\a\..\b` => "//nas/share/b", `\\?\D:\Downloads\` => "D:/Downloads".
+func CleanPath(p string) string {
+	p = ToSlash(p)
+	if strings.HasPrefix(p, "//?/") {
+		p = p[4:]
+		if len(p) >= 4 && strings.EqualFold(p[:4], "UNC/") {
+			p = "//" + p[4:]
+		}
+	} else if strings.HasPrefix(p, "//./") {
+		return "//./" + strings.TrimPrefix(path.Clean(p[4:]), "/")
+	}
+	if IsUncPath(p) {
+		return "/" + path.Clean(p[1:])
+	}
+	return path.Clean(p)
+}
+
+// Similar to path.Join, but uses CleanPath to clean the result, so UNC or extended-length base paths are kept.
+func JoinPath(elem ...string) string {
+	elem = Filter(elem, func(e string) bool { return e != "" })
+	if len(elem) == 0 {
+		return ""
+	}
+	return CleanPath(strings.Join(elem, "/"))
+}
+
+// Parse a path mapper rule "before|after" (or "before:after" if neither path contains ":" char).
+// Both returned paths are cleaned by CleanPath.
+func ParsePathMapperRule(rule string) (before string, after string, err error) {
+	sep := "|"
+	// use ":" as sep only when "|" not exists and no Windows abs path (e.g. "E:\Downloads") exists
+	if !strings.Contains(rule, "|") && !strings.Contains(rule, `:\`) {
+		sep = ":"
+	}
+	before, after, found := strings.Cut(rule, sep)
+	if !found || before == "" || after == "" {
+		return "", "", fmt.Errorf("invalid path mapper rule %q", rule)
+	}
+	return CleanPath(before), CleanPath(after), nil
+}
+
+func isSlash(c byte) bool {
+	return c == '/' || c == '\\'
+}
@@ -0,0 +1,83 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/util"
+)
+
+func TestIsUncPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{`\\nas\share`, true},
+		{"//nas/share/a", true},
+		{`\\?\UNC\nas\share`, false},
+		{`\\?\D:\Downloads`, false},
+		{`\\.\pipe\foo`, false},
+		{`\\.`, false},
+		{`\\.nas\share`, true},
+		{`D:\Downloads`, false},
+		{"/downloads", false},
+		{`\\`, false},
+		{"///a", false},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if result := util.IsUncPath(test.path); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{`\\nas\share\a\..\b`, "//nas/share/b"},
+		{"//nas/share/", "//nas/share"},
+		{`\\?\UNC\nas\share\a\..\b`, "//nas/share/b"},
+		{`\\?\unc\nas\share`, "//nas/share"},
+		{`\\?\D:\Downloads\`, "D:/Downloads"},
+		{`\\?\D:\`, "D:"},
+		{`\\.\pipe\foo`, "//./pipe/foo"},
+		{`\\.\COM1`, "//./COM1"},
+		{`D:\Downloads\a\..\b`, "D:/Downloads/b"},
+		{"/downloads//movies/", "/downloads/movies"},
+		{"downloads/./a", "downloads/a"},
+		{"", "."},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if result := util.CleanPath(test.path); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		elem     []string
+		expected string
+	}{
+		{[]string{`\\nas\share`, "a", "b"}, "//nas/share/a/b"},
+		{[]string{`\\?\UNC\nas\share`, "a"}, "//nas/share/a"},
+		{[]string{`\\?\D:\Downloads`, "a"}, "D:/Downloads/a"},
+		{[]string{`\\.\pipe`, "foo"}, "//./pipe/foo"},
+		{[]string{"/downloads", "", "movies"}, "/downloads/movies"},
+		{[]string{"/downloads", "../a"}, "/a"},
+		{[]string{"", ""}, ""},
+		{nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			if result := util.JoinPath(test.elem...); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Create a copy-on-write clone (reflink) of source file at dest.
+// Only supported by some file systems (e.g. btrfs, xfs), and source & dest must be in the same file system.
+func Reflink(source string, dest string) (err error) {
+	r, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlFileClone(int(w.Fd()), int(r.Fd()))
+	if c := w.Close(); err == nil {
+		err = c
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"errors"
+)
+
+// Placeholder. Reflink is only supported on Linux for now.
+func Reflink(source string, dest string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,315 @@
+// Package releasename parses the (scene / PT style) release names of torrents, e.g.
+// "The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.TrueHD.7.1.Atmos-GROUP", into structured and normalized info:
+// title, year, season / episode, resolution, source, video / audio codec and release group.
+// It also provides the normalization functions of these attributes, which are shared by other parsers
+// (e.g. of the MediaInfo report of torrent details page).
+package releasename
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/sagan/ptool/util"
+)
+
+type Release struct {
+	Title      string // e.g. "The Matrix". CJK words are removed if the title also contains latin words
+	Year       int64  // 0 if not found
+	Season     int64  // 0 if not found
+	Episode    int64  // 0 if not found (or it's a season pack)
+	TV         bool   // whether it's a TV show (episode, season pack or complete series)
+	Resolution string // normalized, e.g. "2160p", "1080p"
+	Source     string // normalized, e.g. "Remux", "BluRay", "WEB-DL", "WEBRip", "HDTV", "DVD"
+	VideoCodec string // normalized, e.g. "HEVC", "AVC"
+	AudioCodec string // normalized, e.g. "TrueHD", "DTS-HD MA", "DDP"
+	Edition    string // normalized, e.g. "Extended", "Director's Cut". Empty for the standard (theatrical) edition
+	Group      string // release group, e.g. "GROUP"
+}
+
+type namedRegexp struct {
+	name   string
+	regexp *regexp.Regexp
+}
+
+var (
+	yearRegexp = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+	// S01E02, S01, S01-S03, Season 1, 第1季, 第一季, 第02集
+	episodeRegexp = regexp.MustCompile(`(?i)\bS(\d{1,2})(?:E(\d{1,4}))?(?:-S?\d{1,2})?\b|\bSeason (\d{1,2})\b|` +
+		`\bComplete Series\b|第([\d一二三四五六七八九十]{1,3})季|第(\d{1,4})[集话話]`)
+	// Tokens of release name that indicate the end of title
+	releaseTokenRegexp = regexp.MustCompile(`(?i)\b(4320p|2160p|1440p|1080[pi]|720p|576p|480p|4K|8K|UHD|` +
+		`Blu-?Ray|BDRip|BRRip|WEB-?DL|WEB-?Rip|HDTV|HDRip|REMUX|DVDRip|[xh] ?26[45]|HEVC|AVC|HDR|` +
+		`HDR10|DoVi|DDP\d?|AAC|DTS|TrueHD|Atmos|FLAC|iNTERNAL|PROPER|REPACK|REMASTERED|UNRATED|EXTENDED|` +
+		`Director'?s Cut|IMAX)\b`)
+	// "[...]" or "【...】" groups at the beginning of name
+	leadingGroupRegexp = regexp.MustCompile(`^\s*(\[[^\]]*\]|【[^】]*】)\s*`)
+	// "[...]" groups, or file extension at the end of name
+	trailingJunkRegexp = regexp.MustCompile(`(?i)\s*(\[[^\]]*\]|\.(mkv|mp4|avi|ts|m2ts|iso|torrent))\s*$`)
+	groupRegexp        = regexp.MustCompile(`-\s*([A-Za-z0-9][A-Za-z0-9@&_]*)$`)
+	// Suffixes of hyphenated release tokens (e.g. "WEB-DL", "DTS-HD"), which are not group names
+	nonGroupRegexp   = regexp.MustCompile(`(?i)^(DL|Rip|HD|MA|X|HDR|DV)$`)
+	separatorRegexp  = regexp.MustCompile(`[._]+`)
+	spacesRegexp     = regexp.MustCompile(`\s+`)
+	resolutionRegexp = regexp.MustCompile(`(?i)\b(4320|2160|1440|1080|720|576|480)[pi]\b|\b(8K|4K|UHD)\b`)
+	chineseNumbers   = map[rune]int64{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	// In the order of matching priority.
+	sourceRegexps = []*namedRegexp{
+		{"Remux", regexp.MustCompile(`(?i)\bREMUX\b`)},
+		{"BluRay", regexp.MustCompile(`(?i)\b(Blu-?Ray|BDRip|BRRip|BDMV|BD(25|50|66|100))\b`)},
+		{"WEBRip", regexp.MustCompile(`(?i)\bWEB-?Rip\b`)},
+		{"WEB-DL", regexp.MustCompile(`(?i)\bWEB(-?DL)?\b`)},
+		{"HDTV", regexp.MustCompile(`(?i)\b(HDTV|UHDTV|HDTVRip)\b`)},
+		{"DVD", regexp.MustCompile(`(?i)\b(DVD(Rip|5|9)?)\b`)},
+		{"HDRip", regexp.MustCompile(`(?i)\bHDRip\b`)},
+	}
+	videoCodecRegexps = []*namedRegexp{
+		{"HEVC", regexp.MustCompile(`(?i)\b(HEVC|[xh][. ]?265)\b`)},
+		{"AVC", regexp.MustCompile(`(?i)\b(AVC|[xh][. ]?264)\b`)},
+		{"AV1", regexp.MustCompile(`(?i)\bAV1\b`)},
+		{"VP9", regexp.MustCompile(`(?i)\bVP9\b`)},
+		{"VC-1", regexp.MustCompile(`(?i)\bVC-?1\b`)},
+		{"MPEG-2", regexp.MustCompile(`(?i)\bMPEG-?2\b|\bMPEG Video\b`)},
+		{"MPEG-4", regexp.MustCompile(`(?i)\b(MPEG-4 Visual|XviD|DivX)\b`)},
+	}
+	editionRegexps = []*namedRegexp{
+		{"Extended", regexp.MustCompile(`(?i)\bExtended( (Cut|Edition))?\b`)},
+		{"Director's Cut", regexp.MustCompile(`(?i)\bDirector'?s (Cut|Edition)\b`)},
+		{"Unrated", regexp.MustCompile(`(?i)\bUnrated\b|\bUncut\b`)},
+		{"Remastered", regexp.MustCompile(`(?i)\bRemastered\b`)},
+		{"IMAX", regexp.MustCompile(`(?i)\bIMAX\b`)},
+		{"Criterion", regexp.MustCompile(`(?i)\bCriterion\b`)},
+	}
+	audioCodecRegexps = []*namedRegexp{
+		{"TrueHD", regexp.MustCompile(`(?i)\bTrue-?HD\b|\bMLP FBA\b`)},
+		{"DTS-HD MA", regexp.MustCompile(`(?i)\bDTS-?HD[ .-]?MA\b|\bDTS XLL\b|\bDTS-HD Master Audio\b`)},
+		{"DTS:X", regexp.MustCompile(`(?i)\bDTS[ .:-]?X\b`)},
+		{"DTS-HD", regexp.MustCompile(`(?i)\bDTS-?HD\b`)},
+		{"DTS", regexp.MustCompile(`(?i)\bDTS\b`)},
+		{"DDP", regexp.MustCompile(`(?i)\b(DDP|E-?AC-?3|DD\+)`)},
+		{"AC3", regexp.MustCompile(`(?i)\b(AC-?3|DD(\d([. ]\d)?)?)\b`)}, // e.g. "DD", "DD5.1"
+		{"FLAC", regexp.MustCompile(`(?i)\bFLAC(\d([. ]\d)?)?\b`)},
+		{"LPCM", regexp.MustCompile(`(?i)\bL?PCM\b`)},
+		{"AAC", regexp.MustCompile(`(?i)\bAAC(\d([. ]\d)?)?\b`)},
+		{"Opus", regexp.MustCompile(`(?i)\bOpus\b`)},
+		{"MP3", regexp.MustCompile(`(?i)\bMP3\b|\bMPEG Audio\b`)},
+	}
+)
+
+// Parse a release name. Attributes that could not be found are left empty.
+func Parse(name string) *Release {
+	release := &Release{}
+	for {
+		stripped := leadingGroupRegexp.ReplaceAllString(name, "")
+		if stripped == name || strings.TrimSpace(stripped) == "" {
+			break
+		}
+		name = stripped
+	}
+	for {
+		stripped := trailingJunkRegexp.ReplaceAllString(name, "")
+		if stripped == name || strings.TrimSpace(stripped) == "" {
+			break
+		}
+		name = stripped
+	}
+	name = strings.TrimSpace(separatorRegexp.ReplaceAllString(name, " "))
+	// the last "-" of a release name that contains release tokens is the group separator,
+	// e.g. "The Matrix 1999 1080p BluRay x264-GROUP".
+	if m := groupRegexp.FindStringSubmatchIndex(name); m != nil && releaseTokenRegexp.MatchString(name[:m[0]]) &&
+		!nonGroupRegexp.MatchString(name[m[2]:m[3]]) {
+		release.Group = name[m[2]:m[3]]
+		name = strings.TrimSpace(name[:m[0]])
+	}
+	release.Resolution = ParseResolution(name)
+	release.Source = ParseSource(name)
+	release.VideoCodec = ParseVideoCodec(name)
+	release.AudioCodec = ParseAudioCodec(name)
+	release.Edition = matchName(editionRegexps, name)
+	end := len(name)
+	if m := episodeRegexp.FindStringSubmatchIndex(name); m != nil && m[0] > 0 {
+		end = m[0]
+		release.TV = true
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return name[m[2*i]:m[2*i+1]]
+		}
+		release.Season = util.ParseInt(group(1) + group(3))
+		release.Episode = util.ParseInt(group(2) + group(5))
+		if season := group(4); season != "" {
+			release.Season = parseChineseNumber(season)
+		}
+	}
+	tokenEnd := len(name)
+	if loc := releaseTokenRegexp.FindStringIndex(name); loc != nil && loc[0] > 0 {
+		tokenEnd = loc[0]
+	}
+	end = min(end, tokenEnd)
+	// use the last year before release tokens, as the title itself may contain a year,
+	// e.g. "2001 A Space Odyssey 1968". The year of TV show may be after the season, e.g. "Title S01 2024".
+	yearStart := -1
+	for _, loc := range yearRegexp.FindAllStringIndex(name[:tokenEnd], -1) {
+		if loc[0] > 0 {
+			release.Year = util.ParseInt(name[loc[0]:loc[1]])
+			yearStart = loc[0]
+		}
+	}
+	if yearStart > 0 {
+		end = min(end, yearStart)
+	}
+	title := name[:end]
+	if strings.IndexFunc(title, isLatinLetter) != -1 && strings.IndexFunc(title, IsCJK) != -1 {
+		title = strings.Map(func(r rune) rune {
+			if IsCJK(r) {
+				return ' '
+			}
+			return r
+		}, title)
+	}
+	release.Title = strings.Trim(spacesRegexp.ReplaceAllString(title, " "), " -([{【（:：/|")
+	return release
+}
+
+// Return the normalized key of release, which is the same for the releases of the same movie / episode
+// (regardless of the quality or group), e.g. "the matrix|1999" or "friends|s01e02".
+// Return empty string if the title of release is unknown.
+func (release *Release) Key() string {
+	title := NormalizeTitle(release.Title)
+	if title == "" {
+		return ""
+	}
+	key := title
+	if release.Year > 0 {
+		key += fmt.Sprintf("|%d", release.Year)
+	}
+	if release.TV {
+		key += fmt.Sprintf("|s%02de%02d", release.Season, release.Episode)
+	}
+	return key
+}
+
+// Report whether the two releases are (probably) the same release: same key and edition, and same resolution,
+// source and group if both of them are known. It's used as a cheap pre-check before comparing the contents.
+func (release *Release) Match(other *Release) bool {
+	if release.Key() == "" || release.Key() != other.Key() || release.Edition != other.Edition {
+		return false
+	}
+	for _, pair := range [][2]string{
+		{release.Resolution, other.Resolution},
+		{release.Source, other.Source},
+		{release.Group, other.Group},
+	} {
+		if pair[0] != "" && pair[1] != "" && !strings.EqualFold(pair[0], pair[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the normalized form of a title for comparison: lower case, letters and digits only,
+// words separated by a single space; "&" is treated as "and".
+// E.g. "Tom & Jerry: The Movie" => "tom and jerry the movie".
+func NormalizeTitle(title string) string {
+	title = strings.ReplaceAll(strings.ToLower(title), "&", " and ")
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		if r == '\'' {
+			return -1
+		}
+		return ' '
+	}, title)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// Parse the normalized resolution from text, e.g. "1080p", "2160p". "4K" / "UHD" is treated as "2160p".
+func ParseResolution(text string) string {
+	m := resolutionRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	if m[1] != "" {
+		return m[1] + "p"
+	}
+	if strings.EqualFold(m[2], "8K") {
+		return "4320p"
+	}
+	return "2160p"
+}
+
+// Return the normalized resolution of video height, e.g. 1080 => "1080p".
+// Cropped videos (e.g. 1920x800) are treated as the nearest standard resolution which height is not smaller.
+func ResolutionByHeight(height int64) string {
+	if height <= 0 {
+		return ""
+	}
+	for _, h := range []int64{480, 576, 720, 1080, 1440, 2160} {
+		if height <= h {
+			return fmt.Sprintf("%dp", h)
+		}
+	}
+	return "4320p"
+}
+
+// Parse the normalized source from text, e.g. "BluRay", "WEB-DL".
+func ParseSource(text string) string {
+	return matchName(sourceRegexps, text)
+}
+
+// Parse the normalized video codec from text, e.g. "HEVC", "AVC".
+func ParseVideoCodec(text string) string {
+	return matchName(videoCodecRegexps, text)
+}
+
+// Parse the normalized (main) audio codec from text, e.g. "TrueHD", "DTS-HD MA".
+func ParseAudioCodec(text string) string {
+	return matchName(audioCodecRegexps, text)
+}
+
+func matchName(regexps []*namedRegexp, text string) string {
+	if text == "" {
+		return ""
+	}
+	for _, item := range regexps {
+		if item.regexp.MatchString(text) {
+			return item.name
+		}
+	}
+	return ""
+}
+
+// Parse "3", "三", "十二" or "二十" style numbers.
+func parseChineseNumber(str string) int64 {
+	if n := util.ParseInt(str); n > 0 {
+		return n
+	}
+	runes := []rune(str)
+	var n int64
+	switch {
+	case len(runes) == 1 && runes[0] == '十':
+		n = 10
+	case len(runes) == 1:
+		n = chineseNumbers[runes[0]]
+	case len(runes) == 2 && runes[0] == '十':
+		n = 10 + chineseNumbers[runes[1]]
+	case len(runes) == 2 && runes[1] == '十':
+		n = chineseNumbers[runes[0]] * 10
+	case len(runes) == 3 && runes[1] == '十':
+		n = chineseNumbers[runes[0]]*10 + chineseNumbers[runes[2]]
+	}
+	return n
+}
+
+func isLatinLetter(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+// Report whether r is a CJK (Chinese, Japanese or Korean) character or punctuation.
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) || r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xffef
+}
@@ -0,0 +1,170 @@
+package releasename_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/util/releasename"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected releasename.Release
+	}{
+		// movies
+		{"The.Matrix.1999.2160p.UHD.BluRay.x265.10bit.HDR.TrueHD.7.1.Atmos-GROUP", releasename.Release{
+			Title: "The Matrix", Year: 1999, Resolution: "2160p", Source: "BluRay", VideoCodec: "HEVC",
+			AudioCodec: "TrueHD", Group: "GROUP"}},
+		{"Inception 2010 1080p BluRay REMUX AVC DTS-HD MA 5.1-FraMeSToR", releasename.Release{
+			Title: "Inception", Year: 2010, Resolution: "1080p", Source: "Remux", VideoCodec: "AVC",
+			AudioCodec: "DTS-HD MA", Group: "FraMeSToR"}},
+		{"Blade.Runner.1982.Final.Cut.720p.BluRay.DD5.1.x264-EbP", releasename.Release{
+			Title: "Blade Runner", Year: 1982, Resolution: "720p", Source: "BluRay", VideoCodec: "AVC",
+			AudioCodec: "AC3", Group: "EbP"}},
+		{"Dune.Part.Two.2024.1080p.WEB-DL.DDP5.1.Atmos.H.264-FLUX", releasename.Release{
+			Title: "Dune Part Two", Year: 2024, Resolution: "1080p", Source: "WEB-DL", VideoCodec: "AVC",
+			AudioCodec: "DDP", Group: "FLUX"}},
+		{"The.Lord.of.the.Rings.2001.EXTENDED.1080p.BluRay.x264-GROUP", releasename.Release{
+			Title: "The Lord of the Rings", Year: 2001, Resolution: "1080p", Source: "BluRay", VideoCodec: "AVC",
+			Edition: "Extended", Group: "GROUP"}},
+		{"[FRDS] Alien.1979.Directors.Cut.1080p.BluRay.DTS.x264.mkv", releasename.Release{
+			Title: "Alien", Year: 1979, Resolution: "1080p", Source: "BluRay", VideoCodec: "AVC",
+			AudioCodec: "DTS", Edition: "Director's Cut"}},
+		// numeric titles
+		{"1917.2019.2160p.UHD.BluRay.x265-GROUP", releasename.Release{
+			Title: "1917", Year: 2019, Resolution: "2160p", Source: "BluRay", VideoCodec: "HEVC", Group: "GROUP"}},
+		{"2001.A.Space.Odyssey.1968.1080p.BluRay.x264-GROUP", releasename.Release{
+			Title: "2001 A Space Odyssey", Year: 1968, Resolution: "1080p", Source: "BluRay", VideoCodec: "AVC",
+			Group: "GROUP"}},
+		{"2012.2009.720p.BluRay.x264-GROUP", releasename.Release{
+			Title: "2012", Year: 2009, Resolution: "720p", Source: "BluRay", VideoCodec: "AVC", Group: "GROUP"}},
+		// episodes
+		{"Friends.S01E02.1080p.BluRay.x264-GROUP", releasename.Release{
+			Title: "Friends", Season: 1, Episode: 2, TV: true, Resolution: "1080p", Source: "BluRay",
+			VideoCodec: "AVC", Group: "GROUP"}},
+		{"The.Last.of.Us.S01E09.2023.2160p.WEB-DL.DDP5.1.HEVC-NTb", releasename.Release{
+			Title: "The Last of Us", Year: 2023, Season: 1, Episode: 9, TV: true, Resolution: "2160p",
+			Source: "WEB-DL", VideoCodec: "HEVC", AudioCodec: "DDP", Group: "NTb"}},
+		{"One.Piece.S01E1089.1080p.WEBRip.AAC.x264-GROUP", releasename.Release{
+			Title: "One Piece", Season: 1, Episode: 1089, TV: true, Resolution: "1080p", Source: "WEBRip",
+			VideoCodec: "AVC", AudioCodec: "AAC", Group: "GROUP"}},
+		// season packs
+		{"Breaking.Bad.S05.1080p.BluRay.DTS-HD.MA.5.1.x264-GROUP", releasename.Release{
+			Title: "Breaking Bad", Season: 5, TV: true, Resolution: "1080p", Source: "BluRay", VideoCodec: "AVC",
+			AudioCodec: "DTS-HD MA", Group: "GROUP"}},
+		{"The.Wire.S01-S05.720p.HDTV.x264-GROUP", releasename.Release{
+			Title: "The Wire", Season: 1, TV: true, Resolution: "720p", Source: "HDTV", VideoCodec: "AVC",
+			Group: "GROUP"}},
+		{"Chernobyl Season 1 2019 1080p WEB-DL", releasename.Release{
+			Title: "Chernobyl", Year: 2019, Season: 1, TV: true, Resolution: "1080p", Source: "WEB-DL"}},
+		// CJK titles
+		{"狂飙 第一季 2023 2160p WEB-DL HEVC", releasename.Release{
+			Title: "狂飙", Year: 2023, Season: 1, TV: true, Resolution: "2160p", Source: "WEB-DL", VideoCodec: "HEVC"}},
+		{"[流浪地球2].The.Wandering.Earth.II.2023.2160p.WEB-DL.H265.DDP5.1-GROUP", releasename.Release{
+			Title: "The Wandering Earth II", Year: 2023, Resolution: "2160p", Source: "WEB-DL", VideoCodec: "HEVC",
+			AudioCodec: "DDP", Group: "GROUP"}},
+		{"三体 第02集 2023 1080p WEB-DL AAC", releasename.Release{
+			Title: "三体", Year: 2023, Episode: 2, TV: true, Resolution: "1080p", Source: "WEB-DL",
+			AudioCodec: "AAC"}},
+		{"让子弹飞 Let the Bullets Fly 2010 1080p BluRay x264 DTS-GROUP", releasename.Release{
+			Title: "Let the Bullets Fly", Year: 2010, Resolution: "1080p", Source: "BluRay", VideoCodec: "AVC",
+			AudioCodec: "DTS", Group: "GROUP"}},
+		// no release tokens
+		{"Some Home Video", releasename.Release{Title: "Some Home Video"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := releasename.Parse(test.name); *result != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, *result)
+			}
+		})
+	}
+}
+
+func TestParseAudioCodec(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"DD5.1", "AC3"},
+		{"DD 5.1", "AC3"},
+		{"DD2.0", "AC3"},
+		{"AC3", "AC3"},
+		{"DDP5.1", "DDP"},
+		{"DDP 5.1 Atmos", "DDP"},
+		{"DD+ 7.1", "DDP"},
+		{"E-AC-3", "DDP"},
+		{"TrueHD 7.1 Atmos", "TrueHD"},
+		{"True-HD", "TrueHD"},
+		{"DTS-HD MA 5.1", "DTS-HD MA"},
+		{"DTS-HD.MA.7.1", "DTS-HD MA"},
+		{"DTS-HD Master Audio", "DTS-HD MA"},
+		{"DTS-HD HRA", "DTS-HD"},
+		{"DTS:X", "DTS:X"},
+		{"DTS", "DTS"},
+		{"FLAC 2.0", "FLAC"},
+		{"LPCM", "LPCM"},
+		{"AAC2.0", "AAC"},
+		{"FLAC2.0", "FLAC"},
+		{"AAC 2.0", "AAC"},
+		{"DDD", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if result := releasename.ParseAudioCodec(test.text); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestKeyAndMatch(t *testing.T) {
+	tests := []struct {
+		a     string
+		b     string
+		key   string
+		match bool
+	}{
+		{"The.Matrix.1999.1080p.BluRay.x264-A", "The Matrix 1999 1080p BluRay x265-A", "the matrix|1999", true},
+		{"The.Matrix.1999.1080p.BluRay.x264-A", "The.Matrix.1999.2160p.BluRay.x265-A", "the matrix|1999", false},
+		{"The.Matrix.1999.1080p.BluRay.x264-A", "The.Matrix.1999.1080p.BluRay.x264-B", "the matrix|1999", false},
+		{"Alien.1979.1080p.BluRay.x264-A", "Alien.1979.Directors.Cut.1080p.BluRay.x264-A", "alien|1979", false},
+		{"Friends.S01E02.1080p.WEB-DL-A", "Friends.S01E02.1080p.WEB-DL", "friends|s01e02", true},
+		{"Friends.S01E02.1080p.WEB-DL-A", "Friends.S01E03.1080p.WEB-DL-A", "friends|s01e02", false},
+		{"Tom.&.Jerry.2021.720p.WEB-DL-A", "Tom and Jerry 2021 720p WEB-DL-A", "tom and jerry|2021", true},
+		{"Some Home Video", "Some Home Video", "some home video", true},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"_"+test.b, func(t *testing.T) {
+			a, b := releasename.Parse(test.a), releasename.Parse(test.b)
+			if key := a.Key(); key != test.key {
+				t.Errorf("key: expected %q, got %q", test.key, key)
+			}
+			if match := a.Match(b); match != test.match {
+				t.Errorf("match: expected %t, got %t", test.match, match)
+			}
+		})
+	}
+}
+
+func TestResolutionByHeight(t *testing.T) {
+	tests := []struct {
+		height   int64
+		expected string
+	}{
+		{0, ""},
+		{480, "480p"},
+		{800, "1080p"},
+		{1080, "1080p"},
+		{1608, "2160p"},
+		{4320, "4320p"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			if result := releasename.ResolutionByHeight(test.height); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
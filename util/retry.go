@@ -0,0 +1,115 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/constants"
+)
+
+// Retry & circuit breaker policy of http GET requests sent by FetchUrl / FetchUrlWithAzuretls.
+type HttpRetryPolicy struct {
+	Retries     int64         // max retry times of a failed request. 0 == no retry
+	Backoff     time.Duration // wait time before first retry, doubled after each retry
+	MaxBackoff  time.Duration
+	StatusCodes []int // http status codes that will be retried. Network errors (no response) are always retried
+	// after this much consecutive failures of a host, the circuit breaker opens, and all requests to that host
+	// fail immediately during cooldown. After cooldown it's half-open: requests are sent again, the first
+	// success closes the breaker, and a failure re-opens it at once. 0 == disable circuit breaker
+	BreakerThreshold int64
+	BreakerCooldown  time.Duration
+}
+
+// Retry & circuit breaker are disabled by default. The other values are used when they are enabled.
+var DefaultHttpRetry = HttpRetryPolicy{
+	Retries:          0,
+	Backoff:          time.Second,
+	MaxBackoff:       time.Second * 30,
+	StatusCodes:      []int{429, 500, 502, 503, 504, 520, 521, 522, 523, 524}, // 52x: Cloudflare errors
+	BreakerThreshold: 0,
+	BreakerCooldown:  time.Minute,
+}
+
+// Current retry policy. It's set by config package from config file.
+var HttpRetry = &DefaultHttpRetry
+
+type circuitBreaker struct {
+	failures  int64
+	openUntil time.Time
+}
+
+var (
+	circuitBreakers   = map[string]*circuitBreaker{} // hostname => breaker
+	circuitBreakersMu sync.Mutex
+)
+
+// Send a request using doRequest, retry it if failed with network error or retryable http status.
+// doRequest returns the http status code (0 if no response) and error.
+// Requests to the same host share a circuit breaker.
+func doWithRetry(url string, doRequest func() (status int, err error)) error {
+	policy := HttpRetry
+	hostname := ParseUrlHostname(url)
+	backoff := policy.Backoff
+	for i := int64(0); ; i++ {
+		if err := allowRequest(policy, hostname); err != nil {
+			return err
+		}
+		status, err := doRequest()
+		if err == nil || status != 0 && !slices.Contains(policy.StatusCodes, status) {
+			recordRequest(policy, hostname, true)
+			return err
+		}
+		recordRequest(policy, hostname, false)
+		if i >= policy.Retries {
+			return err
+		}
+		// query string is omitted as it may contain secrets (e.g. api key or passkey)
+		logUrl, _, _ := strings.Cut(url, "?")
+		log.Warnf("Request %s failed (%v), retry in %v (%d/%d)", logUrl, err, backoff, i+1, policy.Retries)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+}
+
+func allowRequest(policy *HttpRetryPolicy, hostname string) error {
+	if policy.BreakerThreshold <= 0 || hostname == "" {
+		return nil
+	}
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+	if breaker := circuitBreakers[hostname]; breaker != nil && time.Now().Before(breaker.openUntil) {
+		return fmt.Errorf("%w: circuit breaker of %s is open due to consecutive failures, retry after %s",
+			constants.ErrNetwork, hostname, breaker.openUntil.Format(time.TimeOnly))
+	}
+	return nil
+}
+
+func recordRequest(policy *HttpRetryPolicy, hostname string, success bool) {
+	if policy.BreakerThreshold <= 0 || hostname == "" {
+		return
+	}
+	circuitBreakersMu.Lock()
+	defer circuitBreakersMu.Unlock()
+	breaker := circuitBreakers[hostname]
+	if success {
+		if breaker != nil {
+			delete(circuitBreakers, hostname)
+		}
+		return
+	}
+	if breaker == nil {
+		breaker = &circuitBreaker{}
+		circuitBreakers[hostname] = breaker
+	}
+	breaker.failures++
+	if breaker.failures >= policy.BreakerThreshold {
+		log.Warnf("Circuit breaker of %s opened for %v after %d consecutive failures", hostname,
+			policy.BreakerCooldown, breaker.failures)
+		breaker.openUntil = time.Now().Add(policy.BreakerCooldown)
+	}
+}
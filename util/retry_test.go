@@ -0,0 +1,167 @@
+package util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+// A test server which responds with the status codes in order, repeating the last one.
+type retryTestServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	times    []time.Time
+}
+
+func newRetryTestServer(t *testing.T, statuses ...int) *retryTestServer {
+	server := &retryTestServer{statuses: statuses}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		status := server.statuses[min(len(server.times), len(server.statuses)-1)]
+		server.times = append(server.times, time.Now())
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *retryTestServer) requests() []time.Time {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]time.Time(nil), server.times...)
+}
+
+func setHttpRetry(t *testing.T, policy *util.HttpRetryPolicy) {
+	retry := util.HttpRetry
+	util.HttpRetry = policy
+	t.Cleanup(func() { util.HttpRetry = retry })
+}
+
+func TestFetchUrlRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		retries          int64
+		statuses         []int
+		expectedRequests int
+		expectedError    bool
+	}{
+		{"success", 2, []int{200}, 1, false},
+		{"retry then success", 2, []int{503, 429, 200}, 3, false},
+		{"retries exhausted", 2, []int{503}, 3, true},
+		{"status not retried", 2, []int{404, 200}, 1, true},
+		{"retry disabled", 0, []int{503, 200}, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setHttpRetry(t, &util.HttpRetryPolicy{
+				Retries:     test.retries,
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				StatusCodes: []int{429, 503},
+			})
+			server := newRetryTestServer(t, test.statuses...)
+			res, _, err := util.FetchUrl(server.URL, nil, nil)
+			if err == nil {
+				res.Body.Close()
+			}
+			if (err != nil) != test.expectedError {
+				t.Errorf("expected error %t, got %v", test.expectedError, err)
+			}
+			if requests := len(server.requests()); requests != test.expectedRequests {
+				t.Errorf("expected %d requests, got %d", test.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestFetchUrlRetryBackoff(t *testing.T) {
+	backoff := 20 * time.Millisecond
+	setHttpRetry(t, &util.HttpRetryPolicy{
+		Retries:     3,
+		Backoff:     backoff,
+		MaxBackoff:  backoff * 3,
+		StatusCodes: []int{503},
+	})
+	server := newRetryTestServer(t, 503)
+	if _, _, err := util.FetchUrl(server.URL, nil, nil); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	requests := server.requests()
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(requests))
+	}
+	// backoff is doubled after each retry, capped by MaxBackoff
+	for i, expected := range []time.Duration{backoff, backoff * 2, backoff * 3} {
+		if wait := requests[i+1].Sub(requests[i]); wait < expected {
+			t.Errorf("retry %d: expected wait >= %v, got %v", i+1, expected, wait)
+		}
+	}
+}
+
+func TestPostUrlNotRetried(t *testing.T) {
+	setHttpRetry(t, &util.HttpRetryPolicy{
+		Retries:     2,
+		Backoff:     time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		StatusCodes: []int{503},
+	})
+	server := newRetryTestServer(t, 503, 200)
+	var v any
+	if err := util.PostUrlForJson(server.URL, url.Values{"a": {"b"}}, &v, nil); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if requests := len(server.requests()); requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestFetchUrlCircuitBreaker(t *testing.T) {
+	cooldown := 50 * time.Millisecond
+	setHttpRetry(t, &util.HttpRetryPolicy{
+		StatusCodes:      []int{503},
+		BreakerThreshold: 2,
+		BreakerCooldown:  cooldown,
+	})
+	// 2 failures open the breaker; after cooldown, a failure re-opens it; then a success closes it
+	server := newRetryTestServer(t, 503, 503, 503, 200, 200)
+	tests := []struct {
+		name             string
+		wait             time.Duration
+		expectedRequests int
+		expectedError    bool
+		expectedOpen     bool
+	}{
+		{"first failure", 0, 1, true, false},
+		{"second failure opens", 0, 2, true, false},
+		{"open", 0, 2, true, true},
+		{"half-open failure", cooldown, 3, true, false},
+		{"re-opened", 0, 3, true, true},
+		{"half-open success", cooldown, 4, false, false},
+		{"closed", 0, 5, false, false},
+	}
+	for _, test := range tests {
+		time.Sleep(test.wait)
+		res, _, err := util.FetchUrl(server.URL, nil, nil)
+		if err == nil {
+			res.Body.Close()
+		}
+		if (err != nil) != test.expectedError {
+			t.Errorf("%s: expected error %t, got %v", test.name, test.expectedError, err)
+		}
+		if requests := len(server.requests()); requests != test.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", test.name, test.expectedRequests, requests)
+		}
+		if open := err != nil && strings.Contains(err.Error(), "circuit breaker"); open != test.expectedOpen {
+			t.Errorf("%s: expected breaker open %t, got error %v", test.name, test.expectedOpen, err)
+		}
+	}
+}
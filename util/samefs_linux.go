@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Return true if path1 and path2 are in the same file system (device).
+// Both paths must exist.
+func SameFileSystem(path1 string, path2 string) (bool, error) {
+	var stat1, stat2 unix.Stat_t
+	if err := unix.Stat(path1, &stat1); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(path2, &stat2); err != nil {
+		return false, err
+	}
+	return stat1.Dev == stat2.Dev, nil
+}
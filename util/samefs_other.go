@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"errors"
+)
+
+// Placeholder. Checking file system of path is only supported on Linux for now.
+func SameFileSystem(path1 string, path2 string) (bool, error) {
+	return false, errors.ErrUnsupported
+}
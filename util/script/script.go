@@ -0,0 +1,176 @@
+// Embedded Starlark (a dialect of Python) scripting, which allows users to write decision functions
+// (e.g. custom scoring or removal of torrents) that are too complex for declarative config options or flags.
+//
+// A script is a .star file that defines functions, e.g. :
+//
+//	def score(torrent, score):
+//	    if "纪录片" in torrent.tags:
+//	        return score * 2
+//	    return score
+//
+// The item (e.g. torrent) passed to function is a struct, which fields are accessed by "item.field".
+// See https://github.com/google/starlark-go/blob/master/doc/spec.md for the language spec.
+package script
+
+import (
+	"fmt"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Max execution steps of loading a script or a function call, to prevent a buggy script from hanging the program.
+const MAX_EXECUTION_STEPS = 1000000
+
+type Script struct {
+	filename string
+	globals  starlark.StringDict // frozen after loaded, so it's safe to call functions concurrently
+}
+
+var (
+	scripts   = map[string]*Script{}
+	scriptsMu sync.Mutex
+)
+
+// Load and execute the script file. The loaded scripts are cached, so it's safe to call it multiple times.
+func Load(filename string) (*Script, error) {
+	scriptsMu.Lock()
+	defer scriptsMu.Unlock()
+	if script := scripts[filename]; script != nil {
+		return script, nil
+	}
+	thread := &starlark.Thread{Name: filename}
+	thread.SetMaxExecutionSteps(MAX_EXECUTION_STEPS)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{
+		Set:             true,
+		While:           true,
+		TopLevelControl: true,
+		GlobalReassign:  true,
+	}, thread, filename, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script %s: %w", filename, err)
+	}
+	script := &Script{filename: filename, globals: globals}
+	scripts[filename] = script
+	return script, nil
+}
+
+// Return true if script defines a function of name.
+func (script *Script) Has(name string) bool {
+	_, ok := script.globals[name].(starlark.Callable)
+	return ok
+}
+
+// Call the function of name. args are converted to Starlark values, see ToValue.
+// The returned value is converted from Starlark value, see FromValue.
+func (script *Script) Call(name string, args ...any) (any, error) {
+	fn, ok := script.globals[name].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("function %s is not defined in script %s", name, script.filename)
+	}
+	var tuple starlark.Tuple
+	for _, arg := range args {
+		value, err := ToValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		tuple = append(tuple, value)
+	}
+	thread := &starlark.Thread{Name: script.filename}
+	thread.SetMaxExecutionSteps(MAX_EXECUTION_STEPS)
+	result, err := starlark.Call(thread, fn, tuple, nil)
+	if err != nil {
+		return nil, fmt.Errorf("script %s function %s error: %w", script.filename, name, err)
+	}
+	return FromValue(result), nil
+}
+
+// Call the function of name that returns a bool. A None result is treated as false.
+func (script *Script) CallBool(name string, args ...any) (bool, error) {
+	result, err := script.Call(name, args...)
+	if err != nil {
+		return false, err
+	}
+	switch v := result.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("script %s function %s returned %v, bool expected", script.filename, name, result)
+}
+
+// Call the function of name that returns a number.
+func (script *Script) CallNumber(name string, args ...any) (float64, error) {
+	result, err := script.Call(name, args...)
+	if err != nil {
+		return 0, err
+	}
+	switch v := result.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("script %s function %s returned %v, number expected", script.filename, name, result)
+}
+
+// Convert a Go value to Starlark value. Supported types: nil, bool, int, int64, float64, string, []string,
+// map[string]any (converted to a struct).
+func ToValue(v any) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case string:
+		return starlark.String(v), nil
+	case []string:
+		list := make([]starlark.Value, 0, len(v))
+		for _, s := range v {
+			list = append(list, starlark.String(s))
+		}
+		return starlark.NewList(list), nil
+	case map[string]any:
+		dict := starlark.StringDict{}
+		for key, value := range v {
+			value, err := ToValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", key, err)
+			}
+			dict[key] = value
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, dict), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+// Convert a Starlark value to Go value: None => nil, Bool => bool, Int => int64, Float => float64,
+// String => string. Other types are converted to their string representations.
+func FromValue(v starlark.Value) any {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(v)
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i
+		}
+		f, _ := starlark.AsFloat(v)
+		return f
+	case starlark.Float:
+		return float64(v)
+	case starlark.String:
+		return string(v)
+	}
+	return v.String()
+}
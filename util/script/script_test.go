@@ -0,0 +1,36 @@
+package script_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/util/script"
+)
+
+func TestExecutionStepsLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		expectedError bool
+	}{
+		{"load", "while True:\n    pass\n", true},
+		{"call", "def f():\n    while True:\n        pass\n", true},
+		{"normal", "def f():\n    return 1\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), test.name+".star")
+			if err := os.WriteFile(filename, []byte(test.source), 0644); err != nil {
+				t.Fatal(err)
+			}
+			s, err := script.Load(filename)
+			if err == nil {
+				_, err = s.Call("f")
+			}
+			if (err != nil) != test.expectedError {
+				t.Errorf("expected error %t, got %v", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package selfupdate
+
+// Minisign ( https://jedisct1.github.io/minisign/ ) signature verification.
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	MINISIGN_ALG_LEGACY = "Ed" // signature of the message itself
+	MINISIGN_ALG_HASHED = "ED" // signature of the BLAKE2b-512 hash of the message
+)
+
+type PublicKey struct {
+	keyId [8]byte
+	key   ed25519.PublicKey
+}
+
+// Parse a minisign public key, either the base64 key string (e.g. "RWQ...")
+// or the contents of the public key file (with an "untrusted comment:" line).
+func ParsePublicKey(str string) (*PublicKey, error) {
+	keyStr := ""
+	for _, line := range strings.Split(strings.TrimSpace(str), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			keyStr = line
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(keyStr)
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != MINISIGN_ALG_LEGACY {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	publicKey := &PublicKey{key: ed25519.PublicKey(data[10:])}
+	copy(publicKey.keyId[:], data[2:10])
+	return publicKey, nil
+}
+
+// Verify the minisign signature (contents of .minisig file) of message.
+// Both the signature and the global signature (of the trusted comment) are verified.
+func (pk *PublicKey) Verify(message []byte, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(string(signature), "\r\n", "\n")), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("invalid signature format")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid global signature")
+	}
+	if !bytes.Equal(sig[2:10], pk.keyId[:]) {
+		return fmt.Errorf("signature key id %X does not match public key %X", sig[2:10], pk.keyId)
+	}
+	switch string(sig[:2]) {
+	case MINISIGN_ALG_LEGACY:
+	case MINISIGN_ALG_HASHED:
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(pk.key, message, sig[10:]) {
+		return fmt.Errorf("signature verification failed")
+	}
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(pk.key, append(bytes.Clone(sig[10:]), trustedComment...), globalSig) {
+		return fmt.Errorf("global signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package selfupdate_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/sagan/ptool/util/selfupdate"
+)
+
+// Test vector of minisign (legacy "Ed" algorithm) from github.com/jedisct1/go-minisign. Message: "test".
+const (
+	testPublicKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	testSignature = "untrusted comment: signature from minisign secret key\n" +
+		"RWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\n" +
+		"trusted comment: timestamp:1555779966\tfile:test\n" +
+		"QtKMXWyYcwdpZAlPF7tE2ENJkRd1ujvKjlj1m9RtHTBnZPa5WKU5uWRs5GoP5M/VqE81QFuMKI5k/SfNQUaOAA==\n"
+)
+
+// Generate a minisign public key and a prehashed ("ED" algorithm) signature of message.
+func generateHashedSignature(message string, trustedComment string) (publicKey string, signature string) {
+	privateKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	keyId := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	pk := append(append([]byte("Ed"), keyId...), privateKey.Public().(ed25519.PublicKey)...)
+	hash := blake2b.Sum512([]byte(message))
+	sig := append(append([]byte("ED"), keyId...), ed25519.Sign(privateKey, hash[:])...)
+	globalSig := ed25519.Sign(privateKey, append(sig[10:], trustedComment...))
+	return base64.StdEncoding.EncodeToString(pk), "untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(sig) + "\n" +
+		"trusted comment: " + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(globalSig) + "\n"
+}
+
+// Replace the line of index of signature.
+func replaceLine(signature string, index int, line string) string {
+	lines := strings.Split(signature, "\n")
+	lines[index] = line
+	return strings.Join(lines, "\n")
+}
+
+// Flip the last byte of the base64 encoded line of index of signature.
+func tamperLine(signature string, index int) string {
+	data := must(base64.StdEncoding.DecodeString(strings.Split(signature, "\n")[index]))
+	data[len(data)-1] ^= 1
+	return replaceLine(signature, index, base64.StdEncoding.EncodeToString(data))
+}
+
+func TestParsePublicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"key", testPublicKey, false},
+		{"key file", "untrusted comment: minisign public key 37E6620F18428BE4\n" + testPublicKey + "\n", false},
+		{"invalid base64", "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO!", true},
+		{"too short", testPublicKey[:40], true},
+		{"empty", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := selfupdate.ParsePublicKey(test.key)
+			if (err != nil) != test.wantErr {
+				t.Errorf("expected error %t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	hashedPublicKey, hashedSignature := generateHashedSignature("checksums", "timestamp:1700000000\tfile:checksums.txt")
+	otherPublicKey, _ := generateHashedSignature("", "")
+	otherPublicKey = base64.StdEncoding.EncodeToString(append([]byte("Ed12345678"),
+		must(base64.StdEncoding.DecodeString(otherPublicKey))[10:]...))
+	tests := []struct {
+		name      string
+		publicKey string
+		message   string
+		signature string
+		wantErr   bool
+	}{
+		{"legacy", testPublicKey, "test", testSignature, false},
+		{"legacy crlf", testPublicKey, "test", strings.ReplaceAll(testSignature, "\n", "\r\n"), false},
+		{"legacy tampered message", testPublicKey, "test2", testSignature, true},
+		{"legacy tampered signature", testPublicKey, "test", tamperLine(testSignature, 1), true},
+		{"legacy tampered trusted comment", testPublicKey, "test", replaceLine(testSignature, 2,
+			"trusted comment: timestamp:1555779967\tfile:test"), true},
+		{"legacy tampered global signature", testPublicKey, "test", tamperLine(testSignature, 3), true},
+		{"legacy wrong key id", otherPublicKey, "test", testSignature, true},
+		{"legacy missing global signature", testPublicKey, "test", strings.Join(
+			strings.Split(testSignature, "\n")[:3], "\n"), true},
+		{"hashed", hashedPublicKey, "checksums", hashedSignature, false},
+		{"hashed tampered message", hashedPublicKey, "checksums!", hashedSignature, true},
+		{"hashed tampered trusted comment", hashedPublicKey, "checksums", replaceLine(hashedSignature, 2,
+			"trusted comment: timestamp:1700000000\tfile:other.txt"), true},
+		{"hashed tampered signature", hashedPublicKey, "checksums", tamperLine(hashedSignature, 1), true},
+		{"hashed wrong key", testPublicKey, "checksums", hashedSignature, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			publicKey, err := selfupdate.ParsePublicKey(test.publicKey)
+			if err != nil {
+				t.Fatalf("failed to parse public key: %v", err)
+			}
+			err = publicKey.Verify([]byte(test.message), []byte(test.signature))
+			if (err != nil) != test.wantErr {
+				t.Errorf("expected error %t, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
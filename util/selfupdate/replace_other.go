@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package selfupdate
+
+import "os"
+
+// Rename file to target. On Unix, rename is atomic and the running executable can be replaced.
+func replace(file string, target string) error {
+	return os.Rename(file, target)
+}
@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+)
+
+// Rename file to target. On Windows, the running executable can not be overwritten or deleted,
+// but can be renamed: move it to "<target>.old" first (which is removed in next update),
+// and move it back if the rename of file fails.
+func replace(file string, target string) error {
+	old := target + ".old"
+	os.Remove(old)
+	if err := os.Rename(target, old); err != nil {
+		return fmt.Errorf("failed to rename current binary: %w", err)
+	}
+	if err := os.Rename(file, target); err != nil {
+		if restoreErr := os.Rename(old, target); restoreErr != nil {
+			return fmt.Errorf("failed to rename new binary: %w (restore failed: %w)", err, restoreErr)
+		}
+		return fmt.Errorf("failed to rename new binary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Package selfupdate updates the running ptool binary to the latest GitHub release.
+// Release assets are built by goreleaser (see .goreleaser.yaml): "ptool-v{version}-{os}-{arch}.zip" archives
+// and a "checksums.txt" file of their sha256 checksums, which may be signed by minisign ("checksums.txt.minisig").
+package selfupdate
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	REPO             = "sagan/ptool"
+	API_URL          = "https://api.github.com/repos/" + REPO + "/"
+	CHANNEL_STABLE   = "stable" // latest (non-prerelease) release
+	CHANNEL_DEV      = "dev"    // latest release, including prereleases
+	CHECKSUMS_FILE   = "checksums.txt"
+	SIGNATURE_SUFFIX = ".minisig"
+	DEFAULT_TIMEOUT  = int64(300) // seconds. The timeout of downloading release assets
+	// Number of recent releases to query for dev channel.
+	RELEASES_PAGE_SIZE = 20
+)
+
+type Asset struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+type Release struct {
+	TagName     string   `json:"tag_name"`
+	Name        string   `json:"name"`
+	Draft       bool     `json:"draft"`
+	Prerelease  bool     `json:"prerelease"`
+	PublishedAt string   `json:"published_at"`
+	HtmlUrl     string   `json:"html_url"`
+	Assets      []*Asset `json:"assets"`
+}
+
+type Updater struct {
+	httpClient *http.Client
+	publicKey  *PublicKey // nil: do not verify signature
+}
+
+// Create an updater. If publicKey (minisign public key) is not empty,
+// the checksums file of release must be signed by it.
+func New(httpClient *http.Client, publicKey string) (*Updater, error) {
+	updater := &Updater{httpClient: httpClient}
+	if publicKey != "" {
+		var err error
+		if updater.publicKey, err = ParsePublicKey(publicKey); err != nil {
+			return nil, err
+		}
+	}
+	return updater, nil
+}
+
+// Return the latest release of channel.
+func (u *Updater) GetLatestRelease(channel string) (*Release, error) {
+	switch channel {
+	case CHANNEL_STABLE:
+		release := &Release{}
+		if err := util.FetchJson(API_URL+"releases/latest", release, u.httpClient, header()); err != nil {
+			return nil, fmt.Errorf("failed to get latest release: %w", err)
+		}
+		return release, nil
+	case CHANNEL_DEV:
+		var releases []*Release
+		if err := util.FetchJson(fmt.Sprintf("%sreleases?per_page=%d", API_URL, RELEASES_PAGE_SIZE),
+			&releases, u.httpClient, header()); err != nil {
+			return nil, fmt.Errorf("failed to get releases: %w", err)
+		}
+		for _, release := range releases {
+			if !release.Draft {
+				return release, nil
+			}
+		}
+		return nil, fmt.Errorf("no release found")
+	default:
+		return nil, fmt.Errorf("invalid channel %q", channel)
+	}
+}
+
+// Return the asset of name. Return nil if not found.
+func (r *Release) GetAsset(name string) *Asset {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset
+		}
+	}
+	return nil
+}
+
+// Return the release archive name of the platform, e.g. "ptool-v0.1.10-linux-amd64.zip".
+func AssetName(tag string, goos string, goarch string) string {
+	return fmt.Sprintf("ptool-v%s-%s-%s.zip", strings.TrimPrefix(tag, "v"), goos, goarch)
+}
+
+// Download the release archive of assetName, verify it against the checksums file of release
+// (which signature is also verified if updater has public key), and return the ptool binary contents in it.
+func (u *Updater) Download(release *Release, assetName string) ([]byte, error) {
+	asset := release.GetAsset(assetName)
+	if asset == nil {
+		return nil, fmt.Errorf("release %s has no asset %s", release.TagName, assetName)
+	}
+	checksumsAsset := release.GetAsset(CHECKSUMS_FILE)
+	if checksumsAsset == nil {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, CHECKSUMS_FILE)
+	}
+	checksums, err := u.fetch(checksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	if u.publicKey != nil {
+		signatureAsset := release.GetAsset(CHECKSUMS_FILE + SIGNATURE_SUFFIX)
+		if signatureAsset == nil {
+			return nil, fmt.Errorf("release %s has no %s signature", release.TagName, CHECKSUMS_FILE)
+		}
+		signature, err := u.fetch(signatureAsset)
+		if err != nil {
+			return nil, err
+		}
+		if err = u.publicKey.Verify(checksums, signature); err != nil {
+			return nil, fmt.Errorf("invalid signature of %s: %w", CHECKSUMS_FILE, err)
+		}
+	}
+	checksum := findChecksum(checksums, assetName)
+	if checksum == "" {
+		return nil, fmt.Errorf("checksum of %s not found in %s", assetName, CHECKSUMS_FILE)
+	}
+	archive, err := u.fetch(asset)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+		return nil, fmt.Errorf("checksum mismatch of %s: expected %s, got %x", assetName, checksum, sum)
+	}
+	return extractBinary(archive)
+}
+
+// Replace the binary file of target (the running executable) with contents atomically:
+// contents are written to a temporary file in the same dir first, which is then renamed to target.
+func Apply(contents []byte, target string) error {
+	stat, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	dir, base := filepath.Split(target)
+	file, err := os.CreateTemp(dir, "."+base+".new.*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpname := file.Name()
+	defer os.Remove(tmpname)
+	if _, err = file.Write(contents); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err = os.Chmod(tmpname, stat.Mode().Perm()|0100); err != nil {
+		return err
+	}
+	return replace(tmpname, target)
+}
+
+func (u *Updater) fetch(asset *Asset) ([]byte, error) {
+	res, _, err := util.FetchUrl(asset.BrowserDownloadUrl, u.httpClient, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	return data, nil
+}
+
+// Return the sha256 checksum of name in checksums file ("<hex>  <name>" lines). Return empty string if not found.
+func findChecksum(checksums []byte, name string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name && len(fields[0]) == sha256.Size*2 {
+			if _, err := hex.DecodeString(fields[0]); err == nil {
+				return fields[0]
+			}
+		}
+	}
+	return ""
+}
+
+// Return the contents of the ptool binary ("ptool" or "ptool.exe") in zip archive.
+func extractBinary(archive []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	for _, file := range reader.File {
+		if name := filepath.Base(file.Name); file.FileInfo().IsDir() || name != "ptool" && name != "ptool.exe" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("ptool binary not found in archive")
+}
+
+func header() http.Header {
+	return http.Header{
+		"Accept":               {"application/vnd.github+json"},
+		"X-GitHub-Api-Version": {"2022-11-28"},
+	}
+}
+
+// Compare versions a and b (e.g. "v0.1.10", "v0.1.11-rc1"). Return -1 if a < b, 0 if a == b, 1 if a > b.
+// A version with label (prerelease, e.g. "v0.1.10-DEV") is less than the one of same numbers without label.
+func CompareVersion(a string, b string) int {
+	aNumbers, aLabel := parseVersion(a)
+	bNumbers, bLabel := parseVersion(b)
+	for i := range aNumbers {
+		if aNumbers[i] != bNumbers[i] {
+			if aNumbers[i] < bNumbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aLabel == bLabel:
+		return 0
+	case aLabel == "":
+		return 1
+	case bLabel == "":
+		return -1
+	}
+	return strings.Compare(aLabel, bLabel)
+}
+
+func parseVersion(version string) (numbers [3]int64, label string) {
+	version, label, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	for i, number := range strings.SplitN(version, ".", 3) {
+		numbers[i] = util.ParseInt(number)
+	}
+	return numbers, label
+}
@@ -0,0 +1,38 @@
+package selfupdate
+
+import (
+	"testing"
+)
+
+func TestFindChecksum(t *testing.T) {
+	const (
+		linuxSum   = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		windowsSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		sbomSum    = "486EA46224D1BB4FB680F34F7C9AD96A8F24EC88BE73EA8E5A6C65260E9CB8A7"
+	)
+	checksums := []byte(linuxSum + "  ptool-v0.1.10-linux-amd64.zip\n" +
+		windowsSum + " *ptool-v0.1.10-windows-amd64.zip\r\n" +
+		"\n" +
+		"invalid line\n" +
+		"0a1b2c  ptool-v0.1.10-linux-arm64.zip\n" +
+		sbomSum + "  ptool-v0.1.10-linux-amd64.zip.sbom\n")
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"ptool-v0.1.10-linux-amd64.zip", linuxSum},
+		{"ptool-v0.1.10-windows-amd64.zip", windowsSum}, // binary mode ("*") and CRLF
+		{"ptool-v0.1.10-linux-amd64.zip.sbom", sbomSum},
+		{"ptool-v0.1.10-linux-arm64.zip", ""}, // invalid checksum
+		{"ptool-v0.1.10-darwin-amd64.zip", ""},
+		{"line", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := findChecksum(checksums, test.name); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package selfupdate_test
+
+import (
+	"testing"
+
+	"github.com/sagan/ptool/util/selfupdate"
+)
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"v0.1.10", "v0.1.10", 0},
+		{"0.1.10", "v0.1.10", 0},
+		{"v0.1.10", "v0.1.9", 1},
+		{"v0.1.9", "v0.1.10", -1},
+		{"v0.2.0", "v0.1.99", 1},
+		{"v1.0.0", "v0.99.99", 1},
+		{"v0.1", "v0.1.0", 0},
+		{"v0.1.1", "v0.1", 1},
+		{"v0.1.10-rc1", "v0.1.10", -1},
+		{"v0.1.10", "v0.1.10-DEV", 1},
+		{"v0.1.10-rc1", "v0.1.10-rc2", -1},
+		{"v0.1.11-rc1", "v0.1.10", 1},
+		{"v0.1.10-DEV", "v0.1.10-DEV", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"_"+test.b, func(t *testing.T) {
+			if result := selfupdate.CompareVersion(test.a, test.b); result != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, result)
+			}
+			if result := selfupdate.CompareVersion(test.b, test.a); result != -test.expected {
+				t.Errorf("reversed: expected %d, got %d", -test.expected, result)
+			}
+		})
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	tests := []struct {
+		tag      string
+		goos     string
+		goarch   string
+		expected string
+	}{
+		{"v0.1.10", "linux", "amd64", "ptool-v0.1.10-linux-amd64.zip"},
+		{"0.1.10", "windows", "arm64", "ptool-v0.1.10-windows-arm64.zip"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			if result := selfupdate.AssetName(test.tag, test.goos, test.goarch); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// A column of text table. See PrintTable.
+type TableColumn struct {
+	Title      string
+	Width      int64 // Fixed width of column. 0: flexible, the column shares the remaining width of table
+	MinWidth   int64 // Min width of flexible column
+	RightAlign bool
+}
+
+const TABLE_COLUMN_SEPARATOR = "  "
+
+// Print a text table. Cells are truncated to the width of their columns,
+// ASCII char has 1 width and CJK char has 2 width.
+// width: the total width of table, which the flexible columns fill; if <= 0, width is unlimited and
+// the flexible columns are as wide as their widest cells. If wrap is true, the truncated remain contents
+// of flexible column cells are printed in following lines.
+func PrintTable(output io.Writer, columns []*TableColumn, rows [][]string, width int64, wrap bool, noHeader bool) {
+	widths := make([]int64, len(columns))
+	flexibleCnt := int64(0)
+	fixedWidth := int64(len(TABLE_COLUMN_SEPARATOR) * max(len(columns)-1, 0))
+	for i, column := range columns {
+		if column.Width > 0 {
+			widths[i] = column.Width
+			fixedWidth += column.Width
+		} else {
+			flexibleCnt++
+		}
+	}
+	for i, column := range columns {
+		if column.Width > 0 {
+			continue
+		}
+		if width > 0 {
+			widths[i] = (width - fixedWidth) / flexibleCnt
+		} else {
+			widths[i] = int64(runewidth.StringWidth(column.Title))
+			for _, row := range rows {
+				widths[i] = max(widths[i], int64(runewidth.StringWidth(row[i])))
+			}
+		}
+		widths[i] = max(widths[i], column.MinWidth, 1)
+	}
+	if !noHeader {
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.Title
+		}
+		printTableLine(output, columns, widths, header)
+	}
+	for _, row := range rows {
+		remains := printTableLine(output, columns, widths, row)
+		for wrap {
+			more := false
+			for i, remain := range remains {
+				if columns[i].Width > 0 {
+					remains[i] = ""
+				} else if remains[i] = strings.TrimSpace(remain); remains[i] != "" {
+					more = true
+				}
+			}
+			if !more {
+				break
+			}
+			remains = printTableLine(output, columns, widths, remains)
+		}
+	}
+}
+
+// Print a line of table cells, return the truncated remains of cells.
+func printTableLine(output io.Writer, columns []*TableColumn, widths []int64, cells []string) (remains []string) {
+	sb := &strings.Builder{}
+	remains = make([]string, len(cells))
+	for i, cell := range cells {
+		if i > 0 {
+			sb.WriteString(TABLE_COLUMN_SEPARATOR)
+		}
+		remains[i] = PrintStringInWidth(sb, cell, widths[i], !columns[i].RightAlign)
+	}
+	fmt.Fprintln(output, strings.TrimRight(sb.String(), " "))
+	return remains
+}
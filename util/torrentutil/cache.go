@@ -0,0 +1,202 @@
+package torrentutil
+
+// Local torrents cache. The .torrent contents are stored as "<infohash>.torrent" files in cache dir
+// (see config.GetTorrentCacheDir). Site torrents are also indexed by site torrent id in
+// "sites/<sitename>.<id>.json" files, which contain the infohash and original filename of the torrent.
+// The cache is looked up by infohash (when exporting client torrents) or by site torrent id; site torrents
+// specified by url are saved to cache, but the cache is not read for them as their id is unknown before downloading.
+// The cache is disabled by default. Cache files older than config.GetTorrentCacheTtl are ignored (and removed),
+// and the oldest cache files are removed when the total size of cache dir exceeds config.GetTorrentCacheMaxSize.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+)
+
+type siteTorrentIndex struct {
+	InfoHash string `json:"infoHash"`
+	Filename string `json:"filename"`
+}
+
+var cacheKeyRegexp = regexp.MustCompile(`^[-_a-zA-Z0-9]+$`)
+
+// The known total size of cache dir files. -1: not calculated yet.
+var (
+	cacheSize   = int64(-1)
+	cacheSizeMu sync.Mutex
+)
+
+// Load torrent of infoHash from local cache. Return nil if it's not cached or cache is disabled.
+func LoadCachedTorrent(infoHash string) (content []byte, tinfo *TorrentMeta) {
+	dir := config.GetTorrentCacheDir()
+	if dir == "" || !client.IsValidInfoHash(infoHash) {
+		return nil, nil
+	}
+	infoHash = strings.ToLower(infoHash)
+	content, err := readCacheFile(filepath.Join(dir, infoHash+".torrent"))
+	if err != nil {
+		return nil, nil
+	}
+	if tinfo, err = ParseTorrent(content); err != nil || tinfo.InfoHash != infoHash {
+		log.Debugf("Ignore invalid cached torrent %s (err=%v)", infoHash, err)
+		return nil, nil
+	}
+	log.Tracef("Use cached torrent %s", infoHash)
+	return content, tinfo
+}
+
+// Save torrent contents to local cache, tinfo is the parsed meta of it.
+// It does nothing if cache is disabled. Errors are only logged as cache is optional.
+func CacheTorrent(content []byte, tinfo *TorrentMeta) {
+	dir := config.GetTorrentCacheDir()
+	if dir == "" || tinfo == nil {
+		return
+	}
+	if err := writeCacheFile(dir, tinfo.InfoHash+".torrent", content); err != nil {
+		log.Debugf("Failed to cache torrent %s: %v", tinfo.InfoHash, err)
+	}
+}
+
+// Load site torrent of id from local cache. Return nil if it's not cached or cache is disabled.
+func LoadCachedSiteTorrent(sitename string, id string) (content []byte, tinfo *TorrentMeta, filename string) {
+	dir := config.GetTorrentCacheDir()
+	if dir == "" || !cacheKeyRegexp.MatchString(sitename) || !cacheKeyRegexp.MatchString(id) {
+		return nil, nil, ""
+	}
+	data, err := readCacheFile(filepath.Join(dir, "sites", sitename+"."+id+".json"))
+	if err != nil {
+		return nil, nil, ""
+	}
+	var index *siteTorrentIndex
+	if json.Unmarshal(data, &index) != nil || index == nil {
+		return nil, nil, ""
+	}
+	if content, tinfo = LoadCachedTorrent(index.InfoHash); content == nil {
+		return nil, nil, ""
+	}
+	return content, tinfo, index.Filename
+}
+
+// Save site torrent of id to local cache, and index it by site torrent id.
+func CacheSiteTorrent(sitename string, id string, filename string, content []byte, tinfo *TorrentMeta) {
+	dir := config.GetTorrentCacheDir()
+	if dir == "" || tinfo == nil {
+		return
+	}
+	CacheTorrent(content, tinfo)
+	if !cacheKeyRegexp.MatchString(sitename) || !cacheKeyRegexp.MatchString(id) {
+		return
+	}
+	data, _ := json.Marshal(&siteTorrentIndex{InfoHash: tinfo.InfoHash, Filename: filename})
+	if err := writeCacheFile(filepath.Join(dir, "sites"), sitename+"."+id+".json", data); err != nil {
+		log.Debugf("Failed to cache site torrent %s.%s: %v", sitename, id, err)
+	}
+}
+
+// Export .torrent contents of a client torrent, using local cache if possible.
+// As trackers of torrent may be edited in client, the cache is only used if it has the current tracker
+// of client torrent. Exported torrents are saved to cache.
+func ExportClientTorrent(clientInstance client.Client, torrent *client.Torrent) ([]byte, error) {
+	if content, tinfo := LoadCachedTorrent(torrent.InfoHash); content != nil &&
+		(torrent.Tracker == "" || slices.Contains(tinfo.Trackers, torrent.Tracker)) {
+		return content, nil
+	}
+	content, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+	if err != nil {
+		return nil, err
+	}
+	if tinfo, err := ParseTorrent(content); err == nil {
+		CacheTorrent(content, tinfo)
+	}
+	return content, nil
+}
+
+// Read cache file. If it's expired, remove it and return an error.
+func readCacheFile(filename string) ([]byte, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(stat.ModTime()) > time.Duration(config.GetTorrentCacheTtl())*time.Second {
+		os.Remove(filename)
+		return nil, fmt.Errorf("cache expired")
+	}
+	return os.ReadFile(filename)
+}
+
+// Write file to dir atomically, then remove the oldest cache files if cache dir exceeds max size.
+func writeCacheFile(dir string, name string, data []byte) error {
+	if err := os.MkdirAll(dir, constants.PERM_DIR); err != nil {
+		return err
+	}
+	tmpfile := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", name, os.Getpid()))
+	if err := os.WriteFile(tmpfile, data, constants.PERM); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpfile, filepath.Join(dir, name)); err != nil {
+		os.Remove(tmpfile)
+		return err
+	}
+	cacheSizeMu.Lock()
+	defer cacheSizeMu.Unlock()
+	if cacheSize < 0 {
+		cacheSize = pruneCache(config.GetTorrentCacheDir(), -1)
+	} else {
+		cacheSize += int64(len(data))
+	}
+	if maxSize := config.GetTorrentCacheMaxSize(); cacheSize > maxSize {
+		cacheSize = pruneCache(config.GetTorrentCacheDir(), maxSize*9/10)
+	}
+	return nil
+}
+
+// Remove the oldest files of cache dir until the total size of it <= maxSize (-1: no limit).
+// Return the total size of remaining files.
+func pruneCache(dir string, maxSize int64) (size int64) {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []*cacheFile
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			files = append(files, &cacheFile{path, info.Size(), info.ModTime()})
+			size += info.Size()
+		}
+		return nil
+	})
+	if maxSize < 0 || size <= maxSize {
+		return size
+	}
+	slices.SortFunc(files, func(a, b *cacheFile) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	for _, file := range files {
+		if size <= maxSize {
+			break
+		}
+		if err := os.Remove(file.path); err == nil {
+			size -= file.size
+		}
+	}
+	log.Debugf("Pruned torrents cache dir, total size: %d", size)
+	return size
+}
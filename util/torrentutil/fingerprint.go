@@ -0,0 +1,217 @@
+package torrentutil
+
+// Content fingerprint of torrents. Torrents of the same contents (files) may have different info-hashes,
+// e.g. torrents of different sites (trackers, "source" flag) made for the same files. The fingerprint
+// only use the (sorted) files paths and sizes, and ignores the root folder name, so the identical payloads
+// of these torrents could be matched.
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util/releasename"
+)
+
+type ContentIndexEntry struct {
+	Source        string                       `json:"source"` // client name, or local .torrent filename
+	InfoHash      string                       `json:"infoHash"`
+	Name          string                       `json:"name"`
+	Size          int64                        `json:"size"`
+	Tracker       string                       `json:"tracker"`
+	Meta          *TorrentMeta                 `json:"-"` // nil for client torrent
+	ClientTorrent *client.Torrent              `json:"-"` // nil for local torrent
+	Contents      []*client.TorrentContentFile `json:"-"` // files of client torrent. Loaded on demand
+	fingerprint   string
+	client        client.Client
+}
+
+type ContentIndex struct {
+	Entries []*ContentIndexEntry
+	sizes   map[int64][]*ContentIndexEntry
+}
+
+func NewContentIndex() *ContentIndex {
+	return &ContentIndex{sizes: map[int64][]*ContentIndexEntry{}}
+}
+
+// Calculate content fingerprint from files (full path joined by "/", and size) of a torrent.
+// The root folder is ignored, as well as the padding files.
+func ContentFingerprint(paths []string, sizes []int64) string {
+	rootDir := ""
+	if len(paths) > 0 && strings.Contains(paths[0], "/") {
+		rootDir, _, _ = strings.Cut(paths[0], "/")
+		for _, p := range paths {
+			if !strings.HasPrefix(p, rootDir+"/") {
+				rootDir = ""
+				break
+			}
+		}
+	}
+	lines := []string{}
+	for i, p := range paths {
+		if rootDir != "" {
+			p = p[len(rootDir)+1:]
+		}
+		if strings.HasPrefix(p, ".pad/") || strings.HasPrefix(path.Base(p), "_____padding_file") {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d\t%s\n", sizes[i], p))
+	}
+	slices.Sort(lines)
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(lines, ""))))
+}
+
+// Return the content fingerprint of torrent. See ContentFingerprint.
+func (meta *TorrentMeta) ContentFingerprint() string {
+	var paths []string
+	var sizes []int64
+	for _, file := range meta.Files {
+		if meta.RootDir != "" {
+			paths = append(paths, meta.RootDir+"/"+file.Path)
+		} else {
+			paths = append(paths, file.Path)
+		}
+		sizes = append(sizes, file.Size)
+	}
+	return ContentFingerprint(paths, sizes)
+}
+
+// Load (if not loaded yet) and return the content fingerprint of entry.
+// For client torrent, the files are fetched from client.
+func (entry *ContentIndexEntry) Fingerprint() (string, error) {
+	if entry.fingerprint != "" {
+		return entry.fingerprint, nil
+	}
+	if entry.Meta != nil {
+		entry.fingerprint = entry.Meta.ContentFingerprint()
+		return entry.fingerprint, nil
+	}
+	if entry.Contents == nil {
+		contents, err := entry.client.GetTorrentContents(entry.InfoHash)
+		if err != nil {
+			return "", fmt.Errorf("failed to get client torrent %s contents: %w", entry.InfoHash, err)
+		}
+		entry.Contents = contents
+	}
+	var paths []string
+	var sizes []int64
+	for _, file := range entry.Contents {
+		paths = append(paths, file.Path)
+		sizes = append(sizes, file.Size)
+	}
+	entry.fingerprint = ContentFingerprint(paths, sizes)
+	return entry.fingerprint, nil
+}
+
+// Add a local torrent to index. source: the .torrent filename.
+func (index *ContentIndex) AddTorrentMeta(source string, tinfo *TorrentMeta) *ContentIndexEntry {
+	name := tinfo.ContentPath
+	tracker := ""
+	if len(tinfo.Trackers) > 0 {
+		tracker = tinfo.Trackers[0]
+	}
+	return index.add(&ContentIndexEntry{
+		Source:   source,
+		InfoHash: tinfo.InfoHash,
+		Name:     name,
+		Size:     tinfo.Size,
+		Tracker:  tracker,
+		Meta:     tinfo,
+	})
+}
+
+// Add client torrents to index. The files of torrents are fetched from client only when needed.
+func (index *ContentIndex) AddClientTorrents(clientName string, clientInstance client.Client,
+	torrents []*client.Torrent) {
+	for _, torrent := range torrents {
+		index.add(&ContentIndexEntry{
+			Source:        clientName,
+			InfoHash:      torrent.InfoHash,
+			Name:          torrent.Name,
+			Size:          torrent.SizeTotal,
+			Tracker:       torrent.Tracker,
+			ClientTorrent: torrent,
+			client:        clientInstance,
+		})
+	}
+}
+
+func (index *ContentIndex) add(entry *ContentIndexEntry) *ContentIndexEntry {
+	index.Entries = append(index.Entries, entry)
+	index.sizes[entry.Size] = append(index.sizes[entry.Size], entry)
+	return entry
+}
+
+// Return the entries which contents size is size, in the order they are added.
+func (index *ContentIndex) FindBySize(size int64) []*ContentIndexEntry {
+	return index.sizes[size]
+}
+
+// Similar to FindBySize, but the entries of the same release with tinfo (by parsed release names
+// of their names and tinfo content path, see releasename.Release.Match) are ordered first.
+func (index *ContentIndex) FindBySizeAndRelease(tinfo *TorrentMeta) []*ContentIndexEntry {
+	release := releasename.Parse(tinfo.ContentPath)
+	var matched, others []*ContentIndexEntry
+	for _, entry := range index.sizes[tinfo.Size] {
+		if release.Match(releasename.Parse(entry.Name)) {
+			matched = append(matched, entry)
+		} else {
+			others = append(others, entry)
+		}
+	}
+	return append(matched, others...)
+}
+
+// Return the entries which have the same contents with tinfo, in the order they are added.
+func (index *ContentIndex) Find(tinfo *TorrentMeta) []*ContentIndexEntry {
+	fingerprint := tinfo.ContentFingerprint()
+	var entries []*ContentIndexEntry
+	for _, entry := range index.sizes[tinfo.Size] {
+		if entry.Meta == tinfo {
+			continue
+		}
+		if entryFingerprint, err := entry.Fingerprint(); err != nil {
+			log.Debugf("%s: %v", entry.Source, err)
+		} else if entryFingerprint == fingerprint {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Return groups of entries which have the same contents. Each group has at least 2 entries.
+// Only the entries which contents size is same with others are fingerprinted.
+// errs: the errors of entries which fingerprint failed to load.
+func (index *ContentIndex) Duplicates() (groups [][]*ContentIndexEntry, errs []error) {
+	for _, entry := range index.Entries {
+		entries := index.sizes[entry.Size]
+		if len(entries) < 2 || entries[0] != entry {
+			continue
+		}
+		fingerprints := map[string][]*ContentIndexEntry{}
+		var order []string
+		for _, entry := range entries {
+			fingerprint, err := entry.Fingerprint()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", entry.Source, err))
+				continue
+			}
+			if fingerprints[fingerprint] == nil {
+				order = append(order, fingerprint)
+			}
+			fingerprints[fingerprint] = append(fingerprints[fingerprint], entry)
+		}
+		for _, fingerprint := range order {
+			if len(fingerprints[fingerprint]) > 1 {
+				groups = append(groups, fingerprints[fingerprint])
+			}
+		}
+	}
+	return groups, errs
+}
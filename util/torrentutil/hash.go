@@ -0,0 +1,144 @@
+package torrentutil
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Options of hashing torrent contents files when verifying.
+type HashOptions struct {
+	Workers   int64 // number of workers which hash pieces (v1) or files (v2) concurrently. <= 0: 1
+	ReadAhead int64 // max size (bytes) of (v1) pieces data read ahead of hashing workers. <= 0: one piece
+}
+
+// A piece of torrent contents read from disk, to be hashed by workers.
+type pieceJob struct {
+	index int
+	data  []byte
+}
+
+// Verify the (v1) piece hashes of torrent contents files (filenames, in the order of meta.Files).
+// The files are read sequentially by one goroutine, which reads ahead at most options.ReadAhead bytes,
+// while the pieces are hashed concurrently by options.Workers workers.
+// If quick is true, only the first and last piece of each file are verified.
+func (meta *TorrentMeta) verifyPieces(filenames []string, quick bool, options HashOptions) error {
+	pieceLength := meta.Info.PieceLength
+	workers := max(int(options.Workers), 1)
+	buffers := max(int(options.ReadAhead/pieceLength), 1) + workers
+	// the free piece buffers. Allocated on demand
+	pool := make(chan []byte, buffers)
+	for range buffers {
+		pool <- nil
+	}
+	jobs := make(chan *pieceJob, buffers)
+	done := make(chan struct{})
+	var verifyErr error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			verifyErr = err
+			close(done)
+		})
+	}
+	piecesCnt := meta.Info.NumPieces()
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hash := sha1.New()
+			for job := range jobs {
+				select {
+				case <-done:
+				default:
+					hash.Reset()
+					hash.Write(job.data)
+					p := meta.Info.Piece(job.index)
+					good := bytes.Equal(hash.Sum(nil), p.Hash().Bytes())
+					log.Tracef("piece %d/%d verify-hash %x: %v", job.index, piecesCnt-1, p.Hash(), good)
+					if !good {
+						fail(fmt.Errorf("piece %d/%d: hash mismatch", job.index, piecesCnt-1))
+					}
+				}
+				pool <- job.data
+			}
+		}()
+	}
+	if err := meta.readPieces(filenames, quick, pool, jobs, done); err != nil {
+		fail(err)
+	}
+	close(jobs)
+	wg.Wait()
+	return verifyErr
+}
+
+// Read the pieces of torrent contents files in order and send them to jobs, until all pieces are read
+// or done is closed.
+func (meta *TorrentMeta) readPieces(filenames []string, quick bool, pool chan []byte, jobs chan<- *pieceJob,
+	done <-chan struct{}) error {
+	pieceLength := meta.Info.PieceLength
+	piecesCnt := meta.Info.NumPieces()
+	var currentFileIndex = int64(0)
+	var currentFileOffset = int64(0)
+	var currentFileRemain = int64(0)
+	var currentFile *os.File
+	defer func() {
+		if currentFile != nil {
+			currentFile.Close()
+		}
+	}()
+	for i := 0; i < piecesCnt; i++ {
+		if quick && currentFile != nil && currentFileRemain > pieceLength {
+			// skip to the last piece of current file
+			skipPieces := (currentFileRemain - 1) / pieceLength
+			skipLength := skipPieces * pieceLength
+			currentFileOffset += skipLength
+			currentFileRemain -= skipLength
+			i += int(skipPieces)
+		}
+		var data []byte
+		select {
+		case data = <-pool:
+		case <-done:
+			return nil
+		}
+		if data == nil {
+			data = make([]byte, pieceLength)
+		}
+		p := meta.Info.Piece(i)
+		data = data[:p.Length()]
+		offset := int64(0)
+		for offset < p.Length() {
+			if currentFile == nil {
+				var err error
+				if currentFile, err = os.Open(filenames[currentFileIndex]); err != nil {
+					return fmt.Errorf("piece %d/%d: failed to open file %s: %w",
+						i, piecesCnt-1, filenames[currentFileIndex], err)
+				}
+				log.Tracef("piece %d/%d: open file %s", i, piecesCnt-1, filenames[currentFileIndex])
+				currentFileOffset = 0
+				currentFileRemain = meta.Files[currentFileIndex].Size
+			}
+			readlen := min(currentFileRemain, p.Length()-offset)
+			if _, err := currentFile.ReadAt(data[offset:offset+readlen], currentFileOffset); err != nil {
+				return fmt.Errorf("piece %d/%d: failed to read file %s: %w",
+					i, piecesCnt-1, filenames[currentFileIndex], err)
+			}
+			currentFileOffset += readlen
+			currentFileRemain -= readlen
+			offset += readlen
+			if currentFileRemain == 0 {
+				currentFile.Close()
+				currentFile = nil
+				currentFileIndex++
+			}
+		}
+		jobs <- &pieceJob{index: i, data: data}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package torrentutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+// A file of generated test torrent contents.
+type testTorrentFile struct {
+	name string
+	size int
+}
+
+// Files of generated multi-file torrent: 110KiB in total, 7 pieces of 16KiB (the last one is 14KiB).
+// "empty.bin" is zero-length; "b.bin" (offsets 40KiB - 60KiB) spans pieces 2 and 3;
+// "c.bin" (60KiB - 110KiB) spans pieces 3 - 6.
+var hashTestFiles = []testTorrentFile{
+	{"a.bin", 40 * 1024},
+	{"empty.bin", 0},
+	{"b.bin", 20 * 1024},
+	{"c.bin", 50 * 1024},
+}
+
+// Create the files in dir/data, and return the torrent of them with 16KiB pieces,
+// which is also written to dir/test.torrent. format: torrent format, empty for v1.
+func makeTestTorrent(t *testing.T, dir string, files []testTorrentFile, format string) *torrentutil.TorrentMeta {
+	contentPath := filepath.Join(dir, "data")
+	if err := os.MkdirAll(contentPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range files {
+		data := make([]byte, file.size)
+		for j := range data {
+			data[j] = byte(i*31 + j*7)
+		}
+		if err := os.WriteFile(filepath.Join(contentPath, file.name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tinfo, err := torrentutil.MakeTorrent(&torrentutil.TorrentMakeOptions{
+		ContentPath:    contentPath,
+		Output:         filepath.Join(dir, "test.torrent"),
+		PieceLengthStr: "16KiB",
+		CreationDate:   "none",
+		Format:         format,
+	})
+	if err != nil {
+		t.Fatalf("failed to make torrent: %v", err)
+	}
+	return tinfo
+}
+
+// Overwrite one byte of file at offset.
+func corruptByte(filename string, offset int64) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteAt([]byte{'x'}, offset)
+	return err
+}
+
+func TestVerifyPieces(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(contentPath string) error // modify contents after making torrent
+		checkHash int64                          // 1: quick; 2: full
+		options   *torrentutil.HashOptions
+		wantErr   bool
+	}{
+		{"intact", nil, 2, nil, false},
+		{"intact quick", nil, 1, nil, false},
+		{"intact concurrent", nil, 2, &torrentutil.HashOptions{Workers: 4, ReadAhead: 64 * 1024}, false},
+		{"intact concurrent quick", nil, 1, &torrentutil.HashOptions{Workers: 3, ReadAhead: 1}, false},
+		{"corrupted first piece", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "a.bin"), 0)
+		}, 2, &torrentutil.HashOptions{Workers: 4}, true},
+		{"corrupted first piece quick", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "a.bin"), 0)
+		}, 1, nil, true},
+		{"corrupted piece spanning files", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "c.bin"), 0) // piece 3
+		}, 2, &torrentutil.HashOptions{Workers: 2, ReadAhead: 32 * 1024}, true},
+		{"corrupted middle piece", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "c.bin"), 25*1024) // piece 5
+		}, 2, &torrentutil.HashOptions{Workers: 4, ReadAhead: 1024 * 1024}, true},
+		{"corrupted middle piece quick", func(contentPath string) error {
+			// quick mode only verifies the first and last pieces of each file
+			return corruptByte(filepath.Join(contentPath, "c.bin"), 25*1024)
+		}, 1, nil, false},
+		{"corrupted last piece quick", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "c.bin"), 50*1024-1) // piece 6
+		}, 1, &torrentutil.HashOptions{Workers: 4}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tinfo := makeTestTorrent(t, dir, hashTestFiles, "")
+			if tinfo.Info.NumPieces() != 7 {
+				t.Fatalf("unexpected torrent pieces: %d x %d", tinfo.Info.NumPieces(), tinfo.Info.PieceLength)
+			}
+			if test.modify != nil {
+				if err := test.modify(filepath.Join(dir, "data")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := tinfo.Verify(dir, "", test.checkHash, test.options)
+			if test.wantErr && err == nil {
+				t.Errorf("expected error, got nil")
+			} else if !test.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package torrentutil
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Result of matching torrent contents against local disk files. See TorrentMeta.Match.
+type MatchResult struct {
+	Files         int64 `json:"files"`
+	MatchedFiles  int64 `json:"matchedFiles"` // files which exist in disk with the same size (and contents, if hashed)
+	Size          int64 `json:"size"`
+	MatchedSize   int64 `json:"matchedSize"`   // total size of matched files
+	Pieces        int64 `json:"pieces"`        // only set if v1 pieces are hashed
+	MatchedPieces int64 `json:"matchedPieces"` // pieces which hash matches with disk contents
+}
+
+// Return the match percentage (0-100) of torrent contents. It's the percentage of matched pieces
+// if v1 pieces are hashed, otherwise the percentage of the total size of matched files.
+func (result *MatchResult) Percent() float64 {
+	if result.Pieces > 0 {
+		return float64(result.MatchedPieces) * 100 / float64(result.Pieces)
+	}
+	if result.Size == 0 {
+		return 100
+	}
+	return float64(result.MatchedSize) * 100 / float64(result.Size)
+}
+
+// Match torrent contents against local disk files in savePath (or contentPath, see Verify).
+// Unlike Verify, it does not fail on the first mismatch, but reports how much of torrent contents match.
+// A file matches if it exists with the same size. If checkHash is true, the file contents are also hashed:
+// for v2 (or hybrid) torrent, each file is hashed against it's "pieces root";
+// for v1 torrent, each piece is hashed (sequentially) and pieces that span any mismatched file do not match.
+func (meta *TorrentMeta) Match(savePath string, contentPath string, checkHash bool) (*MatchResult, error) {
+	filenames, err := meta.localFilenames(savePath, contentPath)
+	if err != nil {
+		return nil, err
+	}
+	result := &MatchResult{Files: int64(len(meta.Files)), Size: meta.Size}
+	matched := make([]bool, len(meta.Files))
+	for i, file := range meta.Files {
+		if stat, err := os.Stat(filenames[i]); err != nil || stat.Size() != file.Size {
+			log.Debugf("File %q does not match: %v", file.Path, err)
+			continue
+		}
+		if checkHash && meta.InfoHashV2 != "" && file.Size > 0 {
+			piecesRoot, _, err := hashFileV2(filenames[i], file.Size, meta.Info.PieceLength)
+			if err != nil || string(piecesRoot) != file.piecesRoot {
+				log.Debugf("File %q contents do not match: %v", file.Path, err)
+				continue
+			}
+		}
+		matched[i] = true
+		result.MatchedFiles++
+		result.MatchedSize += file.Size
+	}
+	if !checkHash || meta.InfoHashV2 != "" || len(meta.Files) == 0 {
+		return result, nil
+	}
+	if meta.source != nil {
+		return nil, ErrPiecesNotLoaded
+	}
+	result.Pieces, result.MatchedPieces, err = meta.matchPieces(filenames, matched)
+	return result, err
+}
+
+// Hash the (v1) pieces of torrent contents files sequentially.
+// Return the count of all pieces and the matched pieces.
+// A piece does not match if it spans any file which is not matched (e.g. not exists or has wrong size).
+func (meta *TorrentMeta) matchPieces(filenames []string, matched []bool) (pieces int64, matchedPieces int64,
+	err error) {
+	pieceLength := meta.Info.PieceLength
+	// offsets[i] is the offset of meta.Files[i] in torrent contents
+	offsets := make([]int64, len(meta.Files)+1)
+	for i, file := range meta.Files {
+		offsets[i+1] = offsets[i] + file.Size
+	}
+	var currentFile *os.File
+	currentFileIndex := -1
+	defer func() {
+		if currentFile != nil {
+			currentFile.Close()
+		}
+	}()
+	readFile := func(index int, data []byte, offset int64) error {
+		if currentFileIndex != index {
+			if currentFile != nil {
+				currentFile.Close()
+				currentFile = nil
+			}
+			file, err := os.Open(filenames[index])
+			if err != nil {
+				return err
+			}
+			currentFile, currentFileIndex = file, index
+		}
+		_, err := currentFile.ReadAt(data, offset)
+		return err
+	}
+	hash := sha1.New()
+	buf := make([]byte, pieceLength)
+	fileIndex := 0
+	piecesCnt := meta.Info.NumPieces()
+	for i := 0; i < piecesCnt; i++ {
+		p := meta.Info.Piece(i)
+		start := int64(i) * pieceLength
+		end := start + p.Length()
+		for fileIndex < len(meta.Files)-1 && offsets[fileIndex+1] <= start {
+			fileIndex++
+		}
+		good := true
+		hash.Reset()
+		for j := fileIndex; good && j < len(meta.Files) && offsets[j] < end; j++ {
+			if meta.Files[j].Size == 0 {
+				continue
+			}
+			if !matched[j] {
+				good = false
+				break
+			}
+			from, to := max(start, offsets[j]), min(end, offsets[j+1])
+			data := buf[:to-from]
+			if err := readFile(j, data, from-offsets[j]); err != nil {
+				log.Debugf("piece %d/%d: failed to read file %s: %v", i, piecesCnt-1, filenames[j], err)
+				good = false
+				break
+			}
+			hash.Write(data)
+		}
+		good = good && bytes.Equal(hash.Sum(nil), p.Hash().Bytes())
+		log.Tracef("piece %d/%d match-hash %x: %v", i, piecesCnt-1, p.Hash(), good)
+		if good {
+			matchedPieces++
+		}
+	}
+	return int64(piecesCnt), matchedPieces, nil
+}
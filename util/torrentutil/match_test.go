@@ -0,0 +1,78 @@
+package torrentutil_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+const matchTestPieceLength = 16 * 1024
+
+// Files of generated multi-file torrent: 90KiB in total, 6 pieces of 16KiB (the last one is 10KiB).
+// "b" (offsets 40KiB - 60KiB) spans pieces 2 and 3; "c" (60KiB - 90KiB) spans pieces 3, 4 and 5.
+var matchTestFiles = []testTorrentFile{
+	{"a.bin", 40 * 1024},
+	{"b.bin", 20 * 1024},
+	{"c.bin", 30 * 1024},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(contentPath string) error // modify contents after making torrent
+		checkHash     bool
+		matchedFiles  int64
+		matchedSize   int64
+		pieces        int64
+		matchedPieces int64
+	}{
+		{"intact", nil, true, 3, 90 * 1024, 6, 6},
+		{"corrupted file", func(contentPath string) error {
+			return os.WriteFile(filepath.Join(contentPath, "b.bin"), bytes.Repeat([]byte{'x'}, 20*1024), 0644)
+		}, true, 3, 90 * 1024, 6, 4},
+		{"corrupted byte", func(contentPath string) error {
+			return corruptByte(filepath.Join(contentPath, "c.bin"), 29*1024) // piece 5
+		}, true, 3, 90 * 1024, 6, 5},
+		{"missing file", func(contentPath string) error {
+			return os.Remove(filepath.Join(contentPath, "c.bin"))
+		}, true, 2, 60 * 1024, 6, 3},
+		{"wrong size file", func(contentPath string) error {
+			return os.WriteFile(filepath.Join(contentPath, "a.bin"), []byte("a"), 0644)
+		}, true, 2, 50 * 1024, 6, 3},
+		{"corrupted file without hash check", func(contentPath string) error {
+			return os.WriteFile(filepath.Join(contentPath, "b.bin"), bytes.Repeat([]byte{'x'}, 20*1024), 0644)
+		}, false, 3, 90 * 1024, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tinfo := makeTestTorrent(t, dir, matchTestFiles, "")
+			if tinfo.Info.PieceLength != matchTestPieceLength || tinfo.Info.NumPieces() != 6 {
+				t.Fatalf("unexpected torrent pieces: %d x %d", tinfo.Info.NumPieces(), tinfo.Info.PieceLength)
+			}
+			if test.modify != nil {
+				if err := test.modify(filepath.Join(dir, "data")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result, err := tinfo.Match(dir, "", test.checkHash)
+			if err != nil {
+				t.Fatalf("failed to match: %v", err)
+			}
+			expected := &torrentutil.MatchResult{
+				Files:         3,
+				MatchedFiles:  test.matchedFiles,
+				Size:          90 * 1024,
+				MatchedSize:   test.matchedSize,
+				Pieces:        test.pieces,
+				MatchedPieces: test.matchedPieces,
+			}
+			if *result != *expected {
+				t.Errorf("expected %+v, got %+v", *expected, *result)
+			}
+		})
+	}
+}
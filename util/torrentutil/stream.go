@@ -0,0 +1,393 @@
+package torrentutil
+
+// Streaming parsing of .torrent files. The large values (the "pieces" of info dict and the "piece layers"
+// of BitTorrent v2) are never loaded into memory, so that huge .torrent files (hundreds of MiB) can be
+// inspected and edited with limited memory.
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/sagan/ptool/constants"
+)
+
+// Max length of a string value of .torrent file which is loaded into memory in streaming parsing.
+const STREAM_MAX_STRING_LENGTH = 16 * 1024 * 1024
+
+var ErrPiecesNotLoaded = errors.New("pieces of torrent are not loaded as it's parsed in streaming mode")
+
+// Source of torrent meta parsed by ParseTorrentFile.
+type streamSource struct {
+	filename  string
+	size      int64
+	mtime     int64
+	rawRanges map[string][2]int64 // top-level key => [offset, length] of it's (not loaded) raw value in file
+}
+
+type bencodeScanner struct {
+	r      *bufio.Reader
+	offset int64
+	tee    io.Writer // if not nil, all consumed bytes are also written to it
+}
+
+// Parse a local .torrent file in streaming mode. The returned meta has all fields except Info.Pieces,
+// and the MetaInfo.InfoBytes & MetaInfo.PieceLayers; they are copied from the original file when
+// writing the meta (see Write), so the file should not be modified before that.
+func ParseTorrentFile(filename string) (*TorrentMeta, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	s := &bencodeScanner{r: bufio.NewReader(file)}
+	if b, err := s.readByte(); err != nil || b != 'd' {
+		return nil, fmt.Errorf("invalid torrent: not a bencode dict")
+	}
+	top := map[string]any{}
+	rawRanges := map[string][2]int64{}
+	var info map[string]any
+	infoHash, infoHashV2 := "", ""
+	for {
+		if b, err := s.peekByte(); err != nil {
+			return nil, err
+		} else if b == 'e' {
+			break
+		}
+		key, _, err := s.readString(false)
+		if err != nil {
+			return nil, err
+		}
+		start := s.offset
+		switch key {
+		case "info":
+			hash, hashV2 := sha1.New(), sha256.New()
+			s.tee = io.MultiWriter(hash, hashV2)
+			info, err = s.readInfo()
+			s.tee = nil
+			infoHash = hex.EncodeToString(hash.Sum(nil))
+			infoHashV2 = hex.EncodeToString(hashV2.Sum(nil))
+		case "piece layers":
+			_, err = s.readValue(true)
+		default:
+			top[key], err = s.readValue(false)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q: %w", key, err)
+		}
+		rawRanges[key] = [2]int64{start, s.offset - start}
+	}
+	if info == nil {
+		return nil, fmt.Errorf("invalid torrent: no info dict")
+	}
+	var metaInfo metainfo.MetaInfo
+	var torrentInfo metainfo.Info
+	if err := remarshal(top, &metaInfo); err != nil {
+		return nil, err
+	}
+	if err := remarshal(info, &torrentInfo); err != nil {
+		return nil, fmt.Errorf("invalid info: %w", err)
+	}
+	meta, err := FromMetaInfo(&metaInfo, &torrentInfo)
+	if err != nil {
+		return nil, err
+	}
+	meta.InfoHash = infoHash
+	if torrentInfo.MetaVersion == 2 {
+		fileTree, _ := info["file tree"].(map[string]any)
+		meta.setV2Info(infoHashV2, fileTree)
+	}
+	if filename, err = filepath.Abs(filename); err != nil {
+		return nil, err
+	}
+	meta.source = &streamSource{
+		filename:  filename,
+		size:      stat.Size(),
+		mtime:     stat.ModTime().UnixNano(),
+		rawRanges: rawRanges,
+	}
+	return meta, nil
+}
+
+// Write .torrent contents of meta to w. For meta parsed by ParseTorrentFile, the not loaded raw values
+// are copied from the original file.
+func (meta *TorrentMeta) Write(w io.Writer) error {
+	if meta.source == nil {
+		return meta.MetaInfo.Write(w)
+	}
+	_, err := meta.writeStreamed(w)
+	return err
+}
+
+// Write meta parsed by ParseTorrentFile to w. Return the [offset, length] ranges of raw values in written contents.
+func (meta *TorrentMeta) writeStreamed(w io.Writer) (rawRanges map[string][2]int64, err error) {
+	file, err := os.Open(meta.source.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if stat, err := file.Stat(); err != nil {
+		return nil, err
+	} else if stat.Size() != meta.source.size || stat.ModTime().UnixNano() != meta.source.mtime {
+		return nil, fmt.Errorf("original torrent file %s has been modified", meta.source.filename)
+	}
+	var values map[string]bencode.Bytes
+	if err := remarshal(meta.MetaInfo, &values); err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	for key := range meta.source.rawRanges {
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	bw := bufio.NewWriter(w)
+	offset, _ := bw.WriteString("d")
+	rawRanges = map[string][2]int64{}
+	for _, key := range keys {
+		n, _ := fmt.Fprintf(bw, "%d:%s", len(key), key)
+		offset += n
+		if r, ok := meta.source.rawRanges[key]; ok {
+			if _, err := io.Copy(bw, io.NewSectionReader(file, r[0], r[1])); err != nil {
+				return nil, err
+			}
+			rawRanges[key] = [2]int64{int64(offset), r[1]}
+			offset += int(r[1])
+		} else {
+			n, _ = bw.Write(values[key])
+			offset += n
+		}
+	}
+	bw.WriteString("e")
+	return rawRanges, bw.Flush()
+}
+
+// Write .torrent contents of meta to file atomically, which could be the original file meta is parsed from.
+func (meta *TorrentMeta) WriteFile(filename string) error {
+	tmpfile := fmt.Sprintf("%s.%d.tmp", filename, os.Getpid())
+	file, err := os.OpenFile(tmpfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.PERM)
+	if err != nil {
+		return err
+	}
+	var rawRanges map[string][2]int64
+	if meta.source != nil {
+		rawRanges, err = meta.writeStreamed(file)
+	} else {
+		err = meta.MetaInfo.Write(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpfile, filename)
+	}
+	if err != nil {
+		os.Remove(tmpfile)
+		return err
+	}
+	// the original file is overwritten, use the new one as source
+	if absFilename, err := filepath.Abs(filename); err == nil && meta.source != nil &&
+		meta.source.filename == absFilename {
+		if stat, err := os.Stat(filename); err == nil {
+			meta.source.size = stat.Size()
+			meta.source.mtime = stat.ModTime().UnixNano()
+			meta.source.rawRanges = rawRanges
+		}
+	}
+	return nil
+}
+
+// Convert a value to another type through bencode.
+func remarshal(src any, dst any) error {
+	data, err := bencode.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return bencode.Unmarshal(data, dst)
+}
+
+// Read the info dict, skipping the "pieces".
+func (s *bencodeScanner) readInfo() (info map[string]any, err error) {
+	if b, err := s.readByte(); err != nil {
+		return nil, err
+	} else if b != 'd' {
+		return nil, fmt.Errorf("not a dict")
+	}
+	info = map[string]any{}
+	for {
+		if b, err := s.peekByte(); err != nil {
+			return nil, err
+		} else if b == 'e' {
+			s.readByte()
+			return info, nil
+		}
+		key, _, err := s.readString(false)
+		if err != nil {
+			return nil, err
+		}
+		if key == "pieces" {
+			_, _, err = s.readString(true)
+		} else {
+			info[key], err = s.readValue(false)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Read a value, which is int64, string, []any or map[string]any.
+// If discard is true, the value is skipped and nil is returned.
+func (s *bencodeScanner) readValue(discard bool) (any, error) {
+	b, err := s.peekByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b == 'i':
+		s.readByte()
+		return s.readInt('e')
+	case b >= '0' && b <= '9':
+		str, _, err := s.readString(discard)
+		if discard {
+			return nil, err
+		}
+		return str, err
+	case b == 'l' || b == 'd':
+		s.readByte()
+		var list []any
+		dict := map[string]any{}
+		for {
+			if b, err := s.peekByte(); err != nil {
+				return nil, err
+			} else if b == 'e' {
+				s.readByte()
+				break
+			}
+			key := ""
+			if b == 'd' {
+				if key, _, err = s.readString(false); err != nil {
+					return nil, err
+				}
+			}
+			value, err := s.readValue(discard)
+			if err != nil {
+				return nil, err
+			}
+			if discard {
+				continue
+			}
+			if b == 'd' {
+				dict[key] = value
+			} else {
+				list = append(list, value)
+			}
+		}
+		if discard {
+			return nil, nil
+		}
+		if b == 'd' {
+			return dict, nil
+		}
+		if list == nil {
+			list = []any{}
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("invalid bencode value at offset %d", s.offset)
+	}
+}
+
+// Read a string. If discard is true, the string contents is skipped and not returned.
+func (s *bencodeScanner) readString(discard bool) (str string, length int64, err error) {
+	if length, err = s.readInt(':'); err != nil {
+		return
+	}
+	if length < 0 {
+		return "", 0, fmt.Errorf("invalid string length at offset %d", s.offset)
+	}
+	if discard {
+		w := io.Discard
+		if s.tee != nil {
+			w = s.tee
+		}
+		n, err := io.CopyN(w, s.r, length)
+		s.offset += n
+		return "", length, err
+	}
+	if length > STREAM_MAX_STRING_LENGTH {
+		return "", 0, fmt.Errorf("string at offset %d is too long (%d)", s.offset, length)
+	}
+	buf := make([]byte, length)
+	n, err := io.ReadFull(s.r, buf)
+	s.offset += int64(n)
+	if err != nil {
+		return "", 0, err
+	}
+	if s.tee != nil {
+		s.tee.Write(buf)
+	}
+	return string(buf), length, nil
+}
+
+// Read an integer which ends with delim.
+func (s *bencodeScanner) readInt(delim byte) (int64, error) {
+	var buf []byte
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == delim {
+			break
+		}
+		if len(buf) >= 20 || (b < '0' || b > '9') && b != '-' {
+			return 0, fmt.Errorf("invalid integer at offset %d", s.offset-1)
+		}
+		buf = append(buf, b)
+	}
+	return strconv.ParseInt(string(buf), 10, 64)
+}
+
+func (s *bencodeScanner) readByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	s.offset++
+	if s.tee != nil {
+		s.tee.Write([]byte{b})
+	}
+	return b, nil
+}
+
+func (s *bencodeScanner) peekByte() (byte, error) {
+	b, err := s.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
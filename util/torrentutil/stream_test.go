@@ -0,0 +1,178 @@
+package torrentutil_test
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+func TestParseTorrentFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{"v1", ""},
+		{"v2", torrentutil.TORRENT_FORMAT_V2},
+		{"hybrid", torrentutil.TORRENT_FORMAT_HYBRID},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeTestTorrent(t, dir, hashTestFiles, test.format)
+			filename := filepath.Join(dir, "test.torrent")
+			contents, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := torrentutil.ParseTorrent(contents)
+			if err != nil {
+				t.Fatalf("failed to parse torrent: %v", err)
+			}
+			meta, err := torrentutil.ParseTorrentFile(filename)
+			if err != nil {
+				t.Fatalf("failed to parse torrent file: %v", err)
+			}
+			if meta.InfoHash != expected.InfoHash || meta.InfoHashV2 != expected.InfoHashV2 {
+				t.Errorf("expected info hash %s / %s, got %s / %s",
+					expected.InfoHash, expected.InfoHashV2, meta.InfoHash, meta.InfoHashV2)
+			}
+			if meta.Size != expected.Size || len(meta.Files) != len(expected.Files) {
+				t.Errorf("expected size %d of %d files, got %d of %d files",
+					expected.Size, len(expected.Files), meta.Size, len(meta.Files))
+			}
+			buf := &bytes.Buffer{}
+			if err := meta.Write(buf); err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), contents) {
+				t.Errorf("written contents (%d bytes) differ from original (%d bytes)", buf.Len(), len(contents))
+			}
+		})
+	}
+}
+
+func TestParseTorrentFileEdit(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{"v1", ""},
+		{"v2", torrentutil.TORRENT_FORMAT_V2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeTestTorrent(t, dir, hashTestFiles, test.format)
+			filename := filepath.Join(dir, "test.torrent")
+			contents, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			original, err := torrentutil.ParseTorrent(contents)
+			if err != nil {
+				t.Fatalf("failed to parse torrent: %v", err)
+			}
+			meta, err := torrentutil.ParseTorrentFile(filename)
+			if err != nil {
+				t.Fatalf("failed to parse torrent file: %v", err)
+			}
+			meta.MetaInfo.Comment = "edited comment"
+			meta.MetaInfo.Announce = "https://tracker.example.com/announce"
+			meta.MetaInfo.AnnounceList = nil
+			// write back to the original file, then to another file using the new one as source
+			if err := meta.WriteFile(filename); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			meta.MetaInfo.CreatedBy = "test"
+			output := filepath.Join(dir, "edited.torrent")
+			if err := meta.WriteFile(output); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			for _, name := range []string{filename, output} {
+				contents, err := os.ReadFile(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				edited, err := torrentutil.ParseTorrent(contents)
+				if err != nil {
+					t.Fatalf("failed to parse edited torrent %s: %v", name, err)
+				}
+				if edited.MetaInfo.Comment != "edited comment" ||
+					edited.MetaInfo.Announce != "https://tracker.example.com/announce" {
+					t.Errorf("%s: expected edited comment and announce, got %q and %q",
+						name, edited.MetaInfo.Comment, edited.MetaInfo.Announce)
+				}
+				if name == output && edited.MetaInfo.CreatedBy != "test" {
+					t.Errorf("%s: expected edited created by, got %q", name, edited.MetaInfo.CreatedBy)
+				}
+				if edited.InfoHash != original.InfoHash || edited.InfoHashV2 != original.InfoHashV2 ||
+					!bytes.Equal(edited.MetaInfo.InfoBytes, original.MetaInfo.InfoBytes) {
+					t.Errorf("%s: info dict changed", name)
+				}
+				if !maps.Equal(edited.MetaInfo.PieceLayers, original.MetaInfo.PieceLayers) {
+					t.Errorf("%s: piece layers changed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTorrentFileModified(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTorrent(t, dir, hashTestFiles, "")
+	filename := filepath.Join(dir, "test.torrent")
+	meta, err := torrentutil.ParseTorrentFile(filename)
+	if err != nil {
+		t.Fatalf("failed to parse torrent file: %v", err)
+	}
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, append(contents, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := meta.Write(&bytes.Buffer{}); err == nil {
+		t.Errorf("expected error of modified original file, got nil")
+	}
+}
+
+func TestParseTorrentFileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTorrent(t, dir, hashTestFiles, "")
+	contents, err := os.ReadFile(filepath.Join(dir, "test.torrent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		contents []byte
+	}{
+		{"empty", nil},
+		{"not a dict", []byte("l4:infoe")},
+		{"no info", []byte("d7:comment4:teste")},
+		{"info not a dict", []byte("d4:infoi1ee")},
+		{"unterminated", []byte("d7:comment4:test")},
+		{"invalid integer", []byte("d4:infod6:lengthi1x2eee")},
+		{"negative string length", []byte("d4:infod4:name-1:xee")},
+		{"invalid value", []byte("d7:commentx4:infode")},
+		{"string longer than file", []byte("d7:comment99:test4:infodee")},
+		{"truncated in info", contents[:len(contents)/2]},
+		{"truncated at end", contents[:len(contents)-1]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "invalid.torrent")
+			if err := os.WriteFile(filename, test.contents, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := torrentutil.ParseTorrentFile(filename); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
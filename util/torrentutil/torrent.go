@@ -2,7 +2,7 @@ package torrentutil
 
 import (
 	"bytes"
-	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -34,12 +34,14 @@ type TorrentCommentMeta struct {
 }
 
 type TorrentMetaFile struct {
-	Path string // full path joined by '/'
-	Size int64
+	Path       string // full path joined by '/'
+	Size       int64
+	piecesRoot string // v2 merkle root hash of file contents. v2 & hybrid torrent only
 }
 
 type TorrentMeta struct {
-	InfoHash          string
+	InfoHash          string // v1 info hash. For v2 only torrent, it's the truncated v2 info hash used by clients
+	InfoHashV2        string // BitTorrent v2 info hash (sha256). Empty if it's a v1 only torrent
 	Trackers          []string
 	Size              int64
 	SingleFileTorrent bool
@@ -48,6 +50,7 @@ type TorrentMeta struct {
 	Files             []TorrentMetaFile
 	MetaInfo          *metainfo.MetaInfo
 	Info              *metainfo.Info
+	source            *streamSource // set if parsed by ParseTorrentFile
 }
 
 type TorrentMakeOptions struct {
@@ -67,6 +70,7 @@ type TorrentMakeOptions struct {
 	MinSize                       int64
 	Excludes                      []string
 	AllowRestrictedCharInFilename bool
+	Format                        string // v1 (default), v2 or hybrid
 }
 
 var (
@@ -101,6 +105,16 @@ func FromMetaInfo(metaInfo *metainfo.MetaInfo, info *metainfo.Info) (*TorrentMet
 		torrentMeta.Info = &_info
 	}
 	info = torrentMeta.Info
+	if info.MetaVersion == 2 && metaInfo.InfoBytes != nil {
+		var v2info struct {
+			FileTree map[string]any `bencode:"file tree"`
+		}
+		if err := bencode.Unmarshal(metaInfo.InfoBytes, &v2info); err != nil {
+			return nil, fmt.Errorf("invalid file tree: %w", err)
+		}
+		torrentMeta.setV2Info(fmt.Sprintf("%x", sha256.Sum256(metaInfo.InfoBytes)), v2info.FileTree)
+		return torrentMeta, nil
+	}
 	// single file torrent
 	if len(info.Files) == 0 {
 		torrentMeta.Files = append(torrentMeta.Files, TorrentMetaFile{
@@ -128,6 +142,56 @@ func FromMetaInfo(metaInfo *metainfo.MetaInfo, info *metainfo.Info) (*TorrentMet
 	return torrentMeta, nil
 }
 
+// Set v2 info hash and files of a BitTorrent v2 or hybrid torrent. The files are read from "file tree"
+// of info, so the BEP 47 pad files in v1 "files" of hybrid torrent are not included.
+func (meta *TorrentMeta) setV2Info(infoHashV2 string, fileTree map[string]any) {
+	meta.InfoHashV2 = infoHashV2
+	if meta.Format() == TORRENT_FORMAT_V2 {
+		meta.InfoHash = infoHashV2[:40]
+	}
+	meta.Files = nil
+	meta.Size = 0
+	var walk func(tree map[string]any, dir []string)
+	walk = func(tree map[string]any, dir []string) {
+		names := []string{}
+		for name := range tree {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			node, _ := tree[name].(map[string]any)
+			if file, ok := node[""].(map[string]any); ok {
+				length, _ := file["length"].(int64)
+				piecesRoot, _ := file["pieces root"].(string)
+				meta.Files = append(meta.Files, TorrentMetaFile{
+					Path:       util.Clean(strings.Join(append(slices.Clone(dir), name), "/")),
+					Size:       length,
+					piecesRoot: piecesRoot,
+				})
+				meta.Size += length
+			} else if node != nil {
+				walk(node, append(slices.Clone(dir), name))
+			}
+		}
+	}
+	walk(fileTree, nil)
+	name := util.Clean(meta.Info.Name)
+	// a single file v2 torrent has a file tree of only one file with the same name of torrent
+	if len(meta.Files) == 1 && meta.Files[0].Path == name {
+		meta.SingleFileTorrent = true
+		meta.RootDir = ""
+		meta.ContentPath = name
+	} else {
+		meta.SingleFileTorrent = false
+		meta.RootDir = ""
+		meta.ContentPath = ""
+		if meta.Info.Name != "" && meta.Info.Name != metainfo.NoName {
+			meta.RootDir = name
+			meta.ContentPath = name
+		}
+	}
+}
+
 // Encode torrent meta to 'comment' field
 func (meta *TorrentMeta) EncodeComment(commentMeta *TorrentCommentMeta) error {
 	comment := ""
@@ -276,7 +340,7 @@ outer:
 // Generate .torrent file from current content
 func (meta *TorrentMeta) ToBytes() ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
-	if err := meta.MetaInfo.Write(buf); err != nil {
+	if err := meta.Write(buf); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -285,7 +349,18 @@ func (meta *TorrentMeta) ToBytes() ([]byte, error) {
 // Generate magnet: url of this torrent.
 // Must be used on meta parsed from ParseTorrent with fields >= 2
 func (meta *TorrentMeta) MagnetUrl() string {
-	return meta.MetaInfo.Magnet(nil, meta.Info).String()
+	infoHash := metainfo.NewHashFromHex(meta.InfoHash)
+	magnetUrl := meta.MetaInfo.Magnet(&infoHash, meta.Info).String()
+	if meta.InfoHashV2 == "" {
+		return magnetUrl
+	}
+	// BEP 52: v2 info hash is "urn:btmh:" + multihash (0x12 = sha256, 0x20 = 32 bytes length)
+	btih := "xt=urn:btih:" + meta.InfoHash
+	btmh := "xt=urn:btmh:1220" + meta.InfoHashV2
+	if meta.Format() == TORRENT_FORMAT_HYBRID {
+		btmh = btih + "&" + btmh
+	}
+	return strings.Replace(magnetUrl, btih, btmh, 1)
 }
 
 func (meta *TorrentMeta) Fprint(f io.Writer, name string, showAll bool) {
@@ -323,7 +398,7 @@ func (meta *TorrentMeta) Fprint(f io.Writer, name string, showAll bool) {
 			comments = append(comments, fmt.Sprintf("source:%q", meta.Info.Source))
 		}
 		if meta.MetaInfo.CreatedBy != "" {
-			comments = append(comments, fmt.Sprintf("created_by:%q", meta.Info.Source))
+			comments = append(comments, fmt.Sprintf("created_by:%q", meta.MetaInfo.CreatedBy))
 		}
 		creationDate := "-"
 		if meta.MetaInfo.CreationDate > 0 {
@@ -341,12 +416,78 @@ func (meta *TorrentMeta) Fprint(f io.Writer, name string, showAll bool) {
 		fmt.Fprintf(f, "RawSize = %d ; PieceLength = %s ; CreationDate = %s ; AllTrackers (%d): %s ;%s\n",
 			meta.Size, util.BytesSizeAround(float64(meta.Info.PieceLength)), creationDate, len(meta.Trackers),
 			strings.Join(meta.Trackers, " | "), comment)
+		if meta.InfoHashV2 != "" {
+			fmt.Fprintf(f, "! Format = %s ; InfoHashV2 = %s\n", meta.Format(), meta.InfoHashV2)
+		}
 		if !meta.IsPrivate() {
 			fmt.Fprintf(f, "! MagnetURI: %s\n", meta.MagnetUrl())
 		}
 	}
 }
 
+// Print files of torrent as a tree, with the total size of each folder.
+func (meta *TorrentMeta) FprintFileTree(f io.Writer, useRawSize bool) {
+	type node struct {
+		name     string
+		size     int64
+		files    int64
+		isDir    bool
+		children []*node
+	}
+	sizeStr := func(size int64) string {
+		if useRawSize {
+			return fmt.Sprint(size)
+		}
+		return util.BytesSize(float64(size))
+	}
+	root := &node{name: meta.RootDir, isDir: true}
+	for _, file := range meta.Files {
+		current := root
+		current.size += file.Size
+		current.files++
+		parts := strings.Split(file.Path, "/")
+		for i, part := range parts {
+			var child *node
+			if i < len(parts)-1 {
+				if index := slices.IndexFunc(current.children, func(n *node) bool {
+					return n.isDir && n.name == part
+				}); index != -1 {
+					child = current.children[index]
+				}
+			}
+			if child == nil {
+				child = &node{name: part, isDir: i < len(parts)-1}
+				current.children = append(current.children, child)
+			}
+			child.size += file.Size
+			child.files++
+			current = child
+		}
+	}
+	var print func(n *node, prefix string)
+	print = func(n *node, prefix string) {
+		for i, child := range n.children {
+			branch, indent := "├── ", "│   "
+			if i == len(n.children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if child.isDir {
+				fmt.Fprintf(f, "%s%s%s/ (%s, %d files)\n", prefix, branch, child.name, sizeStr(child.size), child.files)
+				print(child, prefix+indent)
+			} else {
+				fmt.Fprintf(f, "%s%s%s (%s)\n", prefix, branch, child.name, sizeStr(child.size))
+			}
+		}
+	}
+	fmt.Fprintf(f, "Files:\n")
+	if meta.RootDir != "" {
+		fmt.Fprintf(f, "%s/ (%s, %d files)\n", root.name, sizeStr(root.size), root.files)
+		print(root, "")
+	} else {
+		print(root, "")
+	}
+}
+
 func (meta *TorrentMeta) FprintFiles(f io.Writer, addRootDirPrefix bool, useRawSize bool) {
 	fmt.Fprintf(f, "Files:\n")
 	for i, file := range meta.Files {
@@ -443,31 +584,19 @@ func (meta *TorrentMeta) VerifyAgaintSavePathFs(savePathFs fs.FS) error {
 	return nil
 }
 
-// checkHash: 0 - none; 1 - quick; 2+ - full.
+// checkHash: 0 - none; 1 - quick; 2+ - full. hashOptions: options of hash checking, nil to use defaults.
 // ts: timestamp of newest file in torrent contents.
-func (meta *TorrentMeta) Verify(savePath string, contentPath string, checkHash int64) (ts int64, err error) {
-	var filenames []string
-	prefixPath := ""
-	if contentPath != "" {
-		contentPath, err = filepath.Abs(contentPath)
-		if err != nil {
-			return 0, fmt.Errorf("invalid content-path: %w", err)
-		}
-		prefixPath = contentPath + "/"
-	} else {
-		prefixPath = savePath + "/"
-		if meta.RootDir != "" {
-			prefixPath += meta.RootDir + "/"
-		}
+func (meta *TorrentMeta) Verify(savePath string, contentPath string, checkHash int64,
+	hashOptions *HashOptions) (ts int64, err error) {
+	if hashOptions == nil {
+		hashOptions = &HashOptions{}
 	}
-	for _, file := range meta.Files {
-		filename := ""
-		if contentPath != "" && meta.SingleFileTorrent {
-			filename = contentPath
-		} else {
-			filename = prefixPath + file.Path
-		}
-		stat, err := os.Stat(filename)
+	filenames, err := meta.localFilenames(savePath, contentPath)
+	if err != nil {
+		return 0, err
+	}
+	for i, file := range meta.Files {
+		stat, err := os.Stat(filenames[i])
 		if err != nil {
 			return ts, fmt.Errorf("failed to get file %q stat: %w", file.Path, err)
 		}
@@ -475,67 +604,21 @@ func (meta *TorrentMeta) Verify(savePath string, contentPath string, checkHash i
 		if stat.Size() != file.Size {
 			return ts, fmt.Errorf("file %q has wrong length: expect=%d, actual=%d", file.Path, file.Size, stat.Size())
 		}
-		if checkHash > 0 {
-			filenames = append(filenames, filename)
-		}
+	}
+	if checkHash > 0 && meta.InfoHashV2 != "" {
+		// the file tree has "pieces root" of each file, it's also available in streaming mode
+		return ts, meta.verifyV2(filenames, hashOptions.Workers)
 	}
 	if checkHash > 0 && len(meta.Files) > 0 {
-		piecesCnt := meta.Info.NumPieces()
-		var currentFileIndex = int64(0)
-		var currentFileOffset = int64(0)
-		var currentFileRemain = int64(0)
-		var currentFile *os.File
-		var err error
-		i := 0
-		for {
-			if i >= piecesCnt {
-				break
-			}
-			if checkHash == 1 && currentFile != nil && currentFileRemain > meta.Info.PieceLength {
-				skipPieces := currentFileRemain / meta.Info.PieceLength
-				skipLength := skipPieces * meta.Info.PieceLength
-				currentFileOffset += skipLength
-				currentFileRemain -= skipLength
-				i += int(skipPieces)
-			}
-			p := meta.Info.Piece(i)
-			hash := sha1.New()
-			len := p.Length()
-			for len > 0 {
-				if currentFile == nil {
-					if currentFile, err = os.Open(filenames[currentFileIndex]); err != nil {
-						return ts, fmt.Errorf("piece %d/%d: failed to open file %s: %w",
-							i, piecesCnt-1, filenames[currentFileIndex], err)
-					}
-					log.Tracef("piece %d/%d: open file %s", i, piecesCnt-1, filenames[currentFileIndex])
-					currentFileOffset = 0
-					currentFileRemain = meta.Files[currentFileIndex].Size
-				}
-				readlen := min(currentFileRemain, len)
-				_, err := io.Copy(hash, io.NewSectionReader(currentFile, currentFileOffset, readlen))
-				if err != nil {
-					currentFile.Close()
-					return ts, err
-				}
-				currentFileOffset += readlen
-				currentFileRemain -= readlen
-				len -= readlen
-				if currentFileRemain == 0 {
-					currentFile.Close()
-					currentFile = nil
-					currentFileIndex++
-				}
-			}
-			good := bytes.Equal(hash.Sum(nil), p.Hash().Bytes())
-			if !good {
-				return ts, fmt.Errorf("piece %d/%d: hash mismatch", i, piecesCnt-1)
-			}
-			log.Tracef("piece %d/%d verify-hash %x: %v", i, piecesCnt-1, p.Hash(), good)
-			i++
+		if meta.source != nil {
+			return ts, ErrPiecesNotLoaded
+		}
+		if err := meta.verifyPieces(filenames, checkHash == 1, *hashOptions); err != nil {
+			return ts, err
 		}
 	}
 	if contentPath != "" {
-		fileStats, err := os.Stat(contentPath)
+		fileStats, err := os.Stat(util.LongPath(contentPath))
 		if err == nil {
 			if meta.SingleFileTorrent {
 				if fileStats.Name() != meta.Files[0].Path {
@@ -555,6 +638,34 @@ func (meta *TorrentMeta) Verify(savePath string, contentPath string, checkHash i
 	return ts, nil
 }
 
+// Return the local filenames of torrent files, in the order of meta.Files.
+// If contentPath (the root folder or single file of torrent contents) is set, files are located in it;
+// Otherwise in savePath.
+func (meta *TorrentMeta) localFilenames(savePath string, contentPath string) ([]string, error) {
+	prefixPath := ""
+	if contentPath != "" {
+		var err error
+		if contentPath, err = filepath.Abs(contentPath); err != nil {
+			return nil, fmt.Errorf("invalid content-path: %w", err)
+		}
+		prefixPath = contentPath + "/"
+	} else {
+		prefixPath = savePath + "/"
+		if meta.RootDir != "" {
+			prefixPath += meta.RootDir + "/"
+		}
+	}
+	var filenames []string
+	for _, file := range meta.Files {
+		filename := prefixPath + file.Path
+		if contentPath != "" && meta.SingleFileTorrent {
+			filename = contentPath
+		}
+		filenames = append(filenames, util.LongPath(filename))
+	}
+	return filenames, nil
+}
+
 // Rename torrent (downloaded filename or name of torrent added to client) according to rename template.
 // filename: original torrent filename (e.g. "abc.torrent").
 // available variable placeholders: [size], [id], [site], [filename], [filename128], [name], [name128].
@@ -596,6 +707,9 @@ func RenameExportedTorrent(client string, torrent *client.Torrent, rename string
 // Create a torrent, return info of created torrent.
 // It may change the values of any fields in options.
 func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
+	if options.Format != "" && !slices.Contains(TorrentFormats, options.Format) {
+		return nil, fmt.Errorf("invalid format %q", options.Format)
+	}
 	mi := &metainfo.MetaInfo{
 		AnnounceList: make([][]string, 0),
 		Comment:      options.Comment,
@@ -648,23 +762,46 @@ func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
 		options.AllowRestrictedCharInFilename); err != nil {
 		return nil, fmt.Errorf("failed to build info from content-path: %w", err)
 	}
-	if len(info.Files) == 0 {
+	if len(info.Files) == 0 && info.Length == 0 {
 		return nil, fmt.Errorf("no files found in content-path")
 	}
-	if options.MinSize > 0 {
-		size := int64(0)
-		for _, file := range info.Files {
-			size += file.Length
-		}
-		if size < options.MinSize {
-			return nil, ErrSmall
-		}
+	if options.MinSize > 0 && info.TotalLength() < options.MinSize {
+		return nil, ErrSmall
 	}
-	if options.InfoName != "" {
-		info.Name = options.InfoName
+	if info.PieceLength == 0 {
+		info.PieceLength = metainfo.ChoosePieceLength(info.TotalLength())
 	}
-	if mi.InfoBytes, err = bencode.Marshal(info); err != nil {
-		return nil, fmt.Errorf("failed to marshal info: %w", err)
+	if options.Format == "" || options.Format == TORRENT_FORMAT_V1 {
+		err = info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+			if len(info.Files) == 0 {
+				return os.Open(options.ContentPath)
+			}
+			return os.Open(filepath.Join(options.ContentPath, strings.Join(fi.Path, string(filepath.Separator))))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error generating pieces: %w", err)
+		}
+		if options.InfoName != "" {
+			info.Name = options.InfoName
+		}
+		if mi.InfoBytes, err = bencode.Marshal(info); err != nil {
+			return nil, fmt.Errorf("failed to marshal info: %w", err)
+		}
+	} else {
+		infoDict, pieceLayers, err := buildV2Info(info, options.ContentPath, options.Format == TORRENT_FORMAT_HYBRID)
+		if err != nil {
+			return nil, err
+		}
+		if options.InfoName != "" {
+			info.Name = options.InfoName
+			infoDict["name"] = options.InfoName
+		}
+		if mi.InfoBytes, err = bencode.Marshal(infoDict); err != nil {
+			return nil, fmt.Errorf("failed to marshal info: %w", err)
+		}
+		if len(pieceLayers) > 0 {
+			mi.PieceLayers = pieceLayers
+		}
 	}
 	if options.Output == "" {
 		if info.Name != "" && info.Name != metainfo.NoName {
@@ -695,7 +832,12 @@ func MakeTorrent(options *TorrentMakeOptions) (tinfo *TorrentMeta, err error) {
 	if err != nil {
 		return nil, err
 	}
-	tinfo, err = FromMetaInfo(mi, info)
+	if options.Format == "" || options.Format == TORRENT_FORMAT_V1 {
+		tinfo, err = FromMetaInfo(mi, info)
+	} else {
+		// the v2 fields are not in metainfo.Info, parse it from info bytes
+		tinfo, err = FromMetaInfo(mi, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -765,14 +907,5 @@ func infoBuildFromFilePath(info *metainfo.Info, root string, excludes []string,
 		}
 		return 0
 	})
-	if info.PieceLength == 0 {
-		info.PieceLength = metainfo.ChoosePieceLength(info.TotalLength())
-	}
-	err = info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
-		return os.Open(filepath.Join(root, strings.Join(fi.Path, string(filepath.Separator))))
-	})
-	if err != nil {
-		err = fmt.Errorf("error generating pieces: %s", err)
-	}
 	return
 }
@@ -0,0 +1,251 @@
+package torrentutil
+
+// BitTorrent v2 (BEP 52) support: creating v2 and hybrid (v1 + v2) torrents, and verifying
+// contents of torrents using the merkle "pieces root" of files.
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/anacrolix/torrent/metainfo"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Format (meta version) of created torrent.
+const (
+	TORRENT_FORMAT_V1     = "v1"
+	TORRENT_FORMAT_V2     = "v2"
+	TORRENT_FORMAT_HYBRID = "hybrid" // contains both v1 and v2 info, compatible with v1 only clients
+)
+
+// The merkle tree leaf block size of BitTorrent v2.
+const V2_BLOCK_SIZE = 16 * 1024
+
+var TorrentFormats = []string{TORRENT_FORMAT_V1, TORRENT_FORMAT_V2, TORRENT_FORMAT_HYBRID}
+
+type v2File struct {
+	path     []string
+	filename string // file system path
+	length   int64
+}
+
+// Build the info dict and piece layers of v2 or hybrid torrent from info, which has the name,
+// piece length and files (generated by infoBuildFromFilePath) set. root: the content path.
+func buildV2Info(info *metainfo.Info, root string, hybrid bool) (
+	infoDict map[string]any, pieceLayers map[string]string, err error) {
+	if info.PieceLength < V2_BLOCK_SIZE || info.PieceLength&(info.PieceLength-1) != 0 {
+		return nil, nil, fmt.Errorf("piece length of v2 torrent must be a power of 2 and >= 16KiB")
+	}
+	var files []*v2File
+	if len(info.Files) == 0 {
+		files = append(files, &v2File{path: []string{info.Name}, filename: root, length: info.Length})
+	} else {
+		for _, file := range info.Files {
+			files = append(files, &v2File{
+				path:     file.Path,
+				filename: filepath.Join(root, filepath.Join(file.Path...)),
+				length:   file.Length,
+			})
+		}
+		// v2 file tree is a dict, the v1 files of hybrid torrent must be in the same order
+		slices.SortStableFunc(files, func(a, b *v2File) int {
+			return slices.Compare(a.path, b.path)
+		})
+	}
+	fileTree := map[string]any{}
+	pieceLayers = map[string]string{}
+	for _, file := range files {
+		node := fileTree
+		for _, name := range file.path[:len(file.path)-1] {
+			if node[name] == nil {
+				node[name] = map[string]any{}
+			}
+			node = node[name].(map[string]any)
+		}
+		fileInfo := map[string]any{"length": file.length}
+		if file.length > 0 {
+			piecesRoot, layer, err := hashFileV2(file.filename, file.length, info.PieceLength)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to hash %s: %w", file.filename, err)
+			}
+			fileInfo["pieces root"] = string(piecesRoot)
+			if layer != nil {
+				pieceLayers[string(piecesRoot)] = string(layer)
+			}
+		}
+		node[file.path[len(file.path)-1]] = map[string]any{"": fileInfo}
+	}
+	infoDict = map[string]any{
+		"name":         info.Name,
+		"piece length": info.PieceLength,
+		"meta version": 2,
+		"file tree":    fileTree,
+	}
+	if info.Private != nil && *info.Private {
+		infoDict["private"] = 1
+	}
+	if hybrid {
+		v1info := &metainfo.Info{PieceLength: info.PieceLength, Length: info.Length}
+		var v1filenames []string // file system path of v1 files, empty for pad files
+		if len(info.Files) > 0 {
+			// BEP 47 pad files align each file to piece boundary, so that v1 and v2 pieces are the same
+			v1files := []map[string]any{}
+			for i, file := range files {
+				v1files = append(v1files, map[string]any{"length": file.length, "path": file.path})
+				v1info.Files = append(v1info.Files, metainfo.FileInfo{Length: file.length, Path: file.path})
+				v1filenames = append(v1filenames, file.filename)
+				if padding := (info.PieceLength - file.length%info.PieceLength) % info.PieceLength; padding > 0 &&
+					i < len(files)-1 {
+					padPath := []string{".pad", fmt.Sprint(padding)}
+					v1files = append(v1files, map[string]any{"attr": "p", "length": padding, "path": padPath})
+					v1info.Files = append(v1info.Files, metainfo.FileInfo{Length: padding, Path: padPath})
+					v1filenames = append(v1filenames, "")
+				}
+			}
+			infoDict["files"] = v1files
+		} else {
+			infoDict["length"] = info.Length
+		}
+		index := 0
+		err = v1info.GeneratePieces(func(fi metainfo.FileInfo) (io.ReadCloser, error) {
+			if len(info.Files) == 0 {
+				return os.Open(root)
+			}
+			index++
+			if v1filenames[index-1] == "" {
+				return io.NopCloser(io.LimitReader(zeroReader{}, fi.Length)), nil
+			}
+			return os.Open(v1filenames[index-1])
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("error generating pieces: %w", err)
+		}
+		infoDict["pieces"] = string(v1info.Pieces)
+	}
+	return infoDict, pieceLayers, nil
+}
+
+// Calculate the merkle root hash ("pieces root") of a file contents of length.
+// If the file is larger than piece length, also return it's piece layer (concatenated piece hashes).
+func hashFileV2(filename string, length int64, pieceLength int64) (piecesRoot []byte, layer []byte, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 1024*1024)
+	blocksPerPiece := int(pieceLength / V2_BLOCK_SIZE)
+	buf := make([]byte, V2_BLOCK_SIZE)
+	var blocks, pieces [][32]byte
+	for remain := length; remain > 0; {
+		n := min(remain, V2_BLOCK_SIZE)
+		if _, err := io.ReadFull(reader, buf[:n]); err != nil {
+			return nil, nil, err
+		}
+		remain -= n
+		blocks = append(blocks, sha256.Sum256(buf[:n]))
+		if len(blocks) == blocksPerPiece {
+			pieces = append(pieces, merkleRoot(blocks, blocksPerPiece, [32]byte{}))
+			blocks = blocks[:0]
+		}
+	}
+	if len(blocks) > 0 {
+		// file smaller than a piece: the leaf layer is padded to the next power of 2, not to the piece size
+		if len(pieces) == 0 {
+			root := merkleRoot(blocks, nextPowerOf2(len(blocks)), [32]byte{})
+			return root[:], nil, nil
+		}
+		pieces = append(pieces, merkleRoot(blocks, blocksPerPiece, [32]byte{}))
+	}
+	if len(pieces) == 1 {
+		return pieces[0][:], nil, nil
+	}
+	// the hash of a piece which is beyond the end of file (all leaf hashes are zero)
+	padPieceHash := merkleRoot(nil, blocksPerPiece, [32]byte{})
+	root := merkleRoot(pieces, nextPowerOf2(len(pieces)), padPieceHash)
+	for _, piece := range pieces {
+		layer = append(layer, piece[:]...)
+	}
+	return root[:], layer, nil
+}
+
+// Verify contents of v2 (or hybrid) torrent files against the "pieces root" of file tree.
+// filenames: file system paths of meta.Files. Files are hashed concurrently by (at least 1) workers.
+func (meta *TorrentMeta) verifyV2(filenames []string, workers int64) error {
+	indexes := make([]int, len(meta.Files))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	_, errs := util.ParallelMap(indexes, max(workers, 1), 0, func(_ context.Context, i int) (any, error) {
+		file := meta.Files[i]
+		if file.Size == 0 {
+			return nil, nil
+		}
+		piecesRoot, _, err := hashFileV2(filenames[i], file.Size, meta.Info.PieceLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash file %q: %w", file.Path, err)
+		}
+		if string(piecesRoot) != file.piecesRoot {
+			return nil, fmt.Errorf("file %q has wrong contents: pieces root hash mismatch", file.Path)
+		}
+		return nil, nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the meta version (format) of torrent: v1, v2 or hybrid.
+func (meta *TorrentMeta) Format() string {
+	if meta.InfoHashV2 == "" {
+		return TORRENT_FORMAT_V1
+	}
+	if len(meta.Info.Pieces) > 0 || len(meta.Info.Files) > 0 || meta.Info.Length > 0 {
+		return TORRENT_FORMAT_HYBRID
+	}
+	return TORRENT_FORMAT_V2
+}
+
+// Return the merkle root of hashes, which are padded to width (a power of 2) with pad.
+func merkleRoot(hashes [][32]byte, width int, pad [32]byte) [32]byte {
+	layer := make([][32]byte, width)
+	copy(layer, hashes)
+	for i := len(hashes); i < width; i++ {
+		layer[i] = pad
+	}
+	var buf [64]byte
+	for len(layer) > 1 {
+		for i := 0; i < len(layer)/2; i++ {
+			copy(buf[:32], layer[2*i][:])
+			copy(buf[32:], layer[2*i+1][:])
+			layer[i] = sha256.Sum256(buf[:])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+func nextPowerOf2(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package torrentutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sha256 of the concatenation of a and b: the parent node of merkle tree.
+func hashPair(a, b [32]byte) [32]byte {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+func TestHashFileV2(t *testing.T) {
+	const block = V2_BLOCK_SIZE
+	// data of 5 blocks, the last one is partial
+	data := make([]byte, 4*block+100)
+	for i := range data {
+		data[i] = byte(i*13 + i/block)
+	}
+	h := func(i int) [32]byte {
+		return sha256.Sum256(data[i*block : min((i+1)*block, len(data))])
+	}
+	var zero [32]byte
+	concat := func(hashes ...[32]byte) (layer []byte) {
+		for _, hash := range hashes {
+			layer = append(layer, hash[:]...)
+		}
+		return layer
+	}
+	tests := []struct {
+		name        string
+		length      int64
+		pieceLength int64
+		piecesRoot  [32]byte
+		layer       []byte
+	}{
+		{"one partial block", 100, block, sha256.Sum256(data[:100]), nil},
+		{"one block", block, 4 * block, h(0), nil},
+		{"two blocks in one piece", 2 * block, 4 * block, hashPair(h(0), h(1)), nil},
+		// leaves of file smaller than a piece are padded to the next power of 2, not to the piece size
+		{"three blocks in one piece", 3 * block, 4 * block, hashPair(hashPair(h(0), h(1)), hashPair(h(2), zero)),
+			nil},
+		{"exactly one piece", 2 * block, 2 * block, hashPair(h(0), h(1)), nil},
+		{"two pieces", 4 * block, 2 * block, hashPair(hashPair(h(0), h(1)), hashPair(h(2), h(3))),
+			concat(hashPair(h(0), h(1)), hashPair(h(2), h(3)))},
+		// the last piece is padded by zero leaves, the pieces layer is padded by the hash of a zero piece
+		{"three pieces", int64(len(data)), 2 * block,
+			hashPair(hashPair(hashPair(h(0), h(1)), hashPair(h(2), h(3))), hashPair(hashPair(h(4), zero),
+				hashPair(zero, zero))),
+			concat(hashPair(h(0), h(1)), hashPair(h(2), h(3)), hashPair(h(4), zero))},
+		{"one block per piece", 3 * block, block, hashPair(hashPair(h(0), h(1)), hashPair(h(2), zero)),
+			concat(h(0), h(1), h(2))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(filename, data[:test.length], 0644); err != nil {
+				t.Fatal(err)
+			}
+			piecesRoot, layer, err := hashFileV2(filename, test.length, test.pieceLength)
+			if err != nil {
+				t.Fatalf("failed to hash file: %v", err)
+			}
+			if !bytes.Equal(piecesRoot, test.piecesRoot[:]) {
+				t.Errorf("expected pieces root %x, got %x", test.piecesRoot, piecesRoot)
+			}
+			if !bytes.Equal(layer, test.layer) {
+				t.Errorf("expected piece layer %x, got %x", test.layer, layer)
+			}
+		})
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	a, b, c := sha256.Sum256([]byte("a")), sha256.Sum256([]byte("b")), sha256.Sum256([]byte("c"))
+	pad := sha256.Sum256([]byte("pad"))
+	tests := []struct {
+		name     string
+		hashes   [][32]byte
+		width    int
+		expected [32]byte
+	}{
+		{"single", [][32]byte{a}, 1, a},
+		{"pair", [][32]byte{a, b}, 2, hashPair(a, b)},
+		{"padded", [][32]byte{a, b, c}, 4, hashPair(hashPair(a, b), hashPair(c, pad))},
+		{"padded wide", [][32]byte{a}, 4, hashPair(hashPair(a, pad), hashPair(pad, pad))},
+		{"all padding", nil, 2, hashPair(pad, pad)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := merkleRoot(test.hashes, test.width, pad); result != test.expected {
+				t.Errorf("expected %x, got %x", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package torrentutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagan/ptool/util/torrentutil"
+)
+
+func TestMakeAndVerifyV2Torrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		corrupt bool // corrupt a byte of contents after making torrent
+	}{
+		{"v2", torrentutil.TORRENT_FORMAT_V2, false},
+		{"v2 corrupted", torrentutil.TORRENT_FORMAT_V2, true},
+		{"hybrid", torrentutil.TORRENT_FORMAT_HYBRID, false},
+		{"hybrid corrupted", torrentutil.TORRENT_FORMAT_HYBRID, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeTestTorrent(t, dir, hashTestFiles, test.format)
+			// parse the written torrent, instead of using the returned one
+			contents, err := os.ReadFile(filepath.Join(dir, "test.torrent"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tinfo, err := torrentutil.ParseTorrent(contents)
+			if err != nil {
+				t.Fatalf("failed to parse torrent: %v", err)
+			}
+			if tinfo.Format() != test.format {
+				t.Errorf("expected format %s, got %s", test.format, tinfo.Format())
+			}
+			if len(tinfo.InfoHashV2) != 64 {
+				t.Errorf("invalid v2 info hash %q", tinfo.InfoHashV2)
+			}
+			if test.format == torrentutil.TORRENT_FORMAT_HYBRID && len(tinfo.InfoHash) != 40 {
+				t.Errorf("invalid v1 info hash %q of hybrid torrent", tinfo.InfoHash)
+			}
+			if test.corrupt {
+				if err := corruptByte(filepath.Join(dir, "data", "c.bin"), 25*1024); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err = tinfo.Verify(dir, "", 2, &torrentutil.HashOptions{Workers: 2})
+			if test.corrupt && err == nil {
+				t.Errorf("expected verify error of corrupted contents, got nil")
+			} else if !test.corrupt && err != nil {
+				t.Errorf("failed to verify: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	TOTP_PERIOD = 30 // seconds
+	TOTP_DIGITS = 6
+)
+
+// The clock used by GenerateCurrentTotp. It can be replaced (e.g. in tests).
+var TotpClock = time.Now
+
+// Generate the TOTP code of secret at current time (of TotpClock). See GenerateTotp.
+func GenerateCurrentTotp(secret string) (string, error) {
+	return GenerateTotp(secret, TotpClock().Unix())
+}
+
+// Generate the TOTP (RFC 6238) code of secret at unix timestamp ts.
+// secret is the Base32 encoded key (case insensitive, spaces and padding are optional),
+// or an "otpauth://totp/..." uri (of 2FA QR code) which contains the secret param.
+// The default parameters of most sites (Google Authenticator compatible) are used:
+// HMAC-SHA1, 30 seconds period, 6 digits; the "algorithm" (SHA1 / SHA256 / SHA512), "period"
+// and "digits" params of otpauth uri, if present, override them.
+func GenerateTotp(secret string, ts int64) (string, error) {
+	newHash := sha1.New
+	period := int64(TOTP_PERIOD)
+	digits := int64(TOTP_DIGITS)
+	if strings.HasPrefix(secret, "otpauth://") {
+		urlObj, err := url.Parse(secret)
+		if err != nil {
+			return "", fmt.Errorf("invalid otpauth uri: %w", err)
+		}
+		query := urlObj.Query()
+		secret = query.Get("secret")
+		switch algorithm := strings.ToUpper(query.Get("algorithm")); algorithm {
+		case "", "SHA1":
+		case "SHA256":
+			newHash = sha256.New
+		case "SHA512":
+			newHash = sha512.New
+		default:
+			return "", fmt.Errorf("unsupported totp algorithm %q", algorithm)
+		}
+		if value := query.Get("period"); value != "" {
+			if period = ParseInt(value); period <= 0 {
+				return "", fmt.Errorf("invalid totp period %q", value)
+			}
+		}
+		if value := query.Get("digits"); value != "" {
+			// HOTP (RFC 4226) codes are truncated from 31 bits integers, 9 digits at most
+			if digits = ParseInt(value); digits <= 0 || digits > 9 {
+				return "", fmt.Errorf("invalid totp digits %q", value)
+			}
+		}
+	}
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	if secret == "" {
+		return "", fmt.Errorf("empty totp secret")
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret (not base32): %w", err)
+	}
+	return hotp(newHash, key, ts/period, digits), nil
+}
+
+// Return the HOTP (RFC 4226) code of key at counter, in digits length.
+func hotp(newHash func() hash.Hash, key []byte, counter int64, digits int64) string {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(counter))
+	mac := hmac.New(newHash, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := int64(0); i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
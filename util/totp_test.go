@@ -0,0 +1,108 @@
+package util_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+// RFC 6238 Appendix B test vectors.
+func TestGenerateTotpRfc6238(t *testing.T) {
+	keys := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		ts        int64
+		algorithm string
+		expected  string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, test := range tests {
+		t.Run(test.algorithm+"-"+test.expected, func(t *testing.T) {
+			secret := base32.StdEncoding.EncodeToString([]byte(keys[test.algorithm]))
+			uri := "otpauth://totp/test?secret=" + secret + "&digits=8&algorithm=" + test.algorithm
+			code, err := util.GenerateTotp(uri, test.ts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, code)
+			}
+		})
+	}
+}
+
+func TestGenerateTotp(t *testing.T) {
+	// base32 of "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		name     string
+		secret   string
+		ts       int64
+		expected string
+		wantErr  bool
+	}{
+		{"default 6 digits", secret, 59, "287082", false},
+		{"lower case with spaces", "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", 59, "287082", false},
+		{"padding", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====", 1111111109, "081804", false},
+		{"uri", "otpauth://totp/site:user?secret=" + secret + "&issuer=site", 1234567890, "005924", false},
+		{"uri 7 digits", "otpauth://totp/a?secret=" + secret + "&digits=7", 2000000000, "9279037", false},
+		{"uri period 60", "otpauth://totp/a?secret=" + secret + "&period=60&digits=8", 119, "94287082", false},
+		{"empty", "", 59, "", true},
+		{"not base32", "0189", 59, "", true},
+		{"invalid digits", "otpauth://totp/a?secret=" + secret + "&digits=10", 59, "", true},
+		{"invalid algorithm", "otpauth://totp/a?secret=" + secret + "&algorithm=MD5", 59, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := util.GenerateTotp(test.secret, test.ts)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got code %s", code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, code)
+			}
+		})
+	}
+}
+
+func TestGenerateCurrentTotp(t *testing.T) {
+	clock := util.TotpClock
+	defer func() { util.TotpClock = clock }()
+	util.TotpClock = func() time.Time { return time.Unix(59, 0) }
+	code, err := util.GenerateCurrentTotp("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != "287082" {
+		t.Errorf("expected 287082, got %s", code)
+	}
+}
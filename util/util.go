@@ -194,13 +194,17 @@ func ParseProxyFromEnv(urlStr string) string {
 // truncated. The function does not copy the file mode, file
 // permission bits, or file attributes.
 func CopyFile(srcpath, dstpath string) (err error) {
-	r, err := os.Open(srcpath)
+	r, err := OpenCopySource(srcpath)
 	if err != nil {
 		return err
 	}
 	defer r.Close() // ignore error: file was opened read-only.
+	stat, err := r.Stat()
+	if err != nil {
+		return err
+	}
 
-	w, err := os.Create(dstpath)
+	w, err := CreateCopyDest(dstpath, stat.Size(), os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
@@ -213,7 +217,7 @@ func CopyFile(srcpath, dstpath string) (err error) {
 		}
 	}()
 
-	_, err = io.Copy(w, r)
+	_, err = CopyData(w, r)
 	return err
 }
 
@@ -221,6 +225,16 @@ func CopyFile(srcpath, dstpath string) (err error) {
 // Symbolinks are ignored.
 // For file with size < limit, create a copy instead.
 func LinkDir(source string, dest string, limit int64) error {
+	return linkDir(source, dest, limit, false)
+}
+
+// Similar to LinkDir, but if a file can NOT be hardlinked (e.g. source and dest are in different file systems),
+// fallback to reflink or copy it.
+func LinkOrCopyDir(source string, dest string, limit int64) error {
+	return linkDir(source, dest, limit, true)
+}
+
+func linkDir(source string, dest string, limit int64, fallback bool) error {
 	return filepath.WalkDir(source, func(sourcePath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -244,6 +258,12 @@ func LinkDir(source string, dest string, limit int64) error {
 				if err := CopyFile(sourcePath, destPath); err != nil {
 					return err
 				}
+			} else if fallback {
+				if method, err := LinkOrCopyFile(sourcePath, destPath); err != nil {
+					return err
+				} else {
+					log.Tracef("%s %s => %s", method, sourcePath, destPath)
+				}
 			} else {
 				log.Tracef("Link %s => %s", sourcePath, destPath)
 				if err := os.Link(sourcePath, destPath); err != nil {
@@ -255,6 +275,28 @@ func LinkDir(source string, dest string, limit int64) error {
 	})
 }
 
+// Create hardlink of source file at dest. If failed (e.g. in different file systems), try reflink then copy.
+// Return the used method: "link", "reflink" or "copy".
+func LinkOrCopyFile(source string, dest string) (method string, err error) {
+	err = os.Link(source, dest)
+	if err == nil {
+		return "link", nil
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	if Reflink(source, dest) == nil {
+		return "reflink", nil
+	}
+	if FileExists(dest) {
+		return "", fmt.Errorf("dest %s already exists", dest)
+	}
+	if err = CopyFile(source, dest); err != nil {
+		return "", err
+	}
+	return "copy", nil
+}
+
 // Check whether a file (or dir) with name exists in file system
 func FileExists(name string) bool {
 	if _, err := os.Stat(name); err == nil || !os.IsNotExist(err) {